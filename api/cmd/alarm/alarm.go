@@ -0,0 +1,127 @@
+package alarm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+
+	"github.com/clickvisual/clickvisual/api/cmd"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/utils"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+var (
+	server   string
+	username string
+	password string
+	file     string
+	alarmIds []int
+	dryRun   bool
+	prune    bool
+)
+
+// CmdRun alarm command, talks to a running server with the HTTP api
+var CmdRun = &cobra.Command{
+	Use:   "alarm",
+	Short: "告警导入导出（YAML）",
+	Long:  `以 YAML 文件管理告警，export 导出告警，apply 对比 YAML 与当前告警并创建、更新或删除。`,
+}
+
+var cmdExport = &cobra.Command{
+	Use:   "export",
+	Short: "导出告警为 YAML",
+	RunE: func(c *cobra.Command, args []string) error {
+		client, err := login()
+		if err != nil {
+			return err
+		}
+		req := client.R()
+		for _, id := range alarmIds {
+			req.QueryParam.Add("alarmIds", fmt.Sprintf("%d", id))
+		}
+		var content string
+		if err = call(req, resty.MethodGet, "/api/v2/alert/alarms-export", &content); err != nil {
+			return err
+		}
+		if file == "" || file == "-" {
+			fmt.Print(content)
+			return nil
+		}
+		return os.WriteFile(file, []byte(content), 0o644)
+	},
+}
+
+var cmdApply = &cobra.Command{
+	Use:   "apply",
+	Short: "应用 YAML 中的告警，--dry-run 仅输出变更计划",
+	RunE: func(c *cobra.Command, args []string) error {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		client, err := login()
+		if err != nil {
+			return err
+		}
+		var plan view.RespAlarmPlan
+		err = call(client.R().SetBody(view.ReqAlarmApply{Content: string(content), DryRun: dryRun, Prune: prune}),
+			resty.MethodPost, "/api/v2/alert/alarms-apply", &plan)
+		fmt.Print(service.AlarmPlanString(plan))
+		return err
+	},
+}
+
+func init() {
+	CmdRun.PersistentFlags().StringVar(&server, "server", "http://127.0.0.1:19001", "clickvisual 服务地址")
+	CmdRun.PersistentFlags().StringVar(&username, "username", "clickvisual", "root 用户名")
+	CmdRun.PersistentFlags().StringVar(&password, "password", "", "用户密码，默认读取环境变量 CLICKVISUAL_PASSWORD")
+	cmdExport.Flags().StringVarP(&file, "file", "f", "-", "导出文件，默认输出到标准输出")
+	cmdExport.Flags().IntSliceVar(&alarmIds, "alarm-id", nil, "导出的告警 id，默认全部")
+	cmdApply.Flags().StringVarP(&file, "file", "f", "", "YAML 文件")
+	cmdApply.Flags().BoolVar(&dryRun, "dry-run", false, "仅输出变更计划")
+	cmdApply.Flags().BoolVar(&prune, "prune", false, "删除 YAML 中不存在的告警")
+	_ = cmdApply.MarkFlagRequired("file")
+
+	CmdRun.AddCommand(cmdExport, cmdApply)
+	cmd.RootCommand.AddCommand(CmdRun)
+}
+
+// login returns a client with the session cookie of the user.
+func login() (*resty.Client, error) {
+	if password == "" {
+		password = os.Getenv("CLICKVISUAL_PASSWORD")
+	}
+	client := resty.New().SetBaseURL(strings.TrimSuffix(server, "/"))
+	// the same as the login page: MD5(32) of the password
+	req := client.R().SetFormData(map[string]string{"username": username, "password": utils.MD5Encode32(password)})
+	if err := call(req, resty.MethodPost, "/api/admin/users/login", nil); err != nil {
+		return nil, errors.Wrap(err, "login")
+	}
+	return client, nil
+}
+
+// call sends the request and decodes the data of the core.Res envelope into data.
+func call(req *resty.Request, method, url string, data interface{}) error {
+	resp, err := req.Execute(method, url)
+	if err != nil {
+		return err
+	}
+	if resp.IsError() {
+		return errors.Errorf("%s %s: %s", method, url, resp.Status())
+	}
+	res := core.Res{Data: data}
+	if err = json.Unmarshal(resp.Body(), &res); err != nil {
+		return errors.Wrapf(err, "%s %s", method, url)
+	}
+	if res.Code != core.CodeOK {
+		return errors.Errorf("%s %s: %s", method, url, res.Msg)
+	}
+	return nil
+}
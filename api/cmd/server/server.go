@@ -53,6 +53,9 @@ func CmdFunc(cmd *cobra.Command, args []string) {
 		egovernor.Load("server.governor").Build(),
 		router.GetServerRouter(),
 	)
+	if econf.GetBool("ingest.enable") && econf.GetInt("server.otlp.port") > 0 {
+		app.Serve(router.GetOTLPServer())
+	}
 	if econf.GetBool("prom2click.enable") {
 		// Compatible with historical versions
 		if econf.GetString("prom2click.dev.host") != "" {
@@ -2,18 +2,30 @@ package i
 
 import (
 	"database/sql"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
 )
 
 type Reader interface {
 	// Description read model
 	Description() string
 
+	// Validate checks the settings before anything is created
+	Validate() error
 	Create() (tables []string, sqls []string, err error)
 	Delete() error
 	Detach() error
 	Attach() error
 }
 
+// Importer is implemented by the readers which import the files periodically.
+type Importer interface {
+	// Import imports at most limit files which are not imported yet and returns them
+	Import(limit int) (files []string, err error)
+	// DeleteFiles forgets the imported files, once the table has stopped importing
+	DeleteFiles() error
+}
+
 type ReaderParams struct {
 	// common
 	CreateType int
@@ -26,9 +38,18 @@ type ReaderParams struct {
 	Conn      *sql.DB // clickhouse
 
 	// reader
+	ReaderType string // db.ReaderType*, kafka when empty
+	Columns    string // columns of the stream table, _log String of JSONAsString when empty
+
+	// kafka
 	Brokers                 string
 	Topics                  string
 	GroupName               string
 	KafkaNumConsumers       int
 	KafkaSkipBrokenMessages int
+
+	// rabbitmq, nats, s3queue and import
+	Settings db.ReaderSettings
+	// import, the hosts which the url source may read, app.importURLHosts
+	ImportURLHosts []string
 }
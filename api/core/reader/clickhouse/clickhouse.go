@@ -3,6 +3,7 @@ package clickhouse
 import (
 	"database/sql"
 	"fmt"
+	"strings"
 
 	"github.com/pkg/errors"
 
@@ -13,7 +14,8 @@ import (
 
 var _ i.Reader = (*Reader)(nil)
 
-type Reader struct {
+// base holds the stream table shared by the readers, the engine reading the logs differs.
+type base struct {
 	createType int
 
 	isShard   bool   // isShard Does it include shard
@@ -21,8 +23,96 @@ type Reader struct {
 	cluster   string // cluster name
 	database  string // database name
 	table     string // table name
+	columns   string // columns of the stream table
 
 	conn *sql.DB // clickhouse instance
+}
+
+func newBase(req i.ReaderParams) base {
+	return base{
+		createType: req.CreateType,
+		isShard:    req.IsShard,
+		isReplica:  req.IsReplica,
+		cluster:    req.Cluster,
+		database:   req.Database,
+		table:      req.Table,
+		columns:    req.Columns,
+		conn:       req.Conn,
+	}
+}
+
+// name returns the stream table with the suffix, on the cluster when it has shards or replicas.
+func (b *base) name(suffix string) string {
+	if b.isReplica || b.isShard {
+		return fmt.Sprintf("`%s`.`%s_local%s` on cluster '%s'", b.database, b.table, suffix, b.cluster)
+	}
+	return fmt.Sprintf("`%s`.`%s%s`", b.database, b.table, suffix)
+}
+
+// localName returns the stream table with the suffix without the cluster.
+func (b *base) localName(suffix string) string {
+	if b.isReplica || b.isShard {
+		return fmt.Sprintf("`%s`.`%s_local%s`", b.database, b.table, suffix)
+	}
+	return fmt.Sprintf("`%s`.`%s%s`", b.database, b.table, suffix)
+}
+
+// group returns the consumer group of the table.
+func (b *base) group() string {
+	return fmt.Sprintf("%s_%s", b.database, b.table)
+}
+
+// format returns the input format of the create type.
+func (b *base) format() (string, error) {
+	switch b.createType {
+	case constx.TableCreateTypeJSONAsString, constx.TableCreateTypeUBW:
+		return "JSONAsString", nil
+	case constx.TableCreateTypeJSONEachRow, constx.TableCreateTypeCV:
+		if b.columns == "" {
+			return "", errors.New("clickhouse reader columns are required by JSONEachRow")
+		}
+		return "JSONEachRow", nil
+	}
+	return "", errors.New("clickhouse reader type not supported")
+}
+
+// streamColumns returns the columns of the stream table.
+func (b *base) streamColumns() string {
+	if b.columns == "" || b.createType == constx.TableCreateTypeJSONAsString || b.createType == constx.TableCreateTypeUBW {
+		return "(\n  _log String\n)"
+	}
+	return strings.TrimSuffix(b.columns, "\n")
+}
+
+// create creates the stream table with the engine.
+func (b *base) create(engine string) (tables []string, sqls []string, err error) {
+	tables = []string{b.table}
+	sqls = []string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s\n%s\n%s;", b.name("_stream"), b.streamColumns(), engine)}
+	err = common.Exec(b.conn, sqls)
+	return
+}
+
+func (b *base) Delete() error {
+	return common.Exec(b.conn, []string{fmt.Sprintf("DROP TABLE IF EXISTS %s", b.name("_stream"))})
+}
+
+// Detach stops reading, the settings are kept by the detached table.
+func (b *base) Detach() error {
+	return common.Exec(b.conn, []string{fmt.Sprintf("DETACH TABLE IF EXISTS %s", b.name("_stream"))})
+}
+
+func (b *base) Attach() error {
+	return common.Exec(b.conn, []string{fmt.Sprintf("ATTACH TABLE %s", b.name("_stream"))})
+}
+
+// quote escapes the value of a string literal.
+func quote(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
+
+// Reader reads Kafka.
+type Reader struct {
+	base
 
 	brokers                 string
 	topics                  string
@@ -33,13 +123,7 @@ type Reader struct {
 
 func NewReader(req i.ReaderParams) *Reader {
 	return &Reader{
-		createType:              req.CreateType,
-		isShard:                 req.IsShard,
-		isReplica:               req.IsReplica,
-		cluster:                 req.Cluster,
-		database:                req.Database,
-		table:                   req.Table,
-		conn:                    req.Conn,
+		base:                    newBase(req),
 		brokers:                 req.Brokers,
 		topics:                  req.Topics,
 		groupName:               req.GroupName,
@@ -52,12 +136,29 @@ func (ch *Reader) Description() string {
 	return "reader_clickhouse"
 }
 
+func (ch *Reader) Validate() error {
+	if ch.brokers == "" || ch.topics == "" {
+		return errors.New("kafka brokers and topics are required")
+	}
+	if ch.kafkaNumConsumers < 0 || ch.kafkaSkipBrokenMessages < 0 {
+		return errors.New("kafka consumers and skipped broken messages must not be negative")
+	}
+	return nil
+}
+
 func (ch *Reader) Create() (tables []string, sqls []string, err error) {
 	tables = make([]string, 0)
 	sqls = make([]string, 0)
+	if err = ch.Validate(); err != nil {
+		return
+	}
 	switch ch.createType {
 	case constx.TableCreateTypeJSONEachRow:
-		// todo nothing, wait for implementation
+		if ch.columns == "" {
+			// created by the stream builder
+			return
+		}
+		return ch.create(ch.engine("JSONEachRow"))
 	case constx.TableCreateTypeJSONAsString:
 		tables, sqls = ch.createJSONAsString()
 	default:
@@ -67,11 +168,24 @@ func (ch *Reader) Create() (tables []string, sqls []string, err error) {
 	return
 }
 
-func (ch *Reader) createJSONAsString() (tables []string, sqls []string) {
-	readerName := fmt.Sprintf("`%s`.`%s_stream`", ch.database, ch.table)
-	if ch.isReplica || ch.isShard {
-		readerName = fmt.Sprintf("`%s`.`%s_local_stream` on cluster '%s'", ch.database, ch.table, ch.cluster)
+func (ch *Reader) engine(format string) string {
+	consumers, group := ch.kafkaNumConsumers, ch.groupName
+	if consumers == 0 {
+		consumers = 1
+	}
+	if group == "" {
+		group = ch.group()
 	}
+	return fmt.Sprintf(`ENGINE = Kafka SETTINGS kafka_broker_list = '%s',
+kafka_topic_list = '%s',
+kafka_group_name = '%s',
+kafka_format = '%s',
+kafka_num_consumers = %d,
+kafka_skip_broken_messages = %d`, quote(ch.brokers), quote(ch.topics), quote(group), format, consumers, ch.kafkaSkipBrokenMessages)
+}
+
+func (ch *Reader) createJSONAsString() (tables []string, sqls []string) {
+	readerName := ch.name("_stream")
 
 	tables = make([]string, 0)
 	tables = append(tables, ch.table)
@@ -90,18 +204,3 @@ kafka_skip_broken_messages = %d;`, readerName, ch.brokers, ch.topics, fmt.Sprint
 
 	return
 }
-
-func (ch *Reader) Delete() error {
-	// TODO implement me
-	panic("implement me")
-}
-
-func (ch *Reader) Detach() error {
-	// TODO implement me
-	panic("implement me")
-}
-
-func (ch *Reader) Attach() error {
-	// TODO implement me
-	panic("implement me")
-}
@@ -0,0 +1,243 @@
+package clickhouse
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/core/common"
+	"github.com/clickvisual/clickvisual/api/core/i"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var _ i.Reader = (*ImportReader)(nil)
+
+// ImportReader imports the archived files of S3, URLs or the user files of the server periodically.
+// The stream table is a Null table whose materialized view writes the inserted rows,
+// the imported files are recorded in the _import_files table so that every file is imported once.
+type ImportReader struct {
+	base
+	settings *db.ReaderImport
+	urlHosts []string
+}
+
+func NewImportReader(req i.ReaderParams) *ImportReader {
+	return &ImportReader{base: newBase(req), settings: req.Settings.Import, urlHosts: req.ImportURLHosts}
+}
+
+func (ch *ImportReader) Description() string {
+	return "reader_clickhouse_import"
+}
+
+func (ch *ImportReader) Validate() error {
+	s := ch.settings
+	if s == nil || s.Path == "" {
+		return errors.New("import path is required")
+	}
+	switch s.Source {
+	case db.ReaderImportSourceS3:
+		if err := validateS3(s.Path, s.AccessKeyID, s.SecretAccessKey); err != nil {
+			return err
+		}
+	case db.ReaderImportSourceURL:
+		if err := validateURLHost(s.Path, ch.urlHosts); err != nil {
+			return err
+		}
+		if s.AccessKeyID != "" || s.SecretAccessKey != "" {
+			return errors.New("import credentials are only supported by s3")
+		}
+	case db.ReaderImportSourceFile:
+		for _, segment := range strings.Split(s.Path, "/") {
+			if segment == ".." {
+				return errors.New("import file path must be in the user files of the server")
+			}
+		}
+		if s.AccessKeyID != "" || s.SecretAccessKey != "" {
+			return errors.New("import credentials are only supported by s3")
+		}
+	default:
+		return errors.Errorf("import source %s is not supported", s.Source)
+	}
+	if s.Interval != 0 && s.Interval < db.ReaderImportMinInterval {
+		return errors.Errorf("import interval must be at least %d seconds", db.ReaderImportMinInterval)
+	}
+	return nil
+}
+
+func (ch *ImportReader) Create() (tables []string, sqls []string, err error) {
+	if err = ch.Validate(); err != nil {
+		return
+	}
+	if _, err = ch.format(); err != nil {
+		return
+	}
+	// the files table is created first, the stream table marks the reader as ready
+	if err = common.Exec(ch.conn, []string{fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+  path String,
+  ctime DateTime DEFAULT now()
+)
+ENGINE = MergeTree
+ORDER BY path;`, ch.name(db.SuffixImportFiles))}); err != nil {
+		return
+	}
+	return ch.create("ENGINE = Null")
+}
+
+func (ch *ImportReader) Delete() error {
+	if err := ch.base.Delete(); err != nil {
+		return err
+	}
+	return ch.DeleteFiles()
+}
+
+func (ch *ImportReader) DeleteFiles() error {
+	return common.Exec(ch.conn, []string{fmt.Sprintf("DROP TABLE IF EXISTS %s", ch.name(db.SuffixImportFiles))})
+}
+
+// Import inserts the files which are not imported yet into the stream table, at most limit files,
+// and returns them. Nothing is imported while the stream table is detached.
+func (ch *ImportReader) Import(limit int) (files []string, err error) {
+	if err = ch.Validate(); err != nil {
+		return
+	}
+	format, err := ch.format()
+	if err != nil {
+		return
+	}
+	structure, err := ch.structure()
+	if err != nil || structure == "" {
+		return
+	}
+	// the One format lists the files without reading them
+	rows, err := ch.conn.Query(fmt.Sprintf("SELECT DISTINCT _path FROM %s WHERE _path NOT IN (SELECT path FROM %s) ORDER BY _path LIMIT %d",
+		ch.source("One", ""), ch.localName(db.SuffixImportFiles), limit))
+	if err != nil {
+		return nil, errors.Wrap(err, "list import files")
+	}
+	paths := make([]string, 0)
+	for rows.Next() {
+		var path string
+		if err = rows.Scan(&path); err != nil {
+			_ = rows.Close()
+			return
+		}
+		paths = append(paths, path)
+	}
+	if err = rows.Close(); err != nil {
+		return
+	}
+	dedup := ch.hasSetting("insert_deduplication_token")
+	files = make([]string, 0, len(paths))
+	for _, path := range paths {
+		// the file is recorded after its rows, a file whose record failed is imported again and its rows are
+		// dropped by the tables which deduplicate the inserts, as the blocks of the file get the same tokens
+		ctx := context.Background()
+		if dedup {
+			ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+				"insert_deduplication_token":                         importToken(path),
+				"deduplicate_blocks_in_dependent_materialized_views": 1,
+				"max_threads":        1,
+				"max_insert_threads": 1,
+			}))
+		}
+		insert := fmt.Sprintf("INSERT INTO %s SELECT * FROM %s WHERE _path = '%s'", ch.localName("_stream"), ch.source(format, structure), quote(path))
+		if _, err = ch.conn.ExecContext(ctx, insert); err != nil {
+			return files, errors.Wrapf(err, "error sql is: %s", insert)
+		}
+		if err = common.Exec(ch.conn, []string{
+			fmt.Sprintf("INSERT INTO %s (path) VALUES ('%s')", ch.localName(db.SuffixImportFiles), quote(path)),
+		}); err != nil {
+			return
+		}
+		files = append(files, path)
+	}
+	return
+}
+
+// hasSetting tells whether the server knows the setting, insert_deduplication_token comes with 22.2.
+func (ch *ImportReader) hasSetting(name string) bool {
+	var count uint64
+	if err := ch.conn.QueryRow(fmt.Sprintf("SELECT count() FROM system.settings WHERE name = '%s'", quote(name))).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
+}
+
+// importToken is the insert deduplication token of the rows of the file.
+func importToken(path string) string {
+	sum := sha256.Sum256([]byte(path))
+	return "clickvisual-import-" + hex.EncodeToString(sum[:16])
+}
+
+// validateURLHost requires a http or https url whose host is one of hosts, "*.example.com" allows the
+// subdomains of example.com. The url source is refused when no host is allowed, so that the tables can not make
+// the server read its internal addresses.
+func validateURLHost(rawURL string, hosts []string) error {
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		return errors.New("import url must be a http or https url")
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return errors.Wrap(err, "import url")
+	}
+	host := strings.ToLower(u.Hostname())
+	// the globs of the host would let the server read hosts which are not listed
+	if u.User != nil || host == "" || strings.ContainsAny(host, "{}*?|,\\") {
+		return errors.Errorf("import url host %s is not allowed", u.Host)
+	}
+	for _, allowed := range hosts {
+		allowed = strings.ToLower(strings.TrimSpace(allowed))
+		if allowed == host || (strings.HasPrefix(allowed, "*.") && strings.HasSuffix(host, allowed[1:])) {
+			return nil
+		}
+	}
+	return errors.Errorf("import url host %s is not in app.importURLHosts", host)
+}
+
+// structure returns the columns of the stream table as the structure of the table functions,
+// it is empty when the table does not exist.
+func (ch *ImportReader) structure() (string, error) {
+	table := ch.table + "_stream"
+	if ch.isReplica || ch.isShard {
+		table = ch.table + "_local_stream"
+	}
+	rows, err := ch.conn.Query(fmt.Sprintf("SELECT name, type FROM system.columns WHERE database = '%s' AND table = '%s' ORDER BY position",
+		quote(ch.database), quote(table)))
+	if err != nil {
+		return "", errors.Wrap(err, "list stream columns")
+	}
+	defer func() { _ = rows.Close() }()
+	columns := make([]string, 0)
+	for rows.Next() {
+		var name, typ string
+		if err = rows.Scan(&name, &typ); err != nil {
+			return "", err
+		}
+		columns = append(columns, fmt.Sprintf("`%s` %s", name, typ))
+	}
+	return strings.Join(columns, ", "), rows.Err()
+}
+
+// source returns the table function reading the files, the structure is omitted by the One format.
+func (ch *ImportReader) source(format, structure string) string {
+	s := ch.settings
+	args := []string{quote(s.Path)}
+	if s.Source == db.ReaderImportSourceS3 && s.AccessKeyID != "" {
+		args = append(args, quote(s.AccessKeyID), quote(s.SecretAccessKey))
+	}
+	args = append(args, format)
+	if structure != "" {
+		args = append(args, quote(structure))
+		if s.Compression != "" {
+			args = append(args, quote(s.Compression))
+		}
+	}
+	return fmt.Sprintf("%s('%s')", s.Source, strings.Join(args, "', '"))
+}
@@ -0,0 +1,71 @@
+package clickhouse
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/core/i"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var _ i.Reader = (*NATSReader)(nil)
+
+// NATSReader subscribes the subjects of NATS in a queue group, which is named after the table by default.
+type NATSReader struct {
+	base
+	settings *db.ReaderNATS
+}
+
+func NewNATSReader(req i.ReaderParams) *NATSReader {
+	return &NATSReader{base: newBase(req), settings: req.Settings.NATS}
+}
+
+func (ch *NATSReader) Description() string {
+	return "reader_clickhouse_nats"
+}
+
+func (ch *NATSReader) Validate() error {
+	s := ch.settings
+	if s == nil || s.URL == "" || s.Subjects == "" {
+		return errors.New("nats url and subjects are required")
+	}
+	if s.Token != "" && s.Username != "" {
+		return errors.New("nats authenticates with a token or a username, not both")
+	}
+	if s.NumConsumers < 0 || s.SkipBrokenMessages < 0 {
+		return errors.New("nats consumers and skipped broken messages must not be negative")
+	}
+	return nil
+}
+
+func (ch *NATSReader) Create() (tables []string, sqls []string, err error) {
+	if err = ch.Validate(); err != nil {
+		return
+	}
+	format, err := ch.format()
+	if err != nil {
+		return
+	}
+	s := ch.settings
+	group, consumers := s.QueueGroup, s.NumConsumers
+	if group == "" {
+		group = ch.group()
+	}
+	if consumers == 0 {
+		consumers = 1
+	}
+	engine := fmt.Sprintf(`ENGINE = NATS SETTINGS nats_url = '%s',
+nats_subjects = '%s',
+nats_queue_group = '%s',
+nats_format = '%s',
+nats_num_consumers = %d,
+nats_skip_broken_messages = %d`, quote(s.URL), quote(s.Subjects), quote(group), format, consumers, s.SkipBrokenMessages)
+	if s.Username != "" {
+		engine += fmt.Sprintf(",\nnats_username = '%s',\nnats_password = '%s'", quote(s.Username), quote(s.Password))
+	}
+	if s.Token != "" {
+		engine += fmt.Sprintf(",\nnats_token = '%s'", quote(s.Token))
+	}
+	return ch.create(engine)
+}
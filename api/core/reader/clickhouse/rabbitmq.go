@@ -0,0 +1,77 @@
+package clickhouse
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/core/i"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var _ i.Reader = (*RabbitMQReader)(nil)
+
+var rabbitMQExchangeTypes = map[string]bool{"": true, "direct": true, "fanout": true, "topic": true, "headers": true, "consistent_hash": true}
+
+// RabbitMQReader reads the queues bound to a RabbitMQ exchange, the queues are named after the table.
+type RabbitMQReader struct {
+	base
+	settings *db.ReaderRabbitMQ
+}
+
+func NewRabbitMQReader(req i.ReaderParams) *RabbitMQReader {
+	return &RabbitMQReader{base: newBase(req), settings: req.Settings.RabbitMQ}
+}
+
+func (ch *RabbitMQReader) Description() string {
+	return "reader_clickhouse_rabbitmq"
+}
+
+func (ch *RabbitMQReader) Validate() error {
+	s := ch.settings
+	if s == nil || s.HostPort == "" || s.ExchangeName == "" {
+		return errors.New("rabbitmq host port and exchange name are required")
+	}
+	if !rabbitMQExchangeTypes[s.ExchangeType] {
+		return errors.Errorf("rabbitmq exchange type %s is not supported", s.ExchangeType)
+	}
+	if s.NumConsumers < 0 || s.SkipBrokenMessages < 0 {
+		return errors.New("rabbitmq consumers and skipped broken messages must not be negative")
+	}
+	return nil
+}
+
+func (ch *RabbitMQReader) Create() (tables []string, sqls []string, err error) {
+	if err = ch.Validate(); err != nil {
+		return
+	}
+	format, err := ch.format()
+	if err != nil {
+		return
+	}
+	s := ch.settings
+	exchangeType, consumers := s.ExchangeType, s.NumConsumers
+	if exchangeType == "" {
+		exchangeType = "fanout"
+	}
+	if consumers == 0 {
+		consumers = 1
+	}
+	engine := fmt.Sprintf(`ENGINE = RabbitMQ SETTINGS rabbitmq_host_port = '%s',
+rabbitmq_exchange_name = '%s',
+rabbitmq_exchange_type = '%s',
+rabbitmq_queue_base = '%s',
+rabbitmq_format = '%s',
+rabbitmq_num_consumers = %d,
+rabbitmq_skip_broken_messages = %d`, quote(s.HostPort), quote(s.ExchangeName), exchangeType, quote(ch.group()), format, consumers, s.SkipBrokenMessages)
+	if s.RoutingKeys != "" {
+		engine += fmt.Sprintf(",\nrabbitmq_routing_key_list = '%s'", quote(s.RoutingKeys))
+	}
+	if s.Vhost != "" {
+		engine += fmt.Sprintf(",\nrabbitmq_vhost = '%s'", quote(s.Vhost))
+	}
+	if s.Username != "" {
+		engine += fmt.Sprintf(",\nrabbitmq_username = '%s',\nrabbitmq_password = '%s'", quote(s.Username), quote(s.Password))
+	}
+	return ch.create(engine)
+}
@@ -0,0 +1,90 @@
+package clickhouse
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/core/i"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var _ i.Reader = (*S3QueueReader)(nil)
+
+// S3QueueReader reads the new files of a S3 bucket, the processed files are tracked in keeper by the table.
+type S3QueueReader struct {
+	base
+	settings *db.ReaderS3Queue
+}
+
+func NewS3QueueReader(req i.ReaderParams) *S3QueueReader {
+	return &S3QueueReader{base: newBase(req), settings: req.Settings.S3Queue}
+}
+
+func (ch *S3QueueReader) Description() string {
+	return "reader_clickhouse_s3queue"
+}
+
+func (ch *S3QueueReader) Validate() error {
+	s := ch.settings
+	if s == nil {
+		return errors.New("s3queue path is required")
+	}
+	if err := validateS3(s.Path, s.AccessKeyID, s.SecretAccessKey); err != nil {
+		return err
+	}
+	if s.Mode != "" && s.Mode != "ordered" && s.Mode != "unordered" {
+		return errors.Errorf("s3queue mode %s is not supported", s.Mode)
+	}
+	if s.ProcessingThreads < 0 {
+		return errors.New("s3queue processing threads must not be negative")
+	}
+	return nil
+}
+
+func (ch *S3QueueReader) Create() (tables []string, sqls []string, err error) {
+	if err = ch.Validate(); err != nil {
+		return
+	}
+	format, err := ch.format()
+	if err != nil {
+		return
+	}
+	s := ch.settings
+	mode := s.Mode
+	if mode == "" {
+		mode = "unordered"
+	}
+	engine := fmt.Sprintf(`ENGINE = S3Queue(%s)
+SETTINGS mode = '%s',
+keeper_path = '/clickhouse/clickvisual/s3queue/%s'`, s3Args(s.Path, s.AccessKeyID, s.SecretAccessKey, format, s.Compression), mode, quote(ch.group()))
+	if s.ProcessingThreads > 0 {
+		engine += fmt.Sprintf(",\ns3queue_processing_threads_num = %d", s.ProcessingThreads)
+	}
+	return ch.create(engine)
+}
+
+// validateS3 checks the url and the credentials of S3.
+func validateS3(path, accessKeyID, secretAccessKey string) error {
+	if !strings.HasPrefix(path, "http://") && !strings.HasPrefix(path, "https://") {
+		return errors.New("s3 path must be a http or https url")
+	}
+	if (accessKeyID == "") != (secretAccessKey == "") {
+		return errors.New("s3 access key id and secret access key must be set together")
+	}
+	return nil
+}
+
+// s3Args returns the arguments of the S3 engines and table functions: url, credentials, format and compression.
+func s3Args(path, accessKeyID, secretAccessKey, format, compression string) string {
+	args := []string{quote(path)}
+	if accessKeyID != "" {
+		args = append(args, quote(accessKeyID), quote(secretAccessKey))
+	}
+	args = append(args, format)
+	if compression != "" {
+		args = append(args, quote(compression))
+	}
+	return "'" + strings.Join(args, "', '") + "'"
+}
@@ -1,15 +1,29 @@
 package reader
 
 import (
+	"github.com/pkg/errors"
+
 	"github.com/clickvisual/clickvisual/api/core/i"
 	"github.com/clickvisual/clickvisual/api/core/reader/clickhouse"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
 )
 
-func New(ds string, params i.ReaderParams) i.Reader {
+func New(ds string, params i.ReaderParams) (i.Reader, error) {
 	switch ds {
 	case db.DatasourceClickHouse:
-		return clickhouse.NewReader(params)
+		switch params.ReaderType {
+		case "", db.ReaderTypeKafka:
+			return clickhouse.NewReader(params), nil
+		case db.ReaderTypeRabbitMQ:
+			return clickhouse.NewRabbitMQReader(params), nil
+		case db.ReaderTypeNATS:
+			return clickhouse.NewNATSReader(params), nil
+		case db.ReaderTypeS3Queue:
+			return clickhouse.NewS3QueueReader(params), nil
+		case db.ReaderTypeImport:
+			return clickhouse.NewImportReader(params), nil
+		}
+		return nil, errors.Errorf("reader type %s is not supported", params.ReaderType)
 	}
-	return nil
+	return nil, errors.Errorf("datasource %s has no reader", ds)
 }
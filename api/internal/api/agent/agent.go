@@ -36,6 +36,14 @@ type ChartsSearchRequest struct {
 	Interval       int64    `json:"interval,string" form:"interval"`
 }
 
+type ContextRequest struct {
+	Path   string `json:"path" form:"path" binding:"required"` // file of the log line
+	Offset int64  `json:"offset,string" form:"offset"`         // byte offset of the log line
+	Sign   string `json:"sign" form:"sign"`                    // _sign_ of the log line in the search result
+	Before int    `json:"before,string" form:"before"`
+	After  int    `json:"after,string" form:"after"`
+}
+
 func (a *Agent) Search(c *core.Context) {
 	postReq := dto.SearchRequest{}
 	err := c.Bind(&postReq)
@@ -152,3 +160,28 @@ func (a *Agent) Charts(c *core.Context) {
 	}
 	c.JSONOK(resp)
 }
+
+// Context returns the lines around a line of a search result, as grep -C.
+func (a *Agent) Context(c *core.Context) {
+	postReq := ContextRequest{}
+	err := c.Bind(&postReq)
+	if err != nil {
+		elog.Error("agent[node] can not bind request", l.E(err), l.A("request", c.Request))
+		c.JSONE(1, "can not bind request", err)
+		return
+	}
+	resp, err := search.Context(search.ContextRequest{
+		Path:   postReq.Path,
+		Offset: postReq.Offset,
+		Sign:   postReq.Sign,
+		Before: postReq.Before,
+		After:  postReq.After,
+	})
+	if err != nil {
+		elog.Error("agent[node] context error", l.E(err))
+		c.JSONE(1, "context error: "+err.Error(), nil)
+		return
+	}
+	resp.K8sClientType = a.client.ClientType
+	c.JSONOK(resp)
+}
@@ -2,10 +2,8 @@ package alarm
 
 import (
 	"strconv"
-	"strings"
 
 	"github.com/ego-component/egorm"
-	"github.com/google/uuid"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/spf13/cast"
 
@@ -16,7 +14,6 @@ import (
 	view2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 	"github.com/clickvisual/clickvisual/api/internal/service"
 	"github.com/clickvisual/clickvisual/api/internal/service/event"
-	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
 	"github.com/clickvisual/clickvisual/api/internal/service/permission"
 	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
 )
@@ -30,6 +27,10 @@ func Create(c *core.Context) {
 		c.JSONE(1, "invalid parameter", err)
 		return
 	}
+	if err := db2.ValidGroupBy(req.GroupBy); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
 	for _, f := range req.Filters {
 		tableInfo, err := db2.TableInfo(invoker.Db, f.Tid)
 		if err != nil {
@@ -49,37 +50,8 @@ func Create(c *core.Context) {
 			return
 		}
 	}
-	tx := invoker.Db.Begin()
-	tableIds := db2.Ints{}
-	for _, f := range req.Filters {
-		tableIds = append(tableIds, f.Tid)
-	}
-	obj := &db2.Alarm{
-		Uuid:             uuid.NewString(),
-		Name:             req.Name,
-		Desc:             req.Desc,
-		Interval:         req.Interval,
-		Unit:             req.Unit,
-		Tags:             req.Tags,
-		NoDataOp:         req.NoDataOp,
-		ChannelIds:       db2.Ints(req.ChannelIds),
-		Uid:              c.Uid(),
-		Level:            req.Level,
-		TableIds:         tableIds,
-		DutyOfficers:     db2.Ints(req.DutyOfficers),
-		IsDisableResolve: req.IsDisableResolve,
-	}
-	if err := db2.AlarmCreate(tx, obj); err != nil {
-		tx.Rollback()
-		c.JSONE(1, "alarm create failed 01", err)
-		return
-	}
-	if err := tx.Commit().Error; err != nil {
-		tx.Rollback()
-		c.JSONE(1, "alarm create failed 03", err)
-		return
-	}
-	if err := service.Alert.CreateOrUpdate(obj, req); err != nil {
+	obj, err := service.Alert.Create(c.Uid(), req)
+	if err != nil {
 		c.JSONE(1, err.Error(), err)
 		return
 	}
@@ -102,6 +74,10 @@ func Update(c *core.Context) {
 		c.JSONE(1, "invalid parameter", err)
 		return
 	}
+	if err = db2.ValidGroupBy(req.GroupBy); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
 	alarmInfo, relatedList, errAlarmInfo := db2.GetAlarmTableInstanceInfo(id)
 	if errAlarmInfo != nil {
 		c.JSONE(1, "alarm info not found", errAlarmInfo)
@@ -125,67 +101,7 @@ func Update(c *core.Context) {
 	case db2.AlarmStatusNormal:
 		err = service.Alert.OpenOperator(id)
 	case db2.AlarmStatusClose:
-		clusterRuleGroups := map[string]db2.ClusterRuleGroup{}
-		// 删除告警规则
-		for _, ri := range relatedList {
-			instance := ri.Instance
-			if instance.RuleStoreType == db2.RuleStoreTypeK8sOperator {
-				clusterRuleGroup := db2.ClusterRuleGroup{}
-				if tmp, ok := clusterRuleGroups[instance.GetRuleStoreKey()]; ok {
-					clusterRuleGroup = tmp
-				} else {
-					clusterRuleGroup.ClusterId = instance.K8sClusterId
-					clusterRuleGroup.Instance = instance
-					clusterRuleGroup.GroupName = alarmInfo.GetGroupName(instance.ID)
-				}
-				clusterRuleGroups[instance.GetRuleStoreKey()] = clusterRuleGroup
-			} else if instance.RuleStoreType == db2.RuleStoreTypeFile || instance.RuleStoreType == db2.RuleStoreTypeK8sConfigMap {
-				if err = service.Alert.DeletePrometheusRule(&ri.Instance, &alarmInfo); err != nil {
-					c.JSONE(core.CodeErr, "prometheus rule delete failed:"+err.Error(), err)
-					return
-				}
-			}
-		}
-		if len(clusterRuleGroups) > 0 {
-			_ = service.Alert.PrometheusRuleBatchRemove(clusterRuleGroups)
-		}
-		// 删除 clickhouse 物化视图
-		for _, ri := range relatedList {
-			op, errInstanceManager := service.InstanceManager.Load(ri.Instance.ID)
-			if errInstanceManager != nil {
-				c.JSONE(core.CodeErr, errInstanceManager.Error(), errInstanceManager)
-				return
-			}
-			if len(alarmInfo.ViewDDLs) > 0 {
-				for iidTable := range alarmInfo.ViewDDLs {
-					table := iidTable
-					iidTableArr := strings.Split(iidTable, "|")
-					if len(iidTableArr) == 2 {
-						table = iidTableArr[1]
-						iid, _ := strconv.Atoi(iidTableArr[0])
-						if iid != ri.Table.Database.Iid {
-							continue
-						}
-						op, err = service.InstanceManager.Load(iid)
-						if err != nil {
-							c.JSONE(core.CodeErr, "clickhouse load failed", err)
-							return
-						}
-					}
-					if err = op.DeleteAlertView(table, ri.Table.Database.Cluster); err != nil {
-						c.JSONE(core.CodeErr, "alert view drop", err)
-						return
-					}
-				}
-			} else {
-				if err = op.DeleteAlertView(alarmInfo.ViewTableName, ri.Table.Database.Cluster); err != nil {
-					c.JSONE(core.CodeErr, "alarm update failed when delete metrics view", err)
-					return
-				}
-			}
-		}
-		_ = db2.AlarmFilterUpdateStatus(invoker.Db, id, map[string]interface{}{"status": db2.AlarmStatusClose})
-		err = db2.AlarmUpdate(invoker.Db, id, map[string]interface{}{"status": db2.AlarmStatusClose})
+		err = service.Alert.CloseOperator(&alarmInfo, relatedList)
 	default:
 		err = service.Alert.Update(c.Uid(), id, req)
 	}
@@ -410,81 +326,7 @@ func Delete(c *core.Context) {
 			return
 		}
 	}
-	tx := invoker.Db.Begin()
-	if err = db2.AlarmDelete(tx, id); err != nil {
-		c.JSONE(1, err.Error(), err)
-		return
-	}
-	// filter
-	if err = db2.AlarmFilterDeleteBatch(tx, id); err != nil {
-		tx.Rollback()
-		c.JSONE(1, err.Error(), err)
-		return
-	}
-	// condition
-	if err = db2.AlarmConditionDeleteBatch(tx, id); err != nil {
-		tx.Rollback()
-		c.JSONE(1, err.Error(), err)
-		return
-	}
-	clusterRuleGroups := map[string]db2.ClusterRuleGroup{}
-	for _, ri := range relatedList {
-		instance := ri.Instance
-		if instance.RuleStoreType == db2.RuleStoreTypeK8sOperator {
-			clusterRuleGroup := db2.ClusterRuleGroup{}
-			if tmp, ok := clusterRuleGroups[instance.GetRuleStoreKey()]; ok {
-				clusterRuleGroup = tmp
-			} else {
-				clusterRuleGroup.ClusterId = instance.K8sClusterId
-				clusterRuleGroup.Instance = instance
-				clusterRuleGroup.GroupName = alarmInfo.GetGroupName(instance.ID)
-			}
-			clusterRuleGroups[instance.GetRuleStoreKey()] = clusterRuleGroup
-		} else if instance.RuleStoreType == db2.RuleStoreTypeFile || instance.RuleStoreType == db2.RuleStoreTypeK8sConfigMap {
-			_ = service.Alert.DeletePrometheusRule(&ri.Instance, &alarmInfo)
-		}
-		var op factory.Operator
-		op, err = service.InstanceManager.Load(ri.Table.Database.Iid)
-		if err != nil {
-			tx.Rollback()
-			c.JSONE(core.CodeErr, err.Error(), err)
-			return
-		}
-		if len(alarmInfo.ViewDDLs) > 0 {
-			for iidTable := range alarmInfo.ViewDDLs {
-				table := iidTable
-				iidTableArr := strings.Split(iidTable, "|")
-				if len(iidTableArr) == 2 {
-					table = iidTableArr[1]
-					iid, _ := strconv.Atoi(iidTableArr[0])
-					op, err = service.InstanceManager.Load(iid)
-					if err != nil {
-						tx.Rollback()
-						c.JSONE(core.CodeErr, err.Error(), err)
-						return
-					}
-					if iid != ri.Table.Database.Iid {
-						continue
-					}
-				}
-				if err = op.DeleteAlertView(table, ri.Table.Database.Cluster); err != nil {
-					tx.Rollback()
-					c.JSONE(core.CodeErr, err.Error(), err)
-					return
-				}
-			}
-		} else {
-			if err = op.DeleteAlertView(alarmInfo.ViewTableName, ri.Table.Database.Cluster); err != nil {
-				tx.Rollback()
-				c.JSONE(core.CodeErr, err.Error(), err)
-				return
-			}
-		}
-	}
-	if len(clusterRuleGroups) > 0 {
-		_ = service.Alert.PrometheusRuleBatchRemove(clusterRuleGroups)
-	}
-	if err = tx.Commit().Error; err != nil {
+	if err = service.Alert.Delete(&alarmInfo, relatedList); err != nil {
 		c.JSONE(core.CodeErr, err.Error(), err)
 		return
 	}
@@ -8,8 +8,11 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
 	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
 	"github.com/clickvisual/clickvisual/api/internal/service/event"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
 )
 
 // ChannelCreate
@@ -26,6 +29,10 @@ func ChannelCreate(c *core.Context) {
 		c.JSONE(1, err.Error(), err)
 		return
 	}
+	if err := pusher.ParseTemplate(req.Template); err != nil {
+		c.JSONE(1, "invalid template: "+err.Error(), err)
+		return
+	}
 	err := db2.AlarmChannelCreate(invoker.Db, &req)
 	if err != nil {
 		c.JSONE(1, "create failed: "+err.Error(), err)
@@ -53,10 +60,15 @@ func ChannelUpdate(c *core.Context) {
 		c.JSONE(1, err.Error(), err)
 		return
 	}
+	if err := pusher.ParseTemplate(req.Template); err != nil {
+		c.JSONE(1, "invalid template: "+err.Error(), err)
+		return
+	}
 	ups := make(map[string]interface{}, 0)
 	ups["name"] = req.Name
 	ups["typ"] = req.Typ
 	ups["key"] = req.Key
+	ups["template"] = req.Template
 	ups["uid"] = c.Uid()
 	if err := db2.AlarmChannelUpdate(invoker.Db, id, ups); err != nil {
 		c.JSONE(1, "update failed: "+err.Error(), err)
@@ -129,3 +141,26 @@ func ChannelSendTest(c *core.Context) {
 	elog.Info("send test success", elog.Any("req", req))
 	c.JSONOK()
 }
+
+// ChannelPreview
+// @Tags         ALARM
+// @Summary	     告警渠道消息模板预览
+func ChannelPreview(c *core.Context) {
+	var req view.ReqAlarmChannelPreview
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if req.AlarmId != 0 {
+		if err := service.CheckAlarmPermission(c.Uid(), req.AlarmId, pmsplugin.ActView); err != nil {
+			c.JSONE(1, "permission verification failed", err)
+			return
+		}
+	}
+	msg, err := service.PreviewChannelTemplate(req)
+	if err != nil {
+		c.JSONE(1, "preview error: "+err.Error(), err)
+		return
+	}
+	c.JSONOK(msg)
+}
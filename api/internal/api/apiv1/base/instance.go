@@ -97,6 +97,8 @@ func InstanceUpdate(c *core.Context) {
 	ups["name"] = req.Name
 	ups["datasource"] = req.Datasource
 	ups["desc"] = req.Desc
+	ups["query_max_concurrency"] = req.QueryMaxConcurrency
+	ups["query_max_execution_time"] = req.QueryMaxExecutionTime
 	if err = db.InstanceUpdate(invoker.Db, id, ups); err != nil {
 		c.JSONE(1, "update failed: "+err.Error(), err)
 		return
@@ -1,6 +1,9 @@
 package base
 
 import (
+	"encoding/json"
+	"fmt"
+	"net/http"
 	"sort"
 	"strconv"
 	"strings"
@@ -148,6 +151,8 @@ func TableInfo(c *core.Context) {
 		Desc:                    tableInfo.Desc,
 		ConsumerNum:             tableInfo.ConsumerNum,
 		KafkaSkipBrokenMessages: tableInfo.KafkaSkipBrokenMessages,
+		ReaderType:              tableInfo.GetReaderType(),
+		ReaderSettings:          tableInfo.ReaderSettings.Masked(),
 		Database: view.RespDatabaseItem{
 			Id:             tableInfo.Database.ID,
 			Iid:            tableInfo.Database.Iid,
@@ -171,7 +176,7 @@ func TableInfo(c *core.Context) {
 	keys := make([]string, 0)
 	data := make(map[string]string, 0)
 
-	if tableInfo.CreateType == constx.TableCreateTypeBufferNullDataPipe {
+	if isAttachedTable(tableInfo.CreateType) {
 		tableAttach := db.BaseTableAttach{}
 		tableAttach.Tid = tableInfo.ID
 		if err = tableAttach.Info(invoker.Db); err != nil {
@@ -187,7 +192,7 @@ func TableInfo(c *core.Context) {
 	} else {
 		keys = append(keys, "data_sql", "stream_sql", "view_sql")
 		data["data_sql"] = tableInfo.SqlData
-		data["stream_sql"] = tableInfo.SqlStream
+		data["stream_sql"] = tableInfo.ReaderSettings.MaskSQL(tableInfo.SqlStream)
 		data["view_sql"] = tableInfo.SqlView
 		if tableInfo.SqlDistributed != "" {
 			keys = append(keys, "distribute_sql")
@@ -228,6 +233,7 @@ func TableList(c *core.Context) {
 		c.JSONE(core.CodeErr, "read list failed: "+err.Error(), nil)
 		return
 	}
+	healths := service.TableHealthStatus()
 	res := make([]view.RespTableSimple, 0)
 	for _, row := range tableList {
 		if !service.TableViewIsPermission(c.Uid(), row.Database.Iid, row.ID) {
@@ -238,6 +244,7 @@ func TableList(c *core.Context) {
 			TableName:  row.Name,
 			CreateType: row.CreateType,
 			Desc:       row.Desc,
+			Health:     healths[row.ID],
 		})
 	}
 	c.JSONOK(res)
@@ -303,7 +310,7 @@ func TableDelete(c *core.Context) {
 		c.JSONE(core.CodeErr, "delete failed 06", err)
 		return
 	}
-	if tableInfo.CreateType != constx.TableCreateTypeExist && tableInfo.CreateType != constx.TableCreateTypeBufferNullDataPipe {
+	if tableInfo.CreateType != constx.TableCreateTypeExist && !isAttachedTable(tableInfo.CreateType) {
 		table := tableInfo.Name
 		iid := tableInfo.Database.Iid
 		database := tableInfo.Database.Name
@@ -318,7 +325,7 @@ func TableDelete(c *core.Context) {
 			return
 		}
 	}
-	if tableInfo.CreateType == constx.TableCreateTypeBufferNullDataPipe {
+	if isAttachedTable(tableInfo.CreateType) {
 		op, errLoad := service.InstanceManager.Load(tableInfo.Database.Iid)
 		if errLoad != nil {
 			c.JSONE(core.CodeErr, errLoad.Error(), errLoad)
@@ -394,6 +401,10 @@ func TableLogs(c *core.Context) {
 		c.JSONE(1, "permission verification failed", err)
 		return
 	}
+	if err = service.CheckQueryMode(c.Uid(), param.QueryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
 	op, err := service.InstanceManager.Load(tableInfo.Database.Iid)
 	if err != nil {
 		c.JSONE(core.CodeErr, "clickhouse i/o timeout", err)
@@ -401,7 +412,7 @@ func TableLogs(c *core.Context) {
 	}
 	firstTry, err := op.Prepare(param, &tableInfo, false)
 	if err != nil {
-		c.JSONE(core.CodeErr, "param prepare failed", err)
+		c.JSONE(core.CodeErr, "param prepare failed: "+err.Error(), err)
 		return
 	}
 	if firstTry.Query == "" {
@@ -433,10 +444,304 @@ func TableLogs(c *core.Context) {
 		}
 	}
 	res.Cost = time.Since(st).Milliseconds()
+	rows := uint64(len(res.Logs))
+	if param.IsQueryCount == 1 {
+		rows = res.Count
+	}
+	history := param
+	history.ST, history.ET = firstTry.ST, firstTry.ET
+	service.RecordQueryHistory(c.Uid(), history, res.Cost, rows)
 	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsQuery, map[string]interface{}{"param": param})
 	c.JSONOK(res)
 }
 
+// TableLogsExport
+// @Tags         LOGSTORE
+// @Summary	 	 日志导出，按 CSV、NDJSON 或 Parquet 流式返回搜索结果
+func TableLogsExport(c *core.Context) {
+	var param view.ReqQueryExport
+	err := c.Bind(&param)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	id := cast.ToInt(c.Param("id"))
+	if id == 0 {
+		c.JSONE(core.CodeErr, "params error", nil)
+		return
+	}
+	tableInfo, _ := db.TableInfo(invoker.Db, id)
+	param.TimeField = db.TimeFieldSecond
+	if tableInfo.CreateType == constx.TableCreateTypeExist && tableInfo.TimeField != "" {
+		param.TimeField = tableInfo.TimeField
+	}
+	param.Tid = tableInfo.ID
+	param.Table = tableInfo.Name
+	param.TimeFieldType = tableInfo.TimeFieldType
+	param.Database = tableInfo.Database.Name
+	if param.Database == "" || param.Table == "" {
+		c.JSONE(core.CodeErr, "db and table are required fields", nil)
+		return
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActView},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(tableInfo.ID),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	if err = service.CheckQueryMode(c.Uid(), param.QueryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
+	op, err := service.InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		c.JSONE(core.CodeErr, "clickhouse i/o timeout", err)
+		return
+	}
+	query, err := op.Prepare(param.ReqQuery, &tableInfo, false)
+	if err != nil {
+		c.JSONE(core.CodeErr, "param prepare failed: "+err.Error(), err)
+		return
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", service.ExportContentTypes[param.Format])
+	header.Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%d.%s"`, tableInfo.Name, time.Now().Unix(), param.Format))
+	rows, err := service.LogsExport(c.Request.Context(), c.Writer, op, tableInfo, query, param.Format, param.Limit)
+	if err != nil {
+		if !c.Writer.Written() {
+			header.Del("Content-Type")
+			header.Del("Content-Disposition")
+			c.JSONE(core.CodeErr, err.Error(), nil)
+			return
+		}
+		// the response is cut off, the client sees an incomplete file
+		elog.Error("TableLogsExport", elog.FieldErr(err), elog.Int("rows", rows))
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsExport, map[string]interface{}{"param": param, "rows": rows})
+}
+
+// TableLogsContext
+// @Tags         LOGSTORE
+// @Summary	 	 日志上下文，返回同一来源（pod、容器或文件）中某条日志前后的日志
+func TableLogsContext(c *core.Context) {
+	var param view.ReqLogsContext
+	err := c.Bind(&param)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	param.Fields = c.QueryMap("fields")
+	id := cast.ToInt(c.Param("id"))
+	if id == 0 {
+		c.JSONE(core.CodeErr, "params error", nil)
+		return
+	}
+	tableInfo, err := db.TableInfo(invoker.Db, id)
+	if err != nil {
+		c.JSONE(core.CodeErr, "table not found", err)
+		return
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActView},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(tableInfo.ID),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	res, err := service.LogsContext(tableInfo, param)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsQuery, map[string]interface{}{"context": param})
+	c.JSONOK(res)
+}
+
+// TableLogsPatterns
+// @Tags         LOGSTORE
+// @Summary	 	 日志模式聚类，将采样的日志聚合为模板，返回数量、趋势和样例
+func TableLogsPatterns(c *core.Context) {
+	var param view.ReqQuery
+	tableInfo, ok := bindLogsPatterns(c, &param)
+	if !ok {
+		return
+	}
+	res, err := service.LogsPatterns(tableInfo, param)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsQuery, map[string]interface{}{"patterns": param})
+	c.JSONOK(res)
+}
+
+// TableLogsPatternsDiff
+// @Tags         LOGSTORE
+// @Summary	 	 日志模式对比，比较当前时间范围与上一个同长度时间范围的日志模式
+func TableLogsPatternsDiff(c *core.Context) {
+	var param view.ReqQuery
+	tableInfo, ok := bindLogsPatterns(c, &param)
+	if !ok {
+		return
+	}
+	res, err := service.LogsPatternsDiff(tableInfo, param)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsQuery, map[string]interface{}{"patternsDiff": param})
+	c.JSONOK(res)
+}
+
+// TableLogsStats
+// @Tags         LOGSTORE
+// @Summary	 	 字段统计，返回多个字段的 top-k、去重数、空值比例，数值字段的分位数与直方图
+func TableLogsStats(c *core.Context) {
+	var req view.ReqFieldStats
+	err := c.Bind(&req)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	tableInfo, ok := checkLogsQuery(c, req.QueryMode)
+	if !ok {
+		return
+	}
+	res, err := service.FieldStats(tableInfo, req)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsQuery, map[string]interface{}{"stats": req})
+	c.JSONOK(res)
+}
+
+// bindLogsPatterns binds the log query of the table and checks the permissions of the user.
+func bindLogsPatterns(c *core.Context, param *view.ReqQuery) (db.BaseTable, bool) {
+	err := c.Bind(param)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return db.BaseTable{}, false
+	}
+	return checkLogsQuery(c, param.QueryMode)
+}
+
+// checkLogsQuery loads the table of the path and checks the permissions of the user to query its logs.
+func checkLogsQuery(c *core.Context, queryMode int) (db.BaseTable, bool) {
+	id := cast.ToInt(c.Param("id"))
+	if id == 0 {
+		c.JSONE(core.CodeErr, "params error", nil)
+		return db.BaseTable{}, false
+	}
+	tableInfo, err := db.TableInfo(invoker.Db, id)
+	if err != nil {
+		c.JSONE(core.CodeErr, "table not found", err)
+		return tableInfo, false
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActView},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(tableInfo.ID),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return tableInfo, false
+	}
+	if err = service.CheckQueryMode(c.Uid(), queryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return tableInfo, false
+	}
+	return tableInfo, true
+}
+
+// TableLogsTail
+// @Tags         LOGSTORE
+// @Summary	 	 日志实时跟随，通过 Server-Sent Events 推送新的日志
+func TableLogsTail(c *core.Context) {
+	var param view.ReqQuery
+	err := c.Bind(&param)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	id := cast.ToInt(c.Param("id"))
+	if id == 0 {
+		c.JSONE(core.CodeErr, "params error", nil)
+		return
+	}
+	tableInfo, err := db.TableInfo(invoker.Db, id)
+	if err != nil {
+		c.JSONE(core.CodeErr, "table not found", err)
+		return
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActView},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(tableInfo.ID),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	if err = service.CheckQueryMode(c.Uid(), param.QueryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
+	tail, err := service.LogsTails.Open(tableInfo, param)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	defer tail.Close()
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesLogsTail, map[string]interface{}{"param": param})
+	header := c.Writer.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("X-Accel-Buffering", "no") // the events must not be buffered by nginx
+	c.Writer.WriteHeader(http.StatusOK)
+	c.Writer.Flush()
+	err = tail.Follow(c.Request.Context(), func(batch *view.RespLogsTail) error {
+		if batch == nil {
+			return writeEvent(c, "ping", struct{}{})
+		}
+		return writeEvent(c, "logs", batch)
+	})
+	if err != nil && c.Request.Context().Err() == nil {
+		elog.Error("TableLogsTail", elog.FieldErr(err), elog.Int("tid", tableInfo.ID))
+		_ = writeEvent(c, "error", core.Res{Code: core.CodeErr, Msg: err.Error()})
+	}
+}
+
+// writeEvent writes a server-sent event with the data in JSON.
+func writeEvent(c *core.Context, name string, data interface{}) error {
+	b, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", name, b); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
 // QueryComplete
 // @Tags         LOGSTORE
 // @Summary      执行SQL请求
@@ -462,6 +767,10 @@ func QueryComplete(c *core.Context) {
 		c.JSONE(1, "", err)
 		return
 	}
+	if err = service.CheckRawSQL(c.Uid(), iid, 0); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
 	op, err := service.InstanceManager.Load(iid)
 	if err != nil {
 		c.JSONE(core.CodeErr, "", err)
@@ -517,6 +826,10 @@ func TableCharts(c *core.Context) {
 		c.JSONE(1, "checkNormalPermission", err)
 		return
 	}
+	if err = service.CheckQueryMode(c.Uid(), param.QueryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
 	op, err := service.InstanceManager.Load(tableInfo.Database.Iid)
 	if err != nil {
 		c.JSONE(core.CodeErr, "instanceManagerLoad", err)
@@ -675,6 +988,10 @@ func TableIndexes(c *core.Context) {
 		c.JSONE(1, "permission verification failed", err)
 		return
 	}
+	if err = service.CheckQueryMode(c.Uid(), param.QueryMode, tableInfo); err != nil {
+		c.JSONE(1, "raw SQL permission verification failed", err)
+		return
+	}
 	indexInfo, _ := db.IndexInfo(invoker.Db, indexId)
 	param.Field = indexInfo.GetFieldName()
 	op, err := service.InstanceManager.Load(tableInfo.Database.Iid)
@@ -965,3 +1282,12 @@ func TableDeps(c *core.Context) {
 	}
 	c.JSONOK(res)
 }
+
+// isAttachedTable tells whether the names and the sqls of the table are kept in the table attach.
+func isAttachedTable(createType int) bool {
+	switch createType {
+	case constx.TableCreateTypeBufferNullDataPipe, constx.TableCreateTypeOTLPLogs, constx.TableCreateTypeOTLPTraces:
+		return true
+	}
+	return false
+}
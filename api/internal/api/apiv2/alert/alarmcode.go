@@ -0,0 +1,67 @@
+package alert
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+)
+
+// ExportAlarm godoc
+// @Summary      Export alarms as YAML
+// @Description  Alarms are exported with their filters and conditions, channels, duty officers, escalation policies
+// @Description  and tables are referenced by name so the document can be applied to another deployment. Requires root.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req query view.ReqAlarmExport true "params"
+// @Success      200 {object} core.Res{data=string}
+// @Router       /api/v2/alert/alarms-export [get]
+func ExportAlarm(c *core.Context) {
+	var req view.ReqAlarmExport
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := checkAlarmPermission(c, 0); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	content, err := service.AlarmExport(req.AlarmIds)
+	if err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	c.JSONOK(string(content))
+}
+
+// ApplyAlarm godoc
+// @Summary      Apply alarms from YAML
+// @Description  The document is diffed against the current alarms by name. The plan is returned without changes when dryRun is set,
+// @Description  otherwise alarms are created, updated and, with prune, deleted. Requires root.
+// @Tags         ALARM
+// @Accept       json
+// @Produce      json
+// @Param        req body view.ReqAlarmApply true "params"
+// @Success      200 {object} core.Res{data=view.RespAlarmPlan}
+// @Router       /api/v2/alert/alarms-apply [post]
+func ApplyAlarm(c *core.Context) {
+	var req view.ReqAlarmApply
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := checkAlarmPermission(c, 0); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	plan, err := service.AlarmApply(c.Uid(), req.Content, req.DryRun, req.Prune)
+	if plan.Applied {
+		event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsApply, map[string]interface{}{"plan": plan})
+	}
+	if err != nil {
+		c.JSONE(1, err.Error(), plan)
+		return
+	}
+	c.JSONOK(plan)
+}
@@ -82,7 +82,8 @@ func SettingUpdate(c *core.Context) {
 		ups["config_prometheus_operator"] = req.ConfigPrometheusOperator
 	}
 
-	if req.RuleStoreType != 0 {
+	// native alarms are evaluated by clickvisual itself, there is no prometheus to check
+	if req.RuleStoreType != 0 && req.RuleStoreType != db2.RuleStoreTypeNative {
 		prometheus := strings.TrimSpace(req.PrometheusTarget)
 		if !strings.HasPrefix(prometheus, "http") {
 			prometheus = "http://" + prometheus
@@ -130,6 +131,10 @@ func SettingList(c *core.Context) {
 			IsPrometheusOK:     1,
 			IsMetricsSamplesOk: 1,
 		}
+		if instance.RuleStoreType == db2.RuleStoreTypeNative {
+			res = append(res, &row)
+			continue
+		}
 		if tmp, ok := checkHistory[instance.PrometheusTarget]; ok {
 			row.IsPrometheusOK = tmp.IsPrometheusOK
 			row.CheckPrometheusResult = tmp.CheckPrometheusResult
@@ -0,0 +1,57 @@
+package alert
+
+import (
+	"strconv"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
+)
+
+// BacktestAlarm godoc
+// @Summary      Backtest an alarm over historical logs
+// @Description  The filters of the alarm payload are evaluated every interval in [startTime, endTime) with their conditions and noDataOp,
+// @Description  the would-be firing and resolved transitions and the number of notifications are returned. Nothing is created.
+// @Description  Only filters in the normal mode are supported, the range is limited to 7 days.
+// @Tags         ALARM
+// @Accept       json
+// @Produce      json
+// @Param        req body view.ReqAlarmBacktest true "params"
+// @Success      200 {object} core.Res{data=view.RespAlarmBacktest}
+// @Router       /api/v2/alert/alarms-backtest [post]
+func BacktestAlarm(c *core.Context) {
+	var req view.ReqAlarmBacktest
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	for _, f := range req.Filters {
+		tableInfo, err := db2.TableInfo(invoker.Db, f.Tid)
+		if err != nil {
+			c.JSONE(1, "table not found", err)
+			return
+		}
+		if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+			UserId:      c.Uid(),
+			ObjectType:  pmsplugin.PrefixInstance,
+			ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+			SubResource: pmsplugin.Alarm,
+			Acts:        []string{pmsplugin.ActView},
+			DomainType:  pmsplugin.PrefixTable,
+			DomainId:    strconv.Itoa(tableInfo.ID),
+		}); err != nil {
+			c.JSONE(1, "permission verification failed", err)
+			return
+		}
+	}
+	res, err := service.AlarmBacktest(req)
+	if err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	c.JSONOK(res)
+}
@@ -0,0 +1,87 @@
+package alert
+
+import (
+	"github.com/ego-component/egorm"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+)
+
+// ListDelivery godoc
+// @Summary      Alarm delivery list
+// @Description  Every (notification, channel) pair is delivered independently, failed deliveries are retried with backoff.
+// @Description  status: 0 pending 1 success 2 retrying 3 dead. Listing without alarmId requires root.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req query db.ReqListAlarmDelivery true "params"
+// @Success      200 {object} core.Res{data=[]db.AlarmDelivery}
+// @Router       /api/v2/alert/deliveries [get]
+func ListDelivery(c *core.Context) {
+	var req db2.ReqListAlarmDelivery
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := checkAlarmPermission(c, req.AlarmId); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	conds := egorm.Conds{}
+	if req.AlarmId != 0 {
+		conds["alarm_id"] = req.AlarmId
+	}
+	if req.HistoryId != 0 {
+		conds["history_id"] = req.HistoryId
+	}
+	if req.Status != nil {
+		conds["status"] = *req.Status
+	}
+	m := db2.AlarmDelivery{}
+	total, list := m.ListPage(invoker.Db, conds, &req.ReqPage)
+	c.JSONPage(list, core.Pagination{
+		Current:  req.Current,
+		PageSize: req.PageSize,
+		Total:    total,
+	})
+}
+
+// ReplayDelivery godoc
+// @Summary      Replay alarm delivery
+// @Description  Sends a dead delivery again, it gets a fresh attempt budget and is retried if the attempt fails.
+// @Tags         ALARM
+// @Produce      json
+// @Param        delivery-id path int true "delivery id"
+// @Success      200 {object} core.Res{data=db.AlarmDelivery}
+// @Router       /api/v2/alert/deliveries/{delivery-id}/replay [post]
+func ReplayDelivery(c *core.Context) {
+	id := cast.ToInt(c.Param("delivery-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	m := db2.AlarmDelivery{}
+	m.ID = id
+	if err := m.Info(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if err := checkAlarmPermission(c, m.AlarmId); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	err := pusher.Replay(&m)
+	if err == pusher.ErrDeliveryNotReplayed {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsDeliveriesReplay, map[string]interface{}{"id": id, "alarmId": m.AlarmId})
+	if err != nil {
+		c.JSONE(1, "replay failed, the delivery will be retried: "+err.Error(), m)
+		return
+	}
+	c.JSONOK(m)
+}
@@ -0,0 +1,193 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+)
+
+// CreateEscalation godoc
+// @Summary      Create escalation policy
+// @Description  The first step is notified when an alert starts firing, each following step is notified
+// @Description  once its delay has passed and the alert is still firing and not acknowledged.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req body db.ReqCreateAlarmEscalation true "params"
+// @Success      200 {object} core.Res{data=db.AlarmEscalation}
+// @Router       /api/v2/alert/escalations [post]
+func CreateEscalation(c *core.Context) {
+	var req db2.ReqCreateAlarmEscalation
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err := checkEscalationPermission(c); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	m := db2.AlarmEscalation{
+		Uid:   c.Uid(),
+		Name:  req.Name,
+		Desc:  req.Desc,
+		Steps: req.Steps,
+	}
+	if err := m.Create(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsEscalationsCreate, map[string]interface{}{"obj": m})
+	c.JSONOK(m)
+}
+
+// UpdateEscalation godoc
+// @Summary      Update escalation policy
+// @Tags         ALARM
+// @Accept       json
+// @Produce      json
+// @Param        escalation-id path int true "escalation id"
+// @Param        req body db.ReqCreateAlarmEscalation true "params"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/escalations/{escalation-id} [patch]
+func UpdateEscalation(c *core.Context) {
+	id := cast.ToInt(c.Param("escalation-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	var req db2.ReqCreateAlarmEscalation
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err := checkEscalationPermission(c, id); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	ups := make(map[string]interface{}, 0)
+	ups["name"] = req.Name
+	ups["desc"] = req.Desc
+	ups["steps"] = db2.EscalationSteps(req.Steps)
+	m := db2.AlarmEscalation{}
+	m.ID = id
+	if err := m.Update(invoker.Db, ups); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsEscalationsUpdate, map[string]interface{}{"req": req})
+	c.JSONOK()
+}
+
+// ListEscalation godoc
+// @Summary      List escalation policies
+// @Tags         ALARM
+// @Produce      json
+// @Success      200 {object} core.Res{data=[]db.AlarmEscalation}
+// @Router       /api/v2/alert/escalations [get]
+func ListEscalation(c *core.Context) {
+	m := db2.AlarmEscalation{}
+	list, err := m.List(invoker.Db, egorm.Conds{})
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK(list)
+}
+
+// DeleteEscalation godoc
+// @Summary      Delete escalation policy
+// @Tags         ALARM
+// @Produce      json
+// @Param        escalation-id path int true "escalation id"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/escalations/{escalation-id} [delete]
+func DeleteEscalation(c *core.Context) {
+	id := cast.ToInt(c.Param("escalation-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := checkEscalationPermission(c, id); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	alarms, err := db2.AlarmList(egorm.Conds{"escalation_id": id})
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	if len(alarms) > 0 {
+		c.JSONE(1, db2.ErrEscalationInUse.Error()+": "+alarms[0].Name, db2.ErrEscalationInUse)
+		return
+	}
+	m := db2.AlarmEscalation{}
+	m.ID = id
+	if err = m.Delete(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsEscalationsDelete, map[string]interface{}{"id": id})
+	c.JSONOK()
+}
+
+// checkEscalationPermission requires alarm edit permission on every alarm which uses one of the escalation policies,
+// as the policies decide who is paged for them. New policies and the ones no alarm uses are limited to root users.
+func checkEscalationPermission(c *core.Context, escalationIds ...int) error {
+	if len(escalationIds) == 0 {
+		return permission.Manager.IsRootUser(c.Uid())
+	}
+	alarms, err := db2.AlarmList(egorm.Conds{"escalation_id": egorm.Cond{Op: "in", Val: escalationIds}})
+	if err != nil {
+		return err
+	}
+	if len(alarms) == 0 {
+		return permission.Manager.IsRootUser(c.Uid())
+	}
+	for _, alarm := range alarms {
+		if err = checkAlarmPermission(c, alarm.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AckAlarm godoc
+// @Summary      Acknowledge alarm
+// @Description  Acknowledges the firing alerts of the alarm, the escalation policy stops notifying the following steps.
+// @Tags         ALARM
+// @Produce      json
+// @Param        alarm-id path int true "alarm id"
+// @Success      200 {object} core.Res{data=int64}
+// @Router       /api/v2/alert/alarms/{alarm-id}/ack [post]
+func AckAlarm(c *core.Context) {
+	id := cast.ToInt(c.Param("alarm-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := checkAlarmPermission(c, id); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	affected, err := db2.AlarmHistoryAck(invoker.Db, id, c.Uid(), time.Now().Unix())
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsAck, map[string]interface{}{"alarmId": id, "affected": affected})
+	c.JSONOK(affected)
+}
@@ -0,0 +1,167 @@
+package alert
+
+import (
+	"github.com/ego-component/egorm"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+)
+
+// CreateOncall godoc
+// @Summary      Create on-call rotation
+// @Description  Users take turns every period seconds from handoffTime, overrides replace the user on duty in their time range.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req body db.ReqCreateAlarmOncall true "params"
+// @Success      200 {object} core.Res{data=db.AlarmOncall}
+// @Router       /api/v2/alert/oncalls [post]
+func CreateOncall(c *core.Context) {
+	var req db2.ReqCreateAlarmOncall
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err := checkOncallPermission(c, 0); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	m := db2.AlarmOncall{
+		Uid:         c.Uid(),
+		Name:        req.Name,
+		Desc:        req.Desc,
+		Users:       req.Users,
+		HandoffTime: req.HandoffTime,
+		Period:      req.Period,
+		Overrides:   req.Overrides,
+	}
+	if err := m.Create(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsOncallsCreate, map[string]interface{}{"obj": m})
+	c.JSONOK(m)
+}
+
+// UpdateOncall godoc
+// @Summary      Update on-call rotation
+// @Tags         ALARM
+// @Accept       json
+// @Produce      json
+// @Param        oncall-id path int true "on-call id"
+// @Param        req body db.ReqCreateAlarmOncall true "params"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/oncalls/{oncall-id} [patch]
+func UpdateOncall(c *core.Context) {
+	id := cast.ToInt(c.Param("oncall-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	var req db2.ReqCreateAlarmOncall
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err := checkOncallPermission(c, id); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	ups := make(map[string]interface{}, 0)
+	ups["name"] = req.Name
+	ups["desc"] = req.Desc
+	ups["users"] = db2.Ints(req.Users)
+	ups["handoff_time"] = req.HandoffTime
+	ups["period"] = req.Period
+	ups["overrides"] = db2.OncallOverrides(req.Overrides)
+	m := db2.AlarmOncall{}
+	m.ID = id
+	if err := m.Update(invoker.Db, ups); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsOncallsUpdate, map[string]interface{}{"req": req})
+	c.JSONOK()
+}
+
+// ListOncall godoc
+// @Summary      List on-call rotations
+// @Tags         ALARM
+// @Produce      json
+// @Success      200 {object} core.Res{data=[]db.AlarmOncall}
+// @Router       /api/v2/alert/oncalls [get]
+func ListOncall(c *core.Context) {
+	m := db2.AlarmOncall{}
+	list, err := m.List(invoker.Db, egorm.Conds{})
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK(list)
+}
+
+// DeleteOncall godoc
+// @Summary      Delete on-call rotation
+// @Tags         ALARM
+// @Produce      json
+// @Param        oncall-id path int true "on-call id"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/oncalls/{oncall-id} [delete]
+func DeleteOncall(c *core.Context) {
+	id := cast.ToInt(c.Param("oncall-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := checkOncallPermission(c, id); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	policies, err := (&db2.AlarmEscalation{}).List(invoker.Db, egorm.Conds{})
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	for _, policy := range policies {
+		if policy.IsUsingOncall(id) {
+			c.JSONE(1, db2.ErrOncallInUse.Error()+": "+policy.Name, db2.ErrOncallInUse)
+			return
+		}
+	}
+	m := db2.AlarmOncall{}
+	m.ID = id
+	if err = m.Delete(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsOncallsDelete, map[string]interface{}{"id": id})
+	c.JSONOK()
+}
+
+// checkOncallPermission requires the permission of the escalation policies which notify the rotation,
+// see checkEscalationPermission. Oncall id 0 stands for a new rotation.
+func checkOncallPermission(c *core.Context, oncallId int) error {
+	escalationIds := make([]int, 0)
+	if oncallId != 0 {
+		policies, err := (&db2.AlarmEscalation{}).List(invoker.Db, egorm.Conds{})
+		if err != nil {
+			return err
+		}
+		for _, policy := range policies {
+			if policy.IsUsingOncall(oncallId) {
+				escalationIds = append(escalationIds, policy.ID)
+			}
+		}
+	}
+	return checkEscalationPermission(c, escalationIds...)
+}
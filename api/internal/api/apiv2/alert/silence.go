@@ -0,0 +1,183 @@
+package alert
+
+import (
+	"github.com/ego-component/egorm"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
+)
+
+// CreateSilence godoc
+// @Summary      Create alarm silence
+// @Description  Mutes the notifications of an alarm, or of the alarms whose tags equal all matchers.
+// @Description  Set cron and duration to create a recurring maintenance window, e.g. cron "0 2 * * 0" and duration 7200.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req body db.ReqCreateAlarmSilence true "params"
+// @Success      200 {object} core.Res{data=db.AlarmSilence}
+// @Router       /api/v2/alert/silences [post]
+func CreateSilence(c *core.Context) {
+	var req db2.ReqCreateAlarmSilence
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err := checkAlarmPermission(c, req.AlarmId); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	m := db2.AlarmSilence{
+		Uid:       c.Uid(),
+		AlarmId:   req.AlarmId,
+		Matchers:  req.Matchers,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Cron:      req.Cron,
+		Duration:  req.Duration,
+		Reason:    req.Reason,
+	}
+	if err := m.Create(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsSilencesCreate, map[string]interface{}{"obj": m})
+	c.JSONOK(m)
+}
+
+// UpdateSilence godoc
+// @Summary      Update alarm silence
+// @Tags         ALARM
+// @Accept       json
+// @Produce      json
+// @Param        silence-id path int true "silence id"
+// @Param        req body db.ReqCreateAlarmSilence true "params"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/silences/{silence-id} [patch]
+func UpdateSilence(c *core.Context) {
+	id := cast.ToInt(c.Param("silence-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	var req db2.ReqCreateAlarmSilence
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := req.Valid(); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	m := db2.AlarmSilence{}
+	m.ID = id
+	if err := m.Info(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if err := checkAlarmPermission(c, m.AlarmId); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	if req.AlarmId != m.AlarmId {
+		if err := checkAlarmPermission(c, req.AlarmId); err != nil {
+			c.JSONE(1, "permission verification failed", err)
+			return
+		}
+	}
+	ups := make(map[string]interface{}, 0)
+	ups["alarm_id"] = req.AlarmId
+	ups["matchers"] = db2.String2String(req.Matchers)
+	ups["start_time"] = req.StartTime
+	ups["end_time"] = req.EndTime
+	ups["cron"] = req.Cron
+	ups["duration"] = req.Duration
+	ups["reason"] = req.Reason
+	if err := m.Update(invoker.Db, ups); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsSilencesUpdate, map[string]interface{}{"req": req})
+	c.JSONOK()
+}
+
+// ListSilence godoc
+// @Summary      List alarm silences
+// @Description  Lists the silences of an alarm with alarm view permission, root users may omit alarmId to list all.
+// @Tags         ALARM
+// @Produce      json
+// @Param        req query db.ReqListAlarmSilence true "params"
+// @Success      200 {object} core.Res{data=[]db.AlarmSilence}
+// @Router       /api/v2/alert/silences [get]
+func ListSilence(c *core.Context) {
+	var req db2.ReqListAlarmSilence
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := checkAlarmAct(c, req.AlarmId, pmsplugin.ActView); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	conds := egorm.Conds{}
+	if req.AlarmId != 0 {
+		conds["alarm_id"] = req.AlarmId
+	}
+	m := db2.AlarmSilence{}
+	total, list := m.ListPage(invoker.Db, conds, &req.ReqPage)
+	c.JSONPage(list, core.Pagination{
+		Current:  req.Current,
+		PageSize: req.PageSize,
+		Total:    total,
+	})
+}
+
+// DeleteSilence godoc
+// @Summary      Delete alarm silence
+// @Tags         ALARM
+// @Produce      json
+// @Param        silence-id path int true "silence id"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/alert/silences/{silence-id} [delete]
+func DeleteSilence(c *core.Context) {
+	id := cast.ToInt(c.Param("silence-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	m := db2.AlarmSilence{}
+	m.ID = id
+	if err := m.Info(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if err := checkAlarmPermission(c, m.AlarmId); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	if err := m.Delete(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.AlarmCMDB(c.User(), db2.OpnAlarmsSilencesDelete, map[string]interface{}{"obj": m})
+	c.JSONOK()
+}
+
+// checkAlarmPermission requires alarm edit permission on every related table,
+// alarm id 0 stands for any alarm, e.g. silences matched by tags, and is limited to root users.
+func checkAlarmPermission(c *core.Context, alarmId int) error {
+	return checkAlarmAct(c, alarmId, pmsplugin.ActEdit)
+}
+
+// checkAlarmAct requires the act of alarm permission on every related table, alarm id 0 is limited to root users.
+func checkAlarmAct(c *core.Context, alarmId int, act string) error {
+	return service.CheckAlarmPermission(c.Uid(), alarmId, act)
+}
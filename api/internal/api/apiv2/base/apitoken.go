@@ -0,0 +1,74 @@
+package base
+
+import (
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+// CreateApiToken godoc
+// @Summary      Create an api token of the current user
+// @Description  The token authenticates the Loki and Elasticsearch compatible apis with the permissions of the user,
+// @Description  it is only returned in the response
+// @Tags         BASE
+// @Accept       json
+// @Produce      json
+// @Param        req body view.ReqApiTokenCreate true "params"
+// @Success      200 {object} core.Res{data=view.RespApiToken}
+// @Router       /api/v2/base/api-tokens [post]
+func CreateApiToken(c *core.Context) {
+	var req view.ReqApiTokenCreate
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	res, err := service.CreateApiToken(c.Uid(), req.Name)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// ListApiToken godoc
+// @Summary      Api tokens of the current user
+// @Tags         BASE
+// @Produce      json
+// @Success      200 {object} core.Res{data=[]view.RespApiToken}
+// @Router       /api/v2/base/api-tokens [get]
+func ListApiToken(c *core.Context) {
+	list, err := db.ApiTokenList(invoker.Db, c.Uid())
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	res := make([]view.RespApiToken, 0, len(list))
+	for _, m := range list {
+		res = append(res, service.ApiTokenView(m))
+	}
+	c.JSONOK(res)
+}
+
+// DeleteApiToken godoc
+// @Summary      Revoke an api token of the current user
+// @Tags         BASE
+// @Produce      json
+// @Param        token-id path int true "token id"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/base/api-tokens/{token-id} [delete]
+func DeleteApiToken(c *core.Context) {
+	id := cast.ToInt(c.Param("token-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := db.ApiTokenDelete(invoker.Db, c.Uid(), id); err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK()
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service"
 	"github.com/clickvisual/clickvisual/api/internal/service/shorturl"
 )
 
@@ -56,3 +57,25 @@ func ShortURLCreate(c *core.Context) {
 	}
 	c.JSONOK(res)
 }
+
+// SavedSearchRedirect
+// @Summary      打开保存的查询，永久链接不会过期
+// @Tags         LOGSTORE
+func SavedSearchRedirect(c *core.Context) {
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	search := db.Collect{}
+	if err := search.InfoByPermalink(invoker.Db, code); err != nil {
+		c.JSONE(core.CodeErr, err.Error(), nil)
+		return
+	}
+	if !service.SavedSearchVisible(c.Uid(), &search) {
+		c.JSONE(1, "permission verification failed", nil)
+		return
+	}
+	// a relative time range moves, so the redirect is not cached
+	c.Redirect(302, service.SavedSearchURL(&search, time.Now()))
+}
@@ -1,12 +1,16 @@
 package storage
 
 import (
+	"strings"
+	"time"
+
 	"github.com/ego-component/egorm"
 	"github.com/spf13/cast"
 
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
 	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service"
 	"github.com/clickvisual/clickvisual/api/internal/service/event"
 )
 
@@ -32,6 +36,14 @@ func CreateCollect(c *core.Context) {
 		Statement:   params.Statement,
 		CollectType: params.CollectType,
 	}
+	if m.CollectType == db2.CollectTypeSearch {
+		if err = checkCollectSearch(c, params.TableId, params.ReqCollectSearch); err != nil {
+			c.JSONE(1, err.Error(), err)
+			return
+		}
+		m.SetSearch(params.ReqCollectSearch)
+		m.Permalink = service.NewPermalink()
+	}
 	if err = m.Create(invoker.Db); err != nil {
 		c.JSONE(1, err.Error(), err)
 		return
@@ -73,7 +85,24 @@ func UpdateCollect(c *core.Context) {
 	// just mysql record update
 	ups := make(map[string]interface{}, 0)
 
-	if req.CollectType != 0 && req.Alias == "" && req.Statement == "" {
+	if currentCollect.CollectType == db2.CollectTypeSearch {
+		if err := checkCollectSearch(c, currentCollect.TableId, req.ReqCollectSearch); err != nil {
+			c.JSONE(1, err.Error(), err)
+			return
+		}
+		// a saved search is updated as a whole
+		ups["alias"] = req.Alias
+		ups["statement"] = req.Statement
+		ups["query_mode"] = req.QueryMode
+		ups["relative"] = req.Relative
+		ups["st"] = req.St
+		ups["et"] = req.Et
+		ups["fields"] = db2.Strings(req.Fields)
+		ups["filters"] = db2.Strings(req.Filters)
+		ups["sort"] = req.Sort
+		ups["scope"] = req.Scope
+		ups["members"] = db2.Ints(req.Members)
+	} else if req.CollectType != 0 && req.Alias == "" && req.Statement == "" {
 		ups["collect_type"] = req.CollectType
 	} else if req.CollectType == 0 && (req.Alias != "" || req.Statement != "") {
 		ups["alias"] = req.Alias
@@ -108,6 +137,22 @@ func ListCollect(c *core.Context) {
 		c.JSONE(1, "request parameter error: "+err.Error(), nil)
 		return
 	}
+	if req.CollectType&db2.CollectTypeSearch == db2.CollectTypeSearch {
+		m := db2.Collect{}
+		list, err := m.ListSearch(invoker.Db, c.Uid(), req.TableId)
+		if err != nil {
+			c.JSONE(core.CodeErr, err.Error(), err)
+			return
+		}
+		resp := make([]*db2.Collect, 0, len(list))
+		for _, row := range list {
+			if service.SavedSearchVisible(c.Uid(), row) {
+				resp = append(resp, row)
+			}
+		}
+		c.JSONOK(resp)
+		return
+	}
 	if req.CollectType&db2.CollectTypeQuery == db2.CollectTypeQuery {
 		conds := egorm.Conds{}
 		conds["uid"] = c.Uid()
@@ -183,3 +228,47 @@ func DeleteCollect(c *core.Context) {
 	}
 	c.JSONOK()
 }
+
+// InfoCollectPermalink godoc
+// @Summary      Saved search of a permalink
+// @Description  The search is returned to its creator, its team members or everyone with table access by its scope
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        code path string true "permalink code"
+// @Success      200 {object} core.Res{data=db.RespCollectPermalink}
+// @Router       /api/v2/storage/collects/permalinks/{code} [get]
+func InfoCollectPermalink(c *core.Context) {
+	code := strings.TrimSpace(c.Param("code"))
+	if code == "" {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	m := db2.Collect{}
+	if err := m.InfoByPermalink(invoker.Db, code); err != nil {
+		c.JSONE(1, "saved search not found", err)
+		return
+	}
+	if !service.SavedSearchVisible(c.Uid(), &m) {
+		c.JSONE(1, "permission verification failed", nil)
+		return
+	}
+	c.JSONOK(db2.RespCollectPermalink{Collect: &m, Url: service.SavedSearchURL(&m, time.Now())})
+}
+
+// checkCollectSearch checks the fields of a saved search and that the user can view its table.
+func checkCollectSearch(c *core.Context, tableId int, req db2.ReqCollectSearch) error {
+	if tableId == 0 {
+		return db2.ErrCollectSearchParams
+	}
+	if err := req.Valid(); err != nil {
+		return err
+	}
+	tableInfo, err := db2.TableInfo(invoker.Db, tableId)
+	if err != nil {
+		return err
+	}
+	if !service.TableViewIsPermission(c.Uid(), tableInfo.Database.Iid, tableInfo.ID) {
+		return db2.ErrCollectSearchTable
+	}
+	return nil
+}
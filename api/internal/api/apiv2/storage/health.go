@@ -0,0 +1,58 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
+)
+
+// Health godoc
+// @Summary      Ingestion health of the table
+// @Description  Reads the kafka consumers, their lag, the consumer exceptions, the view exceptions and the last insert of the table now.
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        storage-id path int true "table id"
+// @Success      200 {object} core.Res{data=view.RespTableHealth}
+// @Router       /api/v2/storage/{storage-id}/health [get]
+func Health(c *core.Context) {
+	id := cast.ToInt(c.Param("storage-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	tableInfo, err := db.TableInfo(invoker.Db, id)
+	if err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if tableInfo.ID == 0 || tableInfo.Database == nil {
+		c.JSONE(1, "table not found", nil)
+		return
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActView},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(id),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	res, err := service.TableHealth.Check(&tableInfo)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK(res)
+}
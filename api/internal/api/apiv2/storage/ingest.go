@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
+)
+
+// Ingest godoc
+// @Summary      Ingest logs
+// @Description  NDJSON logs of a table authenticated by an ingest token, a line is a message of the stream of the table.
+// @Description  The body may be compressed with Content-Encoding gzip, X-Ingest-Id deduplicates the retries of a request.
+// @Description  It responds 429 when the ingestion queue is full.
+// @Tags         LOGSTORE
+// @Accept       plain
+// @Produce      json
+// @Param        Authorization header string true "Bearer <ingest token>"
+// @Success      200 {object} core.Res{data=view.RespIngest}
+// @Router       /api/v1/ingest [post]
+func Ingest(c *core.Context) {
+	tableInfo, body, ok := ingestRequest(c)
+	if !ok {
+		return
+	}
+	res, err := service.Ingest.Push(tableInfo, c.GetHeader("X-Ingest-Id"), body)
+	if err != nil {
+		ingestError(c, err)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// ingestError responds the failures of the ingestion, the client retries the requests rejected with 429.
+func ingestError(c *core.Context, err error) {
+	var lineErr *service.IngestLineError
+	switch {
+	case errors.Is(err, service.ErrIngestQueueFull):
+		c.Header("Retry-After", "1")
+		c.JSON(http.StatusTooManyRequests, core.Res{Code: core.CodeErr, Msg: err.Error()})
+	case errors.Is(err, service.ErrIngestTooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, core.Res{Code: core.CodeErr, Msg: err.Error()})
+	case errors.Is(err, service.ErrIngestDisabled):
+		c.JSON(http.StatusServiceUnavailable, core.Res{Code: core.CodeErr, Msg: err.Error()})
+	case errors.As(err, &lineErr), errors.Is(err, service.ErrIngestUnsupported), errors.Is(err, service.ErrOTLPTable):
+		c.JSON(http.StatusBadRequest, core.Res{Code: core.CodeErr, Msg: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, core.Res{Code: core.CodeErr, Msg: err.Error()})
+	}
+}
+
+// ingestRequest reads the table of the bearer token and the body of the request.
+func ingestRequest(c *core.Context) (tableInfo db.BaseTable, body []byte, ok bool) {
+	token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+	tableInfo, err := service.IngestTable(token)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrIngestToken) {
+			status = http.StatusUnauthorized
+		}
+		c.JSON(status, core.Res{Code: core.CodeErr, Msg: err.Error()})
+		return
+	}
+	body, err = readIngestBody(c.Request, service.Ingest.MaxBodyBytes())
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrIngestTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		c.JSON(status, core.Res{Code: core.CodeErr, Msg: err.Error()})
+		return
+	}
+	return tableInfo, body, true
+}
+
+// readIngestBody reads the body up to max uncompressed bytes.
+func readIngestBody(r *http.Request, max int64) ([]byte, error) {
+	reader := io.Reader(r.Body)
+	switch strings.ToLower(r.Header.Get("Content-Encoding")) {
+	case "", "identity":
+	case "gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return nil, errors.Wrap(err, "gzip")
+		}
+		defer gz.Close()
+		reader = gz
+	default:
+		return nil, errors.New("unsupported content encoding")
+	}
+	body, err := io.ReadAll(io.LimitReader(reader, max+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > max {
+		return nil, service.ErrIngestTooLarge
+	}
+	return body, nil
+}
+
+// CreateIngestToken godoc
+// @Summary      Create an ingest token of the table
+// @Description  The token is only returned in the response
+// @Tags         LOGSTORE
+// @Accept       json
+// @Produce      json
+// @Param        storage-id path int true "table id"
+// @Param        req body view.ReqIngestTokenCreate true "params"
+// @Success      200 {object} core.Res{data=view.RespIngestToken}
+// @Router       /api/v2/storage/{storage-id}/ingest-tokens [post]
+func CreateIngestToken(c *core.Context) {
+	tid := cast.ToInt(c.Param("storage-id"))
+	if tid == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	var req view.ReqIngestTokenCreate
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	if err := checkIngestToken(c, tid); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	res, err := service.CreateIngestToken(tid, c.Uid(), req.Name)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// ListIngestToken godoc
+// @Summary      Ingest tokens of the table
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        storage-id path int true "table id"
+// @Success      200 {object} core.Res{data=[]view.RespIngestToken}
+// @Router       /api/v2/storage/{storage-id}/ingest-tokens [get]
+func ListIngestToken(c *core.Context) {
+	tid := cast.ToInt(c.Param("storage-id"))
+	if tid == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := checkIngestToken(c, tid); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	list, err := db.IngestTokenList(invoker.Db, tid)
+	if err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	res := make([]view.RespIngestToken, 0, len(list))
+	for _, m := range list {
+		res = append(res, service.IngestTokenView(m))
+	}
+	c.JSONOK(res)
+}
+
+// DeleteIngestToken godoc
+// @Summary      Revoke an ingest token of the table
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        storage-id path int true "table id"
+// @Param        token-id path int true "token id"
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/storage/{storage-id}/ingest-tokens/{token-id} [delete]
+func DeleteIngestToken(c *core.Context) {
+	tid := cast.ToInt(c.Param("storage-id"))
+	id := cast.ToInt(c.Param("token-id"))
+	if tid == 0 || id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	if err := checkIngestToken(c, tid); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if err := db.IngestTokenDelete(invoker.Db, tid, id); err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK()
+}
+
+// checkIngestToken requires the permission to edit the table.
+func checkIngestToken(c *core.Context, tid int) error {
+	tableInfo, err := db.TableInfo(invoker.Db, tid)
+	if err != nil {
+		return err
+	}
+	if tableInfo.ID == 0 || tableInfo.Database == nil {
+		return errors.New("table not found")
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActEdit},
+		DomainType:  pmsplugin.PrefixTable,
+		DomainId:    strconv.Itoa(tid),
+	}); err != nil {
+		return errors.Wrap(err, "permission verification failed")
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+// OTLPLogs godoc
+// @Summary      OTLP/HTTP logs receiver
+// @Description  ExportLogsServiceRequest in protobuf or JSON, authenticated by an ingest token of an OTLP logs table.
+// @Description  It responds 429 when the ingestion queue is full.
+// @Tags         LOGSTORE
+// @Accept       application/x-protobuf,json
+// @Produce      application/x-protobuf,json
+// @Param        Authorization header string true "Bearer <ingest token>"
+// @Success      200
+// @Router       /api/v1/otlp/v1/logs [post]
+func OTLPLogs(c *core.Context) {
+	req := &collogspb.ExportLogsServiceRequest{}
+	otlpExport(c, req, &collogspb.ExportLogsServiceResponse{}, func(tableInfo db.BaseTable, key string, body []byte) (view.RespIngest, error) {
+		return service.Ingest.PushLogs(tableInfo, key, body, req)
+	})
+}
+
+// OTLPTraces godoc
+// @Summary      OTLP/HTTP traces receiver
+// @Description  ExportTraceServiceRequest in protobuf or JSON, authenticated by an ingest token of an OTLP traces table.
+// @Description  It responds 429 when the ingestion queue is full.
+// @Tags         LOGSTORE
+// @Accept       application/x-protobuf,json
+// @Produce      application/x-protobuf,json
+// @Param        Authorization header string true "Bearer <ingest token>"
+// @Success      200
+// @Router       /api/v1/otlp/v1/traces [post]
+func OTLPTraces(c *core.Context) {
+	req := &coltracepb.ExportTraceServiceRequest{}
+	otlpExport(c, req, &coltracepb.ExportTraceServiceResponse{}, func(tableInfo db.BaseTable, key string, body []byte) (view.RespIngest, error) {
+		return service.Ingest.PushTraces(tableInfo, key, body, req)
+	})
+}
+
+func otlpExport(c *core.Context, req, resp proto.Message, push func(tableInfo db.BaseTable, key string, body []byte) (view.RespIngest, error)) {
+	tableInfo, body, ok := ingestRequest(c)
+	if !ok {
+		return
+	}
+	contentType := c.GetHeader("Content-Type")
+	if err := service.UnmarshalOTLP(contentType, body, req); err != nil {
+		httpStatus := http.StatusBadRequest
+		if errors.Is(err, service.ErrOTLPContentType) {
+			httpStatus = http.StatusUnsupportedMediaType
+		}
+		c.JSON(httpStatus, core.Res{Code: core.CodeErr, Msg: err.Error()})
+		return
+	}
+	if _, err := push(tableInfo, c.GetHeader("X-Ingest-Id"), body); err != nil {
+		ingestError(c, err)
+		return
+	}
+	b, respType, err := service.MarshalOTLP(contentType, resp)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, core.Res{Code: core.CodeErr, Msg: err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, respType, b)
+}
+
+// OTLPLogsService is the OTLP/gRPC logs receiver, the ingest token is the bearer of the authorization metadata.
+type OTLPLogsService struct {
+	collogspb.UnimplementedLogsServiceServer
+}
+
+func (s *OTLPLogsService) Export(ctx context.Context, req *collogspb.ExportLogsServiceRequest) (*collogspb.ExportLogsServiceResponse, error) {
+	tableInfo, key, body, err := otlpGRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = service.Ingest.PushLogs(tableInfo, key, body, req); err != nil {
+		return nil, otlpStatus(err)
+	}
+	return &collogspb.ExportLogsServiceResponse{}, nil
+}
+
+// OTLPTraceService is the OTLP/gRPC traces receiver, the ingest token is the bearer of the authorization metadata.
+type OTLPTraceService struct {
+	coltracepb.UnimplementedTraceServiceServer
+}
+
+func (s *OTLPTraceService) Export(ctx context.Context, req *coltracepb.ExportTraceServiceRequest) (*coltracepb.ExportTraceServiceResponse, error) {
+	tableInfo, key, body, err := otlpGRPCRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = service.Ingest.PushTraces(tableInfo, key, body, req); err != nil {
+		return nil, otlpStatus(err)
+	}
+	return &coltracepb.ExportTraceServiceResponse{}, nil
+}
+
+func otlpGRPCRequest(ctx context.Context, req proto.Message) (tableInfo db.BaseTable, key string, body []byte, err error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var token string
+	if values := md.Get("authorization"); len(values) > 0 {
+		token = strings.TrimSpace(strings.TrimPrefix(values[0], "Bearer "))
+	}
+	if values := md.Get("x-ingest-id"); len(values) > 0 {
+		key = values[0]
+	}
+	if tableInfo, err = service.IngestTable(token); err != nil {
+		return tableInfo, key, nil, otlpStatus(err)
+	}
+	if body, err = proto.Marshal(req); err != nil {
+		return tableInfo, key, nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return tableInfo, key, body, nil
+}
+
+// otlpStatus maps the failures of the ingestion to the codes of OTLP/gRPC, the exporters retry Unavailable.
+func otlpStatus(err error) error {
+	code := codes.Unavailable
+	switch {
+	case errors.Is(err, service.ErrIngestToken):
+		code = codes.Unauthenticated
+	case errors.Is(err, service.ErrIngestTooLarge), errors.Is(err, service.ErrOTLPTable):
+		code = codes.InvalidArgument
+	case errors.Is(err, service.ErrIngestDisabled):
+		code = codes.FailedPrecondition
+	}
+	return status.Error(code, err.Error())
+}
@@ -0,0 +1,102 @@
+package storage
+
+import (
+	"github.com/ego-component/egorm"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+// ListQueryHistory godoc
+// @Summary      Query history of the user
+// @Description  The log queries of the user, the newest first. Only the newest 200 queries are kept.
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        req query db.ReqListQueryHistory true "params"
+// @Success      200 {object} core.Res{data=db.RespListQueryHistory}
+// @Router       /api/v2/storage/query-histories [get]
+func ListQueryHistory(c *core.Context) {
+	var req db2.ReqListQueryHistory
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	conds := egorm.Conds{}
+	conds["uid"] = c.Uid()
+	if req.TableId != 0 {
+		conds["table_id"] = req.TableId
+	}
+	total, list := db2.QueryHistoryListPage(invoker.Db, conds, &req.ReqPage)
+	c.JSONOK(db2.RespListQueryHistory{Total: total, List: list})
+}
+
+// DeleteQueryHistory godoc
+// @Summary      Clear the query history of the user
+// @Tags         LOGSTORE
+// @Produce      json
+// @Success      200 {object} core.Res{}
+// @Router       /api/v2/storage/query-histories [delete]
+func DeleteQueryHistory(c *core.Context) {
+	if err := db2.QueryHistoryDeleteByUid(invoker.Db, c.Uid()); err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	c.JSONOK()
+}
+
+// PromoteQueryHistory godoc
+// @Summary      Save a query of the history as a saved search
+// @Description  The filters of the query are kept, and its time range is kept unless relative is set
+// @Tags         LOGSTORE
+// @Accept       json
+// @Produce      json
+// @Param        history-id path int true "query history id"
+// @Param        req body db.ReqPromoteQueryHistory true "params"
+// @Success      200 {object} core.Res{data=db.Collect}
+// @Router       /api/v2/storage/query-histories/{history-id}/promote [post]
+func PromoteQueryHistory(c *core.Context) {
+	id := cast.ToInt(c.Param("history-id"))
+	if id == 0 {
+		c.JSONE(1, "invalid parameter", nil)
+		return
+	}
+	var req db2.ReqPromoteQueryHistory
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	history, err := db2.QueryHistoryInfo(invoker.Db, id)
+	if err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	if history.Uid != c.Uid() {
+		c.JSONE(1, "query history not found", nil)
+		return
+	}
+	req.QueryMode, req.Filters = history.QueryMode, history.Filters
+	if req.Relative == 0 {
+		req.St, req.Et = history.St, history.Et
+	}
+	if err = checkCollectSearch(c, history.TableId, req.ReqCollectSearch); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	m := db2.Collect{
+		Uid:         c.Uid(),
+		TableId:     history.TableId,
+		Alias:       req.Alias,
+		Statement:   history.Query,
+		CollectType: db2.CollectTypeSearch,
+	}
+	m.SetSearch(req.ReqCollectSearch)
+	m.Permalink = service.NewPermalink()
+	if err = m.Create(invoker.Db); err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	c.JSONOK(m)
+}
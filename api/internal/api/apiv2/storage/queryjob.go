@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"strconv"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
+)
+
+// CreateQueryJob godoc
+// @Summary      Submit a query job
+// @Description  Runs a log query of a table (kind logs) or a statement of an instance (kind sql) in the background and returns the job.
+// @Description  The running jobs of a user and their execution time are limited by the instance settings.
+// @Description  Statements need the rawsql permission of the instance. When app.isMultiCopy is on, the jobs are shared through redis and any copy answers the polls and the cancellations.
+// @Tags         LOGSTORE
+// @Accept       json
+// @Produce      json
+// @Param        req body view.ReqQueryJobCreate true "params"
+// @Success      200 {object} core.Res{data=view.RespQueryJob}
+// @Router       /api/v2/storage/query-jobs [post]
+func CreateQueryJob(c *core.Context) {
+	var req view.ReqQueryJobCreate
+	if err := c.Bind(&req); err != nil {
+		c.JSONE(1, "invalid parameter: "+err.Error(), nil)
+		return
+	}
+	var (
+		res view.RespQueryJob
+		err error
+	)
+	switch req.Kind {
+	case view.QueryJobKindLogs:
+		tableInfo, errTable := db2.TableInfo(invoker.Db, req.Tid)
+		if errTable != nil {
+			c.JSONE(1, "table not found", errTable)
+			return
+		}
+		if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+			UserId:      c.Uid(),
+			ObjectType:  pmsplugin.PrefixInstance,
+			ObjectIdx:   strconv.Itoa(tableInfo.Database.Iid),
+			SubResource: pmsplugin.Log,
+			Acts:        []string{pmsplugin.ActView},
+			DomainType:  pmsplugin.PrefixTable,
+			DomainId:    strconv.Itoa(tableInfo.ID),
+		}); err != nil {
+			c.JSONE(1, "permission verification failed", err)
+			return
+		}
+		if err = service.CheckQueryMode(c.Uid(), req.Param.QueryMode, tableInfo); err != nil {
+			c.JSONE(1, "raw SQL permission verification failed", err)
+			return
+		}
+		res, err = service.QueryJobs.SubmitLogs(c.Uid(), tableInfo, req.Param)
+	case view.QueryJobKindSQL:
+		if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+			UserId:      c.Uid(),
+			ObjectType:  pmsplugin.PrefixInstance,
+			ObjectIdx:   strconv.Itoa(req.Iid),
+			SubResource: pmsplugin.Log,
+			Acts:        []string{pmsplugin.ActView},
+		}); err != nil {
+			c.JSONE(1, "permission verification failed", err)
+			return
+		}
+		// any statement of the instance can run, so the raw SQL permission is required even when it is not opted in
+		if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+			UserId:      c.Uid(),
+			ObjectType:  pmsplugin.PrefixInstance,
+			ObjectIdx:   strconv.Itoa(req.Iid),
+			SubResource: pmsplugin.RawSQL,
+			Acts:        []string{pmsplugin.ActView},
+		}); err != nil {
+			c.JSONE(1, "raw SQL permission verification failed", err)
+			return
+		}
+		res, err = service.QueryJobs.SubmitSQL(c.Uid(), req.Iid, req.SQL)
+	}
+	if err != nil {
+		c.JSONE(1, err.Error(), err)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db2.OpnTablesLogsQuery, map[string]interface{}{"param": req, "job": res.Id})
+	c.JSONOK(res)
+}
+
+// ListQueryJob godoc
+// @Summary      List the query jobs of the user
+// @Tags         LOGSTORE
+// @Produce      json
+// @Success      200 {object} core.Res{data=[]view.RespQueryJob}
+// @Router       /api/v2/storage/query-jobs [get]
+func ListQueryJob(c *core.Context) {
+	c.JSONOK(service.QueryJobs.List(c.Uid()))
+}
+
+// InfoQueryJob godoc
+// @Summary      Poll a query job
+// @Description  Returns the status and the rows and bytes read so far.
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        job-id path string true "job id"
+// @Success      200 {object} core.Res{data=view.RespQueryJob}
+// @Router       /api/v2/storage/query-jobs/{job-id} [get]
+func InfoQueryJob(c *core.Context) {
+	res, err := service.QueryJobs.Info(c.Param("job-id"))
+	if err == nil {
+		err = checkQueryJob(c, res)
+	}
+	if err != nil {
+		c.JSONE(1, err.Error(), nil)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// ResultQueryJob godoc
+// @Summary      Fetch the result of a query job
+// @Description  Returns the rows read so far by the running statement while the job is running and the result once it succeeded.
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        job-id path string true "job id"
+// @Success      200 {object} core.Res{data=view.RespQueryJobResult}
+// @Router       /api/v2/storage/query-jobs/{job-id}/result [get]
+func ResultQueryJob(c *core.Context) {
+	res, err := service.QueryJobs.Result(c.Param("job-id"))
+	if err == nil {
+		err = checkQueryJob(c, res.RespQueryJob)
+	}
+	if err != nil {
+		c.JSONE(1, err.Error(), nil)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// CancelQueryJob godoc
+// @Summary      Cancel a query job
+// @Description  Stops the job and kills its statements on the server with KILL QUERY.
+// @Tags         LOGSTORE
+// @Produce      json
+// @Param        job-id path string true "job id"
+// @Success      200 {object} core.Res{data=view.RespQueryJob}
+// @Router       /api/v2/storage/query-jobs/{job-id}/cancel [post]
+func CancelQueryJob(c *core.Context) {
+	info, err := service.QueryJobs.Info(c.Param("job-id"))
+	if err == nil {
+		err = checkQueryJob(c, info)
+	}
+	if err != nil {
+		c.JSONE(1, err.Error(), nil)
+		return
+	}
+	res, err := service.QueryJobs.Cancel(info.Id)
+	if err != nil {
+		c.JSONE(1, err.Error(), nil)
+		return
+	}
+	c.JSONOK(res)
+}
+
+// checkQueryJob allows the jobs of the user only, root sees all of them.
+func checkQueryJob(c *core.Context, job view.RespQueryJob) error {
+	if job.Uid == c.Uid() || permission.Manager.IsRootUser(c.Uid()) == nil {
+		return nil
+	}
+	return errors.Wrap(service.ErrQueryJobNotFound, job.Id)
+}
@@ -195,13 +195,12 @@ func Update(c *core.Context) {
 		}
 	}
 	var streamSQL string
-	// check kafka
-	if req.KafkaSkipBrokenMessages != tableInfo.KafkaSkipBrokenMessages ||
-		req.KafkaBrokers != tableInfo.Brokers ||
-		req.KafkaConsumerNum != tableInfo.ConsumerNum ||
-		req.KafkaTopic != tableInfo.Topic {
-		// drop & create kafka engine table
-		if streamSQL, err = op.CreateKafkaTable(&tableInfo, req); err != nil {
+	// check reader, the masked secrets are kept
+	req.KeepReader(&tableInfo)
+	req.ReaderSettings.KeepSecrets(tableInfo.ReaderSettings)
+	if req.ReaderChanged(&tableInfo) {
+		// drop & create reader engine table
+		if streamSQL, err = op.UpdateReader(&tableInfo, req); err != nil {
 			c.JSONE(1, "update failed 03: "+err.Error(), nil)
 			return
 		}
@@ -217,6 +216,8 @@ func Update(c *core.Context) {
 	ups["desc"] = req.Desc
 	ups["kafka_skip_broken_messages"] = req.KafkaSkipBrokenMessages
 	ups["v3_table_type"] = req.V3TableType
+	ups["reader_type"] = req.GetReaderType()
+	ups["reader_settings"] = req.ReaderSettings
 	if streamSQL != "" {
 		ups["sql_stream"] = streamSQL
 	}
@@ -266,6 +267,9 @@ func CreateStorageByTemplate(c *core.Context) {
 	case "agent":
 		createStorageByTemplateAgent(c)
 		return
+	case "otlp":
+		createStorageByTemplateOTLP(c)
+		return
 	}
 	c.JSONE(core.CodeErr, "template error", nil)
 }
@@ -415,3 +419,35 @@ func createStorageByTemplateILogtail(c *core.Context) {
 	event.Event.InquiryCMDB(c.User(), db.OpnTablesCreate, map[string]interface{}{"param": param})
 	c.JSONOK()
 }
+
+func createStorageByTemplateOTLP(c *core.Context) {
+	var param view.ReqCreateStorageByTemplateOTLP
+	err := c.Bind(&param)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	databaseInfo, err := db.DatabaseInfo(invoker.Db, param.DatabaseId)
+	if err != nil {
+		c.JSONE(core.CodeErr, "invalid parameter: "+err.Error(), err)
+		return
+	}
+	if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+		UserId:      c.Uid(),
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(databaseInfo.Iid),
+		SubResource: pmsplugin.Log,
+		Acts:        []string{pmsplugin.ActEdit},
+		DomainType:  pmsplugin.PrefixDatabase,
+		DomainId:    strconv.Itoa(databaseInfo.ID),
+	}); err != nil {
+		c.JSONE(1, "permission verification failed", err)
+		return
+	}
+	if err = service.Storage.CreateByOTLPTemplate(c.Uid(), databaseInfo, param); err != nil {
+		c.JSONE(core.CodeErr, err.Error(), err)
+		return
+	}
+	event.Event.InquiryCMDB(c.User(), db.OpnTablesCreate, map[string]interface{}{"param": param})
+	c.JSONOK()
+}
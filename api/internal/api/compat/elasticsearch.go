@@ -0,0 +1,174 @@
+package compat
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+// maxSearchBody limits the bodies of _search and _msearch.
+const maxSearchBody = 1 << 20
+
+// ESInfo godoc
+// @Summary      Elasticsearch compatible cluster information
+// @Tags         COMPAT
+// @Produce      json
+// @Success      200
+// @Router       /api/compat/es [get]
+func ESInfo(c *core.Context) {
+	c.Context.JSON(http.StatusOK, map[string]interface{}{
+		"name":         "clickvisual",
+		"cluster_name": "clickvisual",
+		"version": map[string]interface{}{
+			"number":                              service.ESVersion,
+			"build_flavor":                        "default",
+			"lucene_version":                      "8.7.0",
+			"minimum_wire_compatibility_version":  "6.8.0",
+			"minimum_index_compatibility_version": "6.0.0-beta1",
+		},
+		"tagline": "You Know, for Search",
+	})
+}
+
+// ESMapping godoc
+// @Summary      Elasticsearch compatible mapping of the table of the index
+// @Tags         COMPAT
+// @Produce      json
+// @Param        index path string true "database.table or table"
+// @Success      200
+// @Router       /api/compat/es/{index}/_mapping [get]
+func ESMapping(c *core.Context) {
+	res, err := service.ESMapping(c.Uid(), c.Param("index"))
+	if err != nil {
+		esError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, res)
+}
+
+// ESSearch godoc
+// @Summary      Elasticsearch compatible search of the table of the index
+// @Description  query_string, bool, term, terms, match, match_phrase, exists and range queries, the range of the
+// @Description  time field is the time range, and terms or date_histogram aggregations without sub aggregations.
+// @Tags         COMPAT
+// @Accept       json
+// @Produce      json
+// @Param        index path string true "database.table or table"
+// @Param        req body view.ReqESSearch false "params"
+// @Success      200 {object} view.RespESSearch
+// @Router       /api/compat/es/{index}/_search [post]
+func ESSearch(c *core.Context) {
+	var req view.ReqESSearch
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxSearchBody))
+	if err != nil {
+		esError(c, err)
+		return
+	}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err = json.Unmarshal(body, &req); err != nil {
+			esError(c, errors.Wrap(service.ErrESQuery, err.Error()))
+			return
+		}
+	}
+	res, err := service.ESSearch(c.Uid(), c.Param("index"), req, time.Now())
+	if err != nil {
+		esError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, res)
+}
+
+// ESMultiSearch godoc
+// @Summary      Elasticsearch compatible multi search
+// @Description  NDJSON of the header with the index and the body of every search, as Grafana sends.
+// @Tags         COMPAT
+// @Accept       plain
+// @Produce      json
+// @Success      200
+// @Router       /api/compat/es/_msearch [post]
+func ESMultiSearch(c *core.Context) {
+	start := time.Now()
+	responses := make([]interface{}, 0)
+	scanner := bufio.NewScanner(io.LimitReader(c.Request.Body, maxSearchBody))
+	scanner.Buffer(make([]byte, 64*1024), maxSearchBody)
+	var header *struct {
+		Index json.RawMessage `json:"index"`
+	}
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		if header == nil {
+			header = &struct {
+				Index json.RawMessage `json:"index"`
+			}{}
+			if err := json.Unmarshal(line, header); err != nil {
+				esError(c, errors.Wrap(service.ErrESQuery, err.Error()))
+				return
+			}
+			continue
+		}
+		var (
+			req view.ReqESSearch
+			res interface{}
+		)
+		err := json.Unmarshal(line, &req)
+		if err != nil {
+			err = errors.Wrap(service.ErrESQuery, err.Error())
+		} else if index, errIndex := esIndex(header.Index); errIndex != nil {
+			err = errIndex
+		} else {
+			res, err = service.ESSearch(c.Uid(), index, req, time.Now())
+		}
+		if err != nil {
+			_, res = esErrorBody(err)
+		}
+		responses = append(responses, res)
+		header = nil
+	}
+	if err := scanner.Err(); err != nil {
+		esError(c, errors.Wrap(service.ErrESQuery, err.Error()))
+		return
+	}
+	c.Context.JSON(http.StatusOK, map[string]interface{}{"took": time.Since(start).Milliseconds(), "responses": responses})
+}
+
+// esIndex reads the index of a header of _msearch, a string or an array of one index.
+func esIndex(raw json.RawMessage) (string, error) {
+	var index string
+	if err := json.Unmarshal(raw, &index); err == nil {
+		return index, nil
+	}
+	var indexes []string
+	if err := json.Unmarshal(raw, &indexes); err != nil || len(indexes) != 1 {
+		return "", errors.Wrap(service.ErrESQuery, "a search reads one index")
+	}
+	return indexes[0], nil
+}
+
+func esError(c *core.Context, err error) {
+	status, body := esErrorBody(err)
+	c.Context.JSON(status, body)
+}
+
+// esErrorBody is the error of Elasticsearch of the failure.
+func esErrorBody(err error) (int, view.RespESError) {
+	status, typ := http.StatusInternalServerError, "exception"
+	switch {
+	case errors.Is(err, service.ErrCompatTable):
+		status, typ = http.StatusNotFound, "index_not_found_exception"
+	case service.IsCompatBadRequest(err):
+		status, typ = http.StatusBadRequest, "parsing_exception"
+	}
+	return status, view.RespESError{Error: view.ESError{Type: typ, Reason: err.Error()}, Status: status}
+}
@@ -0,0 +1,115 @@
+package compat
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service"
+)
+
+// LokiQueryRange godoc
+// @Summary      Loki compatible range query
+// @Description  A subset of LogQL on the table of the table label: label matchers, line filters and
+// @Description  count_over_time or rate of them, authenticated by an api token.
+// @Tags         COMPAT
+// @Produce      json
+// @Param        query query string true "LogQL, e.g. {table=\"logs.app\", level=\"error\"} |= \"timeout\""
+// @Param        start query string false "nanoseconds, seconds or RFC3339, an hour before the end by default"
+// @Param        end query string false "nanoseconds, seconds or RFC3339, now by default"
+// @Param        limit query int false "logs, 100 by default"
+// @Param        step query string false "duration or seconds of the metric queries"
+// @Param        direction query string false "backward (default) or forward"
+// @Success      200 {object} view.RespLoki{data=view.LokiResult}
+// @Router       /api/compat/loki/api/v1/query_range [get]
+func LokiQueryRange(c *core.Context) {
+	var req view.ReqLokiQueryRange
+	if err := c.ShouldBind(&req); err != nil {
+		lokiError(c, errors.Wrap(service.ErrLogQL, err.Error()))
+		return
+	}
+	res, err := service.LokiQueryRange(c.Uid(), req, time.Now())
+	if err != nil {
+		lokiError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, view.RespLoki{Status: "success", Data: res})
+}
+
+// LokiLabels godoc
+// @Summary      Loki compatible label names
+// @Description  The table label and the analysis fields of the table of the query, or of all the tables of the user.
+// @Tags         COMPAT
+// @Produce      json
+// @Param        query query string false "selector of a table"
+// @Success      200 {object} view.RespLoki{data=[]string}
+// @Router       /api/compat/loki/api/v1/labels [get]
+func LokiLabels(c *core.Context) {
+	res, err := service.LokiLabels(c.Uid(), c.Query("query"))
+	if err != nil {
+		lokiError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, view.RespLoki{Status: "success", Data: res})
+}
+
+// LokiLabelValues godoc
+// @Summary      Loki compatible label values
+// @Description  The tables of the user for the table label, the most frequent values of the field in the table
+// @Description  of the query for the other labels.
+// @Tags         COMPAT
+// @Produce      json
+// @Param        name path string true "label"
+// @Param        query query string false "selector of a table"
+// @Param        start query string false "nanoseconds, seconds or RFC3339"
+// @Param        end query string false "nanoseconds, seconds or RFC3339"
+// @Success      200 {object} view.RespLoki{data=[]string}
+// @Router       /api/compat/loki/api/v1/label/{name}/values [get]
+func LokiLabelValues(c *core.Context) {
+	res, err := service.LokiLabelValues(c.Uid(), c.Param("name"), c.Query("query"), c.Query("start"), c.Query("end"), time.Now())
+	if err != nil {
+		lokiError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, view.RespLoki{Status: "success", Data: res})
+}
+
+// LokiSeries godoc
+// @Summary      Loki compatible series
+// @Description  The labels of the selectors, the selectors without the table label match all the tables of the user.
+// @Tags         COMPAT
+// @Produce      json
+// @Param        match[] query []string true "selectors"
+// @Success      200 {object} view.RespLoki{data=[]map[string]string}
+// @Router       /api/compat/loki/api/v1/series [get]
+func LokiSeries(c *core.Context) {
+	matches := c.QueryArray("match[]")
+	if len(matches) == 0 {
+		matches = c.PostFormArray("match[]")
+	}
+	if len(matches) == 0 {
+		lokiError(c, errors.Wrap(service.ErrLogQL, "match[] is required"))
+		return
+	}
+	res, err := service.LokiSeries(c.Uid(), matches)
+	if err != nil {
+		lokiError(c, err)
+		return
+	}
+	c.Context.JSON(http.StatusOK, view.RespLoki{Status: "success", Data: res})
+}
+
+// lokiError responds the errors as the Prometheus style APIs of Loki.
+func lokiError(c *core.Context, err error) {
+	status, typ := http.StatusInternalServerError, "internal"
+	switch {
+	case errors.Is(err, service.ErrCompatTable):
+		status, typ = http.StatusNotFound, "not_found"
+	case service.IsCompatBadRequest(err):
+		status, typ = http.StatusBadRequest, "bad_data"
+	}
+	c.Context.JSON(status, view.RespLoki{Status: "error", ErrorType: typ, Error: err.Error()})
+}
@@ -31,6 +31,8 @@ const (
 	InnerKeyNamespace = "_namespace_"
 	InnerKeyPod       = "_pod_"
 	InnerRawLog       = "_raw_log_"
+	InnerKeyOffset    = "_offset_" // byte offset of the line in the file
+	InnerKeySign      = "_sign_"   // signature of the file and the offset, see Sign
 )
 
 var SystemKeyArr = []string{
@@ -0,0 +1,112 @@
+package search
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// signKey signs the files and the offsets of the search results. It is generated at start, so the results of a
+// former process have to be searched again before reading their context.
+var signKey = func() []byte {
+	key := make([]byte, 32)
+	_, _ = rand.Read(key)
+	return key
+}()
+
+// ContextRequest asks for the lines around the line at the byte offset of a file, as grep -C.
+type ContextRequest struct {
+	Path   string
+	Offset int64  // the _offset_ of a search result
+	Sign   string // the _sign_ of a search result
+	Before int
+	After  int
+}
+
+// Sign returns the _sign_ of the line at the offset of the file, Context reads only the files of signed lines.
+func Sign(path string, offset int64) string {
+	mac := hmac.New(sha256.New, signKey)
+	mac.Write([]byte(path + "\n" + strconv.FormatInt(offset, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Context returns the lines before and after the line at the offset in the order of the file,
+// the empty lines are skipped. The items carry their file and offset as the items of Run.
+func Context(req ContextRequest) (data view.RespAgentContext, err error) {
+	data.Before = make([]view.RespAgentSearchItem, 0, req.Before)
+	data.After = make([]view.RespAgentSearchItem, 0, req.After)
+	if strings.Contains(req.Path, SkipPath) {
+		return data, errors.Errorf("file %s can not be searched", req.Path)
+	}
+	if !hmac.Equal([]byte(req.Sign), []byte(Sign(req.Path, req.Offset))) {
+		return data, errors.Errorf("%s of the log does not match, search the log again", InnerKeySign)
+	}
+	file, err := OpenFile(req.Path)
+	if err != nil {
+		return data, errors.Wrapf(err, "open file %s error", req.Path)
+	}
+	defer file.ptr.Close()
+	if req.Offset < 0 || req.Offset >= file.size {
+		return data, errors.Errorf("offset %d is out of file %s", req.Offset, req.Path)
+	}
+	if req.Offset > 0 {
+		prev := make([]byte, 1)
+		if _, err = file.ptr.ReadAt(prev, req.Offset-1); err != nil {
+			return data, err
+		}
+		if prev[0] != '\n' {
+			return data, errors.Errorf("offset %d is not the start of a line", req.Offset)
+		}
+		// the scan starts before the line break of the previous line
+		scanner := NewBackScan(file.ptr, req.Offset-1)
+		for len(data.Before) < req.Before {
+			line, pos, errLine := scanner.Line()
+			if errLine == io.EOF {
+				break
+			}
+			if errLine != nil {
+				return data, errLine
+			}
+			if line != "" {
+				data.Before = append(data.Before, contextItem(file.path, line, pos))
+			}
+		}
+		for i, j := 0, len(data.Before)-1; i < j; i, j = i+1, j-1 {
+			data.Before[i], data.Before[j] = data.Before[j], data.Before[i]
+		}
+	}
+	reader := bufio.NewReader(io.NewSectionReader(file.ptr, req.Offset, file.size-req.Offset))
+	pos := req.Offset
+	// the first line is the line of the request
+	for first := true; first || len(data.After) < req.After; first = false {
+		line, errLine := reader.ReadBytes('\n')
+		if errLine != nil && errLine != io.EOF {
+			return data, errLine
+		}
+		if text := string(dropCR(bytes.TrimSuffix(line, []byte{'\n'}))); !first && text != "" {
+			data.After = append(data.After, contextItem(file.path, text, pos))
+		}
+		pos += int64(len(line))
+		if errLine == io.EOF {
+			break
+		}
+	}
+	return data, nil
+}
+
+func contextItem(path, line string, offset int64) view.RespAgentSearchItem {
+	return view.RespAgentSearchItem{
+		Line: line,
+		Ext:  map[string]any{InnerKeyFile: path, InnerKeyOffset: offset, InnerKeySign: Sign(path, offset)},
+	}
+}
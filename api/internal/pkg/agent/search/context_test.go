@@ -0,0 +1,100 @@
+package search
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/dto"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func TestRunOffsets(t *testing.T) {
+	content, err := os.ReadFile("test_files/1.testlog")
+	assert.NoError(t, err)
+	data, err := Run(Request{Path: "test_files/1.testlog", Limit: 5})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, data.Data)
+	for _, item := range data.Data {
+		offset := item.Ext[InnerKeyOffset].(int64)
+		assert.True(t, bytes.HasPrefix(content[offset:], []byte(item.Line+"\n")), "line at %d", offset)
+		assert.True(t, offset == 0 || content[offset-1] == '\n', "offset %d is not the start of a line", offset)
+		assert.Equal(t, Sign("test_files/1.testlog", offset), item.Ext[InnerKeySign])
+	}
+}
+
+func TestContext(t *testing.T) {
+	lines := []string{
+		`{"ts":1698972657,"msg":"a"}`,
+		`{"ts":1698972658,"msg":"b"}`,
+		`panic: b`,
+		`	main.go:10`,
+		``,
+		`{"ts":1698972659,"msg":"c"}`,
+		`{"ts":1698972660,"msg":"d"}`,
+	}
+	path := filepath.Join(t.TempDir(), "context.log")
+	assert.NoError(t, os.WriteFile(path, []byte(strings.Join(lines, "\n")), 0o644))
+	offsets := make([]int64, len(lines))
+	for i := 1; i < len(lines); i++ {
+		offsets[i] = offsets[i-1] + int64(len(lines[i-1])+1)
+	}
+	item := func(idx int) view.RespAgentSearchItem {
+		return contextItem(path, lines[idx], offsets[idx])
+	}
+
+	req := func(idx int, before, after int) ContextRequest {
+		return ContextRequest{Path: path, Offset: offsets[idx], Sign: Sign(path, offsets[idx]), Before: before, After: after}
+	}
+
+	data, err := Context(req(3, 2, 2))
+	assert.NoError(t, err)
+	// the empty line is skipped
+	assert.Equal(t, []view.RespAgentSearchItem{item(1), item(2)}, data.Before)
+	assert.Equal(t, []view.RespAgentSearchItem{item(5), item(6)}, data.After)
+
+	// the first and the last lines have no lines before and after them
+	data, err = Context(req(0, 2, 1))
+	assert.NoError(t, err)
+	assert.Empty(t, data.Before)
+	assert.Equal(t, []view.RespAgentSearchItem{item(1)}, data.After)
+	data, err = Context(req(6, 1, 2))
+	assert.NoError(t, err)
+	assert.Equal(t, []view.RespAgentSearchItem{item(5)}, data.Before)
+	assert.Empty(t, data.After)
+
+	_, err = Context(ContextRequest{Path: path, Offset: offsets[3] + 1, Sign: Sign(path, offsets[3]+1)})
+	assert.Error(t, err)
+	end := offsets[6] + int64(len(lines[6]))
+	_, err = Context(ContextRequest{Path: path, Offset: end, Sign: Sign(path, end)})
+	assert.Error(t, err)
+
+	// the files and the offsets which were not searched can not be read
+	_, err = Context(ContextRequest{Path: path, Offset: offsets[3]})
+	assert.Error(t, err)
+	_, err = Context(ContextRequest{Path: path, Offset: offsets[3], Sign: Sign(path, offsets[2])})
+	assert.Error(t, err)
+	other := filepath.Join(t.TempDir(), "other.log")
+	assert.NoError(t, os.WriteFile(other, []byte(lines[0]), 0o644))
+	_, err = Context(ContextRequest{Path: other, Sign: Sign(path, 0)})
+	assert.Error(t, err)
+}
+
+func TestSearchable(t *testing.T) {
+	dir := t.TempDir()
+	outside := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "app.log"), nil, 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(outside, "secret"), nil, 0o644))
+	assert.NoError(t, os.Symlink(filepath.Join(outside, "secret"), filepath.Join(dir, "link.log")))
+	containers := []dto.AgentSearchTargetInfo{{FilePath: filepath.Join(outside, "secret")}}
+
+	assert.True(t, searchable(filepath.Join(dir, "app.log"), dir, nil))
+	assert.False(t, searchable(filepath.Join(dir, "..", filepath.Base(outside), "secret"), dir, nil))
+	assert.False(t, searchable(filepath.Join(dir, "link.log"), dir, nil))
+	assert.False(t, searchable(filepath.Join(outside, "secret"), "", nil))
+	assert.True(t, searchable(filepath.Join(dir, "link.log"), "", containers))
+}
@@ -3,6 +3,9 @@ package search
 import (
 	"os"
 	"path/filepath"
+	"strings"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/dto"
 )
 
 func findFiles(searchDir string) []string {
@@ -19,3 +22,36 @@ func findFiles(searchDir string) []string {
 
 	return arr
 }
+
+// searchable reports whether the file is in the dir, or is the log of one of the containers, after resolving the
+// symlinks of both, so that the _file_ of a keyword can not read the other files of the host.
+func searchable(path, dir string, containers []dto.AgentSearchTargetInfo) bool {
+	resolved, err := resolvePath(path)
+	if err != nil {
+		return false
+	}
+	if dir != "" {
+		root, errDir := resolvePath(dir)
+		if errDir != nil {
+			return false
+		}
+		rel, errRel := filepath.Rel(root, resolved)
+		if errRel == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	for _, c := range containers {
+		if p, errC := resolvePath(c.FilePath); errC == nil && p == resolved {
+			return true
+		}
+	}
+	return false
+}
+
+func resolvePath(path string) (string, error) {
+	abs, err := filepath.Abs(filepath.Clean(path))
+	if err != nil {
+		return "", err
+	}
+	return filepath.EvalSymlinks(abs)
+}
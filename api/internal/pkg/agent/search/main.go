@@ -46,6 +46,7 @@ type Component struct {
 	bash           *Bash
 	limit          int64
 	output         []string
+	offsets        []int64 // offset in the file of every line of output
 	commandOutput  []string
 	k8sInfo        *manager.ContainerInfo
 	interval       int64           // 请求 charts 时，划分的标准时间间隔
@@ -171,12 +172,15 @@ func (req *Request) prepare() error {
 	if err != nil {
 		return err
 	}
+	// the files of the keyword are checked against the searched dir and containers below
+	keywordPath := false
 	for _, value := range systemSearchArr {
 		switch value.Key {
 		case InnerKeyContainer:
 			req.K8SContainer = append(req.K8SContainer, value.ValueString)
 		case InnerKeyFile:
 			req.Path = value.ValueString
+			keywordPath = true
 		case InnerKeyNamespace:
 			req.Namespace = value.ValueString
 			// TODO 目前还没有针对 pod 进行过滤
@@ -210,10 +214,15 @@ func (req *Request) prepare() error {
 		}
 	}
 	if req.Path != "" {
+		containerPaths := filePaths
 		for _, p := range strings.Split(req.Path, ",") {
 			if strings.Contains(p, SkipPath) {
 				continue
 			}
+			if keywordPath && !req.IsCommand && !searchable(p, req.Dir, containerPaths) {
+				elog.Warn("agentRun", l.S("step", "fileOutOfSearch"), l.S("path", p), l.S("dir", req.Dir))
+				continue
+			}
 			filePaths = append(filePaths, dto.AgentSearchTargetInfo{
 				FilePath: p,
 			})
@@ -321,16 +330,18 @@ func Run(req Request) (data view.RespAgentSearch, err error) {
 		}
 	} else {
 		for _, comp := range container.components {
-			for _, value := range comp.output {
+			for idx, value := range comp.output {
 				if value == "" {
 					continue
 				}
 				ext := map[string]any{
-					"_file_":      comp.file.path,
-					"_namespace_": "",
-					"_container_": "",
-					"_pod_":       "",
-					"_image_":     "",
+					"_file_":       comp.file.path,
+					InnerKeyOffset: comp.offsets[idx],
+					InnerKeySign:   Sign(comp.file.path, comp.offsets[idx]),
+					"_namespace_":  "",
+					"_container_":  "",
+					"_pod_":        "",
+					"_image_":      "",
 				}
 				if comp.k8sInfo != nil {
 					ext["_namespace_"] = comp.k8sInfo.Namespace
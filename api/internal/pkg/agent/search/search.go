@@ -260,10 +260,13 @@ func (c *Component) searchLogs(startPos, endPos, remainedLines int64) (int64, er
 		_, _ = c.file.ptr.Seek(readStartPos, 0)
 		_, _ = c.file.ptr.Read(fileReader)
 
+		// base is the offset in the file of data[0]
+		base := readStartPos
 		if readStartPos <= 0 {
 			fileReader = fileReader[:now]
 			if includeFirstLine && readStartPos == 0 {
 				fileReader = append([]byte("\n"), fileReader...)
+				base = -1
 			}
 		}
 
@@ -277,7 +280,7 @@ func (c *Component) searchLogs(startPos, endPos, remainedLines int64) (int64, er
 			includeFileEnd = false
 		}
 
-		limit, before = c.doGetLogs(data, before, limit)
+		limit, before = c.doGetLogs(data, before, limit, base)
 
 		// clear data for next turn
 		data = data[0:0]
@@ -442,7 +445,8 @@ func (c *Component) calcPartitionInterval(n int, start, end int64) [][2]int64 {
 }
 
 // doGetLogs search from the tail to head
-func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines int64, beforeLine []byte) {
+// base is the offset in the file of data[0], the offsets of the lines are kept in c.offsets.
+func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64, base int64) (lines int64, beforeLine []byte) {
 	//		   br2        br1
 	// {xxxxxx}\n{xxxxxxx}\n{xxxxxx}
 	var (
@@ -460,6 +464,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 	if data[0] == '\n' {
 		beforeLine = append(beforeLine, '\n')
 		data = data[1:]
+		base++
 	} else {
 		firstLinePos := bytes.Index(data, []byte{'\n'})
 
@@ -471,6 +476,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 			return lines, beforeLine
 		}
 		data = data[firstLinePos+1:]
+		base += int64(firstLinePos + 1)
 	}
 
 	br1 = bytes.LastIndexByte(data, '\n')
@@ -486,7 +492,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 	if br2 == -1 {
 		_, ok, _ = c.verifyKeyWords(data[:br1], c.customSearches, br1, nil)
 		if ok {
-			c.output = append(c.output, string(data[:br1]))
+			c.appendOutput(data[:br1], base)
 			limit--
 		}
 		return limit, beforeLine
@@ -514,7 +520,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 						if p == -1 {
 							_, ok, _ = c.verifyKeyWords(data[:br1], c.customSearches, br1, nil)
 							if ok {
-								c.output = append(c.output, string(data[:br1]))
+								c.appendOutput(data[:br1], base)
 								limit--
 								if limit <= 0 {
 									return limit, nil
@@ -530,7 +536,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 		}
 
 		if flag {
-			c.output = append(c.output, string(data[br2+1:br1]))
+			c.appendOutput(data[br2+1:br1], base+int64(br2+1))
 			limit--
 			if limit <= 0 {
 				return limit, nil
@@ -541,7 +547,7 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 			if br2 == -1 {
 				_, ok, _ = c.verifyKeyWords(data[:br1], c.customSearches, br1, nil)
 				if ok {
-					c.output = append(c.output, string(data[:br1]))
+					c.appendOutput(data[:br1], base)
 					limit--
 					if limit <= 0 {
 						return limit, nil
@@ -554,6 +560,12 @@ func (c *Component) doGetLogs(data []byte, tailLine []byte, limit int64) (lines
 	return limit, beforeLine
 }
 
+// appendOutput keeps a matched line and its offset in the file.
+func (c *Component) appendOutput(line []byte, offset int64) {
+	c.output = append(c.output, string(line))
+	c.offsets = append(c.offsets, offset)
+}
+
 // doCalcLines calc match log lines
 // startPos: help to calc the line pos in the file
 // section: record the offset 、lines
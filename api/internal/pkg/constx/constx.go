@@ -6,6 +6,8 @@ const (
 	TableCreateTypeJSONAsString       int = 6
 	TableCreateTypeTraceCalculation   int = 4
 	TableCreateTypeBufferNullDataPipe int = 5
+	TableCreateTypeOTLPLogs           int = 7
+	TableCreateTypeOTLPTraces         int = 8
 
 	// Deprecated: TableCreateTypeCV
 	TableCreateTypeCV int = 0
@@ -32,6 +32,13 @@ const (
 	AlarmModeAggregationCheck
 )
 
+// NoDataOp is the status of an alarm whose filter returns no data.
+const (
+	NoDataOpDefault = 0 // unknown, the status is kept
+	NoDataOpOK      = 1
+	NoDataOpAlert   = 2
+)
+
 const (
 	AlarmStatusUnknown = iota
 	AlarmStatusClose
@@ -44,6 +51,8 @@ const (
 	RuleStoreTypeFile         = 1
 	RuleStoreTypeK8sConfigMap = 2
 	RuleStoreTypeK8sOperator  = 3
+	// RuleStoreTypeNative alarms are evaluated by clickvisual itself, prometheus is not required
+	RuleStoreTypeNative = 4
 )
 
 var UnitMap = map[int]UnitItem{
@@ -123,6 +132,11 @@ type Alarm struct {
 	Status           int           `gorm:"column:status;type:int(11)" json:"status"`                          // status
 	DutyOfficers     Ints          `gorm:"column:duty_officers;type:varchar(255)" json:"dutyOfficers"`        // duty officer id list
 	IsDisableResolve int           `gorm:"column:is_disable_resolve;type:tinyint(1)" json:"isDisableResolve"` // is disable resolve message
+	EscalationId     int           `gorm:"column:escalation_id;type:int(11)" json:"escalationId"`             // escalation policy, replaces duty officers when set
+	GroupBy          Strings       `gorm:"column:group_by;type:varchar(255)" json:"groupBy"`                  // alarm, table, instance or tag:<key>, empty means no grouping
+	GroupWait        int           `gorm:"column:group_wait;type:int(11)" json:"groupWait"`                   // seconds before the first digest of a group
+	GroupInterval    int           `gorm:"column:group_interval;type:int(11)" json:"groupInterval"`           // seconds between the digests of a changing group
+	RepeatInterval   int           `gorm:"column:repeat_interval;type:int(11)" json:"repeatInterval"`         // seconds before a digest without change is sent again
 
 	User *User `json:"user,omitempty" gorm:"foreignKey:uid;references:id"`
 
@@ -437,7 +451,7 @@ func AlarmDelete(db *gorm.DB, id int) (err error) {
 }
 
 type ReqAlertSettingUpdate struct {
-	RuleStoreType    int    `json:"ruleStoreType" form:"ruleStoreType"` // ruleStoreType 1 文件 2 configmap 3 prometheus operator
+	RuleStoreType    int    `json:"ruleStoreType" form:"ruleStoreType"` // ruleStoreType 1 文件 2 configmap 3 prometheus operator 4 native
 	PrometheusTarget string `json:"prometheusTarget" form:"prometheusTarget"`
 
 	// file
@@ -12,13 +12,15 @@ import (
 type AlarmCondition struct {
 	BaseModel
 
-	AlarmId        int `gorm:"column:alarm_id;type:int(11)" json:"alarmId"`              // alarm id
-	FilterId       int `gorm:"column:filter_id;type:int(11)" json:"filterId"`            // filter id
-	SetOperatorTyp int `gorm:"column:set_operator_typ;type:int(11);NOT NULL" json:"typ"` // 0 WHEN 1 AND 2 OR
-	SetOperatorExp int `gorm:"column:set_operator_exp;type:int(11);NOT NULL" json:"exp"` // 0 avg 1 min 2 max 3 sum 4 count
-	Cond           int `gorm:"column:cond;type:int(11)" json:"cond"`                     // 0 above 1 below 2 outside range 3 within range
-	Val1           int `gorm:"column:val_1;type:int(11)" json:"val1"`                    // 基准值/最小值
-	Val2           int `gorm:"column:val_2;type:int(11)" json:"val2"`                    // 最大值
+	AlarmId        int    `gorm:"column:alarm_id;type:int(11)" json:"alarmId"`              // alarm id
+	FilterId       int    `gorm:"column:filter_id;type:int(11)" json:"filterId"`            // filter id
+	SetOperatorTyp int    `gorm:"column:set_operator_typ;type:int(11);NOT NULL" json:"typ"` // 0 WHEN 1 AND 2 OR
+	SetOperatorExp int    `gorm:"column:set_operator_exp;type:int(11);NOT NULL" json:"exp"` // 0 avg 1 min 2 max 3 sum 4 count
+	Cond           int    `gorm:"column:cond;type:int(11)" json:"cond"`                     // 0 above 1 below 2 outside range 3 within range 4 percent change 5 z-score 6 new signature
+	Val1           int    `gorm:"column:val_1;type:int(11)" json:"val1"`                    // 基准值/最小值
+	Val2           int    `gorm:"column:val_2;type:int(11)" json:"val2"`                    // 最大值
+	Baseline       int    `gorm:"column:baseline;type:int(11)" json:"baseline"`             // seconds, baseline window of the anomaly conditions
+	Field          string `gorm:"column:field;type:varchar(128)" json:"field"`              // signature field of the new signature condition
 }
 
 func (m *AlarmCondition) TableName() string {
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+const (
+	DeliveryStatusPending = iota
+	DeliveryStatusSuccess
+	DeliveryStatusRetrying
+	DeliveryStatusDead
+)
+
+// AlarmDelivery is the delivery of a rendered alarm message to one channel.
+// Failed deliveries are retried with exponential backoff until the attempts run out, then they are dead
+// and can be replayed.
+type AlarmDelivery struct {
+	BaseModel
+
+	HistoryId  int              `gorm:"column:history_id;type:int(11);index" json:"historyId"`        // alarm history id, 0 for messages without history
+	HistoryIds Ints             `gorm:"column:history_ids;type:text" json:"historyIds"`               // alarm histories of a digest of several alerts
+	AlarmId    int              `gorm:"column:alarm_id;type:int(11);index" json:"alarmId"`            // alarm id
+	ChannelId  int              `gorm:"column:channel_id;type:int(11)" json:"channelId"`              // channel id
+	Msg        PushMsg          `gorm:"column:msg;type:longtext" json:"msg"`                          // rendered message
+	Status     int              `gorm:"column:status;type:int(11);index" json:"status"`               // 0 pending 1 success 2 retrying 3 dead
+	Attempts   int              `gorm:"column:attempts;type:int(11)" json:"attempts"`                 // attempts since the creation or the last replay
+	NextTime   int64            `gorm:"column:next_time;type:bigint(20)" json:"nextTime"`             // next retry time, unix second
+	LastError  string           `gorm:"column:last_error;type:text" json:"lastError"`                 // error of the last attempt
+	History    DeliveryAttempts `gorm:"column:history;type:longtext" json:"history"`                  // outcome of every attempt
+	Channel    *AlarmChannel    `json:"channel,omitempty" gorm:"foreignKey:channel_id;references:id"` // channel info
+}
+
+// DeliveryAttempt is the outcome of one attempt, Error is empty on success.
+type DeliveryAttempt struct {
+	Time  int64  `json:"time"`  // unix second
+	Cost  int64  `json:"cost"`  // milliseconds
+	Error string `json:"error"` // send error
+}
+
+type DeliveryAttempts []DeliveryAttempt
+
+func (t DeliveryAttempts) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *DeliveryAttempts) Scan(input interface{}) error {
+	in := scanBytes(input)
+	if len(in) == 0 {
+		in = []byte("[]")
+	}
+	return json.Unmarshal(in, t)
+}
+
+func (t PushMsg) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *PushMsg) Scan(input interface{}) error {
+	in := scanBytes(input)
+	if len(in) == 0 {
+		in = []byte("{}")
+	}
+	return json.Unmarshal(in, t)
+}
+
+// Histories returns the alarm histories settled by the delivery.
+func (m *AlarmDelivery) Histories() []int {
+	if len(m.HistoryIds) > 0 {
+		return m.HistoryIds
+	}
+	if m.HistoryId != 0 {
+		return []int{m.HistoryId}
+	}
+	return nil
+}
+
+// scanBytes accepts the text columns of the drivers which scan them as string.
+func scanBytes(input interface{}) []byte {
+	if s, ok := input.(string); ok {
+		return []byte(s)
+	}
+	in, _ := input.([]byte)
+	return in
+}
+
+type ReqListAlarmDelivery struct {
+	AlarmId   int  `json:"alarmId" form:"alarmId"`
+	HistoryId int  `json:"historyId" form:"historyId"`
+	Status    *int `json:"status" form:"status"` // empty means all, 3 lists the dead deliveries
+	ReqPage
+}
+
+func (m *AlarmDelivery) TableName() string {
+	return TableNameAlarmDelivery
+}
+
+func (m *AlarmDelivery) Create(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmDelivery{}).Create(m).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", m)
+	}
+	return
+}
+
+func (m *AlarmDelivery) Update(db *gorm.DB, ups map[string]interface{}) (err error) {
+	var sql = "`id`=?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmDelivery{}).Where(sql, binds...).Updates(ups).Error; err != nil {
+		return errors.Wrapf(err, "ups: %v", ups)
+	}
+	return
+}
+
+func (m *AlarmDelivery) Info(db *gorm.DB) (err error) {
+	var sql = "`id`= ?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmDelivery{}).Where(sql, binds...).First(m).Error; err != nil {
+		return errors.Wrapf(err, "id: %d", m.ID)
+	}
+	return
+}
+
+func (m *AlarmDelivery) List(db *gorm.DB, conds egorm.Conds) (resp []*AlarmDelivery, err error) {
+	resp = make([]*AlarmDelivery, 0)
+	sql, binds := egorm.BuildQuery(conds)
+	if err = db.Model(AlarmDelivery{}).Where(sql, binds...).Order("id asc").Find(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+func (m *AlarmDelivery) ListPage(db *gorm.DB, conds egorm.Conds, reqList *ReqPage) (total int64, respList []*AlarmDelivery) {
+	respList = make([]*AlarmDelivery, 0)
+	if reqList.PageSize == 0 {
+		reqList.PageSize = 10
+	}
+	if reqList.Current == 0 {
+		reqList.Current = 1
+	}
+	sql, binds := egorm.BuildQuery(conds)
+	query := db.Model(AlarmDelivery{}).Preload("Channel").Where(sql, binds...).Order("id desc")
+	query.Count(&total)
+	query.Offset((reqList.Current - 1) * reqList.PageSize).Limit(reqList.PageSize).Find(&respList)
+	return
+}
+
+// AlarmDeliveryStatusCount counts the deliveries of the history by status, including the digests containing it.
+func AlarmDeliveryStatusCount(db *gorm.DB, historyId int) (resp map[int]int64, err error) {
+	rows := make([]struct {
+		Status int
+		Count  int64
+	}, 0)
+	if err = db.Model(AlarmDelivery{}).Select("`status`, count(*) as `count`").
+		Where(fmt.Sprintf("`history_id` = ? OR JSON_CONTAINS(`history_ids`, '[%d]')", historyId), historyId).
+		Group("`status`").Scan(&rows).Error; err != nil {
+		return nil, errors.Wrapf(err, "history id: %d", historyId)
+	}
+	resp = make(map[int]int64, len(rows))
+	for _, row := range rows {
+		resp[row.Status] = row.Count
+	}
+	return
+}
@@ -0,0 +1,166 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrEscalationSteps = errors.New("escalation policy requires at least one step")
+	ErrEscalationDelay = errors.New("the first escalation step must have no delay and the delays must be ascending")
+	ErrEscalationInUse = errors.New("escalation policy is referenced by alarms")
+	ErrOncallInUse     = errors.New("on-call rotation is referenced by escalation policies")
+)
+
+// AlarmEscalation is an escalation policy. The first step is notified when an alert
+// starts firing, each following step is notified once its delay has passed and the
+// alert is still firing and not acknowledged.
+type AlarmEscalation struct {
+	BaseModel
+
+	Uid   int             `gorm:"column:uid;type:int(11)" json:"uid"`                 // creator
+	Name  string          `gorm:"column:name;type:varchar(128);NOT NULL" json:"name"` // name
+	Desc  string          `gorm:"column:desc;type:varchar(255);NOT NULL" json:"desc"` // description
+	Steps EscalationSteps `gorm:"column:steps;type:text" json:"steps"`                // steps in delay order
+}
+
+type EscalationStep struct {
+	Delay      int64 `json:"delay"`      // seconds after the alert starts firing
+	OncallIds  []int `json:"oncallIds"`  // rotations whose user on duty is notified
+	UserIds    []int `json:"userIds"`    // users notified
+	ChannelIds []int `json:"channelIds"` // channels notified besides the alarm channels
+}
+
+type EscalationSteps []EscalationStep
+
+func (t EscalationSteps) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *EscalationSteps) Scan(input interface{}) error {
+	in := scanBytes(input)
+	if len(in) == 0 {
+		in = []byte("[]")
+	}
+	return json.Unmarshal(in, t)
+}
+
+type ReqCreateAlarmEscalation struct {
+	Name  string           `json:"name" form:"name" binding:"required"`
+	Desc  string           `json:"desc" form:"desc"`
+	Steps []EscalationStep `json:"steps" form:"steps"`
+}
+
+func (r *ReqCreateAlarmEscalation) Valid() error {
+	if len(r.Steps) == 0 {
+		return ErrEscalationSteps
+	}
+	if r.Steps[0].Delay != 0 {
+		return ErrEscalationDelay
+	}
+	for i := 1; i < len(r.Steps); i++ {
+		if r.Steps[i].Delay <= r.Steps[i-1].Delay {
+			return ErrEscalationDelay
+		}
+	}
+	return nil
+}
+
+func (m *AlarmEscalation) TableName() string {
+	return TableNameAlarmEscalation
+}
+
+// DueStep returns the last step whose delay has passed after firing for elapsed, -1 if there is none.
+func (m *AlarmEscalation) DueStep(elapsed time.Duration) int {
+	step := -1
+	for i, s := range m.Steps {
+		if time.Duration(s.Delay)*time.Second <= elapsed {
+			step = i
+		}
+	}
+	return step
+}
+
+// IsUsingOncall reports whether any step notifies the on-call rotation.
+func (m *AlarmEscalation) IsUsingOncall(oncallId int) bool {
+	for _, s := range m.Steps {
+		for _, id := range s.OncallIds {
+			if id == oncallId {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// DutyOfficers returns the users notified by the step at t.
+func (s *EscalationStep) DutyOfficers(db *gorm.DB, t time.Time) (Ints, error) {
+	res := make(Ints, 0)
+	exists := make(map[int]struct{})
+	add := func(uid int) {
+		if _, ok := exists[uid]; ok || uid == 0 {
+			return
+		}
+		exists[uid] = struct{}{}
+		res = append(res, uid)
+	}
+	for _, oncallId := range s.OncallIds {
+		oncall := AlarmOncall{}
+		oncall.ID = oncallId
+		if err := oncall.Info(db); err != nil {
+			return nil, err
+		}
+		add(oncall.OnCall(t))
+	}
+	for _, uid := range s.UserIds {
+		add(uid)
+	}
+	return res, nil
+}
+
+func (m *AlarmEscalation) Create(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmEscalation{}).Create(m).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", m)
+	}
+	return
+}
+
+func (m *AlarmEscalation) Update(db *gorm.DB, ups map[string]interface{}) (err error) {
+	var sql = "`id`=?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmEscalation{}).Where(sql, binds...).Updates(ups).Error; err != nil {
+		return errors.Wrapf(err, "ups: %v", ups)
+	}
+	return
+}
+
+func (m *AlarmEscalation) Info(db *gorm.DB) (err error) {
+	var sql = "`id`= ?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmEscalation{}).Where(sql, binds...).First(m).Error; err != nil {
+		return errors.Wrapf(err, "id: %d", m.ID)
+	}
+	return
+}
+
+func (m *AlarmEscalation) List(db *gorm.DB, conds egorm.Conds) (resp []*AlarmEscalation, err error) {
+	resp = make([]*AlarmEscalation, 0)
+	sql, binds := egorm.BuildQuery(conds)
+	if err = db.Model(AlarmEscalation{}).Where(sql, binds...).Find(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+func (m *AlarmEscalation) Delete(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmEscalation{}).Unscoped().Delete(&AlarmEscalation{}, m.ID).Error; err != nil {
+		return errors.Wrapf(err, "id: %v", m.ID)
+	}
+	return
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlarmOncall_OnCall(t *testing.T) {
+	oncall := AlarmOncall{
+		Users:       Ints{1, 2, 3},
+		HandoffTime: 1000,
+		Period:      100,
+		Overrides:   OncallOverrides{{Uid: 9, StartTime: 1150, EndTime: 1160}},
+	}
+	tests := []struct {
+		name string
+		t    int64
+		want int
+	}{
+		{name: "before handoff", t: 500, want: 1},
+		{name: "first shift", t: 1099, want: 1},
+		{name: "second shift", t: 1100, want: 2},
+		{name: "override", t: 1155, want: 9},
+		{name: "after override", t: 1160, want: 2},
+		{name: "wrap around", t: 1300, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := oncall.OnCall(time.Unix(tt.t, 0)); got != tt.want {
+				t.Errorf("OnCall() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := (&AlarmOncall{}).OnCall(time.Now()); got != 0 {
+		t.Errorf("OnCall() of empty rotation = %v, want 0", got)
+	}
+}
+
+func TestAlarmEscalation_DueStep(t *testing.T) {
+	policy := AlarmEscalation{Steps: EscalationSteps{{Delay: 0}, {Delay: 900}, {Delay: 1800}}}
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int
+	}{
+		{name: "firing", elapsed: 0, want: 0},
+		{name: "before second", elapsed: 14 * time.Minute, want: 0},
+		{name: "second", elapsed: 15 * time.Minute, want: 1},
+		{name: "last", elapsed: time.Hour, want: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := policy.DueStep(tt.elapsed); got != tt.want {
+				t.Errorf("DueStep() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReqCreateAlarmEscalation_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		steps   []EscalationStep
+		wantErr bool
+	}{
+		{name: "ok", steps: []EscalationStep{{Delay: 0}, {Delay: 900}}},
+		{name: "empty", wantErr: true},
+		{name: "first delayed", steps: []EscalationStep{{Delay: 60}}, wantErr: true},
+		{name: "not ascending", steps: []EscalationStep{{Delay: 0}, {Delay: 900}, {Delay: 900}}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := ReqCreateAlarmEscalation{Name: "policy", Steps: tt.steps}
+			if err := req.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
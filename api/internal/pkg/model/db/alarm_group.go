@@ -0,0 +1,223 @@
+package db
+
+import (
+	"crypto/sha1"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	GroupByAlarm     = "alarm"
+	GroupByTable     = "table"
+	GroupByInstance  = "instance"
+	GroupByTagPrefix = "tag:"
+
+	DefaultGroupWait      = 30       // seconds
+	DefaultGroupInterval  = 300      // seconds
+	DefaultRepeatInterval = 4 * 3600 // seconds
+)
+
+var ErrGroupBy = errors.New("group by must be alarm, table, instance or tag:<key>")
+
+// AlarmGroup collects the alerts which share the values of the group by labels,
+// they are notified together in a digest message.
+// The first digest is sent GroupWait seconds after the group is created, then the changes are sent
+// every GroupInterval seconds, and the firing alerts are sent again after RepeatInterval seconds without change.
+type AlarmGroup struct {
+	BaseModel
+
+	GroupKey       string        `gorm:"column:group_key;type:varchar(64);NOT NULL;uniqueIndex" json:"groupKey"` // hash of the labels
+	Labels         String2String `gorm:"column:labels;type:text" json:"labels"`                                  // group by label values
+	GroupWait      int64         `gorm:"column:group_wait;type:bigint(20)" json:"groupWait"`                     // seconds
+	GroupInterval  int64         `gorm:"column:group_interval;type:bigint(20)" json:"groupInterval"`             // seconds
+	RepeatInterval int64         `gorm:"column:repeat_interval;type:bigint(20)" json:"repeatInterval"`           // seconds
+	NextFlush      int64         `gorm:"column:next_flush;type:bigint(20);index" json:"nextFlush"`               // unix second
+	LastFlush      int64         `gorm:"column:last_flush;type:bigint(20)" json:"lastFlush"`                     // unix second, 0 before the first digest
+	Members        GroupMembers  `gorm:"column:members;type:longtext" json:"members"`                            // alerts of the group
+}
+
+// GroupMember is an alert of a group, identified by its alarm and filter.
+type GroupMember struct {
+	AlarmId   int   `json:"alarmId"`
+	FilterId  int   `json:"filterId"`
+	HistoryId int   `json:"historyId"` // latest alarm history of the alert
+	Status    int   `json:"status"`    // AlarmStatusFiring or AlarmStatusNormal
+	StartsAt  int64 `json:"startsAt"`  // unix second
+	Notified  bool  `json:"notified"`  // the status was sent in a digest
+}
+
+type GroupMembers []GroupMember
+
+func (t GroupMembers) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *GroupMembers) Scan(input interface{}) error {
+	in := scanBytes(input)
+	if len(in) == 0 {
+		in = []byte("[]")
+	}
+	return json.Unmarshal(in, t)
+}
+
+// ValidGroupBy checks the group by labels of an alarm.
+func ValidGroupBy(groupBy []string) error {
+	for _, g := range groupBy {
+		switch {
+		case g == GroupByAlarm, g == GroupByTable, g == GroupByInstance:
+		case strings.HasPrefix(g, GroupByTagPrefix) && len(g) > len(GroupByTagPrefix):
+		default:
+			return errors.Wrap(ErrGroupBy, g)
+		}
+	}
+	return nil
+}
+
+// AlarmGroupKey returns the key of the group with the labels.
+func AlarmGroupKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, k+"="+labels[k])
+	}
+	sum := sha1.Sum([]byte(strings.Join(pairs, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// NewAlarmGroup returns the group of the alarm with the labels, the timings of the alarm are used.
+func NewAlarmGroup(alarm *Alarm, labels map[string]string, now time.Time) *AlarmGroup {
+	g := &AlarmGroup{
+		GroupKey:       AlarmGroupKey(labels),
+		Labels:         labels,
+		GroupWait:      int64(alarm.GroupWait),
+		GroupInterval:  int64(alarm.GroupInterval),
+		RepeatInterval: int64(alarm.RepeatInterval),
+		Members:        GroupMembers{},
+	}
+	if g.GroupWait <= 0 {
+		g.GroupWait = DefaultGroupWait
+	}
+	if g.GroupInterval <= 0 {
+		g.GroupInterval = DefaultGroupInterval
+	}
+	if g.RepeatInterval <= 0 {
+		g.RepeatInterval = DefaultRepeatInterval
+	}
+	g.NextFlush = now.Unix() + g.GroupWait
+	return g
+}
+
+func (m *AlarmGroup) TableName() string {
+	return TableNameAlarmGroup
+}
+
+// Add adds the alert to the group or updates its status.
+// It returns false when the alert is already in the group with the same status.
+func (m *AlarmGroup) Add(member GroupMember) bool {
+	for i, old := range m.Members {
+		if old.AlarmId != member.AlarmId || old.FilterId != member.FilterId {
+			continue
+		}
+		if old.Status == member.Status {
+			return false
+		}
+		m.Members[i] = member
+		return true
+	}
+	m.Members = append(m.Members, member)
+	return true
+}
+
+// Due tells whether a digest has to be sent at now: some alerts changed since the last digest,
+// or alerts are still firing after the repeat interval.
+func (m *AlarmGroup) Due(now time.Time) bool {
+	firing := false
+	for _, member := range m.Members {
+		if !member.Notified {
+			return true
+		}
+		if member.Status == AlarmStatusFiring {
+			firing = true
+		}
+	}
+	return firing && now.Unix()-m.LastFlush >= m.RepeatInterval
+}
+
+// Flush marks the alerts as notified, drops the resolved ones and schedules the next flush.
+// It returns the alerts to put in the digest, which are the alerts before the flush.
+func (m *AlarmGroup) Flush(now time.Time) GroupMembers {
+	digest := make(GroupMembers, len(m.Members))
+	copy(digest, m.Members)
+	remain := make(GroupMembers, 0, len(m.Members))
+	for _, member := range m.Members {
+		if member.Status == AlarmStatusFiring {
+			member.Notified = true
+			remain = append(remain, member)
+		}
+	}
+	m.Members = remain
+	m.LastFlush = now.Unix()
+	m.NextFlush = now.Unix() + m.GroupInterval
+	return digest
+}
+
+// AlarmGroupLock loads the group for update in the transaction, nil when it does not exist.
+func AlarmGroupLock(tx *gorm.DB, conds map[string]interface{}) (resp *AlarmGroup, err error) {
+	resp = &AlarmGroup{}
+	err = tx.Model(AlarmGroup{}).Clauses(clause.Locking{Strength: "UPDATE"}).Where(conds).First(resp).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+// AlarmGroupDueIds returns the ids of the groups to flush.
+func AlarmGroupDueIds(db *gorm.DB, now time.Time) (ids []int, err error) {
+	ids = make([]int, 0)
+	if err = db.Model(AlarmGroup{}).Where("`next_flush` <= ?", now.Unix()).Pluck("id", &ids).Error; err != nil {
+		return nil, errors.Wrap(err, "due groups")
+	}
+	return
+}
+
+func (m *AlarmGroup) Create(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmGroup{}).Create(m).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", m)
+	}
+	return
+}
+
+func (m *AlarmGroup) Save(db *gorm.DB) (err error) {
+	ups := map[string]interface{}{
+		"next_flush": m.NextFlush,
+		"last_flush": m.LastFlush,
+		"members":    m.Members,
+	}
+	if err = db.Model(AlarmGroup{}).Where("`id` = ?", m.ID).Updates(ups).Error; err != nil {
+		return errors.Wrapf(err, "id: %d", m.ID)
+	}
+	return
+}
+
+func (m *AlarmGroup) Delete(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmGroup{}).Unscoped().Delete(&AlarmGroup{}, m.ID).Error; err != nil {
+		return errors.Wrapf(err, "id: %v", m.ID)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestValidGroupBy(t *testing.T) {
+	tests := []struct {
+		groupBy []string
+		wantErr bool
+	}{
+		{nil, false},
+		{[]string{"alarm", "table", "instance", "tag:team"}, false},
+		{[]string{"tag:"}, true},
+		{[]string{"filter"}, true},
+	}
+	for _, tt := range tests {
+		if err := ValidGroupBy(tt.groupBy); (err != nil) != tt.wantErr {
+			t.Errorf("ValidGroupBy(%v) error = %v, wantErr %v", tt.groupBy, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAlarmGroupKey(t *testing.T) {
+	a := AlarmGroupKey(map[string]string{"table": "1", "tag:team": "infra"})
+	b := AlarmGroupKey(map[string]string{"tag:team": "infra", "table": "1"})
+	if a != b {
+		t.Errorf("AlarmGroupKey depends on the map order: %s != %s", a, b)
+	}
+	if a == AlarmGroupKey(map[string]string{"table": "2", "tag:team": "infra"}) {
+		t.Errorf("AlarmGroupKey is equal for different labels")
+	}
+}
+
+func TestAlarmGroup_Flush(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	g := NewAlarmGroup(&Alarm{GroupWait: 30, GroupInterval: 300, RepeatInterval: 3600}, map[string]string{"table": "1"}, now)
+	if g.NextFlush != now.Unix()+30 {
+		t.Fatalf("NextFlush = %d, want group wait", g.NextFlush)
+	}
+	if !g.Add(GroupMember{AlarmId: 1, FilterId: 1, Status: AlarmStatusFiring}) || !g.Add(GroupMember{AlarmId: 1, FilterId: 2, Status: AlarmStatusFiring}) {
+		t.Fatalf("Add new alerts should change the group")
+	}
+	if g.Add(GroupMember{AlarmId: 1, FilterId: 1, Status: AlarmStatusFiring}) {
+		t.Errorf("Add the same status should not change the group")
+	}
+	now = now.Add(30 * time.Second)
+	if !g.Due(now) {
+		t.Fatalf("new alerts should be due")
+	}
+	if digest := g.Flush(now); len(digest) != 2 {
+		t.Fatalf("digest has %d alerts, want 2", len(digest))
+	}
+	if g.Due(now.Add(300 * time.Second)) {
+		t.Errorf("notified alerts should not be due before the repeat interval")
+	}
+	if !g.Due(now.Add(3600 * time.Second)) {
+		t.Errorf("firing alerts should be due after the repeat interval")
+	}
+	g.Add(GroupMember{AlarmId: 1, FilterId: 2, Status: AlarmStatusNormal})
+	if !g.Due(now.Add(300 * time.Second)) {
+		t.Fatalf("resolved alerts should be due")
+	}
+	digest := g.Flush(now.Add(300 * time.Second))
+	if len(digest) != 2 || len(g.Members) != 1 || g.Members[0].FilterId != 1 {
+		t.Errorf("resolved alerts should be sent once then dropped, digest %v members %v", digest, g.Members)
+	}
+	if g.NextFlush != now.Unix()+600 {
+		t.Errorf("NextFlush = %d, want last flush + group interval", g.NextFlush)
+	}
+}
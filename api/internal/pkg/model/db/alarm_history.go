@@ -14,6 +14,9 @@ const (
 	PushedStatusRepeat = iota
 	PushedStatusSuccess
 	PushedStatusFail
+	PushedStatusSilenced
+	PushedStatusRetrying // some deliveries are waiting for a retry
+	PushedStatusGrouped  // waiting for the digest of its alert group
 )
 
 // AlarmHistory 告警渠道
@@ -23,7 +26,12 @@ type AlarmHistory struct {
 	AlarmId      int `gorm:"column:alarm_id;type:int(11)" json:"alarmId"`   // alarm id
 	FilterId     int `gorm:"column:filter_id;type:int(11)" json:"filterId"` // filter id
 	FilterStatus int `gorm:"column:filter_status;type:int(11)" json:"filterStatus"`
-	IsPushed     int `gorm:"column:is_pushed;type:int(11)" json:"isPushed"` // alarm id
+	IsPushed     int `gorm:"column:is_pushed;type:int(11)" json:"isPushed"`   // alarm id
+	SilenceId    int `gorm:"column:silence_id;type:int(11)" json:"silenceId"` // silence which muted the notification
+
+	EscalationStep int   `gorm:"column:escalation_step;type:int(11)" json:"escalationStep"` // last notified escalation step
+	AckUid         int   `gorm:"column:ack_uid;type:int(11)" json:"ackUid"`                 // user who acknowledged the alert
+	AckTime        int64 `gorm:"column:ack_time;type:bigint(20)" json:"ackTime"`            // acknowledge time, stops the escalation
 }
 
 func (m *AlarmHistory) TableName() string {
@@ -71,3 +79,22 @@ func AlarmHistoryUpdate(db *gorm.DB, id int, ups map[string]interface{}) (err er
 	}
 	return
 }
+
+func AlarmHistoryList(db *gorm.DB, conds egorm.Conds) (resp []*AlarmHistory, err error) {
+	sql, binds := egorm.BuildQuery(conds)
+	if err = db.Model(AlarmHistory{}).Where(sql, binds...).Find(&resp).Error; err != nil {
+		return nil, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+// AlarmHistoryAck acknowledges the firing alerts of an alarm, it returns the number of acknowledged histories.
+func AlarmHistoryAck(db *gorm.DB, alarmId int, uid int, ackTime int64) (int64, error) {
+	res := db.Model(AlarmHistory{}).
+		Where("`alarm_id` = ? AND `filter_status` = ? AND `ack_time` = 0", alarmId, AlarmStatusFiring).
+		Updates(map[string]interface{}{"ack_uid": uid, "ack_time": ackTime})
+	if res.Error != nil {
+		return 0, errors.Wrapf(res.Error, "alarm id: %d", alarmId)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrOncallUsers    = errors.New("on-call rotation requires at least one user")
+	ErrOncallPeriod   = errors.New("on-call rotation requires a handoff period")
+	ErrOncallOverride = errors.New("on-call override requires a user and a valid time range")
+)
+
+// AlarmOncall is an on-call rotation, Users take turns every Period seconds from HandoffTime.
+// Overrides replace the user on duty in their time range.
+type AlarmOncall struct {
+	BaseModel
+
+	Uid         int             `gorm:"column:uid;type:int(11)" json:"uid"`                     // creator
+	Name        string          `gorm:"column:name;type:varchar(128);NOT NULL" json:"name"`     // name
+	Desc        string          `gorm:"column:desc;type:varchar(255);NOT NULL" json:"desc"`     // description
+	Users       Ints            `gorm:"column:users;type:text" json:"users"`                    // user id list in rotation order
+	HandoffTime int64           `gorm:"column:handoff_time;type:bigint(20)" json:"handoffTime"` // unix second of the first handoff
+	Period      int64           `gorm:"column:period;type:bigint(20)" json:"period"`            // seconds between handoffs
+	Overrides   OncallOverrides `gorm:"column:overrides;type:text" json:"overrides"`            // temporary replacements
+}
+
+type OncallOverride struct {
+	Uid       int   `json:"uid"`
+	StartTime int64 `json:"startTime"` // unix second
+	EndTime   int64 `json:"endTime"`   // unix second
+}
+
+type OncallOverrides []OncallOverride
+
+func (t OncallOverrides) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *OncallOverrides) Scan(input interface{}) error {
+	in := scanBytes(input)
+	if len(in) == 0 {
+		in = []byte("[]")
+	}
+	return json.Unmarshal(in, t)
+}
+
+type ReqCreateAlarmOncall struct {
+	Name        string           `json:"name" form:"name" binding:"required"`
+	Desc        string           `json:"desc" form:"desc"`
+	Users       []int            `json:"users" form:"users"`
+	HandoffTime int64            `json:"handoffTime" form:"handoffTime"`
+	Period      int64            `json:"period" form:"period"`
+	Overrides   []OncallOverride `json:"overrides" form:"overrides"`
+}
+
+func (r *ReqCreateAlarmOncall) Valid() error {
+	if len(r.Users) == 0 {
+		return ErrOncallUsers
+	}
+	if r.Period <= 0 {
+		return ErrOncallPeriod
+	}
+	for _, o := range r.Overrides {
+		if o.Uid == 0 || o.EndTime <= o.StartTime {
+			return ErrOncallOverride
+		}
+	}
+	return nil
+}
+
+func (m *AlarmOncall) TableName() string {
+	return TableNameAlarmOncall
+}
+
+// OnCall returns the id of the user on duty at t, 0 if the rotation is empty.
+func (m *AlarmOncall) OnCall(t time.Time) int {
+	for _, o := range m.Overrides {
+		if t.Unix() >= o.StartTime && t.Unix() < o.EndTime {
+			return o.Uid
+		}
+	}
+	if len(m.Users) == 0 {
+		return 0
+	}
+	if m.Period <= 0 || t.Unix() < m.HandoffTime {
+		return m.Users[0]
+	}
+	shift := (t.Unix() - m.HandoffTime) / m.Period
+	return m.Users[shift%int64(len(m.Users))]
+}
+
+func (m *AlarmOncall) Create(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmOncall{}).Create(m).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", m)
+	}
+	return
+}
+
+func (m *AlarmOncall) Update(db *gorm.DB, ups map[string]interface{}) (err error) {
+	var sql = "`id`=?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmOncall{}).Where(sql, binds...).Updates(ups).Error; err != nil {
+		return errors.Wrapf(err, "ups: %v", ups)
+	}
+	return
+}
+
+func (m *AlarmOncall) Info(db *gorm.DB) (err error) {
+	var sql = "`id`= ?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmOncall{}).Where(sql, binds...).First(m).Error; err != nil {
+		return errors.Wrapf(err, "id: %d", m.ID)
+	}
+	return
+}
+
+func (m *AlarmOncall) List(db *gorm.DB, conds egorm.Conds) (resp []*AlarmOncall, err error) {
+	resp = make([]*AlarmOncall, 0)
+	sql, binds := egorm.BuildQuery(conds)
+	if err = db.Model(AlarmOncall{}).Where(sql, binds...).Find(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+func (m *AlarmOncall) Delete(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmOncall{}).Unscoped().Delete(&AlarmOncall{}, m.ID).Error; err != nil {
+		return errors.Wrapf(err, "id: %v", m.ID)
+	}
+	return
+}
@@ -0,0 +1,183 @@
+package db
+
+import (
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"github.com/robfig/cron/v3"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSilenceTarget   = errors.New("silence requires an alarm id or tag matchers")
+	ErrSilenceTime     = errors.New("silence end time must be later than start time")
+	ErrSilenceDuration = errors.New("recurring silence requires a duration")
+)
+
+// AlarmSilence mutes the notifications of the matched alarms in a time window.
+// A silence without cron is active in [StartTime, EndTime).
+// A silence with cron is active for Duration seconds from every cron activation,
+// StartTime and EndTime limit the period in which the windows are effective, EndTime 0 means forever.
+type AlarmSilence struct {
+	BaseModel
+
+	Uid       int           `gorm:"column:uid;type:int(11)" json:"uid"`                     // creator
+	AlarmId   int           `gorm:"column:alarm_id;type:int(11);index" json:"alarmId"`      // alarm id, 0 means matched by tags
+	Matchers  String2String `gorm:"column:matchers;type:text" json:"matchers"`              // alarm tags which must all be equal
+	StartTime int64         `gorm:"column:start_time;type:bigint(20)" json:"startTime"`     // unix second
+	EndTime   int64         `gorm:"column:end_time;type:bigint(20)" json:"endTime"`         // unix second
+	Cron      string        `gorm:"column:cron;type:varchar(128)" json:"cron"`              // recurring window start, e.g. 0 2 * * 0
+	Duration  int64         `gorm:"column:duration;type:bigint(20)" json:"duration"`        // recurring window length in seconds
+	Reason    string        `gorm:"column:reason;type:varchar(255);NOT NULL" json:"reason"` // reason
+	User      *User         `json:"user,omitempty" gorm:"foreignKey:uid;references:id"`     // creator info
+}
+
+type ReqCreateAlarmSilence struct {
+	AlarmId   int               `json:"alarmId" form:"alarmId"`
+	Matchers  map[string]string `json:"matchers" form:"matchers"`
+	StartTime int64             `json:"startTime" form:"startTime"`
+	EndTime   int64             `json:"endTime" form:"endTime"`
+	Cron      string            `json:"cron" form:"cron"`
+	Duration  int64             `json:"duration" form:"duration"`
+	Reason    string            `json:"reason" form:"reason"`
+}
+
+type ReqListAlarmSilence struct {
+	AlarmId int `json:"alarmId" form:"alarmId"`
+	ReqPage
+}
+
+func (r *ReqCreateAlarmSilence) Valid() error {
+	if r.AlarmId == 0 && len(r.Matchers) == 0 {
+		return ErrSilenceTarget
+	}
+	if r.Cron == "" {
+		if r.EndTime <= r.StartTime {
+			return ErrSilenceTime
+		}
+		return nil
+	}
+	if _, err := cron.ParseStandard(r.Cron); err != nil {
+		return errors.Wrapf(err, "cron: %s", r.Cron)
+	}
+	if r.Duration <= 0 {
+		return ErrSilenceDuration
+	}
+	if r.EndTime != 0 && r.EndTime <= r.StartTime {
+		return ErrSilenceTime
+	}
+	return nil
+}
+
+func (m *AlarmSilence) TableName() string {
+	return TableNameAlarmSilence
+}
+
+// IsActive reports whether the silence mutes notifications at t.
+func (m *AlarmSilence) IsActive(t time.Time) bool {
+	if t.Unix() < m.StartTime {
+		return false
+	}
+	if m.Cron == "" {
+		return t.Unix() < m.EndTime
+	}
+	if m.EndTime != 0 && t.Unix() >= m.EndTime {
+		return false
+	}
+	schedule, err := cron.ParseStandard(m.Cron)
+	if err != nil {
+		return false
+	}
+	// the latest activation in (t-duration, t] opens the current window
+	window := time.Duration(m.Duration) * time.Second
+	return !schedule.Next(t.Add(-window)).After(t)
+}
+
+// IsMatch reports whether the silence applies to the alarm.
+func (m *AlarmSilence) IsMatch(alarm *Alarm) bool {
+	if m.AlarmId != 0 {
+		return m.AlarmId == alarm.ID
+	}
+	if len(m.Matchers) == 0 {
+		return false
+	}
+	for k, v := range m.Matchers {
+		if tag, ok := alarm.Tags[k]; !ok || tag != v {
+			return false
+		}
+	}
+	return true
+}
+
+func (m *AlarmSilence) Create(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmSilence{}).Create(m).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", m)
+	}
+	return
+}
+
+func (m *AlarmSilence) Update(db *gorm.DB, ups map[string]interface{}) (err error) {
+	var sql = "`id`=?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmSilence{}).Where(sql, binds...).Updates(ups).Error; err != nil {
+		return errors.Wrapf(err, "ups: %v", ups)
+	}
+	return
+}
+
+func (m *AlarmSilence) Info(db *gorm.DB) (err error) {
+	var sql = "`id`= ?"
+	var binds = []interface{}{m.ID}
+	if err = db.Model(AlarmSilence{}).Where(sql, binds...).First(m).Error; err != nil {
+		return errors.Wrapf(err, "id: %d", m.ID)
+	}
+	return
+}
+
+func (m *AlarmSilence) List(db *gorm.DB, conds egorm.Conds) (resp []*AlarmSilence, err error) {
+	resp = make([]*AlarmSilence, 0)
+	sql, binds := egorm.BuildQuery(conds)
+	if err = db.Model(AlarmSilence{}).Where(sql, binds...).Find(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "conds: %v", conds)
+	}
+	return
+}
+
+func (m *AlarmSilence) ListPage(db *gorm.DB, conds egorm.Conds, reqList *ReqPage) (total int64, respList []*AlarmSilence) {
+	respList = make([]*AlarmSilence, 0)
+	if reqList.PageSize == 0 {
+		reqList.PageSize = 10
+	}
+	if reqList.Current == 0 {
+		reqList.Current = 1
+	}
+	sql, binds := egorm.BuildQuery(conds)
+	query := db.Model(AlarmSilence{}).Preload("User").Where(sql, binds...).Order("id desc")
+	query.Count(&total)
+	query.Offset((reqList.Current - 1) * reqList.PageSize).Limit(reqList.PageSize).Find(&respList)
+	return
+}
+
+func (m *AlarmSilence) Delete(db *gorm.DB) (err error) {
+	if err = db.Model(AlarmSilence{}).Unscoped().Delete(&AlarmSilence{}, m.ID).Error; err != nil {
+		return errors.Wrapf(err, "id: %v", m.ID)
+	}
+	return
+}
+
+// AlarmSilenceMatch returns the first silence that mutes the alarm at t, nil if there is none.
+func AlarmSilenceMatch(db *gorm.DB, alarm *Alarm, t time.Time) (*AlarmSilence, error) {
+	list := make([]*AlarmSilence, 0)
+	if err := db.Model(AlarmSilence{}).
+		Where("`alarm_id` IN (?, 0) AND `start_time` <= ? AND (`end_time` = 0 OR `end_time` > ?)", alarm.ID, t.Unix(), t.Unix()).
+		Find(&list).Error; err != nil {
+		return nil, errors.Wrapf(err, "alarm id: %d", alarm.ID)
+	}
+	for _, silence := range list {
+		if silence.IsMatch(alarm) && silence.IsActive(t) {
+			return silence, nil
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,112 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAlarmSilence_IsActive(t *testing.T) {
+	// 2023-11-05 is a Sunday
+	sunday := time.Date(2023, 11, 5, 0, 0, 0, 0, time.Local)
+	tests := []struct {
+		name    string
+		silence AlarmSilence
+		t       time.Time
+		want    bool
+	}{
+		{
+			name:    "window",
+			silence: AlarmSilence{StartTime: sunday.Unix(), EndTime: sunday.Add(time.Hour).Unix()},
+			t:       sunday.Add(time.Minute),
+			want:    true,
+		},
+		{
+			name:    "window expired",
+			silence: AlarmSilence{StartTime: sunday.Unix(), EndTime: sunday.Add(time.Hour).Unix()},
+			t:       sunday.Add(time.Hour),
+			want:    false,
+		},
+		{
+			name:    "recurring inside",
+			silence: AlarmSilence{Cron: "0 2 * * 0", Duration: 7200},
+			t:       sunday.Add(3 * time.Hour),
+			want:    true,
+		},
+		{
+			name:    "recurring start",
+			silence: AlarmSilence{Cron: "0 2 * * 0", Duration: 7200},
+			t:       sunday.Add(2 * time.Hour),
+			want:    true,
+		},
+		{
+			name:    "recurring outside",
+			silence: AlarmSilence{Cron: "0 2 * * 0", Duration: 7200},
+			t:       sunday.Add(4 * time.Hour),
+			want:    false,
+		},
+		{
+			name:    "recurring other day",
+			silence: AlarmSilence{Cron: "0 2 * * 0", Duration: 7200},
+			t:       sunday.Add(27 * time.Hour),
+			want:    false,
+		},
+		{
+			name:    "recurring ended",
+			silence: AlarmSilence{Cron: "0 2 * * 0", Duration: 7200, EndTime: sunday.Unix()},
+			t:       sunday.Add(3 * time.Hour),
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.silence.IsActive(tt.t); got != tt.want {
+				t.Errorf("IsActive() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAlarmSilence_IsMatch(t *testing.T) {
+	alarm := &Alarm{Tags: String2String{"env": "prod", "team": "infra"}}
+	alarm.ID = 1
+	tests := []struct {
+		name    string
+		silence AlarmSilence
+		want    bool
+	}{
+		{name: "alarm id", silence: AlarmSilence{AlarmId: 1}, want: true},
+		{name: "other alarm id", silence: AlarmSilence{AlarmId: 2}, want: false},
+		{name: "matchers", silence: AlarmSilence{Matchers: String2String{"env": "prod"}}, want: true},
+		{name: "matchers mismatch", silence: AlarmSilence{Matchers: String2String{"env": "prod", "team": "app"}}, want: false},
+		{name: "no target", silence: AlarmSilence{}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.silence.IsMatch(alarm); got != tt.want {
+				t.Errorf("IsMatch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReqCreateAlarmSilence_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ReqCreateAlarmSilence
+		wantErr bool
+	}{
+		{name: "window", req: ReqCreateAlarmSilence{AlarmId: 1, StartTime: 1, EndTime: 2}},
+		{name: "no target", req: ReqCreateAlarmSilence{StartTime: 1, EndTime: 2}, wantErr: true},
+		{name: "end before start", req: ReqCreateAlarmSilence{AlarmId: 1, StartTime: 2, EndTime: 1}, wantErr: true},
+		{name: "recurring", req: ReqCreateAlarmSilence{Matchers: map[string]string{"env": "prod"}, Cron: "0 2 * * 0", Duration: 7200}},
+		{name: "bad cron", req: ReqCreateAlarmSilence{AlarmId: 1, Cron: "x", Duration: 7200}, wantErr: true},
+		{name: "no duration", req: ReqCreateAlarmSilence{AlarmId: 1, Cron: "0 2 * * 0"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
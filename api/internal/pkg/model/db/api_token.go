@@ -0,0 +1,48 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// ApiToken authenticates the read APIs of the tools on behalf of a user, only the sha256 of the token is kept.
+type ApiToken struct {
+	BaseModel
+
+	Uid   int    `gorm:"column:uid;type:int(11);index" json:"uid"`
+	Name  string `gorm:"column:name;type:varchar(128)" json:"name"`
+	Token string `gorm:"column:token;type:char(64);NOT NULL;uniqueIndex" json:"-"`
+}
+
+func (m *ApiToken) TableName() string {
+	return TableNameApiToken
+}
+
+func ApiTokenCreate(db *gorm.DB, data *ApiToken) (err error) {
+	if err = db.Model(ApiToken{}).Create(data).Error; err != nil {
+		return errors.Wrapf(err, "uid: %d", data.Uid)
+	}
+	return
+}
+
+// ApiTokenInfoX returns the token of the sha256.
+func ApiTokenInfoX(db *gorm.DB, token string) (resp ApiToken, err error) {
+	if err = db.Model(ApiToken{}).Where("`token` = ?", token).First(&resp).Error; err != nil {
+		return resp, errors.Wrap(err, "api token")
+	}
+	return
+}
+
+func ApiTokenList(db *gorm.DB, uid int) (resp []*ApiToken, err error) {
+	if err = db.Model(ApiToken{}).Where("`uid` = ?", uid).Order("`id` desc").Find(&resp).Error; err != nil {
+		return nil, errors.Wrapf(err, "uid: %d", uid)
+	}
+	return
+}
+
+func ApiTokenDelete(db *gorm.DB, uid, id int) (err error) {
+	if err = db.Model(ApiToken{}).Where("`uid` = ? AND `id` = ?", uid, id).Unscoped().Delete(&ApiToken{}).Error; err != nil {
+		return errors.Wrapf(err, "uid: %d, id: %d", uid, id)
+	}
+	return
+}
@@ -33,7 +33,7 @@ type BaseInstance struct {
 
 	// alarm
 	PrometheusTarget string `gorm:"column:prometheus_target;type:varchar(128)" json:"prometheusTarget"` // prometheus ip or domain, eg: https://prometheus:9090
-	RuleStoreType    int    `gorm:"column:rule_store_type;type:int(11)" json:"ruleStoreType"`           // rule_store_type 1 文件 2 集群
+	RuleStoreType    int    `gorm:"column:rule_store_type;type:int(11)" json:"ruleStoreType"`           // rule_store_type 1 文件 2 集群 3 prometheus operator 4 native
 	// file
 	FilePath string `gorm:"column:file_path;type:varchar(255)" json:"filePath"` // file_path
 	// configmap
@@ -42,6 +42,9 @@ type BaseInstance struct {
 	K8sConfigmap string `gorm:"column:configmap;type:varchar(128)" json:"configmap"` // configmap
 	// operator
 	ConfigPrometheusOperator string `gorm:"column:config_prometheus_operator;type:text" json:"ConfigPrometheusOperator"` // configmap
+	// query jobs
+	QueryMaxConcurrency   int `gorm:"column:query_max_concurrency;type:int(11)" json:"queryMaxConcurrency"`      // running query jobs of a user, 0 is unlimited
+	QueryMaxExecutionTime int `gorm:"column:query_max_execution_time;type:int(11)" json:"queryMaxExecutionTime"` // seconds of a query job, 0 is unlimited
 }
 
 func (b *BaseInstance) TableName() string {
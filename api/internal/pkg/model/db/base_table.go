@@ -40,6 +40,10 @@ type BaseTable struct {
 	RawLogField             string `gorm:"column:raw_log_field;type:varchar(255)" json:"rawLogField"`
 	KafkaSkipBrokenMessages int    `gorm:"column:kafka_skip_broken_messages;type:int(11)" json:"kafkaSkipBrokenMessages"`
 
+	// reader setting, the Kafka reader uses the columns above
+	ReaderType     string         `gorm:"column:reader_type;type:varchar(32);NOT NULL;default:''" json:"readerType"` // kafka when empty
+	ReaderSettings ReaderSettings `gorm:"column:reader_settings;type:text" json:"-"`
+
 	// Deprecated: use CreateType instead
 	IsKafkaTimestamp int `gorm:"column:is_kafka_timestamp;type:tinyint(1)" json:"isKafkaTimestamp"`
 	// Deprecated: use CreateType instead
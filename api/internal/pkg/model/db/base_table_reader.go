@@ -0,0 +1,200 @@
+package db
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+)
+
+// Reader types, the engines reading the logs into the stream table of a table.
+const (
+	ReaderTypeKafka    = "kafka"
+	ReaderTypeRabbitMQ = "rabbitmq"
+	ReaderTypeNATS     = "nats"
+	ReaderTypeS3Queue  = "s3queue"
+	ReaderTypeImport   = "import" // periodic import of the files of S3, URLs or the user files of the server
+)
+
+// Import sources of ReaderTypeImport.
+const (
+	ReaderImportSourceS3   = "s3"
+	ReaderImportSourceURL  = "url"
+	ReaderImportSourceFile = "file"
+)
+
+// Intervals of ReaderTypeImport in seconds.
+const (
+	ReaderImportDefaultInterval = 300
+	ReaderImportMinInterval     = 60
+)
+
+// ReaderSecretMask replaces the secrets of the readers in the responses,
+// an update sending it back keeps the current secret.
+const ReaderSecretMask = "******"
+
+// ReaderSettings holds the settings of the readers other than Kafka, whose settings are the columns of the table.
+type ReaderSettings struct {
+	RabbitMQ *ReaderRabbitMQ `json:"rabbitmq,omitempty"`
+	NATS     *ReaderNATS     `json:"nats,omitempty"`
+	S3Queue  *ReaderS3Queue  `json:"s3queue,omitempty"`
+	Import   *ReaderImport   `json:"import,omitempty"`
+}
+
+type ReaderRabbitMQ struct {
+	HostPort           string `json:"hostPort"`     // host:port
+	ExchangeName       string `json:"exchangeName"` // the exchange the queues are bound to
+	ExchangeType       string `json:"exchangeType"` // direct, fanout, topic, headers or consistent_hash, fanout by default
+	RoutingKeys        string `json:"routingKeys"`  // comma separated
+	Vhost              string `json:"vhost"`
+	Username           string `json:"username"`
+	Password           string `json:"password"`
+	NumConsumers       int    `json:"numConsumers"`
+	SkipBrokenMessages int    `json:"skipBrokenMessages"`
+}
+
+type ReaderNATS struct {
+	URL                string `json:"url"`        // host:port, comma separated
+	Subjects           string `json:"subjects"`   // comma separated
+	QueueGroup         string `json:"queueGroup"` // database_table by default
+	Username           string `json:"username"`
+	Password           string `json:"password"`
+	Token              string `json:"token"`
+	NumConsumers       int    `json:"numConsumers"`
+	SkipBrokenMessages int    `json:"skipBrokenMessages"`
+}
+
+type ReaderS3Queue struct {
+	Path              string `json:"path"` // http(s) url of the files with globs, e.g. https://bucket.s3.amazonaws.com/logs/*.json
+	AccessKeyID       string `json:"accessKeyId"`
+	SecretAccessKey   string `json:"secretAccessKey"`
+	Mode              string `json:"mode"`        // unordered or ordered, unordered by default
+	Compression       string `json:"compression"` // auto by default
+	ProcessingThreads int    `json:"processingThreads"`
+}
+
+type ReaderImport struct {
+	Source          string `json:"source"` // s3, url or file
+	Path            string `json:"path"`   // url or path in the user files of the server, with globs
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+	Compression     string `json:"compression"` // auto by default
+	Interval        int    `json:"interval"`    // seconds between the imports, 300 by default
+}
+
+func (t ReaderSettings) Value() (driver.Value, error) {
+	b, err := json.Marshal(t)
+	return string(b), err
+}
+
+func (t *ReaderSettings) Scan(input interface{}) error {
+	var in []byte
+	switch v := input.(type) {
+	case []byte:
+		in = v
+	case string:
+		in = []byte(v)
+	}
+	if len(in) == 0 {
+		return nil
+	}
+	return json.Unmarshal(in, t)
+}
+
+// GetInterval returns the seconds between the imports.
+func (t *ReaderImport) GetInterval() int {
+	if t.Interval == 0 {
+		return ReaderImportDefaultInterval
+	}
+	return t.Interval
+}
+
+// GetReaderType returns the reader type of the table, the tables created before the readers read Kafka.
+func (b *BaseTable) GetReaderType() string {
+	if b.ReaderType == "" {
+		return ReaderTypeKafka
+	}
+	return b.ReaderType
+}
+
+// secrets returns the pointers to the secrets of the settings.
+func (t *ReaderSettings) secrets() []*string {
+	res := make([]*string, 0)
+	if t.RabbitMQ != nil {
+		res = append(res, &t.RabbitMQ.Password)
+	}
+	if t.NATS != nil {
+		res = append(res, &t.NATS.Password, &t.NATS.Token)
+	}
+	if t.S3Queue != nil {
+		res = append(res, &t.S3Queue.SecretAccessKey)
+	}
+	if t.Import != nil {
+		res = append(res, &t.Import.SecretAccessKey)
+	}
+	return res
+}
+
+// Masked returns a copy of the settings with the secrets replaced by ReaderSecretMask.
+func (t ReaderSettings) Masked() ReaderSettings {
+	res := t.clone()
+	for _, secret := range res.secrets() {
+		if *secret != "" {
+			*secret = ReaderSecretMask
+		}
+	}
+	return res
+}
+
+// MaskSQL replaces the secrets of the settings in the sql of the reader.
+func (t ReaderSettings) MaskSQL(sql string) string {
+	for _, secret := range t.secrets() {
+		if *secret != "" {
+			sql = strings.ReplaceAll(sql, *secret, ReaderSecretMask)
+		}
+	}
+	return sql
+}
+
+// KeepSecrets replaces the masked secrets of the settings with the ones of the current settings.
+func (t *ReaderSettings) KeepSecrets(current ReaderSettings) {
+	*t = t.clone()
+	keep := func(secret *string, currentSecret string) {
+		if *secret == ReaderSecretMask {
+			*secret = currentSecret
+		}
+	}
+	if t.RabbitMQ != nil && current.RabbitMQ != nil {
+		keep(&t.RabbitMQ.Password, current.RabbitMQ.Password)
+	}
+	if t.NATS != nil && current.NATS != nil {
+		keep(&t.NATS.Password, current.NATS.Password)
+		keep(&t.NATS.Token, current.NATS.Token)
+	}
+	if t.S3Queue != nil && current.S3Queue != nil {
+		keep(&t.S3Queue.SecretAccessKey, current.S3Queue.SecretAccessKey)
+	}
+	if t.Import != nil && current.Import != nil {
+		keep(&t.Import.SecretAccessKey, current.Import.SecretAccessKey)
+	}
+}
+
+func (t ReaderSettings) clone() ReaderSettings {
+	res := ReaderSettings{}
+	if t.RabbitMQ != nil {
+		v := *t.RabbitMQ
+		res.RabbitMQ = &v
+	}
+	if t.NATS != nil {
+		v := *t.NATS
+		res.NATS = &v
+	}
+	if t.S3Queue != nil {
+		v := *t.S3Queue
+		res.S3Queue = &v
+	}
+	if t.Import != nil {
+		v := *t.Import
+		res.Import = &v
+	}
+	return res
+}
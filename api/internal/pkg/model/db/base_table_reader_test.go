@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+)
+
+func TestReaderSettings_Secrets(t *testing.T) {
+	current := ReaderSettings{
+		NATS:   &ReaderNATS{URL: "nats:4222", Subjects: "logs", Password: "secret", Token: ""},
+		Import: &ReaderImport{Source: ReaderImportSourceS3, SecretAccessKey: "key"},
+	}
+	masked := current.Masked()
+	if masked.NATS.Password != ReaderSecretMask || masked.NATS.Token != "" || masked.Import.SecretAccessKey != ReaderSecretMask {
+		t.Errorf("Masked() = %+v %+v", masked.NATS, masked.Import)
+	}
+	if current.NATS.Password != "secret" {
+		t.Errorf("Masked() changed the settings: %+v", current.NATS)
+	}
+	if got := current.MaskSQL("nats_password = 'secret'"); got != "nats_password = '******'" {
+		t.Errorf("MaskSQL() = %s", got)
+	}
+
+	update := masked
+	update.KeepSecrets(current)
+	if update.NATS.Password != "secret" || update.Import.SecretAccessKey != "key" {
+		t.Errorf("KeepSecrets() = %+v %+v", update.NATS, update.Import)
+	}
+	if masked.NATS.Password != ReaderSecretMask {
+		t.Errorf("KeepSecrets() changed the masked settings: %+v", masked.NATS)
+	}
+	update = ReaderSettings{NATS: &ReaderNATS{Password: "changed"}}
+	update.KeepSecrets(current)
+	if update.NATS.Password != "changed" {
+		t.Errorf("KeepSecrets() = %+v", update.NATS)
+	}
+}
+
+func TestReaderSettings_Scan(t *testing.T) {
+	var settings ReaderSettings
+	if err := settings.Scan(""); err != nil || settings.RabbitMQ != nil {
+		t.Errorf("Scan() = %+v, %v", settings, err)
+	}
+	value, err := ReaderSettings{RabbitMQ: &ReaderRabbitMQ{HostPort: "mq:5672"}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = settings.Scan([]byte(value.(string))); err != nil || settings.RabbitMQ == nil || settings.RabbitMQ.HostPort != "mq:5672" {
+		t.Errorf("Scan() = %+v, %v", settings, err)
+	}
+}
+
+func TestBaseTable_GetReaderType(t *testing.T) {
+	if got := (&BaseTable{}).GetReaderType(); got != ReaderTypeKafka {
+		t.Errorf("GetReaderType() = %s", got)
+	}
+	if got := (&BaseTable{ReaderType: ReaderTypeNATS}).GetReaderType(); got != ReaderTypeNATS {
+		t.Errorf("GetReaderType() = %s", got)
+	}
+}
@@ -1,6 +1,8 @@
 package db
 
 import (
+	"time"
+
 	"github.com/ego-component/egorm"
 	"github.com/pkg/errors"
 	"gorm.io/gorm"
@@ -10,11 +12,21 @@ const (
 	CollectTypeQuery        = 1 << 0
 	CollectTypeTableFilter  = 1 << 1
 	CollectTypeGlobalFilter = 1 << 2
+	CollectTypeSearch       = 1 << 3 // saved search of a table with its time range, fields and sharing scope
+)
+
+// Sharing scopes of a saved search, the shared searches are visible to the users who can view the table.
+const (
+	CollectScopePrivate = 0
+	CollectScopeTeam    = 1 // the members
+	CollectScopeTable   = 2 // everyone with table access
 )
 
 var (
 	ErrCollectCreator      = errors.New("only the creator can modify")
 	ErrCollectUpdateParams = errors.New("collect update params error")
+	ErrCollectSearchParams = errors.New("saved search requires a table, a valid scope and a valid sort")
+	ErrCollectSearchTable  = errors.New("saved search requires the permission to view its table")
 )
 
 type ICollect interface {
@@ -25,6 +37,8 @@ type ICollect interface {
 	InfoX(db *gorm.DB, conds map[string]interface{}) (err error)
 	List(db *gorm.DB, conds egorm.Conds) (resp []*Collect, err error)
 	ListPage(db *gorm.DB, conds egorm.Conds, reqList *ReqPage) (total int64, respList []*Collect)
+	ListSearch(db *gorm.DB, uid, tableId int) (resp []*Collect, err error)
+	InfoByPermalink(db *gorm.DB, code string) (err error)
 	Delete(db *gorm.DB) (err error)
 }
 
@@ -36,6 +50,18 @@ type Collect struct {
 	Alias       string `gorm:"column:alias;type:varchar(255);NOT NULL" json:"alias"`
 	Statement   string `gorm:"column:statement;type:text" json:"statement"`
 	CollectType int    `gorm:"column:collect_type;type:int" json:"collectType"`
+
+	// the fields of a saved search
+	QueryMode int     `gorm:"column:query_mode;type:int" json:"queryMode"`
+	Relative  int64   `gorm:"column:relative;type:bigint(20)" json:"relative"` // seconds before now, the time range is [St, Et] when 0
+	St        int64   `gorm:"column:st;type:bigint(20)" json:"st"`
+	Et        int64   `gorm:"column:et;type:bigint(20)" json:"et"`
+	Fields    Strings `gorm:"column:fields;type:text" json:"fields"`   // visible fields
+	Filters   Strings `gorm:"column:filters;type:text" json:"filters"` // filters of the query, as filters[] of /logs
+	Sort      string  `gorm:"column:sort;type:varchar(8);NOT NULL;default:''" json:"sort"`
+	Scope     int     `gorm:"column:scope;type:int;NOT NULL;default:0" json:"scope"`
+	Members   Ints    `gorm:"column:members;type:text" json:"members"`                                      // users of the team scope
+	Permalink string  `gorm:"column:permalink;type:varchar(64);NOT NULL;default:'';index" json:"permalink"` // code of the permalink, never expires
 }
 
 type ReqCreateCollect struct {
@@ -46,11 +72,25 @@ type ReqCreateCollect struct {
 type ReqUpdateCollect struct {
 	Alias       string `json:"alias" form:"alias"`
 	Statement   string `json:"statement" form:"statement"`
-	CollectType int    `json:"collectType" form:"collectType"` // 1 query 2 table filter 4 global filter
+	CollectType int    `json:"collectType" form:"collectType"` // 1 query 2 table filter 4 global filter 8 saved search
+	ReqCollectSearch
+}
+
+// ReqCollectSearch holds the fields of a saved search.
+type ReqCollectSearch struct {
+	QueryMode int      `json:"queryMode" form:"queryMode"`
+	Relative  int64    `json:"relative" form:"relative"` // seconds before now, st and et are used when 0
+	St        int64    `json:"st" form:"st"`
+	Et        int64    `json:"et" form:"et"`
+	Fields    []string `json:"fields" form:"fields"`
+	Filters   []string `json:"filters" form:"filters"`
+	Sort      string   `json:"sort" form:"sort"`   // desc or asc of the time, desc by default
+	Scope     int      `json:"scope" form:"scope"` // 0 private 1 team 2 everyone with table access
+	Members   []int    `json:"members" form:"members"`
 }
 
 type ReqListCollect struct {
-	CollectType int `json:"collectType" form:"collectType" required:"true"` // 1 query 2 table filter 4 global filter, if query table filter and global filter, use collectType 6
+	CollectType int `json:"collectType" form:"collectType" required:"true"` // 1 query 2 table filter 4 global filter 8 saved search, if query table filter and global filter, use collectType 6
 	TableId     int `json:"tableId" form:"tableId"`
 }
 
@@ -60,6 +100,11 @@ type RespListCollectItem struct {
 	Statement string `json:"statement"`
 }
 
+type RespCollectPermalink struct {
+	*Collect
+	Url string `json:"url"` // page of the search, a relative time range ends at the time of the request
+}
+
 type RespListCollect struct {
 	Total int64                  `json:"total"`
 	List  []*RespListCollectItem `json:"list"`
@@ -69,6 +114,48 @@ func (model *Collect) TableName() string {
 	return TableNameCollect
 }
 
+// Valid checks the fields of a saved search.
+func (r ReqCollectSearch) Valid() error {
+	if r.Scope < CollectScopePrivate || r.Scope > CollectScopeTable || r.Relative < 0 {
+		return ErrCollectSearchParams
+	}
+	if r.Sort != "" && r.Sort != "desc" && r.Sort != "asc" {
+		return ErrCollectSearchParams
+	}
+	return nil
+}
+
+// SetSearch sets the fields of the saved search.
+func (model *Collect) SetSearch(r ReqCollectSearch) {
+	model.QueryMode = r.QueryMode
+	model.Relative = r.Relative
+	model.St = r.St
+	model.Et = r.Et
+	model.Fields = r.Fields
+	model.Filters = r.Filters
+	model.Sort = r.Sort
+	model.Scope = r.Scope
+	model.Members = r.Members
+}
+
+// TimeRange returns the time range of the saved search at now.
+func (model *Collect) TimeRange(now time.Time) (st, et int64) {
+	if model.Relative > 0 {
+		return now.Unix() - model.Relative, now.Unix()
+	}
+	return model.St, model.Et
+}
+
+// IsMember tells whether the user is a member of the team of the saved search.
+func (model *Collect) IsMember(uid int) bool {
+	for _, member := range model.Members {
+		if member == uid {
+			return true
+		}
+	}
+	return false
+}
+
 func (model *Collect) Create(db *gorm.DB) (err error) {
 	if err = db.Model(Collect{}).Create(model).Error; err != nil {
 		return errors.Wrapf(err, "data: %v", model)
@@ -126,6 +213,28 @@ func (model *Collect) ListPage(db *gorm.DB, conds egorm.Conds, reqList *ReqPage)
 	return
 }
 
+// ListSearch returns the saved searches of the user and the searches shared by the others, which are
+// left to the caller to check against the team members and the table permissions.
+func (model *Collect) ListSearch(db *gorm.DB, uid, tableId int) (resp []*Collect, err error) {
+	resp = make([]*Collect, 0)
+	query := db.Model(Collect{}).Where("`collect_type` = ? AND (`uid` = ? OR `scope` > ?)", CollectTypeSearch, uid, CollectScopePrivate)
+	if tableId != 0 {
+		query = query.Where("`table_id` = ?", tableId)
+	}
+	if err = query.Order("`id` desc").Find(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "uid: %d, table id: %d", uid, tableId)
+	}
+	return
+}
+
+// InfoByPermalink returns the saved search of the permalink code.
+func (model *Collect) InfoByPermalink(db *gorm.DB, code string) (err error) {
+	if err = db.Model(Collect{}).Where("`collect_type` = ? AND `permalink` = ?", CollectTypeSearch, code).First(model).Error; err != nil {
+		return errors.Wrapf(err, "permalink: %s", code)
+	}
+	return
+}
+
 func (model *Collect) Delete(db *gorm.DB) (err error) {
 	if err = db.Model(Collect{}).Unscoped().Delete(&Collect{}, model.ID).Error; err != nil {
 		return errors.Wrapf(err, "id: %v", model.ID)
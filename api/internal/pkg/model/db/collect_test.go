@@ -0,0 +1,59 @@
+package db
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReqCollectSearch_Valid(t *testing.T) {
+	tests := []struct {
+		name    string
+		req     ReqCollectSearch
+		wantErr bool
+	}{
+		{name: "private", req: ReqCollectSearch{}},
+		{name: "team", req: ReqCollectSearch{Scope: CollectScopeTeam, Members: []int{2}, Sort: "asc"}},
+		{name: "table", req: ReqCollectSearch{Scope: CollectScopeTable, Relative: 900, Sort: "desc"}},
+		{name: "unknown scope", req: ReqCollectSearch{Scope: 3}, wantErr: true},
+		{name: "unknown sort", req: ReqCollectSearch{Sort: "random"}, wantErr: true},
+		{name: "negative relative", req: ReqCollectSearch{Relative: -1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.Valid(); (err != nil) != tt.wantErr {
+				t.Errorf("Valid() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCollect_TimeRange(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	search := Collect{St: 100, Et: 200}
+	if st, et := search.TimeRange(now); st != 100 || et != 200 {
+		t.Errorf("TimeRange() of an absolute range = [%d, %d]", st, et)
+	}
+	search.Relative = 900
+	if st, et := search.TimeRange(now); st != now.Unix()-900 || et != now.Unix() {
+		t.Errorf("TimeRange() of a relative range = [%d, %d]", st, et)
+	}
+}
+
+func TestCollect_IsMember(t *testing.T) {
+	search := Collect{Members: Ints{2, 3}}
+	if !search.IsMember(3) || search.IsMember(4) {
+		t.Errorf("IsMember() of %v is wrong", search.Members)
+	}
+}
+
+func TestInts_Scan(t *testing.T) {
+	var members Ints
+	// the rows created before the column are NULL
+	if err := members.Scan(nil); err != nil || len(members) != 0 {
+		t.Errorf("Scan(nil) = %v, %v", members, err)
+	}
+	var fields Strings
+	if err := fields.Scan(nil); err != nil || len(fields) != 0 {
+		t.Errorf("Scan(nil) = %v, %v", fields, err)
+	}
+}
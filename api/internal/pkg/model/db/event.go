@@ -38,6 +38,9 @@ const (
 	OpnTablesUpdate         = "opn_tables_update"
 	OpnTablesIndexUpdate    = "opn_tables_index_update"
 	OpnTablesLogsQuery      = "opn_tables_logs_query"
+	OpnTablesLogsExport     = "opn_tables_logs_export"
+	OpnTablesLogsTail       = "opn_tables_logs_tail"
+	OpnTablesHealthChange   = "opn_tables_health_change"
 	OpnDatabasesDelete      = "opn_databases_delete"
 	OpnDatabasesCreate      = "opn_databases_create"
 	OpnDatabasesUpdate      = "opn_databases_update"
@@ -61,12 +64,24 @@ const (
 	OpnClustersConfigMapCreate = "opn_clusters_config_map_create"
 	OpnClustersConfigMapUpdate = "opn_clusters_config_map_update"
 
-	OpnAlarmsDelete         = "opn_alarms_delete"
-	OpnAlarmsCreate         = "opn_alarms_create"
-	OpnAlarmsUpdate         = "opn_alarms_update"
-	OpnAlarmsChannelsDelete = "opn_alarms_channels_delete"
-	OpnAlarmsChannelsCreate = "opn_alarms_channels_create"
-	OpnAlarmsChannelsUpdate = "opn_alarms_channels_update"
+	OpnAlarmsDelete            = "opn_alarms_delete"
+	OpnAlarmsCreate            = "opn_alarms_create"
+	OpnAlarmsUpdate            = "opn_alarms_update"
+	OpnAlarmsChannelsDelete    = "opn_alarms_channels_delete"
+	OpnAlarmsChannelsCreate    = "opn_alarms_channels_create"
+	OpnAlarmsChannelsUpdate    = "opn_alarms_channels_update"
+	OpnAlarmsSilencesDelete    = "opn_alarms_silences_delete"
+	OpnAlarmsSilencesCreate    = "opn_alarms_silences_create"
+	OpnAlarmsSilencesUpdate    = "opn_alarms_silences_update"
+	OpnAlarmsOncallsDelete     = "opn_alarms_oncalls_delete"
+	OpnAlarmsOncallsCreate     = "opn_alarms_oncalls_create"
+	OpnAlarmsOncallsUpdate     = "opn_alarms_oncalls_update"
+	OpnAlarmsEscalationsDelete = "opn_alarms_escalations_delete"
+	OpnAlarmsEscalationsCreate = "opn_alarms_escalations_create"
+	OpnAlarmsEscalationsUpdate = "opn_alarms_escalations_update"
+	OpnAlarmsAck               = "opn_alarms_ack"
+	OpnAlarmsDeliveriesReplay  = "opn_alarms_deliveries_replay"
+	OpnAlarmsApply             = "opn_alarms_apply"
 
 	OpnBigDataNodeCreate        = "opn_big_data_node_create"
 	OpnBigDataNodeUpdate        = "opn_big_data_node_update"
@@ -104,6 +119,9 @@ var OperationMap = map[string]string{
 	OpnTableCreateSelfBuilt: "an existing data table is connected",
 	OpnTablesIndexUpdate:    "table analysis field updates",
 	OpnTablesLogsQuery:      "log query",
+	OpnTablesLogsExport:     "log export",
+	OpnTablesLogsTail:       "log live tail",
+	OpnTablesHealthChange:   "table ingestion health change",
 	OpnDatabasesDelete:      "database delete",
 	OpnDatabasesCreate:      "database create",
 	OpnDatabasesUpdate:      "database update",
@@ -127,12 +145,24 @@ var OperationMap = map[string]string{
 	OpnClustersConfigMapCreate: "cluster configmap create",
 	OpnClustersConfigMapUpdate: "cluster configmap update",
 
-	OpnAlarmsDelete:         "alarm delete",
-	OpnAlarmsCreate:         "alarm create",
-	OpnAlarmsUpdate:         "alarm update",
-	OpnAlarmsChannelsDelete: "alarm channel delete",
-	OpnAlarmsChannelsCreate: "alarm channel create",
-	OpnAlarmsChannelsUpdate: "alarm channel update",
+	OpnAlarmsDelete:            "alarm delete",
+	OpnAlarmsCreate:            "alarm create",
+	OpnAlarmsUpdate:            "alarm update",
+	OpnAlarmsChannelsDelete:    "alarm channel delete",
+	OpnAlarmsChannelsCreate:    "alarm channel create",
+	OpnAlarmsChannelsUpdate:    "alarm channel update",
+	OpnAlarmsSilencesDelete:    "alarm silence delete",
+	OpnAlarmsSilencesCreate:    "alarm silence create",
+	OpnAlarmsSilencesUpdate:    "alarm silence update",
+	OpnAlarmsOncallsDelete:     "alarm on-call delete",
+	OpnAlarmsOncallsCreate:     "alarm on-call create",
+	OpnAlarmsOncallsUpdate:     "alarm on-call update",
+	OpnAlarmsEscalationsDelete: "alarm escalation delete",
+	OpnAlarmsEscalationsCreate: "alarm escalation create",
+	OpnAlarmsEscalationsUpdate: "alarm escalation update",
+	OpnAlarmsAck:               "alarm acknowledge",
+	OpnAlarmsDeliveriesReplay:  "alarm delivery replay",
+	OpnAlarmsApply:             "alarm apply",
 
 	OpnMigration: "upgrading the database structure",
 
@@ -192,6 +222,9 @@ var (
 			OpnTablesUpdate,
 			OpnTablesIndexUpdate,
 			OpnTablesLogsQuery,
+			OpnTablesLogsExport,
+			OpnTablesLogsTail,
+			OpnTablesHealthChange,
 			OpnDatabasesDelete,
 			OpnDatabasesCreate,
 			OpnDatabasesUpdate,
@@ -214,6 +247,18 @@ var (
 			OpnAlarmsChannelsDelete,
 			OpnAlarmsChannelsCreate,
 			OpnAlarmsChannelsUpdate,
+			OpnAlarmsSilencesDelete,
+			OpnAlarmsSilencesCreate,
+			OpnAlarmsSilencesUpdate,
+			OpnAlarmsOncallsDelete,
+			OpnAlarmsOncallsCreate,
+			OpnAlarmsOncallsUpdate,
+			OpnAlarmsEscalationsDelete,
+			OpnAlarmsEscalationsCreate,
+			OpnAlarmsEscalationsUpdate,
+			OpnAlarmsAck,
+			OpnAlarmsDeliveriesReplay,
+			OpnAlarmsApply,
 		},
 		SourceConfigMgtCenter: {
 			OpnConfigsDelete,
@@ -0,0 +1,49 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// IngestToken authenticates the ingestion of the logs of a table, only the sha256 of the token is kept.
+type IngestToken struct {
+	BaseModel
+
+	Tid   int    `gorm:"column:tid;type:int(11);index" json:"tid"`
+	Uid   int    `gorm:"column:uid;type:int(11)" json:"uid"` // creator
+	Name  string `gorm:"column:name;type:varchar(128)" json:"name"`
+	Token string `gorm:"column:token;type:char(64);NOT NULL;uniqueIndex" json:"-"`
+}
+
+func (m *IngestToken) TableName() string {
+	return TableNameIngestToken
+}
+
+func IngestTokenCreate(db *gorm.DB, data *IngestToken) (err error) {
+	if err = db.Model(IngestToken{}).Create(data).Error; err != nil {
+		return errors.Wrapf(err, "tid: %d", data.Tid)
+	}
+	return
+}
+
+// IngestTokenInfoX returns the token of the sha256.
+func IngestTokenInfoX(db *gorm.DB, token string) (resp IngestToken, err error) {
+	if err = db.Model(IngestToken{}).Where("`token` = ?", token).First(&resp).Error; err != nil {
+		return resp, errors.Wrap(err, "ingest token")
+	}
+	return
+}
+
+func IngestTokenList(db *gorm.DB, tid int) (resp []*IngestToken, err error) {
+	if err = db.Model(IngestToken{}).Where("`tid` = ?", tid).Order("`id` desc").Find(&resp).Error; err != nil {
+		return nil, errors.Wrapf(err, "tid: %d", tid)
+	}
+	return
+}
+
+func IngestTokenDelete(db *gorm.DB, tid, id int) (err error) {
+	if err = db.Model(IngestToken{}).Where("`tid` = ? AND `id` = ?", tid, id).Unscoped().Delete(&IngestToken{}).Error; err != nil {
+		return errors.Wrapf(err, "tid: %d, id: %d", tid, id)
+	}
+	return
+}
@@ -41,7 +41,8 @@ const TimeFieldSecond = "_time_second_"
 const TimeFieldNanoseconds = "_time_nanosecond_"
 
 const (
-	SuffixJaegerJSON = "_jaeger_dependencies"
+	SuffixJaegerJSON  = "_jaeger_dependencies"
+	SuffixImportFiles = "_import_files" // files imported by ReaderTypeImport
 )
 
 const (
@@ -60,6 +61,10 @@ const (
 	TableNameK8SConfigMap = "cv_k8s_cm"
 	TableNameCluster      = "cv_cluster"
 	TableNameCollect      = "cv_collect"
+	TableNameQueryHistory = "cv_query_history"
+	TableNameIngestToken  = "cv_ingest_token"
+	TableNameTableHealth  = "cv_table_health"
+	TableNameApiToken     = "cv_api_token"
 
 	TableNameBaseView        = "cv_base_view"
 	TableNameBaseTable       = "cv_base_table"
@@ -70,11 +75,16 @@ const (
 	TableNameBaseShortURL    = "cv_base_short_url"
 	TableNameBaseHiddenField = "cv_base_hidden_field"
 
-	TableNameAlarm          = "cv_alarm"
-	TableNameAlarmFilter    = "cv_alarm_filter"
-	TableNameAlarmHistory   = "cv_alarm_history"
-	TableNameAlarmChannel   = "cv_alarm_channel"
-	TableNameAlarmCondition = "cv_alarm_condition"
+	TableNameAlarm           = "cv_alarm"
+	TableNameAlarmFilter     = "cv_alarm_filter"
+	TableNameAlarmHistory    = "cv_alarm_history"
+	TableNameAlarmChannel    = "cv_alarm_channel"
+	TableNameAlarmCondition  = "cv_alarm_condition"
+	TableNameAlarmSilence    = "cv_alarm_silence"
+	TableNameAlarmOncall     = "cv_alarm_oncall"
+	TableNameAlarmEscalation = "cv_alarm_escalation"
+	TableNameAlarmDelivery   = "cv_alarm_delivery"
+	TableNameAlarmGroup      = "cv_alarm_group"
 
 	TableNameConfiguration        = "cv_configuration"
 	TableNameConfigurationHistory = "cv_configuration_history"
@@ -146,7 +156,7 @@ func (t Strings) Value() (driver.Value, error) {
 }
 
 func (t *Strings) Scan(input interface{}) error {
-	in := input.([]byte)
+	in, _ := input.([]byte) // nil of the rows created before the column
 	if len(in) == 0 {
 		in = []byte("[]")
 	}
@@ -161,13 +171,14 @@ func (t Ints) Value() (driver.Value, error) {
 }
 
 func (t *Ints) Scan(input interface{}) error {
-	if len(input.([]byte)) == 0 {
+	in := scanBytes(input) // nil of the rows created before the column
+	if len(in) == 0 {
 		return json.Unmarshal([]byte("[]"), t)
 	}
-	if err := json.Unmarshal(input.([]byte), t); err != nil {
+	if err := json.Unmarshal(in, t); err != nil {
 		return json.Unmarshal([]byte("[]"), t)
 	}
-	return json.Unmarshal(input.([]byte), t)
+	return json.Unmarshal(in, t)
 }
 
 type (
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// QueryHistory is a log query run by a user, the newest QueryHistoryKeep queries of a user are kept.
+type QueryHistory struct {
+	BaseModel
+
+	Uid       int     `gorm:"column:uid;type:int(11);index" json:"uid"`
+	TableId   int     `gorm:"column:table_id;type:int(11)" json:"tableId"`
+	Query     string  `gorm:"column:query;type:text" json:"query"`
+	Filters   Strings `gorm:"column:filters;type:text" json:"filters"`
+	QueryMode int     `gorm:"column:query_mode;type:int" json:"queryMode"`
+	St        int64   `gorm:"column:st;type:bigint(20)" json:"st"`
+	Et        int64   `gorm:"column:et;type:bigint(20)" json:"et"`
+	Cost      int64   `gorm:"column:cost;type:bigint(20)" json:"cost"`      // milliseconds of the query
+	Rows      uint64  `gorm:"column:row_count;type:bigint(20)" json:"rows"` // logs found, the logs returned when not counted
+}
+
+const QueryHistoryKeep = 200
+
+type ReqListQueryHistory struct {
+	TableId int `json:"tableId" form:"tableId"`
+	ReqPage
+}
+
+type RespListQueryHistory struct {
+	Total int64           `json:"total"`
+	List  []*QueryHistory `json:"list"`
+}
+
+// ReqPromoteQueryHistory makes a saved search of a query of the history, the filters of the query are
+// kept and its time range is kept when relative is 0.
+type ReqPromoteQueryHistory struct {
+	Alias string `json:"alias" form:"alias" binding:"required"`
+	ReqCollectSearch
+}
+
+func (m *QueryHistory) TableName() string {
+	return TableNameQueryHistory
+}
+
+func QueryHistoryCreate(db *gorm.DB, data *QueryHistory) (err error) {
+	if err = db.Model(QueryHistory{}).Create(data).Error; err != nil {
+		return errors.Wrapf(err, "data: %v", data)
+	}
+	return
+}
+
+func QueryHistoryInfo(db *gorm.DB, id int) (resp QueryHistory, err error) {
+	if err = db.Model(QueryHistory{}).Where("`id` = ?", id).First(&resp).Error; err != nil {
+		return resp, errors.Wrapf(err, "id: %d", id)
+	}
+	return
+}
+
+// QueryHistoryListPage returns the queries in descending order of time.
+func QueryHistoryListPage(db *gorm.DB, conds egorm.Conds, reqList *ReqPage) (total int64, respList []*QueryHistory) {
+	respList = make([]*QueryHistory, 0)
+	if reqList.PageSize == 0 {
+		reqList.PageSize = 10
+	}
+	if reqList.Current == 0 {
+		reqList.Current = 1
+	}
+	sql, binds := egorm.BuildQuery(conds)
+	query := db.Model(QueryHistory{}).Where(sql, binds...)
+	query.Count(&total)
+	query.Order("`id` desc").Offset((reqList.Current - 1) * reqList.PageSize).Limit(reqList.PageSize).Find(&respList)
+	return
+}
+
+// QueryHistoryTrim deletes the queries of the user older than the newest keep queries.
+func QueryHistoryTrim(db *gorm.DB, uid, keep int) (err error) {
+	var ids []int
+	if err = db.Model(QueryHistory{}).Where("`uid` = ?", uid).Order("`id` desc").Offset(keep).Limit(1).Pluck("id", &ids).Error; err != nil {
+		return errors.Wrapf(err, "uid: %d", uid)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	if err = db.Model(QueryHistory{}).Where("`uid` = ? AND `id` <= ?", uid, ids[0]).Unscoped().Delete(&QueryHistory{}).Error; err != nil {
+		return errors.Wrapf(err, "uid: %d", uid)
+	}
+	return
+}
+
+// QueryHistoryDeleteByUid clears the history of the user.
+func QueryHistoryDeleteByUid(db *gorm.DB, uid int) (err error) {
+	if err = db.Model(QueryHistory{}).Where("`uid` = ?", uid).Unscoped().Delete(&QueryHistory{}).Error; err != nil {
+		return errors.Wrapf(err, "uid: %d", uid)
+	}
+	return
+}
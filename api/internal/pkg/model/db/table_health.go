@@ -0,0 +1,71 @@
+package db
+
+import (
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// Ingestion health status of the tables.
+const (
+	TableHealthUnknown  = "unknown"  // the metrics could not be read
+	TableHealthHealthy  = "healthy"  // the logs are read and written
+	TableHealthDegraded = "degraded" // the logs are written with consumer exceptions, view exceptions or a large lag
+	TableHealthStalled  = "stalled"  // the consumers stopped or the lag grows without inserts
+)
+
+// TableHealth is the last ingestion health of a table collected by the health collector.
+type TableHealth struct {
+	BaseModel
+
+	Tid                int    `gorm:"column:tid;type:int(11);uniqueIndex" json:"tid"`
+	Status             string `gorm:"column:status;type:varchar(16);NOT NULL;default:''" json:"status"`
+	Reason             string `gorm:"column:reason;type:varchar(255);NOT NULL;default:''" json:"reason"`
+	Since              int64  `gorm:"column:since;type:bigint(20);NOT NULL;default:0" json:"since"`              // unix time the status started
+	Consumers          int    `gorm:"column:consumers;type:int(11);NOT NULL;default:0" json:"consumers"`         // -1 when unknown
+	ConsumerLag        int64  `gorm:"column:consumer_lag;type:bigint(20);NOT NULL;default:0" json:"consumerLag"` // -1 when unknown
+	ConsumerExceptions int64  `gorm:"column:consumer_exceptions;type:bigint(20);NOT NULL;default:0" json:"consumerExceptions"`
+	ViewExceptions     int64  `gorm:"column:view_exceptions;type:bigint(20);NOT NULL;default:0" json:"viewExceptions"`
+	LastException      string `gorm:"column:last_exception;type:text" json:"lastException"`
+	LastInsert         int64  `gorm:"column:last_insert;type:bigint(20);NOT NULL;default:0" json:"lastInsert"`
+	LastPoll           int64  `gorm:"column:last_poll;type:bigint(20);NOT NULL;default:0" json:"lastPoll"`
+}
+
+func (m *TableHealth) TableName() string {
+	return TableNameTableHealth
+}
+
+// TableHealthInfoX returns the health of the table, the ID is 0 when it is not collected yet.
+func TableHealthInfoX(db *gorm.DB, tid int) (resp TableHealth, err error) {
+	if err = db.Model(TableHealth{}).Where("`tid` = ?", tid).First(&resp).Error; err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return resp, errors.Wrapf(err, "tid: %d", tid)
+	}
+	return resp, nil
+}
+
+// TableHealthMap returns the health of the tables by their id.
+func TableHealthMap(db *gorm.DB) (resp map[int]*TableHealth, err error) {
+	list := make([]*TableHealth, 0)
+	if err = db.Model(TableHealth{}).Find(&list).Error; err != nil {
+		return nil, errors.Wrap(err, "table health")
+	}
+	resp = make(map[int]*TableHealth, len(list))
+	for _, m := range list {
+		resp[m.Tid] = m
+	}
+	return
+}
+
+// TableHealthSave creates the health of the table or updates it.
+func TableHealthSave(db *gorm.DB, data *TableHealth) (err error) {
+	if err = db.Model(TableHealth{}).Save(data).Error; err != nil {
+		return errors.Wrapf(err, "tid: %d", data.Tid)
+	}
+	return
+}
+
+func TableHealthDelete(db *gorm.DB, tid int) (err error) {
+	if err = db.Model(TableHealth{}).Where("`tid` = ?", tid).Unscoped().Delete(&TableHealth{}).Error; err != nil {
+		return errors.Wrapf(err, "tid: %d", tid)
+	}
+	return
+}
@@ -20,6 +20,18 @@ type ReqAlarmCreate struct {
 	Level            int                       `json:"level" form:"level"`
 	DutyOfficers     []int                     `json:"dutyOfficers" form:"dutyOfficers"`
 	IsDisableResolve int                       `json:"isDisableResolve" form:"isDisableResolve"`
+	EscalationId     int                       `json:"escalationId" form:"escalationId"`     // escalation policy, replaces duty officers when set
+	GroupBy          []string                  `json:"groupBy" form:"groupBy"`               // alarm, table, instance or tag:<key>, empty means no grouping
+	GroupWait        int                       `json:"groupWait" form:"groupWait"`           // seconds, 30 by default
+	GroupInterval    int                       `json:"groupInterval" form:"groupInterval"`   // seconds, 300 by default
+	RepeatInterval   int                       `json:"repeatInterval" form:"repeatInterval"` // seconds, 4 hours by default
+}
+
+type ReqAlarmChannelPreview struct {
+	Typ      int    `json:"typ" form:"typ"`
+	Template string `json:"template" form:"template"` // empty means the default template of the channel type
+	AlarmId  int    `json:"alarmId" form:"alarmId"`   // renders the first filter of the alarm, sample data is used when it is 0
+	Status   string `json:"status" form:"status"`     // firing or resolved
 }
 
 func (r *ReqAlarmCreate) ConvertV2() {
@@ -33,7 +45,8 @@ func (r *ReqAlarmCreate) ConvertV2() {
 
 type AlarmFilterItem struct {
 	*db2.AlarmFilter
-	Exp string
+	Exp            string
+	SignatureField string // field exported as the signature label by the alert view, set by new signature conditions
 }
 
 type ReqAlarmFilterCreate struct {
@@ -46,11 +59,13 @@ type ReqAlarmFilterCreate struct {
 }
 
 type ReqAlarmConditionCreate struct {
-	SetOperatorTyp int `json:"typ" form:"typ"`                      // 0 when 1 and  2 or
-	SetOperatorExp int `json:"exp" form:"exp"`                      // 0 avg 1 min 2 max 3 sum 4 count
-	Cond           int `json:"cond" form:"cond"`                    // 0 above 1 below 2 outside range 3 within range
-	Val1           int `json:"val1" form:"val1" binding:"required"` // 基准值/最小值
-	Val2           int `json:"val2" form:"val2"`                    // 最大值
+	SetOperatorTyp int    `json:"typ" form:"typ"`                      // 0 when 1 and  2 or
+	SetOperatorExp int    `json:"exp" form:"exp"`                      // 0 avg 1 min 2 max 3 sum 4 count
+	Cond           int    `json:"cond" form:"cond"`                    // 0 above 1 below 2 outside range 3 within range 4 percent change 5 z-score 6 new signature
+	Val1           int    `json:"val1" form:"val1" binding:"required"` // 基准值/最小值
+	Val2           int    `json:"val2" form:"val2"`                    // 最大值
+	Baseline       int    `json:"baseline" form:"baseline"`            // seconds, baseline window of cond 4, 5 and 6
+	Field          string `json:"field" form:"field"`                  // signature field of cond 6
 }
 
 type (
@@ -161,3 +176,30 @@ type (
 		Text  string `json:"text"`
 	}
 )
+
+type ReqAlarmBacktest struct {
+	ReqAlarmCreate
+	StartTime int64 `json:"startTime" form:"startTime" binding:"required"` // unix seconds
+	EndTime   int64 `json:"endTime" form:"endTime" binding:"required"`     // unix seconds
+}
+
+type (
+	RespAlarmBacktest struct {
+		Filters       []RespAlarmBacktestFilter `json:"filters"`
+		Notifications int                       `json:"notifications"` // notifications of all filters, before silences and grouping
+	}
+
+	RespAlarmBacktestFilter struct {
+		Tid           int                       `json:"tid"`
+		TableName     string                    `json:"tableName"`
+		Windows       int                       `json:"windows"`       // evaluated windows
+		FiringWindows int                       `json:"firingWindows"` // windows whose conditions matched
+		Notifications int                       `json:"notifications"`
+		Transitions   []AlarmBacktestTransition `json:"transitions"`
+	}
+
+	AlarmBacktestTransition struct {
+		Time   int64  `json:"time"`   // end of the evaluated window, unix seconds
+		Status string `json:"status"` // firing or resolved
+	}
+)
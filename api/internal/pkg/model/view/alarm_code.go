@@ -0,0 +1,83 @@
+package view
+
+// AlarmDocument is the declarative YAML form of alarms, ids are replaced by
+// names so that the document can be kept in git and applied to any deployment.
+type AlarmDocument struct {
+	Alarms []AlarmSpec `json:"alarms" yaml:"alarms"`
+}
+
+type AlarmSpec struct {
+	Name             string            `json:"name" yaml:"name"` // unique key of an alarm in the document
+	Desc             string            `json:"desc,omitempty" yaml:"desc,omitempty"`
+	Interval         int               `json:"interval" yaml:"interval"`
+	Unit             int               `json:"unit" yaml:"unit"` // 0 m 1 s 2 h 3 d 4 w 5 y
+	Level            int               `json:"level,omitempty" yaml:"level,omitempty"`
+	NoDataOp         int               `json:"noDataOp,omitempty" yaml:"noDataOp,omitempty"`
+	Tags             map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Channels         []string          `json:"channels" yaml:"channels"`                                     // channel names
+	DutyOfficers     []string          `json:"dutyOfficers,omitempty" yaml:"dutyOfficers,omitempty"`         // usernames
+	Escalation       string            `json:"escalation,omitempty" yaml:"escalation,omitempty"`             // escalation policy name
+	IsDisableResolve int               `json:"isDisableResolve,omitempty" yaml:"isDisableResolve,omitempty"` // 1 disables the resolved message
+	GroupBy          []string          `json:"groupBy,omitempty" yaml:"groupBy,omitempty"`                   // alarm, table, instance or tag:<key>
+	GroupWait        int               `json:"groupWait,omitempty" yaml:"groupWait,omitempty"`               // seconds
+	GroupInterval    int               `json:"groupInterval,omitempty" yaml:"groupInterval,omitempty"`       // seconds
+	RepeatInterval   int               `json:"repeatInterval,omitempty" yaml:"repeatInterval,omitempty"`     // seconds
+	Disabled         bool              `json:"disabled,omitempty" yaml:"disabled,omitempty"`                 // the alarm is closed
+	Filters          []AlarmFilterSpec `json:"filters" yaml:"filters"`
+}
+
+type AlarmFilterSpec struct {
+	Table          AlarmTableRef        `json:"table" yaml:"table"`
+	When           string               `json:"when" yaml:"when"`
+	SetOperatorTyp int                  `json:"typ,omitempty" yaml:"typ,omitempty"`
+	SetOperatorExp string               `json:"exp,omitempty" yaml:"exp,omitempty"`
+	Mode           int                  `json:"mode,omitempty" yaml:"mode,omitempty"`
+	Conditions     []AlarmConditionSpec `json:"conditions" yaml:"conditions"`
+}
+
+// AlarmTableRef references a table by instance, database and table name.
+type AlarmTableRef struct {
+	Instance string `json:"instance" yaml:"instance"`
+	Database string `json:"database" yaml:"database"`
+	Table    string `json:"table" yaml:"table"`
+}
+
+type AlarmConditionSpec struct {
+	SetOperatorTyp int    `json:"typ" yaml:"typ"`   // 0 when 1 and 2 or
+	SetOperatorExp int    `json:"exp" yaml:"exp"`   // 0 avg 1 min 2 max 3 sum 4 count
+	Cond           int    `json:"cond" yaml:"cond"` // 0 above 1 below 2 outside range 3 within range 4 percent change 5 z-score 6 new signature
+	Val1           int    `json:"val1" yaml:"val1"`
+	Val2           int    `json:"val2,omitempty" yaml:"val2,omitempty"`
+	Baseline       int    `json:"baseline,omitempty" yaml:"baseline,omitempty"`
+	Field          string `json:"field,omitempty" yaml:"field,omitempty"`
+}
+
+const (
+	AlarmPlanCreate = "create"
+	AlarmPlanUpdate = "update"
+	AlarmPlanDelete = "delete"
+	AlarmPlanNoop   = "noop"
+)
+
+type ReqAlarmExport struct {
+	AlarmIds []int `json:"alarmIds" form:"alarmIds"` // empty means all alarms
+}
+
+type ReqAlarmApply struct {
+	Content string `json:"content" form:"content" binding:"required"` // YAML document
+	DryRun  bool   `json:"dryRun" form:"dryRun"`                      // only returns the plan
+	Prune   bool   `json:"prune" form:"prune"`                        // deletes the alarms missing in the document
+}
+
+type RespAlarmPlan struct {
+	Items   []AlarmPlanItem `json:"items"`
+	Applied bool            `json:"applied"`
+}
+
+type AlarmPlanItem struct {
+	Action  string   `json:"action"` // create, update, delete or noop
+	Name    string   `json:"name"`
+	AlarmId int      `json:"alarmId"`
+	Changes []string `json:"changes,omitempty"` // changed fields of an update
+	Error   string   `json:"error,omitempty"`   // apply error
+}
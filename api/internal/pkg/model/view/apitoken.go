@@ -0,0 +1,13 @@
+package view
+
+type ReqApiTokenCreate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RespApiToken is the token of the read APIs of a user, the token itself is only returned when created.
+type RespApiToken struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Token string `json:"token,omitempty"`
+	Ctime int64  `json:"ctime"`
+}
@@ -48,6 +48,11 @@ func (i *IndexItem) Name() string {
 	return fmt.Sprintf("%s-%s-%d-%s-%d", i.Field, i.Alias, i.Typ, i.RootName, i.HashTyp)
 }
 
+const (
+	QueryModeLanguage = 0 // level:error AND latency>500, see the lql package
+	QueryModeSQL      = 1 // raw WHERE fragment, requires the raw SQL permission unless app.rawSQLPermission is off
+)
+
 type (
 	ReqQuery struct {
 		Tid           int      `json:"tid" form:"tid"`
@@ -56,12 +61,15 @@ type (
 		DatabaseTable string   `form:"databaseTable"`
 		Field         string   `form:"field"`
 		Query         string   `form:"query"`
+		QueryMode     int      `form:"queryMode"` // 0 query language 1 raw SQL
 		TimeField     string   `form:"timeField"`
 		TimeFieldType int      `form:"timeFieldType"`
 		ST            int64    `form:"st"`
 		ET            int64    `form:"et"`
 		Page          uint32   `form:"page"`
 		PageSize      uint32   `form:"pageSize"`
+		Cursor        string   `form:"cursor"`    // search-after cursor of RespQuery, replaces page
+		Direction     string   `form:"direction"` // older (default) or newer than the cursor
 		AlarmMode     int      `form:"alarmMode"`
 		Filters       []string `form:"filters[]"`
 		GroupByCond   string   `form:"groupByCond"`
@@ -73,6 +81,132 @@ type (
 		Interval      int64    `json:"interval"`
 	}
 
+	ReqQueryExport struct {
+		ReqQuery
+		Format string `form:"format" binding:"required,oneof=csv ndjson parquet"`
+		Limit  int    `form:"limit"` // rows, capped by app.exportMaxRows
+	}
+
+	ReqLogsContext struct {
+		Cursor string `form:"cursor"` // cursor of the log in RespQuery
+		Ts     int64  `form:"ts"`     // _time_nanosecond_ of the log when there is no cursor
+		Before int    `form:"before"` // lines before the log, 10 by default
+		After  int    `form:"after"`  // lines after the log, 10 by default
+		// Fields are the values of the log which identify its source, as _pod_name_, _container_name_ or an analysis field,
+		// the agent and local logs are identified by their _file_, _offset_ and _sign_. Bound from fields[name]=value.
+		Fields map[string]string `form:"-"`
+	}
+
+	// RespLogsContext holds the logs around a log of the same source in ascending time order.
+	RespLogsContext struct {
+		Before []map[string]interface{} `json:"before"`
+		After  []map[string]interface{} `json:"after"`
+		Query  string                   `json:"query"` // the query of the source fields
+	}
+
+	// RespLogsTail is a batch of a live tail, the logs are in ascending time order.
+	RespLogsTail struct {
+		Logs    []map[string]interface{} `json:"logs"`
+		Dropped int                      `json:"dropped"` // logs left out by sampling
+		Skipped bool                     `json:"skipped"` // the tail fell behind and jumped to the newest logs
+	}
+
+	// RespLogsPatterns holds the templates of the logs sampled over the time range, the most frequent first.
+	RespLogsPatterns struct {
+		Field    string        `json:"field"`   // the field clustered
+		Sampled  int           `json:"sampled"` // logs read
+		Total    uint64        `json:"total"`   // estimated logs of the query
+		Buckets  []int64       `json:"buckets"` // start second of every point of the sparklines
+		Patterns []LogsPattern `json:"patterns"`
+	}
+
+	LogsPattern struct {
+		Id        string                   `json:"id"` // hash of the template
+		Template  string                   `json:"template"`
+		Count     uint64                   `json:"count"` // estimated logs of the time range
+		Sampled   int                      `json:"sampled"`
+		Sparkline []uint64                 `json:"sparkline"` // estimated logs of every bucket
+		Samples   []map[string]interface{} `json:"samples"`   // the newest logs sampled
+	}
+
+	// RespLogsPatternsDiff compares the patterns of the time range with the previous range of the same length,
+	// the buckets of the sparklines cover both ranges.
+	RespLogsPatternsDiff struct {
+		Field      string            `json:"field"`
+		Sampled    int               `json:"sampled"`
+		Total      uint64            `json:"total"`
+		Buckets    []int64           `json:"buckets"`
+		PreviousST int64             `json:"previousSt"`
+		PreviousET int64             `json:"previousEt"`
+		Patterns   []LogsPatternDiff `json:"patterns"` // new patterns first
+	}
+
+	LogsPatternDiff struct {
+		LogsPattern
+		Previous uint64 `json:"previous"` // estimated logs of the previous range
+		Status   string `json:"status"`   // new, gone, up, down or same
+	}
+
+	// ReqFieldStats asks the statistics of the fields over the logs of the query, for the field sidebar.
+	ReqFieldStats struct {
+		ReqQuery
+		Fields  []string `form:"fields[]" binding:"required"`
+		K       int      `form:"k"`       // top values of every field, 10 by default
+		Buckets int      `form:"buckets"` // histogram buckets of the numeric fields, 10 by default
+	}
+
+	// FieldStatsParams are the fields computed by a datasource, resolved from the columns of the table.
+	FieldStatsParams struct {
+		Fields   []FieldStatsField
+		K        int
+		Buckets  int
+		Interval int64 // seconds of a point of the time series
+	}
+
+	FieldStatsField struct {
+		Name    string
+		Numeric bool
+	}
+
+	// RespFieldStats holds the statistics of the fields, the time series of the top values share the timeline.
+	RespFieldStats struct {
+		Total    uint64       `json:"total"`    // logs of the query
+		Interval int64        `json:"interval"` // seconds of a point of the time series
+		Timeline []int64      `json:"timeline"` // start second of every point of the time series
+		Fields   []FieldStats `json:"fields"`
+	}
+
+	FieldStats struct {
+		Field      string             `json:"field"`
+		Uniq       uint64             `json:"uniq"`       // estimated distinct values
+		Empty      uint64             `json:"empty"`      // logs of which the value is null or empty
+		EmptyRatio float64            `json:"emptyRatio"` // percent of the logs
+		Top        []FieldStatsValue  `json:"top"`        // the most frequent values which are not empty
+		Other      uint64             `json:"other"`      // logs of the values out of the top
+		Numeric    *FieldStatsNumeric `json:"numeric,omitempty"`
+	}
+
+	FieldStatsValue struct {
+		Value   string   `json:"value"`
+		Count   uint64   `json:"count"`
+		Percent float64  `json:"percent"`
+		Series  []uint64 `json:"series"` // logs of the value at every point of the timeline
+	}
+
+	FieldStatsNumeric struct {
+		Min         float64            `json:"min"`
+		Max         float64            `json:"max"`
+		Avg         float64            `json:"avg"`
+		Percentiles map[string]float64 `json:"percentiles"` // p50, p90, p95 and p99
+		Histogram   []FieldStatsBucket `json:"histogram"`   // buckets of the same width between min and max
+	}
+
+	FieldStatsBucket struct {
+		Lower float64 `json:"lower"`
+		Upper float64 `json:"upper"` // the upper bound of the last bucket is included
+		Count uint64  `json:"count"`
+	}
+
 	RespQuery struct {
 		Limited       uint32                   `json:"limited"`
 		Keys          []*db2.BaseIndex         `json:"keys"`
@@ -82,6 +216,9 @@ type (
 		HiddenFields  []string                 `json:"hiddenFields"`
 		DefaultFields []string                 `json:"defaultFields"`
 		Logs          []map[string]interface{} `json:"logs"`
+		Cursors       []string                 `json:"cursors"` // cursor of every log, for navigation from a log line
+		Older         string                   `json:"older"`   // cursor of the next older page
+		Newer         string                   `json:"newer"`   // cursor of the next newer page
 		Query         string                   `json:"query"`
 		Cost          int64                    `json:"cost"`
 		Where         string                   `json:"where"`
@@ -107,6 +244,12 @@ type RespAgentSearch struct {
 	K8sClientType string                `json:"k8sClientType"`
 }
 
+type RespAgentContext struct {
+	Before        []RespAgentSearchItem `json:"before"`
+	After         []RespAgentSearchItem `json:"after"`
+	K8sClientType string                `json:"k8sClientType"`
+}
+
 type RespAgentChartsSearch struct {
 	Data          map[int64]int64 `json:"data"`
 	MinOffset     int64           `json:"minOffset"`
@@ -218,6 +361,7 @@ type (
 		Desc            string `json:"desc"`
 		V3TableType     int    `json:"v3TableType"`
 		RelTraceTableId int    `json:"relTraceTableId"`
+		Health          string `json:"health"` // ingestion health, empty when it is not collected
 	}
 )
 
@@ -232,7 +376,10 @@ type RespTableDetail struct {
 	Desc                    string `json:"desc"`
 	ConsumerNum             int    `json:"consumerNum"`
 	KafkaSkipBrokenMessages int    `json:"kafkaSkipBrokenMessages"`
-	SQLContent              struct {
+	ReaderType              string `json:"readerType"`
+	// ReaderSettings of the readers other than kafka, the secrets are masked
+	ReaderSettings db2.ReaderSettings `json:"readerSettings"`
+	SQLContent     struct {
 		Keys []string          `json:"keys"`
 		Data map[string]string `json:"data"`
 	} `json:"sqlContent"`
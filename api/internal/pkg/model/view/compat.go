@@ -0,0 +1,105 @@
+package view
+
+import "encoding/json"
+
+// ReqLokiQueryRange is a query of /loki/api/v1/query_range, the times are nanoseconds, seconds or RFC3339.
+type ReqLokiQueryRange struct {
+	Query     string `form:"query" binding:"required"`
+	Start     string `form:"start"`
+	End       string `form:"end"`
+	Limit     int    `form:"limit"`
+	Step      string `form:"step"`      // duration or seconds of the metric queries
+	Direction string `form:"direction"` // backward (default) or forward
+}
+
+// RespLoki is the response of the Loki APIs.
+type RespLoki struct {
+	Status    string      `json:"status"`
+	Data      interface{} `json:"data,omitempty"`
+	ErrorType string      `json:"errorType,omitempty"`
+	Error     string      `json:"error,omitempty"`
+}
+
+// LokiResult is the data of a query, Result is []LokiStream for the logs and []LokiSeries for the metrics.
+type LokiResult struct {
+	ResultType string      `json:"resultType"` // streams or matrix
+	Result     interface{} `json:"result"`
+}
+
+// LokiStream holds the logs of the labels, a value is the time in nanoseconds and the line.
+type LokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values [][2]string       `json:"values"`
+}
+
+// LokiSeries holds the samples of the labels, a value is the time in seconds and the sample.
+type LokiSeries struct {
+	Metric map[string]string `json:"metric"`
+	Values [][2]interface{}  `json:"values"`
+}
+
+// ReqESSearch is the subset of the Elasticsearch search request which is translated to the query language.
+type ReqESSearch struct {
+	Query        json.RawMessage            `json:"query"`
+	Size         *int                       `json:"size"` // 10 by default
+	From         int                        `json:"from"`
+	Sort         json.RawMessage            `json:"sort"`
+	Aggs         map[string]json.RawMessage `json:"aggs"`
+	Aggregations map[string]json.RawMessage `json:"aggregations"`
+}
+
+type RespESSearch struct {
+	Took         int64                    `json:"took"`
+	TimedOut     bool                     `json:"timed_out"`
+	Shards       ESShards                 `json:"_shards"`
+	Hits         ESHits                   `json:"hits"`
+	Aggregations map[string]ESAggregation `json:"aggregations,omitempty"`
+}
+
+type ESShards struct {
+	Total      int `json:"total"`
+	Successful int `json:"successful"`
+	Skipped    int `json:"skipped"`
+	Failed     int `json:"failed"`
+}
+
+type ESHits struct {
+	Total    ESTotal  `json:"total"`
+	MaxScore *float64 `json:"max_score"`
+	Hits     []ESHit  `json:"hits"`
+}
+
+type ESTotal struct {
+	Value    uint64 `json:"value"`
+	Relation string `json:"relation"`
+}
+
+type ESHit struct {
+	Index  string                 `json:"_index"`
+	ID     string                 `json:"_id"`
+	Score  *float64               `json:"_score"`
+	Source map[string]interface{} `json:"_source"`
+	Sort   []interface{}          `json:"sort,omitempty"`
+}
+
+type ESAggregation struct {
+	Buckets []ESBucket `json:"buckets"`
+}
+
+// ESBucket is a bucket of a terms or a date_histogram aggregation, the key of a date_histogram is in milliseconds.
+type ESBucket struct {
+	Key         interface{} `json:"key"`
+	KeyAsString string      `json:"key_as_string,omitempty"`
+	DocCount    uint64      `json:"doc_count"`
+}
+
+// RespESError is the error of an Elasticsearch API.
+type RespESError struct {
+	Error  ESError `json:"error"`
+	Status int     `json:"status"`
+}
+
+type ESError struct {
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
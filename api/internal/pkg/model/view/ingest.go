@@ -0,0 +1,37 @@
+package view
+
+// IngestColumn is a column of the data table written by the ingestion.
+type IngestColumn struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // type of the column in the database, as Nullable(String)
+	Hash int    `json:"hash"` // db.HashTypeSip or db.HashTypeURL when the values are hashed by the datasource, the values are strings
+}
+
+// IngestBatch is a batch of the rows of a data table, the values of a row are nil, string, int64, float64 or time.Time
+// in the order of the columns.
+type IngestBatch struct {
+	Database string
+	Table    string
+	Columns  []IngestColumn
+	Rows     [][]interface{}
+	Token    string // the same for the retries of the batch, the datasources which deduplicate the inserts drop the retries
+}
+
+type RespIngest struct {
+	Rows      int  `json:"rows"`      // logs accepted
+	Duplicate bool `json:"duplicate"` // the request was accepted before in the dedup window, its logs are not queued again
+}
+
+type ReqIngestTokenCreate struct {
+	Name string `json:"name" binding:"required"`
+}
+
+// RespIngestToken is the token of the ingestion of a table, the token itself is only returned when created.
+type RespIngestToken struct {
+	ID    int    `json:"id"`
+	Tid   int    `json:"tid"`
+	Uid   int    `json:"uid"`
+	Name  string `json:"name"`
+	Token string `json:"token,omitempty"`
+	Ctime int64  `json:"ctime"`
+}
@@ -29,6 +29,9 @@ type ReqCreateInstance struct {
 	Namespace        string `json:"namespace"`
 	Configmap        string `json:"configmap"`
 	PrometheusTarget string `json:"prometheusTarget"`
+
+	QueryMaxConcurrency   int `json:"queryMaxConcurrency" binding:"min=0"`
+	QueryMaxExecutionTime int `json:"queryMaxExecutionTime" binding:"min=0"`
 }
 
 type Cluster struct {
@@ -0,0 +1,43 @@
+package view
+
+const (
+	QueryJobKindLogs = "logs"
+	QueryJobKindSQL  = "sql"
+)
+
+const (
+	QueryJobRunning   = "running"
+	QueryJobSucceeded = "succeeded"
+	QueryJobFailed    = "failed"
+	QueryJobCancelled = "cancelled"
+)
+
+type ReqQueryJobCreate struct {
+	Kind  string   `json:"kind" binding:"required,oneof=logs sql"`
+	Tid   int      `json:"tid"`   // the table of a logs job
+	Param ReqQuery `json:"param"` // the log query of a logs job, as the query of /tables/:id/logs
+	Iid   int      `json:"iid"`   // the instance of a sql job
+	SQL   string   `json:"sql"`   // the statement of a sql job
+}
+
+type RespQueryJob struct {
+	Id          string `json:"id"` // also the query_id of the statements in ClickHouse
+	Kind        string `json:"kind"`
+	Uid         int    `json:"uid"`
+	Iid         int    `json:"iid"`
+	Tid         int    `json:"tid"`
+	Status      string `json:"status"`
+	ReadRows    uint64 `json:"readRows"`
+	ReadBytes   uint64 `json:"readBytes"`
+	TotalRows   uint64 `json:"totalRows"` // estimated rows to read, 0 when unknown
+	PartialRows int    `json:"partialRows"`
+	Error       string `json:"error"`
+	Ctime       int64  `json:"ctime"`
+	Ftime       int64  `json:"ftime"` // finish time, 0 while running
+}
+
+type RespQueryJobResult struct {
+	RespQueryJob
+	Partial []map[string]interface{} `json:"partial"` // rows of the running statement read so far
+	Result  interface{}              `json:"result"`  // RespQuery of a logs job or RespComplete of a sql job, once succeeded
+}
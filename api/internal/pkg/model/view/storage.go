@@ -15,22 +15,24 @@ type ReqKafkaJSONMapping struct {
 }
 
 type ReqStorageCreate struct {
-	TableName               string       `form:"tableName" binding:"required"`
-	Typ                     int          `form:"typ" binding:"required"` // 1 string 2 float
-	Days                    int          `form:"days" binding:"required"`
-	Brokers                 string       `form:"brokers" binding:"required"`
-	Topics                  string       `form:"topics" binding:"required"`
-	Consumers               int          `form:"consumers" binding:"required"`
-	KafkaSkipBrokenMessages int          `form:"kafkaSkipBrokenMessages"`
-	Desc                    string       `form:"desc"`
-	Source                  string       `form:"source" binding:"required"` // Raw JSON data
-	DatabaseId              int          `form:"databaseId" binding:"required"`
-	TimeField               string       `form:"timeField" binding:"required"`
-	TimeFieldParent         string       `form:"timeFieldParent"`
-	RawLogField             string       `form:"rawLogField"`
-	RawLogFieldParent       string       `form:"rawLogFieldParent"`
-	SourceMapping           mapping.List `form:"-"`
-	CreateType              int          `form:"createType"`
+	TableName               string             `form:"tableName" binding:"required"`
+	Typ                     int                `form:"typ" binding:"required"` // 1 string 2 float
+	Days                    int                `form:"days" binding:"required"`
+	ReaderType              string             `form:"readerType"` // kafka, rabbitmq, nats, s3queue or import, kafka by default
+	Brokers                 string             `form:"brokers"`    // required by kafka
+	Topics                  string             `form:"topics"`     // required by kafka
+	Consumers               int                `form:"consumers"`
+	KafkaSkipBrokenMessages int                `form:"kafkaSkipBrokenMessages"`
+	ReaderSettings          db2.ReaderSettings `form:"readerSettings"` // settings of the readers other than kafka
+	Desc                    string             `form:"desc"`
+	Source                  string             `form:"source" binding:"required"` // Raw JSON data
+	DatabaseId              int                `form:"databaseId" binding:"required"`
+	TimeField               string             `form:"timeField" binding:"required"`
+	TimeFieldParent         string             `form:"timeFieldParent"`
+	RawLogField             string             `form:"rawLogField"`
+	RawLogFieldParent       string             `form:"rawLogFieldParent"`
+	SourceMapping           mapping.List       `form:"-"`
+	CreateType              int                `form:"createType"`
 }
 
 type ReqCreateStorageByTemplateEgo struct {
@@ -51,6 +53,14 @@ type ReqCreateStorageByTemplateILogtail struct {
 	Topic      string `form:"topic" binding:"required"`
 }
 
+// ReqCreateStorageByTemplateOTLP creates the tables of the OTLP receiver, <name>_logs and <name>_traces.
+type ReqCreateStorageByTemplateOTLP struct {
+	DatabaseId int    `form:"databaseId" binding:"required"`
+	Name       string `form:"name" binding:"required"`
+	Days       int    `form:"days" binding:"required"`
+	Desc       string `form:"desc"`
+}
+
 type ReqCreateAgentStorage struct {
 	Name       string `form:"name" binding:"required"`
 	DatabaseId int    `form:"databaseId" binding:"required"`
@@ -93,8 +103,18 @@ func (r *ReqStorageCreate) SelectFields() string {
 	return res
 }
 
+func (r *ReqStorageCreate) GetReaderType() string {
+	if r.ReaderType == "" {
+		return db2.ReaderTypeKafka
+	}
+	return r.ReaderType
+}
+
 func (r *ReqStorageCreate) JSON() string {
-	resp, _ := json.Marshal(r)
+	c := *r
+	// the reader settings are kept by the table, without the secrets here
+	c.ReaderSettings = db2.ReaderSettings{}
+	resp, _ := json.Marshal(c)
 	return string(resp)
 }
 
@@ -195,6 +215,45 @@ type ReqStorageUpdate struct {
 	KafkaSkipBrokenMessages int    `form:"kafkaSkipBrokenMessages"`
 	Desc                    string `form:"desc"`
 	V3TableType             int    `form:"v3TableType"`
+	ReaderType              string `form:"readerType"` // the reader of the table when empty, see KeepReader
+	// ReaderSettings of the readers other than kafka, the masked secrets keep the current ones
+	ReaderSettings db2.ReaderSettings `form:"readerSettings"`
+}
+
+// KeepReader fills the reader of an update without readerType from the table, so that the updates of the other
+// fields keep the reader and its settings. The Kafka fields of the request still apply to a Kafka table.
+func (r *ReqStorageUpdate) KeepReader(tableInfo *db2.BaseTable) {
+	if r.ReaderType != "" {
+		return
+	}
+	r.ReaderType = tableInfo.GetReaderType()
+	if r.ReaderType != db2.ReaderTypeKafka {
+		r.ReaderSettings = tableInfo.ReaderSettings
+	}
+}
+
+func (r *ReqStorageUpdate) GetReaderType() string {
+	if r.ReaderType == "" {
+		return db2.ReaderTypeKafka
+	}
+	return r.ReaderType
+}
+
+// ReaderChanged reports whether the reader of the table differs from the one of the request,
+// the masked secrets of the request must be replaced by ReaderSettings.KeepSecrets before.
+func (r *ReqStorageUpdate) ReaderChanged(tableInfo *db2.BaseTable) bool {
+	if r.GetReaderType() != tableInfo.GetReaderType() {
+		return true
+	}
+	if r.GetReaderType() == db2.ReaderTypeKafka {
+		return r.KafkaSkipBrokenMessages != tableInfo.KafkaSkipBrokenMessages ||
+			r.KafkaBrokers != tableInfo.Brokers ||
+			r.KafkaConsumerNum != tableInfo.ConsumerNum ||
+			r.KafkaTopic != tableInfo.Topic
+	}
+	current, _ := tableInfo.ReaderSettings.Value()
+	settings, _ := r.ReaderSettings.Value()
+	return current != settings
 }
 
 type (
@@ -0,0 +1,31 @@
+package view
+
+import (
+	"testing"
+
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestReqStorageUpdate_KeepReader(t *testing.T) {
+	nats := &db2.BaseTable{ReaderType: db2.ReaderTypeNATS, ReaderSettings: db2.ReaderSettings{NATS: &db2.ReaderNATS{URL: "nats:4222", Subjects: "logs"}}}
+	kafka := &db2.BaseTable{Brokers: "kafka:9092", Topic: "logs", ConsumerNum: 1}
+	tests := []struct {
+		name    string
+		req     ReqStorageUpdate
+		table   *db2.BaseTable
+		changed bool
+	}{
+		{name: "nats without reader", req: ReqStorageUpdate{MergeTreeTTL: 7, Desc: "d"}, table: nats, changed: false},
+		{name: "nats to kafka", req: ReqStorageUpdate{ReaderType: db2.ReaderTypeKafka, KafkaBrokers: "kafka:9092", KafkaTopic: "logs"}, table: nats, changed: true},
+		{name: "kafka without reader", req: ReqStorageUpdate{KafkaBrokers: "kafka:9092", KafkaTopic: "logs", KafkaConsumerNum: 1}, table: kafka, changed: false},
+		{name: "kafka topic", req: ReqStorageUpdate{KafkaBrokers: "kafka:9092", KafkaTopic: "app", KafkaConsumerNum: 1}, table: kafka, changed: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.KeepReader(tt.table)
+			if got := tt.req.ReaderChanged(tt.table); got != tt.changed {
+				t.Errorf("ReaderChanged() = %v, want %v", got, tt.changed)
+			}
+		})
+	}
+}
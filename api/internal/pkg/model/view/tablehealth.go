@@ -0,0 +1,32 @@
+package view
+
+import (
+	"time"
+)
+
+// IngestHealthMetrics are the ingestion metrics of a table read from the datasource.
+type IngestHealthMetrics struct {
+	Consumers          int       // kafka consumers of the stream table, -1 when the datasource does not expose them
+	ConsumerLag        int64     // sum of the lag of the partitions, -1 when unknown
+	ConsumerExceptions int64     // exceptions of the consumers after since, a lower bound as ClickHouse keeps the last 10 per consumer
+	ViewExceptions     int64     // exceptions of the materialized views after since
+	LastException      string    // the last exception of the consumers or the views
+	LastInsert         time.Time // modification of the last active part of the data table, zero without parts
+	LastPoll           time.Time // zero without consumers
+}
+
+// RespTableHealth is the ingestion health of a table.
+type RespTableHealth struct {
+	Tid                int    `json:"tid"`
+	Status             string `json:"status"` // healthy, degraded, stalled or unknown
+	Reason             string `json:"reason"`
+	Since              int64  `json:"since"` // unix time the status started
+	Consumers          int    `json:"consumers"`
+	ConsumerLag        int64  `json:"consumerLag"`
+	ConsumerExceptions int64  `json:"consumerExceptions"`
+	ViewExceptions     int64  `json:"viewExceptions"`
+	LastException      string `json:"lastException"`
+	LastInsert         int64  `json:"lastInsert"`
+	LastPoll           int64  `json:"lastPoll"`
+	Checked            int64  `json:"checked"` // unix time of the collection
+}
@@ -0,0 +1,194 @@
+// Package pattern clusters log lines into templates with the Drain algorithm,
+// the tokens which vary between the lines of a template are masked by Wildcard.
+package pattern
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Wildcard masks the variable tokens of a template.
+const Wildcard = "<*>"
+
+// delimiters are tokens of their own, so that the fields of JSON or logfmt lines are aligned.
+const delimiters = `,;"'()[]{}`
+
+const (
+	defaultDepth        = 4
+	defaultSimThreshold = 0.4
+	defaultMaxChildren  = 100
+	defaultMaxTokens    = 256 // tokens of a line beyond are dropped
+)
+
+type Config struct {
+	Depth        int     // depth of the prefix tree with the root and the leaves, the first Depth-3 tokens of a line route it
+	SimThreshold float64 // share of the tokens a line must have in common with a template to join it
+	MaxChildren  int     // children of a tree node, the other tokens share the wildcard child
+}
+
+// Cluster is a template and the number of lines which joined it.
+type Cluster struct {
+	tokens []string
+	spaces []bool // whether a space precedes every token, as in the first line
+	Size   int
+}
+
+// Template returns the template as a line.
+func (c *Cluster) Template() string {
+	var b strings.Builder
+	for i, token := range c.tokens {
+		if i > 0 && c.spaces[i] {
+			b.WriteByte(' ')
+		}
+		b.WriteString(token)
+	}
+	return b.String()
+}
+
+type node struct {
+	children map[string]*node
+	clusters []*Cluster
+}
+
+// Drain is not safe for concurrent use.
+type Drain struct {
+	conf     Config
+	root     map[int]*node // by the number of tokens
+	clusters []*Cluster
+}
+
+func NewDrain(conf Config) *Drain {
+	if conf.Depth < 4 {
+		conf.Depth = defaultDepth
+	}
+	if conf.SimThreshold <= 0 {
+		conf.SimThreshold = defaultSimThreshold
+	}
+	if conf.MaxChildren <= 0 {
+		conf.MaxChildren = defaultMaxChildren
+	}
+	return &Drain{conf: conf, root: make(map[int]*node)}
+}
+
+// Clusters returns the clusters in the order they were created.
+func (d *Drain) Clusters() []*Cluster {
+	return d.clusters
+}
+
+// Add puts the line into the most similar cluster of the lines with as many tokens and the same prefix,
+// or into a new cluster when none is similar enough.
+func (d *Drain) Add(line string) *Cluster {
+	tokens, spaces := Tokenize(line)
+	leaf := d.leaf(tokens)
+	var (
+		best    *Cluster
+		bestSim = -1.0
+		bestWc  = -1
+	)
+	for _, c := range leaf.clusters {
+		sim, wc := similarity(c.tokens, tokens)
+		if sim > bestSim || (sim == bestSim && wc > bestWc) {
+			best, bestSim, bestWc = c, sim, wc
+		}
+	}
+	if best != nil && (len(tokens) == 0 || bestSim >= d.conf.SimThreshold) {
+		for i, token := range tokens {
+			if best.tokens[i] != token {
+				best.tokens[i] = Wildcard
+			}
+		}
+		best.Size++
+		return best
+	}
+	c := &Cluster{tokens: tokens, spaces: spaces, Size: 1}
+	leaf.clusters = append(leaf.clusters, c)
+	d.clusters = append(d.clusters, c)
+	return c
+}
+
+func (d *Drain) leaf(tokens []string) *node {
+	n, ok := d.root[len(tokens)]
+	if !ok {
+		n = &node{children: make(map[string]*node)}
+		d.root[len(tokens)] = n
+	}
+	for depth := 0; depth < d.conf.Depth-3 && depth < len(tokens); depth++ {
+		key := tokens[depth]
+		if hasDigit(key) {
+			key = Wildcard
+		}
+		child, ok := n.children[key]
+		if !ok {
+			if len(n.children) >= d.conf.MaxChildren-1 {
+				key = Wildcard
+			}
+			if child, ok = n.children[key]; !ok {
+				child = &node{children: make(map[string]*node)}
+				n.children[key] = child
+			}
+		}
+		n = child
+	}
+	return n
+}
+
+// similarity returns the share of the tokens of the line matched by the template and the wildcards of the template.
+func similarity(template, tokens []string) (float64, int) {
+	if len(tokens) == 0 {
+		return 1, 0
+	}
+	same, wc := 0, 0
+	for i, token := range template {
+		if token == Wildcard {
+			wc++
+		}
+		if token == Wildcard || token == tokens[i] {
+			same++
+		}
+	}
+	return float64(same) / float64(len(tokens)), wc
+}
+
+// Tokenize splits the line by the spaces and the delimiters and masks the tokens with digits,
+// spaces tells whether a space precedes every token.
+func Tokenize(line string) (tokens []string, spaces []bool) {
+	start, space := -1, false
+	flush := func(end int) {
+		if start >= 0 && len(tokens) < defaultMaxTokens {
+			tokens = append(tokens, maskToken(line[start:end]))
+			spaces = append(spaces, space)
+			space = false
+		}
+		start = -1
+	}
+	for i, r := range line {
+		switch {
+		case unicode.IsSpace(r):
+			flush(i)
+			space = true
+		case strings.ContainsRune(delimiters, r):
+			flush(i)
+			start = i
+			flush(i + 1)
+		case start < 0:
+			start = i
+		}
+	}
+	flush(len(line))
+	return tokens, spaces
+}
+
+// maskToken masks a token with digits, the key of a key=value or key:value token is kept.
+func maskToken(token string) string {
+	if !hasDigit(token) {
+		return token
+	}
+	if i := strings.IndexAny(token, "=:"); i >= 0 && !hasDigit(token[:i]) {
+		return token[:i+1] + Wildcard
+	}
+	return Wildcard
+}
+
+func hasDigit(s string) bool {
+	return strings.IndexFunc(s, unicode.IsDigit) >= 0
+}
@@ -0,0 +1,66 @@
+package pattern
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenize(t *testing.T) {
+	tokens, spaces := Tokenize(`level=info user "bob" took 12ms, code:200 at 2024-01-02T03:04:05Z`)
+	assert.Equal(t, []string{"level=info", "user", `"`, "bob", `"`, "took", Wildcard, ",", "code:" + Wildcard, "at", Wildcard}, tokens)
+	assert.Equal(t, []bool{false, true, true, false, false, true, true, false, true, true, true}, spaces)
+
+	tokens, _ = Tokenize(`{"id":42,"msg":"ok"}`)
+	assert.Equal(t, []string{"{", `"`, "id", `"`, ":" + Wildcard, ",", `"`, "msg", `"`, ":", `"`, "ok", `"`, "}"}, tokens)
+
+	tokens, _ = Tokenize("   ")
+	assert.Empty(t, tokens)
+}
+
+func TestDrain(t *testing.T) {
+	d := NewDrain(Config{})
+	lines := []string{
+		"connected to 10.0.0.1 in 3ms",
+		"connected to 10.0.0.2 in 15ms",
+		"user alice logged in",
+		"user bob logged in",
+		"user carol logged out",
+		`{"level":"error","msg":"disk full"}`,
+		`{"level":"error","msg":"disk slow"}`,
+		"",
+	}
+	for _, line := range lines {
+		d.Add(line)
+	}
+	got := make(map[string]int)
+	for _, c := range d.Clusters() {
+		got[c.Template()] = c.Size
+	}
+	assert.Equal(t, map[string]int{
+		"connected to <*> in <*>":            2,
+		"user <*> logged <*>":                3,
+		`{"level":"error","msg":"disk <*>"}`: 2,
+		"":                                   1,
+	}, got)
+}
+
+func TestDrainSimThreshold(t *testing.T) {
+	d := NewDrain(Config{SimThreshold: 0.9})
+	a := d.Add("GET /api/users ok")
+	b := d.Add("GET /api/orders ok")
+	assert.NotSame(t, a, b)
+	assert.Same(t, a, d.Add("GET /api/users ok"))
+	assert.Equal(t, 2, a.Size)
+}
+
+func TestDrainMaxChildren(t *testing.T) {
+	d := NewDrain(Config{MaxChildren: 2})
+	a := d.Add("alpha starts now")
+	b := d.Add("beta starts now")
+	c := d.Add("gamma starts now")
+	assert.NotSame(t, a, b)
+	// the tokens beyond the children of a node share the wildcard child
+	assert.Same(t, b, c)
+	assert.Equal(t, "<*> starts now", b.Template())
+}
@@ -3,6 +3,7 @@ package middlewares
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/ego-component/egorm"
 	"github.com/gin-contrib/sessions"
@@ -16,6 +17,7 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service"
 	"github.com/clickvisual/clickvisual/api/internal/service/permission"
 )
 
@@ -153,3 +155,23 @@ func isNotAuthProxy(c *gin.Context) bool {
 	c.Next()
 	return false
 }
+
+// ApiTokenChecker authenticates the tools by the api tokens of the users, as the bearer of the authorization or
+// the password of the basic authentication.
+func ApiTokenChecker() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token := strings.TrimSpace(strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer "))
+		if _, password, ok := c.Request.BasicAuth(); ok {
+			token = password
+		}
+		u, err := service.ApiTokenUser(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, core.Res{Code: core.CodeErr, Msg: err.Error()})
+			c.Abort()
+			return
+		}
+		ctxUser := &core.User{Uid: int64(u.ID), Nickname: u.Nickname, Username: u.Username, Avatar: u.Avatar, Email: u.Email}
+		c.Set(core.UserContextKey, ctxUser)
+		c.Next()
+	}
+}
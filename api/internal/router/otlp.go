@@ -0,0 +1,17 @@
+package router
+
+import (
+	"github.com/gotomicro/ego/server/egrpc"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+
+	"github.com/clickvisual/clickvisual/api/internal/api/apiv2/storage"
+)
+
+// GetOTLPServer serves the OTLP/gRPC receiver, the exports are authenticated by the ingest tokens of the tables.
+func GetOTLPServer() *egrpc.Component {
+	server := egrpc.Load("server.otlp").Build()
+	collogspb.RegisterLogsServiceServer(server.Server, &storage.OTLPLogsService{})
+	coltracepb.RegisterTraceServiceServer(server.Server, &storage.OTLPTraceService{})
+	return server
+}
@@ -14,6 +14,8 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/api/apiv1/user"
 	"github.com/clickvisual/clickvisual/api/internal/api/apiv2/alert"
 	"github.com/clickvisual/clickvisual/api/internal/api/apiv2/base"
+	"github.com/clickvisual/clickvisual/api/internal/api/apiv2/storage"
+	"github.com/clickvisual/clickvisual/api/internal/api/compat"
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/utils"
@@ -52,18 +54,38 @@ func GetServerRouter() *egin.Component {
 	}
 	g := r.Group(apiPrefix)
 	r.Group(apiPrefix).GET("/api/share/:s-code", middlewares.AuthChecker(), core.Handle(base.ShortURLRedirect))
+	r.Group(apiPrefix).GET("/api/share/searches/:code", middlewares.AuthChecker(), core.Handle(base.SavedSearchRedirect))
 
 	v1Open := g.Group("/api/v1")
 	{
 		v1Open.POST("/install", core.Handle(initialize.Install))
 		v1Open.GET("/install", core.Handle(initialize.IsInstall))
 		v1Open.POST("/prometheus/alerts", core.Handle(alert.Webhook))
+		v1Open.POST("/ingest", core.Handle(storage.Ingest)) // authenticated by the ingest tokens of the tables
+		v1Open.POST("/otlp/v1/logs", core.Handle(storage.OTLPLogs))
+		v1Open.POST("/otlp/v1/traces", core.Handle(storage.OTLPTraces))
 	}
 	admin := g.Group("/api/admin")
 	{
 		admin.GET("/login/:oauth", core.Handle(user.Oauth)) // non-authentication api
 		admin.POST("/users/login", core.Handle(user.Login))
 	}
+	// Loki and Elasticsearch compatible read apis, authenticated by the api tokens of the users
+	compatGroup := g.Group("/api/compat", middlewares.ApiTokenChecker())
+	{
+		compatGroup.GET("/loki/api/v1/query_range", core.Handle(compat.LokiQueryRange))
+		compatGroup.POST("/loki/api/v1/query_range", core.Handle(compat.LokiQueryRange))
+		compatGroup.GET("/loki/api/v1/labels", core.Handle(compat.LokiLabels))
+		compatGroup.GET("/loki/api/v1/label/:name/values", core.Handle(compat.LokiLabelValues))
+		compatGroup.GET("/loki/api/v1/series", core.Handle(compat.LokiSeries))
+		compatGroup.POST("/loki/api/v1/series", core.Handle(compat.LokiSeries))
+		compatGroup.GET("/es", core.Handle(compat.ESInfo))
+		compatGroup.GET("/es/", core.Handle(compat.ESInfo))
+		compatGroup.POST("/es/_msearch", core.Handle(compat.ESMultiSearch))
+		compatGroup.GET("/es/:index/_mapping", core.Handle(compat.ESMapping))
+		compatGroup.GET("/es/:index/_search", core.Handle(compat.ESSearch))
+		compatGroup.POST("/es/:index/_search", core.Handle(compat.ESSearch))
+	}
 
 	v1(g)
 	v2(g)
@@ -76,5 +98,6 @@ func GetAgentRouter() *egin.Component {
 	k8sAgent := agent.NewAgent()
 	g.GET("/api/v1/search", core.Handle(k8sAgent.Search))
 	g.GET("/api/v1/charts", core.Handle(k8sAgent.Charts))
+	g.GET("/api/v1/context", core.Handle(k8sAgent.Context))
 	return g
 }
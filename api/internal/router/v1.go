@@ -72,6 +72,12 @@ func v1(r *gin.RouterGroup) {
 	r.GET("/tables/:id", core.Handle(base.TableInfo))
 	r.PATCH("/tables/:id", core.Handle(base.TableUpdate))
 	r.GET("/tables/:id/logs", core.Handle(base.TableLogs))
+	r.GET("/tables/:id/logs/export", core.Handle(base.TableLogsExport))
+	r.GET("/tables/:id/logs/tail", core.Handle(base.TableLogsTail))
+	r.GET("/tables/:id/logs/context", core.Handle(base.TableLogsContext))
+	r.GET("/tables/:id/logs/patterns", core.Handle(base.TableLogsPatterns))
+	r.GET("/tables/:id/logs/patterns/diff", core.Handle(base.TableLogsPatternsDiff))
+	r.GET("/tables/:id/logs/stats", core.Handle(base.TableLogsStats))
 	r.DELETE("/tables/:id", core.Handle(base.TableDelete))
 	r.GET("/tables/:id/charts", core.Handle(base.TableCharts))
 	r.GET("/databases/:did/tables", core.Handle(base.TableList))
@@ -106,6 +112,7 @@ func v1(r *gin.RouterGroup) {
 	r.PATCH("/alarms-channels/:id", core.Handle(alarm.ChannelUpdate))
 	r.DELETE("/alarms-channels/:id", core.Handle(alarm.ChannelDelete))
 	r.POST("/alarms-channels/send-test", core.Handle(alarm.ChannelSendTest)) // alarms send test
+	r.POST("/alarms-channels/preview", core.Handle(alarm.ChannelPreview))    // alarms message template preview
 	// OpEvent Operation event interface
 	r.GET("/events", core.Handle(event.ListPage))
 	r.GET("/event/enums", core.Handle(event.GetAllEnums))
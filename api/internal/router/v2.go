@@ -45,6 +45,10 @@ func v2(r *gin.RouterGroup) {
 		r.PATCH("/base/users/:user-id", core.Handle(base.UpdateUser))
 		r.DELETE("/base/users/:user-id", core.Handle(base.DeleteUser))
 		r.PATCH("/base/users/:user-id/password-reset", core.Handle(base.ResetUserPassword))
+		// api tokens of the current user, for the loki and elasticsearch compatible apis
+		r.GET("/base/api-tokens", core.Handle(base.ListApiToken))
+		r.POST("/base/api-tokens", core.Handle(base.CreateApiToken))
+		r.DELETE("/base/api-tokens/:token-id", core.Handle(base.DeleteApiToken))
 		// other apis
 		r.GET("/base/instances", core.Handle(base.InstanceList))
 		// todo: deprecated
@@ -79,16 +83,32 @@ func v2(r *gin.RouterGroup) {
 		r.POST("/storage/mapping-json", core.Handle(storage.KafkaJsonMapping))
 		r.POST("/storage/:template", core.Handle(storage.CreateStorageByTemplate))
 		r.GET("/storage/:storage-id/analysis-fields", core.Handle(storage.AnalysisFields))
+		r.GET("/storage/:storage-id/health", core.Handle(storage.Health))
 		// trace apis
 		r.GET("/storage/traces", core.Handle(storage.GetTraceList))
 		r.PATCH("/storage/:storage-id/trace", core.Handle(storage.UpdateTraceInfo))
 		r.GET("/storage/:storage-id/trace-graph", core.Handle(storage.GetTraceGraph))
 		r.GET("/storage/:storage-id/columns", core.Handle(storage.GetStorageColumns))
+		// ingestion
+		r.GET("/storage/:storage-id/ingest-tokens", core.Handle(storage.ListIngestToken))
+		r.POST("/storage/:storage-id/ingest-tokens", core.Handle(storage.CreateIngestToken))
+		r.DELETE("/storage/:storage-id/ingest-tokens/:token-id", core.Handle(storage.DeleteIngestToken))
 		// collect
 		r.GET("/storage/collects", core.Handle(storage.ListCollect))
 		r.POST("/storage/collects", core.Handle(storage.CreateCollect))
 		r.PATCH("/storage/collects/:collect-id", core.Handle(storage.UpdateCollect))
 		r.DELETE("/storage/collects/:collect-id", core.Handle(storage.DeleteCollect))
+		r.GET("/storage/collects/permalinks/:code", core.Handle(storage.InfoCollectPermalink))
+		// query history
+		r.GET("/storage/query-histories", core.Handle(storage.ListQueryHistory))
+		r.DELETE("/storage/query-histories", core.Handle(storage.DeleteQueryHistory))
+		r.POST("/storage/query-histories/:history-id/promote", core.Handle(storage.PromoteQueryHistory))
+		// query jobs
+		r.POST("/storage/query-jobs", core.Handle(storage.CreateQueryJob))
+		r.GET("/storage/query-jobs", core.Handle(storage.ListQueryJob))
+		r.GET("/storage/query-jobs/:job-id", core.Handle(storage.InfoQueryJob))
+		r.GET("/storage/query-jobs/:job-id/result", core.Handle(storage.ResultQueryJob))
+		r.POST("/storage/query-jobs/:job-id/cancel", core.Handle(storage.CancelQueryJob))
 	}
 	// The log module - alert
 	{
@@ -96,5 +116,23 @@ func v2(r *gin.RouterGroup) {
 		r.GET("/alert/settings/:instance-id", core.Handle(alert.SettingInfo))
 		r.POST("/alert/metrics-samples", core.Handle(alert.CreateMetricsSamples))
 		r.PATCH("/alert/settings/:instance-id", core.Handle(alert.SettingUpdate))
+		r.GET("/alert/silences", core.Handle(alert.ListSilence))
+		r.POST("/alert/silences", core.Handle(alert.CreateSilence))
+		r.PATCH("/alert/silences/:silence-id", core.Handle(alert.UpdateSilence))
+		r.DELETE("/alert/silences/:silence-id", core.Handle(alert.DeleteSilence))
+		r.GET("/alert/oncalls", core.Handle(alert.ListOncall))
+		r.POST("/alert/oncalls", core.Handle(alert.CreateOncall))
+		r.PATCH("/alert/oncalls/:oncall-id", core.Handle(alert.UpdateOncall))
+		r.DELETE("/alert/oncalls/:oncall-id", core.Handle(alert.DeleteOncall))
+		r.GET("/alert/escalations", core.Handle(alert.ListEscalation))
+		r.POST("/alert/escalations", core.Handle(alert.CreateEscalation))
+		r.PATCH("/alert/escalations/:escalation-id", core.Handle(alert.UpdateEscalation))
+		r.DELETE("/alert/escalations/:escalation-id", core.Handle(alert.DeleteEscalation))
+		r.POST("/alert/alarms/:alarm-id/ack", core.Handle(alert.AckAlarm))
+		r.GET("/alert/deliveries", core.Handle(alert.ListDelivery))
+		r.POST("/alert/deliveries/:delivery-id/replay", core.Handle(alert.ReplayDelivery))
+		r.GET("/alert/alarms-export", core.Handle(alert.ExportAlarm))
+		r.POST("/alert/alarms-apply", core.Handle(alert.ApplyAlarm))
+		r.POST("/alert/alarms-backtest", core.Handle(alert.BacktestAlarm))
 	}
 }
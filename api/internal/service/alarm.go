@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/ego-component/egorm"
+	"github.com/google/uuid"
 	"github.com/gotomicro/ego/core/econf"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/pkg/errors"
@@ -19,10 +20,13 @@ import (
 	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 	"github.com/clickvisual/clickvisual/api/internal/service/alarm/alertcomponent"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/evaluator"
 	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
 	"github.com/clickvisual/clickvisual/api/internal/service/alarm/rule"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/bumo"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission"
+	"github.com/clickvisual/clickvisual/api/internal/service/permission/pmsplugin"
 )
 
 // const prometheusRuleTemplate = `groups:
@@ -53,12 +57,6 @@ const (
 	reloadInterval = time.Second * 5
 )
 
-const (
-	NoDataOpDefault = 0
-	NoDataOpOK      = 1
-	NoDataOpAlert   = 2
-)
-
 var _ iAlert = (*alert)(nil)
 
 type iAlert interface {
@@ -71,6 +69,8 @@ type iAlert interface {
 	CreateOrUpdate(alarmObj *db2.Alarm, req view.ReqAlarmCreate) (err error)
 	OpenOperator(id int) (err error)
 	Update(uid, alarmId int, req view.ReqAlarmCreate) (err error)
+	Create(uid int, req view.ReqAlarmCreate) (obj *db2.Alarm, err error)
+	Delete(alarmInfo *db2.Alarm, relatedList []*db2.RespAlarmListRelatedInfo) (err error)
 	AddPrometheusReloadChan()
 	IsAllClosed(instanceId int) (err error)
 	HandlerAlertManager(alarmUUID string, filterId string, notification db2.Notification) (err error)
@@ -124,6 +124,11 @@ func (i *alert) FilterCreate(tx *gorm.DB, alarmObj *db2.Alarm, filters []view.Re
 		if err != nil {
 			return
 		}
+		for _, condition := range filter.Conditions {
+			if condition.Cond == evaluator.CondNewSignature {
+				row.SignatureField = condition.Field
+			}
+		}
 		res[filterObj.ID] = row
 	}
 	return
@@ -134,7 +139,24 @@ func (i *alert) ConditionCreate(tx *gorm.DB, obj *db2.Alarm, conditions []view.R
 	sort.Slice(conditions, func(i, j int) bool {
 		return conditions[i].SetOperatorTyp < conditions[j].SetOperatorTyp
 	})
+	conditionObjs := make([]*db2.AlarmCondition, 0, len(conditions))
 	for _, condition := range conditions {
+		conditionObjs = append(conditionObjs, &db2.AlarmCondition{
+			AlarmId:        obj.ID,
+			FilterId:       filter.ID,
+			SetOperatorTyp: condition.SetOperatorTyp,
+			SetOperatorExp: condition.SetOperatorExp,
+			Cond:           condition.Cond,
+			Val1:           condition.Val1,
+			Val2:           condition.Val2,
+			Baseline:       condition.Baseline,
+			Field:          condition.Field,
+		})
+	}
+	if err = evaluator.ValidConditions(conditionObjs, filter.Mode, obj.GetInterval()); err != nil {
+		return
+	}
+	for _, condition := range conditionObjs {
 		var innerCond string
 		var ot string
 		switch condition.SetOperatorExp {
@@ -159,6 +181,8 @@ func (i *alert) ConditionCreate(tx *gorm.DB, obj *db2.Alarm, conditions []view.R
 			innerCond = fmt.Sprintf("(%s<%d or %s>%d)", expValOverTime, condition.Val1, expValOverTime, condition.Val2)
 		case 3:
 			innerCond = fmt.Sprintf("(%s>=%d and %s<=%d)", expValOverTime, condition.Val1, expValOverTime, condition.Val2)
+		case evaluator.CondPercentChange, evaluator.CondZScore, evaluator.CondNewSignature:
+			innerCond = anomalyExp(obj, filter.ID, ot, condition)
 		}
 		switch condition.SetOperatorTyp {
 		case 0:
@@ -176,16 +200,7 @@ func (i *alert) ConditionCreate(tx *gorm.DB, obj *db2.Alarm, conditions []view.R
 			}
 			exp = fmt.Sprintf("%s or %s", exp, innerCond)
 		}
-		conditionObj := &db2.AlarmCondition{
-			AlarmId:        obj.ID,
-			FilterId:       filter.ID,
-			SetOperatorTyp: condition.SetOperatorTyp,
-			SetOperatorExp: condition.SetOperatorExp,
-			Cond:           condition.Cond,
-			Val1:           condition.Val1,
-			Val2:           condition.Val2,
-		}
-		err = db2.AlarmConditionCreate(tx, conditionObj)
+		err = db2.AlarmConditionCreate(tx, condition)
 		if err != nil {
 			return
 		}
@@ -197,6 +212,33 @@ func (i *alert) ConditionCreate(tx *gorm.DB, obj *db2.Alarm, conditions []view.R
 	return
 }
 
+// anomalyExp returns the prometheus expression of an anomaly condition, the
+// baseline is read from the same metric stream as the current window.
+func anomalyExp(obj *db2.Alarm, filterId int, ot string, condition *db2.AlarmCondition) string {
+	metric := fmt.Sprintf("%s{%s}", bumo.PrometheusMetricName, factory.TagsToString(obj, false, filterId))
+	interval := int(obj.GetInterval().Seconds())
+	baseline := int(evaluator.Baseline(condition).Seconds())
+	cur := fmt.Sprintf("%s(%s[%s] offset 10s)", ot, metric, obj.AlertInterval())
+	switch condition.Cond {
+	case evaluator.CondPercentChange:
+		// the same window one baseline ago, e.g. yesterday or last week
+		base := fmt.Sprintf("%s(%s[%s] offset %ds)", ot, metric, obj.AlertInterval(), 10+baseline)
+		op := ">"
+		if condition.Val1 < 0 {
+			op = "<"
+		}
+		return fmt.Sprintf("((%s - %s) / %s * 100)%s%d", cur, base, base, op, condition.Val1)
+	case evaluator.CondZScore:
+		// windows of the alarm interval before the current one
+		windows := fmt.Sprintf("%s(%s[%s])[%ds:%ds] offset %ds", ot, metric, obj.AlertInterval(), baseline, interval, 10+interval)
+		return fmt.Sprintf("abs((%s - avg_over_time(%s)) / stddev_over_time(%s))>%d", cur, windows, windows, condition.Val1)
+	case evaluator.CondNewSignature:
+		return fmt.Sprintf("(sum by (signature) (sum_over_time(%s[%s] offset 10s))>=%d unless on (signature) sum by (signature) (sum_over_time(%s[%ds] offset %ds)))",
+			metric, obj.AlertInterval(), condition.Val1, metric, baseline, 10+interval)
+	}
+	return ""
+}
+
 func (i *alert) PrometheusReload(prometheusTarget string) (err error) {
 	resp, err := http.Post(strings.TrimSuffix(prometheusTarget, "/")+"/-/reload", "text/html;charset=utf-8", nil)
 	if err != nil {
@@ -366,6 +408,10 @@ func (i *alert) CreateOrUpdate(alarmObj *db2.Alarm, req view.ReqAlarmCreate) (er
 				}
 			}
 		}
+		// native alarms are evaluated by clickvisual, neither views nor rules are required
+		if instance.RuleStoreType == db2.RuleStoreTypeNative {
+			continue
+		}
 		// gen view table name & sql
 		table, ddl, errAlertViewGen := op.GetAlertViewSQL(alarmObj, tableInfo, filterId, &filterItem)
 		if errAlertViewGen != nil {
@@ -408,6 +454,9 @@ func (i *alert) CreateOrUpdate(alarmObj *db2.Alarm, req view.ReqAlarmCreate) (er
 	ups["alert_rules"] = alertRules
 	ups["view_ddl_s"] = viewDDLs
 	ups["status"] = db2.AlarmStatusRuleCheck
+	if len(alertRules) == 0 {
+		ups["status"] = db2.AlarmStatusNormal
+	}
 	err = db2.AlarmUpdate(invoker.Db, alarmObj.ID, ups)
 	if err != nil {
 		return
@@ -428,6 +477,9 @@ func (i *alert) OpenOperator(id int) (err error) {
 	clusterRuleGroups := map[string]db2.ClusterRuleGroup{}
 
 	for _, ri := range relatedList {
+		if ri.Instance.RuleStoreType == db2.RuleStoreTypeNative {
+			continue
+		}
 		op, errInstanceManager := InstanceManager.Load(ri.Instance.ID)
 		if errInstanceManager != nil {
 			return errInstanceManager
@@ -520,12 +572,75 @@ func (i *alert) OpenOperator(id int) (err error) {
 		}
 	}
 	_ = db2.AlarmFilterUpdateStatus(invoker.Db, id, map[string]interface{}{"status": db2.AlarmStatusNormal})
-	if err = db2.AlarmUpdate(invoker.Db, id, map[string]interface{}{"status": db2.AlarmStatusRuleCheck}); err != nil {
+	status := db2.AlarmStatusRuleCheck
+	if len(alarmInfo.AlertRules) == 0 && alarmInfo.AlertRule == "" {
+		status = db2.AlarmStatusNormal
+	}
+	if err = db2.AlarmUpdate(invoker.Db, id, map[string]interface{}{"status": status}); err != nil {
 		return
 	}
 	return
 }
 
+// CloseOperator removes the rules and the views of the alarm and closes it.
+func (i *alert) CloseOperator(alarmInfo *db2.Alarm, relatedList []*db2.RespAlarmListRelatedInfo) (err error) {
+	clusterRuleGroups := map[string]db2.ClusterRuleGroup{}
+	// 删除告警规则
+	for _, ri := range relatedList {
+		instance := ri.Instance
+		if instance.RuleStoreType == db2.RuleStoreTypeK8sOperator {
+			clusterRuleGroup := db2.ClusterRuleGroup{}
+			if tmp, ok := clusterRuleGroups[instance.GetRuleStoreKey()]; ok {
+				clusterRuleGroup = tmp
+			} else {
+				clusterRuleGroup.ClusterId = instance.K8sClusterId
+				clusterRuleGroup.Instance = instance
+				clusterRuleGroup.GroupName = alarmInfo.GetGroupName(instance.ID)
+			}
+			clusterRuleGroups[instance.GetRuleStoreKey()] = clusterRuleGroup
+		} else if instance.RuleStoreType == db2.RuleStoreTypeFile || instance.RuleStoreType == db2.RuleStoreTypeK8sConfigMap {
+			if err = i.DeletePrometheusRule(&ri.Instance, alarmInfo); err != nil {
+				return errors.Wrap(err, "prometheus rule delete failed")
+			}
+		}
+	}
+	if len(clusterRuleGroups) > 0 {
+		_ = i.PrometheusRuleBatchRemove(clusterRuleGroups)
+	}
+	// 删除 clickhouse 物化视图
+	for _, ri := range relatedList {
+		op, errInstanceManager := InstanceManager.Load(ri.Instance.ID)
+		if errInstanceManager != nil {
+			return errInstanceManager
+		}
+		if len(alarmInfo.ViewDDLs) > 0 {
+			for iidTable := range alarmInfo.ViewDDLs {
+				table := iidTable
+				iidTableArr := strings.Split(iidTable, "|")
+				if len(iidTableArr) == 2 {
+					table = iidTableArr[1]
+					iid, _ := strconv.Atoi(iidTableArr[0])
+					if iid != ri.Table.Database.Iid {
+						continue
+					}
+					if op, err = InstanceManager.Load(iid); err != nil {
+						return errors.Wrap(err, "clickhouse load failed")
+					}
+				}
+				if err = op.DeleteAlertView(table, ri.Table.Database.Cluster); err != nil {
+					return errors.Wrap(err, "alert view drop")
+				}
+			}
+		} else {
+			if err = op.DeleteAlertView(alarmInfo.ViewTableName, ri.Table.Database.Cluster); err != nil {
+				return errors.Wrap(err, "alarm update failed when delete metrics view")
+			}
+		}
+	}
+	_ = db2.AlarmFilterUpdateStatus(invoker.Db, alarmInfo.ID, map[string]interface{}{"status": db2.AlarmStatusClose})
+	return db2.AlarmUpdate(invoker.Db, alarmInfo.ID, map[string]interface{}{"status": db2.AlarmStatusClose})
+}
+
 func (i *alert) Update(uid, alarmId int, req view.ReqAlarmCreate) (err error) {
 	if req.Name == "" || req.Interval == 0 || len(req.ChannelIds) == 0 {
 		return errors.New("error params")
@@ -542,6 +657,11 @@ func (i *alert) Update(uid, alarmId int, req view.ReqAlarmCreate) (err error) {
 	ups["channel_ids"] = db2.Ints(req.ChannelIds)
 	ups["duty_officers"] = db2.Ints(req.DutyOfficers)
 	ups["is_disable_resolve"] = req.IsDisableResolve
+	ups["escalation_id"] = req.EscalationId
+	ups["group_by"] = db2.Strings(req.GroupBy)
+	ups["group_wait"] = req.GroupWait
+	ups["group_interval"] = req.GroupInterval
+	ups["repeat_interval"] = req.RepeatInterval
 	tableIds := db2.Ints{}
 	for _, f := range req.Filters {
 		tableIds = append(tableIds, f.Tid)
@@ -575,6 +695,120 @@ func (i *alert) Update(uid, alarmId int, req view.ReqAlarmCreate) (err error) {
 	return
 }
 
+// Create creates an alarm with its filters, conditions, views and rules.
+func (i *alert) Create(uid int, req view.ReqAlarmCreate) (obj *db2.Alarm, err error) {
+	tx := invoker.Db.Begin()
+	tableIds := db2.Ints{}
+	for _, f := range req.Filters {
+		tableIds = append(tableIds, f.Tid)
+	}
+	obj = &db2.Alarm{
+		Uuid:             uuid.NewString(),
+		Name:             req.Name,
+		Desc:             req.Desc,
+		Interval:         req.Interval,
+		Unit:             req.Unit,
+		Tags:             req.Tags,
+		NoDataOp:         req.NoDataOp,
+		ChannelIds:       db2.Ints(req.ChannelIds),
+		Uid:              uid,
+		Level:            req.Level,
+		TableIds:         tableIds,
+		DutyOfficers:     db2.Ints(req.DutyOfficers),
+		IsDisableResolve: req.IsDisableResolve,
+		EscalationId:     req.EscalationId,
+		GroupBy:          req.GroupBy,
+		GroupWait:        req.GroupWait,
+		GroupInterval:    req.GroupInterval,
+		RepeatInterval:   req.RepeatInterval,
+	}
+	if err = db2.AlarmCreate(tx, obj); err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "alarm create failed 01")
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, errors.Wrap(err, "alarm create failed 03")
+	}
+	if err = i.CreateOrUpdate(obj, req); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Delete removes an alarm with its filters, conditions, views and rules.
+func (i *alert) Delete(alarmInfo *db2.Alarm, relatedList []*db2.RespAlarmListRelatedInfo) (err error) {
+	tx := invoker.Db.Begin()
+	if err = db2.AlarmDelete(tx, alarmInfo.ID); err != nil {
+		tx.Rollback()
+		return
+	}
+	// filter
+	if err = db2.AlarmFilterDeleteBatch(tx, alarmInfo.ID); err != nil {
+		tx.Rollback()
+		return
+	}
+	// condition
+	if err = db2.AlarmConditionDeleteBatch(tx, alarmInfo.ID); err != nil {
+		tx.Rollback()
+		return
+	}
+	clusterRuleGroups := map[string]db2.ClusterRuleGroup{}
+	for _, ri := range relatedList {
+		instance := ri.Instance
+		if instance.RuleStoreType == db2.RuleStoreTypeK8sOperator {
+			clusterRuleGroup := db2.ClusterRuleGroup{}
+			if tmp, ok := clusterRuleGroups[instance.GetRuleStoreKey()]; ok {
+				clusterRuleGroup = tmp
+			} else {
+				clusterRuleGroup.ClusterId = instance.K8sClusterId
+				clusterRuleGroup.Instance = instance
+				clusterRuleGroup.GroupName = alarmInfo.GetGroupName(instance.ID)
+			}
+			clusterRuleGroups[instance.GetRuleStoreKey()] = clusterRuleGroup
+		} else if instance.RuleStoreType == db2.RuleStoreTypeFile || instance.RuleStoreType == db2.RuleStoreTypeK8sConfigMap {
+			_ = i.DeletePrometheusRule(&ri.Instance, alarmInfo)
+		}
+		var op factory.Operator
+		op, err = InstanceManager.Load(ri.Table.Database.Iid)
+		if err != nil {
+			tx.Rollback()
+			return
+		}
+		if len(alarmInfo.ViewDDLs) > 0 {
+			for iidTable := range alarmInfo.ViewDDLs {
+				table := iidTable
+				iidTableArr := strings.Split(iidTable, "|")
+				if len(iidTableArr) == 2 {
+					table = iidTableArr[1]
+					iid, _ := strconv.Atoi(iidTableArr[0])
+					op, err = InstanceManager.Load(iid)
+					if err != nil {
+						tx.Rollback()
+						return
+					}
+					if iid != ri.Table.Database.Iid {
+						continue
+					}
+				}
+				if err = op.DeleteAlertView(table, ri.Table.Database.Cluster); err != nil {
+					tx.Rollback()
+					return
+				}
+			}
+		} else {
+			if err = op.DeleteAlertView(alarmInfo.ViewTableName, ri.Table.Database.Cluster); err != nil {
+				tx.Rollback()
+				return
+			}
+		}
+	}
+	if len(clusterRuleGroups) > 0 {
+		_ = i.PrometheusRuleBatchRemove(clusterRuleGroups)
+	}
+	return tx.Commit().Error
+}
+
 func (i *alert) AddPrometheusReloadChan() {
 	// 10 times
 	for k := 0; k < reloadTimes; k++ {
@@ -721,6 +955,49 @@ func SendTestToChannel(c *db2.AlarmChannel) (err error) {
 	return
 }
 
+// PreviewChannelTemplate renders the message of a channel template without sending it
+func PreviewChannelTemplate(req view.ReqAlarmChannelPreview) (*db2.PushMsg, error) {
+	if err := pusher.ParseTemplate(req.Template); err != nil {
+		return nil, errors.Wrap(err, "template parse failed")
+	}
+	status := "firing"
+	if req.Status == "resolved" {
+		status = req.Status
+	}
+	now := time.Now()
+	notification := db2.Notification{Status: status, Alerts: []db2.Alert{{StartsAt: now.Add(-time.Minute)}}}
+	if status == "resolved" {
+		notification.Alerts[0].EndsAt = now
+	}
+	if req.AlarmId == 0 {
+		ctx := pusher.NewPreviewContext(notification,
+			&db2.BaseTable{Name: "ingress_stdout", Desc: "nginx ingress"},
+			&db2.BaseInstance{Name: "clickhouse", Desc: "sample instance"},
+			&db2.Alarm{Name: "ingress 5xx", Desc: "sample alarm", Interval: 1},
+			&db2.AlarmFilter{When: "status >= 500"},
+			`{"status":"502","url":"/api/v1/sample"}`,
+		)
+		return pusher.Render(req.Template, req.Typ, ctx)
+	}
+	alarm, err := db2.AlarmInfo(invoker.Db, req.AlarmId)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := Alert.compatibleFilter(alarm.ID, 0)
+	if err != nil {
+		return nil, err
+	}
+	table, err := db2.TableInfo(invoker.Db, filter.Tid)
+	if err != nil {
+		return nil, err
+	}
+	if _, err = escalationTarget(&alarm, 0, now); err != nil {
+		return nil, err
+	}
+	ctx := pusher.NewTemplateContext(notification, &table, &alarm, filter, "").WithoutShortURL()
+	return pusher.Render(req.Template, req.Typ, ctx)
+}
+
 func AlarmAttachInfo(respList []*db2.Alarm) []view.RespAlarmList {
 	res := make([]view.RespAlarmList, 0)
 	cache := make(map[int]*db2.RespAlarmListRelatedInfo, 0)
@@ -771,11 +1048,11 @@ func aggregationOp(mode int, exp string, expVal string) string {
 
 func noDataOp(op int, exp, expVal string) string {
 	switch op {
-	case NoDataOpDefault:
+	case db2.NoDataOpDefault:
 		return exp
-	case NoDataOpOK:
+	case db2.NoDataOpOK:
 		return fmt.Sprintf("(%s) or absent(%s)!=1", exp, expVal)
-	case NoDataOpAlert:
+	case db2.NoDataOpAlert:
 		return fmt.Sprintf("(%s) or absent(%s)==1", exp, expVal)
 	default:
 		return exp
@@ -796,3 +1073,29 @@ func alarmRuleDelete(instance *db2.BaseInstance, groupName, ruleName string) (er
 	}
 	return rc.Delete(groupName, ruleName)
 }
+
+// CheckAlarmPermission requires the act of alarm permission on every table of the alarm,
+// alarm id 0 stands for any alarm and is limited to root users.
+func CheckAlarmPermission(uid, alarmId int, act string) error {
+	if alarmId == 0 {
+		return permission.Manager.IsRootUser(uid)
+	}
+	_, relatedList, err := db2.GetAlarmTableInstanceInfo(alarmId)
+	if err != nil {
+		return err
+	}
+	for _, ri := range relatedList {
+		if err = permission.Manager.CheckNormalPermission(view.ReqPermission{
+			UserId:      uid,
+			ObjectType:  pmsplugin.PrefixInstance,
+			ObjectIdx:   strconv.Itoa(ri.Table.Database.Iid),
+			SubResource: pmsplugin.Alarm,
+			Acts:        []string{act},
+			DomainType:  pmsplugin.PrefixTable,
+			DomainId:    strconv.Itoa(ri.Table.ID),
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,190 @@
+package evaluator
+
+import (
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// Default baselines of the anomaly conditions, in seconds.
+const (
+	DefaultBaselinePercentChange = 86400     // the same window yesterday
+	DefaultBaselineZScore        = 3600      // the windows of the last hour
+	DefaultBaselineNewSignature  = 86400 * 7 // signatures seen in the last 7 days
+)
+
+// MaxBaselineZScore bounds the z-score baseline, it is read as per second samples.
+const MaxBaselineZScore = 86400
+
+var (
+	ErrAnomalyMatcher   = errors.New("anomaly conditions are not supported here")
+	ErrAnomalyMode      = errors.New("anomaly conditions only support the normal mode")
+	ErrAnomalyBaseline  = errors.New("anomaly condition baseline error")
+	ErrAnomalyThreshold = errors.New("anomaly condition threshold error")
+	ErrSignatureField   = errors.New("new signature condition requires a valid field")
+	ErrSignatureOnly    = errors.New("new signature condition must be the only condition of a filter")
+)
+
+var signatureFieldRegexp = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// AnomalyMatcher reports whether an anomaly condition matches, cur is the
+// aggregation of the current window selected by the condition.
+type AnomalyMatcher func(cond *db.AlarmCondition, cur float64) (bool, error)
+
+// Sample is a value of the metric stream at a unix second.
+type Sample struct {
+	Ts  int64
+	Val float64
+}
+
+// IsAnomaly reports whether cond compares the current window to a baseline.
+func IsAnomaly(cond int) bool {
+	return cond == CondPercentChange || cond == CondZScore || cond == CondNewSignature
+}
+
+// Baseline returns the baseline window of an anomaly condition.
+func Baseline(cond *db.AlarmCondition) time.Duration {
+	if cond.Baseline > 0 {
+		return time.Duration(cond.Baseline) * time.Second
+	}
+	switch cond.Cond {
+	case CondPercentChange:
+		return DefaultBaselinePercentChange * time.Second
+	case CondZScore:
+		return DefaultBaselineZScore * time.Second
+	case CondNewSignature:
+		return DefaultBaselineNewSignature * time.Second
+	}
+	return 0
+}
+
+// ValidConditions checks the anomaly conditions of a filter evaluated every interval.
+func ValidConditions(conditions []*db.AlarmCondition, mode int, interval time.Duration) error {
+	for _, cond := range conditions {
+		if !IsAnomaly(cond.Cond) {
+			continue
+		}
+		if mode != 0 {
+			return ErrAnomalyMode
+		}
+		if cond.Baseline < 0 {
+			return ErrAnomalyBaseline
+		}
+		baseline := Baseline(cond)
+		switch cond.Cond {
+		case CondPercentChange:
+			if cond.Val1 == 0 {
+				return ErrAnomalyThreshold
+			}
+			if baseline < interval {
+				return errors.Wrapf(ErrAnomalyBaseline, "percent change baseline must not be shorter than %s", interval)
+			}
+		case CondZScore:
+			if cond.Val1 <= 0 {
+				return ErrAnomalyThreshold
+			}
+			if baseline < 2*interval || baseline > MaxBaselineZScore*time.Second {
+				return errors.Wrapf(ErrAnomalyBaseline, "z-score baseline must be between %s and %ds", 2*interval, MaxBaselineZScore)
+			}
+		case CondNewSignature:
+			if cond.Val1 <= 0 {
+				return ErrAnomalyThreshold
+			}
+			if !signatureFieldRegexp.MatchString(cond.Field) {
+				return ErrSignatureField
+			}
+			if len(conditions) != 1 {
+				return ErrSignatureOnly
+			}
+		}
+	}
+	return nil
+}
+
+// SignatureField returns the field of the new signature condition, empty when there is none.
+func SignatureField(conditions []*db.AlarmCondition) string {
+	for _, cond := range conditions {
+		if cond.Cond == CondNewSignature {
+			return cond.Field
+		}
+	}
+	return ""
+}
+
+// PercentChange returns the change of cur relative to base in percent.
+// Like prometheus it is +Inf/-Inf when base is 0 and NaN when both are 0.
+func PercentChange(cur, base float64) float64 {
+	return (cur - base) / base * 100
+}
+
+// MatchPercentChange reports whether the change exceeds Val1, a negative Val1 matches drops.
+func MatchPercentChange(cond *db.AlarmCondition, cur, base float64) bool {
+	change := PercentChange(cur, base)
+	if cond.Val1 < 0 {
+		return change < float64(cond.Val1)
+	}
+	return change > float64(cond.Val1)
+}
+
+// ZScore returns the z-score of cur against the baseline values using the
+// population standard deviation, as stddev_over_time does.
+// The second return value is false when there is no baseline.
+func ZScore(cur float64, baseline []float64) (float64, bool) {
+	if len(baseline) == 0 {
+		return 0, false
+	}
+	var sum float64
+	for _, v := range baseline {
+		sum += v
+	}
+	mean := sum / float64(len(baseline))
+	var variance float64
+	for _, v := range baseline {
+		variance += (v - mean) * (v - mean)
+	}
+	stddev := math.Sqrt(variance / float64(len(baseline)))
+	return (cur - mean) / stddev, true
+}
+
+// MatchZScore reports whether the absolute z-score of cur exceeds Val1.
+func MatchZScore(cond *db.AlarmCondition, cur float64, baseline []float64) bool {
+	z, ok := ZScore(cur, baseline)
+	return ok && math.Abs(z) > float64(cond.Val1)
+}
+
+// Windows splits the samples of [st, et) into consecutive windows of the given
+// length and aggregates each of them with exp, empty windows are skipped like
+// the steps without samples of a prometheus subquery.
+func Windows(exp int, samples []Sample, st, et int64, window int64) []float64 {
+	if window <= 0 || et <= st {
+		return nil
+	}
+	buckets := make([][]float64, (et-st+window-1)/window)
+	for _, s := range samples {
+		if s.Ts < st || s.Ts >= et {
+			continue
+		}
+		idx := (s.Ts - st) / window
+		buckets[idx] = append(buckets[idx], s.Val)
+	}
+	res := make([]float64, 0, len(buckets))
+	for _, bucket := range buckets {
+		if val, ok := Aggregate(exp, bucket); ok {
+			res = append(res, val)
+		}
+	}
+	return res
+}
+
+// Values returns the values of the samples.
+func Values(samples []Sample) []float64 {
+	res := make([]float64, 0, len(samples))
+	for _, s := range samples {
+		res = append(res, s.Val)
+	}
+	return res
+}
@@ -0,0 +1,94 @@
+package evaluator
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestValidConditions(t *testing.T) {
+	tests := []struct {
+		name  string
+		conds []*db.AlarmCondition
+		mode  int
+		want  error
+	}{
+		{name: "threshold", conds: []*db.AlarmCondition{{Cond: CondAbove, Val1: 1}}, mode: db.AlarmModeAggregation},
+		{name: "percent change", conds: []*db.AlarmCondition{{Cond: CondPercentChange, Val1: 50}}},
+		{name: "aggregation mode", conds: []*db.AlarmCondition{{Cond: CondPercentChange, Val1: 50}}, mode: db.AlarmModeAggregation, want: ErrAnomalyMode},
+		{name: "zero change", conds: []*db.AlarmCondition{{Cond: CondPercentChange}}, want: ErrAnomalyThreshold},
+		{name: "short baseline", conds: []*db.AlarmCondition{{Cond: CondPercentChange, Val1: 50, Baseline: 30}}, want: ErrAnomalyBaseline},
+		{name: "z-score", conds: []*db.AlarmCondition{{Cond: CondZScore, Val1: 3}}},
+		{name: "long z-score baseline", conds: []*db.AlarmCondition{{Cond: CondZScore, Val1: 3, Baseline: MaxBaselineZScore + 1}}, want: ErrAnomalyBaseline},
+		{name: "new signature", conds: []*db.AlarmCondition{{Cond: CondNewSignature, Val1: 1, Field: "code"}}},
+		{name: "signature field", conds: []*db.AlarmCondition{{Cond: CondNewSignature, Val1: 1, Field: "code`"}}, want: ErrSignatureField},
+		{name: "signature only", conds: []*db.AlarmCondition{
+			{Cond: CondNewSignature, Val1: 1, Field: "code"},
+			{SetOperatorTyp: OperatorAnd, Cond: CondAbove, Val1: 1},
+		}, want: ErrSignatureOnly},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidConditions(tt.conds, tt.mode, time.Minute)
+			if tt.want == nil {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestMatchPercentChange(t *testing.T) {
+	rise := &db.AlarmCondition{Cond: CondPercentChange, Val1: 50}
+	drop := &db.AlarmCondition{Cond: CondPercentChange, Val1: -50}
+	assert.True(t, MatchPercentChange(rise, 160, 100))
+	assert.False(t, MatchPercentChange(rise, 150, 100))
+	assert.True(t, MatchPercentChange(rise, 1, 0))
+	assert.False(t, MatchPercentChange(rise, 0, 0))
+	assert.True(t, MatchPercentChange(drop, 40, 100))
+	assert.False(t, MatchPercentChange(drop, 60, 100))
+	assert.False(t, MatchPercentChange(drop, 0, 0))
+}
+
+func TestZScore(t *testing.T) {
+	z, ok := ZScore(9, []float64{2, 4, 4, 4, 5, 5, 7, 9})
+	assert.True(t, ok)
+	assert.Equal(t, 2.0, z)
+	_, ok = ZScore(1, nil)
+	assert.False(t, ok)
+	z, _ = ZScore(2, []float64{1, 1})
+	assert.True(t, math.IsInf(z, 1))
+
+	cond := &db.AlarmCondition{Cond: CondZScore, Val1: 1}
+	assert.True(t, MatchZScore(cond, 9, []float64{2, 4, 4, 4, 5, 5, 7, 9}))
+	assert.False(t, MatchZScore(cond, 5, []float64{2, 4, 4, 4, 5, 5, 7, 9}))
+}
+
+func TestWindows(t *testing.T) {
+	samples := []Sample{{Ts: 100, Val: 1}, {Ts: 101, Val: 2}, {Ts: 130, Val: 4}, {Ts: 199, Val: 5}, {Ts: 200, Val: 9}}
+	assert.Equal(t, []float64{3, 4, 5}, Windows(ExpSum, samples, 100, 200, 25))
+	assert.Nil(t, Windows(ExpSum, samples, 100, 100, 25))
+}
+
+func TestEvaluateAnomaly(t *testing.T) {
+	conds := []*db.AlarmCondition{
+		{SetOperatorTyp: OperatorWhen, SetOperatorExp: ExpSum, Cond: CondPercentChange, Val1: 50},
+		{SetOperatorTyp: OperatorAnd, SetOperatorExp: ExpSum, Cond: CondAbove, Val1: 10},
+	}
+	baseline := func(cond *db.AlarmCondition, cur float64) (bool, error) {
+		return MatchPercentChange(cond, cur, 10), nil
+	}
+	status, err := EvaluateAnomaly(conds, []float64{10, 10}, 0, db.NoDataOpDefault, baseline)
+	assert.NoError(t, err)
+	assert.Equal(t, db.AlarmStatusFiring, status)
+	status, err = EvaluateAnomaly(conds, []float64{6, 6}, 0, db.NoDataOpDefault, baseline)
+	assert.NoError(t, err)
+	assert.Equal(t, db.AlarmStatusNormal, status)
+	_, err = Evaluate(conds, []float64{10}, 0, db.NoDataOpDefault)
+	assert.ErrorIs(t, err, ErrAnomalyMatcher)
+}
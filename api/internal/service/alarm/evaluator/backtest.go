@@ -0,0 +1,49 @@
+package evaluator
+
+import (
+	"sort"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// SortSamples sorts the samples by time, Between requires sorted samples.
+func SortSamples(samples []Sample) {
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].Ts < samples[j].Ts
+	})
+}
+
+// Between returns the sorted samples in [st, et) without copying them.
+func Between(samples []Sample, st, et int64) []Sample {
+	from := sort.Search(len(samples), func(i int) bool { return samples[i].Ts >= st })
+	to := sort.Search(len(samples), func(i int) bool { return samples[i].Ts >= et })
+	if from >= to {
+		return nil
+	}
+	return samples[from:to]
+}
+
+// Backtest evaluates the conditions on the consecutive windows of the given
+// length in [st, et), the windows the native evaluator reads when it runs
+// every interval. samples are sorted and cover the windows, the baselines of
+// the anomaly conditions are matched by the matcher returned for a window.
+// It returns the status of every window.
+func Backtest(conditions []*db.AlarmCondition, samples []Sample, mode, noDataOp int, st, et, window int64,
+	matcher func(st, et int64) AnomalyMatcher) ([]int, error) {
+	if window <= 0 {
+		return nil, ErrConditions
+	}
+	res := make([]int, 0, (et-st)/window)
+	for end := st + window; end <= et; end += window {
+		var anomaly AnomalyMatcher
+		if matcher != nil {
+			anomaly = matcher(end-window, end)
+		}
+		status, err := EvaluateAnomaly(conditions, Values(Between(samples, end-window, end)), mode, noDataOp, anomaly)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, status)
+	}
+	return res, nil
+}
@@ -0,0 +1,45 @@
+package evaluator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestBetween(t *testing.T) {
+	samples := []Sample{{Ts: 30, Val: 3}, {Ts: 10, Val: 1}, {Ts: 20, Val: 2}}
+	SortSamples(samples)
+	assert.Equal(t, []float64{1, 2, 3}, Values(samples))
+	assert.Equal(t, []float64{2}, Values(Between(samples, 15, 30)))
+	assert.Equal(t, []float64{1, 2, 3}, Values(Between(samples, 10, 31)))
+	assert.Empty(t, Between(samples, 31, 40))
+	assert.Empty(t, Between(samples, 20, 20))
+}
+
+func TestBacktest(t *testing.T) {
+	conds := []*db.AlarmCondition{{SetOperatorExp: ExpSum, Cond: CondAbove, Val1: 5}}
+	// windows [0,60) [60,120) [120,180) [180,240)
+	samples := []Sample{{Ts: 0, Val: 1}, {Ts: 70, Val: 4}, {Ts: 90, Val: 4}, {Ts: 200, Val: 1}}
+
+	got, err := Backtest(conds, samples, 0, db.NoDataOpDefault, 0, 240, 60, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{db.AlarmStatusNormal, db.AlarmStatusFiring, db.AlarmStatusUnknown, db.AlarmStatusNormal}, got)
+
+	got, err = Backtest(conds, samples, 0, db.NoDataOpAlert, 0, 230, 60, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{db.AlarmStatusNormal, db.AlarmStatusFiring, db.AlarmStatusFiring}, got, "partial windows are dropped")
+
+	var windows [][2]int64
+	_, err = Backtest([]*db.AlarmCondition{{Cond: CondPercentChange, Val1: 50}}, samples, 0, db.NoDataOpDefault, 0, 120, 60,
+		func(st, et int64) AnomalyMatcher {
+			windows = append(windows, [2]int64{st, et})
+			return func(cond *db.AlarmCondition, cur float64) (bool, error) { return false, nil }
+		})
+	assert.NoError(t, err)
+	assert.Equal(t, [][2]int64{{0, 60}, {60, 120}}, windows)
+
+	_, err = Backtest(conds, samples, 0, db.NoDataOpDefault, 0, 240, 0, nil)
+	assert.ErrorIs(t, err, ErrConditions)
+}
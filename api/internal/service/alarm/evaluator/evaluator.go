@@ -0,0 +1,192 @@
+package evaluator
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+const (
+	ExpAvg = iota
+	ExpMin
+	ExpMax
+	ExpSum
+	ExpCount
+)
+
+const (
+	CondAbove = iota
+	CondBelow
+	CondOutside
+	CondWithin
+	CondPercentChange
+	CondZScore
+	CondNewSignature
+)
+
+const (
+	OperatorWhen = iota
+	OperatorAnd
+	OperatorOr
+)
+
+// Offset keeps the evaluation window behind the wall clock, the same as the
+// `offset 10s` used by the generated prometheus rules, so late writes are counted.
+const Offset = time.Second * 10
+
+var ErrConditions = errors.New("conditions error")
+
+// Window returns the [st, et) range evaluated for an alarm at time now.
+func Window(alarm *db.Alarm, now time.Time) (st, et int64) {
+	end := now.Add(-Offset)
+	return end.Add(-alarm.GetInterval()).Unix(), end.Unix()
+}
+
+// Aggregate applies the *_over_time function selected by exp to the samples.
+// The second return value is false when there are no samples.
+func Aggregate(exp int, samples []float64) (float64, bool) {
+	if len(samples) == 0 {
+		return 0, false
+	}
+	switch exp {
+	case ExpMin:
+		res := math.Inf(1)
+		for _, v := range samples {
+			res = math.Min(res, v)
+		}
+		return res, true
+	case ExpMax:
+		res := math.Inf(-1)
+		for _, v := range samples {
+			res = math.Max(res, v)
+		}
+		return res, true
+	case ExpSum:
+		var res float64
+		for _, v := range samples {
+			res += v
+		}
+		return res, true
+	case ExpCount:
+		return float64(len(samples)), true
+	default:
+		var res float64
+		for _, v := range samples {
+			res += v
+		}
+		return res / float64(len(samples)), true
+	}
+}
+
+// Match reports whether val satisfies a single alarm condition.
+func Match(cond *db.AlarmCondition, val float64) bool {
+	v1, v2 := float64(cond.Val1), float64(cond.Val2)
+	switch cond.Cond {
+	case CondAbove:
+		return val > v1
+	case CondBelow:
+		return val < v1
+	case CondOutside:
+		return val < v1 || val > v2
+	case CondWithin:
+		return val >= v1 && val <= v2
+	}
+	return false
+}
+
+// Evaluate returns the filter status computed from its conditions and samples.
+// It mirrors the prometheus expression built by ConditionCreate: conditions are
+// chained in set operator order, aggregation mode treats -1 as no data, and
+// noDataOp decides what an empty window means.
+// AlarmStatusUnknown is returned when there is no data and noDataOp keeps the current state.
+func Evaluate(conditions []*db.AlarmCondition, samples []float64, mode int, noDataOp int) (int, error) {
+	return EvaluateAnomaly(conditions, samples, mode, noDataOp, nil)
+}
+
+// EvaluateAnomaly is Evaluate with anomaly conditions, they are matched by the
+// given function since their baseline has to be read from the data source.
+func EvaluateAnomaly(conditions []*db.AlarmCondition, samples []float64, mode int, noDataOp int, anomaly AnomalyMatcher) (int, error) {
+	if mode == db.AlarmModeAggregation {
+		valid := make([]float64, 0, len(samples))
+		for _, v := range samples {
+			if v != -1 {
+				valid = append(valid, v)
+			}
+		}
+		samples = valid
+	}
+	if len(samples) == 0 {
+		switch noDataOp {
+		case db.NoDataOpOK:
+			return db.AlarmStatusNormal, nil
+		case db.NoDataOpAlert:
+			return db.AlarmStatusFiring, nil
+		}
+		return db.AlarmStatusUnknown, nil
+	}
+	sorted := make([]*db.AlarmCondition, len(conditions))
+	copy(sorted, conditions)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].SetOperatorTyp < sorted[j].SetOperatorTyp
+	})
+	var (
+		res     bool
+		started bool
+	)
+	for _, cond := range sorted {
+		val, _ := Aggregate(cond.SetOperatorExp, samples)
+		var matched bool
+		if IsAnomaly(cond.Cond) {
+			if anomaly == nil {
+				return db.AlarmStatusUnknown, ErrAnomalyMatcher
+			}
+			var err error
+			if matched, err = anomaly(cond, val); err != nil {
+				return db.AlarmStatusUnknown, err
+			}
+		} else {
+			matched = Match(cond, val)
+		}
+		switch cond.SetOperatorTyp {
+		case OperatorWhen:
+			res = matched
+			started = true
+		case OperatorAnd:
+			if !started {
+				return db.AlarmStatusUnknown, ErrConditions
+			}
+			res = res && matched
+		case OperatorOr:
+			if !started {
+				return db.AlarmStatusUnknown, ErrConditions
+			}
+			res = res || matched
+		}
+	}
+	if res {
+		return db.AlarmStatusFiring, nil
+	}
+	return db.AlarmStatusNormal, nil
+}
+
+// ToFloat64 converts a value scanned by a factory.Operator into a sample.
+func ToFloat64(v interface{}) (float64, error) {
+	switch val := v.(type) {
+	case nil:
+		return 0, errors.New("empty value")
+	case *float64:
+		return *val, nil
+	case *int64:
+		return float64(*val), nil
+	case *uint64:
+		return float64(*val), nil
+	case *string:
+		return cast.ToFloat64E(*val)
+	}
+	return cast.ToFloat64E(v)
+}
@@ -0,0 +1,100 @@
+package evaluator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestAggregate(t *testing.T) {
+	samples := []float64{3, 1, 2, 6}
+	tests := []struct {
+		name string
+		exp  int
+		want float64
+	}{
+		{name: "avg", exp: ExpAvg, want: 3},
+		{name: "min", exp: ExpMin, want: 1},
+		{name: "max", exp: ExpMax, want: 6},
+		{name: "sum", exp: ExpSum, want: 12},
+		{name: "count", exp: ExpCount, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Aggregate(tt.exp, samples)
+			assert.True(t, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+	_, ok := Aggregate(ExpSum, nil)
+	assert.False(t, ok)
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		cond db.AlarmCondition
+		val  float64
+		want bool
+	}{
+		{name: "above", cond: db.AlarmCondition{Cond: CondAbove, Val1: 10}, val: 11, want: true},
+		{name: "not above", cond: db.AlarmCondition{Cond: CondAbove, Val1: 10}, val: 10, want: false},
+		{name: "below", cond: db.AlarmCondition{Cond: CondBelow, Val1: 10}, val: 9, want: true},
+		{name: "outside", cond: db.AlarmCondition{Cond: CondOutside, Val1: 10, Val2: 20}, val: 21, want: true},
+		{name: "not outside", cond: db.AlarmCondition{Cond: CondOutside, Val1: 10, Val2: 20}, val: 15, want: false},
+		{name: "within", cond: db.AlarmCondition{Cond: CondWithin, Val1: 10, Val2: 20}, val: 20, want: true},
+		{name: "not within", cond: db.AlarmCondition{Cond: CondWithin, Val1: 10, Val2: 20}, val: 9, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Match(&tt.cond, tt.val))
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	sumAbove := &db.AlarmCondition{SetOperatorTyp: OperatorWhen, SetOperatorExp: ExpSum, Cond: CondAbove, Val1: 10}
+	maxBelow := &db.AlarmCondition{SetOperatorTyp: OperatorAnd, SetOperatorExp: ExpMax, Cond: CondBelow, Val1: 5}
+	countAbove := &db.AlarmCondition{SetOperatorTyp: OperatorOr, SetOperatorExp: ExpCount, Cond: CondAbove, Val1: 2}
+	tests := []struct {
+		name       string
+		conditions []*db.AlarmCondition
+		samples    []float64
+		mode       int
+		noDataOp   int
+		want       int
+		wantErr    bool
+	}{
+		{name: "firing", conditions: []*db.AlarmCondition{sumAbove}, samples: []float64{5, 6}, want: db.AlarmStatusFiring},
+		{name: "normal", conditions: []*db.AlarmCondition{sumAbove}, samples: []float64{5, 5}, want: db.AlarmStatusNormal},
+		{name: "and", conditions: []*db.AlarmCondition{maxBelow, sumAbove}, samples: []float64{4, 4, 4}, want: db.AlarmStatusFiring},
+		{name: "and normal", conditions: []*db.AlarmCondition{sumAbove, maxBelow}, samples: []float64{6, 6}, want: db.AlarmStatusNormal},
+		{name: "or", conditions: []*db.AlarmCondition{sumAbove, countAbove}, samples: []float64{1, 1, 1}, want: db.AlarmStatusFiring},
+		{name: "no data default", conditions: []*db.AlarmCondition{sumAbove}, noDataOp: db.NoDataOpDefault, want: db.AlarmStatusUnknown},
+		{name: "no data ok", conditions: []*db.AlarmCondition{sumAbove}, noDataOp: db.NoDataOpOK, want: db.AlarmStatusNormal},
+		{name: "no data alert", conditions: []*db.AlarmCondition{sumAbove}, noDataOp: db.NoDataOpAlert, want: db.AlarmStatusFiring},
+		{name: "aggregation no data", conditions: []*db.AlarmCondition{sumAbove}, samples: []float64{-1}, mode: db.AlarmModeAggregation, noDataOp: db.NoDataOpAlert, want: db.AlarmStatusFiring},
+		{name: "missing when", conditions: []*db.AlarmCondition{maxBelow}, samples: []float64{1}, want: db.AlarmStatusUnknown, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Evaluate(tt.conditions, tt.samples, tt.mode, tt.noDataOp)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWindow(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	st, et := Window(&db.Alarm{Interval: 5, Unit: 0}, now)
+	assert.Equal(t, int64(1700000000-10), et)
+	assert.Equal(t, int64(1700000000-10-300), st)
+}
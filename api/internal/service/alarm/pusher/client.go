@@ -0,0 +1,63 @@
+package pusher
+
+import (
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/pkg/errors"
+)
+
+var (
+	clientOnce  sync.Once
+	httpClient  *http.Client
+	restyClient *resty.Client
+)
+
+// HTTPClient returns the client shared by the pushers, the timeout is alarm.delivery.timeout, 10s by default.
+func HTTPClient() *http.Client {
+	clientOnce.Do(func() {
+		timeout := econf.GetDuration("alarm.delivery.timeout")
+		if timeout <= 0 {
+			timeout = 10 * time.Second
+		}
+		httpClient = &http.Client{
+			Timeout: timeout,
+			Transport: &http.Transport{
+				Proxy:                 http.ProxyFromEnvironment,
+				DialContext:           (&net.Dialer{Timeout: 5 * time.Second, KeepAlive: 30 * time.Second}).DialContext,
+				MaxIdleConns:          100,
+				MaxIdleConnsPerHost:   10,
+				IdleConnTimeout:       90 * time.Second,
+				TLSHandshakeTimeout:   5 * time.Second,
+				ExpectContinueTimeout: time.Second,
+			},
+		}
+		restyClient = resty.NewWithClient(httpClient)
+	})
+	return httpClient
+}
+
+func restyR() *resty.Request {
+	HTTPClient()
+	return restyClient.R()
+}
+
+// checkErrCode checks the http status and the errcode of the DingDing and WeChat robot responses.
+func checkErrCode(resp *http.Response, body []byte) error {
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("status: %d, body: %s", resp.StatusCode, string(body))
+	}
+	var res struct {
+		ErrCode int    `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+	}
+	if err := json.Unmarshal(body, &res); err == nil && res.ErrCode != 0 {
+		return errors.Errorf("errcode: %d, errmsg: %s", res.ErrCode, res.ErrMsg)
+	}
+	return nil
+}
@@ -0,0 +1,263 @@
+package pusher
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+	"go.uber.org/multierr"
+	"golang.org/x/time/rate"
+	"gorm.io/gorm"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var (
+	ErrRateLimited         = errors.New("channel rate limited, the delivery is postponed")
+	ErrDeliveryNotReplayed = errors.New("only dead deliveries can be replayed")
+	ErrDeliveryQueued      = errors.New("send failed, the delivery is queued for retry")
+)
+
+// pendingTimeout is the time after which a pending delivery is considered abandoned, e.g. the process exited
+// during its first attempt, and it is retried.
+const pendingTimeout = 10 * time.Minute
+
+type deliveryConfig struct {
+	maxAttempts int
+	backoff     time.Duration
+	maxBackoff  time.Duration
+	rateLimit   rate.Limit
+	burst       int
+}
+
+var (
+	deliveryOnce sync.Once
+	delivery     deliveryConfig
+	limiters     sync.Map // channel id -> *rate.Limiter
+)
+
+// deliveryConf reads the alarm.delivery config:
+// maxAttempts 8, backoff 10s, maxBackoff 30m, rateLimit 30 messages per minute and burst 10 by default.
+func deliveryConf() deliveryConfig {
+	deliveryOnce.Do(func() {
+		delivery = deliveryConfig{
+			maxAttempts: econf.GetInt("alarm.delivery.maxAttempts"),
+			backoff:     econf.GetDuration("alarm.delivery.backoff"),
+			maxBackoff:  econf.GetDuration("alarm.delivery.maxBackoff"),
+			burst:       econf.GetInt("alarm.delivery.burst"),
+		}
+		if delivery.maxAttempts <= 0 {
+			delivery.maxAttempts = 8
+		}
+		if delivery.backoff <= 0 {
+			delivery.backoff = 10 * time.Second
+		}
+		if delivery.maxBackoff <= 0 {
+			delivery.maxBackoff = 30 * time.Minute
+		}
+		perMinute := econf.GetFloat64("alarm.delivery.rateLimit")
+		if perMinute <= 0 {
+			perMinute = 30
+		}
+		delivery.rateLimit = rate.Limit(perMinute / 60)
+		if delivery.burst <= 0 {
+			delivery.burst = 10
+		}
+	})
+	return delivery
+}
+
+// Backoff returns the delay before the next attempt after the given number of failed attempts.
+func Backoff(attempts int) time.Duration {
+	conf := deliveryConf()
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := float64(conf.backoff) * math.Pow(2, float64(attempts-1))
+	if d > float64(conf.maxBackoff) {
+		return conf.maxBackoff
+	}
+	return time.Duration(d)
+}
+
+// limiter returns the token bucket of the channel, the buckets are local to the process.
+func limiter(channelId int) *rate.Limiter {
+	if v, ok := limiters.Load(channelId); ok {
+		return v.(*rate.Limiter)
+	}
+	conf := deliveryConf()
+	v, _ := limiters.LoadOrStore(channelId, rate.NewLimiter(conf.rateLimit, conf.burst))
+	return v.(*rate.Limiter)
+}
+
+// Deliver renders the message of every channel, stores a delivery for each of them and attempts it once.
+// The channels are independent: a failed channel does not stop the others, its delivery is retried by Retry.
+// historyIds are the alarm histories notified by the message, several for a digest.
+// pending is the number of deliveries waiting for a retry, err joins the errors of the channels which
+// could not be queued at all, e.g. a deleted channel or a broken template.
+func Deliver(historyIds []int, alarmId int, channelIds []int, render func(*db.AlarmChannel) (*db.PushMsg, error)) (pending int, err error) {
+	for _, channelId := range channelIds {
+		channel, errChannel := db.AlarmChannelInfo(invoker.Db, channelId)
+		if errChannel != nil {
+			err = multierr.Append(err, errChannel)
+			continue
+		}
+		msg, errRender := render(&channel)
+		if errRender != nil {
+			err = multierr.Append(err, errors.Wrapf(errRender, "channel %d", channelId))
+			continue
+		}
+		d := &db.AlarmDelivery{
+			AlarmId:   alarmId,
+			ChannelId: channelId,
+			Msg:       *msg,
+			Status:    db.DeliveryStatusPending,
+			NextTime:  time.Now().Unix(),
+			History:   db.DeliveryAttempts{},
+		}
+		if len(historyIds) == 1 {
+			d.HistoryId = historyIds[0]
+		} else if len(historyIds) > 1 {
+			d.HistoryIds = historyIds
+		}
+		if errCreate := d.Create(invoker.Db); errCreate != nil {
+			err = multierr.Append(err, errCreate)
+			continue
+		}
+		if errAttempt := attempt(invoker.Db, d, &channel, time.Now()); errAttempt != nil {
+			elog.Warn("alarmDelivery", l.I("deliveryId", d.ID), l.I("channelId", channelId), l.E(errAttempt))
+			pending++
+		}
+	}
+	return
+}
+
+// attempt sends the delivery unless the channel is rate limited, and stores the outcome.
+// The returned error is the send error or ErrRateLimited.
+func attempt(tx *gorm.DB, d *db.AlarmDelivery, channel *db.AlarmChannel, now time.Time) error {
+	r := limiter(channel.ID).ReserveN(now, 1)
+	if delay := r.DelayFrom(now); delay > 0 {
+		r.CancelAt(now)
+		d.Status = db.DeliveryStatusRetrying
+		d.NextTime = now.Add(delay).Unix() + 1
+		if err := d.Update(tx, map[string]interface{}{"status": d.Status, "next_time": d.NextTime}); err != nil {
+			return err
+		}
+		return ErrRateLimited
+	}
+	sendErr := send(d, channel)
+	return record(tx, d, now, time.Since(now), sendErr)
+}
+
+func send(d *db.AlarmDelivery, channel *db.AlarmChannel) error {
+	p, err := GetPusher(channel.Typ)
+	if err != nil {
+		return err
+	}
+	msg := d.Msg
+	return p.Send(channel, &msg)
+}
+
+// record stores the outcome of an attempt and schedules the next one.
+func record(tx *gorm.DB, d *db.AlarmDelivery, now time.Time, cost time.Duration, sendErr error) error {
+	d.Attempts++
+	a := db.DeliveryAttempt{Time: now.Unix(), Cost: cost.Milliseconds()}
+	d.LastError = ""
+	d.Status = db.DeliveryStatusSuccess
+	if sendErr != nil {
+		a.Error = sendErr.Error()
+		d.LastError = a.Error
+		d.Status = db.DeliveryStatusRetrying
+		d.NextTime = now.Add(Backoff(d.Attempts)).Unix()
+		if d.Attempts >= deliveryConf().maxAttempts {
+			d.Status = db.DeliveryStatusDead
+		}
+	}
+	d.History = append(d.History, a)
+	ups := map[string]interface{}{
+		"status":     d.Status,
+		"attempts":   d.Attempts,
+		"next_time":  d.NextTime,
+		"last_error": d.LastError,
+		"history":    d.History,
+	}
+	if err := d.Update(tx, ups); err != nil {
+		return multierr.Append(sendErr, err)
+	}
+	return sendErr
+}
+
+// Retry attempts the deliveries whose retry time is due and settles their alarm histories.
+func Retry(now time.Time) error {
+	due := make([]*db.AlarmDelivery, 0)
+	err := invoker.Db.Model(db.AlarmDelivery{}).
+		Where("(`status` = ? and `next_time` <= ?) or (`status` = ? and `utime` < ?)",
+			db.DeliveryStatusRetrying, now.Unix(), db.DeliveryStatusPending, now.Add(-pendingTimeout).Unix()).
+		Order("next_time asc").Limit(100).Find(&due).Error
+	if err != nil {
+		return errors.Wrap(err, "list due deliveries")
+	}
+	histories := make(map[int]struct{})
+	for _, d := range due {
+		if errRetry := retry(d, time.Now()); errRetry != nil && !errors.Is(errRetry, ErrRateLimited) {
+			elog.Warn("alarmDelivery", l.I("deliveryId", d.ID), l.I("attempts", d.Attempts), l.E(errRetry))
+		}
+		for _, historyId := range d.Histories() {
+			histories[historyId] = struct{}{}
+		}
+	}
+	for historyId := range histories {
+		err = multierr.Append(err, SettleHistory(historyId))
+	}
+	return err
+}
+
+func retry(d *db.AlarmDelivery, now time.Time) error {
+	channel, err := db.AlarmChannelInfo(invoker.Db, d.ChannelId)
+	if err != nil {
+		// the channel is gone, there is nothing to retry
+		d.Attempts = deliveryConf().maxAttempts - 1
+		return record(invoker.Db, d, now, 0, err)
+	}
+	return attempt(invoker.Db, d, &channel, now)
+}
+
+// Replay attempts a dead delivery again with a fresh attempt budget.
+func Replay(d *db.AlarmDelivery) error {
+	if d.Status != db.DeliveryStatusDead {
+		return ErrDeliveryNotReplayed
+	}
+	channel, err := db.AlarmChannelInfo(invoker.Db, d.ChannelId)
+	if err != nil {
+		return err
+	}
+	d.Attempts = 0
+	if err = attempt(invoker.Db, d, &channel, time.Now()); err != nil {
+		return err
+	}
+	for _, historyId := range d.Histories() {
+		err = multierr.Append(err, SettleHistory(historyId))
+	}
+	return err
+}
+
+// SettleHistory updates the push status of the alarm history from its deliveries:
+// success when all are delivered, fail when the undelivered ones are dead, retrying otherwise.
+func SettleHistory(historyId int) error {
+	counts, err := db.AlarmDeliveryStatusCount(invoker.Db, historyId)
+	if err != nil {
+		return err
+	}
+	status := db.PushedStatusSuccess
+	if counts[db.DeliveryStatusPending]+counts[db.DeliveryStatusRetrying] > 0 {
+		status = db.PushedStatusRetrying
+	} else if counts[db.DeliveryStatusDead] > 0 {
+		status = db.PushedStatusFail
+	}
+	return db.AlarmHistoryUpdate(invoker.Db, historyId, map[string]interface{}{"is_pushed": status})
+}
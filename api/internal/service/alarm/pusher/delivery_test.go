@@ -0,0 +1,142 @@
+package pusher
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/glebarez/sqlite"
+	"github.com/pkg/errors"
+	. "github.com/smartystreets/goconvey/convey"
+	"gorm.io/gorm"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// deliveryStandIn replaces the database with sqlite and returns a webhook channel answering with the status.
+func deliveryStandIn(t *testing.T, status int) (channelId int) {
+	gdb, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, _ := gdb.DB()
+	sqlDB.SetMaxOpenConns(1)
+	if err = gdb.AutoMigrate(&db.AlarmChannel{}, &db.AlarmDelivery{}); err != nil {
+		t.Fatal(err)
+	}
+	origin := invoker.Db
+	invoker.Db = gdb
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(func() {
+		server.Close()
+		invoker.Db = origin
+		_ = sqlDB.Close()
+	})
+	channel := &db.AlarmChannel{Name: "webhook", Typ: db.ChannelWebHook, Key: server.URL}
+	if err = gdb.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	return channel.ID
+}
+
+func deliveryStatus(t *testing.T) []int {
+	list := make([]db.AlarmDelivery, 0)
+	if err := invoker.Db.Order("id asc").Find(&list).Error; err != nil {
+		t.Fatal(err)
+	}
+	res := make([]int, 0, len(list))
+	for _, d := range list {
+		res = append(res, d.Status)
+	}
+	return res
+}
+
+func TestBackoff(t *testing.T) {
+	Convey("exponential backoff with a maximum", t, func() {
+		So(Backoff(0), ShouldEqual, 10*time.Second)
+		So(Backoff(1), ShouldEqual, 10*time.Second)
+		So(Backoff(2), ShouldEqual, 20*time.Second)
+		So(Backoff(5), ShouldEqual, 160*time.Second)
+		So(Backoff(20), ShouldEqual, 30*time.Minute)
+	})
+}
+
+func TestLimiter(t *testing.T) {
+	Convey("token bucket per channel", t, func() {
+		now := time.Now()
+		l := limiter(-1)
+		So(limiter(-1), ShouldEqual, l)
+		So(l.AllowN(now, 10), ShouldBeTrue)
+		So(l.AllowN(now, 1), ShouldBeFalse)
+		So(limiter(-2).AllowN(now, 1), ShouldBeTrue)
+		So(l.AllowN(now.Add(2*time.Second), 1), ShouldBeTrue)
+	})
+}
+
+func TestCheckErrCode(t *testing.T) {
+	Convey("robot responses", t, func() {
+		So(checkErrCode(&http.Response{StatusCode: 200}, []byte(`{"errcode":0,"errmsg":"ok"}`)), ShouldBeNil)
+		So(checkErrCode(&http.Response{StatusCode: 200}, []byte(`{"errcode":310000,"errmsg":"keywords not in content"}`)), ShouldNotBeNil)
+		So(checkErrCode(&http.Response{StatusCode: 502}, []byte(`bad gateway`)), ShouldNotBeNil)
+		So(checkErrCode(&http.Response{StatusCode: 200}, []byte(`ok`)), ShouldBeNil)
+	})
+}
+
+func TestDeliver(t *testing.T) {
+	Convey("delivered", t, func() {
+		channelId := deliveryStandIn(t, http.StatusOK)
+		pending, err := Deliver([]int{1}, 1, []int{channelId}, func(*db.AlarmChannel) (*db.PushMsg, error) {
+			return &db.PushMsg{Title: "t"}, nil
+		})
+		So(err, ShouldBeNil)
+		So(pending, ShouldEqual, 0)
+		So(deliveryStatus(t), ShouldResemble, []int{db.DeliveryStatusSuccess})
+	})
+	Convey("failed channels are queued, the others are independent", t, func() {
+		channelId := deliveryStandIn(t, http.StatusBadGateway)
+		pending, err := Deliver([]int{1}, 1, []int{channelId, channelId + 100}, func(*db.AlarmChannel) (*db.PushMsg, error) {
+			return &db.PushMsg{Title: "t"}, nil
+		})
+		So(err, ShouldNotBeNil)
+		So(pending, ShouldEqual, 1)
+		So(deliveryStatus(t), ShouldResemble, []int{db.DeliveryStatusRetrying})
+	})
+	Convey("a digest keeps every history", t, func() {
+		channelId := deliveryStandIn(t, http.StatusOK)
+		_, err := Deliver([]int{3, 4}, 0, []int{channelId}, func(*db.AlarmChannel) (*db.PushMsg, error) {
+			return &db.PushMsg{Title: "t"}, nil
+		})
+		So(err, ShouldBeNil)
+		d := db.AlarmDelivery{}
+		So(invoker.Db.First(&d).Error, ShouldBeNil)
+		So(d.HistoryId, ShouldEqual, 0)
+		So(d.Histories(), ShouldResemble, []int{3, 4})
+	})
+	Convey("render errors are not queued", t, func() {
+		channelId := deliveryStandIn(t, http.StatusOK)
+		pending, err := Deliver([]int{1}, 1, []int{channelId}, func(*db.AlarmChannel) (*db.PushMsg, error) {
+			return nil, errors.New("broken template")
+		})
+		So(err, ShouldNotBeNil)
+		So(pending, ShouldEqual, 0)
+		So(deliveryStatus(t), ShouldBeEmpty)
+	})
+}
+
+func TestExecute(t *testing.T) {
+	Convey("delivered", t, func() {
+		channelId := deliveryStandIn(t, http.StatusOK)
+		So(Execute([]int{channelId}, &db.PushMsg{Title: "t"}, &db.PushMsg{Title: "t"}), ShouldBeNil)
+	})
+	Convey("queued for retry", t, func() {
+		channelId := deliveryStandIn(t, http.StatusInternalServerError)
+		err := Execute([]int{channelId}, &db.PushMsg{Title: "t"}, &db.PushMsg{Title: "t"})
+		So(errors.Is(err, ErrDeliveryQueued), ShouldBeTrue)
+		So(deliveryStatus(t), ShouldResemble, []int{db.DeliveryStatusRetrying})
+	})
+}
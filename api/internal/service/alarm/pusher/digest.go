@@ -0,0 +1,144 @@
+package pusher
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// DefaultDigestTitleTemplate renders the digest title when the channel template does not define "digestTitle".
+const DefaultDigestTitleTemplate = `【{{.Firing}} 告警中{{with .ResolvedCount}} / {{.}} 已恢复{{end}}】{{.Name}}`
+
+// DefaultDigestTemplate is the markdown digest used when the channel template does not define "digest".
+const DefaultDigestTemplate = `{{if .Firing}}<font color=#FF0000>您有 {{.Firing}} 条待处理的告警</font>{{else}}<font color=#008000>您的告警已全部恢复</font>{{end}}
+
+【告警分组】: {{.Name}}
+{{range .Members}}
+{{if .Resolved}}<font color=#008000>已恢复</font>{{else}}<font color=red>告警中</font>{{end}} {{.Alarm.Name}} {{.Table.Name}}{{range .Alerts}} {{formatTime .StartsAt "2006-01-02 15:04:05" "+08:00"}} {{.URL}}{{end}}
+{{end}}{{if .DutyOfficers}}
+【告警责任】: {{range $i, $u := .DutyOfficers}}{{if $.At}}@{{or $u.Phone $u.Nickname}}{{else}}{{if $i}}/{{end}}{{$u.Nickname}}{{end}}{{end}}{{end}}`
+
+// DigestContext is the data rendered by the digest templates, a digest notifies the alerts of a group together.
+type DigestContext struct {
+	GroupKey      string             // key of the group
+	Labels        map[string]string  // group by label values
+	Name          string             // readable labels of the group
+	Firing        int                // number of firing alerts
+	ResolvedCount int                // number of resolved alerts
+	Members       []*TemplateContext // context of every alert
+	DutyOfficers  []db.User          // duty officers of all the alarms
+	Mobiles       []string           // phones of the duty officers
+	At            bool               // the channel mentions the duty officers by phone
+}
+
+// NewDigestContext merges the contexts of the alerts of a group, the firing alerts are listed first.
+func NewDigestContext(groupKey string, labels map[string]string, members []*TemplateContext) *DigestContext {
+	ctx := &DigestContext{
+		GroupKey: groupKey,
+		Labels:   labels,
+		Members:  members,
+	}
+	sort.SliceStable(members, func(i, j int) bool {
+		return !members[i].Resolved && members[j].Resolved
+	})
+	users := make(map[int]struct{})
+	for _, m := range members {
+		if m.Resolved {
+			ctx.ResolvedCount++
+		} else {
+			ctx.Firing++
+		}
+		for _, u := range m.DutyOfficers {
+			if _, ok := users[u.ID]; ok {
+				continue
+			}
+			users[u.ID] = struct{}{}
+			ctx.DutyOfficers = append(ctx.DutyOfficers, u)
+			if u.Phone != "" {
+				ctx.Mobiles = append(ctx.Mobiles, u.Phone)
+			}
+		}
+	}
+	ctx.Name = digestName(labels, members)
+	return ctx
+}
+
+// digestName shows the names of the alarm, table and instance labels and the tag values.
+func digestName(labels map[string]string, members []*TemplateContext) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, 0, len(keys))
+	for _, k := range keys {
+		v := labels[k]
+		if len(members) > 0 {
+			first := members[0]
+			switch {
+			case k == db.GroupByAlarm && first.Alarm != nil:
+				v = first.Alarm.Name
+			case k == db.GroupByTable && first.Table != nil:
+				v = first.Table.Name
+			case k == db.GroupByInstance && first.Instance != nil:
+				v = first.Instance.Name
+			}
+		}
+		parts = append(parts, fmt.Sprintf("%s=%s", strings.TrimPrefix(k, db.GroupByTagPrefix), v))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// RenderDigest renders the digest of a channel, the template may define "digest" and "digestTitle",
+// the default digest templates are used otherwise.
+func RenderDigest(tpl string, typ int, ctx *DigestContext) (*db.PushMsg, error) {
+	t, err := template.New("text").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse template")
+	}
+	if t.Lookup("digest") == nil {
+		if _, err = t.New("digest").Parse(DefaultDigestTemplate); err != nil {
+			return nil, errors.Wrap(err, "parse digest template")
+		}
+	}
+	if t.Lookup("digestTitle") == nil {
+		if _, err = t.New("digestTitle").Parse(DefaultDigestTitleTemplate); err != nil {
+			return nil, errors.Wrap(err, "parse digest title template")
+		}
+	}
+	c := *ctx
+	c.At = typ == db.ChannelDingDing
+	var text, title bytes.Buffer
+	if err = t.ExecuteTemplate(&text, "digest", &c); err != nil {
+		return nil, errors.Wrap(err, "execute digest template")
+	}
+	if err = t.ExecuteTemplate(&title, "digestTitle", &c); err != nil {
+		return nil, errors.Wrap(err, "execute digest title template")
+	}
+	msg := &db.PushMsg{
+		Title:    title.String(),
+		Text:     text.String(),
+		DedupKey: "group-" + ctx.GroupKey,
+		Status:   db.AlarmStatusFiring,
+	}
+	if ctx.Firing == 0 {
+		msg.Status = db.AlarmStatusNormal
+	}
+	if len(ctx.Mobiles) != 0 {
+		msg.Mobiles = ctx.Mobiles
+	}
+	return msg, nil
+}
+
+// ExecuteDigest renders the digest of every channel with its template and delivers it for the histories, see Deliver.
+func ExecuteDigest(historyIds []int, channelIds []int, ctx *DigestContext) (pending int, err error) {
+	return Deliver(historyIds, 0, channelIds, func(channel *db.AlarmChannel) (*db.PushMsg, error) {
+		return RenderDigest(channel.Template, channel.Typ, ctx)
+	})
+}
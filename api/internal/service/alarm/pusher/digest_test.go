@@ -0,0 +1,58 @@
+package pusher
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestRenderDigest(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2023-01-02T03:04:05Z")
+	member := func(name string, status string, officer db.User) *TemplateContext {
+		notification := db.Notification{Status: status, Alerts: []db.Alert{{StartsAt: startsAt}}}
+		ctx := NewPreviewContext(notification, &db.BaseTable{Name: "ingress"}, &db.BaseInstance{Name: "ch"}, &db.Alarm{Name: name}, &db.AlarmFilter{}, "")
+		ctx.DutyOfficers = []db.User{officer}
+		return ctx
+	}
+	alice := db.User{BaseModel: db.BaseModel{ID: 1}, Nickname: "alice", Phone: "123"}
+	bob := db.User{BaseModel: db.BaseModel{ID: 2}, Nickname: "bob"}
+	newContext := func() *DigestContext {
+		return NewDigestContext("key", map[string]string{"table": "1", "tag:team": "infra"}, []*TemplateContext{
+			member("5xx", "resolved", alice),
+			member("4xx", "firing", alice),
+			member("slow", "firing", bob),
+		})
+	}
+	Convey("default digest", t, func() {
+		ctx := newContext()
+		So(ctx.Firing, ShouldEqual, 2)
+		So(ctx.ResolvedCount, ShouldEqual, 1)
+		So(ctx.Members[0].Alarm.Name, ShouldEqual, "4xx")
+		So(ctx.Members[2].Alarm.Name, ShouldEqual, "5xx")
+		So(ctx.Name, ShouldEqual, "table=ingress, team=infra")
+		msg, err := RenderDigest("", db.ChannelFeiShu, ctx)
+		So(err, ShouldBeNil)
+		So(msg.Title, ShouldEqual, "【2 告警中 / 1 已恢复】table=ingress, team=infra")
+		So(msg.Text, ShouldContainSubstring, "告警中</font> 4xx ingress 2023-01-02 11:04:05")
+		So(msg.Text, ShouldContainSubstring, "已恢复</font> 5xx ingress")
+		So(msg.Text, ShouldEndWith, "【告警责任】: alice/bob")
+		So(msg.Mobiles, ShouldResemble, []string{"123"})
+		So(msg.Status, ShouldEqual, db.AlarmStatusFiring)
+		So(msg.DedupKey, ShouldEqual, "group-key")
+	})
+	Convey("dingding mentions by phone", t, func() {
+		msg, err := RenderDigest("", db.ChannelDingDing, newContext())
+		So(err, ShouldBeNil)
+		So(msg.Text, ShouldEndWith, "【告警责任】: @123@bob")
+	})
+	Convey("custom digest", t, func() {
+		tpl := `{{define "digestTitle"}}{{.Firing}} firing{{end}}{{define "digest"}}{{range .Members}}{{.Alarm.Name}};{{end}}{{end}}`
+		msg, err := RenderDigest(tpl, db.ChannelSlack, newContext())
+		So(err, ShouldBeNil)
+		So(msg.Title, ShouldEqual, "2 firing")
+		So(msg.Text, ShouldEqual, "4xx;slow;5xx;")
+	})
+}
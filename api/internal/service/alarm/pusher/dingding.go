@@ -3,6 +3,7 @@ package pusher
 import (
 	"bytes"
 	"encoding/json"
+	"io"
 	"net/http"
 
 	"github.com/pkg/errors"
@@ -39,11 +40,14 @@ func (d *DingDing) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
 		return errors.Wrap(err, "new request failed")
 	}
 	req.Header.Set("Content-Type", "application/json")
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
 		return errors.Wrap(err, "client do failed")
 	}
 	defer func() { _ = resp.Body.Close() }()
-	return
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read body failed")
+	}
+	return errors.Wrap(checkErrCode(resp, body), "dingding send")
 }
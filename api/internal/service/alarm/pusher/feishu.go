@@ -30,7 +30,7 @@ func (s *FeiShu) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
 func (s *FeiShu) sendMessage(url string, title, text string) (err error) {
 	msg := feishu.NewCardMsg(title, feishu.WARNING)
 	msg.AddElement(text)
-	sendMsg, errflag, err := feishu.SendMsg(url, msg)
+	sendMsg, errflag, err := feishu.SendMsg(HTTPClient(), url, msg)
 	// err 不为空基本为本地问题
 	// err is not empty is basically a local problem
 	if err != nil {
@@ -23,7 +23,7 @@ func GenSign(secret, timestamp string) string {
 	return signature
 }
 
-func SendMsg(webhook string, v interface{}) (response interface{}, isErrResponse bool, err error) {
+func SendMsg(client *http.Client, webhook string, v interface{}) (response interface{}, isErrResponse bool, err error) {
 	if webhook == "" {
 		return nil, false, errors.New("no specified webhook")
 	}
@@ -33,7 +33,7 @@ func SendMsg(webhook string, v interface{}) (response interface{}, isErrResponse
 		return nil, false, errors.New("marshal data error")
 	}
 
-	resp, err := http.Post(webhook, "application/json", bytes.NewReader(data))
+	resp, err := client.Post(webhook, "application/json", bytes.NewReader(data))
 	if err != nil {
 		return nil, false, err
 	}
@@ -0,0 +1,68 @@
+package pusher
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+const opsgenieAlertsURL = "https://api.opsgenie.com/v2/alerts"
+
+var _ IPusher = (*Opsgenie)(nil)
+
+// Opsgenie creates and closes alerts, the channel key is the api integration key.
+// The dedup key of the message is the alert alias, so a resolved alarm closes the alert it created.
+type Opsgenie struct {
+	url string
+}
+
+type opsgenieAlert struct {
+	Message     string `json:"message"`
+	Alias       string `json:"alias,omitempty"`
+	Description string `json:"description,omitempty"`
+	Source      string `json:"source"`
+	Priority    string `json:"priority,omitempty"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+	Note   string `json:"note,omitempty"`
+}
+
+func (o *Opsgenie) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
+	base := o.url
+	if base == "" {
+		base = opsgenieAlertsURL
+	}
+	req := restyR().
+		SetHeader("Content-Type", "application/json").
+		SetHeader("Authorization", "GenieKey "+channel.Key)
+	var target string
+	if msg.Status == db.AlarmStatusNormal {
+		if msg.DedupKey == "" {
+			return errors.New("opsgenie close requires a dedup key")
+		}
+		target = strings.TrimRight(base, "/") + "/" + url.PathEscape(msg.DedupKey) + "/close?identifierType=alias"
+		req.SetBody(opsgenieClose{Source: FOOTER, Note: truncate(25000, msg.Text)})
+	} else {
+		target = base
+		req.SetBody(opsgenieAlert{
+			Message:     truncate(130, msg.Title),
+			Alias:       msg.DedupKey,
+			Description: truncate(15000, msg.Text),
+			Source:      FOOTER,
+			Priority:    "P1",
+		})
+	}
+	resp, err := req.Post(target)
+	if err != nil {
+		return errors.Wrap(err, "opsgenie send")
+	}
+	if resp.IsError() {
+		return errors.Errorf("opsgenie send error, status: %d, body: %s", resp.StatusCode(), resp.String())
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package pusher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestOpsgenie_Send(t *testing.T) {
+	requests := make([]*http.Request, 0)
+	var created opsgenieAlert
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.URL.Path == "/v2/alerts" {
+			_ = json.NewDecoder(r.Body).Decode(&created)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	o := &Opsgenie{url: srv.URL + "/v2/alerts"}
+	channel := &db.AlarmChannel{Typ: db.ChannelOpsgenie, Key: "api-key"}
+	Convey("create and close by alias", t, func() {
+		So(o.Send(channel, &db.PushMsg{Title: "title", Text: "text", DedupKey: "uuid-1", Status: db.AlarmStatusFiring}), ShouldBeNil)
+		So(o.Send(channel, &db.PushMsg{Title: "title", Text: "text", DedupKey: "uuid-1", Status: db.AlarmStatusNormal}), ShouldBeNil)
+		So(len(requests), ShouldEqual, 2)
+		So(requests[0].Header.Get("Authorization"), ShouldEqual, "GenieKey api-key")
+		So(created.Message, ShouldEqual, "title")
+		So(created.Alias, ShouldEqual, "uuid-1")
+		So(requests[1].URL.Path, ShouldEqual, "/v2/alerts/uuid-1/close")
+		So(requests[1].URL.Query().Get("identifierType"), ShouldEqual, "alias")
+	})
+	Convey("close requires dedup key", t, func() {
+		So(o.Send(channel, &db.PushMsg{Status: db.AlarmStatusNormal}), ShouldNotBeNil)
+	})
+}
@@ -0,0 +1,64 @@
+package pusher
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+const pagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+var _ IPusher = (*PagerDuty)(nil)
+
+// PagerDuty sends Events API v2 events, the channel key is the integration routing key.
+// Firing alarms trigger an incident and resolved alarms resolve it, both use the dedup key of the message.
+type PagerDuty struct {
+	url string
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key,omitempty"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+func (p *PagerDuty) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
+	event := pagerDutyEvent{
+		RoutingKey:  channel.Key,
+		EventAction: "trigger",
+		DedupKey:    msg.DedupKey,
+	}
+	if msg.Status == db.AlarmStatusNormal {
+		event.EventAction = "resolve"
+	} else {
+		event.Payload = &pagerDutyPayload{
+			Summary:       truncate(1024, msg.Title),
+			Source:        FOOTER,
+			Severity:      "critical",
+			CustomDetails: map[string]string{"text": msg.Text},
+		}
+	}
+	target := p.url
+	if target == "" {
+		target = pagerDutyEventsURL
+	}
+	resp, err := restyR().
+		SetHeader("Content-Type", "application/json").
+		SetBody(event).
+		Post(target)
+	if err != nil {
+		return errors.Wrap(err, "pagerduty send")
+	}
+	if resp.IsError() {
+		return errors.Errorf("pagerduty send error, status: %d, body: %s", resp.StatusCode(), resp.String())
+	}
+	return nil
+}
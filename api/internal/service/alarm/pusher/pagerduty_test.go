@@ -0,0 +1,43 @@
+package pusher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestPagerDuty_Send(t *testing.T) {
+	events := make([]pagerDutyEvent, 0)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event pagerDutyEvent
+		_ = json.NewDecoder(r.Body).Decode(&event)
+		events = append(events, event)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+	p := &PagerDuty{url: srv.URL}
+	channel := &db.AlarmChannel{Typ: db.ChannelPagerDuty, Key: "routing-key"}
+	Convey("trigger and resolve with the same dedup key", t, func() {
+		So(p.Send(channel, &db.PushMsg{Title: "title", Text: "text", DedupKey: "uuid-1", Status: db.AlarmStatusFiring}), ShouldBeNil)
+		So(p.Send(channel, &db.PushMsg{Title: "title", Text: "text", DedupKey: "uuid-1", Status: db.AlarmStatusNormal}), ShouldBeNil)
+		So(len(events), ShouldEqual, 2)
+		So(events[0].RoutingKey, ShouldEqual, "routing-key")
+		So(events[0].EventAction, ShouldEqual, "trigger")
+		So(events[0].Payload.Summary, ShouldEqual, "title")
+		So(events[1].EventAction, ShouldEqual, "resolve")
+		So(events[1].DedupKey, ShouldEqual, events[0].DedupKey)
+		So(events[1].Payload, ShouldBeNil)
+	})
+	Convey("error status", t, func() {
+		failed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer failed.Close()
+		So((&PagerDuty{url: failed.URL}).Send(channel, &db.PushMsg{Title: "title"}), ShouldNotBeNil)
+	})
+}
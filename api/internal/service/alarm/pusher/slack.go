@@ -53,7 +53,7 @@ func (s *Slack) sendMessage(url string, title, text string) (err error) {
 	msg := slack.WebhookMessage{
 		Attachments: []slack.Attachment{attachment},
 	}
-	err = slack.PostWebhook(url, &msg)
+	err = slack.PostWebhookCustomHTTP(url, HTTPClient(), &msg)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,71 @@
+package pusher
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var _ IPusher = (*Teams)(nil)
+
+// Teams posts an Adaptive Card to a Microsoft Teams incoming webhook, the channel key is the webhook url.
+type Teams struct{}
+
+type teamsMessage struct {
+	Type        string            `json:"type"`
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+type teamsAttachment struct {
+	ContentType string            `json:"contentType"`
+	Content     teamsAdaptiveCard `json:"content"`
+}
+
+type teamsAdaptiveCard struct {
+	Schema  string           `json:"$schema"`
+	Type    string           `json:"type"`
+	Version string           `json:"version"`
+	Body    []teamsTextBlock `json:"body"`
+}
+
+type teamsTextBlock struct {
+	Type   string `json:"type"`
+	Text   string `json:"text"`
+	Wrap   bool   `json:"wrap"`
+	Weight string `json:"weight,omitempty"`
+	Size   string `json:"size,omitempty"`
+	Color  string `json:"color,omitempty"`
+}
+
+func (t *Teams) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
+	color := "Attention"
+	if msg.Status == db.AlarmStatusNormal {
+		color = "Good"
+	}
+	card := teamsMessage{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: teamsAdaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body: []teamsTextBlock{
+					{Type: "TextBlock", Text: msg.Title, Wrap: true, Weight: "Bolder", Size: "Medium", Color: color},
+					{Type: "TextBlock", Text: msg.Text, Wrap: true},
+				},
+			},
+		}},
+	}
+	resp, err := restyR().
+		SetHeader("Content-Type", "application/json").
+		SetBody(card).
+		Post(channel.Key)
+	if err != nil {
+		return errors.Wrap(err, "teams send")
+	}
+	if resp.IsError() {
+		return errors.Errorf("teams send error, status: %d, body: %s", resp.StatusCode(), resp.String())
+	}
+	return nil
+}
@@ -0,0 +1,32 @@
+package pusher
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestTeams_Send(t *testing.T) {
+	var received teamsMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+	Convey("adaptive card", t, func() {
+		err := (&Teams{}).Send(&db.AlarmChannel{Typ: db.ChannelTeams, Key: srv.URL}, &db.PushMsg{Title: "title", Text: "text", Status: db.AlarmStatusNormal})
+		So(err, ShouldBeNil)
+		So(received.Type, ShouldEqual, "message")
+		So(len(received.Attachments), ShouldEqual, 1)
+		card := received.Attachments[0]
+		So(card.ContentType, ShouldEqual, "application/vnd.microsoft.card.adaptive")
+		So(card.Content.Type, ShouldEqual, "AdaptiveCard")
+		So(card.Content.Body[0].Text, ShouldEqual, "title")
+		So(card.Content.Body[0].Color, ShouldEqual, "Good")
+		So(card.Content.Body[1].Text, ShouldEqual, "text")
+	})
+}
@@ -55,6 +55,7 @@ func NewTelegram(token string) (*TelegramBot, error) {
 	bot, err := tb.NewBot(tb.Settings{
 		Token:  token,
 		Poller: &tb.LongPoller{Timeout: 15 * time.Second},
+		Client: HTTPClient(),
 	})
 	if err != nil {
 		return nil, err
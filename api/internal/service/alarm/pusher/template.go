@@ -0,0 +1,276 @@
+package pusher
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"text/template"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/shorturl"
+)
+
+// DefaultTitleTemplate renders the message title when the channel template does not define "title".
+const DefaultTitleTemplate = `【{{if .Resolved}}已恢复{{else}}告警中{{end}}】{{.Alarm.Name}}`
+
+// DefaultTemplate is the markdown message used by channels without template.
+const DefaultTemplate = `{{if .Resolved}}<font color=#008000>您的告警已恢复</font>
+{{else}}<font color=#FF0000>您有待处理的告警</font>
+{{end}}【告警名称】: {{.Alarm.Name}}
+{{with .Alarm.Desc}}【告警描述】: {{.}}
+{{end}}{{range .Alerts}}【触发时间】: {{formatTime .StartsAt "2006-01-02 15:04:05" "+08:00"}}
+【相关实例】: {{$.Instance.Name}} {{$.Instance.Desc}}
+【日志库表】: {{$.Table.Name}} {{$.Table.Desc}}
+{{if $.Resolved}}【告警状态】: <font color=#008000>已恢复</font>
+{{else}}【告警状态】: <font color=red>告警中</font>
+{{end}}{{if $.DutyOfficers}}【告警责任】: {{range $i, $u := $.DutyOfficers}}{{if $i}}/{{end}}{{$u.Nickname}}{{end}}
+{{else}}【告警更新】: {{$.Updater.Nickname}}
+
+{{end}}【链接跳转】: {{.URL}}
+{{with $.PartialLog}}【告警日志】: {{replace "\"" "" . | cutLog}}{{end}}{{end}}`
+
+// DefaultTemplateWithAt is the markdown message used by dingding channels without template,
+// the duty officers are mentioned by phone.
+const DefaultTemplateWithAt = `{{if .Resolved}}<font color=#008000>您的告警已恢复</font>
+
+{{else}}<font color=#FF0000>您有待处理的告警</font>
+
+{{end}}【告警名称】: {{.Alarm.Name}}
+
+{{with .Alarm.Desc}}【告警描述】: {{.}}
+
+{{end}}{{range .Alerts}}【触发时间】: {{formatTime .StartsAt "2006-01-02 15:04:05" "+08:00"}}
+
+【相关实例】: {{$.Instance.Name}} {{$.Instance.Desc}}
+
+【日志库表】: {{$.Table.Name}} {{$.Table.Desc}}
+
+{{if $.Resolved}}【告警状态】: <font color=#008000>已恢复</font>
+
+{{else}}【告警状态】: <font color=red>告警中</font>
+
+{{end}}{{if $.DutyOfficers}}【告警责任】: {{range $.DutyOfficers}}@{{or .Phone .Nickname}}{{end}}
+
+{{else}}【告警更新】: {{$.Updater.Nickname}}
+
+{{end}}【链接跳转】: {{.RawLogURL}}
+
+{{with $.PartialLog}}【告警日志】: {{replace "\"" "" . | cutLog}}{{end}}{{end}}`
+
+var templateFuncs = template.FuncMap{
+	"formatTime": formatTime,
+	"truncate":   truncate,
+	"replace":    replace,
+	"cutLog":     cutLog,
+	"join":       strings.Join,
+}
+
+// TemplateContext is the data rendered by the alarm message templates.
+type TemplateContext struct {
+	Status       int              // db.AlarmStatusFiring or db.AlarmStatusNormal
+	Resolved     bool             // the alarm is resolved
+	Alarm        *db.Alarm        // alarm
+	Filter       *db.AlarmFilter  // filter which fired
+	Table        *db.BaseTable    // table of the filter
+	Instance     *db.BaseInstance // instance of the table
+	Alerts       []*TemplateAlert // alerts of the notification
+	DutyOfficers []db.User        // duty officers who have a phone
+	Updater      db.User          // last user who updated the alarm
+	PartialLog   string           // one of the matched logs in json
+	Mobiles      []string         // phones of the duty officers
+}
+
+// TemplateAlert is an alert of the notification with the link to its logs.
+type TemplateAlert struct {
+	db.Alert
+
+	filter    *db.AlarmFilter
+	alarm     *db.Alarm
+	shorten   bool
+	url       string
+	rawLogURL string
+}
+
+// URL returns the link to the logs around the alert in the mode of the filter, shortened when possible.
+func (a *TemplateAlert) URL() string {
+	if a.url == "" {
+		if a.filter.Mode == db.AlarmModeAggregation {
+			a.url = a.link(fmt.Sprintf("mode=1&tab=custom&tid=%d&kw=%s&start=%d&end=%d&queryType=statisticalTable", a.filter.Tid, url.QueryEscape(a.filter.When), a.start(), a.end()))
+		} else {
+			a.url = a.link(fmt.Sprintf("mode=0&tab=custom&tid=%d&kw=%s&start=%d&end=%d&queryType=rawLog", a.filter.Tid, url.QueryEscape(a.filter.When), a.start(), a.end()))
+		}
+	}
+	return a.url
+}
+
+// RawLogURL returns the link to the raw logs around the alert whatever the mode of the filter,
+// as the dingding messages link them, shortened when possible.
+func (a *TemplateAlert) RawLogURL() string {
+	if a.rawLogURL == "" {
+		a.rawLogURL = a.link(fmt.Sprintf("mode=0&tab=custom&tid=%d&kw=%s&start=%d&end=%d", a.filter.Tid, url.QueryEscape(a.filter.When), a.start(), a.end()))
+	}
+	return a.rawLogURL
+}
+
+func (a *TemplateAlert) start() int64 {
+	return a.StartsAt.Add(-a.alarm.GetInterval() - time.Minute).Unix()
+}
+
+func (a *TemplateAlert) end() int64 {
+	return a.StartsAt.Add(time.Minute).Unix()
+}
+
+// link returns the share page with the query, shortened when possible.
+func (a *TemplateAlert) link(query string) string {
+	jumpURL := fmt.Sprintf("%s/share?%s", strings.TrimRight(econf.GetString("app.rootURL"), "/"), query)
+	if !a.shorten {
+		return jumpURL
+	}
+	shortURL, err := shorturl.GenShortURL(jumpURL)
+	if err != nil {
+		elog.Error("shorturl.GenShortURL", elog.FieldErr(err), elog.String("jumpURL", jumpURL))
+		return jumpURL
+	}
+	return shortURL
+}
+
+// NewTemplateContext collects the data of a notification for the message templates.
+func NewTemplateContext(notification db.Notification, table *db.BaseTable, alarm *db.Alarm, filter *db.AlarmFilter, partialLog string) *TemplateContext {
+	users, phones := dutyOffices(alarm)
+	instance, _ := db.InstanceInfo(invoker.Db, table.Database.Iid)
+	updater, _ := db.UserInfo(alarm.Uid)
+	ctx := &TemplateContext{
+		Status:       notification.GetStatus(),
+		Resolved:     notification.GetStatus() == db.AlarmStatusNormal,
+		Alarm:        alarm,
+		Filter:       filter,
+		Table:        table,
+		Instance:     &instance,
+		Alerts:       make([]*TemplateAlert, 0, len(notification.Alerts)),
+		DutyOfficers: users,
+		Updater:      updater,
+		PartialLog:   partialLog,
+		Mobiles:      phones,
+	}
+	for _, alert := range notification.Alerts {
+		ctx.Alerts = append(ctx.Alerts, &TemplateAlert{Alert: alert, filter: filter, alarm: alarm, shorten: true})
+	}
+	return ctx
+}
+
+// WithoutShortURL keeps the full links, so previews do not create short urls.
+func (c *TemplateContext) WithoutShortURL() *TemplateContext {
+	for _, alert := range c.Alerts {
+		alert.shorten = false
+	}
+	return c
+}
+
+// NewPreviewContext returns a context built from the given data only, its links are not shortened.
+func NewPreviewContext(notification db.Notification, table *db.BaseTable, instance *db.BaseInstance, alarm *db.Alarm, filter *db.AlarmFilter, partialLog string) *TemplateContext {
+	ctx := &TemplateContext{
+		Status:     notification.GetStatus(),
+		Resolved:   notification.GetStatus() == db.AlarmStatusNormal,
+		Alarm:      alarm,
+		Filter:     filter,
+		Table:      table,
+		Instance:   instance,
+		Alerts:     make([]*TemplateAlert, 0, len(notification.Alerts)),
+		PartialLog: partialLog,
+	}
+	for _, alert := range notification.Alerts {
+		ctx.Alerts = append(ctx.Alerts, &TemplateAlert{Alert: alert, filter: filter, alarm: alarm})
+	}
+	return ctx
+}
+
+// ParseTemplate checks the syntax of a channel template, an empty template is valid.
+func ParseTemplate(tpl string) error {
+	if tpl == "" {
+		return nil
+	}
+	_, err := template.New("text").Funcs(templateFuncs).Parse(tpl)
+	return err
+}
+
+// Render renders the message of a channel, the default template of the channel type is used when tpl is empty.
+func Render(tpl string, typ int, ctx *TemplateContext) (*db.PushMsg, error) {
+	if tpl == "" {
+		tpl = DefaultTemplate
+		if typ == db.ChannelDingDing {
+			tpl = DefaultTemplateWithAt
+		}
+	}
+	t, err := template.New("text").Funcs(templateFuncs).Parse(tpl)
+	if err != nil {
+		return nil, errors.Wrap(err, "parse template")
+	}
+	if t.Lookup("title") == nil {
+		if t, err = t.New("title").Parse(DefaultTitleTemplate); err != nil {
+			return nil, errors.Wrap(err, "parse title template")
+		}
+	}
+	var text, title bytes.Buffer
+	if err = t.ExecuteTemplate(&text, "text", ctx); err != nil {
+		return nil, errors.Wrap(err, "execute template")
+	}
+	if err = t.ExecuteTemplate(&title, "title", ctx); err != nil {
+		return nil, errors.Wrap(err, "execute title template")
+	}
+	msg := &db.PushMsg{
+		Title:  title.String(),
+		Text:   text.String(),
+		Status: ctx.Status,
+	}
+	if ctx.Alarm != nil && ctx.Filter != nil {
+		msg.DedupKey = fmt.Sprintf("%s-%d", ctx.Alarm.Uuid, ctx.Filter.ID)
+	}
+	if len(ctx.Mobiles) != 0 {
+		msg.Mobiles = ctx.Mobiles
+	}
+	return msg, nil
+}
+
+// formatTime formats t in tz, tz is a fixed offset like +08:00 or a location name like UTC.
+func formatTime(t time.Time, layout string, tz string) (string, error) {
+	if offset, err := time.Parse("-07:00", tz); err == nil {
+		_, sec := offset.Zone()
+		return t.In(time.FixedZone(tz, sec)).Format(layout), nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return "", err
+	}
+	return t.In(loc).Format(layout), nil
+}
+
+// truncate keeps at most n bytes of s without splitting a character.
+func truncate(n int, s string) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// cutLog keeps the logs of up to 600 bytes and cuts the longer ones to 599 bytes.
+func cutLog(s string) string {
+	if len(s) > 600 {
+		return truncate(599, s)
+	}
+	return s
+}
+
+func replace(old, new, s string) string {
+	return strings.ReplaceAll(s, old, new)
+}
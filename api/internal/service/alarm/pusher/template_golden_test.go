@@ -0,0 +1,197 @@
+package pusher
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gotomicro/ego/core/econf"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// legacyAlarmMsg is BuildAlarmMsg before the templates, with the duty officers, the instance and the updater given
+// instead of read from the database and the links not shortened.
+func legacyAlarmMsg(notification db.Notification, table *db.BaseTable, instance db.BaseInstance, alarm *db.Alarm, filter *db.AlarmFilter, partialLog string, users []db.User, phones []string, updater db.User) *db.PushMsg {
+	var buffer bytes.Buffer
+	if notification.GetStatus() == db.AlarmStatusNormal {
+		buffer.WriteString("<font color=#008000>您的告警已恢复</font>\n")
+	} else {
+		buffer.WriteString("<font color=#FF0000>您有待处理的告警</font>\n")
+	}
+	buffer.WriteString(fmt.Sprintf("【告警名称】: %s\n", alarm.Name))
+	if alarm.Desc != "" {
+		buffer.WriteString(fmt.Sprintf("【告警描述】: %s\n", alarm.Desc))
+	}
+	statusText := "告警中"
+	for _, alert := range notification.Alerts {
+		end := alert.StartsAt.Add(time.Minute).Unix()
+		start := alert.StartsAt.Add(-alarm.GetInterval() - time.Minute).Unix()
+		buffer.WriteString(fmt.Sprintf("【触发时间】: %s\n", alert.StartsAt.Add(time.Hour*8).Format("2006-01-02 15:04:05")))
+		buffer.WriteString(fmt.Sprintf("【相关实例】: %s %s\n", instance.Name, instance.Desc))
+		buffer.WriteString(fmt.Sprintf("【日志库表】: %s %s\n", table.Name, table.Desc))
+		if notification.GetStatus() == db.AlarmStatusNormal {
+			statusText = "已恢复"
+			buffer.WriteString("【告警状态】: <font color=#008000>已恢复</font>\n")
+		} else {
+			buffer.WriteString("【告警状态】: <font color=red>告警中</font>\n")
+		}
+		dutyOfficesStr := ""
+		for _, u := range users {
+			if dutyOfficesStr == "" {
+				dutyOfficesStr = u.Nickname
+			} else {
+				dutyOfficesStr = fmt.Sprintf("%s/%s", dutyOfficesStr, u.Nickname)
+			}
+		}
+		if dutyOfficesStr != "" {
+			buffer.WriteString(fmt.Sprintf("【告警责任】: %s\n", dutyOfficesStr))
+		} else {
+			buffer.WriteString(fmt.Sprintf("【告警更新】: %s\n\n", updater.Nickname))
+		}
+		mode := 0
+		filterMode := "rawLog"
+		if filter.Mode == db.AlarmModeAggregation {
+			mode = 1
+			filterMode = "statisticalTable"
+		}
+		jumpURL := fmt.Sprintf("%s/share?mode=%d&tab=custom&tid=%d&kw=%s&start=%d&end=%d&queryType=%s", strings.TrimRight(econf.GetString("app.rootURL"), "/"), mode, filter.Tid, url.QueryEscape(filter.When), start, end, filterMode)
+		buffer.WriteString(fmt.Sprintf("【链接跳转】: %s\n", jumpURL))
+		if partialLog != "" {
+			partialLog = strings.Replace(partialLog, "\"", "", -1)
+			if len(partialLog) > 600 {
+				buffer.WriteString(fmt.Sprintf("【告警日志】: %s", partialLog[0:599]))
+			} else {
+				buffer.WriteString(fmt.Sprintf("【告警日志】: %s", partialLog))
+			}
+		}
+	}
+	pushMsg := &db.PushMsg{
+		Title: fmt.Sprintf("【%s】%s", statusText, alarm.Name),
+		Text:  buffer.String(),
+	}
+	if len(phones) != 0 {
+		pushMsg.Mobiles = phones
+	}
+	return pushMsg
+}
+
+// legacyAlarmMsgWithAt is BuildAlarmMsgWithAt before the templates, see legacyAlarmMsg.
+func legacyAlarmMsgWithAt(notification db.Notification, table *db.BaseTable, instance db.BaseInstance, alarm *db.Alarm, filter *db.AlarmFilter, partialLog string, users []db.User, phones []string, updater db.User) *db.PushMsg {
+	var buffer bytes.Buffer
+	if notification.GetStatus() == db.AlarmStatusNormal {
+		buffer.WriteString("<font color=#008000>您的告警已恢复</font>\n\n")
+	} else {
+		buffer.WriteString("<font color=#FF0000>您有待处理的告警</font>\n\n")
+	}
+	buffer.WriteString(fmt.Sprintf("【告警名称】: %s\n\n", alarm.Name))
+	if alarm.Desc != "" {
+		buffer.WriteString(fmt.Sprintf("【告警描述】: %s\n\n", alarm.Desc))
+	}
+	statusText := "告警中"
+	for _, alert := range notification.Alerts {
+		end := alert.StartsAt.Add(time.Minute).Unix()
+		start := alert.StartsAt.Add(-alarm.GetInterval() - time.Minute).Unix()
+		buffer.WriteString(fmt.Sprintf("【触发时间】: %s\n\n", alert.StartsAt.Add(time.Hour*8).Format("2006-01-02 15:04:05")))
+		buffer.WriteString(fmt.Sprintf("【相关实例】: %s %s\n\n", instance.Name, instance.Desc))
+		buffer.WriteString(fmt.Sprintf("【日志库表】: %s %s\n\n", table.Name, table.Desc))
+		if notification.GetStatus() == db.AlarmStatusNormal {
+			statusText = "已恢复"
+			buffer.WriteString("【告警状态】: <font color=#008000>已恢复</font>\n\n")
+		} else {
+			buffer.WriteString("【告警状态】: <font color=red>告警中</font>\n\n")
+		}
+		dutyOfficesStr := ""
+		for _, u := range users {
+			at := u.Phone
+			if at == "" {
+				at = u.Nickname
+			}
+			if dutyOfficesStr == "" {
+				dutyOfficesStr = fmt.Sprintf("@%s", at)
+			} else {
+				dutyOfficesStr = fmt.Sprintf("%s@%s", dutyOfficesStr, at)
+			}
+		}
+		if dutyOfficesStr != "" {
+			buffer.WriteString(fmt.Sprintf("【告警责任】: %s\n\n", dutyOfficesStr))
+		} else {
+			buffer.WriteString(fmt.Sprintf("【告警更新】: %s\n\n", updater.Nickname))
+		}
+		jumpURL := fmt.Sprintf("%s/share?mode=0&tab=custom&tid=%d&kw=%s&start=%d&end=%d",
+			strings.TrimRight(econf.GetString("app.rootURL"), "/"), filter.Tid, url.QueryEscape(filter.When), start, end,
+		)
+		buffer.WriteString(fmt.Sprintf("【链接跳转】: %s\n\n", jumpURL))
+		if partialLog != "" {
+			partialLog = strings.Replace(partialLog, "\"", "", -1)
+			if len(partialLog) > 600 {
+				buffer.WriteString(fmt.Sprintf("【告警日志】: %s", partialLog[0:599]))
+			} else {
+				buffer.WriteString(fmt.Sprintf("【告警日志】: %s", partialLog))
+			}
+		}
+	}
+	pushMsg := &db.PushMsg{
+		Title: fmt.Sprintf("【%s】%s", statusText, alarm.Name),
+		Text:  buffer.String(),
+	}
+	if len(phones) != 0 {
+		pushMsg.Mobiles = phones
+	}
+	return pushMsg
+}
+
+// TestRenderDefaultGolden checks that the default templates render the messages of the builders they replaced.
+func TestRenderDefaultGolden(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2023-01-02T03:04:05Z")
+	alerts := []db.Alert{{StartsAt: startsAt}, {StartsAt: startsAt.Add(time.Hour)}}
+	table := &db.BaseTable{Name: "ingress", Desc: "nginx"}
+	instance := db.BaseInstance{Name: "ch", Desc: "prod"}
+	updater := db.User{Nickname: "bob"}
+	officers := []db.User{{Nickname: "alice", Phone: "123"}, {Nickname: "carol", Phone: "456"}}
+	channels := []int{db.ChannelDingDing, db.ChannelWeChat, db.ChannelFeiShu, db.ChannelSlack, db.ChannelWebHook,
+		db.ChannelTelegram, db.ChannelEmail, db.ChannelPagerDuty, db.ChannelOpsgenie, db.ChannelTeams}
+	logs := []string{"", `{"status":"502"}`, strings.Repeat("a", 600), `"` + strings.Repeat("b", 700)}
+	for _, status := range []string{"firing", "resolved"} {
+		for _, desc := range []string{"", "5xx of the gateway"} {
+			for _, mode := range []int{0, db.AlarmModeAggregation} {
+				for _, users := range [][]db.User{nil, officers} {
+					for _, partialLog := range logs {
+						notification := db.Notification{Status: status, Alerts: alerts}
+						alarm := &db.Alarm{Name: "5xx", Desc: desc, Interval: 1, Unit: 1}
+						filter := &db.AlarmFilter{Tid: 3, When: "status>=500 and host='a'", Mode: mode}
+						var phones []string
+						for _, u := range users {
+							phones = append(phones, u.Phone)
+						}
+						for _, typ := range channels {
+							ctx := NewPreviewContext(notification, table, &instance, alarm, filter, partialLog)
+							ctx.DutyOfficers, ctx.Mobiles, ctx.Updater = users, phones, updater
+							msg, err := Render("", typ, ctx)
+							if err != nil {
+								t.Fatalf("Render() error = %v", err)
+							}
+							want := legacyAlarmMsg(notification, table, instance, alarm, filter, partialLog, users, phones, updater)
+							if typ == db.ChannelDingDing {
+								want = legacyAlarmMsgWithAt(notification, table, instance, alarm, filter, partialLog, users, phones, updater)
+							}
+							name := fmt.Sprintf("channel %d %s desc %q mode %d officers %d log %d", typ, status, desc, mode, len(users), len(partialLog))
+							if msg.Title != want.Title {
+								t.Errorf("%s: title = %q, want %q", name, msg.Title, want.Title)
+							}
+							if msg.Text != want.Text {
+								t.Errorf("%s: text = %q, want %q", name, msg.Text, want.Text)
+							}
+							if strings.Join(msg.Mobiles, ",") != strings.Join(want.Mobiles, ",") {
+								t.Errorf("%s: mobiles = %v, want %v", name, msg.Mobiles, want.Mobiles)
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
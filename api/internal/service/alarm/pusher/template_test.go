@@ -0,0 +1,53 @@
+package pusher
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestRender(t *testing.T) {
+	startsAt, _ := time.Parse(time.RFC3339, "2023-01-02T03:04:05Z")
+	notification := db.Notification{Status: "firing", Alerts: []db.Alert{{StartsAt: startsAt}}}
+	newContext := func() *TemplateContext {
+		ctx := NewPreviewContext(notification, &db.BaseTable{Name: "ingress"}, &db.BaseInstance{Name: "ch"}, &db.Alarm{Name: "5xx"}, &db.AlarmFilter{}, `{"status":"502"}`)
+		ctx.DutyOfficers = []db.User{{Nickname: "alice", Phone: "123"}}
+		ctx.Mobiles = []string{"123"}
+		return ctx
+	}
+	Convey("default template", t, func() {
+		msg, err := Render("", db.ChannelFeiShu, newContext())
+		So(err, ShouldBeNil)
+		So(msg.Title, ShouldEqual, "【告警中】5xx")
+		So(msg.Text, ShouldContainSubstring, "【触发时间】: 2023-01-02 11:04:05\n")
+		So(msg.Text, ShouldContainSubstring, "【告警责任】: alice\n")
+		So(msg.Text, ShouldEndWith, "【告警日志】: {status:502}")
+		So(msg.Mobiles, ShouldResemble, []string{"123"})
+	})
+	Convey("default dingding template", t, func() {
+		msg, err := Render("", db.ChannelDingDing, newContext())
+		So(err, ShouldBeNil)
+		So(msg.Text, ShouldContainSubstring, "【告警责任】: @123\n\n")
+	})
+	Convey("custom template with title", t, func() {
+		tpl := `{{define "title"}}[{{if .Resolved}}RESOLVED{{else}}FIRING{{end}}] {{.Alarm.Name}}{{end}}` +
+			`{{range .Alerts}}{{formatTime .StartsAt "15:04" "UTC"}} {{$.Table.Name}} {{truncate 4 $.PartialLog}}{{end}}`
+		msg, err := Render(tpl, db.ChannelSlack, newContext())
+		So(err, ShouldBeNil)
+		So(msg.Title, ShouldEqual, "[FIRING] 5xx")
+		So(msg.Text, ShouldEqual, `03:04 ingress {"st`)
+	})
+	Convey("invalid template", t, func() {
+		So(ParseTemplate("{{.Alarm.Name"), ShouldNotBeNil)
+		So(ParseTemplate(""), ShouldBeNil)
+		_, err := Render("{{.Unknown}}", db.ChannelSlack, newContext())
+		So(err, ShouldNotBeNil)
+	})
+	Convey("truncate keeps characters", t, func() {
+		So(truncate(4, "告警"), ShouldEqual, "告")
+		So(truncate(10, "告警"), ShouldEqual, "告警")
+	})
+}
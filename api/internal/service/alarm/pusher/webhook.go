@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"strings"
 
-	"github.com/go-resty/resty/v2"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/pkg/errors"
 
@@ -23,8 +22,7 @@ func (e *Webhook) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
 		elog.Error("webhookSend", elog.String("title", msg.Title), elog.Any("mobiles", msg.Mobiles), elog.FieldErr(err))
 		return errors.New(err.Error())
 	}
-	client := resty.New()
-	resp, err := client.R().
+	resp, err := restyR().
 		SetHeader("Content-Type", "application/json").
 		SetBody(b).
 		SetResult(&dto.WebhookResp{}). // or SetResult(AuthSuccess{}).
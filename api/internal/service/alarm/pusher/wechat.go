@@ -4,9 +4,11 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
-	"net/http"
+	"io"
 	"strings"
 
+	"github.com/pkg/errors"
+
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
 )
 
@@ -40,12 +42,19 @@ func (d *WeChat) Send(channel *db.AlarmChannel, msg *db.PushMsg) (err error) {
 	msg.Text = strings.ReplaceAll(msg.Text, "#####", "")
 	dataJsonStr := fmt.Sprintf(`{"msgtype": "%s", "%s": {"content": "%s", "mentioned_list": %s, "mentioned_mobile_list": %s}}`, typeStr, typeStr, msg.Text, string(b1), string(b2))
 	// 默认markdown 可以制作格式
-	resp, err := http.Post(
+	resp, err := HTTPClient().Post(
 		channel.Key,
 		"application/json",
 		bytes.NewBuffer([]byte(dataJsonStr)))
+	if err != nil {
+		return errors.Wrap(err, "wechat send")
+	}
 	defer func() {
 		_ = resp.Body.Close()
 	}()
-	return
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return errors.Wrap(err, "read body failed")
+	}
+	return errors.Wrap(checkErrCode(resp, body), "wechat send")
 }
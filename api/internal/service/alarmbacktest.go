@@ -0,0 +1,145 @@
+package service
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/evaluator"
+)
+
+const (
+	// backtestMaxRange bounds the backtest time range, the metric stream is read per second.
+	backtestMaxRange = time.Hour * 24 * 7
+	// backtestMaxWindows bounds the evaluated windows of a filter.
+	backtestMaxWindows = 2000
+)
+
+var (
+	ErrBacktestRange = errors.Errorf("backtest range must be positive and at most %s", backtestMaxRange)
+	ErrBacktestMode  = errors.New("backtest only supports filters in the normal mode, aggregation SQL is not bound to a time range")
+)
+
+// AlarmBacktest evaluates an alarm that is not saved yet over historical logs.
+// Every filter reads its metric stream with the table operator and applies its
+// conditions window by window like the native evaluator, no view or rule is created.
+func AlarmBacktest(req view.ReqAlarmBacktest) (res view.RespAlarmBacktest, err error) {
+	req.ConvertV2()
+	alarm := &db.Alarm{Interval: req.Interval, Unit: req.Unit, NoDataOp: req.NoDataOp}
+	interval := int64(alarm.GetInterval().Seconds())
+	if interval <= 0 || len(req.Filters) == 0 {
+		return res, errors.New("interval and filters are required")
+	}
+	if req.EndTime <= req.StartTime || time.Duration(req.EndTime-req.StartTime)*time.Second > backtestMaxRange {
+		return res, ErrBacktestRange
+	}
+	if (req.EndTime-req.StartTime)/interval > backtestMaxWindows {
+		return res, errors.Errorf("backtest range covers more than %d windows of the alarm interval", backtestMaxWindows)
+	}
+	res.Filters = make([]view.RespAlarmBacktestFilter, 0, len(req.Filters))
+	for _, filter := range req.Filters {
+		row, errFilter := backtestFilter(alarm, filter, req.StartTime, req.EndTime, req.IsDisableResolve == 1)
+		if errFilter != nil {
+			return res, errors.Wrapf(errFilter, "table id: %d", filter.Tid)
+		}
+		res.Notifications += row.Notifications
+		res.Filters = append(res.Filters, row)
+	}
+	return res, nil
+}
+
+func backtestFilter(alarm *db.Alarm, req view.ReqAlarmFilterCreate, st, et int64, isDisableResolve bool) (res view.RespAlarmBacktestFilter, err error) {
+	if req.Mode != 0 {
+		return res, ErrBacktestMode
+	}
+	conditions := make([]*db.AlarmCondition, 0, len(req.Conditions))
+	var lookback int64
+	for _, c := range req.Conditions {
+		cond := &db.AlarmCondition{
+			SetOperatorTyp: c.SetOperatorTyp,
+			SetOperatorExp: c.SetOperatorExp,
+			Cond:           c.Cond,
+			Val1:           c.Val1,
+			Val2:           c.Val2,
+			Baseline:       c.Baseline,
+			Field:          c.Field,
+		}
+		if cond.Cond == evaluator.CondPercentChange || cond.Cond == evaluator.CondZScore {
+			if b := int64(evaluator.Baseline(cond).Seconds()); b > lookback {
+				lookback = b
+			}
+		}
+		conditions = append(conditions, cond)
+	}
+	if err = evaluator.ValidConditions(conditions, req.Mode, alarm.GetInterval()); err != nil {
+		return
+	}
+	table, err := db.TableInfo(invoker.Db, req.Tid)
+	if err != nil {
+		return
+	}
+	op, err := InstanceManager.Load(table.Database.Iid)
+	if err != nil {
+		return
+	}
+	filter := &db.AlarmFilter{Tid: req.Tid, When: req.When, Mode: req.Mode}
+	if filter.When == "" {
+		filter.When = "1=1"
+	}
+	// the stream is read once, the baselines are slices of it
+	samples, err := AlertEvaluator.series(op, filter.When, &table, st-lookback, et)
+	if err != nil {
+		return
+	}
+	evaluator.SortSamples(samples)
+	series := func(st, et int64) ([]evaluator.Sample, error) {
+		return evaluator.Between(samples, st, et), nil
+	}
+	interval := int64(alarm.GetInterval().Seconds())
+	statuses, err := evaluator.Backtest(conditions, samples, filter.Mode, alarm.NoDataOp, st, et, interval, func(st, et int64) evaluator.AnomalyMatcher {
+		return AlertEvaluator.anomaly(op, filter, &table, st, et, series)
+	})
+	if err != nil {
+		return
+	}
+	res = backtestTimeline(statuses, st, interval, isDisableResolve)
+	res.Tid = table.ID
+	res.TableName = table.Name
+	return res, nil
+}
+
+// backtestTimeline turns the window statuses into the transitions the native
+// evaluator would dispatch, the alarm is assumed to be normal before st.
+func backtestTimeline(statuses []int, st, interval int64, isDisableResolve bool) view.RespAlarmBacktestFilter {
+	res := view.RespAlarmBacktestFilter{
+		Windows:     len(statuses),
+		Transitions: make([]view.AlarmBacktestTransition, 0),
+	}
+	prev := db.AlarmStatusNormal
+	for k, status := range statuses {
+		end := st + int64(k+1)*interval
+		switch status {
+		case db.AlarmStatusFiring:
+			res.FiringWindows++
+			if prev != db.AlarmStatusFiring {
+				res.Transitions = append(res.Transitions, view.AlarmBacktestTransition{Time: end, Status: "firing"})
+				res.Notifications++
+			}
+		case db.AlarmStatusNormal:
+			if prev == db.AlarmStatusFiring {
+				res.Transitions = append(res.Transitions, view.AlarmBacktestTransition{Time: end, Status: "resolved"})
+				if !isDisableResolve {
+					res.Notifications++
+				}
+			}
+		default:
+			// no data keeps the current state
+			continue
+		}
+		prev = status
+	}
+	return res
+}
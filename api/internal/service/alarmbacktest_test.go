@@ -0,0 +1,48 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func Test_backtestTimeline(t *testing.T) {
+	statuses := []int{
+		db.AlarmStatusNormal,
+		db.AlarmStatusFiring,
+		db.AlarmStatusUnknown,
+		db.AlarmStatusFiring,
+		db.AlarmStatusNormal,
+		db.AlarmStatusUnknown,
+		db.AlarmStatusFiring,
+	}
+	transitions := []view.AlarmBacktestTransition{
+		{Time: 1120, Status: "firing"},
+		{Time: 1300, Status: "resolved"},
+		{Time: 1420, Status: "firing"},
+	}
+	tests := []struct {
+		name             string
+		isDisableResolve bool
+		want             view.RespAlarmBacktestFilter
+	}{
+		{
+			name: "resolve",
+			want: view.RespAlarmBacktestFilter{Windows: 7, FiringWindows: 3, Notifications: 3, Transitions: transitions},
+		},
+		{
+			name:             "disable resolve",
+			isDisableResolve: true,
+			want:             view.RespAlarmBacktestFilter{Windows: 7, FiringWindows: 3, Notifications: 2, Transitions: transitions},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := backtestTimeline(statuses, 1000, 60, tt.isDisableResolve); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("backtestTimeline() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,472 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// alarmRefs resolves the ids stored with alarms to the names used by
+// view.AlarmDocument and back, every lookup is cached for one export or plan.
+type alarmRefs struct {
+	channels    map[int]string
+	users       map[int]string
+	escalations map[int]string
+	tables      map[int]view.AlarmTableRef
+	instances   map[int]string
+}
+
+func newAlarmRefs() (*alarmRefs, error) {
+	r := &alarmRefs{
+		channels:    make(map[int]string),
+		users:       make(map[int]string),
+		escalations: make(map[int]string),
+		tables:      make(map[int]view.AlarmTableRef),
+		instances:   make(map[int]string),
+	}
+	channels, err := db2.AlarmChannelList(egorm.Conds{})
+	if err != nil {
+		return nil, err
+	}
+	for _, ch := range channels {
+		r.channels[ch.ID] = ch.Name
+	}
+	users, err := db2.UserList(egorm.Conds{})
+	if err != nil {
+		return nil, err
+	}
+	for _, u := range users {
+		r.users[u.ID] = u.Username
+	}
+	escalations, err := (&db2.AlarmEscalation{}).List(invoker.Db, egorm.Conds{})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range escalations {
+		r.escalations[e.ID] = e.Name
+	}
+	return r, nil
+}
+
+func (r *alarmRefs) table(tid int) (view.AlarmTableRef, error) {
+	if ref, ok := r.tables[tid]; ok {
+		return ref, nil
+	}
+	table, err := db2.TableInfo(invoker.Db, tid)
+	if err != nil {
+		return view.AlarmTableRef{}, err
+	}
+	instance, ok := r.instances[table.Database.Iid]
+	if !ok {
+		ins, errIns := db2.InstanceInfo(invoker.Db, table.Database.Iid)
+		if errIns != nil {
+			return view.AlarmTableRef{}, errIns
+		}
+		instance = ins.Name
+		r.instances[table.Database.Iid] = instance
+	}
+	ref := view.AlarmTableRef{Instance: instance, Database: table.Database.Name, Table: table.Name}
+	r.tables[tid] = ref
+	return ref, nil
+}
+
+func (r *alarmRefs) tableId(ref view.AlarmTableRef) (int, error) {
+	for tid, v := range r.tables {
+		if v == ref {
+			return tid, nil
+		}
+	}
+	ins, err := db2.InstanceInfoX(invoker.Db, map[string]interface{}{"name": ref.Instance})
+	if err != nil || ins.ID == 0 {
+		return 0, errors.Errorf("instance %s not found", ref.Instance)
+	}
+	database, err := db2.DatabaseInfoX(invoker.Db, map[string]interface{}{"iid": ins.ID, "name": ref.Database})
+	if err != nil || database.ID == 0 {
+		return 0, errors.Errorf("database %s.%s not found", ref.Instance, ref.Database)
+	}
+	table, err := db2.TableInfoX(invoker.Db, map[string]interface{}{"did": database.ID, "name": ref.Table})
+	if err != nil || table.ID == 0 {
+		return 0, errors.Errorf("table %s.%s.%s not found", ref.Instance, ref.Database, ref.Table)
+	}
+	r.tables[table.ID] = ref
+	return table.ID, nil
+}
+
+// lookup returns the unique id of name in m.
+func lookup(kind string, m map[int]string, name string) (int, error) {
+	id := 0
+	for k, v := range m {
+		if v != name {
+			continue
+		}
+		if id != 0 {
+			return 0, errors.Errorf("%s %s is ambiguous", kind, name)
+		}
+		id = k
+	}
+	if id == 0 {
+		return 0, errors.Errorf("%s %s not found", kind, name)
+	}
+	return id, nil
+}
+
+func names(m map[int]string, ids []int) []string {
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		if name, ok := m[id]; ok {
+			res = append(res, name)
+		}
+	}
+	return res
+}
+
+// spec converts an alarm to its declarative form.
+func (r *alarmRefs) spec(alarm *db2.Alarm) (view.AlarmSpec, error) {
+	res := view.AlarmSpec{
+		Name:             alarm.Name,
+		Desc:             alarm.Desc,
+		Interval:         alarm.Interval,
+		Unit:             alarm.Unit,
+		Level:            alarm.Level,
+		NoDataOp:         alarm.NoDataOp,
+		Tags:             alarm.Tags,
+		Channels:         names(r.channels, alarm.ChannelIds),
+		DutyOfficers:     names(r.users, alarm.DutyOfficers),
+		Escalation:       r.escalations[alarm.EscalationId],
+		IsDisableResolve: alarm.IsDisableResolve,
+		GroupBy:          alarm.GroupBy,
+		GroupWait:        alarm.GroupWait,
+		GroupInterval:    alarm.GroupInterval,
+		RepeatInterval:   alarm.RepeatInterval,
+		Disabled:         alarm.Status == db2.AlarmStatusClose,
+		Filters:          make([]view.AlarmFilterSpec, 0),
+	}
+	filters, err := db2.AlarmFilterList(invoker.Db, egorm.Conds{"alarm_id": alarm.ID})
+	if err != nil {
+		return res, err
+	}
+	for _, filter := range filters {
+		ref, errTable := r.table(filter.Tid)
+		if errTable != nil {
+			return res, errors.Wrapf(errTable, "alarm %s", alarm.Name)
+		}
+		conditions, errCond := db2.AlarmConditionList(egorm.Conds{"alarm_id": alarm.ID, "filter_id": filter.ID})
+		if errCond != nil {
+			return res, errCond
+		}
+		fs := view.AlarmFilterSpec{
+			Table:          ref,
+			When:           filter.When,
+			SetOperatorTyp: filter.SetOperatorTyp,
+			SetOperatorExp: filter.SetOperatorExp,
+			Mode:           filter.Mode,
+			Conditions:     make([]view.AlarmConditionSpec, 0, len(conditions)),
+		}
+		for _, cond := range conditions {
+			fs.Conditions = append(fs.Conditions, view.AlarmConditionSpec{
+				SetOperatorTyp: cond.SetOperatorTyp,
+				SetOperatorExp: cond.SetOperatorExp,
+				Cond:           cond.Cond,
+				Val1:           cond.Val1,
+				Val2:           cond.Val2,
+				Baseline:       cond.Baseline,
+				Field:          cond.Field,
+			})
+		}
+		res.Filters = append(res.Filters, fs)
+	}
+	return res, nil
+}
+
+// request converts a declarative alarm to the request accepted by Create and Update.
+func (r *alarmRefs) request(spec view.AlarmSpec) (req view.ReqAlarmCreate, err error) {
+	req = view.ReqAlarmCreate{
+		Name:             spec.Name,
+		Desc:             spec.Desc,
+		Interval:         spec.Interval,
+		Unit:             spec.Unit,
+		Level:            spec.Level,
+		NoDataOp:         spec.NoDataOp,
+		Tags:             spec.Tags,
+		IsDisableResolve: spec.IsDisableResolve,
+		GroupBy:          spec.GroupBy,
+		GroupWait:        spec.GroupWait,
+		GroupInterval:    spec.GroupInterval,
+		RepeatInterval:   spec.RepeatInterval,
+	}
+	if req.Name == "" || req.Interval == 0 || len(spec.Channels) == 0 || len(spec.Filters) == 0 {
+		return req, errors.New("name, interval, channels and filters are required")
+	}
+	if err = db2.ValidGroupBy(req.GroupBy); err != nil {
+		return
+	}
+	for _, name := range spec.Channels {
+		id, errLookup := lookup("channel", r.channels, name)
+		if errLookup != nil {
+			return req, errLookup
+		}
+		req.ChannelIds = append(req.ChannelIds, id)
+	}
+	for _, name := range spec.DutyOfficers {
+		id, errLookup := lookup("user", r.users, name)
+		if errLookup != nil {
+			return req, errLookup
+		}
+		req.DutyOfficers = append(req.DutyOfficers, id)
+	}
+	if spec.Escalation != "" {
+		if req.EscalationId, err = lookup("escalation", r.escalations, spec.Escalation); err != nil {
+			return
+		}
+	}
+	for _, fs := range spec.Filters {
+		tid, errTable := r.tableId(fs.Table)
+		if errTable != nil {
+			return req, errTable
+		}
+		filter := view.ReqAlarmFilterCreate{
+			Tid:            tid,
+			When:           fs.When,
+			SetOperatorTyp: fs.SetOperatorTyp,
+			SetOperatorExp: fs.SetOperatorExp,
+			Mode:           fs.Mode,
+		}
+		for _, cond := range fs.Conditions {
+			filter.Conditions = append(filter.Conditions, view.ReqAlarmConditionCreate{
+				SetOperatorTyp: cond.SetOperatorTyp,
+				SetOperatorExp: cond.SetOperatorExp,
+				Cond:           cond.Cond,
+				Val1:           cond.Val1,
+				Val2:           cond.Val2,
+				Baseline:       cond.Baseline,
+				Field:          cond.Field,
+			})
+		}
+		req.Filters = append(req.Filters, filter)
+	}
+	return req, nil
+}
+
+// AlarmExport returns the YAML document of the alarms, all the alarms when ids is empty.
+func AlarmExport(ids []int) ([]byte, error) {
+	conds := egorm.Conds{}
+	if len(ids) > 0 {
+		conds["id"] = egorm.Cond{Op: "in", Val: ids}
+	}
+	alarms, err := db2.AlarmList(conds)
+	if err != nil {
+		return nil, err
+	}
+	refs, err := newAlarmRefs()
+	if err != nil {
+		return nil, err
+	}
+	doc := view.AlarmDocument{Alarms: make([]view.AlarmSpec, 0, len(alarms))}
+	for _, alarm := range alarms {
+		spec, errSpec := refs.spec(alarm)
+		if errSpec != nil {
+			return nil, errSpec
+		}
+		doc.Alarms = append(doc.Alarms, spec)
+	}
+	sort.Slice(doc.Alarms, func(i, j int) bool {
+		return doc.Alarms[i].Name < doc.Alarms[j].Name
+	})
+	return yaml.Marshal(doc)
+}
+
+// AlarmApply diffs the YAML document against the current alarms and applies
+// the plan through Create, Update and Delete unless dryRun is set.
+// Alarms are matched by name, the ones missing in the document are only deleted with prune.
+func AlarmApply(uid int, content string, dryRun, prune bool) (res view.RespAlarmPlan, err error) {
+	var doc view.AlarmDocument
+	if err = yaml.Unmarshal([]byte(content), &doc); err != nil {
+		return res, errors.Wrap(err, "invalid document")
+	}
+	refs, err := newAlarmRefs()
+	if err != nil {
+		return
+	}
+	alarms, err := db2.AlarmList(egorm.Conds{})
+	if err != nil {
+		return
+	}
+	existing := make(map[string][]*db2.Alarm, len(alarms))
+	for _, alarm := range alarms {
+		existing[alarm.Name] = append(existing[alarm.Name], alarm)
+	}
+	reqs := make(map[string]view.ReqAlarmCreate, len(doc.Alarms))
+	disabled := make(map[string]bool, len(doc.Alarms))
+	res.Items = make([]view.AlarmPlanItem, 0, len(doc.Alarms))
+	for _, spec := range doc.Alarms {
+		if _, ok := reqs[spec.Name]; ok {
+			return res, errors.Errorf("alarm %s is declared twice", spec.Name)
+		}
+		req, errReq := refs.request(spec)
+		if errReq != nil {
+			return res, errors.Wrapf(errReq, "alarm %s", spec.Name)
+		}
+		reqs[spec.Name] = req
+		disabled[spec.Name] = spec.Disabled
+		item := view.AlarmPlanItem{Action: view.AlarmPlanCreate, Name: spec.Name}
+		switch current := existing[spec.Name]; len(current) {
+		case 0:
+		case 1:
+			cur, errSpec := refs.spec(current[0])
+			if errSpec != nil {
+				return res, errSpec
+			}
+			item.AlarmId = current[0].ID
+			item.Changes = diffAlarmSpec(cur, spec)
+			item.Action = view.AlarmPlanUpdate
+			if len(item.Changes) == 0 {
+				item.Action = view.AlarmPlanNoop
+			}
+		default:
+			return res, errors.Errorf("alarm %s is ambiguous, %d alarms have this name", spec.Name, len(current))
+		}
+		res.Items = append(res.Items, item)
+	}
+	if prune {
+		for _, alarm := range alarms {
+			if _, ok := reqs[alarm.Name]; !ok {
+				res.Items = append(res.Items, view.AlarmPlanItem{Action: view.AlarmPlanDelete, Name: alarm.Name, AlarmId: alarm.ID})
+			}
+		}
+	}
+	if dryRun {
+		return res, nil
+	}
+	for k, item := range res.Items {
+		var errApply error
+		switch item.Action {
+		case view.AlarmPlanCreate:
+			var obj *db2.Alarm
+			if obj, errApply = Alert.Create(uid, reqs[item.Name]); errApply == nil {
+				res.Items[k].AlarmId = obj.ID
+			}
+		case view.AlarmPlanUpdate:
+			errApply = Alert.Update(uid, item.AlarmId, reqs[item.Name])
+		case view.AlarmPlanDelete:
+			alarmInfo, relatedList, errInfo := db2.GetAlarmTableInstanceInfo(item.AlarmId)
+			if errInfo != nil {
+				errApply = errInfo
+				break
+			}
+			errApply = Alert.Delete(&alarmInfo, relatedList)
+		}
+		// Create and Update open the alarm
+		if errApply == nil && disabled[item.Name] && item.Action != view.AlarmPlanNoop && item.Action != view.AlarmPlanDelete {
+			errApply = closeAlarm(res.Items[k].AlarmId)
+		}
+		if errApply != nil {
+			res.Items[k].Error = errApply.Error()
+			err = errors.Errorf("%d of %d alarms failed to apply", countPlanErrors(res.Items), len(res.Items))
+		}
+	}
+	res.Applied = true
+	return res, err
+}
+
+func closeAlarm(id int) error {
+	alarmInfo, relatedList, err := db2.GetAlarmTableInstanceInfo(id)
+	if err != nil {
+		return err
+	}
+	return Alert.CloseOperator(&alarmInfo, relatedList)
+}
+
+func countPlanErrors(items []view.AlarmPlanItem) int {
+	n := 0
+	for _, item := range items {
+		if item.Error != "" {
+			n++
+		}
+	}
+	return n
+}
+
+// diffAlarmSpec returns the yaml names of the fields that differ between the current and the wanted alarm.
+func diffAlarmSpec(cur, want view.AlarmSpec) []string {
+	cur, want = normalizeAlarmSpec(cur), normalizeAlarmSpec(want)
+	res := make([]string, 0)
+	cv, wv := reflect.ValueOf(cur), reflect.ValueOf(want)
+	for i := 0; i < cv.NumField(); i++ {
+		if reflect.DeepEqual(cv.Field(i).Interface(), wv.Field(i).Interface()) {
+			continue
+		}
+		name := strings.Split(cv.Type().Field(i).Tag.Get("yaml"), ",")[0]
+		if name == "" {
+			name = cv.Type().Field(i).Name
+		}
+		res = append(res, name)
+	}
+	return res
+}
+
+// normalizeAlarmSpec makes equivalent alarms deep equal: empty collections are nil,
+// channels and duty officers are unordered and an empty filter matches everything.
+func normalizeAlarmSpec(spec view.AlarmSpec) view.AlarmSpec {
+	if len(spec.Tags) == 0 {
+		spec.Tags = nil
+	}
+	spec.Channels = sortedStrings(spec.Channels)
+	spec.DutyOfficers = sortedStrings(spec.DutyOfficers)
+	if len(spec.GroupBy) == 0 {
+		spec.GroupBy = nil
+	}
+	filters := make([]view.AlarmFilterSpec, 0, len(spec.Filters))
+	for _, f := range spec.Filters {
+		if f.When == "" {
+			f.When = "1=1"
+		}
+		if len(f.Conditions) == 0 {
+			f.Conditions = nil
+		}
+		filters = append(filters, f)
+	}
+	spec.Filters = filters
+	return spec
+}
+
+func sortedStrings(in []string) []string {
+	if len(in) == 0 {
+		return nil
+	}
+	res := make([]string, len(in))
+	copy(res, in)
+	sort.Strings(res)
+	return res
+}
+
+// AlarmPlanString returns the plan in the terraform like format printed by the CLI.
+func AlarmPlanString(plan view.RespAlarmPlan) string {
+	var b strings.Builder
+	counts := make(map[string]int)
+	for _, item := range plan.Items {
+		counts[item.Action]++
+		if item.Action == view.AlarmPlanNoop {
+			continue
+		}
+		fmt.Fprintf(&b, "%-6s %s", item.Action, item.Name)
+		if len(item.Changes) > 0 {
+			fmt.Fprintf(&b, " (%s)", strings.Join(item.Changes, ", "))
+		}
+		if item.Error != "" {
+			fmt.Fprintf(&b, ": %s", item.Error)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "%d to create, %d to update, %d to delete, %d unchanged\n",
+		counts[view.AlarmPlanCreate], counts[view.AlarmPlanUpdate], counts[view.AlarmPlanDelete], counts[view.AlarmPlanNoop])
+	return b.String()
+}
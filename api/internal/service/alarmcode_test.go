@@ -0,0 +1,103 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func Test_diffAlarmSpec(t *testing.T) {
+	base := view.AlarmSpec{
+		Name:     "ingress-5xx",
+		Interval: 1,
+		Channels: []string{"ops", "dev"},
+		Filters: []view.AlarmFilterSpec{{
+			Table:      view.AlarmTableRef{Instance: "ch", Database: "logs", Table: "ingress"},
+			When:       "status>=500",
+			Conditions: []view.AlarmConditionSpec{{Cond: 0, Val1: 10}},
+		}},
+	}
+	tests := []struct {
+		name string
+		want func(s view.AlarmSpec) view.AlarmSpec
+		diff []string
+	}{
+		{
+			name: "same",
+			want: func(s view.AlarmSpec) view.AlarmSpec { return s },
+			diff: []string{},
+		},
+		{
+			name: "channel order and empty collections",
+			want: func(s view.AlarmSpec) view.AlarmSpec {
+				s.Channels = []string{"dev", "ops"}
+				s.Tags = map[string]string{}
+				s.GroupBy = []string{}
+				return s
+			},
+			diff: []string{},
+		},
+		{
+			name: "empty when",
+			want: func(s view.AlarmSpec) view.AlarmSpec {
+				s.Filters = []view.AlarmFilterSpec{s.Filters[0]}
+				s.Filters[0].When = ""
+				return s
+			},
+			diff: []string{"filters"},
+		},
+		{
+			name: "fields",
+			want: func(s view.AlarmSpec) view.AlarmSpec {
+				s.Interval = 5
+				s.Channels = []string{"ops"}
+				s.Filters = []view.AlarmFilterSpec{s.Filters[0]}
+				s.Filters[0].Conditions = []view.AlarmConditionSpec{{Cond: 0, Val1: 20}}
+				return s
+			},
+			diff: []string{"interval", "channels", "filters"},
+		},
+		{
+			name: "disabled",
+			want: func(s view.AlarmSpec) view.AlarmSpec {
+				s.Disabled = true
+				return s
+			},
+			diff: []string{"disabled"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffAlarmSpec(base, tt.want(base)); !reflect.DeepEqual(got, tt.diff) {
+				t.Errorf("diffAlarmSpec() = %v, want %v", got, tt.diff)
+			}
+		})
+	}
+	cur := base
+	cur.Filters = []view.AlarmFilterSpec{base.Filters[0]}
+	cur.Filters[0].When = "1=1"
+	want := cur
+	want.Filters = []view.AlarmFilterSpec{cur.Filters[0]}
+	want.Filters[0].When = ""
+	if got := diffAlarmSpec(cur, want); len(got) != 0 {
+		t.Errorf("diffAlarmSpec() = %v, want no change for an empty filter", got)
+	}
+}
+
+func TestAlarmPlanString(t *testing.T) {
+	plan := view.RespAlarmPlan{Items: []view.AlarmPlanItem{
+		{Action: view.AlarmPlanCreate, Name: "a"},
+		{Action: view.AlarmPlanUpdate, Name: "b", Changes: []string{"interval", "filters"}},
+		{Action: view.AlarmPlanNoop, Name: "c"},
+		{Action: view.AlarmPlanDelete, Name: "d", Error: "boom"},
+	}}
+	want := `create a
+update b (interval, filters)
+delete d: boom
+1 to create, 1 to update, 1 to delete, 1 unchanged
+`
+	if got := AlarmPlanString(plan); got != want {
+		t.Errorf("AlarmPlanString() = %q, want %q", got, want)
+	}
+}
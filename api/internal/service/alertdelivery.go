@@ -0,0 +1,47 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
+)
+
+const deliveryTickInterval = 10 * time.Second
+
+// alertDelivery retries the failed alarm deliveries when their backoff is over.
+type alertDelivery struct {
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+func NewAlertDelivery() *alertDelivery {
+	return &alertDelivery{}
+}
+
+func (d *alertDelivery) tickerRetry() {
+	d.mu.Lock()
+	d.stopChan = make(chan struct{})
+	stopChan := d.stopChan
+	d.mu.Unlock()
+	ticker := time.NewTicker(deliveryTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("alertDelivery", "retry", pusher.Retry(now))
+		}
+	}
+}
+
+func (d *alertDelivery) stop() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.stopChan != nil {
+		close(d.stopChan)
+		d.stopChan = nil
+	}
+}
@@ -0,0 +1,177 @@
+package service
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+const (
+	escalationTickInterval = time.Minute
+	// firing alerts older than escalationMaxAge are not escalated anymore
+	escalationMaxAge = time.Hour * 24
+)
+
+// alertEscalator notifies the following steps of the escalation policies
+// while the alerts are firing and not acknowledged.
+type alertEscalator struct {
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+func NewAlertEscalator() *alertEscalator {
+	return &alertEscalator{}
+}
+
+func (e *alertEscalator) tickerEscalate() {
+	e.mu.Lock()
+	e.stopChan = make(chan struct{})
+	stopChan := e.stopChan
+	e.mu.Unlock()
+	ticker := time.NewTicker(escalationTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("alertEscalator", "escalate", e.escalate(now))
+		}
+	}
+}
+
+func (e *alertEscalator) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopChan != nil {
+		close(e.stopChan)
+		e.stopChan = nil
+	}
+}
+
+func (e *alertEscalator) escalate(now time.Time) error {
+	alarms, err := db.AlarmList(egorm.Conds{
+		"status":        db.AlarmStatusFiring,
+		"escalation_id": egorm.Cond{Op: "!=", Val: 0},
+	})
+	if err != nil {
+		return err
+	}
+	for _, alarm := range alarms {
+		policy := db.AlarmEscalation{}
+		policy.ID = alarm.EscalationId
+		if errPolicy := policy.Info(invoker.Db); errPolicy != nil {
+			err = errors.Wrapf(errPolicy, "alarm id: %d", alarm.ID)
+			continue
+		}
+		histories, errHistories := db.AlarmHistoryList(invoker.Db, egorm.Conds{
+			"alarm_id":        alarm.ID,
+			"filter_status":   db.AlarmStatusFiring,
+			"is_pushed":       egorm.Cond{Op: "in", Val: []int{db.PushedStatusSuccess, db.PushedStatusRetrying}},
+			"ack_time":        0,
+			"escalation_step": egorm.Cond{Op: "<", Val: len(policy.Steps) - 1},
+			"ctime":           egorm.Cond{Op: ">", Val: now.Add(-escalationMaxAge).Unix()},
+		})
+		if errHistories != nil {
+			err = errors.Wrapf(errHistories, "alarm id: %d", alarm.ID)
+			continue
+		}
+		for _, history := range histories {
+			if errHistory := e.escalateHistory(alarm, &policy, history, now); errHistory != nil {
+				elog.Error("alertEscalator", l.I("alarmId", alarm.ID), l.I("historyId", history.ID), l.E(errHistory))
+			}
+		}
+	}
+	return err
+}
+
+func (e *alertEscalator) escalateHistory(alarm *db.Alarm, policy *db.AlarmEscalation, history *db.AlarmHistory, now time.Time) error {
+	step := policy.DueStep(now.Sub(time.Unix(history.Ctime, 0)))
+	if step <= history.EscalationStep {
+		return nil
+	}
+	// the alert is resolved or a newer notification took over the escalation
+	filter, err := db.AlarmFilterInfo(invoker.Db, history.FilterId)
+	if err != nil {
+		return err
+	}
+	if filter.Status != db.AlarmStatusFiring {
+		return nil
+	}
+	newer, err := db.AlarmHistoryList(invoker.Db, egorm.Conds{
+		"filter_id": history.FilterId,
+		"is_pushed": egorm.Cond{Op: "in", Val: []int{db.PushedStatusSuccess, db.PushedStatusRetrying}},
+		"id":        egorm.Cond{Op: ">", Val: history.ID},
+	})
+	if err != nil {
+		return err
+	}
+	if len(newer) > 0 {
+		return nil
+	}
+	target := *alarm
+	channelIds, err := escalationTarget(&target, step, now)
+	if err != nil {
+		return err
+	}
+	labels := map[string]string{
+		"alertname": alarm.UniqueName(history.FilterId),
+		"uuid":      alarm.Uuid,
+		"alarmId":   strconv.Itoa(alarm.ID),
+		"filterId":  strconv.Itoa(history.FilterId),
+	}
+	notification := db.Notification{
+		Version:      "4",
+		Receiver:     "clickvisual",
+		Status:       "firing",
+		CommonLabels: labels,
+		Alerts:       []db.Alert{{Labels: labels, StartsAt: time.Unix(history.Ctime, 0)}},
+	}
+	// failed deliveries are retried by the delivery worker, the step is notified once
+	errPush := Alert.push(&target, history.ID, history.FilterId, notification, channelIds)
+	if err = db.AlarmHistoryUpdate(invoker.Db, history.ID, map[string]interface{}{"escalation_step": step}); err != nil {
+		return err
+	}
+	return errPush
+}
+
+// escalationTarget applies a step of the alarm escalation policy: the duty officers are replaced
+// by the users of the step, and the channels of the step are returned besides the alarm channels.
+// Alarms without escalation policy keep their duty officers and channels.
+func escalationTarget(alarm *db.Alarm, step int, t time.Time) ([]int, error) {
+	if alarm.EscalationId == 0 {
+		return alarm.ChannelIds, nil
+	}
+	policy := db.AlarmEscalation{}
+	policy.ID = alarm.EscalationId
+	if err := policy.Info(invoker.Db); err != nil {
+		return nil, err
+	}
+	if step >= len(policy.Steps) {
+		return nil, errors.Errorf("escalation step %d out of range", step)
+	}
+	dutyOfficers, err := policy.Steps[step].DutyOfficers(invoker.Db, t)
+	if err != nil {
+		return nil, err
+	}
+	alarm.DutyOfficers = dutyOfficers
+	channelIds := make([]int, 0, len(alarm.ChannelIds)+len(policy.Steps[step].ChannelIds))
+	exists := make(map[int]struct{})
+	for _, id := range append(append([]int{}, alarm.ChannelIds...), policy.Steps[step].ChannelIds...) {
+		if _, ok := exists[id]; ok {
+			continue
+		}
+		exists[id] = struct{}{}
+		channelIds = append(channelIds, id)
+	}
+	return channelIds, nil
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/evaluator"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/clickhouse"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const evaluatorTickInterval = time.Second * 10
+
+// alertEvaluator evaluates the alarms of instances whose rule store type is
+// db.RuleStoreTypeNative, without prometheus and alertmanager.
+type alertEvaluator struct {
+	mu sync.Mutex
+	// filter id -> last evaluation time
+	lastEval map[int]time.Time
+	// filter id -> first firing time
+	firingAt map[int]time.Time
+	stopChan chan struct{}
+}
+
+func NewAlertEvaluator() *alertEvaluator {
+	return &alertEvaluator{
+		lastEval: make(map[int]time.Time),
+		firingAt: make(map[int]time.Time),
+	}
+}
+
+func (e *alertEvaluator) tickerEvaluate() {
+	e.mu.Lock()
+	e.stopChan = make(chan struct{})
+	stopChan := e.stopChan
+	e.mu.Unlock()
+	ticker := time.NewTicker(evaluatorTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("alertEvaluator", "evaluate", e.evaluate(now))
+		}
+	}
+}
+
+func (e *alertEvaluator) stop() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stopChan != nil {
+		close(e.stopChan)
+		e.stopChan = nil
+	}
+}
+
+func (e *alertEvaluator) evaluate(now time.Time) error {
+	instances, err := db.InstanceList(egorm.Conds{"rule_store_type": db.RuleStoreTypeNative})
+	if err != nil {
+		return err
+	}
+	if len(instances) == 0 {
+		return nil
+	}
+	natives := make(map[int]struct{}, len(instances))
+	for _, ins := range instances {
+		natives[ins.ID] = struct{}{}
+	}
+	alarms, err := db.AlarmList(egorm.Conds{"status": egorm.Cond{Op: "!=", Val: db.AlarmStatusClose}})
+	if err != nil {
+		return err
+	}
+	for _, alarm := range alarms {
+		filters, errFilters := db.AlarmFilterList(invoker.Db, egorm.Conds{"alarm_id": alarm.ID})
+		if errFilters != nil {
+			err = errors.Wrapf(errFilters, "alarm id: %d", alarm.ID)
+			continue
+		}
+		for _, filter := range filters {
+			if !e.isDue(filter.ID, alarm.GetInterval(), now) {
+				continue
+			}
+			table, errTable := db.TableInfo(invoker.Db, filter.Tid)
+			if errTable != nil {
+				elog.Error("alertEvaluator", l.S("step", "TableInfo"), l.I("filterId", filter.ID), l.E(errTable))
+				continue
+			}
+			if _, ok := natives[table.Database.Iid]; !ok {
+				e.evaluated(filter.ID, now)
+				continue
+			}
+			if errFilter := e.evaluateFilter(alarm, filter, &table, now); errFilter != nil {
+				elog.Error("alertEvaluator", l.S("step", "evaluateFilter"), l.I("alarmId", alarm.ID), l.I("filterId", filter.ID), l.E(errFilter))
+				continue
+			}
+			e.evaluated(filter.ID, now)
+		}
+	}
+	return err
+}
+
+// isDue reports whether the filter has to be evaluated, each filter runs once per alarm interval
+// and a failed evaluation is retried at the next tick.
+func (e *alertEvaluator) isDue(filterId int, interval time.Duration, now time.Time) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	last, ok := e.lastEval[filterId]
+	return !ok || now.Sub(last) >= interval
+}
+
+// evaluated records the time of the last successful evaluation of the filter.
+func (e *alertEvaluator) evaluated(filterId int, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lastEval[filterId] = now
+}
+
+func (e *alertEvaluator) evaluateFilter(alarm *db.Alarm, filter *db.AlarmFilter, table *db.BaseTable, now time.Time) error {
+	op, err := InstanceManager.Load(table.Database.Iid)
+	if err != nil {
+		return err
+	}
+	samples, err := e.samples(op, alarm, filter, table, now)
+	if err != nil {
+		return err
+	}
+	conditions, err := db.AlarmConditionList(egorm.Conds{"alarm_id": alarm.ID, "filter_id": filter.ID})
+	if err != nil {
+		return err
+	}
+	st, et := evaluator.Window(alarm, now)
+	series := func(st, et int64) ([]evaluator.Sample, error) {
+		return e.series(op, filter.When, table, st, et)
+	}
+	status, err := evaluator.EvaluateAnomaly(conditions, samples, filter.Mode, alarm.NoDataOp, e.anomaly(op, filter, table, st, et, series))
+	if err != nil {
+		return err
+	}
+	if status == db.AlarmStatusUnknown {
+		return nil
+	}
+	// Only state transitions are dispatched, repeats are left to the alarm history.
+	if status == db.AlarmStatusFiring && filter.Status == db.AlarmStatusFiring {
+		return nil
+	}
+	if status == db.AlarmStatusNormal && filter.Status != db.AlarmStatusFiring {
+		return nil
+	}
+	return Alert.HandlerAlertManager(alarm.Uuid, strconv.Itoa(filter.ID), e.notification(alarm, filter, status, now))
+}
+
+// samples returns the per second values of the metric stream built by GetAlertViewSQL.
+func (e *alertEvaluator) samples(op factory.Operator, alarm *db.Alarm, filter *db.AlarmFilter, table *db.BaseTable, now time.Time) ([]float64, error) {
+	res := make([]float64, 0)
+	if filter.Mode == db.AlarmModeAggregation || filter.Mode == db.AlarmModeAggregationCheck {
+		complete, err := op.DoSQL(fmt.Sprintf("SELECT val FROM (%s) LIMIT 1", filter.When))
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range complete.Logs {
+			val, errVal := evaluator.ToFloat64(row["val"])
+			if errVal != nil {
+				continue
+			}
+			res = append(res, val)
+		}
+		return res, nil
+	}
+	st, et := evaluator.Window(alarm, now)
+	series, err := e.series(op, filter.When, table, st, et)
+	if err != nil {
+		return nil, err
+	}
+	return evaluator.Values(series), nil
+}
+
+// series returns the per second samples of the metric stream in [st, et).
+func (e *alertEvaluator) series(op factory.Operator, when string, table *db.BaseTable, st, et int64) ([]evaluator.Sample, error) {
+	param, err := e.prepare(op, when, table, st, et)
+	if err != nil {
+		return nil, err
+	}
+	param.GroupByCond, param.Interval = op.CalculateInterval(1, clickhouse.TransferGroupTimeField(param.TimeField, table.TimeFieldType))
+	charts, q, err := op.Chart(param)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sql: %s", q)
+	}
+	res := make([]evaluator.Sample, 0, len(charts))
+	for _, chart := range charts {
+		res = append(res, evaluator.Sample{Ts: chart.From, Val: float64(chart.Count)})
+	}
+	return res, nil
+}
+
+func (e *alertEvaluator) prepare(op factory.Operator, when string, table *db.BaseTable, st, et int64) (view.ReqQuery, error) {
+	return op.Prepare(view.ReqQuery{
+		Tid:           table.ID,
+		Database:      table.Database.Name,
+		Table:         table.Name,
+		Query:         when,
+		QueryMode:     view.QueryModeSQL,
+		TimeField:     table.GetTimeField(),
+		TimeFieldType: table.TimeFieldType,
+		ST:            st,
+		ET:            et,
+	}, table, false)
+}
+
+// anomaly matches the anomaly conditions of a filter for the window [st, et), the baselines
+// are read by series from the same stream as the samples so they agree with the prometheus rules.
+func (e *alertEvaluator) anomaly(op factory.Operator, filter *db.AlarmFilter, table *db.BaseTable, st, et int64,
+	series func(st, et int64) ([]evaluator.Sample, error)) evaluator.AnomalyMatcher {
+	return func(cond *db.AlarmCondition, cur float64) (bool, error) {
+		baseline := int64(evaluator.Baseline(cond).Seconds())
+		switch cond.Cond {
+		case evaluator.CondPercentChange:
+			samples, err := series(st-baseline, et-baseline)
+			if err != nil {
+				return false, err
+			}
+			base, ok := evaluator.Aggregate(cond.SetOperatorExp, evaluator.Values(samples))
+			return ok && evaluator.MatchPercentChange(cond, cur, base), nil
+		case evaluator.CondZScore:
+			samples, err := series(st-baseline, st)
+			if err != nil {
+				return false, err
+			}
+			windows := evaluator.Windows(cond.SetOperatorExp, samples, st-baseline, st, et-st)
+			return evaluator.MatchZScore(cond, cur, windows), nil
+		case evaluator.CondNewSignature:
+			return e.newSignature(op, filter, table, cond, st, et, baseline)
+		}
+		return false, nil
+	}
+}
+
+// newSignature reports whether a value of the signature field that occurs at
+// least Val1 times in [st, et) was not seen during the baseline before st.
+func (e *alertEvaluator) newSignature(op factory.Operator, filter *db.AlarmFilter, table *db.BaseTable, cond *db.AlarmCondition, st, et, baseline int64) (bool, error) {
+	reader, ok := factory.Unwrap(op).(factory.NewGroupReader)
+	if !ok {
+		return false, errors.New("new signature conditions are not supported by the datasource")
+	}
+	param, err := e.prepare(op, filter.When, table, st, et)
+	if err != nil {
+		return false, err
+	}
+	param.Field = cond.Field
+	return reader.HasNewGroup(param, st-baseline, uint64(cond.Val1))
+}
+
+func (e *alertEvaluator) notification(alarm *db.Alarm, filter *db.AlarmFilter, status int, now time.Time) db.Notification {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	labels := map[string]string{
+		"alertname": alarm.UniqueName(filter.ID),
+		"uuid":      alarm.Uuid,
+		"alarmId":   strconv.Itoa(alarm.ID),
+		"filterId":  strconv.Itoa(filter.ID),
+	}
+	for k, v := range alarm.Tags {
+		if _, ok := labels[k]; !ok {
+			labels[k] = v
+		}
+	}
+	item := db.Alert{Labels: labels, StartsAt: now}
+	notification := db.Notification{
+		Version:      "4",
+		Receiver:     "clickvisual",
+		Status:       "firing",
+		CommonLabels: labels,
+	}
+	if status == db.AlarmStatusFiring {
+		e.firingAt[filter.ID] = now
+	} else {
+		notification.Status = "resolved"
+		if startsAt, ok := e.firingAt[filter.ID]; ok {
+			item.StartsAt = startsAt
+		}
+		item.EndsAt = now
+		delete(e.firingAt, filter.ID)
+	}
+	notification.Alerts = []db.Alert{item}
+	return notification
+}
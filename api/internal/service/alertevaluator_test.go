@@ -0,0 +1,25 @@
+package service
+
+import (
+	"testing"
+	"time"
+)
+
+func Test_alertEvaluator_isDue(t *testing.T) {
+	e := NewAlertEvaluator()
+	now := time.Unix(1700000000, 0)
+	if !e.isDue(1, time.Minute, now) {
+		t.Fatal("isDue() = false, want true for a filter never evaluated")
+	}
+	// a failed evaluation is not recorded and is retried at the next tick
+	if !e.isDue(1, time.Minute, now.Add(evaluatorTickInterval)) {
+		t.Error("isDue() = false, want true after a failed evaluation")
+	}
+	e.evaluated(1, now)
+	if e.isDue(1, time.Minute, now.Add(evaluatorTickInterval)) {
+		t.Error("isDue() = true, want false within the interval")
+	}
+	if !e.isDue(1, time.Minute, now.Add(time.Minute)) {
+		t.Error("isDue() = false, want true after the interval")
+	}
+}
@@ -0,0 +1,247 @@
+package service
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
+)
+
+const groupTickInterval = 5 * time.Second
+
+// alertGrouper sends the digests of the alert groups when their group wait or group interval is over.
+type alertGrouper struct {
+	mu       sync.Mutex
+	stopChan chan struct{}
+}
+
+func NewAlertGrouper() *alertGrouper {
+	return &alertGrouper{}
+}
+
+func (g *alertGrouper) tickerFlush() {
+	g.mu.Lock()
+	g.stopChan = make(chan struct{})
+	stopChan := g.stopChan
+	g.mu.Unlock()
+	ticker := time.NewTicker(groupTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("alertGrouper", "flush", g.flush(now))
+		}
+	}
+}
+
+func (g *alertGrouper) stop() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopChan != nil {
+		close(g.stopChan)
+		g.stopChan = nil
+	}
+}
+
+func (g *alertGrouper) flush(now time.Time) error {
+	ids, err := db.AlarmGroupDueIds(invoker.Db, now)
+	if err != nil {
+		return err
+	}
+	for _, id := range ids {
+		if errFlush := g.flushGroup(id, now); errFlush != nil {
+			elog.Error("alertGrouper", l.I("groupId", id), l.E(errFlush))
+		}
+	}
+	return nil
+}
+
+// flushGroup sends the digest of the group if it changed or the repeat interval is over,
+// the resolved alerts are dropped once they are sent and the empty groups are deleted.
+func (g *alertGrouper) flushGroup(id int, now time.Time) error {
+	tx := invoker.Db.Begin()
+	group, err := db.AlarmGroupLock(tx, map[string]interface{}{"id": id})
+	if err != nil || group == nil || group.NextFlush > now.Unix() {
+		tx.Rollback()
+		return err
+	}
+	if !group.Due(now) {
+		group.NextFlush = now.Unix() + group.GroupInterval
+		if err = group.Save(tx); err != nil {
+			tx.Rollback()
+			return err
+		}
+		return tx.Commit().Error
+	}
+	members := group.Flush(now)
+	if len(group.Members) == 0 {
+		err = group.Delete(tx)
+	} else {
+		err = group.Save(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return err
+	}
+	return sendDigest(group, members, now)
+}
+
+// sendDigest delivers the digest of the members to the channels of their alarms,
+// and settles the histories of the alerts which were not notified yet, the retries of the deliveries settle them again.
+func sendDigest(group *db.AlarmGroup, members db.GroupMembers, now time.Time) error {
+	contexts := make([]*pusher.TemplateContext, 0, len(members))
+	histories := make([]int, 0, len(members))
+	channels := make(map[int]struct{})
+	channelIds := make([]int, 0)
+	for _, member := range members {
+		alarm, err := db.AlarmInfo(invoker.Db, member.AlarmId)
+		if err != nil {
+			// the alarm is deleted
+			elog.Warn("alertGrouper", l.I("alarmId", member.AlarmId), l.E(err))
+			continue
+		}
+		ids, err := escalationTarget(&alarm, 0, now)
+		if err != nil {
+			return err
+		}
+		ctx, err := Alert.templateContext(&alarm, member.FilterId, groupNotification(&alarm, member))
+		if err != nil {
+			elog.Warn("alertGrouper", l.I("alarmId", member.AlarmId), l.I("filterId", member.FilterId), l.E(err))
+			continue
+		}
+		contexts = append(contexts, ctx)
+		if !member.Notified {
+			histories = append(histories, member.HistoryId)
+		}
+		for _, channelId := range ids {
+			if _, ok := channels[channelId]; !ok {
+				channels[channelId] = struct{}{}
+				channelIds = append(channelIds, channelId)
+			}
+		}
+	}
+	if len(contexts) == 0 {
+		return nil
+	}
+	_, err := pusher.ExecuteDigest(histories, channelIds, pusher.NewDigestContext(group.GroupKey, group.Labels, contexts))
+	for _, historyId := range histories {
+		if err != nil {
+			_ = db.AlarmHistoryUpdate(invoker.Db, historyId, map[string]interface{}{"is_pushed": db.PushedStatusFail})
+			continue
+		}
+		if errSettle := pusher.SettleHistory(historyId); errSettle != nil {
+			elog.Warn("alertGrouper", l.I("historyId", historyId), l.E(errSettle))
+		}
+	}
+	return err
+}
+
+func groupNotification(alarm *db.Alarm, member db.GroupMember) db.Notification {
+	labels := map[string]string{
+		"alertname": alarm.UniqueName(member.FilterId),
+		"uuid":      alarm.Uuid,
+		"alarmId":   strconv.Itoa(alarm.ID),
+		"filterId":  strconv.Itoa(member.FilterId),
+	}
+	status := "firing"
+	if member.Status == db.AlarmStatusNormal {
+		status = "resolved"
+	}
+	return db.Notification{
+		Version:      "4",
+		Receiver:     "clickvisual",
+		Status:       status,
+		CommonLabels: labels,
+		Alerts:       []db.Alert{{Labels: labels, StartsAt: time.Unix(member.StartsAt, 0)}},
+	}
+}
+
+// groupAlert adds the alert of the filter to the group of the alarm, the history waits for the digest.
+// An alert which is already in the group with the same status is a repeat.
+func groupAlert(alarm *db.Alarm, filterId int, history *db.AlarmHistory, status int, now time.Time) error {
+	labels, err := groupLabels(alarm, filterId)
+	if err != nil {
+		return err
+	}
+	key := db.AlarmGroupKey(labels)
+	tx := invoker.Db.Begin()
+	group, err := db.AlarmGroupLock(tx, map[string]interface{}{"group_key": key})
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	created := group == nil
+	if created {
+		group = db.NewAlarmGroup(alarm, labels, now)
+	}
+	changed := group.Add(db.GroupMember{
+		AlarmId:   alarm.ID,
+		FilterId:  filterId,
+		HistoryId: history.ID,
+		Status:    status,
+		StartsAt:  now.Unix(),
+	})
+	if created {
+		err = group.Create(tx)
+	} else if changed {
+		err = group.Save(tx)
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Commit().Error; err != nil {
+		return err
+	}
+	pushed := db.PushedStatusGrouped
+	if !changed {
+		pushed = db.PushedStatusRepeat
+	}
+	return db.AlarmHistoryUpdate(invoker.Db, history.ID, map[string]interface{}{"is_pushed": pushed})
+}
+
+// groupLabels returns the values of the group by labels of the alarm for the filter.
+func groupLabels(alarm *db.Alarm, filterId int) (map[string]string, error) {
+	labels := make(map[string]string, len(alarm.GroupBy))
+	var table *db.BaseTable
+	for _, g := range alarm.GroupBy {
+		switch {
+		case g == db.GroupByAlarm:
+			labels[g] = strconv.Itoa(alarm.ID)
+		case g == db.GroupByTable, g == db.GroupByInstance:
+			if table == nil {
+				filter, err := Alert.compatibleFilter(alarm.ID, filterId)
+				if err != nil {
+					return nil, err
+				}
+				info, err := db.TableInfo(invoker.Db, filter.Tid)
+				if err != nil {
+					return nil, errors.Wrapf(err, "table id: %d", filter.Tid)
+				}
+				table = &info
+			}
+			if g == db.GroupByTable {
+				labels[g] = strconv.Itoa(table.ID)
+			} else {
+				labels[g] = strconv.Itoa(table.Database.Iid)
+			}
+		case strings.HasPrefix(g, db.GroupByTagPrefix):
+			labels[g] = alarm.Tags[strings.TrimPrefix(g, db.GroupByTagPrefix)]
+		}
+	}
+	return labels, nil
+}
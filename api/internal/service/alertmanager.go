@@ -76,15 +76,62 @@ func (i *alert) HandlerAlertManager(alarmUUID string, filterIdStr string, notifi
 	}
 	// 完成告警状态更新
 	tx.Commit()
+	// the state is kept up to date, only the notification is muted by silences
+	silence, err := db.AlarmSilenceMatch(invoker.Db, &alarm, time.Now())
+	if err != nil {
+		return fmt.Errorf("AlarmSilenceMatch %s, error: %w", alarmUUID, err)
+	}
+	if silence != nil {
+		log.Info("PushAlertManagerSilenced", l.I("filterId", filterId), l.I("silenceId", silence.ID))
+		if err = db.AlarmHistoryUpdate(invoker.Db, alarmHistory.ID, map[string]interface{}{"is_pushed": db.PushedStatusSilenced, "silence_id": silence.ID}); err != nil {
+			return fmt.Errorf("AlarmHistoryUpdate %s, error: %w", alarmUUID, err)
+		}
+		return nil
+	}
+	if len(alarm.GroupBy) > 0 {
+		if err = groupAlert(&alarm, filterId, &alarmHistory, notificationStatus, time.Now()); err != nil {
+			return fmt.Errorf("groupAlert %s, error: %w", alarmUUID, err)
+		}
+		return nil
+	}
+	channelIds, err := escalationTarget(&alarm, 0, time.Now())
+	if err != nil {
+		return fmt.Errorf("escalationTarget %s, error: %w", alarmUUID, err)
+	}
+	if err = i.push(&alarm, alarmHistory.ID, filterId, notification, channelIds); err != nil {
+		_ = db.AlarmHistoryUpdate(invoker.Db, alarmHistory.ID, map[string]interface{}{"is_pushed": db.PushedStatusFail})
+		return fmt.Errorf("push %s, error: %w", alarmUUID, err)
+	}
+	if err = pusher.SettleHistory(alarmHistory.ID); err != nil {
+		return fmt.Errorf("SettleHistory %s, error: %w", alarmUUID, err)
+	}
+	return nil
+}
+
+// push builds the alarm messages of the filter and delivers them to the channels,
+// the failed deliveries are retried by the delivery worker.
+func (i *alert) push(alarm *db.Alarm, historyId int, filterId int, notification db.Notification, channelIds []int) error {
+	ctx, err := i.templateContext(alarm, filterId, notification)
+	if err != nil {
+		return err
+	}
+	if _, err = pusher.ExecuteTemplate(historyId, channelIds, ctx); err != nil {
+		return fmt.Errorf("execute, error: %w", err)
+	}
+	return nil
+}
+
+// templateContext collects the data of the filter notification for the message templates
+func (i *alert) templateContext(alarm *db.Alarm, filterId int, notification db.Notification) (*pusher.TemplateContext, error) {
 	// get alarm filter info
 	filter, err := i.compatibleFilter(alarm.ID, filterId)
 	if err != nil {
-		return fmt.Errorf("compatibleFilter %s, error: %w", alarmUUID, err)
+		return nil, fmt.Errorf("compatibleFilter, error: %w", err)
 	}
 	// get table info
 	tableInfo, err := db.TableInfo(invoker.Db, filter.Tid)
 	if err != nil {
-		return fmt.Errorf("TableInfo %s, error: %w", alarmUUID, err)
+		return nil, fmt.Errorf("TableInfo, error: %w", err)
 	}
 	if tableInfo.TimeField == "" {
 		tableInfo.TimeField = db.TimeFieldSecond
@@ -92,30 +139,11 @@ func (i *alert) HandlerAlertManager(alarmUUID string, filterIdStr string, notifi
 	// get op
 	op, err := InstanceManager.Load(tableInfo.Database.Iid)
 	if err != nil {
-		return fmt.Errorf("InstanceManager.Load %s, error: %w", alarmUUID, err)
+		return nil, fmt.Errorf("InstanceManager.Load, error: %w", err)
 	}
 	// get partial log
-	partialLog := i.getPartialLog(op, &tableInfo, &alarm, filter)
-
-	pushMsg, err := pusher.BuildAlarmMsg(notification, &tableInfo, &alarm, filter, partialLog)
-	if err != nil {
-		return fmt.Errorf("BuildAlarmMsg %s, error: %w", alarmUUID, err)
-	}
-
-	pushMsgWithAt, err := pusher.BuildAlarmMsgWithAt(notification, &tableInfo, &alarm, filter, partialLog)
-	if err != nil {
-		return fmt.Errorf("BuildAlarmMsgWithAt %s, error: %w", alarmUUID, err)
-	}
-
-	if err = pusher.Execute(alarm.ChannelIds, pushMsg, pushMsgWithAt); err != nil {
-		_ = db.AlarmHistoryUpdate(invoker.Db, alarmHistory.ID, map[string]interface{}{"is_pushed": db.PushedStatusFail})
-		return fmt.Errorf("execute %s, error: %w", alarmUUID, err)
-	}
-
-	if err = db.AlarmHistoryUpdate(invoker.Db, alarmHistory.ID, map[string]interface{}{"is_pushed": db.PushedStatusSuccess}); err != nil {
-		return fmt.Errorf("AlarmHistoryUpdate %s, error: %w", alarmUUID, err)
-	}
-	return nil
+	partialLog := i.getPartialLog(op, &tableInfo, alarm, filter)
+	return pusher.NewTemplateContext(notification, &tableInfo, alarm, filter, partialLog), nil
 }
 
 func (i *alert) compatibleFilter(alarmId int, filterId int) (res *db.AlarmFilter, err error) {
@@ -146,6 +174,7 @@ func (i *alert) getPartialLog(op factory.Operator, table *db.BaseTable, alarm *d
 		Database:      table.Database.Name,
 		Table:         table.Name,
 		Query:         filter.When,
+		QueryMode:     view.QueryModeSQL,
 		AlarmMode:     filter.Mode,
 		TimeField:     table.TimeField,
 		TimeFieldType: table.TimeFieldType,
@@ -0,0 +1,55 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+const apiTokenPrefix = "cvk_"
+
+var ErrApiToken = errors.New("invalid api token")
+
+// ApiTokenUser returns the user of the token, the tools act with the permissions of the user.
+func ApiTokenUser(token string) (u db.User, err error) {
+	if !strings.HasPrefix(token, apiTokenPrefix) {
+		return u, ErrApiToken
+	}
+	m, err := db.ApiTokenInfoX(invoker.Db, hashIngestToken(token))
+	if err != nil {
+		return u, ErrApiToken
+	}
+	if u, err = db.UserInfo(m.Uid); err != nil {
+		return u, err
+	}
+	if u.ID == 0 {
+		return u, ErrApiToken
+	}
+	return u, nil
+}
+
+// CreateApiToken creates a token of the user, the token is only returned here.
+func CreateApiToken(uid int, name string) (res view.RespApiToken, err error) {
+	b := make([]byte, 24)
+	if _, err = rand.Read(b); err != nil {
+		return res, err
+	}
+	token := apiTokenPrefix + hex.EncodeToString(b)
+	m := db.ApiToken{Uid: uid, Name: name, Token: hashIngestToken(token)}
+	if err = db.ApiTokenCreate(invoker.Db, &m); err != nil {
+		return res, err
+	}
+	res = ApiTokenView(&m)
+	res.Token = token
+	return res, nil
+}
+
+func ApiTokenView(m *db.ApiToken) view.RespApiToken {
+	return view.RespApiToken{ID: m.ID, Name: m.Name, Ctime: m.Ctime}
+}
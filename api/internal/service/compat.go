@@ -0,0 +1,289 @@
+package service
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/clickhouse"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+// The Loki and Elasticsearch compatible APIs read the tables with the query language, the tables are named
+// database.table or by the table name when it is unique among the tables of the user.
+
+const (
+	compatMaxLogs  = 5000
+	compatMaxTerms = 100
+)
+
+var ErrCompatTable = errors.New("table not found")
+
+// IsCompatBadRequest tells whether the error is of the query of a compatible API rather than of the datasource.
+func IsCompatBadRequest(err error) bool {
+	for _, target := range []error{
+		ErrLogQL, ErrESQuery, constx.ErrQueryIntervalLimit,
+		lql.ErrSyntax, lql.ErrUnknownField, lql.ErrFieldType, lql.ErrFullText, factory.ErrCursor,
+	} {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// CompatTableName is the name of the table in the Loki labels and the Elasticsearch indexes.
+func CompatTableName(tableInfo *db.BaseTable) string {
+	if tableInfo.Database == nil {
+		return tableInfo.Name
+	}
+	return tableInfo.Database.Name + "." + tableInfo.Name
+}
+
+// CompatTables returns the tables the user can view.
+func CompatTables(uid int) ([]*db.BaseTable, error) {
+	tables, err := db.TableList(invoker.Db, egorm.Conds{})
+	if err != nil {
+		return nil, err
+	}
+	res := make([]*db.BaseTable, 0, len(tables))
+	for _, t := range tables {
+		if t.Database == nil || !TableViewIsPermission(uid, t.Database.Iid, t.ID) {
+			continue
+		}
+		res = append(res, t)
+	}
+	return res, nil
+}
+
+// CompatTable resolves the table of the name for the user, the tables the user can not view are not found.
+func CompatTable(uid int, name string) (db.BaseTable, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return db.BaseTable{}, errors.Wrap(ErrCompatTable, "the table is required")
+	}
+	tables, err := CompatTables(uid)
+	if err != nil {
+		return db.BaseTable{}, err
+	}
+	return compatTable(tables, name)
+}
+
+func compatTable(tables []*db.BaseTable, name string) (db.BaseTable, error) {
+	matches := make([]*db.BaseTable, 0, 1)
+	for _, t := range tables {
+		if CompatTableName(t) == name {
+			return *t, nil
+		}
+		if t.Name == name {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return db.BaseTable{}, errors.Wrapf(ErrCompatTable, "%s", name)
+	case 1:
+		return *matches[0], nil
+	}
+	return db.BaseTable{}, errors.Wrapf(ErrCompatTable, "%s is ambiguous, name it as database.table", name)
+}
+
+// compatLog is a log of the compatible APIs, Ts is the time in nanoseconds.
+type compatLog struct {
+	ID     string
+	Ts     int64
+	Line   string
+	Fields map[string]interface{}
+}
+
+// compatLogs reads the logs of the query language, the logs are in the descending order of the time unless asc.
+func compatLogs(tableInfo db.BaseTable, req view.ReqQuery, asc, count bool) (logs []compatLog, total uint64, err error) {
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, 0, err
+	}
+	param := logsQueryParam(tableInfo, req)
+	param.QueryMode = view.QueryModeLanguage
+	// the direction asks for the cursors, which are the ids of the logs
+	param.Direction = factory.CursorOlder
+	if asc {
+		param.Direction = factory.CursorNewer
+	}
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return nil, 0, errors.Wrap(err, "param prepare failed")
+	}
+	if count {
+		if total, err = op.Count(param); err != nil {
+			return nil, 0, err
+		}
+	}
+	if req.PageSize == 0 {
+		return nil, total, nil
+	}
+	res, err := op.GetLogs(param, tableInfo.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	rawLogField := "_raw_log_"
+	if tableInfo.CreateType == constx.TableCreateTypeExist {
+		rawLogField = tableInfo.RawLogField
+	}
+	logs = make([]compatLog, 0, len(res.Logs))
+	for i, row := range res.Logs {
+		log := compatLog{Fields: row}
+		if i < len(res.Cursors) {
+			cur, _ := factory.ParseCursor(res.Cursors[i])
+			log.ID, log.Ts = res.Cursors[i], cur.Ts
+		}
+		if line, ok := row[rawLogField].(string); ok && line != "" {
+			log.Line = line
+		} else {
+			b, _ := json.Marshal(row)
+			log.Line = string(b)
+		}
+		logs = append(logs, log)
+	}
+	if asc {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+		}
+	}
+	return logs, total, nil
+}
+
+// compatPoint is the count of the logs in [From, From+step).
+type compatPoint struct {
+	From  int64
+	Count uint64
+}
+
+// compatChart counts the logs of the query language in buckets of step seconds aligned to the epoch,
+// the buckets of the datasource are the widest ones which are not wider than step.
+func compatChart(tableInfo db.BaseTable, req view.ReqQuery, step int64) ([]compatPoint, error) {
+	if step <= 0 {
+		return nil, errors.New("the step must be positive")
+	}
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	param := logsQueryParam(tableInfo, req)
+	param.QueryMode = view.QueryModeLanguage
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return nil, errors.Wrap(err, "param prepare failed")
+	}
+	param.GroupByCond, param.Interval = op.CalculateInterval(compatGranularity(step), clickhouse.TransferGroupTimeField(param.TimeField, tableInfo.TimeFieldType))
+	charts, q, err := op.Chart(param)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sql: %s", q)
+	}
+	return compatBuckets(charts, step), nil
+}
+
+// compatGranularity is the range of which CalculateInterval returns the widest buckets which are not wider than step.
+func compatGranularity(step int64) int64 {
+	for _, g := range []struct{ bucket, rng int64 }{
+		{86400, 8 * 86400},
+		{21600, 7 * 86400},
+		{3600, 86400},
+		{600, 4 * 3600},
+		{60, 1800},
+	} {
+		if step >= g.bucket {
+			return g.rng
+		}
+	}
+	return 1
+}
+
+func compatBuckets(charts []*view.HighChart, step int64) []compatPoint {
+	counts := make(map[int64]uint64)
+	for _, chart := range charts {
+		from := chart.From - chart.From%step
+		counts[from] += chart.Count
+	}
+	res := make([]compatPoint, 0, len(counts))
+	for from, count := range counts {
+		res = append(res, compatPoint{From: from, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].From < res[j].From
+	})
+	return res
+}
+
+// compatTerms returns the most frequent values of the field, the datasources without factory.FieldStatsReader
+// return the top 10 values.
+func compatTerms(tableInfo db.BaseTable, req view.ReqQuery, field string, size int) ([]view.FieldStatsValue, error) {
+	if size <= 0 || size > compatMaxTerms {
+		size = compatMaxTerms
+	}
+	req.QueryMode = view.QueryModeLanguage
+	stats, err := FieldStats(tableInfo, view.ReqFieldStats{ReqQuery: req, Fields: []string{field}, K: size})
+	if err == nil {
+		if len(stats.Fields) == 0 {
+			return nil, nil
+		}
+		return stats.Fields[0].Top, nil
+	}
+	if !errors.Is(err, ErrFieldStatsUnsupported) {
+		return nil, err
+	}
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	param := logsQueryParam(tableInfo, req)
+	param.Field = field
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return nil, errors.Wrap(err, "param prepare failed")
+	}
+	res := make([]view.FieldStatsValue, 0)
+	for value, count := range op.GroupBy(param) {
+		res = append(res, view.FieldStatsValue{Value: value, Count: count})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Count != res[j].Count {
+			return res[i].Count > res[j].Count
+		}
+		return res[i].Value < res[j].Value
+	})
+	if len(res) > size {
+		res = res[:size]
+	}
+	return res, nil
+}
+
+// compatFields returns the fields of the table which can be queried, with the types of view.RespColumn.
+func compatFields(tableInfo db.BaseTable) (map[string]int, error) {
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]int)
+	columns, err := op.ListColumn(tableInfo.Database.Name, tableInfo.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range columns {
+		res[col.Name] = col.Type
+	}
+	indexes, err := db.IndexList(egorm.Conds{"tid": tableInfo.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if _, ok := res[index.GetFieldName()]; !ok {
+			res[index.GetFieldName()] = index.Typ
+		}
+	}
+	return res, nil
+}
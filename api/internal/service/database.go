@@ -37,6 +37,7 @@ func DatabaseListFilterPms(uid int) (res []view2.RespDatabaseSimple, err error)
 	if err != nil {
 		return
 	}
+	healths := TableHealthStatus()
 	for _, row := range ts {
 		if row.Database == nil {
 			continue
@@ -56,6 +57,7 @@ func DatabaseListFilterPms(uid int) (res []view2.RespDatabaseSimple, err error)
 			Desc:            row.Desc,
 			V3TableType:     row.V3TableType,
 			RelTraceTableId: row.TraceTableId,
+			Health:          healths[row.ID],
 		}
 		item.Tables = append(item.Tables, respTableSimple)
 		dMap[row.Database.ID] = item
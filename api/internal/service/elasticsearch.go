@@ -0,0 +1,717 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+// The Elasticsearch APIs read the table of the index with a subset of the query DSL: query_string is the query
+// language, bool, term, terms, match, match_phrase, exists and range are translated to it, and a range of the time
+// field is the time range of the search. The aggregations are terms and date_histogram without sub aggregations.
+
+const (
+	ESVersion      = "7.10.2"
+	esDefaultSize  = 10
+	esMaxResults   = 10000
+	esMaxBuckets   = 65536
+	esDateFormat   = "2006-01-02T15:04:05.000Z"
+	esTypeDate     = "date"
+	esTypeKeyword  = "keyword"
+	esTypeLong     = "long"
+	esTypeDouble   = "double"
+	esFormatSecond = "epoch_second"
+)
+
+var ErrESQuery = errors.New("query")
+
+// ESSearch runs a search request on the table of the index.
+func ESSearch(uid int, index string, req view.ReqESSearch, now time.Time) (res view.RespESSearch, err error) {
+	start := time.Now()
+	tableInfo, err := CompatTable(uid, index)
+	if err != nil {
+		return res, err
+	}
+	t := newESTranslator(tableInfo, now)
+	query, err := t.query(req.Query)
+	if err != nil {
+		return res, err
+	}
+	asc, err := t.sort(req.Sort)
+	if err != nil {
+		return res, err
+	}
+	size := esDefaultSize
+	if req.Size != nil {
+		size = *req.Size
+	}
+	if size < 0 || req.From < 0 || size+req.From > esMaxResults {
+		return res, errors.Wrapf(ErrESQuery, "from + size must be in [0, %d]", esMaxResults)
+	}
+	param := view.ReqQuery{Query: query, ST: t.st, ET: t.et, PageSize: uint32(size)}
+	if size > 0 {
+		if req.From%size != 0 {
+			return res, errors.Wrap(ErrESQuery, "from must be a multiple of size")
+		}
+		param.Page = uint32(req.From/size + 1)
+	}
+	logs, total, err := compatLogs(tableInfo, param, asc, true)
+	if err != nil {
+		return res, err
+	}
+	name := CompatTableName(&tableInfo)
+	res.Shards = view.ESShards{Total: 1, Successful: 1}
+	res.Hits = view.ESHits{Total: view.ESTotal{Value: total, Relation: "eq"}, Hits: make([]view.ESHit, 0, len(logs))}
+	for _, log := range logs {
+		res.Hits.Hits = append(res.Hits.Hits, view.ESHit{Index: name, ID: log.ID, Source: log.Fields, Sort: []interface{}{log.Ts / 1e6}})
+	}
+	aggs := req.Aggs
+	if len(aggs) == 0 {
+		aggs = req.Aggregations
+	}
+	if len(aggs) > 0 {
+		res.Aggregations = make(map[string]view.ESAggregation, len(aggs))
+		for aggName, raw := range aggs {
+			if res.Aggregations[aggName], err = t.aggregation(param, raw); err != nil {
+				return res, errors.Wrapf(err, "aggregation %s", aggName)
+			}
+		}
+	}
+	res.Took = time.Since(start).Milliseconds()
+	return res, nil
+}
+
+// ESMapping returns the properties of the fields of the table, the time field is a date.
+func ESMapping(uid int, index string) (map[string]interface{}, error) {
+	tableInfo, err := CompatTable(uid, index)
+	if err != nil {
+		return nil, err
+	}
+	fields, err := compatFields(tableInfo)
+	if err != nil {
+		return nil, err
+	}
+	properties := make(map[string]interface{}, len(fields))
+	for name, typ := range fields {
+		if esType := esFieldType(typ); esType != "" {
+			properties[name] = map[string]string{"type": esType}
+		}
+	}
+	properties[tableInfo.GetTimeField()] = map[string]string{"type": esTypeDate}
+	return map[string]interface{}{
+		CompatTableName(&tableInfo): map[string]interface{}{"mappings": map[string]interface{}{"properties": properties}},
+	}, nil
+}
+
+// esFieldType maps the types of view.RespColumn, the JSON fields can not be queried and have no type.
+func esFieldType(typ int) string {
+	switch {
+	case typ == -1 || typ == -2:
+		return esTypeDate
+	case typ == 2 || typ == 15:
+		return esTypeDouble
+	case typ == 1 || (typ >= 4 && typ <= 14):
+		return esTypeLong
+	case typ == 3:
+		return ""
+	}
+	return esTypeKeyword
+}
+
+// esTranslator translates the DSL, the time ranges of the required clauses narrow [st, et) in seconds.
+type esTranslator struct {
+	tableInfo  db.BaseTable
+	timeFields map[string]bool
+	now        time.Time
+	st, et     int64
+}
+
+func newESTranslator(tableInfo db.BaseTable, now time.Time) *esTranslator {
+	return &esTranslator{
+		tableInfo: tableInfo,
+		timeFields: map[string]bool{
+			tableInfo.GetTimeField(): true,
+			db.TimeFieldSecond:       true,
+			db.TimeFieldNanoseconds:  true,
+			"@timestamp":             true,
+		},
+		now: now,
+	}
+}
+
+func (t *esTranslator) query(raw json.RawMessage) (string, error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return "", nil
+	}
+	q, err := t.clause(raw, true)
+	if err != nil {
+		return "", err
+	}
+	if t.et != 0 && t.et <= t.st {
+		return "", errors.Wrap(ErrESQuery, "the time range is empty")
+	}
+	return q, nil
+}
+
+// clause translates a query clause, an empty result matches all logs. The clauses which are not required are in
+// should or must_not, their time ranges can not be the time range of the search.
+func (t *esTranslator) clause(raw json.RawMessage, required bool) (string, error) {
+	var clause map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &clause); err != nil {
+		return "", errors.Wrap(ErrESQuery, err.Error())
+	}
+	if len(clause) != 1 {
+		return "", errors.Wrapf(ErrESQuery, "a clause has one query, got %d", len(clause))
+	}
+	for typ, body := range clause {
+		switch typ {
+		case "match_all":
+			return "", nil
+		case "query_string":
+			var qs struct {
+				Query string `json:"query"`
+			}
+			if err := json.Unmarshal(body, &qs); err != nil {
+				return "", errors.Wrap(ErrESQuery, err.Error())
+			}
+			if q := strings.TrimSpace(qs.Query); q != "*" {
+				return q, nil
+			}
+			return "", nil
+		case "bool":
+			return t.boolClause(body, required)
+		case "range":
+			return t.rangeClause(body, required)
+		case "term", "match", "match_phrase":
+			field, value, err := esFieldValue(body)
+			if err != nil {
+				return "", err
+			}
+			return (&lql.Term{Field: field, Op: lql.OpMatch, Value: value, Quoted: true}).String(), nil
+		case "terms":
+			return esTerms(body)
+		case "exists":
+			var exists struct {
+				Field string `json:"field"`
+			}
+			if err := json.Unmarshal(body, &exists); err != nil || exists.Field == "" {
+				return "", errors.Wrap(ErrESQuery, "exists requires a field")
+			}
+			return exists.Field + lql.OpMatch + "*", nil
+		default:
+			return "", errors.Wrapf(ErrESQuery, "%s queries are not supported", typ)
+		}
+	}
+	return "", nil
+}
+
+func (t *esTranslator) boolClause(body json.RawMessage, required bool) (string, error) {
+	var b struct {
+		Must               json.RawMessage `json:"must"`
+		Filter             json.RawMessage `json:"filter"`
+		Should             json.RawMessage `json:"should"`
+		MustNot            json.RawMessage `json:"must_not"`
+		MinimumShouldMatch interface{}     `json:"minimum_should_match"`
+	}
+	if err := json.Unmarshal(body, &b); err != nil {
+		return "", errors.Wrap(ErrESQuery, err.Error())
+	}
+	parts := make([]string, 0)
+	requiredClauses := 0
+	for _, list := range []json.RawMessage{b.Must, b.Filter} {
+		clauses, err := esClauses(list)
+		if err != nil {
+			return "", err
+		}
+		requiredClauses += len(clauses)
+		for _, raw := range clauses {
+			q, err := t.clause(raw, required)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, q)
+		}
+	}
+	should, err := esClauses(b.Should)
+	if err != nil {
+		return "", err
+	}
+	// should only scores the logs of the required clauses unless minimum_should_match asks for them
+	if len(should) > 0 && (requiredClauses == 0 || cast.ToInt(b.MinimumShouldMatch) > 0) {
+		alts := make([]string, 0, len(should))
+		all := false
+		for _, raw := range should {
+			q, err := t.clause(raw, false)
+			if err != nil {
+				return "", err
+			}
+			all = all || q == ""
+			alts = append(alts, "("+q+")")
+		}
+		if !all {
+			parts = append(parts, strings.Join(alts, " "+lql.OpOr+" "))
+		}
+	}
+	mustNot, err := esClauses(b.MustNot)
+	if err != nil {
+		return "", err
+	}
+	for _, raw := range mustNot {
+		q, err := t.clause(raw, false)
+		if err != nil {
+			return "", err
+		}
+		if q == "" {
+			return "", errors.Wrap(ErrESQuery, "must_not matches all logs")
+		}
+		parts = append(parts, "NOT ("+q+")")
+	}
+	if q := lql.Join(parts...); q != "*" {
+		return q, nil
+	}
+	return "", nil
+}
+
+// rangeClause narrows the time range by the range of a time field, the other fields are compared.
+func (t *esTranslator) rangeClause(body json.RawMessage, required bool) (string, error) {
+	var ranges map[string]map[string]json.RawMessage
+	if err := json.Unmarshal(body, &ranges); err != nil {
+		return "", errors.Wrap(ErrESQuery, err.Error())
+	}
+	parts := make([]string, 0)
+	for field, bounds := range ranges {
+		var format string
+		if raw, ok := bounds["format"]; ok {
+			_ = json.Unmarshal(raw, &format)
+		}
+		if t.timeFields[field] {
+			if !required {
+				return "", errors.Wrapf(ErrESQuery, "the range of %s is only supported in must and filter", field)
+			}
+			if err := t.timeRange(bounds, format); err != nil {
+				return "", err
+			}
+			continue
+		}
+		for _, op := range []string{"gt", "gte", "lt", "lte"} {
+			raw, ok := bounds[op]
+			if !ok || string(raw) == "null" {
+				continue
+			}
+			value, quoted, err := esValue(raw)
+			if err != nil {
+				return "", err
+			}
+			cmp := map[string]string{"gt": lql.OpGt, "gte": lql.OpGte, "lt": lql.OpLt, "lte": lql.OpLte}[op]
+			parts = append(parts, (&lql.Term{Field: field, Op: cmp, Value: value, Quoted: quoted}).String())
+		}
+	}
+	if q := lql.Join(parts...); q != "*" {
+		return q, nil
+	}
+	return "", nil
+}
+
+func (t *esTranslator) timeRange(bounds map[string]json.RawMessage, format string) error {
+	for _, op := range []string{"gt", "gte", "lt", "lte"} {
+		raw, ok := bounds[op]
+		if !ok || string(raw) == "null" {
+			continue
+		}
+		ts, err := esTime(raw, format, t.now)
+		if err != nil {
+			return err
+		}
+		if op == "gt" || op == "gte" {
+			if st := ts.Unix(); st > t.st {
+				t.st = st
+			}
+			continue
+		}
+		et := ts.Unix()
+		if ts.Nanosecond() > 0 || op == "lte" {
+			et++
+		}
+		if t.et == 0 || et < t.et {
+			t.et = et
+		}
+	}
+	return nil
+}
+
+// sort tells whether the logs are in the ascending order of the time, only the time fields can be sorted.
+func (t *esTranslator) sort(raw json.RawMessage) (asc bool, err error) {
+	if len(bytes.TrimSpace(raw)) == 0 || string(bytes.TrimSpace(raw)) == "null" {
+		return false, nil
+	}
+	var list []json.RawMessage
+	if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+		if err = json.Unmarshal(raw, &list); err != nil {
+			return false, errors.Wrap(ErrESQuery, err.Error())
+		}
+	} else {
+		list = []json.RawMessage{raw}
+	}
+	for _, item := range list {
+		var (
+			field string
+			order = "asc"
+		)
+		var fields map[string]json.RawMessage
+		if err = json.Unmarshal(item, &field); err != nil {
+			if err = json.Unmarshal(item, &fields); err != nil || len(fields) != 1 {
+				return false, errors.Wrap(ErrESQuery, "invalid sort")
+			}
+			for name, value := range fields {
+				field = name
+				var opts struct {
+					Order string `json:"order"`
+				}
+				if json.Unmarshal(value, &order) != nil {
+					if err = json.Unmarshal(value, &opts); err != nil {
+						return false, errors.Wrap(ErrESQuery, "invalid sort")
+					}
+					order = opts.Order
+				}
+			}
+		}
+		switch {
+		case field == "_doc" || field == "_score":
+		case t.timeFields[field]:
+			asc = strings.ToLower(order) != "desc"
+		default:
+			return false, errors.Wrapf(ErrESQuery, "only the time field can be sorted, got %s", field)
+		}
+	}
+	return asc, nil
+}
+
+// aggregation computes a terms or a date_histogram aggregation of the logs of the search.
+func (t *esTranslator) aggregation(param view.ReqQuery, raw json.RawMessage) (res view.ESAggregation, err error) {
+	var agg map[string]json.RawMessage
+	if err = json.Unmarshal(raw, &agg); err != nil {
+		return res, errors.Wrap(ErrESQuery, err.Error())
+	}
+	var typ string
+	var body json.RawMessage
+	for k, v := range agg {
+		switch k {
+		case "meta":
+		case "aggs", "aggregations":
+			var sub map[string]json.RawMessage
+			if err = json.Unmarshal(v, &sub); err != nil || len(sub) > 0 {
+				return res, errors.Wrap(ErrESQuery, "sub aggregations are not supported")
+			}
+		default:
+			if typ != "" {
+				return res, errors.Wrap(ErrESQuery, "an aggregation has one type")
+			}
+			typ, body = k, v
+		}
+	}
+	switch typ {
+	case "date_histogram":
+		return t.dateHistogram(param, body)
+	case "terms":
+		return t.terms(param, body)
+	}
+	return res, errors.Wrapf(ErrESQuery, "%s aggregations are not supported", typ)
+}
+
+func (t *esTranslator) dateHistogram(param view.ReqQuery, body json.RawMessage) (res view.ESAggregation, err error) {
+	var h struct {
+		Field            string  `json:"field"`
+		FixedInterval    string  `json:"fixed_interval"`
+		CalendarInterval string  `json:"calendar_interval"`
+		Interval         string  `json:"interval"`
+		MinDocCount      *uint64 `json:"min_doc_count"`
+		ExtendedBounds   *struct {
+			Min json.RawMessage `json:"min"`
+			Max json.RawMessage `json:"max"`
+		} `json:"extended_bounds"`
+		Format string `json:"format"`
+	}
+	if err = json.Unmarshal(body, &h); err != nil {
+		return res, errors.Wrap(ErrESQuery, err.Error())
+	}
+	if !t.timeFields[h.Field] {
+		return res, errors.Wrapf(ErrESQuery, "date_histogram is only supported on the time field, got %s", h.Field)
+	}
+	interval := h.FixedInterval
+	if interval == "" {
+		interval = h.CalendarInterval
+	}
+	if interval == "" {
+		interval = h.Interval
+	}
+	step, err := esInterval(interval)
+	if err != nil {
+		return res, err
+	}
+	points, err := compatChart(t.tableInfo, param, step)
+	if err != nil {
+		return res, err
+	}
+	minDocCount := uint64(0)
+	if h.MinDocCount != nil {
+		minDocCount = *h.MinDocCount
+	}
+	st, et := param.ST, param.ET
+	if h.ExtendedBounds != nil {
+		if ts, err := esTime(h.ExtendedBounds.Min, h.Format, t.now); err == nil && (st == 0 || ts.Unix() < st) {
+			st = ts.Unix()
+		}
+		if ts, err := esTime(h.ExtendedBounds.Max, h.Format, t.now); err == nil && ts.Unix() >= et {
+			et = ts.Unix() + 1
+		}
+	}
+	res.Buckets = esDateBuckets(points, st, et, step, minDocCount)
+	if len(res.Buckets) > esMaxBuckets {
+		return res, errors.Wrapf(ErrESQuery, "more than %d buckets, increase the interval", esMaxBuckets)
+	}
+	return res, nil
+}
+
+// esDateBuckets fills the buckets without logs in [st, et) when minDocCount is 0 as Elasticsearch does.
+func esDateBuckets(points []compatPoint, st, et, step int64, minDocCount uint64) []view.ESBucket {
+	counts := make(map[int64]uint64, len(points))
+	froms := make([]int64, 0, len(points))
+	for _, p := range points {
+		counts[p.From] = p.Count
+		froms = append(froms, p.From)
+	}
+	if minDocCount == 0 && et > st && (et-st)/step <= esMaxBuckets {
+		for from := st - st%step; from < et; from += step {
+			if _, ok := counts[from]; !ok {
+				counts[from] = 0
+				froms = append(froms, from)
+			}
+		}
+	}
+	sort.Slice(froms, func(i, j int) bool { return froms[i] < froms[j] })
+	res := make([]view.ESBucket, 0, len(froms))
+	for _, from := range froms {
+		if counts[from] < minDocCount {
+			continue
+		}
+		res = append(res, view.ESBucket{
+			Key:         from * 1000,
+			KeyAsString: time.Unix(from, 0).UTC().Format(esDateFormat),
+			DocCount:    counts[from],
+		})
+	}
+	return res
+}
+
+func (t *esTranslator) terms(param view.ReqQuery, body json.RawMessage) (res view.ESAggregation, err error) {
+	var terms struct {
+		Field string            `json:"field"`
+		Size  *int              `json:"size"`
+		Order map[string]string `json:"order"`
+	}
+	if err = json.Unmarshal(body, &terms); err != nil {
+		return res, errors.Wrap(ErrESQuery, err.Error())
+	}
+	if terms.Field == "" {
+		return res, errors.Wrap(ErrESQuery, "terms requires a field")
+	}
+	size := esDefaultSize
+	if terms.Size != nil {
+		size = *terms.Size
+	}
+	values, err := compatTerms(t.tableInfo, param, terms.Field, size)
+	if err != nil {
+		return res, err
+	}
+	res.Buckets = make([]view.ESBucket, 0, len(values))
+	for _, v := range values {
+		res.Buckets = append(res.Buckets, view.ESBucket{Key: v.Value, DocCount: v.Count})
+	}
+	for key, order := range terms.Order {
+		desc := strings.ToLower(order) == "desc"
+		switch key {
+		case "_key", "_term":
+			sort.SliceStable(res.Buckets, func(i, j int) bool {
+				if desc {
+					return res.Buckets[i].Key.(string) > res.Buckets[j].Key.(string)
+				}
+				return res.Buckets[i].Key.(string) < res.Buckets[j].Key.(string)
+			})
+		case "_count":
+			sort.SliceStable(res.Buckets, func(i, j int) bool {
+				if desc {
+					return res.Buckets[i].DocCount > res.Buckets[j].DocCount
+				}
+				return res.Buckets[i].DocCount < res.Buckets[j].DocCount
+			})
+		default:
+			return res, errors.Wrapf(ErrESQuery, "terms can be ordered by _key or _count, got %s", key)
+		}
+	}
+	return res, nil
+}
+
+// esClauses reads a clause or an array of clauses.
+func esClauses(raw json.RawMessage) ([]json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil, nil
+	}
+	if raw[0] != '[' {
+		return []json.RawMessage{raw}, nil
+	}
+	var res []json.RawMessage
+	if err := json.Unmarshal(raw, &res); err != nil {
+		return nil, errors.Wrap(ErrESQuery, err.Error())
+	}
+	return res, nil
+}
+
+// esFieldValue reads {"field": value} or {"field": {"value"|"query": value}} of term and match.
+func esFieldValue(body json.RawMessage) (field, value string, err error) {
+	var fields map[string]json.RawMessage
+	if err = json.Unmarshal(body, &fields); err != nil {
+		return "", "", errors.Wrap(ErrESQuery, err.Error())
+	}
+	if len(fields) != 1 {
+		return "", "", errors.Wrap(ErrESQuery, "term and match have one field")
+	}
+	for name, raw := range fields {
+		field = name
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("{")) {
+			var opts struct {
+				Value json.RawMessage `json:"value"`
+				Query json.RawMessage `json:"query"`
+			}
+			if err = json.Unmarshal(raw, &opts); err != nil {
+				return "", "", errors.Wrap(ErrESQuery, err.Error())
+			}
+			if raw = opts.Value; raw == nil {
+				raw = opts.Query
+			}
+		}
+		if value, _, err = esValue(raw); err != nil {
+			return "", "", err
+		}
+	}
+	return field, value, nil
+}
+
+func esTerms(body json.RawMessage) (string, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return "", errors.Wrap(ErrESQuery, err.Error())
+	}
+	delete(fields, "boost")
+	if len(fields) != 1 {
+		return "", errors.Wrap(ErrESQuery, "terms has one field")
+	}
+	for field, raw := range fields {
+		var values []json.RawMessage
+		if err := json.Unmarshal(raw, &values); err != nil || len(values) == 0 {
+			return "", errors.Wrapf(ErrESQuery, "terms of %s requires values", field)
+		}
+		alts := make([]string, 0, len(values))
+		for _, v := range values {
+			value, _, err := esValue(v)
+			if err != nil {
+				return "", err
+			}
+			alts = append(alts, (&lql.Term{Field: field, Op: lql.OpMatch, Value: value, Quoted: true}).String())
+		}
+		return strings.Join(alts, " "+lql.OpOr+" "), nil
+	}
+	return "", nil
+}
+
+// esValue reads a string, a number or a boolean, the strings are quoted in the query language.
+func esValue(raw json.RawMessage) (value string, quoted bool, err error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(raw))
+	d.UseNumber()
+	if err = d.Decode(&v); err != nil {
+		return "", false, errors.Wrap(ErrESQuery, err.Error())
+	}
+	switch v := v.(type) {
+	case string:
+		return v, true, nil
+	case json.Number:
+		return v.String(), false, nil
+	case bool:
+		return strconv.FormatBool(v), false, nil
+	}
+	return "", false, errors.Wrapf(ErrESQuery, "unsupported value %s", raw)
+}
+
+// esTime parses a date of the DSL: milliseconds or the seconds of epoch_second, RFC3339, a date, or now with an offset.
+func esTime(raw json.RawMessage, format string, now time.Time) (time.Time, error) {
+	value, quoted, err := esValue(raw)
+	if err != nil {
+		return time.Time{}, err
+	}
+	// a quoted year is a date as in strict_date_optional_time||epoch_millis, the default format
+	if n, err := strconv.ParseFloat(value, 64); err == nil && (!quoted || len(value) > 4 || strings.Contains(format, "epoch")) {
+		if format == esFormatSecond {
+			sec, frac := math.Modf(n)
+			return time.Unix(int64(sec), int64(frac*1e9)), nil
+		}
+		return time.Unix(0, int64(n*1e6)), nil
+	}
+	if strings.HasPrefix(value, "now") {
+		offset := strings.TrimPrefix(value, "now")
+		if offset == "" {
+			return now, nil
+		}
+		sign := time.Duration(1)
+		switch offset[0] {
+		case '-':
+			sign = -1
+		case '+':
+		default:
+			return time.Time{}, errors.Wrapf(ErrESQuery, "unsupported date %s", value)
+		}
+		sec, err := lokiDuration(offset[1:])
+		if err != nil {
+			return time.Time{}, errors.Wrapf(ErrESQuery, "unsupported date %s", value)
+		}
+		return now.Add(sign * time.Duration(sec) * time.Second), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02 15:04:05", "2006-01-02"} {
+		if ts, err := time.Parse(layout, value); err == nil {
+			return ts, nil
+		}
+	}
+	return time.Time{}, errors.Wrapf(ErrESQuery, "unsupported date %s", value)
+}
+
+// esInterval parses the interval of a date_histogram to seconds, the months and the years are not fixed.
+func esInterval(interval string) (int64, error) {
+	switch interval {
+	case "":
+		return 0, errors.Wrap(ErrESQuery, "date_histogram requires an interval")
+	case "minute":
+		return 60, nil
+	case "hour":
+		return 3600, nil
+	case "day":
+		return 86400, nil
+	case "week":
+		return 7 * 86400, nil
+	}
+	step, err := lokiDuration(interval)
+	if err != nil {
+		return 0, errors.Wrapf(ErrESQuery, "unsupported interval %s", interval)
+	}
+	return step, nil
+}
@@ -0,0 +1,134 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+func testESTranslator() *esTranslator {
+	return newESTranslator(db.BaseTable{Name: "app", Database: &db.BaseDatabase{Name: "logs"}}, time.Unix(1700003600, 0))
+}
+
+func Test_esTranslatorQuery(t *testing.T) {
+	tests := []struct {
+		query  string
+		want   string
+		st, et int64
+	}{
+		{query: `{"match_all":{}}`},
+		{
+			// the search of Grafana
+			query: `{"bool":{"filter":[{"range":{"_time_second_":{"gte":1700000000000,"lte":1700003600000,"format":"epoch_millis"}}},{"query_string":{"analyze_wildcard":true,"query":"level:error"}}]}}`,
+			want:  "level:error",
+			st:    1700000000,
+			et:    1700003601,
+		},
+		{
+			query: `{"bool":{"must":[{"term":{"service":"api"}},{"terms":{"code":[500,502]}}],"must_not":{"match_phrase":{"msg":"timeout"}},"should":[{"match":{"a":"1"}}]}}`,
+			want:  `(service:"api") AND (code:"500" OR code:"502") AND (NOT (msg:"timeout"))`,
+		},
+		{
+			query: `{"bool":{"should":[{"term":{"a":{"value":"x"}}},{"exists":{"field":"b"}}],"filter":{"range":{"@timestamp":{"gt":"now-1h","lt":"now"}}},"minimum_should_match":1}}`,
+			want:  `(a:"x") OR (b:*)`,
+			st:    1700000000,
+			et:    1700003600,
+		},
+		{
+			query: `{"range":{"latency":{"gte":500,"lt":"1s"}}}`,
+			want:  `(latency>=500) AND (latency<"1s")`,
+		},
+	}
+	for _, tt := range tests {
+		tr := testESTranslator()
+		got, err := tr.query(json.RawMessage(tt.query))
+		if err != nil {
+			t.Errorf("query(%s) error = %v", tt.query, err)
+			continue
+		}
+		if got != tt.want || tr.st != tt.st || tr.et != tt.et {
+			t.Errorf("query(%s) = %s [%d, %d), want %s [%d, %d)", tt.query, got, tr.st, tr.et, tt.want, tt.st, tt.et)
+		}
+		if _, err = lql.Parse(got); err != nil {
+			t.Errorf("query(%s) = %s error = %v", tt.query, got, err)
+		}
+	}
+	for _, query := range []string{
+		`{"wildcard":{"a":"x*"}}`,
+		`{"bool":{"should":[{"range":{"_time_second_":{"gte":1}}}]}}`,
+		`{"bool":{"must_not":{"match_all":{}}}}`,
+		`{"term":{"a":"x"},"match":{"b":"y"}}`,
+		`{"range":{"_time_second_":{"gte":1700000000000,"lt":1700000000000}}}`,
+	} {
+		if _, err := testESTranslator().query(json.RawMessage(query)); !errors.Is(err, ErrESQuery) {
+			t.Errorf("query(%s) error = %v, want %v", query, err, ErrESQuery)
+		}
+	}
+}
+
+func Test_esTranslatorSort(t *testing.T) {
+	for raw, want := range map[string]bool{
+		``: false,
+		`[{"_time_second_":{"order":"desc","unmapped_type":"boolean"}},{"_doc":{"order":"desc"}}]`: false,
+		`[{"_time_nanosecond_":"asc"}]`: true,
+		`"_time_second_"`:               true,
+	} {
+		if got, err := testESTranslator().sort(json.RawMessage(raw)); err != nil || got != want {
+			t.Errorf("sort(%s) = %v, %v, want %v", raw, got, err, want)
+		}
+	}
+	if _, err := testESTranslator().sort(json.RawMessage(`[{"level":"asc"}]`)); !errors.Is(err, ErrESQuery) {
+		t.Errorf("sort() of a field error = %v, want %v", err, ErrESQuery)
+	}
+}
+
+func Test_esTime(t *testing.T) {
+	now := time.Unix(1700003600, 0)
+	for raw, want := range map[string]int64{
+		`1700000000500`:          1700000000,
+		`"1700000000000"`:        1700000000,
+		`"2023-11-14T22:13:20Z"`: 1700000000,
+		`"now-1h"`:               1700000000,
+		`"now"`:                  1700003600,
+	} {
+		if got, err := esTime(json.RawMessage(raw), "", now); err != nil || got.Unix() != want {
+			t.Errorf("esTime(%s) = %v, %v, want %d", raw, got, err, want)
+		}
+	}
+	if got, err := esTime(json.RawMessage(`1700000000`), esFormatSecond, now); err != nil || got.Unix() != 1700000000 {
+		t.Errorf("esTime() of epoch_second = %v, %v", got, err)
+	}
+	if _, err := esTime(json.RawMessage(`"now/d"`), "", now); !errors.Is(err, ErrESQuery) {
+		t.Errorf("esTime() of a rounding error = %v, want %v", err, ErrESQuery)
+	}
+}
+
+func Test_esDateBuckets(t *testing.T) {
+	points := []compatPoint{{From: 60, Count: 2}, {From: 180, Count: 1}}
+	want := []view.ESBucket{
+		{Key: int64(0), KeyAsString: "1970-01-01T00:00:00.000Z", DocCount: 0},
+		{Key: int64(60000), KeyAsString: "1970-01-01T00:01:00.000Z", DocCount: 2},
+		{Key: int64(120000), KeyAsString: "1970-01-01T00:02:00.000Z", DocCount: 0},
+		{Key: int64(180000), KeyAsString: "1970-01-01T00:03:00.000Z", DocCount: 1},
+	}
+	if got := esDateBuckets(points, 30, 200, 60, 0); !reflect.DeepEqual(got, want) {
+		t.Errorf("esDateBuckets() = %v, want %v", got, want)
+	}
+	if got := esDateBuckets(points, 30, 200, 60, 2); len(got) != 1 || got[0].DocCount != 2 {
+		t.Errorf("esDateBuckets() with min_doc_count = %v", got)
+	}
+	for interval, want := range map[string]int64{"30s": 30, "1m": 60, "hour": 3600, "1d": 86400} {
+		if got, err := esInterval(interval); err != nil || got != want {
+			t.Errorf("esInterval(%s) = %d, %v, want %d", interval, got, err, want)
+		}
+	}
+	if _, err := esInterval("1M"); !errors.Is(err, ErrESQuery) {
+		t.Errorf("esInterval(1M) error = %v, want %v", err, ErrESQuery)
+	}
+}
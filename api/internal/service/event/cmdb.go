@@ -77,6 +77,19 @@ func (a *event) InquiryCMDB(opUser *core.User, operation string, metaData map[st
 	a.PutEvent(userEvent)
 }
 
+// TableHealth records the ingestion health transitions of the tables, they are not done by a user.
+func (a *event) TableHealth(tid int, metaData map[string]interface{}) {
+	res, _ := json.Marshal(metaData)
+	obj := db2.Event{
+		Source:     db2.SourceInquiryMgtCenter,
+		Operation:  db2.OpnTablesHealthChange,
+		ObjectType: db2.TableNameBaseTable,
+		ObjectId:   tid,
+		Metadata:   string(res),
+	}
+	a.PutEvent(obj)
+}
+
 func (a *event) SystemMigration(u *core.User, metaData string) {
 	userEvent := db2.Event{
 		Source:     db2.SourceSystemSetting,
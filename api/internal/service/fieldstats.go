@@ -0,0 +1,111 @@
+package service
+
+import (
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	fieldStatsMaxFields  = 20
+	fieldStatsK          = 10
+	fieldStatsMaxK       = 100
+	fieldStatsBuckets    = 10
+	fieldStatsMaxBuckets = 100
+)
+
+// ErrFieldStatsUnsupported is returned for the datasources which do not implement factory.FieldStatsReader.
+var ErrFieldStatsUnsupported = errors.New("field statistics are not supported by the datasource")
+
+// FieldStats computes the statistics of the fields over the logs of the query, as the query of /tables/:id/logs.
+func FieldStats(tableInfo db.BaseTable, req view.ReqFieldStats) (res view.RespFieldStats, err error) {
+	if len(req.Fields) > fieldStatsMaxFields {
+		return res, errors.Errorf("at most %d fields are computed at once", fieldStatsMaxFields)
+	}
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return res, err
+	}
+	reader, ok := factory.Unwrap(op).(factory.FieldStatsReader)
+	if !ok {
+		return res, ErrFieldStatsUnsupported
+	}
+	param := logsQueryParam(tableInfo, req.ReqQuery)
+	if param.ET <= param.ST {
+		param.ST, param.ET = time.Now().Add(-15*time.Minute).Unix(), time.Now().Unix()
+	}
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return res, errors.Wrap(err, "param prepare failed")
+	}
+	fields, err := fieldStatsFields(op, tableInfo, req.Fields)
+	if err != nil {
+		return res, err
+	}
+	return reader.FieldStats(param, view.FieldStatsParams{
+		Fields:   fields,
+		K:        fieldStatsLimit(req.K, fieldStatsK, fieldStatsMaxK),
+		Buckets:  fieldStatsLimit(req.Buckets, fieldStatsBuckets, fieldStatsMaxBuckets),
+		Interval: factory.FieldStatsInterval(param.ST, param.ET),
+	})
+}
+
+// fieldStatsFields resolves the fields from the columns of the table and the analysis fields,
+// the other names are refused for the fields are put into the SQL.
+func fieldStatsFields(op factory.Operator, tableInfo db.BaseTable, names []string) ([]view.FieldStatsField, error) {
+	numeric := make(map[string]bool)
+	columns, err := op.ListColumn(tableInfo.Database.Name, tableInfo.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	for _, col := range columns {
+		numeric[col.Name] = isNumericColumn(col.Type)
+	}
+	indexes, err := db.IndexList(egorm.Conds{"tid": tableInfo.ID})
+	if err != nil {
+		return nil, err
+	}
+	for _, index := range indexes {
+		if _, ok := numeric[index.GetFieldName()]; !ok {
+			// 1 int 2 float
+			numeric[index.GetFieldName()] = index.Typ == 1 || index.Typ == 2
+		}
+	}
+	res := make([]view.FieldStatsField, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		isNumeric, ok := numeric[name]
+		if !ok {
+			return nil, errors.Errorf("field %s not found", name)
+		}
+		seen[name] = true
+		res = append(res, view.FieldStatsField{Name: name, Numeric: isNumeric})
+	}
+	if len(res) == 0 {
+		return nil, errors.New("fields are required")
+	}
+	return res, nil
+}
+
+// isNumericColumn tells whether the type of view.RespColumn is an integer or a float.
+func isNumericColumn(typ int) bool {
+	// 1 Int64 2 Float64, 4 to 15 the other integers and Float32
+	return typ == 1 || typ == 2 || (typ >= 4 && typ <= 15)
+}
+
+func fieldStatsLimit(v, def, max int) int {
+	if v <= 0 {
+		return def
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
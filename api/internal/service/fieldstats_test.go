@@ -0,0 +1,28 @@
+package service
+
+import "testing"
+
+func TestFieldStatsLimit(t *testing.T) {
+	tests := []struct {
+		v, want int
+	}{
+		{v: 0, want: 10},
+		{v: -1, want: 10},
+		{v: 25, want: 25},
+		{v: 1000, want: 100},
+	}
+	for _, tt := range tests {
+		if got := fieldStatsLimit(tt.v, fieldStatsK, fieldStatsMaxK); got != tt.want {
+			t.Errorf("fieldStatsLimit(%d) = %d, want %d", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestIsNumericColumn(t *testing.T) {
+	// -2 DateTime64, 0 String, 1 Int64, 3 JSON, 15 Float32
+	for typ, want := range map[int]bool{-2: false, 0: false, 1: true, 3: false, 15: true} {
+		if got := isNumericColumn(typ); got != want {
+			t.Errorf("isNumericColumn(%d) = %v, want %v", typ, got, want)
+		}
+	}
+}
@@ -0,0 +1,741 @@
+package service
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	ingestSchemaTTL   = time.Minute // the columns of a table are read again after it, new analysis fields take effect
+	ingestTokenPrefix = "cvi_"
+)
+
+var (
+	ErrIngestDisabled    = errors.New("ingestion is disabled")
+	ErrIngestQueueFull   = errors.New("ingestion queue is full")
+	ErrIngestTooLarge    = errors.New("request exceeds the ingestion limits")
+	ErrIngestToken       = errors.New("invalid ingest token")
+	ErrIngestUnsupported = errors.New("ingestion is not supported by the table, only the tables created with JSONEachRow streams are")
+)
+
+// IngestLineError is a line of the request which is not a log, no log of the request is queued.
+type IngestLineError struct {
+	Line int
+	Err  error
+}
+
+func (e *IngestLineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+}
+
+// ingest metrics are exposed on the /metrics of the governor server, labeled by the database.table.
+var (
+	ingestRowsCounter = emetric.CounterVecOpts{
+		Namespace: "clickvisual",
+		Name:      "ingest_rows_total",
+		Help:      "logs of the ingestion by table and result (accepted, written, failed, rejected or duplicate)",
+		Labels:    []string{"table", "result"},
+	}.Build()
+	ingestBytesCounter = emetric.CounterVecOpts{
+		Namespace: "clickvisual",
+		Name:      "ingest_bytes_total",
+		Help:      "uncompressed bytes of the accepted requests of the ingestion by table",
+		Labels:    []string{"table"},
+	}.Build()
+	ingestErrorsCounter = emetric.CounterVecOpts{
+		Namespace: "clickvisual",
+		Name:      "ingest_errors_total",
+		Help:      "failures of the ingestion by table and reason (invalid, queue_full, too_large or insert)",
+		Labels:    []string{"table", "reason"},
+	}.Build()
+	ingestQueueGauge = emetric.GaugeVecOpts{
+		Namespace: "clickvisual",
+		Name:      "ingest_queue_rows",
+		Help:      "logs of the ingestion waiting to be written",
+		Labels:    []string{},
+	}.Build()
+)
+
+type ingestConfig struct {
+	enable        bool
+	maxBodyBytes  int64
+	queueSize     int
+	batchSize     int
+	flushInterval time.Duration
+	dedupWindow   time.Duration
+	maxRetries    int
+}
+
+// ingest buffers the logs of the tables in memory and writes them in batches, a batch is written when it reaches
+// batchSize or after flushInterval. The buffered logs of all the tables are limited by queueSize.
+type ingest struct {
+	conf   ingestConfig
+	mu     sync.Mutex
+	queued int
+	tables map[int]*ingestTable
+	stop   chan struct{}
+	wg     sync.WaitGroup
+
+	load  func(tableInfo db.BaseTable) (*ingestSchema, error)
+	write func(batch view.IngestBatch, iid int) error
+}
+
+type ingestTable struct {
+	tid     int
+	name    string // database.table, the label of the metrics
+	mu      sync.Mutex
+	schema  *ingestSchema
+	pending []*ingestPending
+	size    int
+	recent  map[string]time.Time // keys of the requests in the dedup window
+	flush   chan struct{}
+}
+
+// ingestPending is a batch of the rows built with the same columns.
+type ingestPending struct {
+	schema *ingestSchema
+	rows   [][]interface{}
+	token  string
+	keys   []string // of the requests of the rows, forgotten when the batch is dropped so that the retries are accepted
+}
+
+// NewIngest reads the ingest config: enable false, maxBodyBytes 10MB, queueSize 100000 logs, batchSize 10000 logs,
+// flushInterval 1s, dedupWindow 10m and maxRetries 3 by default.
+func NewIngest() *ingest {
+	i := &ingest{
+		conf: ingestConfig{
+			enable:        econf.GetBool("ingest.enable"),
+			maxBodyBytes:  econf.GetInt64("ingest.maxBodyBytes"),
+			queueSize:     econf.GetInt("ingest.queueSize"),
+			batchSize:     econf.GetInt("ingest.batchSize"),
+			flushInterval: econf.GetDuration("ingest.flushInterval"),
+			dedupWindow:   10 * time.Minute,
+			maxRetries:    3,
+		},
+		tables: make(map[int]*ingestTable),
+		stop:   make(chan struct{}),
+	}
+	if i.conf.maxBodyBytes <= 0 {
+		i.conf.maxBodyBytes = 10 << 20
+	}
+	if i.conf.queueSize <= 0 {
+		i.conf.queueSize = 100000
+	}
+	if i.conf.batchSize <= 0 {
+		i.conf.batchSize = 10000
+	}
+	if i.conf.flushInterval <= 0 {
+		i.conf.flushInterval = time.Second
+	}
+	if econf.Get("ingest.dedupWindow") != nil {
+		i.conf.dedupWindow = econf.GetDuration("ingest.dedupWindow")
+	}
+	if econf.Get("ingest.maxRetries") != nil {
+		i.conf.maxRetries = econf.GetInt("ingest.maxRetries")
+	}
+	i.load = loadIngestSchema
+	i.write = writeIngestBatch
+	return i
+}
+
+// MaxBodyBytes limits the uncompressed body of a request.
+func (i *ingest) MaxBodyBytes() int64 {
+	return i.conf.maxBodyBytes
+}
+
+// Push queues the logs of the NDJSON body, a line is a message of the stream of the table. The requests of the same key
+// are accepted once in the dedup window, the key is the sha256 of the body when empty.
+func (i *ingest) Push(tableInfo db.BaseTable, key string, body []byte) (res view.RespIngest, err error) {
+	if !i.conf.enable {
+		return res, ErrIngestDisabled
+	}
+	if tableInfo.CreateType != constx.TableCreateTypeCV && tableInfo.CreateType != constx.TableCreateTypeJSONEachRow {
+		return res, ErrIngestUnsupported
+	}
+	t := i.table(tableInfo)
+	if key == "" {
+		sum := sha256.Sum256(body)
+		key = hex.EncodeToString(sum[:])
+	}
+	schema, err := i.schema(t, tableInfo)
+	if err != nil {
+		return res, err
+	}
+	rows, err := schema.rows(body, time.Now())
+	if err != nil {
+		ingestErrorsCounter.Inc(t.name, "invalid")
+		return res, err
+	}
+	return i.enqueue(t, schema, key, rows, len(body))
+}
+
+// enqueue appends the rows built with the schema to the pending batches of the table.
+func (i *ingest) enqueue(t *ingestTable, schema *ingestSchema, key string, rows [][]interface{}, size int) (res view.RespIngest, err error) {
+	if len(rows) > i.conf.queueSize {
+		ingestErrorsCounter.Inc(t.name, "too_large")
+		return res, ErrIngestTooLarge
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if at, ok := t.recent[key]; ok && now.Sub(at) < i.conf.dedupWindow {
+		ingestRowsCounter.Add(float64(len(rows)), t.name, "duplicate")
+		return view.RespIngest{Duplicate: true}, nil
+	}
+	if len(rows) == 0 {
+		return res, nil
+	}
+	if !i.reserve(len(rows)) {
+		ingestRowsCounter.Add(float64(len(rows)), t.name, "rejected")
+		ingestErrorsCounter.Inc(t.name, "queue_full")
+		return res, ErrIngestQueueFull
+	}
+	if n := len(t.pending); n == 0 || t.pending[n-1].schema != schema {
+		t.pending = append(t.pending, &ingestPending{schema: schema, token: newIngestBatchToken(t.tid)})
+	}
+	p := t.pending[len(t.pending)-1]
+	p.rows = append(p.rows, rows...)
+	t.size += len(rows)
+	if i.conf.dedupWindow > 0 {
+		t.recent[key] = now
+		p.keys = append(p.keys, key)
+	}
+	if t.size >= i.conf.batchSize {
+		select {
+		case t.flush <- struct{}{}:
+		default:
+		}
+	}
+	ingestRowsCounter.Add(float64(len(rows)), t.name, "accepted")
+	ingestBytesCounter.Add(float64(size), t.name)
+	return view.RespIngest{Rows: len(rows)}, nil
+}
+
+// Close writes the buffered logs.
+func (i *ingest) Close() {
+	close(i.stop)
+	i.wg.Wait()
+}
+
+func (i *ingest) table(tableInfo db.BaseTable) *ingestTable {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if t, ok := i.tables[tableInfo.ID]; ok {
+		return t
+	}
+	t := &ingestTable{
+		tid:    tableInfo.ID,
+		name:   tableInfo.Name,
+		recent: make(map[string]time.Time),
+		flush:  make(chan struct{}, 1),
+	}
+	if tableInfo.Database != nil {
+		t.name = tableInfo.Database.Name + "." + tableInfo.Name
+	}
+	i.tables[tableInfo.ID] = t
+	i.wg.Add(1)
+	go i.loop(t)
+	return t
+}
+
+func (i *ingest) schema(t *ingestTable, tableInfo db.BaseTable) (*ingestSchema, error) {
+	t.mu.Lock()
+	schema := t.schema
+	t.mu.Unlock()
+	if schema != nil && time.Since(schema.loadedAt) < ingestSchemaTTL {
+		return schema, nil
+	}
+	schema, err := i.load(tableInfo)
+	if err != nil {
+		return nil, err
+	}
+	t.mu.Lock()
+	t.schema = schema
+	t.mu.Unlock()
+	return schema, nil
+}
+
+// reserve takes the room of the logs in the queue.
+func (i *ingest) reserve(n int) bool {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if i.queued+n > i.conf.queueSize {
+		return false
+	}
+	i.queued += n
+	ingestQueueGauge.Set(float64(i.queued))
+	return true
+}
+
+func (i *ingest) release(n int) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.queued -= n
+	ingestQueueGauge.Set(float64(i.queued))
+}
+
+func (i *ingest) loop(t *ingestTable) {
+	defer i.wg.Done()
+	ticker := time.NewTicker(i.conf.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-t.flush:
+		case <-i.stop:
+			i.flush(t)
+			return
+		}
+		i.flush(t)
+	}
+}
+
+// flush writes the pending batches of the table, a batch is retried with the same token and dropped after maxRetries,
+// the keys of a dropped batch leave the dedup window.
+func (i *ingest) flush(t *ingestTable) {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending, t.size = nil, 0
+	now := time.Now()
+	for key, at := range t.recent {
+		if now.Sub(at) >= i.conf.dedupWindow {
+			delete(t.recent, key)
+		}
+	}
+	t.mu.Unlock()
+	for _, p := range pending {
+		batch := view.IngestBatch{
+			Database: p.schema.database,
+			Table:    p.schema.table,
+			Columns:  p.schema.columns,
+			Rows:     p.rows,
+			Token:    p.token,
+		}
+		var err error
+		for attempt := 0; ; attempt++ {
+			if err = i.write(batch, p.schema.iid); err == nil || attempt >= i.conf.maxRetries {
+				break
+			}
+			time.Sleep(time.Duration(attempt+1) * 500 * time.Millisecond)
+		}
+		i.release(len(p.rows))
+		if err != nil {
+			t.mu.Lock()
+			for _, key := range p.keys {
+				delete(t.recent, key)
+			}
+			t.mu.Unlock()
+			elog.Error("ingest", elog.String("step", "flush"), elog.String("table", t.name), elog.Int("rows", len(p.rows)), elog.FieldErr(err))
+			ingestRowsCounter.Add(float64(len(p.rows)), t.name, "failed")
+			ingestErrorsCounter.Inc(t.name, "insert")
+			continue
+		}
+		ingestRowsCounter.Add(float64(len(p.rows)), t.name, "written")
+	}
+}
+
+func newIngestBatchToken(tid int) string {
+	return fmt.Sprintf("clickvisual-ingest-%d-%d", tid, time.Now().UnixNano())
+}
+
+func writeIngestBatch(batch view.IngestBatch, iid int) error {
+	op, err := InstanceManager.Load(iid)
+	if err != nil {
+		return err
+	}
+	inserter, ok := factory.Unwrap(op).(factory.Inserter)
+	if !ok {
+		return errors.New("ingestion is not supported by the datasource")
+	}
+	return inserter.Insert(batch)
+}
+
+// ingestSchema lays out the rows of a table as its materialized views: the time columns from the time field or the time
+// of the view of the log, _raw_log_ from the raw log field and the analysis fields from the raw log.
+// The other columns are read from the keys of the same names.
+type ingestSchema struct {
+	iid         int
+	database    string
+	table       string
+	columns     []view.IngestColumn
+	fields      []ingestField // of the columns
+	timeField   string
+	timeString  bool
+	rawLogField string
+	views       []*db.BaseView
+	loadedAt    time.Time
+}
+
+const (
+	ingestFieldKey = iota
+	ingestFieldTimeSecond
+	ingestFieldTimeNanosecond
+	ingestFieldRawLog
+	ingestFieldIndex
+	ingestFieldHash
+)
+
+type ingestField struct {
+	kind  int
+	typ   int // view.RespColumn type of the key columns
+	index *db.BaseIndex
+}
+
+func loadIngestSchema(tableInfo db.BaseTable) (*ingestSchema, error) {
+	if tableInfo.Database == nil {
+		return nil, errors.New("database of the table not found")
+	}
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := factory.Unwrap(op).(factory.Inserter); !ok {
+		return nil, errors.New("ingestion is not supported by the datasource")
+	}
+	if isOTLPTable(tableInfo) {
+		return newOTLPSchema(tableInfo), nil
+	}
+	columns, err := op.ListColumn(tableInfo.Database.Name, tableInfo.Name, false)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := db.IndexList(egorm.Conds{"tid": tableInfo.ID, "kind": db.IndexKindLog})
+	if err != nil {
+		return nil, err
+	}
+	views, err := db.ViewList(invoker.Db, egorm.Conds{"tid": tableInfo.ID})
+	if err != nil {
+		return nil, err
+	}
+	return newIngestSchema(tableInfo, columns, indexes, views)
+}
+
+func newIngestSchema(tableInfo db.BaseTable, columns []*view.RespColumn, indexes []*db.BaseIndex, views []*db.BaseView) (*ingestSchema, error) {
+	s := &ingestSchema{
+		table:       tableInfo.Name,
+		timeField:   tableInfo.TimeField,
+		timeString:  tableInfo.TimeFieldKind == factory.TableTypeString,
+		rawLogField: tableInfo.RawLogField,
+		views:       views,
+		loadedAt:    time.Now(),
+	}
+	if tableInfo.Database != nil {
+		s.iid, s.database = tableInfo.Database.Iid, tableInfo.Database.Name
+	}
+	if s.timeField == "" {
+		s.timeField = "_time_"
+	}
+	if s.rawLogField == "" {
+		s.rawLogField = "_log_"
+	}
+	if strings.Contains(s.timeField, "(") || strings.Contains(s.rawLogField, "(") {
+		return nil, ErrIngestUnsupported
+	}
+	fields := make(map[string]ingestField, len(indexes)*2)
+	for _, index := range indexes {
+		fields[index.GetFieldName()] = ingestField{kind: ingestFieldIndex, index: index}
+		if name, ok := index.GetHashFieldName(); ok {
+			fields[name] = ingestField{kind: ingestFieldHash, index: index}
+		}
+	}
+	for _, col := range columns {
+		f, ok := fields[col.Name]
+		switch {
+		case ok:
+		case col.Name == "_time_second_":
+			f.kind = ingestFieldTimeSecond
+		case col.Name == "_time_nanosecond_":
+			f.kind = ingestFieldTimeNanosecond
+		case col.Name == "_raw_log_":
+			f.kind = ingestFieldRawLog
+		case isIngestKeyColumn(col):
+			f = ingestField{kind: ingestFieldKey, typ: col.Type}
+		default:
+			continue
+		}
+		c := view.IngestColumn{Name: col.Name, Type: col.TypeDesc}
+		if f.kind == ingestFieldHash {
+			c.Hash = f.index.HashTyp
+		}
+		s.columns = append(s.columns, c)
+		s.fields = append(s.fields, f)
+	}
+	if len(s.columns) == 0 {
+		return nil, errors.Errorf("columns of the table %s not found", tableInfo.Name)
+	}
+	return s, nil
+}
+
+// isIngestKeyColumn tells whether the column is written from the key of the same name: strings, numbers and times.
+func isIngestKeyColumn(col *view.RespColumn) bool {
+	for _, prefix := range []string{"Array(", "Map(", "Tuple(", "Nested("} {
+		if strings.HasPrefix(col.TypeDesc, prefix) {
+			return false
+		}
+	}
+	return col.Type == 0 || col.Type == -1 || col.Type == -2 || isNumericColumn(col.Type)
+}
+
+// rows builds the rows of the NDJSON body, the empty lines are skipped.
+func (s *ingestSchema) rows(body []byte, now time.Time) ([][]interface{}, error) {
+	rows := make([][]interface{}, 0)
+	for n, line := range bytes.Split(body, []byte("\n")) {
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+		row, err := s.row(line, now)
+		if err != nil {
+			return nil, &IngestLineError{Line: n + 1, Err: err}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *ingestSchema) row(line []byte, now time.Time) ([]interface{}, error) {
+	keys := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(line, &keys); err != nil {
+		return nil, errors.New("invalid JSON object")
+	}
+	rawLog := string(line)
+	if v, ok := keys[s.rawLogField]; ok {
+		rawLog = jsonText(v)
+	}
+	logKeys := make(map[string]json.RawMessage)
+	_ = json.Unmarshal([]byte(rawLog), &logKeys)
+	t, err := s.time(keys, logKeys, now)
+	if err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(s.fields))
+	for idx, f := range s.fields {
+		switch f.kind {
+		case ingestFieldTimeSecond:
+			row[idx] = t.Truncate(time.Second)
+		case ingestFieldTimeNanosecond:
+			row[idx] = t
+		case ingestFieldRawLog:
+			row[idx] = rawLog
+		case ingestFieldHash:
+			row[idx] = indexRawValue(logKeys, f.index)
+		case ingestFieldIndex:
+			row[idx] = indexValue(logKeys, f.index)
+		default:
+			row[idx] = keyValue(keys[s.columns[idx].Name], f.typ)
+		}
+	}
+	return row, nil
+}
+
+// time of the log, from the key of the view of which the raw log has the key or the time field, now when missing.
+func (s *ingestSchema) time(keys, logKeys map[string]json.RawMessage, now time.Time) (time.Time, error) {
+	for _, v := range s.views {
+		raw, ok := logKeys[v.Key]
+		if !ok {
+			continue
+		}
+		if v.Format == "fromUnixTimestamp64Micro" && v.IsUseDefaultTime == 0 {
+			sec, err := strconv.ParseFloat(string(raw), 64)
+			if err != nil {
+				return now, errors.Errorf("invalid time %s", raw)
+			}
+			return unixSeconds(sec), nil
+		}
+		break
+	}
+	raw, ok := keys[s.timeField]
+	if !ok {
+		return now, nil
+	}
+	if s.timeString {
+		return parseIngestTime(jsonText(raw))
+	}
+	sec, err := strconv.ParseFloat(strings.Trim(string(raw), `"`), 64)
+	if err != nil {
+		return now, errors.Errorf("invalid time %s", raw)
+	}
+	return unixSeconds(sec), nil
+}
+
+var ingestTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02",
+}
+
+// parseIngestTime parses the time as parseDateTimeBestEffort for the common layouts and unix seconds,
+// the times without a zone are local.
+func parseIngestTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range ingestTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	if sec, err := strconv.ParseFloat(s, 64); err == nil {
+		return unixSeconds(sec), nil
+	}
+	return time.Time{}, errors.Errorf("invalid time %q", s)
+}
+
+func unixSeconds(sec float64) time.Time {
+	return time.Unix(0, int64(sec*1e9))
+}
+
+// jsonText is the string of a JSON string, or the compact JSON of the other values.
+func jsonText(raw json.RawMessage) string {
+	var s string
+	if json.Unmarshal(raw, &s) == nil {
+		return s
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) == nil {
+		return buf.String()
+	}
+	return string(raw)
+}
+
+// indexRawValue is the value of the analysis field of the raw log without the quotes, as JSONExtractRaw of the views.
+func indexRawValue(logKeys map[string]json.RawMessage, index *db.BaseIndex) string {
+	raw, ok := indexRaw(logKeys, index)
+	if !ok {
+		return ""
+	}
+	return strings.ReplaceAll(string(raw), `"`, "")
+}
+
+func indexRaw(logKeys map[string]json.RawMessage, index *db.BaseIndex) (json.RawMessage, bool) {
+	if index.RootName != "" {
+		root := make(map[string]json.RawMessage)
+		if json.Unmarshal(logKeys[index.RootName], &root) != nil {
+			return nil, false
+		}
+		logKeys = root
+	}
+	raw, ok := logKeys[index.Field]
+	if !ok {
+		return nil, false
+	}
+	var buf bytes.Buffer
+	if json.Compact(&buf, raw) != nil {
+		return raw, true
+	}
+	return buf.Bytes(), true
+}
+
+// indexValue is the value of the analysis field in its type, nil for the numbers which do not parse.
+func indexValue(logKeys map[string]json.RawMessage, index *db.BaseIndex) interface{} {
+	switch index.Typ {
+	case db.IndexTypeRaw:
+		raw, _ := indexRaw(logKeys, index)
+		return string(raw)
+	case 1:
+		if v, err := strconv.ParseInt(indexRawValue(logKeys, index), 10, 64); err == nil {
+			return v
+		}
+		return nil
+	case 2:
+		if v, err := strconv.ParseFloat(indexRawValue(logKeys, index), 64); err == nil {
+			return v
+		}
+		return nil
+	}
+	return indexRawValue(logKeys, index)
+}
+
+// keyValue is the value of the key in the type of the column, nil when missing.
+func keyValue(raw json.RawMessage, typ int) interface{} {
+	if raw == nil || string(raw) == "null" {
+		return nil
+	}
+	text := jsonText(raw)
+	switch {
+	case typ == 0:
+		return text
+	case typ == -1 || typ == -2:
+		t, err := parseIngestTime(text)
+		if err != nil {
+			return nil
+		}
+		return t
+	case typ == 2 || typ == 15:
+		if v, err := strconv.ParseFloat(text, 64); err == nil {
+			return v
+		}
+		return nil
+	}
+	if v, err := strconv.ParseInt(text, 10, 64); err == nil {
+		return v
+	}
+	if v, err := strconv.ParseFloat(text, 64); err == nil {
+		return int64(v)
+	}
+	return nil
+}
+
+// IngestTable returns the table of the token.
+func IngestTable(token string) (tableInfo db.BaseTable, err error) {
+	if !strings.HasPrefix(token, ingestTokenPrefix) {
+		return tableInfo, ErrIngestToken
+	}
+	m, err := db.IngestTokenInfoX(invoker.Db, hashIngestToken(token))
+	if err != nil {
+		return tableInfo, ErrIngestToken
+	}
+	tableInfo, err = db.TableInfo(invoker.Db, m.Tid)
+	if err != nil {
+		return tableInfo, err
+	}
+	if tableInfo.ID == 0 {
+		return tableInfo, ErrIngestToken
+	}
+	return tableInfo, nil
+}
+
+// CreateIngestToken creates a token of the table, the token is only returned here.
+func CreateIngestToken(tid, uid int, name string) (res view.RespIngestToken, err error) {
+	b := make([]byte, 24)
+	if _, err = rand.Read(b); err != nil {
+		return res, err
+	}
+	token := ingestTokenPrefix + hex.EncodeToString(b)
+	m := db.IngestToken{Tid: tid, Uid: uid, Name: name, Token: hashIngestToken(token)}
+	if err = db.IngestTokenCreate(invoker.Db, &m); err != nil {
+		return res, err
+	}
+	res = IngestTokenView(&m)
+	res.Token = token
+	return res, nil
+}
+
+func IngestTokenView(m *db.IngestToken) view.RespIngestToken {
+	return view.RespIngestToken{ID: m.ID, Tid: m.Tid, Uid: m.Uid, Name: m.Name, Ctime: m.Ctime}
+}
+
+func hashIngestToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+func testIngestSchema(t *testing.T) *ingestSchema {
+	tableInfo := db.BaseTable{
+		BaseModel:     db.BaseModel{ID: 1},
+		Name:          "app",
+		TimeFieldKind: factory.TableTypeFloat,
+		Database:      &db.BaseDatabase{Iid: 2, Name: "logs"},
+	}
+	columns := []*view.RespColumn{
+		{Name: "_namespace_", TypeDesc: "String", Type: 0},
+		{Name: "_time_second_", TypeDesc: "DateTime", Type: -1},
+		{Name: "_time_nanosecond_", TypeDesc: "DateTime64(9)", Type: -2},
+		{Name: "_raw_log_", TypeDesc: "String", Type: 0},
+		{Name: "code", TypeDesc: "Nullable(Int64)", Type: 1},
+		{Name: "_inner_siphash_code_", TypeDesc: "UInt64", Type: 4},
+		{Name: "req.path", TypeDesc: "Nullable(String)", Type: 0},
+		{Name: "tags", TypeDesc: "Array(String)", Type: -3},
+	}
+	indexes := []*db.BaseIndex{
+		{Field: "code", Typ: 1, HashTyp: db.HashTypeSip, Kind: db.IndexKindLog},
+		{Field: "path", RootName: "req", Typ: 0, Kind: db.IndexKindLog},
+	}
+	s, err := newIngestSchema(tableInfo, columns, indexes, nil)
+	if err != nil {
+		t.Fatalf("newIngestSchema() error = %v", err)
+	}
+	return s
+}
+
+func TestIngestSchemaRows(t *testing.T) {
+	s := testIngestSchema(t)
+	if len(s.columns) != 7 || s.columns[5].Hash != db.HashTypeSip {
+		t.Fatalf("newIngestSchema() columns = %v", s.columns)
+	}
+	now := time.Unix(1700000100, 0)
+	body := []byte(`{"_time_":1700000000.5,"_log_":"{\"code\":\"200\",\"req\":{\"path\":\"/a\"}}","_namespace_":"default"}
+
+{"_log_":"plain text"}`)
+	rows, err := s.rows(body, now)
+	if err != nil {
+		t.Fatalf("rows() error = %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("rows() = %d rows, want 2", len(rows))
+	}
+	row := rows[0]
+	if row[0] != "default" || row[1] != time.Unix(1700000000, 0) || row[2] != time.Unix(1700000000, 5e8) {
+		t.Errorf("rows() keys and time = %v", row[:3])
+	}
+	if row[3] != `{"code":"200","req":{"path":"/a"}}` || row[4] != int64(200) || row[5] != "200" || row[6] != "/a" {
+		t.Errorf("rows() raw log and analysis fields = %v", row[3:])
+	}
+	row = rows[1]
+	if row[0] != nil || row[1] != now || row[3] != "plain text" || row[4] != nil || row[5] != "" {
+		t.Errorf("rows() of the line without the fields = %v", row)
+	}
+	var lineErr *IngestLineError
+	if _, err = s.rows([]byte("{}\n{\"_time_\":\"x\"}"), now); !errors.As(err, &lineErr) || lineErr.Line != 2 {
+		t.Errorf("rows() error = %v, want the error of line 2", err)
+	}
+}
+
+func TestIngestPush(t *testing.T) {
+	s := testIngestSchema(t)
+	var (
+		mu      sync.Mutex
+		batches []view.IngestBatch
+		failed  bool
+	)
+	i := &ingest{
+		conf: ingestConfig{
+			enable:        true,
+			queueSize:     3,
+			batchSize:     100,
+			flushInterval: time.Hour,
+			dedupWindow:   time.Minute,
+		},
+		tables: make(map[int]*ingestTable),
+		stop:   make(chan struct{}),
+		load: func(db.BaseTable) (*ingestSchema, error) {
+			return s, nil
+		},
+		write: func(batch view.IngestBatch, iid int) error {
+			mu.Lock()
+			defer mu.Unlock()
+			if failed {
+				return errors.New("insert failed")
+			}
+			batches = append(batches, batch)
+			return nil
+		},
+	}
+	defer i.Close()
+	tableInfo := db.BaseTable{BaseModel: db.BaseModel{ID: 1}, Name: "app"}
+	res, err := i.Push(tableInfo, "", []byte("{}\n{}"))
+	if err != nil || res.Rows != 2 {
+		t.Fatalf("Push() = %v, %v", res, err)
+	}
+	if res, err = i.Push(tableInfo, "", []byte("{}\n{}")); err != nil || !res.Duplicate {
+		t.Errorf("Push() of the same body = %v, %v, want a duplicate", res, err)
+	}
+	if _, err = i.Push(tableInfo, "retry-1", []byte("{}\n{}")); !errors.Is(err, ErrIngestQueueFull) {
+		t.Errorf("Push() beyond the queue error = %v, want %v", err, ErrIngestQueueFull)
+	}
+	if _, err = i.Push(tableInfo, "retry-1", []byte("{}\n{}\n{}\n{}")); !errors.Is(err, ErrIngestTooLarge) {
+		t.Errorf("Push() of more logs than the queue error = %v, want %v", err, ErrIngestTooLarge)
+	}
+	i.flush(i.tables[1])
+	if len(batches) != 1 || len(batches[0].Rows) != 2 || batches[0].Token == "" || batches[0].Table != "app" {
+		t.Fatalf("flush() batches = %v", batches)
+	}
+	if res, err = i.Push(tableInfo, "retry-1", []byte("{}\n{}")); err != nil || res.Rows != 2 {
+		t.Errorf("Push() after the flush = %v, %v", res, err)
+	}
+	i.flush(i.tables[1])
+	// a dropped batch is not a duplicate of its retry
+	mu.Lock()
+	failed = true
+	mu.Unlock()
+	if res, err = i.Push(tableInfo, "retry-2", []byte("{}")); err != nil || res.Rows != 1 {
+		t.Fatalf("Push() = %v, %v", res, err)
+	}
+	i.flush(i.tables[1])
+	if res, err = i.Push(tableInfo, "retry-2", []byte("{}")); err != nil || res.Duplicate {
+		t.Errorf("Push() after the batch was dropped = %v, %v, want it accepted", res, err)
+	}
+}
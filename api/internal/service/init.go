@@ -20,15 +20,32 @@ import (
 var (
 	Permission      *permission.Service
 	InstanceManager *instanceManager
+	QueryCache      *queryCache
 	Index           *index
 	Alert           *alert
 	Node            *node
 	Storage         *srvStorage
+	AlertEvaluator  *alertEvaluator
+	AlertEscalator  *alertEscalator
+	AlertDelivery   *alertDelivery
+	AlertGrouper    *alertGrouper
+	ReaderImporter  *readerImporter
+	TableHealth     *tableHealth
+	QueryJobs       *queryJobs
+	LogsTails       *logsTails
+	Ingest          *ingest
 	ppt             *preempt.Preempt
+	evaluatorPpt    *preempt.Preempt
+	escalatorPpt    *preempt.Preempt
+	deliveryPpt     *preempt.Preempt
+	grouperPpt      *preempt.Preempt
+	importerPpt     *preempt.Preempt
+	healthPpt       *preempt.Preempt
 )
 
 func Init() error {
 	Permission = permission.New(&permission.Config{ResFilePath: econf.GetString("app.permissionFile")})
+	QueryCache = NewQueryCache()
 	InstanceManager = NewInstanceManager()
 
 	Index = NewIndex()
@@ -52,6 +69,69 @@ func Init() error {
 
 	Node = NewNode()
 
+	// Native alert evaluator start
+	AlertEvaluator = NewAlertEvaluator()
+	if econf.GetBool("app.isMultiCopy") {
+		evaluatorPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:alert-evaluator", AlertEvaluator.tickerEvaluate, AlertEvaluator.stop)
+	} else {
+		xgo.Go(func() { AlertEvaluator.tickerEvaluate() })
+	}
+	// Native alert evaluator start end
+
+	// Alert escalation start
+	AlertEscalator = NewAlertEscalator()
+	if econf.GetBool("app.isMultiCopy") {
+		escalatorPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:alert-escalator", AlertEscalator.tickerEscalate, AlertEscalator.stop)
+	} else {
+		xgo.Go(func() { AlertEscalator.tickerEscalate() })
+	}
+	// Alert escalation start end
+
+	// Alert delivery retry start
+	AlertDelivery = NewAlertDelivery()
+	if econf.GetBool("app.isMultiCopy") {
+		deliveryPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:alert-delivery", AlertDelivery.tickerRetry, AlertDelivery.stop)
+	} else {
+		xgo.Go(func() { AlertDelivery.tickerRetry() })
+	}
+	// Alert delivery retry start end
+
+	// Alert grouping start
+	AlertGrouper = NewAlertGrouper()
+	if econf.GetBool("app.isMultiCopy") {
+		grouperPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:alert-grouper", AlertGrouper.tickerFlush, AlertGrouper.stop)
+	} else {
+		xgo.Go(func() { AlertGrouper.tickerFlush() })
+	}
+	// Alert grouping start end
+
+	// Reader import start
+	ReaderImporter = NewReaderImporter()
+	if econf.GetBool("app.isMultiCopy") {
+		importerPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:reader-import", ReaderImporter.tickerImport, ReaderImporter.stop)
+	} else {
+		xgo.Go(func() { ReaderImporter.tickerImport() })
+	}
+	// Reader import start end
+
+	// Ingestion health start
+	TableHealth = NewTableHealth()
+	if TableHealth.Enabled() {
+		if econf.GetBool("app.isMultiCopy") {
+			healthPpt = preempt.NewPreempt(context.Background(), invoker.Redis, "clickvisual:table-health", TableHealth.tickerCollect, TableHealth.stop)
+		} else {
+			xgo.Go(func() { TableHealth.tickerCollect() })
+		}
+	}
+	// Ingestion health start end
+
+	// Query jobs run in the process which accepted them, and are shared through redis in multi-copy mode
+	QueryJobs = NewQueryJobs()
+	xgo.Go(func() { QueryJobs.tickerSync() })
+	LogsTails = NewLogsTails()
+	// Ingested logs are buffered by the process which accepted them
+	Ingest = NewIngest()
+
 	// Storage service start
 	Storage = NewSrvStorage()
 	// Support for multiple copies mode
@@ -68,11 +148,27 @@ func Init() error {
 }
 
 func Close() error {
+	QueryJobs.stop()
+	Ingest.Close()
 	// Storage service stop
 	if econf.GetBool("app.isMultiCopy") {
 		ppt.Close()
+		evaluatorPpt.Close()
+		escalatorPpt.Close()
+		deliveryPpt.Close()
+		grouperPpt.Close()
+		importerPpt.Close()
+		if healthPpt != nil {
+			healthPpt.Close()
+		}
 	} else {
 		Storage.stop()
+		AlertEvaluator.stop()
+		AlertEscalator.stop()
+		AlertDelivery.stop()
+		AlertGrouper.stop()
+		ReaderImporter.stop()
+		TableHealth.stop()
 	}
 	// Storage service stop end
 	return nil
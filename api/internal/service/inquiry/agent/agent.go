@@ -135,6 +135,67 @@ func (a *Agent) GetLogs(query view.ReqQuery, i int) (resp view.RespQuery, err er
 	return resp, nil
 }
 
+// ContextLogs reads the context of the log from the agents, the first agent which has the file of the log answers.
+func (a *Agent) ContextLogs(req view.ReqLogsContext) (resp view.RespLogsContext, err error) {
+	data := map[string]string{
+		"path":   req.Fields[search.InnerKeyFile],
+		"offset": req.Fields[search.InnerKeyOffset],
+		"sign":   req.Fields[search.InnerKeySign],
+		"before": strconv.Itoa(req.Before),
+		"after":  strconv.Itoa(req.After),
+	}
+	err = errors.New("no agent has the file of the log")
+	for _, agent := range a.agents {
+		if !strings.HasPrefix(agent, "http://") {
+			agent = "http://" + agent
+		}
+		contextResp, errReq := a.httpClient.R().SetQueryParams(data).Get(agent + "/api/v1/context")
+		if errReq != nil {
+			elog.Error("get agent context error", l.E(errReq), l.S("agent", agent))
+			err = errors.Wrapf(errReq, "request agent %s error", agent)
+			continue
+		}
+		var res struct {
+			Code int                   `json:"code"`
+			Msg  string                `json:"msg"`
+			Data view.RespAgentContext `json:"data"`
+		}
+		if errReq = json.Unmarshal(contextResp.Body(), &res); errReq != nil {
+			err = errors.Wrapf(errReq, "unmarshal agent %s response error, body is %s", agent, string(contextResp.Body()))
+			continue
+		}
+		if res.Code != 0 {
+			err = errors.Errorf("agent %s: %s", agent, res.Msg)
+			continue
+		}
+		resp.Before = a.contextLogs(res.Data.K8sClientType, res.Data.Before, req.Fields)
+		resp.After = a.contextLogs(res.Data.K8sClientType, res.Data.After, req.Fields)
+		return resp, nil
+	}
+	return resp, err
+}
+
+// contextLogs keeps the lines without a time too, as the lines of a stack trace.
+func (a *Agent) contextLogs(k8sClientType string, items []view.RespAgentSearchItem, fields map[string]string) []map[string]interface{} {
+	res := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		log, _ := a.parseHitLog(k8sClientType, item)
+		if log == nil {
+			log = map[string]interface{}{search.InnerRawLog: item.Line}
+			for k, v := range item.Ext {
+				log[k] = v
+			}
+		}
+		for k, v := range fields {
+			if _, ok := log[k]; !ok {
+				log[k] = v
+			}
+		}
+		res = append(res, log)
+	}
+	return res
+}
+
 func (a *Agent) Chart(query view.ReqQuery) ([]*view.HighChart, string, error) {
 	resp := make([]*view.HighChart, 0)
 	chartsOffsetMap := make(map[int64]int64)
@@ -307,11 +368,6 @@ func (a *Agent) CreateAlertView(s string, s2 string, s3 string) error {
 	panic("implement me")
 }
 
-func (a *Agent) CreateKafkaTable(table *db2.BaseTable, update view.ReqStorageUpdate) (string, error) {
-	// TODO implement me
-	panic("implement me")
-}
-
 func (a *Agent) CreateTraceJaegerDependencies(database, cluster, table string, ttl int) (err error) {
 	// TODO implement me
 	panic("implement me")
@@ -352,6 +408,11 @@ func (a *Agent) UpdateMergeTreeTable(table *db2.BaseTable, update view.ReqStorag
 	panic("implement me")
 }
 
+func (a *Agent) UpdateReader(table *db2.BaseTable, update view.ReqStorageUpdate) (string, error) {
+	// TODO implement me
+	panic("implement me")
+}
+
 func (a *Agent) GetCreateSQL(database, table string) (string, error) {
 	// TODO implement me
 	panic("implement me")
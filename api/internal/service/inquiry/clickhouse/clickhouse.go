@@ -8,13 +8,16 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ego-component/egorm"
 	"github.com/gotomicro/cetus/l"
 	"github.com/gotomicro/ego/core/econf"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/pkg/errors"
+	"github.com/spf13/cast"
 
 	"github.com/clickvisual/clickvisual/api/core/i"
 	"github.com/clickvisual/clickvisual/api/core/reader"
@@ -29,15 +32,37 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/bumo"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/cluster"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/common"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/standalone"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builderv2"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
 )
 
 var _ factory.Operator = (*ClickHouseX)(nil)
 
+var _ factory.JobOperator = (*ClickHouseX)(nil)
+
+var _ factory.Exporter = (*ClickHouseX)(nil)
+
+var _ factory.FieldStatsReader = (*ClickHouseX)(nil)
+
+var _ factory.SQLBuilder = (*ClickHouseX)(nil)
+
+var _ factory.Inserter = (*ClickHouseX)(nil)
+
+var _ factory.OTLPCreator = (*ClickHouseX)(nil)
+
+var _ factory.ReaderImporter = (*ClickHouseX)(nil)
+
+var _ factory.IngestHealthReader = (*ClickHouseX)(nil)
+
+var _ factory.NewGroupReader = (*ClickHouseX)(nil)
+
 type ClickHouseX struct {
-	id int
-	db *sql.DB
+	id       int
+	db       *sql.DB
+	job      *factory.Job
+	settings *sync.Map // setting name -> known by the server, see hasSetting
 }
 
 func NewClickHouse(db *sql.DB, ins *db.BaseInstance) (*ClickHouseX, error) {
@@ -45,8 +70,9 @@ func NewClickHouse(db *sql.DB, ins *db.BaseInstance) (*ClickHouseX, error) {
 		return nil, errors.New("clickhouse add err, id is 0")
 	}
 	return &ClickHouseX{
-		db: db,
-		id: ins.ID,
+		db:       db,
+		id:       ins.ID,
+		settings: &sync.Map{},
 	}, nil
 }
 
@@ -365,8 +391,8 @@ func (c *ClickHouseX) Prepare(res view.ReqQuery, table *db.BaseTable, isRegroup
 	if res.PageSize <= 0 {
 		res.PageSize = 20
 	}
-	if res.Query == "" {
-		res.Query = defaultCondition
+	if err := factory.ValidCursor(res.Cursor, res.Direction); err != nil {
+		return res, err
 	}
 	if res.ET == res.ST && res.ST != 0 {
 		res.ET = res.ST + 1
@@ -379,6 +405,15 @@ func (c *ClickHouseX) Prepare(res view.ReqQuery, table *db.BaseTable, isRegroup
 		res.ST = time.Now().Add(-time.Minute * 15).Unix()
 		res.ET = time.Now().Unix()
 	}
+	if res.QueryMode == view.QueryModeLanguage {
+		// filters are expressions of the query language too
+		res.Query = lql.Join(append([]string{res.Query}, res.Filters...)...)
+		_, err := queryCompile(res, table, false)
+		return res, err
+	}
+	if res.Query == "" {
+		res.Query = defaultCondition
+	}
 	for _, filter := range res.Filters {
 		res.Query = fmt.Sprintf("%s and %s", res.Query, filter)
 	}
@@ -541,11 +576,12 @@ func (c *ClickHouseX) GetAlertViewSQL(alarm *db.Alarm, tableInfo db.BaseTable, f
 
 	sourceTableName = fmt.Sprintf("`%s`.`%s`", tableInfo.Database.Name, tableName)
 	vp := bumo.ParamsView{
-		ViewType:     bumo.ViewTypePrometheusMetric,
-		ViewTable:    viewTableName,
-		CommonFields: factory.TagsToString(alarm, true, filterId),
-		SourceTable:  sourceTableName,
-		Where:        filter.When,
+		ViewType:       bumo.ViewTypePrometheusMetric,
+		ViewTable:      viewTableName,
+		CommonFields:   factory.TagsToString(alarm, true, filterId),
+		SourceTable:    sourceTableName,
+		Where:          filter.When,
+		SignatureField: filter.SignatureField,
 	}
 	if filter.Mode == db.AlarmModeAggregation || filter.Mode == db.AlarmModeAggregationCheck {
 		vp.ViewType = bumo.ViewTypePrometheusMetricAggregation
@@ -681,6 +717,15 @@ func (c *ClickHouseX) DeleteTable(database, table, cluster string, tid int) (err
 	if err != nil {
 		return err
 	}
+	// imported files of the import reader
+	delImportFilesSQL := fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s%s`;", database, table, db.SuffixImportFiles)
+	if isCluster == ModeCluster {
+		delImportFilesSQL = fmt.Sprintf("DROP TABLE IF EXISTS `%s`.`%s_local%s` ON CLUSTER '%s';", database, table, db.SuffixImportFiles, cluster)
+	}
+	_, err = c.db.Exec(delImportFilesSQL)
+	if err != nil {
+		return err
+	}
 	_, err = c.db.Exec(delDataSQL)
 	if err != nil {
 		return err
@@ -715,6 +760,7 @@ func (c *ClickHouseX) GetLogs(param view.ReqQuery, tid int) (res view.RespQuery,
 		defaultSQL    string
 		originalWhere string
 		optimizeSQL   string
+		withCursor    bool
 	)
 	switch param.AlarmMode {
 	case db.AlarmModeAggregation:
@@ -722,7 +768,13 @@ func (c *ClickHouseX) GetLogs(param view.ReqQuery, tid int) (res view.RespQuery,
 	case db.AlarmModeAggregationCheck:
 		defaultSQL = alarmAggregationSQLWith(param)
 	default:
+		if param.Cursor != "" {
+			if param.Cursor, err = c.turnCursor(param, tid); err != nil {
+				return
+			}
+		}
 		defaultSQL, optimizeSQL, originalWhere = c.logsSQL(param, tid)
+		withCursor = param.Cursor != "" || param.Direction != ""
 	}
 	var execSQL = defaultSQL
 	if optimizeSQL != "" {
@@ -732,6 +784,12 @@ func (c *ClickHouseX) GetLogs(param view.ReqQuery, tid int) (res view.RespQuery,
 	if err != nil {
 		return
 	}
+	if withCursor {
+		res.Cursors = factory.TakeCursors(res.Logs, param.Cursor, param.Direction)
+		if len(res.Cursors) > 0 {
+			res.Newer, res.Older = res.Cursors[0], res.Cursors[len(res.Cursors)-1]
+		}
+	}
 	// try again
 	res.Query = defaultSQL
 	res.Where = strings.TrimSuffix(strings.TrimPrefix(originalWhere, "AND ("), ")")
@@ -787,6 +845,16 @@ func (c *ClickHouseX) GetLogs(param view.ReqQuery, tid int) (res view.RespQuery,
 	return
 }
 
+func (c *ClickHouseX) ExportLogs(ctx context.Context, param view.ReqQuery, tid int, limit int, w factory.RowWriter) error {
+	q := c.exportSQL(param, tid, limit)
+	rows, err := c.db.QueryContext(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, q)
+	}
+	defer func() { _ = rows.Close() }()
+	return errors.Wrap(factory.ScanRows(rows, limit, w), q)
+}
+
 func (c *ClickHouseX) Chart(param view.ReqQuery) (res []*view.HighChart, q string, err error) {
 	q = c.chartSQL(param)
 	charts, err := c.doQueryWithRetry(q, false)
@@ -877,6 +945,48 @@ func (c *ClickHouseX) GroupBy(param view.ReqQuery) (res map[string]uint64) {
 	return
 }
 
+// FieldStats computes the statistics of the fields over the logs of the query. The summaries of all the fields
+// are read in one query, the top values with their time series in another and the histograms of the numeric fields in a third.
+func (c *ClickHouseX) FieldStats(param view.ReqQuery, req view.FieldStatsParams) (res view.RespFieldStats, err error) {
+	res = factory.NewFieldStats(param, req)
+	where := fmt.Sprintf(genTimeCondition(param), param.ST, param.ET) + " " + c.queryTransform(param, true)
+	rows, err := c.doQueryWithRetry(fieldSummarySQL(param.DatabaseTable, where, req), false)
+	if err != nil {
+		return res, err
+	}
+	if len(rows) > 0 {
+		factory.SetFieldStatsSummary(&res, rows[0], req.Buckets)
+	}
+	if res.Total == 0 {
+		return res, nil
+	}
+	rows, err = c.doQueryWithRetry(fieldTopSQL(param, where, req), false)
+	if err != nil {
+		return res, err
+	}
+	factory.SetFieldStatsTop(&res, rows)
+	if q := fieldHistogramSQL(param.DatabaseTable, where, res); q != "" {
+		if rows, err = c.doQueryWithRetry(q, false); err != nil {
+			return res, err
+		}
+		factory.SetFieldStatsHistogram(&res, rows)
+	}
+	return res, nil
+}
+
+// HasNewGroup reports whether a value of the field occurs at least min times in the time range of the param
+// and not in [baselineST, param.ST), the values of both ranges are compared by one statement.
+func (c *ClickHouseX) HasNewGroup(param view.ReqQuery, baselineST int64, min uint64) (bool, error) {
+	query := c.queryTransform(param, true)
+	where := fmt.Sprintf(genTimeCondition(param), param.ST, param.ET) + " " + query
+	baseline := fmt.Sprintf(genTimeCondition(param), baselineST, param.ST) + " " + query
+	rows, err := c.doQueryWithRetry(newGroupSQL(param, where, baseline, min), false)
+	if err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
+}
+
 func (c *ClickHouseX) databases() map[string][]*view.RespTablesSelfBuilt {
 	res := make(map[string][]*view.RespTablesSelfBuilt)
 	query := "select name from system.databases"
@@ -1148,6 +1258,22 @@ func (c *ClickHouseX) CreateStorageJSONAsString(database db.BaseDatabase, ct vie
 	var storeSQLs []string
 	var readerSQLs []string
 	var switcherSQLs []string
+	// the reader is validated before anything is created
+	params := c.readerParams(database, ct.TableName, ct.CreateType)
+	params.ReaderType = ct.GetReaderType()
+	params.Brokers = ct.Brokers
+	params.Topics = ct.Topics
+	params.GroupName = database.Name + "_" + ct.TableName
+	params.KafkaNumConsumers = ct.Consumers
+	params.KafkaSkipBrokenMessages = ct.KafkaSkipBrokenMessages
+	params.Settings = ct.ReaderSettings
+	rd, err := reader.New(db.DatasourceClickHouse, params)
+	if err != nil {
+		return
+	}
+	if err = rd.Validate(); err != nil {
+		return
+	}
 	// storer
 	_, storeSQLs, err = storer.New(db.DatasourceClickHouse, i.StorerParams{
 		CreateType: ct.CreateType,
@@ -1164,20 +1290,7 @@ func (c *ClickHouseX) CreateStorageJSONAsString(database db.BaseDatabase, ct vie
 		return
 	}
 	// reader
-	_, readerSQLs, err = reader.New(db.DatasourceClickHouse, i.ReaderParams{
-		CreateType:              ct.CreateType,
-		IsShard:                 c.isShard(database.Cluster),
-		IsReplica:               c.isReplica(database.Cluster),
-		Cluster:                 database.Cluster,
-		Database:                database.Name,
-		Table:                   ct.TableName,
-		Conn:                    c.Conn(),
-		Brokers:                 ct.Brokers,
-		Topics:                  ct.Topics,
-		GroupName:               database.Name + "_" + ct.TableName,
-		KafkaNumConsumers:       ct.Consumers,
-		KafkaSkipBrokenMessages: ct.KafkaSkipBrokenMessages,
-	}).Create()
+	_, readerSQLs, err = rd.Create()
 	if err != nil {
 		return
 	}
@@ -1251,6 +1364,22 @@ func (c *ClickHouseX) CreateStorage(did int, database db.BaseDatabase, ct view.R
 			KafkaSkipBrokenMessages: ct.KafkaSkipBrokenMessages,
 		},
 	}
+	// the reader is validated before anything is created, the stream table of kafka is created by the stream builder
+	params := c.readerParams(database, ct.TableName, ct.CreateType)
+	params.ReaderType = ct.GetReaderType()
+	params.Columns = common.BuilderFieldsStream(streamParams.KafkaJsonMapping, streamParams.TimeField, timeTyp, streamParams.LogField)
+	params.Brokers = ct.Brokers
+	params.Topics = ct.Topics
+	params.KafkaNumConsumers = ct.Consumers
+	params.KafkaSkipBrokenMessages = ct.KafkaSkipBrokenMessages
+	params.Settings = ct.ReaderSettings
+	rd, err := reader.New(db.DatasourceClickHouse, params)
+	if err != nil {
+		return
+	}
+	if err = rd.Validate(); err != nil {
+		return
+	}
 	if isCluster == ModeCluster {
 		dataParams.Cluster = database.Cluster
 		streamParams.Cluster = database.Cluster
@@ -1267,8 +1396,14 @@ func (c *ClickHouseX) CreateStorage(did int, database db.BaseDatabase, ct view.R
 		dDataSQL = builder.Do(new(standalone.DataBuilder), dataParams)
 		dStreamSQL = builder.Do(new(standalone.StreamBuilder), streamParams)
 	}
-	_, err = c.db.Exec(dStreamSQL)
-	if err != nil {
+	if ct.GetReaderType() != db.ReaderTypeKafka {
+		var readerSQLs []string
+		if _, readerSQLs, err = rd.Create(); err != nil {
+			elog.Error("CreateTable", elog.Any("reader", rd.Description()), elog.Any("err", err.Error()), elog.Any("isCluster", isCluster), elog.Any("cluster", database.Cluster))
+			return
+		}
+		dStreamSQL = readerSQLs[0]
+	} else if _, err = c.db.Exec(dStreamSQL); err != nil {
 		elog.Error("CreateTable", elog.Any("dStreamSQL", dStreamSQL), elog.Any("err", err.Error()), elog.Any("isCluster", isCluster), elog.Any("cluster", database.Cluster))
 		return
 	}
@@ -1326,56 +1461,89 @@ func (c *ClickHouseX) UpdateMergeTreeTable(tableInfo *db.BaseTable, params view.
 	return
 }
 
-// CreateKafkaTable Drop and Create
-func (c *ClickHouseX) CreateKafkaTable(tableInfo *db.BaseTable, params view.ReqStorageUpdate) (streamSQL string, err error) {
-	currentKafkaSQL := tableInfo.SqlStream
-	// Drop TableName
+// UpdateReader drops the stream table and creates it with the reader of the params,
+// the current stream table is created again when the new one fails.
+func (c *ClickHouseX) UpdateReader(tableInfo *db.BaseTable, params view.ReqStorageUpdate) (streamSQL string, err error) {
+	currentStreamSQL := tableInfo.SqlStream
 	isCluster, err := c.isCluster(tableInfo.Database.Cluster)
 	if err != nil {
 		return "", errors.Wrap(err, "isCluster error")
 	}
+	streamParams := bumo.Params{
+		TableCreateType: tableInfo.CreateType,
+		Stream: bumo.ParamsStream{
+			TableName:               genStreamNameWithMode(isCluster, tableInfo.Database.Name, tableInfo.Name),
+			TableTyp:                tableTypStr(tableInfo.TimeFieldKind),
+			Group:                   tableInfo.Database.Name + "_" + tableInfo.Name,
+			Brokers:                 params.KafkaBrokers,
+			Topic:                   params.KafkaTopic,
+			ConsumerNum:             params.KafkaConsumerNum,
+			KafkaSkipBrokenMessages: params.KafkaSkipBrokenMessages,
+		},
+	}
+	// 兼容旧版本
+	if tableInfo.TimeField != "" {
+		streamParams.TimeField = tableInfo.TimeField
+	}
+	if tableInfo.RawLogField != "" {
+		streamParams.LogField = tableInfo.RawLogField
+	}
+	// 新版本数据填充
+	if tableInfo.AnyJSON != "" {
+		rsc := view.ReqStorageCreateUnmarshal(tableInfo.AnyJSON)
+		streamParams.KafkaJsonMapping = rsc.Mapping2String(true, "")
+		if rsc.TimeField != "" {
+			streamParams.TimeField = rsc.TimeField
+		}
+		if rsc.RawLogField != "" {
+			streamParams.LogField = rsc.RawLogField
+		}
+	}
+	// the new reader is validated before the current one is dropped
+	readerParams := c.readerParams(*tableInfo.Database, tableInfo.Name, tableInfo.CreateType)
+	readerParams.ReaderType = params.GetReaderType()
+	readerParams.Columns = common.BuilderFieldsStream(streamParams.KafkaJsonMapping, streamParams.TimeField, streamParams.Stream.TableTyp, streamParams.LogField)
+	readerParams.Brokers = params.KafkaBrokers
+	readerParams.Topics = params.KafkaTopic
+	readerParams.GroupName = streamParams.Stream.Group
+	readerParams.KafkaNumConsumers = params.KafkaConsumerNum
+	readerParams.KafkaSkipBrokenMessages = params.KafkaSkipBrokenMessages
+	readerParams.Settings = params.ReaderSettings
+	rd, err := reader.New(db.DatasourceClickHouse, readerParams)
+	if err != nil {
+		return
+	}
+	if err = rd.Validate(); err != nil {
+		return
+	}
+	// the imported files are kept until the new reader is created, the import table of a rollback skips them
+	var importer i.Importer
+	if tableInfo.GetReaderType() == db.ReaderTypeImport && params.GetReaderType() != db.ReaderTypeImport {
+		currentParams := c.readerParams(*tableInfo.Database, tableInfo.Name, tableInfo.CreateType)
+		currentParams.ReaderType = db.ReaderTypeImport
+		currentParams.Settings = tableInfo.ReaderSettings
+		current, errCurrent := reader.New(db.DatasourceClickHouse, currentParams)
+		if errCurrent != nil {
+			return "", errCurrent
+		}
+		if importer, _ = current.(i.Importer); importer == nil {
+			return "", errors.Errorf("reader %s does not import", current.Description())
+		}
+	}
+	// Drop TableName
 	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s%s", genStreamNameWithMode(isCluster, tableInfo.Database.Name, tableInfo.Name), genSQLClusterInfo(isCluster, tableInfo.Database.Cluster))
 	if _, err = c.db.Exec(dropSQL); err != nil {
-		elog.Error("CreateKafkaTable", elog.Any("dropSQL", dropSQL), elog.Any("err", err.Error()))
+		elog.Error("UpdateReader", elog.Any("dropSQL", dropSQL), elog.Any("err", err.Error()))
 		return
 	}
 
-	if tableInfo.CreateType == constx.TableCreateTypeJSONAsString {
-		streamSQL, err = c.updateReaderJSONAsString(tableInfo, params)
-		return
+	if tableInfo.CreateType == constx.TableCreateTypeJSONAsString || params.GetReaderType() != db.ReaderTypeKafka {
+		var readerSQLs []string
+		if _, readerSQLs, err = rd.Create(); err == nil {
+			streamSQL = readerSQLs[0]
+		}
 	} else {
 		// Create TableName
-		streamParams := bumo.Params{
-			TableCreateType: tableInfo.CreateType,
-			Stream: bumo.ParamsStream{
-				TableName:               genStreamNameWithMode(isCluster, tableInfo.Database.Name, tableInfo.Name),
-				TableTyp:                tableTypStr(tableInfo.TimeFieldKind),
-				Group:                   tableInfo.Database.Name + "_" + tableInfo.Name,
-				Brokers:                 params.KafkaBrokers,
-				Topic:                   params.KafkaTopic,
-				ConsumerNum:             params.KafkaConsumerNum,
-				KafkaSkipBrokenMessages: params.KafkaSkipBrokenMessages,
-			},
-		}
-		// 兼容旧版本
-		if tableInfo.TimeField != "" {
-			streamParams.TimeField = tableInfo.TimeField
-		}
-		if tableInfo.RawLogField != "" {
-			streamParams.LogField = tableInfo.RawLogField
-		}
-		// 新版本数据填充
-		if tableInfo.AnyJSON != "" {
-			rsc := view.ReqStorageCreateUnmarshal(tableInfo.AnyJSON)
-			streamParams.KafkaJsonMapping = rsc.Mapping2String(true, "")
-			if rsc.TimeField != "" {
-				streamParams.TimeField = rsc.TimeField
-			}
-			if rsc.RawLogField != "" {
-				streamParams.LogField = rsc.RawLogField
-			}
-		}
-
 		if isCluster == ModeCluster {
 			streamParams.Cluster = tableInfo.Database.Cluster
 			if c.isReplica(tableInfo.Database.Cluster) {
@@ -1391,13 +1559,34 @@ func (c *ClickHouseX) CreateKafkaTable(tableInfo *db.BaseTable, params view.ReqS
 	}
 
 	if err != nil {
-		elog.Error("CreateKafkaTable", elog.Any("streamSQL", streamSQL), elog.Any("err", err.Error()))
-		_, _ = c.db.Exec(currentKafkaSQL)
+		elog.Error("UpdateReader", elog.Any("streamSQL", streamSQL), elog.Any("err", err.Error()))
+		_, _ = c.db.Exec(currentStreamSQL)
 		return
 	}
+	if importer != nil {
+		if errFiles := importer.DeleteFiles(); errFiles != nil {
+			elog.Error("UpdateReader", elog.String("step", "deleteImportFiles"), elog.Any("err", errFiles.Error()))
+		}
+	}
 	return
 }
 
+// ImportReader imports the files of the table read by db.ReaderTypeImport.
+func (c *ClickHouseX) ImportReader(tableInfo *db.BaseTable, limit int) (files []string, err error) {
+	params := c.readerParams(*tableInfo.Database, tableInfo.Name, tableInfo.CreateType)
+	params.ReaderType = tableInfo.GetReaderType()
+	params.Settings = tableInfo.ReaderSettings
+	rd, err := reader.New(db.DatasourceClickHouse, params)
+	if err != nil {
+		return
+	}
+	importer, ok := rd.(i.Importer)
+	if !ok {
+		return nil, errors.Errorf("reader %s does not import", rd.Description())
+	}
+	return importer.Import(limit)
+}
+
 func (c *ClickHouseX) CreateTraceJaegerDependencies(database, cluster, table string, ttl int) (err error) {
 	// jaegerJson dependencies table
 	sc, err := builderv2.GetTableCreator(constx.TableCreateTypeTraceCalculation)
@@ -1433,6 +1622,38 @@ func (c *ClickHouseX) CreateTraceJaegerDependencies(database, cluster, table str
 	return nil
 }
 
+// CreateOTLPTable creates the logs or the spans table of the OTLP receiver.
+func (c *ClickHouseX) CreateOTLPTable(createType int, database, cluster, table string, ttl int) (names []string, sqls []string, err error) {
+	sc, err := builderv2.GetTableCreator(createType)
+	if err != nil {
+		return
+	}
+	params := builderv2.Params{
+		Cluster:  cluster,
+		Database: database,
+		Table:    table,
+		TTL:      ttl,
+		DB:       c.db,
+	}
+	isCluster, err := c.isCluster(cluster)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "isCluster get failed")
+	}
+	if isCluster == ModeCluster {
+		params.IsShard = true
+		if c.isReplica(cluster) {
+			params.IsReplica = true
+		}
+	}
+	sc.SetParams(params)
+	names, sqls = sc.GetSQLs()
+	if _, err = sc.Execute(sqls); err != nil {
+		elog.Error("CreateTable", elog.String("step", "CreateOTLPTable"), elog.FieldErr(err))
+		return
+	}
+	return
+}
+
 func (c *ClickHouseX) DeleteTraceJaegerDependencies(database, cluster, table string) (err error) {
 	table = table + db.SuffixJaegerJSON
 	isCluster, err := c.isCluster(cluster)
@@ -1576,33 +1797,19 @@ func (c *ClickHouseX) CreateBufferNullDataPipe(req db.ReqCreateBufferNullDataPip
 	return
 }
 
-// MergeTreeTTL
-// KafkaBrokers
-// KafkaTopic
-// KafkaConsumerNum
-// KafkaSkipBrokenMessages
-// Desc
-// V3TableType
-func (c *ClickHouseX) updateReaderJSONAsString(tableInfo *db.BaseTable, params view.ReqStorageUpdate) (res string, err error) {
-	// reader
-	_, readerSQLs, err := reader.New(db.DatasourceClickHouse, i.ReaderParams{
-		CreateType:              tableInfo.CreateType,
-		IsShard:                 c.isShard(tableInfo.Database.Cluster),
-		IsReplica:               c.isReplica(tableInfo.Database.Cluster),
-		Cluster:                 tableInfo.Database.Cluster,
-		Database:                tableInfo.Database.Name,
-		Table:                   tableInfo.Name,
-		Conn:                    c.Conn(),
-		Brokers:                 params.KafkaBrokers,
-		Topics:                  params.KafkaTopic,
-		GroupName:               tableInfo.Database.Name + "_" + tableInfo.Name,
-		KafkaNumConsumers:       params.KafkaConsumerNum,
-		KafkaSkipBrokenMessages: params.KafkaSkipBrokenMessages,
-	}).Create()
-	if err != nil {
-		return
+// readerParams returns the common params of the reader of the table.
+func (c *ClickHouseX) readerParams(database db.BaseDatabase, table string, createType int) i.ReaderParams {
+	return i.ReaderParams{
+		CreateType: createType,
+		IsShard:    c.isShard(database.Cluster),
+		IsReplica:  c.isReplica(database.Cluster),
+		Cluster:    database.Cluster,
+		Database:   database.Name,
+		Table:      table,
+		Conn:       c.Conn(),
+
+		ImportURLHosts: econf.GetStringSlice("app.importURLHosts"),
 	}
-	return readerSQLs[0], nil
 }
 
 func (c *ClickHouseX) updateSwitcherJSONAsString(ct view.ReqStorageCreate, timeView *db.BaseView, timeViewList []*db.BaseView, database *db.BaseDatabase, tid int, customTimeField string, indexes map[string]*db.BaseIndex) (res string, err error) {
@@ -2066,28 +2273,50 @@ func (c *ClickHouseX) logsSQL(param view.ReqQuery, tid int) (sql, optSQL, origin
 	if len(views) > 0 {
 		orderByField = db.TimeFieldNanoseconds
 	}
-	selectFields := genSelectFields(tid)
+	selectFields, orderBy := genSelectFields(tid), orderByField+" DESC"
+	tsExpr, tieExpr := cursorKey(param, orderByField)
+	if param.Cursor != "" || param.Direction != "" {
+		// the order key is selected for the cursors, the row hash orders the logs of the same time
+		selectFields = fmt.Sprintf("%s, %s AS %s, %s AS %s", selectFields, tsExpr, factory.CursorTsField, tieExpr, factory.CursorTieField)
+		orderBy = factory.CursorOrder(orderByField, param.Direction)
+	}
 	c2 := time.Since(st).Milliseconds()
+	originalWhere = c.queryTransform(param, false)
+	if param.Cursor != "" {
+		// search after the cursor, the cursor is validated by Prepare
+		cur, _ := factory.ParseCursor(param.Cursor)
+		param.ST, param.ET = factory.CursorRange(param.ST, param.ET, cur, param.Direction)
+		sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genTimeCondition(param)+" %s %s ORDER BY %s LIMIT %d OFFSET %d",
+			selectFields,
+			param.DatabaseTable,
+			param.ST, param.ET,
+			factory.CursorCondition(tsExpr, tieExpr, cur, param.Direction),
+			originalWhere,
+			orderBy,
+			param.PageSize, cur.Seen)
+		return
+	}
 	// Request for the first 100 pages of data
 	// optimizing, the idea is to reduce the number of fields involved in operation;
 	if param.Page*param.PageSize <= 100 {
 		timeFieldEqual := c.timeFieldEqual(param, tid)
 		if timeFieldEqual != "" {
-			optSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s %s ORDER BY "+orderByField+" DESC LIMIT %d OFFSET %d",
+			optSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s %s ORDER BY %s LIMIT %d OFFSET %d",
 				selectFields,
 				param.DatabaseTable,
 				timeFieldEqual,
 				c.queryTransform(param, true),
+				orderBy,
 				param.PageSize, (param.Page-1)*param.PageSize)
 		}
 	}
 	c3 := time.Since(st).Milliseconds()
-	originalWhere = c.queryTransform(param, false)
-	sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genTimeCondition(param)+" %s ORDER BY "+orderByField+" DESC LIMIT %d OFFSET %d",
+	sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genTimeCondition(param)+" %s ORDER BY %s LIMIT %d OFFSET %d",
 		selectFields,
 		param.DatabaseTable,
 		param.ST, param.ET,
 		originalWhere,
+		orderBy,
 		param.PageSize, (param.Page-1)*param.PageSize)
 	c4 := time.Since(st).Milliseconds()
 	elog.Debug("logsTimelineSQL",
@@ -2099,7 +2328,64 @@ func (c *ClickHouseX) logsSQL(param view.ReqQuery, tid int) (sql, optSQL, origin
 	return
 }
 
+// turnCursor returns the cursor of the page in the order of the direction,
+// the logs of the key of a turned cursor are counted to turn it.
+func (c *ClickHouseX) turnCursor(param view.ReqQuery, tid int) (string, error) {
+	cur, _ := factory.ParseCursor(param.Cursor)
+	if !cur.Turned(param.Direction) {
+		return param.Cursor, nil
+	}
+	orderByField := param.TimeField
+	if views, _ := db.ViewList(invoker.Db, egorm.Conds{"tid": tid}); len(views) > 0 {
+		orderByField = db.TimeFieldNanoseconds
+	}
+	tsExpr, tieExpr := cursorKey(param, orderByField)
+	param.ST, param.ET = cur.Ts/1e9, cur.Ts/1e9+1
+	q := fmt.Sprintf("SELECT count(*) as count FROM %s WHERE "+genTimeCondition(param)+" %s %s",
+		param.DatabaseTable,
+		param.ST, param.ET,
+		factory.CursorKeyCondition(tsExpr, tieExpr, cur),
+		c.queryTransform(param, false))
+	res, err := c.doQueryWithRetry(q, false)
+	if err != nil {
+		return "", err
+	}
+	count := 0
+	if len(res) > 0 {
+		count = cast.ToInt(res[0]["count"])
+	}
+	return cur.Turn(count).String(), nil
+}
+
+// exportSQL selects the logs of the query without the cursor keys and the pages.
+func (c *ClickHouseX) exportSQL(param view.ReqQuery, tid int, limit int) string {
+	orderByField := param.TimeField
+	if views, _ := db.ViewList(invoker.Db, egorm.Conds{"tid": tid}); len(views) > 0 {
+		orderByField = db.TimeFieldNanoseconds
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE "+genTimeCondition(param)+" %s ORDER BY %s DESC LIMIT %d",
+		genSelectFields(tid),
+		param.DatabaseTable,
+		param.ST, param.ET,
+		c.queryTransform(param, false),
+		orderByField,
+		limit)
+}
+
 func (c *ClickHouseX) queryTransform(params view.ReqQuery, isOptimized bool) string {
+	if params.QueryMode == view.QueryModeLanguage {
+		table, _ := db.TableInfo(invoker.Db, params.Tid)
+		query, err := queryCompile(params, &table, isOptimized)
+		if err != nil {
+			// the query is validated by Prepare, an invalid query matches nothing
+			elog.Error("queryTransform", elog.String("query", params.Query), elog.FieldErr(err))
+			return "AND 1=0"
+		}
+		if query == "" {
+			return query
+		}
+		return fmt.Sprintf("AND (%s)", query)
+	}
 	if isOptimized {
 		params.Query = queryTransformHash(params) // hash transform
 	}
@@ -2111,6 +2397,16 @@ func (c *ClickHouseX) queryTransform(params view.ReqQuery, isOptimized bool) str
 	return fmt.Sprintf("AND (%s)", query)
 }
 
+// ChartSQL is the statement of Chart, the key of its cached results.
+func (c *ClickHouseX) ChartSQL(param view.ReqQuery) string {
+	return c.chartSQL(param)
+}
+
+// CountSQL is the statement of Count, the key of its cached results.
+func (c *ClickHouseX) CountSQL(param view.ReqQuery) string {
+	return c.countSQL(param)
+}
+
 func (c *ClickHouseX) countSQL(param view.ReqQuery) (sql string) {
 	sql = fmt.Sprintf("SELECT count(*) as count FROM %s WHERE "+genTimeCondition(param)+" %s",
 		param.DatabaseTable,
@@ -2172,9 +2468,35 @@ func (c *ClickHouseX) doQueryWithRetry(sql string, isShowNull bool) (res []map[s
 	return res, err
 }
 
+// WithJob returns a copy of the operator whose queries carry the query id, the limit and the callbacks of the job.
+func (c *ClickHouseX) WithJob(job factory.Job) factory.Operator {
+	return &ClickHouseX{id: c.id, db: c.db, job: &job, settings: c.settings}
+}
+
+func (c *ClickHouseX) KillQuery(queryId string) error {
+	_, err := c.db.Exec(fmt.Sprintf("KILL QUERY WHERE query_id = %s ASYNC", lqlDialect{}.String(queryId)))
+	return err
+}
+
+func (c *ClickHouseX) query(sql string) (*sql.Rows, error) {
+	if c.job == nil {
+		return c.db.Query(sql)
+	}
+	opts := []clickhouse.QueryOption{clickhouse.WithQueryID(c.job.QueryId)}
+	if c.job.MaxExecutionTime > 0 {
+		opts = append(opts, clickhouse.WithSettings(clickhouse.Settings{"max_execution_time": c.job.MaxExecutionTime}))
+	}
+	if c.job.Progress != nil {
+		opts = append(opts, clickhouse.WithProgress(func(p *clickhouse.Progress) {
+			c.job.Progress(p.Rows, p.Bytes, p.TotalRows)
+		}))
+	}
+	return c.db.QueryContext(clickhouse.Context(c.job.Ctx, opts...), sql)
+}
+
 func (c *ClickHouseX) doQuery(sql string, isShowNull bool) (res []map[string]interface{}, err error) {
 	res = make([]map[string]interface{}, 0)
-	rows, err := c.db.Query(sql)
+	rows, err := c.query(sql)
 	if err != nil {
 		return res, errors.Wrap(err, sql)
 	}
@@ -2207,6 +2529,9 @@ func (c *ClickHouseX) doQuery(sql string, isShowNull bool) (res []map[string]int
 				line[fields[k]] = values[k]
 			}
 		}
+		if c.job != nil && c.job.Row != nil {
+			c.job.Row(sql, line)
+		}
 		res = append(res, line)
 	}
 	if err = rows.Err(); err != nil {
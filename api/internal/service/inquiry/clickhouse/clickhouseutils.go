@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -17,6 +18,7 @@ import (
 	view2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/utils"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
 )
 
 var regSingleWord = regexp.MustCompile(`([a-z]|[A-Z]|[0-9]|_|-|')+`)
@@ -123,6 +125,75 @@ func TransferGroupTimeField(timeField string, timeFieldTyp int) string {
 	return timeField
 }
 
+// cursorKey returns the order key expressions of the cursors, the time in nanoseconds
+// and a hash of all the columns which orders the logs of the same time.
+func cursorKey(param view2.ReqQuery, orderByField string) (ts, tie string) {
+	tie = "cityHash64(*)"
+	switch {
+	case orderByField == db2.TimeFieldNanoseconds,
+		param.TimeFieldType == db2.TimeFieldTypeDT3,
+		param.TimeFieldType == db2.TimeFieldTypeDT6,
+		param.TimeFieldType == db2.TimeFieldTypeDT9:
+		return fmt.Sprintf("toUnixTimestamp64Nano(%s)", orderByField), tie
+	case param.TimeFieldType == db2.TimeFieldTypeTsMs:
+		return fmt.Sprintf("toInt64(%s) * 1000000", orderByField), tie
+	}
+	return fmt.Sprintf("toInt64(%s) * 1000000000", orderByField), tie
+}
+
+// fieldSummarySQL selects the count of the logs and the summaries of the fields in the columns of factory.FieldStatsColumn.
+func fieldSummarySQL(table, where string, req view2.FieldStatsParams) string {
+	columns := []string{"count(*) AS count"}
+	for idx, f := range req.Fields {
+		columns = append(columns,
+			fmt.Sprintf("uniq(`%s`) AS %s", f.Name, factory.FieldStatsColumn("uniq", idx)),
+			fmt.Sprintf("countIf(isNull(`%s`) OR toString(`%s`) = '') AS %s", f.Name, f.Name, factory.FieldStatsColumn("empty", idx)))
+		if !f.Numeric {
+			continue
+		}
+		v := fmt.Sprintf("toFloat64(`%s`)", f.Name)
+		columns = append(columns,
+			fmt.Sprintf("min(%s) AS %s", v, factory.FieldStatsColumn("min", idx)),
+			fmt.Sprintf("max(%s) AS %s", v, factory.FieldStatsColumn("max", idx)),
+			fmt.Sprintf("avg(%s) AS %s", v, factory.FieldStatsColumn("avg", idx)))
+		for _, p := range factory.FieldStatsPercentiles {
+			columns = append(columns, fmt.Sprintf("quantile(%g)(%s) AS %s", p.Level, v, factory.FieldStatsColumn(p.Name, idx)))
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), table, where)
+}
+
+// fieldTopSQL counts the top values of every field which are not empty at every point of the timeline.
+func fieldTopSQL(param view2.ReqQuery, where string, req view2.FieldStatsParams) string {
+	ts := fmt.Sprintf("intDiv(toUInt32(toDateTime(%s)), %d) * %d", TransferGroupTimeField(param.TimeField, param.TimeFieldType), req.Interval, req.Interval)
+	parts := make([]string, 0, len(req.Fields))
+	for idx, f := range req.Fields {
+		v := fmt.Sprintf("toString(`%s`)", f.Name)
+		parts = append(parts, fmt.Sprintf("SELECT toUInt16(%d) AS idx, %s AS v, %s AS t, count(*) AS count FROM %s WHERE %s AND %s IN "+
+			"(SELECT %s FROM %s WHERE %s AND %s != '' GROUP BY %s ORDER BY count(*) DESC LIMIT %d) GROUP BY v, t",
+			idx, v, ts, param.DatabaseTable, where, v,
+			v, param.DatabaseTable, where, v, v, req.K))
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
+// fieldHistogramSQL counts the values of the numeric fields in the buckets laid out by the summary,
+// it is empty when no histogram is read.
+func fieldHistogramSQL(table, where string, res view2.RespFieldStats) string {
+	parts := make([]string, 0)
+	for idx, f := range res.Fields {
+		lower, width, ok := factory.FieldStatsHistogram(f)
+		if !ok {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("SELECT toUInt16(%d) AS idx, least(greatest(toInt64(floor((toFloat64(`%s`) - (%s)) / %s)), 0), %d) AS b, count(*) AS count "+
+			"FROM %s WHERE %s AND isNotNull(`%s`) GROUP BY b",
+			idx, f.Field, strconv.FormatFloat(lower, 'g', -1, 64), strconv.FormatFloat(width, 'g', -1, 64), len(f.Numeric.Histogram)-1,
+			table, where, f.Field))
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
 func genTimeConditionEqual(param view2.ReqQuery, t time.Time) string {
 	switch param.TimeFieldType {
 	case db2.TimeFieldTypeDT:
@@ -240,6 +311,45 @@ func queryTransformHash(params view2.ReqQuery) string {
 	return query
 }
 
+// lqlDialect writes the query language in ClickHouse SQL.
+type lqlDialect struct{}
+
+func (lqlDialect) Ident(name string) string {
+	return "`" + name + "`"
+}
+
+func (lqlDialect) String(val string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val) + "'"
+}
+
+func (d lqlDialect) Like(ident, pattern string) string {
+	return ident + " LIKE " + d.String(pattern)
+}
+
+// Hash uses the hash functions of the hash columns, the same as hashTransform.
+func (lqlDialect) Hash(hashTyp int, literal string) string {
+	if hashTyp == db2.HashTypeURL {
+		return fmt.Sprintf("URLHash(%s)", literal)
+	}
+	return fmt.Sprintf("sipHash64(%s)", literal)
+}
+
+// queryCompile compiles the query language of params to a WHERE fragment, full text terms
+// match the raw log field and hashed fields are compared by their hash columns when isOptimized.
+func queryCompile(params view2.ReqQuery, table *db2.BaseTable, isOptimized bool) (string, error) {
+	node, err := lql.Parse(params.Query)
+	if err != nil {
+		return "", err
+	}
+	indexes, err := db2.IndexList(egorm.Conds{"tid": params.Tid})
+	if err != nil {
+		return "", err
+	}
+	schema := lql.NewSchema(table, indexes)
+	schema.Hash = isOptimized
+	return lql.Compile(node, schema, lqlDialect{})
+}
+
 func likeTransform(createType int, rawLogField, query string) string {
 	// 判断是否可以进行转换
 	matches := regSingleWord.FindAllString(strings.TrimSpace(query), -1)
@@ -437,3 +547,9 @@ func getDistributedSubTableName(sql string) (string, error) {
 	}
 	return "", errors.New("cannot find distributed sub table")
 }
+
+// newGroupSQL selects a value of the field which occurs at least min times in where and never in baseline.
+func newGroupSQL(param view2.ReqQuery, where, baseline string, min uint64) string {
+	return fmt.Sprintf("SELECT `%s` FROM %s WHERE %s GROUP BY `%s` HAVING count(*) >= %d AND `%s` NOT IN (SELECT `%s` FROM %s WHERE %s) LIMIT 1",
+		param.Field, param.DatabaseTable, where, param.Field, min, param.Field, param.Field, param.DatabaseTable, baseline)
+}
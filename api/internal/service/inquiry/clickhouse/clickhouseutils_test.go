@@ -1,7 +1,11 @@
 package clickhouse
 
 import (
+	"strings"
 	"testing"
+
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	view2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 )
 
 func Test_getDistributedSubTableName(t *testing.T) {
@@ -49,3 +53,74 @@ ENGINE = Distributed('shard2-repl1', 'mogo_shimo_dev', 'shimodev_svc_front_track
 		})
 	}
 }
+
+func Test_lqlDialect(t *testing.T) {
+	d := lqlDialect{}
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{name: "string", got: d.String(`it's \ ok`), want: `'it\'s \\ ok'`},
+		{name: "like", got: d.Like(d.Ident("a.b"), `50\%`), want: "`a.b` LIKE '50\\\\%'"},
+		{name: "siphash", got: d.Hash(1, "'x'"), want: "sipHash64('x')"},
+		{name: "urlhash", got: d.Hash(2, "'x'"), want: "URLHash('x')"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("lqlDialect = %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_fieldStatsSQL(t *testing.T) {
+	req := view2.FieldStatsParams{
+		Fields:   []view2.FieldStatsField{{Name: "level"}, {Name: "cost", Numeric: true}},
+		K:        5,
+		Buckets:  2,
+		Interval: 60,
+	}
+	param := view2.ReqQuery{DatabaseTable: "`db`.`logs`", TimeField: "_time_second_", TimeFieldType: db2.TimeFieldTypeDT}
+	where := "_time_second_ >= toDateTime(1) AND _time_second_ < toDateTime(2) "
+	summary := fieldSummarySQL(param.DatabaseTable, where, req)
+	if want := "SELECT count(*) AS count, uniq(`level`) AS uniq_0, countIf(isNull(`level`) OR toString(`level`) = '') AS empty_0, " +
+		"uniq(`cost`) AS uniq_1, countIf(isNull(`cost`) OR toString(`cost`) = '') AS empty_1, " +
+		"min(toFloat64(`cost`)) AS min_1, max(toFloat64(`cost`)) AS max_1, avg(toFloat64(`cost`)) AS avg_1, " +
+		"quantile(0.5)(toFloat64(`cost`)) AS p50_1, quantile(0.9)(toFloat64(`cost`)) AS p90_1, " +
+		"quantile(0.95)(toFloat64(`cost`)) AS p95_1, quantile(0.99)(toFloat64(`cost`)) AS p99_1 " +
+		"FROM `db`.`logs` WHERE " + where; summary != want {
+		t.Errorf("fieldSummarySQL() = %v, want %v", summary, want)
+	}
+	top := fieldTopSQL(param, where, req)
+	if want := "SELECT toUInt16(0) AS idx, toString(`level`) AS v, intDiv(toUInt32(toDateTime(_time_second_)), 60) * 60 AS t, count(*) AS count " +
+		"FROM `db`.`logs` WHERE " + where + " AND toString(`level`) IN (SELECT toString(`level`) FROM `db`.`logs` WHERE " + where +
+		" AND toString(`level`) != '' GROUP BY toString(`level`) ORDER BY count(*) DESC LIMIT 5) GROUP BY v, t UNION ALL SELECT toUInt16(1) AS idx"; !strings.HasPrefix(top, want) {
+		t.Errorf("fieldTopSQL() = %v, want prefix %v", top, want)
+	}
+	res := view2.RespFieldStats{Fields: []view2.FieldStats{
+		{Field: "level"},
+		{Field: "cost", Numeric: &view2.FieldStatsNumeric{Histogram: []view2.FieldStatsBucket{{Lower: -1.5, Upper: 0.5}, {Lower: 0.5, Upper: 2.5}}}},
+	}}
+	if got, want := fieldHistogramSQL(param.DatabaseTable, where, res), "SELECT toUInt16(1) AS idx, least(greatest(toInt64(floor((toFloat64(`cost`) - (-1.5)) / 2)), 0), 1) AS b, count(*) AS count "+
+		"FROM `db`.`logs` WHERE "+where+" AND isNotNull(`cost`) GROUP BY b"; got != want {
+		t.Errorf("fieldHistogramSQL() = %v, want %v", got, want)
+	}
+	res.Fields[1].Numeric.Histogram = res.Fields[1].Numeric.Histogram[:1]
+	if got := fieldHistogramSQL(param.DatabaseTable, where, res); got != "" {
+		t.Errorf("fieldHistogramSQL() of a single bucket = %v", got)
+	}
+}
+
+func Test_newGroupSQL(t *testing.T) {
+	param := view2.ReqQuery{DatabaseTable: "`db`.`logs`", Field: "msg"}
+	got := newGroupSQL(param, "_time_second_ >= toDateTime(2) AND _time_second_ < toDateTime(3) AND (lv = 'error')",
+		"_time_second_ >= toDateTime(1) AND _time_second_ < toDateTime(2) AND (lv = 'error')", 5)
+	want := "SELECT `msg` FROM `db`.`logs` WHERE _time_second_ >= toDateTime(2) AND _time_second_ < toDateTime(3) AND (lv = 'error') " +
+		"GROUP BY `msg` HAVING count(*) >= 5 AND `msg` NOT IN (SELECT `msg` FROM `db`.`logs` " +
+		"WHERE _time_second_ >= toDateTime(1) AND _time_second_ < toDateTime(2) AND (lv = 'error')) LIMIT 1"
+	if got != want {
+		t.Errorf("newGroupSQL() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,184 @@
+package clickhouse
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/go-faster/city"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// Insert writes the batch with a native batch insert. The retries of a batch are dropped by the tables which
+// deduplicate the inserts, the Replicated tables or the tables with non_replicated_deduplication_window.
+func (c *ClickHouseX) Insert(batch view.IngestBatch) error {
+	ctx := context.Background()
+	if batch.Token != "" && c.hasSetting("insert_deduplication_token") {
+		ctx = clickhouse.Context(ctx, clickhouse.WithSettings(clickhouse.Settings{
+			"insert_deduplication_token": batch.Token,
+		}))
+	}
+	scope, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(err, "begin")
+	}
+	stmt, err := scope.PrepareContext(ctx, insertSQL(batch))
+	if err != nil {
+		_ = scope.Rollback()
+		return errors.Wrap(err, "prepare")
+	}
+	vals := make([]interface{}, len(batch.Columns))
+	for _, row := range batch.Rows {
+		for i, col := range batch.Columns {
+			vals[i] = ingestValue(col, row[i])
+		}
+		if _, err = stmt.ExecContext(ctx, vals...); err != nil {
+			_ = scope.Rollback()
+			return errors.Wrap(err, "append")
+		}
+	}
+	return errors.Wrap(scope.Commit(), "send")
+}
+
+// hasSetting tells whether the server knows the setting, insert_deduplication_token comes with 22.2.
+// The answer is kept by the operator, a failed lookup is tried again by the next call.
+func (c *ClickHouseX) hasSetting(name string) bool {
+	if c.settings != nil {
+		if ok, loaded := c.settings.Load(name); loaded {
+			return ok.(bool)
+		}
+	}
+	var count uint64
+	if err := c.db.QueryRow("SELECT count() FROM system.settings WHERE name = ?", name).Scan(&count); err != nil {
+		return false
+	}
+	if c.settings != nil {
+		c.settings.Store(name, count > 0)
+	}
+	return count > 0
+}
+
+func insertSQL(batch view.IngestBatch) string {
+	columns := make([]string, 0, len(batch.Columns))
+	for _, col := range batch.Columns {
+		columns = append(columns, "`"+col.Name+"`")
+	}
+	return fmt.Sprintf("INSERT INTO `%s`.`%s` (%s)", batch.Database, batch.Table, strings.Join(columns, ", "))
+}
+
+// ingestValue converts the value of the ingestion to the type of the column, the hash columns are computed
+// as sipHash64 and URLHash of the materialized views.
+func ingestValue(col view.IngestColumn, v interface{}) interface{} {
+	switch col.Hash {
+	case db.HashTypeSip:
+		return sipHash64([]byte(cast.ToString(v)))
+	case db.HashTypeURL:
+		return urlHash(cast.ToString(v))
+	}
+	typ, nullable := ingestType(col.Type)
+	if v == nil && nullable {
+		return nil
+	}
+	switch typ {
+	case "String":
+		return cast.ToString(v)
+	case "Int8":
+		return cast.ToInt8(v)
+	case "Int16":
+		return cast.ToInt16(v)
+	case "Int32":
+		return cast.ToInt32(v)
+	case "Int64":
+		return cast.ToInt64(v)
+	case "UInt8":
+		return cast.ToUint8(v)
+	case "UInt16":
+		return cast.ToUint16(v)
+	case "UInt32":
+		return cast.ToUint32(v)
+	case "UInt64":
+		return cast.ToUint64(v)
+	case "Float32":
+		return cast.ToFloat32(v)
+	case "Float64":
+		return cast.ToFloat64(v)
+	}
+	if strings.HasPrefix(typ, "DateTime") {
+		if t, ok := v.(time.Time); ok {
+			return t
+		}
+		return time.Unix(0, 0)
+	}
+	return v
+}
+
+// ingestType returns the type under LowCardinality and Nullable.
+func ingestType(typ string) (string, bool) {
+	typ = strings.TrimSpace(typ)
+	if strings.HasPrefix(typ, "LowCardinality(") {
+		typ = strings.TrimSuffix(strings.TrimPrefix(typ, "LowCardinality("), ")")
+	}
+	if strings.HasPrefix(typ, "Nullable(") {
+		return strings.TrimSuffix(strings.TrimPrefix(typ, "Nullable("), ")"), true
+	}
+	return typ, false
+}
+
+// urlHash is URLHash of ClickHouse, CityHash64 of the url without a trailing /, ? or #.
+func urlHash(s string) uint64 {
+	if n := len(s); n > 0 && (s[n-1] == '/' || s[n-1] == '?' || s[n-1] == '#') {
+		s = s[:n-1]
+	}
+	return city.CH64([]byte(s))
+}
+
+// sipHash64 is sipHash64 of ClickHouse, SipHash-2-4 with the zero key.
+func sipHash64(b []byte) uint64 {
+	return sipHash(0, 0, b)
+}
+
+func sipHash(k0, k1 uint64, b []byte) uint64 {
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13) ^ v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16) ^ v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21) ^ v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17) ^ v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	compress := func(m uint64) {
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+	n := len(b)
+	for ; len(b) >= 8; b = b[8:] {
+		compress(binary.LittleEndian.Uint64(b))
+	}
+	var last [8]byte
+	copy(last[:], b)
+	last[7] = byte(n)
+	compress(binary.LittleEndian.Uint64(last[:]))
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		round()
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
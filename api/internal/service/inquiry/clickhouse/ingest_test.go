@@ -0,0 +1,66 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-faster/city"
+
+	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	view2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func Test_sipHash(t *testing.T) {
+	// the reference vectors of SipHash-2-4 with the key 00 01 02 ... 0f
+	k0, k1 := uint64(0x0706050403020100), uint64(0x0f0e0d0c0b0a0908)
+	tests := []struct {
+		size int
+		want uint64
+	}{
+		{size: 0, want: 0x726fdb47dd0e0e31},
+		{size: 1, want: 0x74f839c593dc67fd},
+		{size: 8, want: 0x93f5f5799a932462},
+	}
+	for _, tt := range tests {
+		msg := make([]byte, tt.size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		if got := sipHash(k0, k1, msg); got != tt.want {
+			t.Errorf("sipHash() of %d bytes = %#x, want %#x", tt.size, got, tt.want)
+		}
+	}
+}
+
+func Test_urlHash(t *testing.T) {
+	want := city.CH64([]byte("https://clickvisual.net/doc"))
+	for _, u := range []string{"https://clickvisual.net/doc", "https://clickvisual.net/doc/", "https://clickvisual.net/doc?"} {
+		if got := urlHash(u); got != want {
+			t.Errorf("urlHash(%s) = %d, want %d", u, got, want)
+		}
+	}
+}
+
+func Test_ingestValue(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		name string
+		col  view2.IngestColumn
+		v    interface{}
+		want interface{}
+	}{
+		{name: "nullable", col: view2.IngestColumn{Type: "Nullable(Int64)"}, v: nil, want: nil},
+		{name: "not nullable", col: view2.IngestColumn{Type: "Int32"}, v: nil, want: int32(0)},
+		{name: "narrowed", col: view2.IngestColumn{Type: "Nullable(UInt16)"}, v: int64(8080), want: uint16(8080)},
+		{name: "low cardinality", col: view2.IngestColumn{Type: "LowCardinality(String)"}, v: "svc", want: "svc"},
+		{name: "time", col: view2.IngestColumn{Type: "DateTime64(9)"}, v: now, want: now},
+		{name: "hash", col: view2.IngestColumn{Type: "UInt64", Hash: db2.HashTypeSip}, v: "", want: sipHash64(nil)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ingestValue(tt.col, tt.v); got != tt.want {
+				t.Errorf("ingestValue() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
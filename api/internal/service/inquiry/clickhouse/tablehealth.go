@@ -0,0 +1,176 @@
+package clickhouse
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// kafkaConsumer is a row of system.kafka_consumers.
+type kafkaConsumer struct {
+	lastPoll       time.Time
+	exceptionTimes []time.Time
+	exceptionTexts []string
+	stat           string // rdkafka_stat, empty when the statistics of librdkafka are disabled
+}
+
+// IngestHealth reads the consumers of the stream table from system.kafka_consumers (23.8+), the exceptions of the
+// materialized views from system.query_views_log and the last part of the data table from system.parts,
+// of all the replicas on clusters.
+func (c *ClickHouseX) IngestHealth(tableInfo *db.BaseTable, since time.Time) (res view.IngestHealthMetrics, err error) {
+	if tableInfo.Database == nil {
+		return res, errors.New("database of the table is not found")
+	}
+	database, cluster := tableInfo.Database.Name, tableInfo.Database.Cluster
+	isCluster, err := c.isCluster(cluster)
+	if err != nil {
+		return
+	}
+	dataTable, streamTable := tableInfo.Name, tableInfo.Name+"_stream"
+	if isCluster == ModeCluster {
+		dataTable, streamTable = tableInfo.Name+"_local", tableInfo.Name+"_local_stream"
+	}
+	res.Consumers, res.ConsumerLag = -1, -1
+	var lastException time.Time
+	if tableInfo.GetReaderType() == db.ReaderTypeKafka && c.hasSystemTable("kafka_consumers") {
+		consumers, errConsumers := c.kafkaConsumers(systemTable("kafka_consumers", isCluster, cluster), database, streamTable)
+		if errConsumers != nil {
+			return res, errors.Wrap(errConsumers, "kafka consumers")
+		}
+		lastException = kafkaConsumersHealth(&res, consumers, since)
+	}
+	if c.hasSystemTable("query_views_log") {
+		var (
+			last      time.Time
+			exception string
+		)
+		if res.ViewExceptions, last, exception, err = c.viewExceptions(systemTable("query_views_log", isCluster, cluster), tableInfo, since); err != nil {
+			return res, errors.Wrap(err, "view exceptions")
+		}
+		if res.ViewExceptions > 0 && last.After(lastException) {
+			res.LastException = exception
+		}
+	}
+	var lastInsert time.Time
+	if err = c.db.QueryRow(fmt.Sprintf("SELECT max(modification_time) FROM %s WHERE database = ? AND table = ? AND active",
+		systemTable("parts", isCluster, cluster)), database, dataTable).Scan(&lastInsert); err != nil {
+		return res, errors.Wrap(err, "parts")
+	}
+	if lastInsert.Unix() > 0 {
+		res.LastInsert = lastInsert
+	}
+	return res, nil
+}
+
+func (c *ClickHouseX) hasSystemTable(name string) bool {
+	var count uint64
+	if err := c.db.QueryRow("SELECT count() FROM system.tables WHERE database = 'system' AND name = ?", name).Scan(&count); err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (c *ClickHouseX) kafkaConsumers(source, database, table string) (res []kafkaConsumer, err error) {
+	rows, err := c.db.Query(fmt.Sprintf("SELECT last_poll_time, exceptions.time, exceptions.text, rdkafka_stat FROM %s WHERE database = ? AND table = ?", source), database, table)
+	if err != nil {
+		return
+	}
+	defer func() { _ = rows.Close() }()
+	for rows.Next() {
+		var consumer kafkaConsumer
+		if err = rows.Scan(&consumer.lastPoll, &consumer.exceptionTimes, &consumer.exceptionTexts, &consumer.stat); err != nil {
+			return
+		}
+		res = append(res, consumer)
+	}
+	return res, rows.Err()
+}
+
+// viewExceptions counts the failed inserts of the materialized views of the table, and returns the last one.
+func (c *ClickHouseX) viewExceptions(source string, tableInfo *db.BaseTable, since time.Time) (count int64, last time.Time, exception string, err error) {
+	views, err := db.ViewList(invoker.Db, egorm.Conds{"tid": tableInfo.ID})
+	if err != nil {
+		return
+	}
+	names := []string{fmt.Sprintf("'%s.%s_view'", tableInfo.Database.Name, tableInfo.Name)}
+	for _, v := range views {
+		names = append(names, fmt.Sprintf("'%s.%s_%s_view'", tableInfo.Database.Name, tableInfo.Name, v.Key))
+	}
+	err = c.db.QueryRow(fmt.Sprintf("SELECT count(), max(event_time), argMax(exception, event_time) FROM %s WHERE view_name IN (%s) AND event_time >= toDateTime(%d) AND exception_code != 0",
+		source, strings.Join(names, ", "), since.Unix())).Scan(&count, &last, &exception)
+	return
+}
+
+// systemTable returns the system table, of all the replicas on clusters.
+func systemTable(name string, isCluster int, cluster string) string {
+	if isCluster == ModeCluster {
+		return fmt.Sprintf("clusterAllReplicas('%s', system.%s)", cluster, name)
+	}
+	return "system." + name
+}
+
+// kafkaConsumersHealth fills the consumers, their lag and the exceptions after since, and returns the time of the last
+// exception. The lag is unknown when no consumer has the statistics of librdkafka.
+// ClickHouse keeps only the last 10 exceptions of a consumer, so the count is a lower bound, and it is not the number
+// of the messages skipped by kafka_skip_broken_messages, which ClickHouse does not expose per table.
+func kafkaConsumersHealth(res *view.IngestHealthMetrics, consumers []kafkaConsumer, since time.Time) (lastException time.Time) {
+	res.Consumers, res.ConsumerLag = len(consumers), -1
+	for _, consumer := range consumers {
+		if consumer.lastPoll.After(res.LastPoll) {
+			res.LastPoll = consumer.lastPoll
+		}
+		for k, t := range consumer.exceptionTimes {
+			if t.Before(since) {
+				continue
+			}
+			res.ConsumerExceptions++
+			if t.After(lastException) && k < len(consumer.exceptionTexts) {
+				lastException = t
+				res.LastException = consumer.exceptionTexts[k]
+			}
+		}
+		if lag, ok := rdKafkaLag(consumer.stat); ok {
+			if res.ConsumerLag < 0 {
+				res.ConsumerLag = 0
+			}
+			res.ConsumerLag += lag
+		}
+	}
+	return
+}
+
+// rdKafkaLag sums the consumer_lag of the partitions in the statistics of librdkafka,
+// the partitions without a known lag and the internal partition -1 are skipped.
+func rdKafkaLag(stat string) (lag int64, ok bool) {
+	if stat == "" {
+		return 0, false
+	}
+	var s struct {
+		Topics map[string]struct {
+			Partitions map[string]struct {
+				ConsumerLag int64 `json:"consumer_lag"`
+			} `json:"partitions"`
+		} `json:"topics"`
+	}
+	if err := json.Unmarshal([]byte(stat), &s); err != nil {
+		return 0, false
+	}
+	for _, topic := range s.Topics {
+		for id, partition := range topic.Partitions {
+			if id == "-1" || partition.ConsumerLag < 0 {
+				continue
+			}
+			lag += partition.ConsumerLag
+			ok = true
+		}
+	}
+	return
+}
@@ -0,0 +1,50 @@
+package clickhouse
+
+import (
+	"testing"
+	"time"
+
+	view2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func Test_rdKafkaLag(t *testing.T) {
+	stat := `{"topics":{"logs":{"partitions":{"0":{"consumer_lag":5},"1":{"consumer_lag":-1},"2":{"consumer_lag":7},"-1":{"consumer_lag":100}}}}}`
+	if lag, ok := rdKafkaLag(stat); !ok || lag != 12 {
+		t.Errorf("rdKafkaLag() = %d, %v, want 12", lag, ok)
+	}
+	for _, stat = range []string{"", "{", `{"topics":{"logs":{"partitions":{"0":{"consumer_lag":-1}}}}}`} {
+		if _, ok := rdKafkaLag(stat); ok {
+			t.Errorf("rdKafkaLag(%s) is known", stat)
+		}
+	}
+}
+
+func Test_kafkaConsumersHealth(t *testing.T) {
+	since := time.Unix(1700000000, 0)
+	consumers := []kafkaConsumer{
+		{
+			lastPoll:       since.Add(time.Minute),
+			exceptionTimes: []time.Time{since.Add(-time.Second), since.Add(time.Second), since.Add(3 * time.Second)},
+			exceptionTexts: []string{"old", "first", "last"},
+			stat:           `{"topics":{"logs":{"partitions":{"0":{"consumer_lag":3}}}}}`,
+		},
+		{
+			lastPoll:       since.Add(2 * time.Minute),
+			exceptionTimes: []time.Time{since.Add(2 * time.Second)},
+			exceptionTexts: []string{"second"},
+		},
+	}
+	var res view2.IngestHealthMetrics
+	last := kafkaConsumersHealth(&res, consumers, since)
+	if res.Consumers != 2 || res.ConsumerLag != 3 || res.ConsumerExceptions != 3 || res.LastException != "last" {
+		t.Errorf("kafkaConsumersHealth() = %+v", res)
+	}
+	if !last.Equal(since.Add(3*time.Second)) || !res.LastPoll.Equal(since.Add(2*time.Minute)) {
+		t.Errorf("kafkaConsumersHealth() last = %v, poll = %v", last, res.LastPoll)
+	}
+	res = view2.IngestHealthMetrics{}
+	kafkaConsumersHealth(&res, consumers[1:], since)
+	if res.ConsumerLag != -1 {
+		t.Errorf("kafkaConsumersHealth() lag without statistics = %d", res.ConsumerLag)
+	}
+}
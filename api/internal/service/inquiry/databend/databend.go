@@ -7,12 +7,14 @@ import (
 	"reflect"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/ego-component/egorm"
 	"github.com/gotomicro/ego/core/econf"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/pkg/errors"
+	"github.com/spf13/cast"
 
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	constx2 "github.com/clickvisual/clickvisual/api/internal/pkg/constx"
@@ -24,15 +26,24 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/bumo"
 	standalone2 "github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builder/standalone"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builderv2"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
 )
 
 var _ factory.Operator = (*Databend)(nil)
+var _ factory.JobOperator = (*Databend)(nil)
+var _ factory.Exporter = (*Databend)(nil)
+var _ factory.FieldStatsReader = (*Databend)(nil)
+var _ factory.SQLBuilder = (*Databend)(nil)
+var _ factory.Inserter = (*Databend)(nil)
+var _ factory.NewGroupReader = (*Databend)(nil)
 
 type Databend struct {
-	id   int
-	mode int
-	rs   int // replica status
-	db   *sql.DB
+	id       int
+	mode     int
+	rs       int // replica status
+	db       *sql.DB
+	job      *factory.Job
+	settings *sync.Map // setting name -> known by the server, see hasSetting
 }
 
 func (c *Databend) ClusterInfo() (clusters map[string]dto.ClusterInfo, err error) {
@@ -61,10 +72,11 @@ func NewDatabend(db *sql.DB, ins *db2.BaseInstance) (*Databend, error) {
 		return nil, errors.New("databend add err, id is 0")
 	}
 	return &Databend{
-		db:   db,
-		id:   ins.ID,
-		mode: ins.Mode,
-		rs:   ins.ReplicaStatus,
+		db:       db,
+		id:       ins.ID,
+		mode:     ins.Mode,
+		rs:       ins.ReplicaStatus,
+		settings: &sync.Map{},
 	}, nil
 }
 
@@ -72,6 +84,16 @@ func (c *Databend) Conn() *sql.DB {
 	return c.db
 }
 
+func (c *Databend) ExportLogs(ctx context.Context, param view2.ReqQuery, tid int, limit int, w factory.RowWriter) error {
+	q := c.exportSQL(param, tid, limit)
+	rows, err := c.db.QueryContext(ctx, q)
+	if err != nil {
+		return errors.Wrap(err, q)
+	}
+	defer func() { _ = rows.Close() }()
+	return errors.Wrap(factory.ScanRows(rows, limit, w), q)
+}
+
 func (c *Databend) Chart(param view2.ReqQuery) (res []*view2.HighChart, q string, err error) {
 	q = c.chartSQL(param)
 	charts, err := c.doQuery(q)
@@ -153,37 +175,46 @@ func (c *Databend) GroupBy(param view2.ReqQuery) (res map[string]uint64) {
 	return
 }
 
-// CreateKafkaTable Drop and Create
-func (c *Databend) CreateKafkaTable(tableInfo *db2.BaseTable, params view2.ReqStorageUpdate) (streamSQL string, err error) {
-	currentKafkaSQL := tableInfo.SqlStream
-	// Drop TableName
-	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s%s",
-		genStreamNameWithMode(c.mode, tableInfo.Database.Name, tableInfo.Name),
-		genSQLClusterInfo(c.mode, tableInfo.Database.Cluster))
-	if _, err = c.db.Exec(dropSQL); err != nil {
-		elog.Error("CreateKafkaTable", elog.Any("dropSQL", dropSQL), elog.Any("err", err.Error()))
-		return
+// FieldStats computes the statistics of the fields over the logs of the query. The summaries of all the fields
+// are read in one query, the top values with their time series in another and the histograms of the numeric fields in a third.
+func (c *Databend) FieldStats(param view2.ReqQuery, req view2.FieldStatsParams) (res view2.RespFieldStats, err error) {
+	res = factory.NewFieldStats(param, req)
+	where := fmt.Sprintf(genDatabendTimeCondition(param), param.ST, param.ET) + " " + c.queryTransform(param, true)
+	rows, err := c.doQuery(fieldSummarySQL(param.DatabaseTable, where, req))
+	if err != nil {
+		return res, err
 	}
-	// Create TableName
-	streamParams := bumo.Params{
-		TableCreateType: tableInfo.CreateType,
-		Stream: bumo.ParamsStream{
-			TableName:               genStreamNameWithMode(c.mode, tableInfo.Database.Name, tableInfo.Name),
-			TableTyp:                tableTypStr(tableInfo.TimeFieldKind),
-			Group:                   tableInfo.Database.Name + "_" + tableInfo.Name,
-			Brokers:                 params.KafkaBrokers,
-			Topic:                   params.KafkaTopic,
-			ConsumerNum:             params.KafkaConsumerNum,
-			KafkaSkipBrokenMessages: params.KafkaSkipBrokenMessages,
-		},
+	if len(rows) > 0 {
+		factory.SetFieldStatsSummary(&res, rows[0], req.Buckets)
 	}
-	streamSQL = builder.Do(new(standalone2.StreamBuilder), streamParams)
-	if _, err = c.db.Exec(streamSQL); err != nil {
-		elog.Error("CreateKafkaTable", elog.Any("streamSQL", streamSQL), elog.Any("err", err.Error()))
-		_, _ = c.db.Exec(currentKafkaSQL)
-		return
+	if res.Total == 0 {
+		return res, nil
 	}
-	return
+	rows, err = c.doQuery(fieldTopSQL(param, where, req))
+	if err != nil {
+		return res, err
+	}
+	factory.SetFieldStatsTop(&res, rows)
+	if q := fieldHistogramSQL(param.DatabaseTable, where, res); q != "" {
+		if rows, err = c.doQuery(q); err != nil {
+			return res, err
+		}
+		factory.SetFieldStatsHistogram(&res, rows)
+	}
+	return res, nil
+}
+
+// HasNewGroup reports whether a value of the field occurs at least min times in the time range of the param
+// and not in [baselineST, param.ST), the values of both ranges are compared by one statement.
+func (c *Databend) HasNewGroup(param view2.ReqQuery, baselineST int64, min uint64) (bool, error) {
+	query := c.queryTransform(param, true)
+	where := fmt.Sprintf(genDatabendTimeCondition(param), param.ST, param.ET) + " " + query
+	baseline := fmt.Sprintf(genDatabendTimeCondition(param), baselineST, param.ST) + " " + query
+	rows, err := c.doQuery(newGroupSQL(param, where, baseline, min))
+	if err != nil {
+		return false, err
+	}
+	return len(rows) > 0, nil
 }
 
 func (c *Databend) CreateTraceJaegerDependencies(database, cluster, table string, ttl int) (err error) {
@@ -213,6 +244,9 @@ func (c *Databend) CreateTraceJaegerDependencies(database, cluster, table string
 
 // CreateStorage create default stream data table and view
 func (c *Databend) CreateStorage(did int, database db2.BaseDatabase, ct view2.ReqStorageCreate) (dStreamSQL, dDataSQL, dViewSQL, dDistributedSQL string, err error) {
+	if ct.GetReaderType() != db2.ReaderTypeKafka {
+		return "", "", "", "", errors.Errorf("databend does not support reader type %s", ct.GetReaderType())
+	}
 	dName := genNameWithMode(c.mode, database.Name, ct.TableName)
 	dStreamName := genStreamNameWithMode(c.mode, database.Name, ct.TableName)
 	// build view statement
@@ -369,6 +403,42 @@ func (c *Databend) UpdateMergeTreeTable(tableInfo *db2.BaseTable, params view2.R
 	return
 }
 
+// UpdateReader Drop and Create, databend only reads kafka
+func (c *Databend) UpdateReader(tableInfo *db2.BaseTable, params view2.ReqStorageUpdate) (streamSQL string, err error) {
+	if params.GetReaderType() != db2.ReaderTypeKafka {
+		return "", errors.Errorf("databend does not support reader type %s", params.GetReaderType())
+	}
+	currentKafkaSQL := tableInfo.SqlStream
+	// Drop TableName
+	dropSQL := fmt.Sprintf("DROP TABLE IF EXISTS %s%s",
+		genStreamNameWithMode(c.mode, tableInfo.Database.Name, tableInfo.Name),
+		genSQLClusterInfo(c.mode, tableInfo.Database.Cluster))
+	if _, err = c.db.Exec(dropSQL); err != nil {
+		elog.Error("UpdateReader", elog.Any("dropSQL", dropSQL), elog.Any("err", err.Error()))
+		return
+	}
+	// Create TableName
+	streamParams := bumo.Params{
+		TableCreateType: tableInfo.CreateType,
+		Stream: bumo.ParamsStream{
+			TableName:               genStreamNameWithMode(c.mode, tableInfo.Database.Name, tableInfo.Name),
+			TableTyp:                tableTypStr(tableInfo.TimeFieldKind),
+			Group:                   tableInfo.Database.Name + "_" + tableInfo.Name,
+			Brokers:                 params.KafkaBrokers,
+			Topic:                   params.KafkaTopic,
+			ConsumerNum:             params.KafkaConsumerNum,
+			KafkaSkipBrokenMessages: params.KafkaSkipBrokenMessages,
+		},
+	}
+	streamSQL = builder.Do(new(standalone2.StreamBuilder), streamParams)
+	if _, err = c.db.Exec(streamSQL); err != nil {
+		elog.Error("UpdateReader", elog.Any("streamSQL", streamSQL), elog.Any("err", err.Error()))
+		_, _ = c.db.Exec(currentKafkaSQL)
+		return
+	}
+	return
+}
+
 func (c *Databend) GetLogs(param view2.ReqQuery, tid int) (res view2.RespQuery, err error) {
 	res.Logs = make([]map[string]interface{}, 0)
 	res.Keys = make([]*db2.BaseIndex, 0)
@@ -377,6 +447,7 @@ func (c *Databend) GetLogs(param view2.ReqQuery, tid int) (res view2.RespQuery,
 		defaultSQL    string
 		originalWhere string
 		optimizeSQL   string
+		withCursor    bool
 	)
 	switch param.AlarmMode {
 	case db2.AlarmModeAggregation:
@@ -384,7 +455,13 @@ func (c *Databend) GetLogs(param view2.ReqQuery, tid int) (res view2.RespQuery,
 	case db2.AlarmModeAggregationCheck:
 		defaultSQL = alarmAggregationSQLWith(param)
 	default:
+		if param.Cursor != "" {
+			if param.Cursor, err = c.turnCursor(param, tid); err != nil {
+				return
+			}
+		}
 		defaultSQL, optimizeSQL, originalWhere = c.logsSQL(param, tid)
+		withCursor = param.Cursor != "" || param.Direction != ""
 	}
 	var execSQL = defaultSQL
 	if optimizeSQL != "" {
@@ -394,6 +471,12 @@ func (c *Databend) GetLogs(param view2.ReqQuery, tid int) (res view2.RespQuery,
 	if err != nil {
 		return
 	}
+	if withCursor {
+		res.Cursors = factory.TakeCursors(res.Logs, param.Cursor, param.Direction)
+		if len(res.Cursors) > 0 {
+			res.Newer, res.Older = res.Cursors[0], res.Cursors[len(res.Cursors)-1]
+		}
+	}
 	// try again
 	res.Query = defaultSQL
 	res.Where = strings.TrimSuffix(strings.TrimPrefix(originalWhere, "AND ("), ")")
@@ -455,28 +538,51 @@ func (c *Databend) logsSQL(param view2.ReqQuery, tid int) (sql, optSQL, original
 	if len(views) > 0 {
 		orderByField = db2.TimeFieldNanoseconds
 	}
-	selectFields := genSelectFields(tid)
+	selectFields, orderBy := genSelectFields(tid), orderByField+" DESC"
+	table, _ := db2.TableInfo(invoker.Db, tid)
+	tsExpr, tieExpr := cursorKey(param, &table, orderByField)
+	if param.Cursor != "" || param.Direction != "" {
+		// the order key is selected for the cursors, the row hash orders the logs of the same time
+		selectFields = fmt.Sprintf("%s, %s AS %s, %s AS %s", selectFields, tsExpr, factory.CursorTsField, tieExpr, factory.CursorTieField)
+		orderBy = factory.CursorOrder(orderByField, param.Direction)
+	}
 	c2 := time.Since(st).Milliseconds()
+	originalWhere = c.queryTransform(param, false)
+	if param.Cursor != "" {
+		// search after the cursor, the cursor is validated by Prepare
+		cur, _ := factory.ParseCursor(param.Cursor)
+		param.ST, param.ET = factory.CursorRange(param.ST, param.ET, cur, param.Direction)
+		sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genDatabendTimeCondition(param)+" %s %s ORDER BY %s LIMIT %d OFFSET %d",
+			selectFields,
+			param.DatabaseTable,
+			param.ST, param.ET,
+			factory.CursorCondition(tsExpr, tieExpr, cur, param.Direction),
+			originalWhere,
+			orderBy,
+			param.PageSize, cur.Seen)
+		return
+	}
 	// Request for the first 100 pages of data
 	// optimizing, the idea is to reduce the number of fields involved in operation;
 	if param.Page*param.PageSize <= 100 {
 		timeFieldEqual := c.timeFieldEqual(param, tid)
 		if timeFieldEqual != "" {
-			optSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s %s ORDER BY "+orderByField+" DESC LIMIT %d OFFSET %d",
+			optSQL = fmt.Sprintf("SELECT %s FROM %s WHERE %s %s ORDER BY %s LIMIT %d OFFSET %d",
 				selectFields,
 				param.DatabaseTable,
 				timeFieldEqual,
 				c.queryTransform(param, true),
+				orderBy,
 				param.PageSize, (param.Page-1)*param.PageSize)
 		}
 	}
 	c3 := time.Since(st).Milliseconds()
-	originalWhere = c.queryTransform(param, false)
-	sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genDatabendTimeCondition(param)+" %s ORDER BY "+orderByField+" DESC LIMIT %d OFFSET %d",
+	sql = fmt.Sprintf("SELECT %s FROM %s WHERE "+genDatabendTimeCondition(param)+" %s ORDER BY %s LIMIT %d OFFSET %d",
 		selectFields,
 		param.DatabaseTable,
 		param.ST, param.ET,
 		originalWhere,
+		orderBy,
 		param.PageSize, (param.Page-1)*param.PageSize)
 	c4 := time.Since(st).Milliseconds()
 	elog.Debug("logsTimelineSQL",
@@ -737,8 +843,8 @@ func (c *Databend) Prepare(res view2.ReqQuery, table *db2.BaseTable, isFilter bo
 	if res.PageSize <= 0 {
 		res.PageSize = 20
 	}
-	if res.Query == "" {
-		res.Query = defaultDatabendCondition
+	if err := factory.ValidCursor(res.Cursor, res.Direction); err != nil {
+		return res, err
 	}
 	if res.ET == res.ST && res.ST != 0 {
 		res.ET = res.ST + 1
@@ -751,6 +857,15 @@ func (c *Databend) Prepare(res view2.ReqQuery, table *db2.BaseTable, isFilter bo
 		res.ST = time.Now().Add(-time.Minute * 15).Unix()
 		res.ET = time.Now().Unix()
 	}
+	if res.QueryMode == view2.QueryModeLanguage {
+		// filters are expressions of the query language too
+		res.Query = lql.Join(append([]string{res.Query}, res.Filters...)...)
+		_, err := queryCompile(res, table, false)
+		return res, err
+	}
+	if res.Query == "" {
+		res.Query = defaultDatabendCondition
+	}
 	for _, filter := range res.Filters {
 		res.Query = fmt.Sprintf("%s and %s", res.Query, filter)
 	}
@@ -831,11 +946,12 @@ func (c *Databend) GetAlertViewSQL(alarm *db2.Alarm, tableInfo db2.BaseTable, fi
 
 	sourceTableName = fmt.Sprintf("`%s`.`%s`", tableInfo.Database.Name, tableName)
 	vp := bumo.ParamsView{
-		ViewType:     bumo.ViewTypePrometheusMetric,
-		ViewTable:    viewTableName,
-		CommonFields: factory.TagsToString(alarm, true, filterId),
-		SourceTable:  sourceTableName,
-		Where:        filter.When,
+		ViewType:       bumo.ViewTypePrometheusMetric,
+		ViewTable:      viewTableName,
+		CommonFields:   factory.TagsToString(alarm, true, filterId),
+		SourceTable:    sourceTableName,
+		Where:          filter.When,
+		SignatureField: filter.SignatureField,
 	}
 	if filter.Mode == db2.AlarmModeAggregation || filter.Mode == db2.AlarmModeAggregationCheck {
 		vp.ViewType = bumo.ViewTypePrometheusMetricAggregation
@@ -925,9 +1041,35 @@ func (c *Databend) DoSQL(sql string) (res view2.RespComplete, err error) {
 	return
 }
 
+// WithJob returns a copy of the operator whose queries run in the context and the time limit of the job,
+// databend reports no progress so only the rows are watched.
+func (c *Databend) WithJob(job factory.Job) factory.Operator {
+	return &Databend{id: c.id, mode: c.mode, rs: c.rs, db: c.db, job: &job, settings: c.settings}
+}
+
+// KillQuery does nothing, the statements of a job are cancelled by its context.
+func (c *Databend) KillQuery(string) error {
+	return nil
+}
+
+// query returns the rows and the cancel func of the statement context.
+func (c *Databend) query(sql string) (*sql.Rows, context.CancelFunc, error) {
+	if c.job == nil {
+		rows, err := c.db.Query(sql)
+		return rows, func() {}, err
+	}
+	ctx, cancel := c.job.Ctx, context.CancelFunc(func() {})
+	if c.job.MaxExecutionTime > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(c.job.MaxExecutionTime)*time.Second)
+	}
+	rows, err := c.db.QueryContext(ctx, sql)
+	return rows, cancel, err
+}
+
 func (c *Databend) doQuery(sql string) (res []map[string]interface{}, err error) {
 	res = make([]map[string]interface{}, 0)
-	rows, err := c.db.Query(sql)
+	rows, cancel, err := c.query(sql)
+	defer cancel()
 	if err != nil {
 		return res, errors.Wrap(err, sql)
 	}
@@ -956,6 +1098,9 @@ func (c *Databend) doQuery(sql string) (res []map[string]interface{}, err error)
 				line[fields[k]] = values[k]
 			}
 		}
+		if c.job != nil && c.job.Row != nil {
+			c.job.Row(sql, line)
+		}
 		res = append(res, line)
 	}
 	if err = rows.Err(); err != nil {
@@ -1013,7 +1158,65 @@ func (c *Databend) logsTimelineSQL(param view2.ReqQuery, tid int) (sql string) {
 	return
 }
 
+// turnCursor returns the cursor of the page in the order of the direction,
+// the logs of the key of a turned cursor are counted to turn it.
+func (c *Databend) turnCursor(param view2.ReqQuery, tid int) (string, error) {
+	cur, _ := factory.ParseCursor(param.Cursor)
+	if !cur.Turned(param.Direction) {
+		return param.Cursor, nil
+	}
+	orderByField := param.TimeField
+	if views, _ := db2.ViewList(invoker.Db, egorm.Conds{"tid": tid}); len(views) > 0 {
+		orderByField = db2.TimeFieldNanoseconds
+	}
+	table, _ := db2.TableInfo(invoker.Db, tid)
+	tsExpr, tieExpr := cursorKey(param, &table, orderByField)
+	param.ST, param.ET = cur.Ts/1e9, cur.Ts/1e9+1
+	q := fmt.Sprintf("SELECT count(*) as count FROM %s WHERE "+genDatabendTimeCondition(param)+" %s %s",
+		param.DatabaseTable,
+		param.ST, param.ET,
+		factory.CursorKeyCondition(tsExpr, tieExpr, cur),
+		c.queryTransform(param, false))
+	res, err := c.doQuery(q)
+	if err != nil {
+		return "", err
+	}
+	count := 0
+	if len(res) > 0 {
+		count = cast.ToInt(res[0]["count"])
+	}
+	return cur.Turn(count).String(), nil
+}
+
+// exportSQL selects the logs of the query without the cursor keys and the pages.
+func (c *Databend) exportSQL(param view2.ReqQuery, tid int, limit int) string {
+	orderByField := param.TimeField
+	if views, _ := db2.ViewList(invoker.Db, egorm.Conds{"tid": tid}); len(views) > 0 {
+		orderByField = db2.TimeFieldNanoseconds
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE "+genDatabendTimeCondition(param)+" %s ORDER BY %s DESC LIMIT %d",
+		genSelectFields(tid),
+		param.DatabaseTable,
+		param.ST, param.ET,
+		c.queryTransform(param, false),
+		orderByField,
+		limit)
+}
+
 func (c *Databend) queryTransform(params view2.ReqQuery, isOptimized bool) string {
+	if params.QueryMode == view2.QueryModeLanguage {
+		table, _ := db2.TableInfo(invoker.Db, params.Tid)
+		query, err := queryCompile(params, &table, isOptimized)
+		if err != nil {
+			// the query is validated by Prepare, an invalid query matches nothing
+			elog.Error("queryTransform", elog.String("query", params.Query), elog.FieldErr(err))
+			return "AND 1=0"
+		}
+		if query == "" {
+			return query
+		}
+		return fmt.Sprintf("AND (%s)", query)
+	}
 	if isOptimized {
 		params.Query = queryTransformHash(params) // hash transform
 	}
@@ -1025,6 +1228,53 @@ func (c *Databend) queryTransform(params view2.ReqQuery, isOptimized bool) strin
 	return fmt.Sprintf("AND (%s)", query)
 }
 
+// ChartSQL is the statement of Chart, the key of its cached results.
+func (c *Databend) ChartSQL(param view2.ReqQuery) string {
+	return c.chartSQL(param)
+}
+
+// CountSQL is the statement of Count, the key of its cached results.
+func (c *Databend) CountSQL(param view2.ReqQuery) string {
+	return c.countSQL(param)
+}
+
+// Insert writes the batch with an INSERT per insertChunkRows rows. Each INSERT is labelled with the token of the
+// batch and the position of its rows, so the rows of a retried batch which were written are dropped by Databend.
+// The batches with a token are refused by the servers without deduplicate_label.
+func (c *Databend) Insert(batch view2.IngestBatch) error {
+	if batch.Token != "" && !c.hasSetting("deduplicate_label") {
+		return errors.New("the server does not support deduplicate_label, the retries of the batch would be inserted twice")
+	}
+	for i, chunk := range insertChunks(batch, insertChunkRows) {
+		label := ""
+		if batch.Token != "" {
+			label = fmt.Sprintf("%s-%d", batch.Token, i)
+		}
+		if _, err := c.db.Exec(insertSQL(chunk, label)); err != nil {
+			return errors.Wrapf(err, "rows %d", i*insertChunkRows)
+		}
+	}
+	return nil
+}
+
+// hasSetting tells whether the server knows the setting, the answer is kept by the operator,
+// a failed lookup is tried again by the next call.
+func (c *Databend) hasSetting(name string) bool {
+	if c.settings != nil {
+		if ok, loaded := c.settings.Load(name); loaded {
+			return ok.(bool)
+		}
+	}
+	var count uint64
+	if err := c.db.QueryRow("SELECT count(*) FROM system.settings WHERE name = ?", name).Scan(&count); err != nil {
+		return false
+	}
+	if c.settings != nil {
+		c.settings.Store(name, count > 0)
+	}
+	return count > 0
+}
+
 func (c *Databend) countSQL(param view2.ReqQuery) (sql string) {
 	sql = fmt.Sprintf("SELECT count(*) as count FROM %s WHERE "+genDatabendTimeCondition(param)+" %s",
 		param.DatabaseTable,
@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"reflect"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ego-component/egorm"
 	"github.com/gotomicro/ego/core/elog"
+	"github.com/spf13/cast"
 
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
 	constx2 "github.com/clickvisual/clickvisual/api/internal/pkg/constx"
@@ -16,12 +18,130 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/utils"
 	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
 )
 
 func genDatabendTimeCondition(param view.ReqQuery) string {
 	return fmt.Sprintf("%s >= to_timestamp(%s) AND %s < to_timestamp(%s)", param.TimeField, "%d", param.TimeField, "%d")
 }
 
+// fieldSummarySQL selects the count of the logs and the summaries of the fields in the columns of factory.FieldStatsColumn.
+func fieldSummarySQL(table, where string, req view.FieldStatsParams) string {
+	columns := []string{"count(*) AS count"}
+	for idx, f := range req.Fields {
+		columns = append(columns,
+			fmt.Sprintf("approx_count_distinct(`%s`) AS %s", f.Name, factory.FieldStatsColumn("uniq", idx)),
+			fmt.Sprintf("count_if(`%s` IS NULL OR CAST(`%s` AS VARCHAR) = '') AS %s", f.Name, f.Name, factory.FieldStatsColumn("empty", idx)))
+		if !f.Numeric {
+			continue
+		}
+		v := fmt.Sprintf("CAST(`%s` AS DOUBLE)", f.Name)
+		columns = append(columns,
+			fmt.Sprintf("min(%s) AS %s", v, factory.FieldStatsColumn("min", idx)),
+			fmt.Sprintf("max(%s) AS %s", v, factory.FieldStatsColumn("max", idx)),
+			fmt.Sprintf("avg(%s) AS %s", v, factory.FieldStatsColumn("avg", idx)))
+		for _, p := range factory.FieldStatsPercentiles {
+			columns = append(columns, fmt.Sprintf("quantile_cont(%g)(%s) AS %s", p.Level, v, factory.FieldStatsColumn(p.Name, idx)))
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), table, where)
+}
+
+// fieldTopSQL counts the top values of every field which are not empty at every point of the timeline.
+func fieldTopSQL(param view.ReqQuery, where string, req view.FieldStatsParams) string {
+	ts := fmt.Sprintf("(to_unix_timestamp(to_timestamp(%s)) DIV %d) * %d", param.TimeField, req.Interval, req.Interval)
+	parts := make([]string, 0, len(req.Fields))
+	for idx, f := range req.Fields {
+		v := fmt.Sprintf("CAST(`%s` AS VARCHAR)", f.Name)
+		parts = append(parts, fmt.Sprintf("SELECT CAST(%d AS UINT16) AS idx, %s AS v, %s AS t, count(*) AS count FROM %s WHERE %s AND %s IN "+
+			"(SELECT %s FROM %s WHERE %s AND %s != '' GROUP BY %s ORDER BY count(*) DESC LIMIT %d) GROUP BY v, t",
+			idx, v, ts, param.DatabaseTable, where, v,
+			v, param.DatabaseTable, where, v, v, req.K))
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
+// fieldHistogramSQL counts the values of the numeric fields in the buckets laid out by the summary,
+// it is empty when no histogram is read.
+func fieldHistogramSQL(table, where string, res view.RespFieldStats) string {
+	parts := make([]string, 0)
+	for idx, f := range res.Fields {
+		lower, width, ok := factory.FieldStatsHistogram(f)
+		if !ok {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("SELECT CAST(%d AS UINT16) AS idx, least(greatest(CAST(floor((CAST(`%s` AS DOUBLE) - (%s)) / %s) AS BIGINT), 0), %d) AS b, count(*) AS count "+
+			"FROM %s WHERE %s AND `%s` IS NOT NULL GROUP BY b",
+			idx, f.Field, strconv.FormatFloat(lower, 'g', -1, 64), strconv.FormatFloat(width, 'g', -1, 64), len(f.Numeric.Histogram)-1,
+			table, where, f.Field))
+	}
+	return strings.Join(parts, " UNION ALL ")
+}
+
+// insertChunkRows is the maximum number of rows of an INSERT of Insert.
+const insertChunkRows = 1000
+
+// insertChunks splits the rows of the batch in chunks of n rows, the chunks of a batch are the same on every retry.
+func insertChunks(batch view.IngestBatch, n int) []view.IngestBatch {
+	res := make([]view.IngestBatch, 0, (len(batch.Rows)+n-1)/n)
+	for i := 0; i < len(batch.Rows); i += n {
+		chunk := batch
+		chunk.Rows = batch.Rows[i:min(i+n, len(batch.Rows))]
+		res = append(res, chunk)
+	}
+	return res
+}
+
+// insertSQL inserts the rows of the batch, the hash columns are computed by siphash64 as the materialized views.
+// A label makes the insert idempotent, Databend drops the inserts with a label it has written.
+func insertSQL(batch view.IngestBatch, label string) string {
+	columns := make([]string, 0, len(batch.Columns))
+	for _, col := range batch.Columns {
+		columns = append(columns, "`"+col.Name+"`")
+	}
+	rows := make([]string, 0, len(batch.Rows))
+	vals := make([]string, len(batch.Columns))
+	for _, row := range batch.Rows {
+		for i, col := range batch.Columns {
+			vals[i] = ingestLiteral(col, row[i])
+		}
+		rows = append(rows, "("+strings.Join(vals, ", ")+")")
+	}
+	hint := ""
+	if label != "" {
+		hint = fmt.Sprintf("/*+ SET_VAR(deduplicate_label=%s) */ ", quoteLiteral(label))
+	}
+	return fmt.Sprintf("INSERT %sINTO `%s`.`%s` (%s) VALUES %s", hint, batch.Database, batch.Table, strings.Join(columns, ", "), strings.Join(rows, ", "))
+}
+
+// ingestLiteral returns the literal of the value in the type of the column, as Nullable(String) or VARCHAR NULL.
+func ingestLiteral(col view.IngestColumn, v interface{}) string {
+	if col.Hash != 0 {
+		return fmt.Sprintf("siphash64(%s)", quoteLiteral(cast.ToString(v)))
+	}
+	typ := strings.ToUpper(col.Type)
+	if v == nil && (strings.HasPrefix(typ, "NULLABLE(") || (strings.HasSuffix(typ, " NULL") && !strings.HasSuffix(typ, "NOT NULL"))) {
+		return "NULL"
+	}
+	switch {
+	case strings.Contains(typ, "INT"):
+		return strconv.FormatInt(cast.ToInt64(v), 10)
+	case strings.Contains(typ, "FLOAT") || strings.Contains(typ, "DOUBLE"):
+		return strconv.FormatFloat(cast.ToFloat64(v), 'g', -1, 64)
+	case strings.Contains(typ, "TIMESTAMP") || strings.Contains(typ, "DATETIME"):
+		t, ok := v.(time.Time)
+		if !ok {
+			t = time.Unix(0, 0)
+		}
+		return quoteLiteral(t.UTC().Format("2006-01-02 15:04:05.999999999"))
+	}
+	return quoteLiteral(cast.ToString(v))
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
 func genDatabendTimeConditionEqual(param view.ReqQuery, t time.Time) string {
 	switch param.TimeFieldType {
 	case db.TimeFieldTypeDT:
@@ -190,6 +310,25 @@ func adaSelectPart(in string) (out string) {
 	return fmt.Sprintf("%s,%s FROM %s", arr[0], onlySelect, arr[1])
 }
 
+// cursorKey returns the order key expressions of the cursors, the time in nanoseconds and a hash
+// of the raw log which orders the logs of the same time. Databend can not hash all the columns,
+// the logs of a table without raw log field share the tie and are paged by the Seen count of the cursors.
+func cursorKey(param view.ReqQuery, table *db.BaseTable, orderByField string) (ts, tie string) {
+	rawLogField := "_raw_log_"
+	if table.CreateType == constx2.TableCreateTypeExist {
+		rawLogField = table.RawLogField
+	}
+	tie = "to_uint64(0)"
+	if rawLogField != "" {
+		tie = fmt.Sprintf("xxhash64(to_string(%s))", rawLogField)
+	}
+	if param.TimeFieldType == db.TimeFieldTypeTsMs && orderByField != db.TimeFieldNanoseconds {
+		return fmt.Sprintf("to_int64(%s) * 1000000", orderByField), tie
+	}
+	// timestamps are microseconds
+	return fmt.Sprintf("to_int64(%s) * 1000", orderByField), tie
+}
+
 func genSelectFields(tid int) string {
 	tableInfo, _ := db.TableInfo(invoker.Db, tid)
 	if tableInfo.CreateType == constx2.TableCreateTypeCV {
@@ -251,6 +390,45 @@ func queryTransformHash(params view.ReqQuery) string {
 	return query
 }
 
+// lqlDialect writes the query language in Databend SQL.
+type lqlDialect struct{}
+
+func (lqlDialect) Ident(name string) string {
+	return "`" + name + "`"
+}
+
+func (lqlDialect) String(val string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val) + "'"
+}
+
+func (d lqlDialect) Like(ident, pattern string) string {
+	return ident + " LIKE " + d.String(pattern)
+}
+
+// Hash uses the hash functions of the hash columns, the same as hashTransform.
+func (lqlDialect) Hash(hashTyp int, literal string) string {
+	if hashTyp == db.HashTypeURL {
+		return fmt.Sprintf("URLHash(%s)", literal)
+	}
+	return fmt.Sprintf("sipHash64(%s)", literal)
+}
+
+// queryCompile compiles the query language of params to a WHERE fragment, full text terms
+// match the raw log field and hashed fields are compared by their hash columns when isOptimized.
+func queryCompile(params view.ReqQuery, table *db.BaseTable, isOptimized bool) (string, error) {
+	node, err := lql.Parse(params.Query)
+	if err != nil {
+		return "", err
+	}
+	indexes, err := db.IndexList(egorm.Conds{"tid": params.Tid})
+	if err != nil {
+		return "", err
+	}
+	schema := lql.NewSchema(table, indexes)
+	schema.Hash = isOptimized
+	return lql.Compile(node, schema, lqlDialect{})
+}
+
 func likeTransform(createType int, rawLogField, query string) string {
 	// 判断是否可以进行转换
 	matches := regSingleWord.FindAllString(strings.TrimSpace(query), -1)
@@ -448,3 +626,9 @@ func queryEncodeOperation(a string, op string, res *[]queryItem) error {
 // 	}
 // 	return "", errors.New("cannot find distributed sub table")
 // }
+
+// newGroupSQL selects a value of the field which occurs at least min times in where and never in baseline.
+func newGroupSQL(param view.ReqQuery, where, baseline string, min uint64) string {
+	return fmt.Sprintf("SELECT `%s` FROM %s WHERE %s GROUP BY `%s` HAVING count(*) >= %d AND `%s` NOT IN (SELECT `%s` FROM %s WHERE %s) LIMIT 1",
+		param.Field, param.DatabaseTable, where, param.Field, min, param.Field, param.Field, param.DatabaseTable, baseline)
+}
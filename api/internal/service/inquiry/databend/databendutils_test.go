@@ -0,0 +1,33 @@
+package databend
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func Test_insertChunks(t *testing.T) {
+	batch := view.IngestBatch{
+		Database: "logs",
+		Table:    "app",
+		Columns:  []view.IngestColumn{{Name: "msg", Type: "VARCHAR"}},
+	}
+	for i := 0; i < 5; i++ {
+		batch.Rows = append(batch.Rows, []interface{}{"m"})
+	}
+	chunks := insertChunks(batch, 2)
+	if len(chunks) != 3 || len(chunks[0].Rows) != 2 || len(chunks[2].Rows) != 1 {
+		t.Fatalf("insertChunks() = %d chunks, want 2+2+1 rows", len(chunks))
+	}
+	if got := insertChunks(view.IngestBatch{}, 2); len(got) != 0 {
+		t.Errorf("insertChunks() of an empty batch = %d chunks", len(got))
+	}
+	sql := insertSQL(chunks[2], "tok-2")
+	if want := "INSERT /*+ SET_VAR(deduplicate_label='tok-2') */ INTO `logs`.`app` (`msg`) VALUES ('m')"; sql != want {
+		t.Errorf("insertSQL() = %s, want %s", sql, want)
+	}
+	if sql = insertSQL(chunks[2], ""); !strings.HasPrefix(sql, "INSERT INTO `logs`.`app`") {
+		t.Errorf("insertSQL() without label = %s", sql)
+	}
+}
@@ -51,6 +51,8 @@ type ParamsView struct {
 	Where            string
 	TimeConvert      string
 	IsKafkaTimestamp int
+	// SignatureField adds a signature=<value> tag to the prometheus metric view, one series per value
+	SignatureField string
 }
 
 const PrometheusMetricName = "clickvisual_alert_metrics"
@@ -45,7 +45,7 @@ FROM %s
 `,
 			b.QueryAssembly.Params.TimeField,
 			bumo.PrometheusMetricName,
-			b.QueryAssembly.Params.View.CommonFields,
+			common.BuilderViewMetricTags(b.QueryAssembly.Params.View),
 			b.QueryAssembly.Params.TimeField,
 			b.QueryAssembly.Params.TimeField,
 			b.QueryAssembly.Params.View.SourceTable)
@@ -61,7 +61,8 @@ FROM %s
 func (b *ViewBuilder) BuilderWhere() {
 	switch b.QueryAssembly.Params.View.ViewType {
 	case bumo.ViewTypePrometheusMetric:
-		b.QueryAssembly.Result += fmt.Sprintf("WHERE %s GROUP BY %s\n", b.QueryAssembly.Params.View.Where, b.QueryAssembly.Params.TimeField)
+		b.QueryAssembly.Result += fmt.Sprintf("WHERE %s GROUP BY %s\n", b.QueryAssembly.Params.View.Where,
+			common.BuilderViewMetricGroupBy(b.QueryAssembly.Params.TimeField, b.QueryAssembly.Params.View))
 	case bumo.ViewTypePrometheusMetricAggregation:
 		b.QueryAssembly.Result += fmt.Sprintf("GROUP BY %s\n", b.QueryAssembly.Params.TimeField)
 	default:
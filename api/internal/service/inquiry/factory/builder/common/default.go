@@ -124,3 +124,20 @@ kafka_skip_broken_messages = %d
 		consumerNum,
 		stream.KafkaSkipBrokenMessages)
 }
+
+// BuilderViewMetricTags returns the tags of the prometheus metric view,
+// the signature field is exported as the signature label when it is set.
+func BuilderViewMetricTags(paramsView bumo.ParamsView) string {
+	if paramsView.SignatureField == "" {
+		return paramsView.CommonFields
+	}
+	return fmt.Sprintf("%s,concat('signature=', toString(`%s`))", paramsView.CommonFields, paramsView.SignatureField)
+}
+
+// BuilderViewMetricGroupBy returns the GROUP BY keys of the prometheus metric view.
+func BuilderViewMetricGroupBy(timeField string, paramsView bumo.ParamsView) string {
+	if paramsView.SignatureField == "" {
+		return timeField
+	}
+	return fmt.Sprintf("%s, `%s`", timeField, paramsView.SignatureField)
+}
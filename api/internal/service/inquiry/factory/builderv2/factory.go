@@ -48,6 +48,10 @@ func GetTableCreator(cType int) (IStorageCreator, error) {
 		return newComputeTrace(), nil
 	case constx.TableCreateTypeBufferNullDataPipe:
 		return newBuffNullDataPipe(), nil
+	case constx.TableCreateTypeOTLPLogs:
+		return newOTLPLogs(), nil
+	case constx.TableCreateTypeOTLPTraces:
+		return newOTLPTraces(), nil
 	}
 	return nil, ErrorCreateType
 }
@@ -0,0 +1,164 @@
+package builderv2
+
+import (
+	"fmt"
+	"strings"
+)
+
+var (
+	_ IStorageCreator = (*OTLPLogs)(nil)
+	_ IStorageCreator = (*OTLPTraces)(nil)
+)
+
+// OTLPColumn is a column of the tables of the OTLP receiver, the OTLP data is written to them in this order.
+type OTLPColumn struct {
+	Name  string
+	Type  string
+	Codec string
+}
+
+// OTLPLogsColumns lays out a LogRecord: _raw_log_ is the body, the attributes of the resource, the scope and the log
+// are kept in Map columns with the values as strings.
+var OTLPLogsColumns = []OTLPColumn{
+	{Name: "_time_second_", Type: "DateTime"},
+	{Name: "_time_nanosecond_", Type: "DateTime64(9)"},
+	{Name: "_raw_log_", Type: "String", Codec: "ZSTD(1)"},
+	{Name: "observed_time", Type: "DateTime64(9)"},
+	{Name: "trace_id", Type: "String"},
+	{Name: "span_id", Type: "String"},
+	{Name: "trace_flags", Type: "UInt32"},
+	{Name: "severity_text", Type: "LowCardinality(String)"},
+	{Name: "severity_number", Type: "Int32"},
+	{Name: "service_name", Type: "LowCardinality(String)"},
+	{Name: "resource_attributes", Type: "Map(LowCardinality(String), String)", Codec: "ZSTD(1)"},
+	{Name: "scope_name", Type: "String"},
+	{Name: "scope_version", Type: "String"},
+	{Name: "scope_attributes", Type: "Map(LowCardinality(String), String)", Codec: "ZSTD(1)"},
+	{Name: "log_attributes", Type: "Map(LowCardinality(String), String)", Codec: "ZSTD(1)"},
+}
+
+// OTLPTracesColumns lays out a Span: the time is the start of the span, _raw_log_ is the span in JSON and duration is
+// in nanoseconds. The events and links are parallel arrays.
+var OTLPTracesColumns = []OTLPColumn{
+	{Name: "_time_second_", Type: "DateTime"},
+	{Name: "_time_nanosecond_", Type: "DateTime64(9)"},
+	{Name: "_raw_log_", Type: "String", Codec: "ZSTD(1)"},
+	{Name: "trace_id", Type: "String"},
+	{Name: "span_id", Type: "String"},
+	{Name: "parent_span_id", Type: "String"},
+	{Name: "trace_state", Type: "String"},
+	{Name: "span_name", Type: "LowCardinality(String)"},
+	{Name: "span_kind", Type: "LowCardinality(String)"},
+	{Name: "service_name", Type: "LowCardinality(String)"},
+	{Name: "resource_attributes", Type: "Map(LowCardinality(String), String)", Codec: "ZSTD(1)"},
+	{Name: "scope_name", Type: "String"},
+	{Name: "scope_version", Type: "String"},
+	{Name: "span_attributes", Type: "Map(LowCardinality(String), String)", Codec: "ZSTD(1)"},
+	{Name: "duration", Type: "Int64"},
+	{Name: "status_code", Type: "LowCardinality(String)"},
+	{Name: "status_message", Type: "String"},
+	{Name: "events.timestamp", Type: "Array(DateTime64(9))"},
+	{Name: "events.name", Type: "Array(LowCardinality(String))"},
+	{Name: "events.attributes", Type: "Array(Map(LowCardinality(String), String))"},
+	{Name: "links.trace_id", Type: "Array(String)"},
+	{Name: "links.span_id", Type: "Array(String)"},
+	{Name: "links.trace_state", Type: "Array(String)"},
+	{Name: "links.attributes", Type: "Array(Map(LowCardinality(String), String))"},
+}
+
+// OTLPLogs the logs table of the OTLP receiver
+type OTLPLogs struct {
+	Storage
+}
+
+func newOTLPLogs() IStorageCreator {
+	return &OTLPLogs{}
+}
+
+func (t *OTLPLogs) GetSQLs() (names []string, sqls []string) {
+	names = make([]string, 0)
+	sqls = make([]string, 0)
+	appendSQL(&names, &sqls, func() (string, string) {
+		return t.otlpDataTable(OTLPLogsColumns, []string{
+			"INDEX idx_trace_id trace_id TYPE bloom_filter(0.001) GRANULARITY 1",
+			"INDEX idx_span_id span_id TYPE bloom_filter(0.001) GRANULARITY 1",
+			"INDEX idx_res_attr_key mapKeys(resource_attributes) TYPE bloom_filter(0.01) GRANULARITY 1",
+			"INDEX idx_log_attr_key mapKeys(log_attributes) TYPE bloom_filter(0.01) GRANULARITY 1",
+			"INDEX idx_raw_log _raw_log_ TYPE tokenbf_v1(30720, 2, 0) GRANULARITY 1",
+		})
+	})
+	appendSQL(&names, &sqls, t.otlpDistributed)
+	return
+}
+
+// OTLPTraces the spans table of the OTLP receiver
+type OTLPTraces struct {
+	Storage
+}
+
+func newOTLPTraces() IStorageCreator {
+	return &OTLPTraces{}
+}
+
+func (t *OTLPTraces) GetSQLs() (names []string, sqls []string) {
+	names = make([]string, 0)
+	sqls = make([]string, 0)
+	appendSQL(&names, &sqls, func() (string, string) {
+		return t.otlpDataTable(OTLPTracesColumns, []string{
+			"INDEX idx_trace_id trace_id TYPE bloom_filter(0.001) GRANULARITY 1",
+			"INDEX idx_span_id span_id TYPE bloom_filter(0.001) GRANULARITY 1",
+			"INDEX idx_res_attr_key mapKeys(resource_attributes) TYPE bloom_filter(0.01) GRANULARITY 1",
+			"INDEX idx_span_attr_key mapKeys(span_attributes) TYPE bloom_filter(0.01) GRANULARITY 1",
+			"INDEX idx_duration duration TYPE minmax GRANULARITY 1",
+		})
+	})
+	appendSQL(&names, &sqls, t.otlpDistributed)
+	return
+}
+
+func (t *Storage) otlpDataTable(columns []OTLPColumn, indexes []string) (name string, sql string) {
+	var engine string
+	var tableNameWithCluster string
+	tableName := fmt.Sprintf("`%s`.`%s`", t.database, t.table)
+	if t.isReplica || t.isShard {
+		tableName = fmt.Sprintf("`%s`.`%s_local`", t.database, t.table)
+		tableNameWithCluster = fmt.Sprintf("%s on cluster '%s'", tableName, t.cluster)
+		engine = fmt.Sprintf("ENGINE = ReplicatedMergeTree('/clickhouse/tables/%s.%s_local/{shard}', '{replica}')", t.database, t.table)
+	} else {
+		tableNameWithCluster = tableName
+		engine = "ENGINE = MergeTree"
+	}
+	lines := make([]string, 0, len(columns)+len(indexes))
+	for _, col := range columns {
+		line := fmt.Sprintf("    `%s` %s", col.Name, col.Type)
+		if col.Codec != "" {
+			line += fmt.Sprintf(" CODEC(%s)", col.Codec)
+		}
+		lines = append(lines, line)
+	}
+	for _, index := range indexes {
+		lines = append(lines, "    "+index)
+	}
+	return tableName, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s
+(
+%s
+)
+%s
+PARTITION BY toYYYYMMDD(_time_second_)
+ORDER BY (service_name, _time_second_)
+TTL toDateTime(_time_second_) + INTERVAL %d DAY
+SETTINGS index_granularity = 8192;
+`, tableNameWithCluster, strings.Join(lines, ",\n"), engine, t.ttl)
+}
+
+func (t *Storage) otlpDistributed() (name string, sql string) {
+	if t.isReplica || t.isShard {
+		// ddn distribution database table name
+		ddt := fmt.Sprintf("`%s`.`%s`", t.database, t.table)
+		// mdt merge tree database table
+		mdt := fmt.Sprintf("`%s`.`%s_local`", t.database, t.table)
+		return ddt, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s on cluster '%s' AS %s
+ENGINE = Distributed('%s', '%s', '%s_local', rand());`, ddt, t.cluster, mdt, t.cluster, t.database, t.table)
+	}
+	return "", ""
+}
@@ -0,0 +1,29 @@
+package factory
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// SQLBuilder is implemented by the operators of which the results of Chart and Count are cached,
+// the results are keyed by the statements.
+type SQLBuilder interface {
+	ChartSQL(view.ReqQuery) string
+	CountSQL(view.ReqQuery) string
+}
+
+// Unwrapper is implemented by the operators which wrap the operator of a datasource, as the query cache.
+type Unwrapper interface {
+	Unwrap() Operator
+}
+
+// Unwrap returns the operator of the datasource under the wrappers, the optional interfaces
+// as JobOperator are asserted on it.
+func Unwrap(op Operator) Operator {
+	for {
+		w, ok := op.(Unwrapper)
+		if !ok {
+			return op
+		}
+		op = w.Unwrap()
+	}
+}
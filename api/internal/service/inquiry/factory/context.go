@@ -0,0 +1,12 @@
+package factory
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// ContextReader is implemented by the operators which read the context of a log from its file,
+// the log is located by the _file_, _offset_ and _sign_ fields. The context of the other operators is read
+// through the cursors of GetLogs.
+type ContextReader interface {
+	ContextLogs(req view.ReqLogsContext) (view.RespLogsContext, error)
+}
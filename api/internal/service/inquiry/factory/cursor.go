@@ -0,0 +1,163 @@
+package factory
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+)
+
+const (
+	CursorOlder = "older"
+	CursorNewer = "newer"
+)
+
+// The order key columns are selected with the logs and removed by TakeCursors.
+const (
+	CursorTsField  = "_cursor_ts_"
+	CursorTieField = "_cursor_tie_"
+)
+
+var ErrCursor = errors.New("invalid cursor")
+
+// Cursor is the search-after position of a log line in the order (time, tie) of its page,
+// Ts is the time in nanoseconds and Tie is a hash of the row which orders the logs of the same time.
+// The logs of the same key are not told apart by the order, Seen counts the logs of the key
+// read up to the log in the order of the page, which is ascending when Asc.
+type Cursor struct {
+	Ts   int64  `json:"t"`
+	Tie  uint64 `json:"h"`
+	Seen int    `json:"n,omitempty"`
+	Asc  bool   `json:"a,omitempty"`
+}
+
+func (c Cursor) String() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Turned reports whether the cursor was read in the opposite order of the direction,
+// such a cursor is turned by Turn before the page is read.
+func (c Cursor) Turned(direction string) bool {
+	return c.Asc != (direction == CursorNewer)
+}
+
+// Turn returns the cursor of the same log in the opposite order, count is the number of the logs of its key.
+func (c Cursor) Turn(count int) Cursor {
+	c.Asc = !c.Asc
+	c.Seen = count - c.Seen + 1
+	if c.Seen < 0 {
+		c.Seen = 0
+	}
+	return c
+}
+
+// ParseCursor decodes a cursor of RespQuery.
+func ParseCursor(s string) (res Cursor, err error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return res, errors.Wrap(ErrCursor, err.Error())
+	}
+	if err = json.Unmarshal(b, &res); err != nil {
+		return res, errors.Wrap(ErrCursor, err.Error())
+	}
+	return res, nil
+}
+
+// ValidCursor checks the cursor and the direction of a request.
+func ValidCursor(cursor, direction string) error {
+	if direction != "" && direction != CursorOlder && direction != CursorNewer {
+		return errors.Wrapf(ErrCursor, "direction must be %s or %s", CursorOlder, CursorNewer)
+	}
+	if cursor == "" {
+		return nil
+	}
+	_, err := ParseCursor(cursor)
+	return err
+}
+
+// CursorOrder is the ORDER BY of a page, newer pages are read in ascending order.
+func CursorOrder(orderField, direction string) string {
+	if direction == CursorNewer {
+		return fmt.Sprintf("%s ASC, %s ASC", orderField, CursorTieField)
+	}
+	return fmt.Sprintf("%s DESC, %s DESC", orderField, CursorTieField)
+}
+
+// CursorCondition is the WHERE fragment of the page after cur in the direction, ts and tie are the order
+// key expressions of the dialect. The logs of the key of cur are kept, the page skips the cur.Seen of them.
+func CursorCondition(ts, tie string, cur Cursor, direction string) string {
+	op := "<="
+	if direction == CursorNewer {
+		op = ">="
+	}
+	return fmt.Sprintf("AND (%s %s %d AND (%s != %d OR %s %s %d))", ts, op, cur.Ts, ts, cur.Ts, tie, op, cur.Tie)
+}
+
+// CursorKeyCondition is the WHERE fragment of the logs of the key of cur, which are counted to turn cur.
+func CursorKeyCondition(ts, tie string, cur Cursor) string {
+	return fmt.Sprintf("AND %s = %d AND %s = %d", ts, cur.Ts, tie, cur.Tie)
+}
+
+// CursorRange narrows the time range [st, et) in seconds to the part which can contain the page after cur,
+// so that the time index is used before the order key is compared.
+func CursorRange(st, et int64, cur Cursor, direction string) (int64, int64) {
+	sec := cur.Ts / 1e9
+	if direction == CursorNewer {
+		if sec > st {
+			st = sec
+		}
+		return st, et
+	}
+	if sec+1 < et {
+		et = sec + 1
+	}
+	return st, et
+}
+
+// TakeCursors removes the order key columns from the logs of the page after cursor and returns the cursor
+// of every log, the logs of a newer page are reversed to the descending order.
+func TakeCursors(logs []map[string]interface{}, cursor, direction string) []string {
+	prev, err := ParseCursor(cursor)
+	if cursor == "" || err != nil {
+		prev = Cursor{Ts: -1}
+	}
+	res := make([]string, 0, len(logs))
+	for _, log := range logs {
+		cur := Cursor{Ts: cast.ToInt64(log[CursorTsField]), Tie: toUint64(log[CursorTieField]), Seen: 1, Asc: direction == CursorNewer}
+		if cur.Ts == prev.Ts && cur.Tie == prev.Tie {
+			cur.Seen = prev.Seen + 1
+		}
+		delete(log, CursorTsField)
+		delete(log, CursorTieField)
+		res = append(res, cur.String())
+		prev = cur
+	}
+	if direction == CursorNewer {
+		for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+			logs[i], logs[j] = logs[j], logs[i]
+			res[i], res[j] = res[j], res[i]
+		}
+	}
+	return res
+}
+
+// toUint64 keeps the hashes above the max int64 which cast refuses in strings.
+func toUint64(v interface{}) uint64 {
+	switch v := v.(type) {
+	case uint64:
+		return v
+	case *uint64:
+		if v != nil {
+			return *v
+		}
+		return 0
+	case string:
+		res, _ := strconv.ParseUint(v, 10, 64)
+		return res
+	}
+	return cast.ToUint64(v)
+}
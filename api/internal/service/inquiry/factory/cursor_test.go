@@ -0,0 +1,108 @@
+package factory
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseCursor(t *testing.T) {
+	want := Cursor{Ts: 1700000000123456789, Tie: 18446744073709551615, Seen: 2, Asc: true}
+	got, err := ParseCursor(want.String())
+	if err != nil || got != want {
+		t.Errorf("ParseCursor() = %v, %v, want %v", got, err, want)
+	}
+	for _, s := range []string{"!", "bm90IGpzb24"} {
+		if _, err = ParseCursor(s); !errors.Is(err, ErrCursor) {
+			t.Errorf("ParseCursor(%q) error = %v, want %v", s, err, ErrCursor)
+		}
+	}
+	if err = ValidCursor("", "sideways"); !errors.Is(err, ErrCursor) {
+		t.Errorf("ValidCursor() error = %v, want %v", err, ErrCursor)
+	}
+	if err = ValidCursor(want.String(), CursorNewer); err != nil {
+		t.Errorf("ValidCursor() error = %v", err)
+	}
+}
+
+func TestCursorCondition(t *testing.T) {
+	cur := Cursor{Ts: 1500000000, Tie: 7}
+	tests := []struct {
+		direction string
+		cond      string
+		order     string
+		st, et    int64
+	}{
+		{
+			direction: "",
+			cond:      "AND (ts <= 1500000000 AND (ts != 1500000000 OR tie <= 7))",
+			order:     "t DESC, _cursor_tie_ DESC",
+			st:        0, et: 2,
+		},
+		{
+			direction: CursorNewer,
+			cond:      "AND (ts >= 1500000000 AND (ts != 1500000000 OR tie >= 7))",
+			order:     "t ASC, _cursor_tie_ ASC",
+			st:        1, et: 10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.direction, func(t *testing.T) {
+			if got := CursorCondition("ts", "tie", cur, tt.direction); got != tt.cond {
+				t.Errorf("CursorCondition() = %v, want %v", got, tt.cond)
+			}
+			if got := CursorOrder("t", tt.direction); got != tt.order {
+				t.Errorf("CursorOrder() = %v, want %v", got, tt.order)
+			}
+			if st, et := CursorRange(0, 10, cur, tt.direction); st != tt.st || et != tt.et {
+				t.Errorf("CursorRange() = %d, %d, want %d, %d", st, et, tt.st, tt.et)
+			}
+		})
+	}
+}
+
+func TestCursorTurn(t *testing.T) {
+	// the second of three logs of the key in the descending order is the second in the ascending order
+	cur := Cursor{Ts: 1, Tie: 2, Seen: 2}
+	if cur.Turned(CursorOlder) || !cur.Turned(CursorNewer) {
+		t.Errorf("Turned() of %v is wrong", cur)
+	}
+	if got, want := cur.Turn(3), (Cursor{Ts: 1, Tie: 2, Seen: 2, Asc: true}); got != want {
+		t.Errorf("Turn() = %v, want %v", got, want)
+	}
+	if got, want := cur.Turn(5).Turn(5), cur; got != want {
+		t.Errorf("Turn().Turn() = %v, want %v", got, want)
+	}
+	if got := CursorKeyCondition("ts", "tie", cur); got != "AND ts = 1 AND tie = 2" {
+		t.Errorf("CursorKeyCondition() = %v", got)
+	}
+}
+
+func TestTakeCursors(t *testing.T) {
+	logs := []map[string]interface{}{
+		{"msg": "b", CursorTsField: int64(1), CursorTieField: uint64(2)},
+		{"msg": "b", CursorTsField: int64(1), CursorTieField: uint64(2)},
+		{"msg": "a", CursorTsField: "2", CursorTieField: "18446744073709551615"},
+	}
+	got := TakeCursors(logs, Cursor{Ts: 1, Tie: 2, Seen: 3, Asc: true}.String(), CursorNewer)
+	want := []string{
+		Cursor{Ts: 2, Tie: 18446744073709551615, Seen: 1, Asc: true}.String(),
+		Cursor{Ts: 1, Tie: 2, Seen: 5, Asc: true}.String(),
+		Cursor{Ts: 1, Tie: 2, Seen: 4, Asc: true}.String(),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TakeCursors() = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(logs, []map[string]interface{}{{"msg": "a"}, {"msg": "b"}, {"msg": "b"}}) {
+		t.Errorf("TakeCursors() logs = %v", logs)
+	}
+	logs = []map[string]interface{}{
+		{"msg": "b", CursorTsField: int64(1), CursorTieField: uint64(2)},
+		{"msg": "b", CursorTsField: int64(1), CursorTieField: uint64(2)},
+	}
+	got = TakeCursors(logs, "", CursorOlder)
+	want = []string{Cursor{Ts: 1, Tie: 2, Seen: 1}.String(), Cursor{Ts: 1, Tie: 2, Seen: 2}.String()}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TakeCursors() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,75 @@
+package factory
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+var ErrExportUnsupported = errors.New("export is not supported by the datasource")
+
+// RowWriter receives the rows of an export as they are scanned,
+// the values slice is reused between rows and must not be kept.
+type RowWriter interface {
+	Columns(names []string) error
+	Row(values []interface{}) error
+}
+
+// Exporter is implemented by the operators which can stream the logs of a query.
+type Exporter interface {
+	// ExportLogs writes at most limit logs of the prepared query in the order of GetLogs.
+	ExportLogs(ctx context.Context, param view.ReqQuery, tid int, limit int, w RowWriter) error
+}
+
+// ScanRows writes the rows to w until they are read or the limit is reached.
+func ScanRows(rows *sql.Rows, limit int, w RowWriter) error {
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(cts))
+	for idx, ct := range cts {
+		names[idx] = ct.Name()
+	}
+	if err = w.Columns(names); err != nil {
+		return err
+	}
+	var (
+		values = make([]interface{}, len(cts))
+		dest   = make([]interface{}, len(cts))
+	)
+	for idx := range dest {
+		dest[idx] = &values[idx]
+	}
+	for n := 0; n < limit && rows.Next(); n++ {
+		if err = rows.Scan(dest...); err != nil {
+			return err
+		}
+		for idx := range values {
+			values[idx] = deref(values[idx])
+		}
+		if err = w.Row(values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// deref returns the value of a nullable column, nil when it is null.
+func deref(v interface{}) interface{} {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
@@ -34,7 +34,6 @@ type Operator interface {
 
 	CreateDatabase(string, string) error
 	CreateAlertView(string, string, string) error
-	CreateKafkaTable(*db.BaseTable, view.ReqStorageUpdate) (string, error)
 	CreateTraceJaegerDependencies(database, cluster, table string, ttl int) (err error)
 	CreateTable(int, db.BaseDatabase, view.ReqTableCreate) (string, string, string, string, error)
 	CreateStorageJSONAsString(db.BaseDatabase, view.ReqStorageCreate) (string, string, string, string, error)
@@ -44,6 +43,7 @@ type Operator interface {
 
 	UpdateLogAnalysisFields(db.BaseDatabase, db.BaseTable, map[string]*db.BaseIndex, map[string]*db.BaseIndex, map[string]*db.BaseIndex) error
 	UpdateMergeTreeTable(*db.BaseTable, view.ReqStorageUpdate) error
+	UpdateReader(*db.BaseTable, view.ReqStorageUpdate) (string, error)
 
 	GetLogs(view.ReqQuery, int) (view.RespQuery, error)
 	GetCreateSQL(database, table string) (string, error)
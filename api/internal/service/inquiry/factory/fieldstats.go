@@ -0,0 +1,203 @@
+package factory
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/gotomicro/cetus/pkg/kutl"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// FieldStatsReader is implemented by the operators which compute the statistics of the fields in the database,
+// the fields are computed together so that the queries do not grow with the number of the fields.
+type FieldStatsReader interface {
+	FieldStats(param view.ReqQuery, req view.FieldStatsParams) (view.RespFieldStats, error)
+}
+
+// FieldStatsPercentiles are the percentiles of the numeric fields.
+var FieldStatsPercentiles = []struct {
+	Name  string
+	Level float64
+}{
+	{Name: "p50", Level: 0.5},
+	{Name: "p90", Level: 0.9},
+	{Name: "p95", Level: 0.95},
+	{Name: "p99", Level: 0.99},
+}
+
+const fieldStatsPoints = 60 // points of the time series at most
+
+var fieldStatsIntervals = []int64{1, 5, 10, 30, 60, 300, 600, 1800, 3600, 3 * 3600, 6 * 3600, 12 * 3600, 86400}
+
+// FieldStatsInterval returns the seconds of a point of the time series of [st, et).
+func FieldStatsInterval(st, et int64) int64 {
+	for _, interval := range fieldStatsIntervals {
+		if (et-st+interval-1)/interval <= fieldStatsPoints {
+			return interval
+		}
+	}
+	return fieldStatsIntervals[len(fieldStatsIntervals)-1]
+}
+
+// FieldStatsColumn is the alias of a statistic of the field at idx of the params, as uniq_0.
+func FieldStatsColumn(name string, idx int) string {
+	return fmt.Sprintf("%s_%d", name, idx)
+}
+
+// NewFieldStats returns the statistics of the fields without values, the points of the timeline
+// start at multiples of the interval.
+func NewFieldStats(param view.ReqQuery, req view.FieldStatsParams) view.RespFieldStats {
+	res := view.RespFieldStats{
+		Interval: req.Interval,
+		Timeline: make([]int64, 0),
+		Fields:   make([]view.FieldStats, 0, len(req.Fields)),
+	}
+	for t := param.ST / req.Interval * req.Interval; t < param.ET; t += req.Interval {
+		res.Timeline = append(res.Timeline, t)
+	}
+	for _, f := range req.Fields {
+		item := view.FieldStats{Field: f.Name, Top: make([]view.FieldStatsValue, 0)}
+		if f.Numeric {
+			item.Numeric = &view.FieldStatsNumeric{
+				Percentiles: make(map[string]float64, len(FieldStatsPercentiles)),
+				Histogram:   make([]view.FieldStatsBucket, 0),
+			}
+		}
+		res.Fields = append(res.Fields, item)
+	}
+	return res
+}
+
+// SetFieldStatsSummary reads the row of the summary query, which selects the count of the logs and the columns
+// of every field: uniq and empty, and min, max, avg and the percentiles of the numeric fields.
+// The buckets of the histograms are laid out from min to max.
+func SetFieldStatsSummary(res *view.RespFieldStats, row map[string]interface{}, buckets int) {
+	res.Total = cast.ToUint64(row["count"])
+	for idx := range res.Fields {
+		f := &res.Fields[idx]
+		f.Uniq = cast.ToUint64(row[FieldStatsColumn("uniq", idx)])
+		f.Empty = cast.ToUint64(row[FieldStatsColumn("empty", idx)])
+		f.EmptyRatio = percent(f.Empty, res.Total)
+		f.Other = sub(res.Total, f.Empty)
+		if f.Numeric == nil {
+			continue
+		}
+		f.Numeric.Min = toFloat64(row[FieldStatsColumn("min", idx)])
+		f.Numeric.Max = toFloat64(row[FieldStatsColumn("max", idx)])
+		f.Numeric.Avg = toFloat64(row[FieldStatsColumn("avg", idx)])
+		for _, p := range FieldStatsPercentiles {
+			f.Numeric.Percentiles[p.Name] = toFloat64(row[FieldStatsColumn(p.Name, idx)])
+		}
+		if f.Other == 0 {
+			continue
+		}
+		if f.Numeric.Max <= f.Numeric.Min {
+			// a single value
+			f.Numeric.Histogram = append(f.Numeric.Histogram, view.FieldStatsBucket{Lower: f.Numeric.Min, Upper: f.Numeric.Max, Count: f.Other})
+			continue
+		}
+		width := (f.Numeric.Max - f.Numeric.Min) / float64(buckets)
+		for i := 0; i < buckets; i++ {
+			f.Numeric.Histogram = append(f.Numeric.Histogram, view.FieldStatsBucket{
+				Lower: f.Numeric.Min + width*float64(i),
+				Upper: f.Numeric.Min + width*float64(i+1),
+			})
+		}
+		f.Numeric.Histogram[buckets-1].Upper = f.Numeric.Max
+	}
+}
+
+// FieldStatsHistogram returns the lower bound and the width of the buckets of the field,
+// ok is false when the histogram is not read from the database.
+func FieldStatsHistogram(f view.FieldStats) (lower, width float64, ok bool) {
+	if f.Numeric == nil || len(f.Numeric.Histogram) < 2 {
+		return 0, 0, false
+	}
+	h := f.Numeric.Histogram
+	return h[0].Lower, h[0].Upper - h[0].Lower, true
+}
+
+// SetFieldStatsHistogram reads the rows of the histogram query, a row is the index of the field idx,
+// the index of the bucket b and the count.
+func SetFieldStatsHistogram(res *view.RespFieldStats, rows []map[string]interface{}) {
+	for _, row := range rows {
+		idx, b := cast.ToInt(row["idx"]), cast.ToInt(row["b"])
+		if idx < 0 || idx >= len(res.Fields) || res.Fields[idx].Numeric == nil {
+			continue
+		}
+		h := res.Fields[idx].Numeric.Histogram
+		if b < 0 || b >= len(h) {
+			continue
+		}
+		h[b].Count += cast.ToUint64(row["count"])
+	}
+}
+
+// SetFieldStatsTop reads the rows of the top query, a row is the index of the field idx, the value v,
+// the start second t of the point of the timeline and the count. It is read after the summary.
+func SetFieldStatsTop(res *view.RespFieldStats, rows []map[string]interface{}) {
+	values := make([]map[string]*view.FieldStatsValue, len(res.Fields))
+	for _, row := range rows {
+		idx := cast.ToInt(row["idx"])
+		if idx < 0 || idx >= len(res.Fields) {
+			continue
+		}
+		if values[idx] == nil {
+			values[idx] = make(map[string]*view.FieldStatsValue)
+		}
+		v := cast.ToString(row["v"])
+		item, ok := values[idx][v]
+		if !ok {
+			item = &view.FieldStatsValue{Value: v, Series: make([]uint64, len(res.Timeline))}
+			values[idx][v] = item
+		}
+		count := cast.ToUint64(row["count"])
+		item.Count += count
+		if len(res.Timeline) > 0 {
+			if i := (cast.ToInt64(row["t"]) - res.Timeline[0]) / res.Interval; i >= 0 && i < int64(len(item.Series)) {
+				item.Series[i] += count
+			}
+		}
+	}
+	for idx := range res.Fields {
+		f := &res.Fields[idx]
+		for _, item := range values[idx] {
+			item.Percent = percent(item.Count, res.Total)
+			f.Top = append(f.Top, *item)
+			f.Other = sub(f.Other, item.Count)
+		}
+		sort.Slice(f.Top, func(i, j int) bool {
+			if f.Top[i].Count != f.Top[j].Count {
+				return f.Top[i].Count > f.Top[j].Count
+			}
+			return f.Top[i].Value < f.Top[j].Value
+		})
+	}
+}
+
+func percent(count, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return kutl.Decimal(float64(count) * 100 / float64(total))
+}
+
+// sub keeps the counts from wrapping when the logs change between the queries.
+func sub(a, b uint64) uint64 {
+	if a < b {
+		return 0
+	}
+	return a - b
+}
+
+// toFloat64 returns 0 for NaN and Inf which the aggregates of no rows may return and JSON refuses.
+func toFloat64(v interface{}) float64 {
+	res := cast.ToFloat64(v)
+	if math.IsNaN(res) || math.IsInf(res, 0) {
+		return 0
+	}
+	return res
+}
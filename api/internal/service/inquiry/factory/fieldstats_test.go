@@ -0,0 +1,102 @@
+package factory
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func TestFieldStatsInterval(t *testing.T) {
+	tests := []struct {
+		st, et int64
+		want   int64
+	}{
+		{st: 0, et: 60, want: 1},
+		{st: 0, et: 900, want: 30},
+		{st: 0, et: 3600, want: 60},
+		{st: 0, et: 86400, want: 1800},
+		{st: 0, et: 86400 * 365, want: 86400},
+	}
+	for _, tt := range tests {
+		if got := FieldStatsInterval(tt.st, tt.et); got != tt.want {
+			t.Errorf("FieldStatsInterval(%d, %d) = %d, want %d", tt.st, tt.et, got, tt.want)
+		}
+	}
+}
+
+func TestFieldStats(t *testing.T) {
+	param := view.ReqQuery{ST: 100, ET: 220}
+	req := view.FieldStatsParams{
+		Fields:   []view.FieldStatsField{{Name: "level"}, {Name: "cost", Numeric: true}},
+		K:        2,
+		Buckets:  4,
+		Interval: 60,
+	}
+	res := NewFieldStats(param, req)
+	if want := []int64{60, 120, 180}; !reflect.DeepEqual(res.Timeline, want) {
+		t.Fatalf("NewFieldStats() timeline = %v, want %v", res.Timeline, want)
+	}
+	SetFieldStatsSummary(&res, map[string]interface{}{
+		"count":   uint64(10),
+		"uniq_0":  uint64(3),
+		"empty_0": uint64(1),
+		"uniq_1":  uint64(8),
+		"empty_1": uint64(2),
+		"min_1":   float64(0),
+		"max_1":   float64(8),
+		"avg_1":   math.NaN(),
+		"p99_1":   float64(7.5),
+	}, req.Buckets)
+	level, cost := res.Fields[0], res.Fields[1]
+	if level.Numeric != nil || level.EmptyRatio != 10 || level.Other != 9 {
+		t.Errorf("SetFieldStatsSummary() level = %+v", level)
+	}
+	if cost.Numeric.Avg != 0 || cost.Numeric.Percentiles["p99"] != 7.5 || len(cost.Numeric.Histogram) != 4 {
+		t.Errorf("SetFieldStatsSummary() cost = %+v", cost.Numeric)
+	}
+	if lower, width, ok := FieldStatsHistogram(cost); !ok || lower != 0 || width != 2 {
+		t.Errorf("FieldStatsHistogram() = %v, %v, %v", lower, width, ok)
+	}
+	if _, _, ok := FieldStatsHistogram(level); ok {
+		t.Errorf("FieldStatsHistogram() of a string field is read")
+	}
+
+	SetFieldStatsTop(&res, []map[string]interface{}{
+		{"idx": uint16(0), "v": "info", "t": int64(60), "count": uint64(3)},
+		{"idx": uint16(0), "v": "info", "t": int64(180), "count": uint64(2)},
+		{"idx": uint16(0), "v": "error", "t": int64(120), "count": uint64(3)},
+		{"idx": uint16(9), "v": "lost", "t": int64(120), "count": uint64(3)},
+	})
+	want := []view.FieldStatsValue{
+		{Value: "info", Count: 5, Percent: 50, Series: []uint64{3, 0, 2}},
+		{Value: "error", Count: 3, Percent: 30, Series: []uint64{0, 3, 0}},
+	}
+	if !reflect.DeepEqual(res.Fields[0].Top, want) || res.Fields[0].Other != 1 {
+		t.Errorf("SetFieldStatsTop() = %+v, other %d", res.Fields[0].Top, res.Fields[0].Other)
+	}
+
+	SetFieldStatsHistogram(&res, []map[string]interface{}{
+		{"idx": uint16(1), "b": int64(0), "count": uint64(5)},
+		{"idx": uint16(1), "b": int64(3), "count": uint64(3)},
+		{"idx": uint16(1), "b": int64(4), "count": uint64(1)},
+	})
+	h := res.Fields[1].Numeric.Histogram
+	if h[0].Count != 5 || h[3].Count != 3 || h[3].Upper != 8 {
+		t.Errorf("SetFieldStatsHistogram() = %+v", h)
+	}
+}
+
+func TestSetFieldStatsSummary_singleValue(t *testing.T) {
+	req := view.FieldStatsParams{Fields: []view.FieldStatsField{{Name: "code", Numeric: true}}, Buckets: 10, Interval: 60}
+	res := NewFieldStats(view.ReqQuery{ST: 0, ET: 60}, req)
+	SetFieldStatsSummary(&res, map[string]interface{}{"count": uint64(4), "min_0": float64(200), "max_0": float64(200)}, req.Buckets)
+	want := []view.FieldStatsBucket{{Lower: 200, Upper: 200, Count: 4}}
+	if h := res.Fields[0].Numeric.Histogram; !reflect.DeepEqual(h, want) {
+		t.Errorf("SetFieldStatsSummary() histogram = %+v, want %+v", h, want)
+	}
+	if _, _, ok := FieldStatsHistogram(res.Fields[0]); ok {
+		t.Errorf("FieldStatsHistogram() of a single value is read")
+	}
+}
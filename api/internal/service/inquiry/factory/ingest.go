@@ -0,0 +1,17 @@
+package factory
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// Inserter is implemented by the operators which write the logs into the data tables directly,
+// for the tables without the stream tables of Kafka.
+type Inserter interface {
+	Insert(batch view.IngestBatch) error
+}
+
+// OTLPCreator is implemented by the operators which create the tables of the OTLP receiver,
+// createType is constx.TableCreateTypeOTLPLogs or constx.TableCreateTypeOTLPTraces.
+type OTLPCreator interface {
+	CreateOTLPTable(createType int, database, cluster, table string, ttl int) (names []string, sqls []string, err error)
+}
@@ -0,0 +1,28 @@
+package factory
+
+import (
+	"context"
+
+	"github.com/pkg/errors"
+)
+
+var ErrJobUnsupported = errors.New("query jobs are not supported by the datasource")
+
+// Job runs the queries of an operator under an id and limits so that they can be watched and cancelled.
+type Job struct {
+	Ctx              context.Context
+	QueryId          string // the query_id of the statements, assigned by clickvisual
+	MaxExecutionTime int    // seconds, 0 is unlimited
+	// Progress receives the increments of the rows and bytes read and of the estimated rows to read.
+	Progress func(rows, bytes, totalRows uint64)
+	// Row receives every scanned row of the statement sql before the statement returns.
+	Row func(sql string, row map[string]interface{})
+}
+
+// JobOperator is implemented by the operators which can run query jobs.
+type JobOperator interface {
+	// WithJob returns an operator of the same instance which runs its queries in the job.
+	WithJob(job Job) Operator
+	// KillQuery stops the statements of the query id on the server.
+	KillQuery(queryId string) error
+}
@@ -0,0 +1,13 @@
+package factory
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// NewGroupReader is implemented by the operators which find the new values of a field in one statement,
+// instead of counting the logs of every value.
+type NewGroupReader interface {
+	// HasNewGroup reports whether a value of param.Field occurs at least min times in [param.ST, param.ET)
+	// and never in [baselineST, param.ST).
+	HasNewGroup(param view.ReqQuery, baselineST int64, min uint64) (bool, error)
+}
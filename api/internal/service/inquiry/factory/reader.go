@@ -0,0 +1,11 @@
+package factory
+
+import (
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+// ReaderImporter is implemented by the operators which import the files of the tables read by db.ReaderTypeImport,
+// it imports at most limit files which are not imported yet and returns them.
+type ReaderImporter interface {
+	ImportReader(tableInfo *db.BaseTable, limit int) (files []string, err error)
+}
@@ -0,0 +1,14 @@
+package factory
+
+import (
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// IngestHealthReader is implemented by the operators which expose the ingestion metrics of the tables,
+// the exceptions are counted after since.
+type IngestHealthReader interface {
+	IngestHealth(tableInfo *db.BaseTable, since time.Time) (view.IngestHealthMetrics, error)
+}
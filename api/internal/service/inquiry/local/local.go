@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"regexp"
 	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/gotomicro/ego/core/elog"
@@ -134,11 +135,6 @@ func (l Local) CreateAlertView(s string, s2 string, s3 string) error {
 	panic("implement me")
 }
 
-func (l Local) CreateKafkaTable(table *db.BaseTable, update view.ReqStorageUpdate) (string, error) {
-	// TODO implement me
-	panic("implement me")
-}
-
 func (l Local) CreateTraceJaegerDependencies(database, cluster, table string, ttl int) (err error) {
 	// TODO implement me
 	panic("implement me")
@@ -179,6 +175,11 @@ func (l Local) UpdateMergeTreeTable(table *db.BaseTable, update view.ReqStorageU
 	panic("implement me")
 }
 
+func (l Local) UpdateReader(table *db.BaseTable, update view.ReqStorageUpdate) (string, error) {
+	// TODO implement me
+	panic("implement me")
+}
+
 func (l Local) GetLogs(query view.ReqQuery, i int) (resp view.RespQuery, err error) {
 	data := search.Request{
 		StartTime: query.ST,
@@ -244,6 +245,49 @@ func (l Local) GetLogs(query view.ReqQuery, i int) (resp view.RespQuery, err err
 	return resp, nil
 }
 
+// ContextLogs reads the context of the log from its file.
+func (l Local) ContextLogs(req view.ReqLogsContext) (view.RespLogsContext, error) {
+	offset, err := strconv.ParseInt(req.Fields[search.InnerKeyOffset], 10, 64)
+	if err != nil {
+		return view.RespLogsContext{}, fmt.Errorf("invalid %s: %w", search.InnerKeyOffset, err)
+	}
+	data, err := search.Context(search.ContextRequest{
+		Path:   req.Fields[search.InnerKeyFile],
+		Offset: offset,
+		Sign:   req.Fields[search.InnerKeySign],
+		Before: req.Before,
+		After:  req.After,
+	})
+	if err != nil {
+		return view.RespLogsContext{}, fmt.Errorf("context run fail, err: %w", err)
+	}
+	return view.RespLogsContext{
+		Before: l.contextLogs(data.Before, req.Fields),
+		After:  l.contextLogs(data.After, req.Fields),
+	}, nil
+}
+
+// contextLogs keeps the lines without a time too, as the lines of a stack trace.
+func (l Local) contextLogs(items []view.RespAgentSearchItem, fields map[string]string) []map[string]any {
+	res := make([]map[string]any, 0, len(items))
+	for _, item := range items {
+		log, _ := l.parseHitLog(item)
+		if log == nil {
+			log = map[string]any{search.InnerRawLog: item.Line}
+			for k, v := range item.Ext {
+				log[k] = v
+			}
+		}
+		for k, v := range fields {
+			if _, ok := log[k]; !ok {
+				log[k] = v
+			}
+		}
+		res = append(res, log)
+	}
+	return res
+}
+
 func (l *Local) parseHitLog(item view.RespAgentSearchItem) (log map[string]interface{}, err error) {
 	line := item.Line
 	if line == "" {
@@ -0,0 +1,81 @@
+// Package lql is the log query language of the search box, a Lucene/KQL style
+// syntax that is parsed into an AST, validated against the table indexes and
+// compiled to a WHERE fragment by the dialect of the table instance:
+//
+//	level:error AND service:"api" AND NOT msg:*timeout* AND latency>500
+//
+// A term without field searches the raw log, terms next to each other are
+// joined with AND, and NOT binds tighter than AND which binds tighter than OR.
+package lql
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	OpAnd = "AND"
+	OpOr  = "OR"
+)
+
+const (
+	OpMatch = ":"
+	OpGt    = ">"
+	OpGte   = ">="
+	OpLt    = "<"
+	OpLte   = "<="
+)
+
+// Node is an expression of the query.
+type Node interface {
+	String() string
+}
+
+// Binary joins two expressions with AND or OR.
+type Binary struct {
+	Op    string
+	Left  Node
+	Right Node
+}
+
+// Not negates an expression.
+type Not struct {
+	Expr Node
+}
+
+// Term matches one field, or the raw log when Field is empty.
+type Term struct {
+	Field  string
+	Op     string
+	Value  string
+	Quoted bool // a quoted value is matched literally, * and ? are not wildcards
+}
+
+func (b *Binary) String() string {
+	return fmt.Sprintf("(%s %s %s)", b.Left, b.Op, b.Right)
+}
+
+func (n *Not) String() string {
+	return fmt.Sprintf("NOT %s", n.Expr)
+}
+
+func (t *Term) String() string {
+	val := t.Value
+	if t.Quoted {
+		val = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(val) + `"`
+	}
+	if t.Field == "" {
+		return val
+	}
+	return t.Field + t.Op + val
+}
+
+// IsExists reports whether the term only requires the field to have a value, e.g. `trace_id:*`.
+func (t *Term) IsExists() bool {
+	return t.Field != "" && t.Op == OpMatch && !t.Quoted && t.Value == "*"
+}
+
+// IsWildcard reports whether the value contains * or ? wildcards.
+func (t *Term) IsWildcard() bool {
+	return !t.Quoted && strings.ContainsAny(t.Value, "*?")
+}
@@ -0,0 +1,212 @@
+package lql
+
+import (
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+var (
+	ErrUnknownField = errors.New("unknown field")
+	ErrFieldType    = errors.New("invalid value for the field type")
+	ErrFullText     = errors.New("full text search requires the raw log field of the table")
+)
+
+const (
+	fieldString = iota
+	fieldNumber
+	fieldUnsupported
+)
+
+// rawLogField is the raw log column of the tables created by clickvisual.
+const rawLogField = "_raw_log_"
+
+// cvBaseFields are the columns of the tables created by clickvisual.
+var cvBaseFields = []string{"_source_", "_cluster_", "_log_agent_", "_namespace_", "_node_name_", "_node_ip_",
+	"_container_name_", "_pod_name_"}
+
+// Field is a queryable column.
+type Field struct {
+	Name    string
+	Typ     int // the index type, 0 string 1 int 2 float
+	HashTyp int // the hash type, 0 no hash 1 sipHash64 2 URLHash
+}
+
+// Schema is what a query is validated and compiled against.
+type Schema struct {
+	Fields      map[string]Field
+	RawLogField string // the column of full text terms, empty disables them
+	Hash        bool   // compares hashed string fields by their hash columns
+}
+
+// NewSchema returns the schema of the table, the fields are the indexes and the base columns of the table.
+func NewSchema(table *db.BaseTable, indexes []*db.BaseIndex) Schema {
+	res := Schema{Fields: make(map[string]Field)}
+	if table.CreateType == constx.TableCreateTypeExist {
+		res.RawLogField = table.RawLogField
+	} else {
+		res.RawLogField = rawLogField
+		for _, name := range cvBaseFields {
+			res.Fields[name] = Field{Name: name}
+		}
+	}
+	if res.RawLogField != "" {
+		res.Fields[res.RawLogField] = Field{Name: res.RawLogField}
+	}
+	for _, index := range indexes {
+		name := index.GetFieldName()
+		res.Fields[name] = Field{Name: name, Typ: index.Typ, HashTyp: index.HashTyp}
+	}
+	return res
+}
+
+func (f Field) kind() int {
+	switch f.Typ {
+	case 1, 2:
+		return fieldNumber
+	case -3, 3:
+		// Array(String) and JSON
+		return fieldUnsupported
+	}
+	return fieldString
+}
+
+// Dialect writes the SQL of the instance.
+type Dialect interface {
+	// Ident quotes a column name.
+	Ident(name string) string
+	// String quotes a string literal.
+	String(val string) string
+	// Like matches ident with the LIKE pattern, % _ and \ of the pattern are already escaped.
+	Like(ident, pattern string) string
+	// Hash returns the hash expression of the quoted literal for the hash type.
+	Hash(hashTyp int, literal string) string
+}
+
+// Compile compiles the query to a WHERE fragment, a nil node returns an empty fragment.
+func Compile(node Node, schema Schema, d Dialect) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+	c := compiler{schema: schema, d: d}
+	return c.compile(node)
+}
+
+type compiler struct {
+	schema Schema
+	d      Dialect
+}
+
+func (c compiler) compile(node Node) (string, error) {
+	switch n := node.(type) {
+	case *Binary:
+		left, err := c.compile(n.Left)
+		if err != nil {
+			return "", err
+		}
+		right, err := c.compile(n.Right)
+		if err != nil {
+			return "", err
+		}
+		return "(" + left + " " + n.Op + " " + right + ")", nil
+	case *Not:
+		expr, err := c.compile(n.Expr)
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + expr, nil
+	case *Term:
+		return c.term(n)
+	}
+	return "", errors.Errorf("unknown node %T", node)
+}
+
+func (c compiler) term(t *Term) (string, error) {
+	if t.Field == "" {
+		if c.schema.RawLogField == "" {
+			return "", ErrFullText
+		}
+		return c.d.Like(c.d.Ident(c.schema.RawLogField), "%"+likePattern(t.Value, !t.Quoted)+"%"), nil
+	}
+	field, ok := c.schema.Fields[t.Field]
+	if !ok {
+		return "", errors.Wrapf(ErrUnknownField, "%s", t.Field)
+	}
+	ident := c.d.Ident(field.Name)
+	switch field.kind() {
+	case fieldUnsupported:
+		return "", errors.Wrapf(ErrFieldType, "%s can not be queried", t.Field)
+	case fieldNumber:
+		if t.IsExists() {
+			return ident + " IS NOT NULL", nil
+		}
+		val, err := number(t.Value)
+		if err != nil {
+			return "", errors.Wrapf(ErrFieldType, "%s is a number: %s", t.Field, t.Value)
+		}
+		return ident + " " + sqlOp(t.Op) + " " + val, nil
+	}
+	if t.IsExists() {
+		return ident + " != ''", nil
+	}
+	if t.IsWildcard() {
+		if t.Op != OpMatch {
+			return "", errors.Wrapf(ErrFieldType, "wildcards can not be compared: %s", t)
+		}
+		return c.d.Like(ident, likePattern(t.Value, true)), nil
+	}
+	literal := c.d.String(t.Value)
+	if c.schema.Hash && t.Op == OpMatch && field.HashTyp != 0 {
+		index := db.BaseIndex{Field: field.Name, HashTyp: field.HashTyp}
+		if hashField, ok := index.GetHashFieldName(); ok {
+			return c.d.Ident(hashField) + " = " + c.d.Hash(field.HashTyp, literal), nil
+		}
+	}
+	return ident + " " + sqlOp(t.Op) + " " + literal, nil
+}
+
+func sqlOp(op string) string {
+	if op == OpMatch {
+		return "="
+	}
+	return op
+}
+
+// number returns the canonical form of a number so that it can be written into SQL.
+func number(val string) (string, error) {
+	if i, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	f, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return "", err
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", errors.New("not a finite number")
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64), nil
+}
+
+// likePattern escapes val for LIKE, * and ? become % and _ when wildcard is set.
+func likePattern(val string, wildcard bool) string {
+	var sb strings.Builder
+	for _, r := range val {
+		switch {
+		case r == '%' || r == '_' || r == '\\':
+			sb.WriteRune('\\')
+			sb.WriteRune(r)
+		case wildcard && r == '*':
+			sb.WriteRune('%')
+		case wildcard && r == '?':
+			sb.WriteRune('_')
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
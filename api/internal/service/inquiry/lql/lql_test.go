@@ -0,0 +1,114 @@
+package lql
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+type testDialect struct{}
+
+func (testDialect) Ident(name string) string { return "`" + name + "`" }
+
+func (testDialect) String(val string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(val) + "'"
+}
+
+func (d testDialect) Like(ident, pattern string) string { return ident + " LIKE " + d.String(pattern) }
+
+func (testDialect) Hash(hashTyp int, literal string) string { return "sipHash64(" + literal + ")" }
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{query: "", want: "<nil>"},
+		{query: " * ", want: "<nil>"},
+		{query: "error", want: "error"},
+		{query: `level:error AND service:"api" AND NOT msg:*timeout* AND latency>500`,
+			want: `(((level:error AND service:"api") AND NOT msg:*timeout*) AND latency>500)`},
+		{query: "a:1 b:2 OR c:3", want: "((a:1 AND b:2) OR c:3)"},
+		{query: "a:1 AND (b:2 OR c:3)", want: "(a:1 AND (b:2 OR c:3))"},
+		{query: "-a:1 not b:2", want: "(NOT a:1 AND NOT b:2)"},
+		{query: "a:>=-1 a<-2 x-y", want: "((a>=-1 AND a<-2) AND x-y)"},
+		{query: `msg:"say \"hi\"" \AND`, want: `(msg:"say \"hi\"" AND AND)`},
+		{query: "a:1 && b:2 || c:3", want: "((a:1 AND b:2) OR c:3)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			got := "<nil>"
+			if node != nil {
+				got = node.String()
+			}
+			if got != tt.want {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for _, query := range []string{"a:", "(a:1", "a:1)", `"abc`, "a='1'", "AND", "a:(b)"} {
+		if _, err := Parse(query); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) error = %v, want %v", query, err, ErrSyntax)
+		}
+	}
+}
+
+func TestCompile(t *testing.T) {
+	schema := NewSchema(&db.BaseTable{CreateType: constx.TableCreateTypeJSONEachRow}, []*db.BaseIndex{
+		{Field: "level"},
+		{Field: "latency", Typ: 1},
+		{Field: "url", RootName: "req", HashTyp: db.HashTypeSip},
+		{Field: "tags", Typ: -3},
+	})
+	tests := []struct {
+		query string
+		hash  bool
+		want  string
+		err   error
+	}{
+		{query: "*", want: ""},
+		{query: `level:error AND NOT _pod_name_:*api-? OR latency>=500`,
+			want: "((`level` = 'error' AND NOT `_pod_name_` LIKE '%api-_') OR `latency` >= 500)"},
+		{query: `timeout`, want: "`_raw_log_` LIKE '%timeout%'"},
+		{query: `"50%_o'k*"`, want: "`_raw_log_` LIKE '%50\\\\%\\\\_o\\'k*%'"},
+		{query: `level:*`, want: "`level` != ''"},
+		{query: `latency:*`, want: "`latency` IS NOT NULL"},
+		{query: `latency:1e3`, want: "`latency` = 1000"},
+		{query: `req.url:"/a"`, want: "`req.url` = '/a'"},
+		{query: `req.url:"/a"`, hash: true, want: "`_inner_siphash_req.url_` = sipHash64('/a')"},
+		{query: `req.url>"/a"`, hash: true, want: "`req.url` > '/a'"},
+		{query: `latency:"1; DROP TABLE x"`, err: ErrFieldType},
+		{query: `latency:NaN`, err: ErrFieldType},
+		{query: `level>*a`, err: ErrFieldType},
+		{query: `tags:a`, err: ErrFieldType},
+		{query: "`level`:1", err: ErrUnknownField},
+		{query: "_time_second_:1", err: ErrUnknownField},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			node, err := Parse(tt.query)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			schema.Hash = tt.hash
+			got, err := Compile(node, schema, testDialect{})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Compile() error = %v, want %v", err, tt.err)
+			}
+			if got != tt.want {
+				t.Errorf("Compile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	node, _ := Parse("error")
+	if _, err := Compile(node, NewSchema(&db.BaseTable{CreateType: constx.TableCreateTypeExist}, nil), testDialect{}); !errors.Is(err, ErrFullText) {
+		t.Errorf("Compile() error = %v, want %v", err, ErrFullText)
+	}
+}
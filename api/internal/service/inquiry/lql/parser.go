@@ -0,0 +1,287 @@
+package lql
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/pkg/errors"
+)
+
+var ErrSyntax = errors.New("query syntax error")
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenWord
+	tokenString
+	tokenLParen
+	tokenRParen
+	tokenColon
+	tokenCompare
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind tokenKind
+	val  string
+	pos  int
+}
+
+func (t token) String() string {
+	if t.kind == tokenEOF {
+		return "end of query"
+	}
+	return `"` + t.val + `"`
+}
+
+// isWordRune reports whether r can be part of an unquoted word.
+func isWordRune(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	return !strings.ContainsRune(`()":<>=\`, r)
+}
+
+func lex(query string) ([]token, error) {
+	var (
+		res   = make([]token, 0)
+		runes = []rune(query)
+	)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(':
+			res = append(res, token{kind: tokenLParen, val: "(", pos: i})
+			i++
+		case r == ')':
+			res = append(res, token{kind: tokenRParen, val: ")", pos: i})
+			i++
+		case r == ':':
+			res = append(res, token{kind: tokenColon, val: ":", pos: i})
+			i++
+		case r == '>' || r == '<':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' {
+				op += "="
+			}
+			res = append(res, token{kind: tokenCompare, val: op, pos: i})
+			i += len(op)
+		case r == '"':
+			var sb strings.Builder
+			start := i
+			for i++; ; i++ {
+				if i >= len(runes) {
+					return nil, errors.Wrapf(ErrSyntax, "unterminated string at %d", start)
+				}
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					sb.WriteRune(runes[i])
+					continue
+				}
+				if runes[i] == '"' {
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+			}
+			res = append(res, token{kind: tokenString, val: sb.String(), pos: start})
+		case r == '-' && i+1 < len(runes) && (runes[i+1] == '(' || runes[i+1] == '"' || isWordRune(runes[i+1])) &&
+			(i == 0 || unicode.IsSpace(runes[i-1]) || runes[i-1] == '(') && !afterOperator(res):
+			// -term is a short NOT, -1 after : or a comparison is a value
+			res = append(res, token{kind: tokenNot, val: "-", pos: i})
+			i++
+		case isWordRune(r) || r == '\\':
+			var sb strings.Builder
+			start := i
+			for ; i < len(runes); i++ {
+				if runes[i] == '\\' && i+1 < len(runes) {
+					i++
+					sb.WriteRune(runes[i])
+					continue
+				}
+				if !isWordRune(runes[i]) {
+					break
+				}
+				sb.WriteRune(runes[i])
+			}
+			word := sb.String()
+			kind := tokenWord
+			switch strings.ToUpper(word) {
+			case OpAnd, "&&":
+				kind = tokenAnd
+			case OpOr, "||":
+				kind = tokenOr
+			case "NOT":
+				kind = tokenNot
+			}
+			if runes[start] == '\\' {
+				// an escaped keyword is a word
+				kind = tokenWord
+			}
+			res = append(res, token{kind: kind, val: word, pos: start})
+		default:
+			return nil, errors.Wrapf(ErrSyntax, "unexpected %q at %d", r, i)
+		}
+	}
+	return append(res, token{kind: tokenEOF, pos: len(runes)}), nil
+}
+
+func afterOperator(tokens []token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	kind := tokens[len(tokens)-1].kind
+	return kind == tokenColon || kind == tokenCompare
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses the query into its AST, an empty query or * returns nil which matches all logs.
+func Parse(query string) (Node, error) {
+	query = strings.TrimSpace(query)
+	if query == "" || query == "*" {
+		return nil, nil
+	}
+	tokens, err := lex(query)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	node, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokenEOF {
+		return nil, errors.Wrapf(ErrSyntax, "unexpected %s at %d", t, t.pos)
+	}
+	return node, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) or() (Node, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokenOr {
+		p.next()
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: OpOr, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (Node, error) {
+	left, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokenAnd:
+			p.next()
+		case tokenWord, tokenString, tokenLParen, tokenNot:
+			// terms next to each other are joined with AND
+		default:
+			return left, nil
+		}
+		right, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: OpAnd, Left: left, Right: right}
+	}
+}
+
+func (p *parser) not() (Node, error) {
+	if p.peek().kind != tokenNot {
+		return p.primary()
+	}
+	p.next()
+	expr, err := p.not()
+	if err != nil {
+		return nil, err
+	}
+	return &Not{Expr: expr}, nil
+}
+
+func (p *parser) primary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenLParen:
+		node, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if r := p.next(); r.kind != tokenRParen {
+			return nil, errors.Wrapf(ErrSyntax, "expected \")\" at %d, got %s", r.pos, r)
+		}
+		return node, nil
+	case tokenString:
+		return &Term{Value: t.val, Quoted: true}, nil
+	case tokenWord:
+		switch p.peek().kind {
+		case tokenColon:
+			p.next()
+			op := OpMatch
+			if p.peek().kind == tokenCompare {
+				op = p.next().val
+			}
+			return p.value(t.val, op)
+		case tokenCompare:
+			return p.value(t.val, p.next().val)
+		}
+		return &Term{Value: t.val}, nil
+	}
+	return nil, errors.Wrapf(ErrSyntax, "unexpected %s at %d", t, t.pos)
+}
+
+func (p *parser) value(field, op string) (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenWord:
+		return &Term{Field: field, Op: op, Value: t.val}, nil
+	case tokenString:
+		return &Term{Field: field, Op: op, Value: t.val, Quoted: true}, nil
+	}
+	return nil, errors.Wrapf(ErrSyntax, "expected a value of %s at %d, got %s", field, t.pos, t)
+}
+
+// Join joins the queries with AND, empty queries and * are skipped.
+func Join(queries ...string) string {
+	parts := make([]string, 0, len(queries))
+	for _, query := range queries {
+		if query = strings.TrimSpace(query); query != "" && query != "*" {
+			parts = append(parts, "("+query+")")
+		}
+	}
+	if len(parts) == 0 {
+		return "*"
+	}
+	if len(parts) == 1 {
+		return strings.TrimSuffix(strings.TrimPrefix(parts[0], "("), ")")
+	}
+	return strings.Join(parts, " "+OpAnd+" ")
+}
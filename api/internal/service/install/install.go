@@ -19,6 +19,10 @@ var models = []interface{}{
 	db.AlarmHistory{},
 
 	db.Collect{},
+	db.QueryHistory{},
+	db.IngestToken{},
+	db.TableHealth{},
+	db.ApiToken{},
 
 	db.BigdataWorkflow{},
 	db.BigdataSource{},
@@ -39,6 +43,11 @@ var models = []interface{}{
 	db.Alarm{},
 	db.AlarmCondition{},
 	db.AlarmChannel{},
+	db.AlarmSilence{},
+	db.AlarmOncall{},
+	db.AlarmEscalation{},
+	db.AlarmDelivery{},
+	db.AlarmGroup{},
 
 	db.User{},
 	db.Event{},
@@ -127,9 +127,9 @@ func (i *instanceManager) Load(id int) (factory.Operator, error) {
 	}
 	switch instance.Datasource {
 	case db.DatasourceClickHouse:
-		return obj.(*clickhouse.ClickHouseX), nil
+		return QueryCache.Wrap(obj.(*clickhouse.ClickHouseX)), nil
 	case db.DatasourceDatabend:
-		return obj.(*databend.Databend), nil
+		return QueryCache.Wrap(obj.(*databend.Databend)), nil
 	case db.DatasourceAgent:
 		return obj.(*agent.Agent), nil
 	case db.DatasourceLocal:
@@ -255,6 +255,9 @@ func InstanceCreate(req view.ReqCreateInstance) (obj db.BaseInstance, err error)
 		K8sConfigmap:     req.Configmap,
 		PrometheusTarget: req.PrometheusTarget,
 		Clusters:         make(db.Strings, 0),
+
+		QueryMaxConcurrency:   req.QueryMaxConcurrency,
+		QueryMaxExecutionTime: req.QueryMaxExecutionTime,
 	}
 	obj.Dsn = obj.SetDSN(strings.TrimSpace(req.Dsn))
 	if req.PrometheusTarget != "" {
@@ -0,0 +1,110 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/agent/search"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+const (
+	logsContextDefaultLines = 10
+	logsContextMaxLines     = 500
+	logsContextWindow       = int64(time.Hour / time.Second) // seconds searched on each side of the log
+)
+
+// LogsContext returns the logs before and after a log of the table which have the same source fields, as grep -C.
+func LogsContext(tableInfo db.BaseTable, req view.ReqLogsContext) (view.RespLogsContext, error) {
+	req.Before = logsContextLines(req.Before)
+	req.After = logsContextLines(req.After)
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return view.RespLogsContext{}, err
+	}
+	if reader, ok := factory.Unwrap(op).(factory.ContextReader); ok {
+		if req.Fields[search.InnerKeyFile] == "" || req.Fields[search.InnerKeyOffset] == "" || req.Fields[search.InnerKeySign] == "" {
+			return view.RespLogsContext{}, errors.Errorf("%s, %s and %s of the log are required", search.InnerKeyFile, search.InnerKeyOffset, search.InnerKeySign)
+		}
+		return reader.ContextLogs(req)
+	}
+	return logsContextByCursor(op, tableInfo, req)
+}
+
+func logsContextLines(n int) int {
+	if n <= 0 {
+		return logsContextDefaultLines
+	}
+	if n > logsContextMaxLines {
+		return logsContextMaxLines
+	}
+	return n
+}
+
+// logsContextByCursor reads the pages older and newer than the log with the query of the source fields,
+// the fields are checked against the analysis fields of the table by the query language.
+func logsContextByCursor(op factory.Operator, tableInfo db.BaseTable, req view.ReqLogsContext) (res view.RespLogsContext, err error) {
+	var cur factory.Cursor
+	switch {
+	case req.Cursor != "":
+		if cur, err = factory.ParseCursor(req.Cursor); err != nil {
+			return res, err
+		}
+	case req.Ts > 0:
+		// without the hash of the log, the other logs of the same time are left out
+		cur = factory.Cursor{Ts: req.Ts - 1, Tie: math.MaxUint64}
+	default:
+		return res, errors.New("cursor or ts of the log is required")
+	}
+	res.Query = logsContextQuery(req.Fields)
+	param := logsQueryParam(tableInfo, view.ReqQuery{QueryMode: view.QueryModeLanguage, Query: res.Query})
+	sec := req.Ts / int64(time.Second)
+	if req.Cursor != "" {
+		sec = cur.Ts / int64(time.Second)
+	}
+	param.ST, param.ET = sec-logsContextWindow, sec+logsContextWindow+1
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return res, errors.Wrap(err, "param prepare failed")
+	}
+
+	older := param
+	older.Cursor, older.Direction, older.PageSize = cur.String(), factory.CursorOlder, uint32(req.Before)
+	olderLogs, err := op.GetLogs(older, tableInfo.ID)
+	if err != nil {
+		return res, err
+	}
+	if req.Cursor == "" {
+		cur = factory.Cursor{Ts: req.Ts + 1, Asc: true}
+	}
+	newer := param
+	newer.Cursor, newer.Direction, newer.PageSize = cur.String(), factory.CursorNewer, uint32(req.After)
+	newerLogs, err := op.GetLogs(newer, tableInfo.ID)
+	if err != nil {
+		return res, err
+	}
+	// both pages are in descending order
+	reverseLogs(olderLogs.Logs)
+	reverseLogs(newerLogs.Logs)
+	res.Before, res.After = olderLogs.Logs, newerLogs.Logs
+	return res, nil
+}
+
+// logsContextQuery matches the values of the fields literally.
+func logsContextQuery(fields map[string]string) string {
+	names := make([]string, 0, len(fields))
+	for name := range fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	terms := make([]string, 0, len(names))
+	for _, name := range names {
+		terms = append(terms, (&lql.Term{Field: name, Op: lql.OpMatch, Value: fields[name], Quoted: true}).String())
+	}
+	return lql.Join(terms...)
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+// fakeContextOperator returns the pages of the directions in descending order as GetLogs does.
+type fakeContextOperator struct {
+	factory.Operator
+	pages map[string][]map[string]interface{}
+	calls []view.ReqQuery
+}
+
+func (o *fakeContextOperator) Prepare(param view.ReqQuery, table *db.BaseTable, isRegroup bool) (view.ReqQuery, error) {
+	return param, nil
+}
+
+func (o *fakeContextOperator) GetLogs(param view.ReqQuery, tid int) (view.RespQuery, error) {
+	o.calls = append(o.calls, param)
+	logs := append([]map[string]interface{}{}, o.pages[param.Direction]...)
+	return view.RespQuery{Logs: logs}, nil
+}
+
+func Test_logsContextByCursor(t *testing.T) {
+	hit := factory.Cursor{Ts: 1700000000123456789, Tie: 42, Seen: 1}
+	tests := []struct {
+		name   string
+		req    view.ReqLogsContext
+		older  factory.Cursor
+		newer  factory.Cursor
+		errors bool
+	}{
+		{
+			name:  "cursor",
+			req:   view.ReqLogsContext{Cursor: hit.String(), Before: 2, After: 3},
+			older: hit,
+			newer: hit,
+		},
+		{
+			name:  "ts",
+			req:   view.ReqLogsContext{Ts: hit.Ts, Before: 2, After: 3},
+			older: factory.Cursor{Ts: hit.Ts - 1, Tie: math.MaxUint64},
+			newer: factory.Cursor{Ts: hit.Ts + 1, Asc: true},
+		},
+		{
+			name:   "no log",
+			req:    view.ReqLogsContext{Before: 2, After: 3},
+			errors: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			op := &fakeContextOperator{pages: map[string][]map[string]interface{}{
+				factory.CursorOlder: {{"n": 2}, {"n": 1}},
+				factory.CursorNewer: {{"n": 5}, {"n": 4}, {"n": 3}},
+			}}
+			tt.req.Fields = map[string]string{"_pod_name_": "api-1", "_container_name_": `say "hi"`}
+			res, err := logsContextByCursor(op, db.BaseTable{Database: &db.BaseDatabase{}}, tt.req)
+			if tt.errors {
+				if err == nil {
+					t.Errorf("logsContextByCursor() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("logsContextByCursor() error = %v", err)
+			}
+			want := view.RespLogsContext{
+				Before: []map[string]interface{}{{"n": 1}, {"n": 2}},
+				After:  []map[string]interface{}{{"n": 3}, {"n": 4}, {"n": 5}},
+				Query:  `(_container_name_:"say \"hi\"") AND (_pod_name_:"api-1")`,
+			}
+			if !reflect.DeepEqual(res, want) {
+				t.Errorf("logsContextByCursor() = %+v, want %+v", res, want)
+			}
+			older, newer := op.calls[0], op.calls[1]
+			if older.Cursor != tt.older.String() || older.PageSize != 2 || older.Query != want.Query || older.QueryMode != view.QueryModeLanguage {
+				t.Errorf("older query = %+v", older)
+			}
+			if newer.Cursor != tt.newer.String() || newer.Direction != factory.CursorNewer || newer.PageSize != 3 {
+				t.Errorf("newer query = %+v", newer)
+			}
+			if sec := hit.Ts / 1e9; older.ST != sec-logsContextWindow || older.ET != sec+logsContextWindow+1 {
+				t.Errorf("time range = [%d, %d)", older.ST, older.ET)
+			}
+		})
+	}
+}
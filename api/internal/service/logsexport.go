@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"github.com/xitongsys/parquet-go/writer"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+const (
+	exportDefaultMaxRows = 100000
+	exportRowGroupSize   = 16 * 1024 * 1024 // bytes of a parquet row group kept in memory before it is written
+)
+
+var ExportContentTypes = map[string]string{
+	ExportFormatCSV:     "text/csv; charset=utf-8",
+	ExportFormatNDJSON:  "application/x-ndjson",
+	ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+// ExportMaxRows reads app.exportMaxRows, the cap of the rows of an export, 100000 by default.
+func ExportMaxRows() int {
+	if n := econf.GetInt("app.exportMaxRows"); n > 0 {
+		return n
+	}
+	return exportDefaultMaxRows
+}
+
+// LogsExport streams the logs of the prepared query to w in the format,
+// the hidden fields and the hash columns of the table are left out.
+func LogsExport(ctx context.Context, w io.Writer, op factory.Operator, tableInfo db.BaseTable, param view.ReqQuery, format string, limit int) (int, error) {
+	exporter, ok := factory.Unwrap(op).(factory.Exporter)
+	if !ok {
+		return 0, factory.ErrExportUnsupported
+	}
+	if limit <= 0 || limit > ExportMaxRows() {
+		limit = ExportMaxRows()
+	}
+	enc, err := newExportEncoder(format, w)
+	if err != nil {
+		return 0, err
+	}
+	ew := &exportWriter{enc: enc, skip: exportSkipFields(tableInfo.ID)}
+	if err = exporter.ExportLogs(ctx, param, tableInfo.ID, limit, ew); err != nil {
+		return ew.rows, err
+	}
+	return ew.rows, enc.close()
+}
+
+// exportSkipFields returns the hidden fields and the hash columns of the table.
+func exportSkipFields(tid int) map[string]bool {
+	res := make(map[string]bool)
+	hidden, _ := db.HiddenFieldList(egorm.Conds{"tid": tid})
+	for _, f := range hidden {
+		res[f.Field] = true
+	}
+	indexes, _ := db.IndexList(egorm.Conds{"tid": tid})
+	for _, index := range indexes {
+		if hashField, ok := index.GetHashFieldName(); ok {
+			res[hashField] = true
+		}
+	}
+	return res
+}
+
+type exportEncoder interface {
+	header(names []string) error
+	write(values []interface{}) error
+	close() error
+}
+
+// exportWriter drops the skipped columns and passes the rows to the encoder.
+type exportWriter struct {
+	enc   exportEncoder
+	skip  map[string]bool
+	keep  []int
+	names []string
+	row   []interface{}
+	rows  int
+}
+
+func (e *exportWriter) Columns(names []string) error {
+	e.keep = e.keep[:0]
+	e.names = make([]string, 0, len(names))
+	for idx, name := range names {
+		if !e.skip[name] {
+			e.keep = append(e.keep, idx)
+			e.names = append(e.names, name)
+		}
+	}
+	e.row = make([]interface{}, len(e.keep))
+	return e.enc.header(e.names)
+}
+
+func (e *exportWriter) Row(values []interface{}) error {
+	for i, idx := range e.keep {
+		e.row[i] = values[idx]
+	}
+	e.rows++
+	return e.enc.write(e.row)
+}
+
+func newExportEncoder(format string, w io.Writer) (exportEncoder, error) {
+	switch format {
+	case ExportFormatCSV:
+		return &csvEncoder{w: csv.NewWriter(w)}, nil
+	case ExportFormatNDJSON:
+		return &ndjsonEncoder{enc: json.NewEncoder(w)}, nil
+	case ExportFormatParquet:
+		return &parquetEncoder{w: w}, nil
+	}
+	return nil, errors.Errorf("unknown export format %s", format)
+}
+
+type csvEncoder struct {
+	w      *csv.Writer
+	record []string
+}
+
+func (c *csvEncoder) header(names []string) error {
+	c.record = make([]string, len(names))
+	return c.w.Write(names)
+}
+
+func (c *csvEncoder) write(values []interface{}) error {
+	for idx, v := range values {
+		c.record[idx] = exportString(v)
+	}
+	return c.w.Write(c.record)
+}
+
+func (c *csvEncoder) close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+type ndjsonEncoder struct {
+	enc   *json.Encoder
+	names []string
+}
+
+func (n *ndjsonEncoder) header(names []string) error {
+	n.names = names
+	return nil
+}
+
+func (n *ndjsonEncoder) write(values []interface{}) error {
+	line := make(map[string]interface{}, len(values))
+	for idx, v := range values {
+		line[n.names[idx]] = v
+	}
+	return n.enc.Encode(line)
+}
+
+func (n *ndjsonEncoder) close() error {
+	return nil
+}
+
+// parquetEncoder writes every column as an optional UTF8 string.
+type parquetEncoder struct {
+	w      io.Writer
+	pw     *writer.CSVWriter
+	values []string
+	record []*string
+}
+
+func (p *parquetEncoder) header(names []string) error {
+	md := make([]string, len(names))
+	for idx, name := range names {
+		md[idx] = fmt.Sprintf("name=%s, type=BYTE_ARRAY, convertedtype=UTF8, repetitiontype=OPTIONAL", name)
+	}
+	pw, err := writer.NewCSVWriterFromWriter(md, p.w, 1)
+	if err != nil {
+		return err
+	}
+	pw.RowGroupSize = exportRowGroupSize
+	p.pw = pw
+	p.values = make([]string, len(names))
+	p.record = make([]*string, len(names))
+	return nil
+}
+
+func (p *parquetEncoder) write(values []interface{}) error {
+	for idx, v := range values {
+		if v == nil {
+			p.record[idx] = nil
+			continue
+		}
+		p.values[idx] = exportString(v)
+		p.record[idx] = &p.values[idx]
+	}
+	return p.pw.WriteString(p.record)
+}
+
+func (p *parquetEncoder) close() error {
+	return p.pw.WriteStop()
+}
+
+// exportString formats a column value, arrays and maps are written as JSON.
+func exportString(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case []byte:
+		return string(val)
+	case time.Time:
+		return val.Format(time.RFC3339Nano)
+	case float32:
+		return strconv.FormatFloat(float64(val), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return cast.ToString(val)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return cast.ToString(v)
+	}
+	return string(b)
+}
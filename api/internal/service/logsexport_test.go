@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/xitongsys/parquet-go-source/buffer"
+	"github.com/xitongsys/parquet-go/reader"
+)
+
+func exportRows(t *testing.T, format string) []byte {
+	var buf bytes.Buffer
+	enc, err := newExportEncoder(format, &buf)
+	if err != nil {
+		t.Fatalf("newExportEncoder() error = %v", err)
+	}
+	w := &exportWriter{enc: enc, skip: map[string]bool{"_inner_siphash_url_": true}}
+	if err = w.Columns([]string{"msg", "_inner_siphash_url_", "ts", "tags"}); err != nil {
+		t.Fatalf("Columns() error = %v", err)
+	}
+	ts := time.Date(2023, 1, 2, 3, 4, 5, 600, time.UTC)
+	for _, row := range [][]interface{}{
+		{`say "hi"`, uint64(1), ts, []string{"a", "b"}},
+		{nil, uint64(2), ts, nil},
+	} {
+		if err = w.Row(row); err != nil {
+			t.Fatalf("Row() error = %v", err)
+		}
+	}
+	if err = enc.close(); err != nil {
+		t.Fatalf("close() error = %v", err)
+	}
+	if w.rows != 2 {
+		t.Errorf("rows = %d, want 2", w.rows)
+	}
+	return buf.Bytes()
+}
+
+func Test_exportEncoder(t *testing.T) {
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: ExportFormatCSV,
+			want: "msg,ts,tags\n" +
+				`"say ""hi""",2023-01-02T03:04:05.0000006Z,"[""a"",""b""]"` + "\n" +
+				",2023-01-02T03:04:05.0000006Z,\n",
+		},
+		{
+			format: ExportFormatNDJSON,
+			want: `{"msg":"say \"hi\"","tags":["a","b"],"ts":"2023-01-02T03:04:05.0000006Z"}` + "\n" +
+				`{"msg":null,"tags":null,"ts":"2023-01-02T03:04:05.0000006Z"}` + "\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if got := string(exportRows(t, tt.format)); got != tt.want {
+				t.Errorf("export = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_parquetEncoder(t *testing.T) {
+	f, err := buffer.NewBufferFile(exportRows(t, ExportFormatParquet))
+	if err != nil {
+		t.Fatalf("NewBufferFile() error = %v", err)
+	}
+	pr, err := reader.NewParquetColumnReader(f, 1)
+	if err != nil {
+		t.Fatalf("NewParquetColumnReader() error = %v", err)
+	}
+	defer pr.ReadStop()
+	if n := pr.GetNumRows(); n != 2 {
+		t.Fatalf("GetNumRows() = %d, want 2", n)
+	}
+	want := [][]interface{}{
+		{`say "hi"`, nil},
+		{"2023-01-02T03:04:05.0000006Z", "2023-01-02T03:04:05.0000006Z"},
+		{`["a","b"]`, nil},
+	}
+	for idx, column := range want {
+		values, _, _, err := pr.ReadColumnByIndex(int64(idx), 2)
+		if err != nil {
+			t.Fatalf("ReadColumnByIndex(%d) error = %v", idx, err)
+		}
+		if !reflect.DeepEqual(values, column) {
+			t.Errorf("column %d = %v, want %v", idx, values, column)
+		}
+	}
+}
@@ -0,0 +1,325 @@
+package service
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/cetus/pkg/xgo"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/pattern"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	patternSlices     = 20  // points of the sparklines, every slice of the time range is sampled alike
+	patternParallel   = 4   // slices read at once
+	patternSamples    = 3   // sample logs of a pattern
+	patternMaxResults = 200 // patterns returned
+)
+
+const (
+	patternStatusNew  = "new"
+	patternStatusUp   = "up"
+	patternStatusGone = "gone"
+	patternStatusDown = "down"
+	patternStatusSame = "same"
+)
+
+var patternStatusRank = map[string]int{
+	patternStatusNew:  0,
+	patternStatusUp:   1,
+	patternStatusGone: 2,
+	patternStatusDown: 3,
+	patternStatusSame: 4,
+}
+
+// LogsPatterns clusters the logs of the query into templates, as the query of /tables/:id/logs.
+// The logs are sampled alike from every slice of the time range and the counts are estimated from the samples.
+func LogsPatterns(tableInfo db.BaseTable, param view.ReqQuery) (res view.RespLogsPatterns, err error) {
+	op, param, err := logsPatternParam(tableInfo, param)
+	if err != nil {
+		return res, err
+	}
+	slices := newPatternSlices(param.ST, param.ET)
+	if err = readPatternSlices(op, tableInfo.ID, param, slices); err != nil {
+		return res, err
+	}
+	m := minePatterns(param.Field, slices)
+	res = view.RespLogsPatterns{
+		Field:    param.Field,
+		Sampled:  m.sampled,
+		Buckets:  patternBuckets(slices),
+		Patterns: make([]view.LogsPattern, 0, len(m.clusters)),
+	}
+	for _, c := range m.clusters {
+		item := m.pattern(c, 0, len(slices))
+		res.Total += item.Count
+		res.Patterns = append(res.Patterns, item)
+	}
+	sort.SliceStable(res.Patterns, func(i, j int) bool {
+		return res.Patterns[i].Count > res.Patterns[j].Count
+	})
+	if len(res.Patterns) > patternMaxResults {
+		res.Patterns = res.Patterns[:patternMaxResults]
+	}
+	return res, nil
+}
+
+// LogsPatternsDiff clusters the logs of the time range and of the previous range of the same length together,
+// so that the patterns which are new, gone or changed are found.
+func LogsPatternsDiff(tableInfo db.BaseTable, param view.ReqQuery) (res view.RespLogsPatternsDiff, err error) {
+	op, param, err := logsPatternParam(tableInfo, param)
+	if err != nil {
+		return res, err
+	}
+	length := param.ET - param.ST
+	previous := newPatternSlices(param.ST-length, param.ST)
+	current := newPatternSlices(param.ST, param.ET)
+	slices := append(previous, current...)
+	if err = readPatternSlices(op, tableInfo.ID, param, slices); err != nil {
+		return res, err
+	}
+	m := minePatterns(param.Field, slices)
+	res = view.RespLogsPatternsDiff{
+		Field:      param.Field,
+		Sampled:    m.sampled,
+		Buckets:    patternBuckets(slices),
+		PreviousST: param.ST - length,
+		PreviousET: param.ST,
+		Patterns:   make([]view.LogsPatternDiff, 0, len(m.clusters)),
+	}
+	for _, c := range m.clusters {
+		item := view.LogsPatternDiff{
+			LogsPattern: m.pattern(c, len(previous), len(slices)),
+			Previous:    m.pattern(c, 0, len(previous)).Count,
+		}
+		item.Sparkline = m.sparkline(c)
+		item.Status = patternStatus(item.Count, item.Previous)
+		res.Total += item.Count
+		res.Patterns = append(res.Patterns, item)
+	}
+	sort.SliceStable(res.Patterns, func(i, j int) bool {
+		a, b := res.Patterns[i], res.Patterns[j]
+		if patternStatusRank[a.Status] != patternStatusRank[b.Status] {
+			return patternStatusRank[a.Status] < patternStatusRank[b.Status]
+		}
+		return a.Count+a.Previous > b.Count+b.Previous
+	})
+	if len(res.Patterns) > patternMaxResults {
+		res.Patterns = res.Patterns[:patternMaxResults]
+	}
+	return res, nil
+}
+
+// logsPatternParam prepares the query, the field clustered is the raw log field by default.
+func logsPatternParam(tableInfo db.BaseTable, param view.ReqQuery) (factory.Operator, view.ReqQuery, error) {
+	field := param.Field
+	if field == "" {
+		field = "_raw_log_"
+		if tableInfo.CreateType == constx.TableCreateTypeExist {
+			field = tableInfo.RawLogField
+		}
+	}
+	if field == "" {
+		return nil, param, errors.New("field is required, the table has no raw log field")
+	}
+	if param.ET <= param.ST {
+		param.ST, param.ET = time.Now().Add(-15*time.Minute).Unix(), time.Now().Unix()
+	}
+	param = logsQueryParam(tableInfo, param)
+	param.Field = ""
+	op, err := InstanceManager.Load(tableInfo.Database.Iid)
+	if err != nil {
+		return nil, param, err
+	}
+	if param, err = op.Prepare(param, &tableInfo, false); err != nil {
+		return nil, param, errors.Wrap(err, "param prepare failed")
+	}
+	if param.Query == "" {
+		return nil, param, errors.New("query parameter error")
+	}
+	param.Field = field
+	return op, param, nil
+}
+
+// patternSampleSize reads app.patternSampleSize, 5000 logs by default.
+func patternSampleSize() int {
+	if size := econf.GetInt("app.patternSampleSize"); size > 0 {
+		return size
+	}
+	return 5000
+}
+
+// patternSlice is a part of the time range, the logs are its newest logs and weight is the logs of the slice
+// each of them stands for.
+type patternSlice struct {
+	st, et int64
+	logs   []map[string]interface{}
+	weight float64
+}
+
+func newPatternSlices(st, et int64) []*patternSlice {
+	n := int64(patternSlices)
+	if et-st < n {
+		n = et - st
+	}
+	res := make([]*patternSlice, 0, n)
+	for i := int64(0); i < n; i++ {
+		res = append(res, &patternSlice{st: st + (et-st)*i/n, et: st + (et-st)*(i+1)/n, weight: 1})
+	}
+	return res
+}
+
+func patternBuckets(slices []*patternSlice) []int64 {
+	res := make([]int64, 0, len(slices))
+	for _, s := range slices {
+		res = append(res, s.st)
+	}
+	return res
+}
+
+// readPatternSlices reads the slices a few at a time, a full slice is counted to weigh its logs.
+func readPatternSlices(op factory.Operator, tid int, param view.ReqQuery, slices []*patternSlice) error {
+	pageSize := patternSampleSize() / patternSlices
+	if pageSize <= 0 {
+		pageSize = 1
+	}
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		errRead error
+		sem     = make(chan struct{}, patternParallel)
+	)
+	for _, s := range slices {
+		s := s
+		wg.Add(1)
+		sem <- struct{}{}
+		xgo.Go(func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			if err := readPatternSlice(op, tid, param, s, pageSize); err != nil {
+				mu.Lock()
+				errRead = err
+				mu.Unlock()
+			}
+		})
+	}
+	wg.Wait()
+	return errRead
+}
+
+func readPatternSlice(op factory.Operator, tid int, param view.ReqQuery, s *patternSlice, pageSize int) error {
+	param.ST, param.ET = s.st, s.et
+	param.Page, param.PageSize, param.Cursor, param.Direction = 1, uint32(pageSize), "", ""
+	param.Field = ""
+	res, err := op.GetLogs(param, tid)
+	if err != nil {
+		return err
+	}
+	s.logs = res.Logs
+	if len(s.logs) < pageSize {
+		return nil
+	}
+	total, err := op.Count(param)
+	if err != nil {
+		return err
+	}
+	if total > uint64(len(s.logs)) {
+		s.weight = float64(total) / float64(len(s.logs))
+	}
+	return nil
+}
+
+type patternMiner struct {
+	sampled  int
+	clusters []*pattern.Cluster // in the order they were created
+	stats    map[*pattern.Cluster]*patternStats
+}
+
+type patternStats struct {
+	buckets []float64 // estimated logs of every slice
+	samples []map[string]interface{}
+}
+
+// minePatterns clusters the logs of the slices from the newest, the logs without the field are left out.
+func minePatterns(field string, slices []*patternSlice) *patternMiner {
+	m := &patternMiner{stats: make(map[*pattern.Cluster]*patternStats)}
+	drain := pattern.NewDrain(pattern.Config{})
+	for i := len(slices) - 1; i >= 0; i-- {
+		for _, log := range slices[i].logs {
+			value, ok := log[field]
+			if !ok || value == nil {
+				continue
+			}
+			c := drain.Add(cast.ToString(value))
+			stats, ok := m.stats[c]
+			if !ok {
+				stats = &patternStats{buckets: make([]float64, len(slices))}
+				m.stats[c] = stats
+			}
+			stats.buckets[i] += slices[i].weight
+			if len(stats.samples) < patternSamples {
+				stats.samples = append(stats.samples, log)
+			}
+			m.sampled++
+		}
+	}
+	m.clusters = drain.Clusters()
+	return m
+}
+
+// pattern returns the pattern of the cluster with the sparkline of the slices in [from, to).
+func (m *patternMiner) pattern(c *pattern.Cluster, from, to int) view.LogsPattern {
+	stats := m.stats[c]
+	res := view.LogsPattern{
+		Id:        patternId(c.Template()),
+		Template:  c.Template(),
+		Sampled:   c.Size,
+		Sparkline: make([]uint64, 0, to-from),
+		Samples:   stats.samples,
+	}
+	sum := 0.0
+	for _, n := range stats.buckets[from:to] {
+		sum += n
+		res.Sparkline = append(res.Sparkline, uint64(math.Round(n)))
+	}
+	res.Count = uint64(math.Round(sum))
+	return res
+}
+
+func (m *patternMiner) sparkline(c *pattern.Cluster) []uint64 {
+	return m.pattern(c, 0, len(m.stats[c].buckets)).Sparkline
+}
+
+func patternId(template string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(template))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func patternStatus(count, previous uint64) string {
+	switch {
+	case previous == 0:
+		return patternStatusNew
+	case count == 0:
+		return patternStatusGone
+	case count >= 2*previous:
+		return patternStatusUp
+	case 2*count <= previous:
+		return patternStatusDown
+	default:
+		return patternStatusSame
+	}
+}
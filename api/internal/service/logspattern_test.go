@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+// fakePatternOperator returns the logs of the slice starting at ST, n logs of a slice are counted as total[ST].
+type fakePatternOperator struct {
+	factory.Operator
+	mu    sync.Mutex
+	logs  map[int64][]map[string]interface{}
+	total map[int64]uint64
+	calls []view.ReqQuery
+}
+
+func (o *fakePatternOperator) GetLogs(param view.ReqQuery, tid int) (view.RespQuery, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.calls = append(o.calls, param)
+	logs := o.logs[param.ST]
+	if len(logs) > int(param.PageSize) {
+		logs = logs[:param.PageSize]
+	}
+	return view.RespQuery{Logs: logs}, nil
+}
+
+func (o *fakePatternOperator) Count(param view.ReqQuery) (uint64, error) {
+	return o.total[param.ST], nil
+}
+
+func patternLogs(lines ...string) []map[string]interface{} {
+	res := make([]map[string]interface{}, 0, len(lines))
+	for _, line := range lines {
+		res = append(res, map[string]interface{}{"_raw_log_": line})
+	}
+	return res
+}
+
+func Test_newPatternSlices(t *testing.T) {
+	tests := []struct {
+		st, et int64
+		want   []int64
+	}{
+		{st: 100, et: 140, want: []int64{100, 102, 104, 106, 108, 110, 112, 114, 116, 118, 120, 122, 124, 126, 128, 130, 132, 134, 136, 138}},
+		{st: 100, et: 103, want: []int64{100, 101, 102}},
+	}
+	for _, tt := range tests {
+		slices := newPatternSlices(tt.st, tt.et)
+		if got := patternBuckets(slices); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("newPatternSlices(%d, %d) = %v, want %v", tt.st, tt.et, got, tt.want)
+		}
+		if last := slices[len(slices)-1]; last.et != tt.et {
+			t.Errorf("newPatternSlices(%d, %d) ends at %d", tt.st, tt.et, last.et)
+		}
+	}
+}
+
+func Test_readPatternSlices(t *testing.T) {
+	pageSize := patternSampleSize() / patternSlices
+	full := make([]string, pageSize+10)
+	for i := range full {
+		full[i] = fmt.Sprintf("request %d done", i)
+	}
+	op := &fakePatternOperator{
+		logs: map[int64][]map[string]interface{}{
+			100: patternLogs("user bob logged in", "user alice logged in"),
+			101: patternLogs(full...),
+		},
+		total: map[int64]uint64{101: uint64(pageSize * 4)},
+	}
+	slices := newPatternSlices(100, 103)
+	if err := readPatternSlices(op, 1, view.ReqQuery{Field: "_raw_log_", Cursor: "c"}, slices); err != nil {
+		t.Fatalf("readPatternSlices() error = %v", err)
+	}
+	if len(slices[0].logs) != 2 || slices[0].weight != 1 {
+		t.Errorf("slice 0 = %d logs weight %v", len(slices[0].logs), slices[0].weight)
+	}
+	if len(slices[1].logs) != pageSize || slices[1].weight != 4 {
+		t.Errorf("slice 1 = %d logs weight %v, want %d weight 4", len(slices[1].logs), slices[1].weight, pageSize)
+	}
+	for _, call := range op.calls {
+		if call.Field != "" || call.Cursor != "" || call.PageSize != uint32(pageSize) || call.ET-call.ST != 1 {
+			t.Errorf("slice query = %+v", call)
+		}
+	}
+}
+
+func Test_minePatterns(t *testing.T) {
+	slices := newPatternSlices(100, 104)
+	slices[0].logs = patternLogs("user bob logged in", "disk full")
+	slices[2].logs = patternLogs("user alice logged in", "user carol logged in")
+	slices[2].weight = 3
+	slices[3].logs = append(patternLogs("user dave logged in"), map[string]interface{}{"msg": "no raw log"})
+	m := minePatterns("_raw_log_", slices)
+	if m.sampled != 5 || len(m.clusters) != 2 {
+		t.Fatalf("minePatterns() sampled %d clusters %d, want 5 and 2", m.sampled, len(m.clusters))
+	}
+	got := m.pattern(m.clusters[0], 0, len(slices))
+	want := view.LogsPattern{
+		Id:        patternId("user <*> logged in"),
+		Template:  "user <*> logged in",
+		Count:     8,
+		Sampled:   4,
+		Sparkline: []uint64{1, 0, 6, 1},
+		Samples:   patternLogs("user dave logged in", "user alice logged in", "user carol logged in"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("pattern() = %+v, want %+v", got, want)
+	}
+	if previous := m.pattern(m.clusters[0], 0, 2); previous.Count != 1 || len(previous.Sparkline) != 2 {
+		t.Errorf("pattern() of the first slices = %+v", previous)
+	}
+}
+
+func Test_patternStatus(t *testing.T) {
+	tests := []struct {
+		count, previous uint64
+		want            string
+	}{
+		{count: 5, previous: 0, want: patternStatusNew},
+		{count: 0, previous: 5, want: patternStatusGone},
+		{count: 10, previous: 5, want: patternStatusUp},
+		{count: 2, previous: 5, want: patternStatusDown},
+		{count: 6, previous: 5, want: patternStatusSame},
+	}
+	for _, tt := range tests {
+		if got := patternStatus(tt.count, tt.previous); got != tt.want {
+			t.Errorf("patternStatus(%d, %d) = %s, want %s", tt.count, tt.previous, got, tt.want)
+		}
+	}
+}
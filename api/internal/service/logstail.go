@@ -0,0 +1,310 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gotomicro/cetus/pkg/xgo"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+	"golang.org/x/time/rate"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	tailBatchSize  = 500 // logs read by a poll
+	tailBufferSize = 8   // batches waiting for a slow client before the polls stop
+	tailHeartbeat  = 15 * time.Second
+)
+
+var ErrLogsTailLimit = errors.New("too many live tails on the instance")
+
+type logsTailConfig struct {
+	maxPerInstance int
+	rateLimit      int // logs per second sent to a tail
+	interval       time.Duration
+}
+
+// logsTails counts the live tails of every instance, a tail lives as long as the request which opened it.
+type logsTails struct {
+	mu      sync.Mutex
+	conf    logsTailConfig
+	running map[int]int
+}
+
+// NewLogsTails reads the app config:
+// tailMaxPerInstance 10, tailRateLimit 200 logs per second and tailInterval 2s by default.
+func NewLogsTails() *logsTails {
+	conf := logsTailConfig{
+		maxPerInstance: econf.GetInt("app.tailMaxPerInstance"),
+		rateLimit:      econf.GetInt("app.tailRateLimit"),
+		interval:       econf.GetDuration("app.tailInterval"),
+	}
+	if conf.maxPerInstance <= 0 {
+		conf.maxPerInstance = 10
+	}
+	if conf.rateLimit <= 0 {
+		conf.rateLimit = 200
+	}
+	if conf.interval <= 0 {
+		conf.interval = 2 * time.Second
+	}
+	return &logsTails{conf: conf, running: make(map[int]int)}
+}
+
+// Open starts a tail of the log query of the table, as the query of /tables/:id/logs.
+// The tail must be closed to free its place on the instance.
+func (s *logsTails) Open(tableInfo db.BaseTable, param view.ReqQuery) (*logsTail, error) {
+	param = logsQueryParam(tableInfo, param)
+	ins, err := db.InstanceInfo(invoker.Db, tableInfo.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	op, err := InstanceManager.Load(ins.ID)
+	if err != nil {
+		return nil, err
+	}
+	param, err = op.Prepare(param, &tableInfo, false)
+	if err != nil {
+		return nil, errors.Wrap(err, "param prepare failed")
+	}
+	if param.Query == "" {
+		return nil, errors.New("query parameter error")
+	}
+	if err = s.acquire(ins.ID); err != nil {
+		return nil, err
+	}
+	tail := newLogsTail(op, tableInfo.ID, param, s.conf)
+	// agent and local instances have no order key, their logs are read by time windows
+	tail.windowed = ins.Datasource == db.DatasourceAgent || ins.Datasource == db.DatasourceLocal
+	tail.release = func() { s.release(ins.ID) }
+	return tail, nil
+}
+
+func (s *logsTails) acquire(iid int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[iid] >= s.conf.maxPerInstance {
+		return errors.Wrapf(ErrLogsTailLimit, "limit %d", s.conf.maxPerInstance)
+	}
+	s.running[iid]++
+	return nil
+}
+
+func (s *logsTails) release(iid int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.running[iid]--; s.running[iid] <= 0 {
+		delete(s.running, iid)
+	}
+}
+
+// logsTail polls the logs newer than the newest log it has sent.
+type logsTail struct {
+	op       factory.Operator
+	tid      int
+	param    view.ReqQuery
+	interval time.Duration
+	limiter  *rate.Limiter
+	release  func()
+	polled   bool
+	hwm      string // cursor of the newest log read, the high-water mark
+	windowed bool
+	since    int64            // second of the newest log read by the windows
+	seen     map[string]int64 // logs of the last window which can be read again by the next one
+}
+
+func newLogsTail(op factory.Operator, tid int, param view.ReqQuery, conf logsTailConfig) *logsTail {
+	param.Cursor, param.Direction, param.Page = "", "", 1
+	return &logsTail{
+		op:       op,
+		tid:      tid,
+		param:    param,
+		interval: conf.interval,
+		limiter:  rate.NewLimiter(rate.Limit(conf.rateLimit), tailBatchSize),
+		release:  func() {},
+		seen:     make(map[string]int64),
+	}
+}
+
+func (t *logsTail) Close() {
+	t.release()
+}
+
+// Follow sends the batches of new logs until ctx is done or send fails,
+// send is called with nil every heartbeat without logs so that the idle connections are kept.
+// The polls wait while the client is slow to take the batches.
+func (t *logsTail) Follow(ctx context.Context, send func(*view.RespLogsTail) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if jop, ok := factory.Unwrap(t.op).(factory.JobOperator); ok {
+		// the running poll stops with the request
+		t.op = jop.WithJob(factory.Job{Ctx: ctx, QueryId: uuid.NewString()})
+	}
+	batches := make(chan view.RespLogsTail, tailBufferSize)
+	errc := make(chan error, 1)
+	xgo.Go(func() { errc <- t.run(ctx, batches) })
+	heartbeat := time.NewTicker(tailHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case batch := <-batches:
+			if err := send(&batch); err != nil {
+				return err
+			}
+			heartbeat.Reset(tailHeartbeat)
+		case <-heartbeat.C:
+			if err := send(nil); err != nil {
+				return err
+			}
+		case err := <-errc:
+			for len(batches) > 0 {
+				batch := <-batches
+				if errSend := send(&batch); errSend != nil {
+					return errSend
+				}
+			}
+			return err
+		}
+	}
+}
+
+// run polls every interval until ctx is done, a poll waits until there is room for its batch in out.
+func (t *logsTail) run(ctx context.Context, out chan<- view.RespLogsTail) error {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+	for {
+		res, err := t.poll(time.Now())
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		if len(res.Logs) > 0 || res.Dropped > 0 {
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+}
+
+// poll reads the new logs and samples them down to the rate limit.
+// The first poll reads the newest page of the query as tail -n does.
+func (t *logsTail) poll(now time.Time) (res view.RespLogsTail, err error) {
+	param := t.param
+	param.ET = now.Unix() + 1
+	if t.polled {
+		param.PageSize = tailBatchSize
+	}
+	var logs []map[string]interface{}
+	if t.windowed {
+		logs, err = t.pollWindow(param)
+	} else {
+		logs, res.Skipped, err = t.pollCursor(param)
+	}
+	if err != nil {
+		return res, err
+	}
+	t.polled = true
+	res.Logs, res.Dropped = sampleLogs(logs, int(t.limiter.TokensAt(now)))
+	t.limiter.AllowN(now, len(res.Logs))
+	return res, nil
+}
+
+// pollCursor reads the logs after the high-water mark, a full page means the tail fell behind
+// the rate of the table and the newest page is read instead.
+func (t *logsTail) pollCursor(param view.ReqQuery) ([]map[string]interface{}, bool, error) {
+	skipped := false
+	param.Cursor, param.Direction = "", factory.CursorOlder
+	if t.hwm != "" {
+		param.Cursor, param.Direction = t.hwm, factory.CursorNewer
+	}
+	res, err := t.op.GetLogs(param, t.tid)
+	if err != nil {
+		return nil, false, err
+	}
+	if t.hwm != "" && len(res.Logs) >= int(param.PageSize) {
+		param.Cursor, param.Direction = "", factory.CursorOlder
+		if res, err = t.op.GetLogs(param, t.tid); err != nil {
+			return nil, false, err
+		}
+		skipped = true
+	}
+	if res.Newer != "" {
+		t.hwm = res.Newer
+	}
+	reverseLogs(res.Logs)
+	return res.Logs, skipped, nil
+}
+
+// pollWindow reads the logs from the second of the newest log read,
+// the logs of that second which were sent by the last window are left out.
+func (t *logsTail) pollWindow(param view.ReqQuery) ([]map[string]interface{}, error) {
+	if t.since > param.ST {
+		param.ST = t.since
+	}
+	res, err := t.op.GetLogs(param, t.tid)
+	if err != nil {
+		return nil, err
+	}
+	logs := make([]map[string]interface{}, 0, len(res.Logs))
+	for _, log := range res.Logs {
+		key, _ := json.Marshal(log)
+		if _, ok := t.seen[string(key)]; ok {
+			continue
+		}
+		sec := cast.ToInt64(log[db.TimeFieldSecond])
+		t.seen[string(key)] = sec
+		if sec > t.since {
+			t.since = sec
+		}
+		logs = append(logs, log)
+	}
+	for key, sec := range t.seen {
+		if sec < t.since {
+			delete(t.seen, key)
+		}
+	}
+	sort.SliceStable(logs, func(i, j int) bool {
+		return cast.ToInt64(logs[i][db.TimeFieldSecond]) < cast.ToInt64(logs[j][db.TimeFieldSecond])
+	})
+	return logs, nil
+}
+
+// sampleLogs keeps n logs evenly spaced over the batch, the newest log is always kept.
+func sampleLogs(logs []map[string]interface{}, n int) ([]map[string]interface{}, int) {
+	if len(logs) <= n {
+		return logs, 0
+	}
+	if n <= 0 {
+		return []map[string]interface{}{}, len(logs)
+	}
+	res := make([]map[string]interface{}, n)
+	for i := range res {
+		res[i] = logs[(i+1)*len(logs)/n-1]
+	}
+	return res, len(logs) - n
+}
+
+func reverseLogs(logs []map[string]interface{}) {
+	for i, j := 0, len(logs)-1; i < j; i, j = i+1, j-1 {
+		logs[i], logs[j] = logs[j], logs[i]
+	}
+}
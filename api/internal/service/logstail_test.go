@@ -0,0 +1,170 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+// fakeTailOperator returns the pages in order and records the queries.
+type fakeTailOperator struct {
+	factory.Operator
+	pages []view.RespQuery
+	calls []view.ReqQuery
+}
+
+func (o *fakeTailOperator) GetLogs(param view.ReqQuery, tid int) (view.RespQuery, error) {
+	o.calls = append(o.calls, param)
+	res := o.pages[0]
+	o.pages = o.pages[1:]
+	return res, nil
+}
+
+func tailLogs(ns ...int) []map[string]interface{} {
+	res := make([]map[string]interface{}, 0, len(ns))
+	for _, n := range ns {
+		res = append(res, map[string]interface{}{db.TimeFieldSecond: int64(n / 10), "n": n})
+	}
+	return res
+}
+
+func tailNs(logs []map[string]interface{}) []int {
+	res := make([]int, 0, len(logs))
+	for _, log := range logs {
+		res = append(res, log["n"].(int))
+	}
+	return res
+}
+
+func Test_logsTail_pollCursor(t *testing.T) {
+	full := make([]int, tailBatchSize)
+	for i := range full {
+		full[i] = 100 + tailBatchSize - i
+	}
+	op := &fakeTailOperator{pages: []view.RespQuery{
+		{Logs: tailLogs(20, 10), Newer: "c20"},
+		{Logs: []map[string]interface{}{}},
+		{Logs: tailLogs(40, 30), Newer: "c40"},
+		{Logs: tailLogs(full...), Newer: "cfull"},
+		{Logs: tailLogs(900, 800), Newer: "c900"},
+	}}
+	tail := newLogsTail(op, 1, view.ReqQuery{PageSize: 20, Cursor: "c1"}, logsTailConfig{rateLimit: 1000, interval: time.Second})
+	now := time.Now()
+	tests := []struct {
+		want    []int
+		skipped bool
+		cursor  string
+	}{
+		{want: []int{10, 20}},
+		{want: []int{}, cursor: "c20"},
+		{want: []int{30, 40}, cursor: "c20"},
+		{want: []int{800, 900}, skipped: true, cursor: "c40"},
+	}
+	for idx, tt := range tests {
+		calls := len(op.calls)
+		res, err := tail.poll(now.Add(time.Duration(idx) * time.Second))
+		if err != nil {
+			t.Fatalf("poll(%d) error = %v", idx, err)
+		}
+		if got := tailNs(res.Logs); !reflect.DeepEqual(got, tt.want) || res.Skipped != tt.skipped || res.Dropped != 0 {
+			t.Errorf("poll(%d) = %v skipped %v dropped %d, want %v skipped %v", idx, got, res.Skipped, res.Dropped, tt.want, tt.skipped)
+		}
+		if call := op.calls[calls]; call.Cursor != tt.cursor {
+			t.Errorf("poll(%d) cursor = %q, want %q", idx, call.Cursor, tt.cursor)
+		}
+	}
+	if op.calls[0].PageSize != 20 || op.calls[0].Direction != factory.CursorOlder || op.calls[1].PageSize != tailBatchSize || op.calls[1].Direction != factory.CursorNewer {
+		t.Errorf("poll() queries = %+v", op.calls[:2])
+	}
+	if last := op.calls[len(op.calls)-1]; last.Cursor != "" || tail.hwm != "c900" {
+		t.Errorf("poll() after skip cursor = %q hwm = %q", last.Cursor, tail.hwm)
+	}
+}
+
+func Test_logsTail_pollWindow(t *testing.T) {
+	op := &fakeTailOperator{pages: []view.RespQuery{
+		{Logs: tailLogs(21, 20, 10)},
+		{Logs: tailLogs(30, 22, 21, 20)},
+	}}
+	tail := newLogsTail(op, 1, view.ReqQuery{ST: 1, PageSize: 20}, logsTailConfig{rateLimit: 1000, interval: time.Second})
+	tail.windowed = true
+	now := time.Now()
+	for idx, want := range [][]int{{10, 21, 20}, {22, 30}} {
+		res, err := tail.poll(now)
+		if err != nil {
+			t.Fatalf("poll(%d) error = %v", idx, err)
+		}
+		if got := tailNs(res.Logs); !reflect.DeepEqual(got, want) {
+			t.Errorf("poll(%d) = %v, want %v", idx, got, want)
+		}
+	}
+	if st := op.calls[1].ST; st != 2 {
+		t.Errorf("poll() window start = %d, want 2", st)
+	}
+	if len(tail.seen) != 1 {
+		t.Errorf("seen = %v, want the logs of the last second", tail.seen)
+	}
+}
+
+func Test_logsTail_sample(t *testing.T) {
+	ns := make([]int, 50)
+	for i := range ns {
+		ns[i] = 49 - i
+	}
+	op := &fakeTailOperator{pages: []view.RespQuery{{Logs: tailLogs(ns...)}}}
+	tail := newLogsTail(op, 1, view.ReqQuery{PageSize: 50}, logsTailConfig{rateLimit: 10, interval: time.Second})
+	now := time.Now()
+	tail.limiter.AllowN(now, tailBatchSize-5)
+	res, err := tail.poll(now)
+	if err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	if got, want := tailNs(res.Logs), []int{9, 19, 29, 39, 49}; !reflect.DeepEqual(got, want) || res.Dropped != 45 {
+		t.Errorf("poll() = %v dropped %d, want %v dropped 45", got, res.Dropped, want)
+	}
+	if tokens := tail.limiter.TokensAt(now); tokens != 0 {
+		t.Errorf("tokens = %v, want 0", tokens)
+	}
+}
+
+func Test_sampleLogs(t *testing.T) {
+	logs := tailLogs(1, 2, 3, 4, 5, 6)
+	tests := []struct {
+		n       int
+		want    []int
+		dropped int
+	}{
+		{n: 10, want: []int{1, 2, 3, 4, 5, 6}},
+		{n: 3, want: []int{2, 4, 6}, dropped: 3},
+		{n: 1, want: []int{6}, dropped: 5},
+		{n: 0, want: []int{}, dropped: 6},
+	}
+	for _, tt := range tests {
+		got, dropped := sampleLogs(logs, tt.n)
+		if !reflect.DeepEqual(tailNs(got), tt.want) || dropped != tt.dropped {
+			t.Errorf("sampleLogs(%d) = %v %d, want %v %d", tt.n, tailNs(got), dropped, tt.want, tt.dropped)
+		}
+	}
+}
+
+func Test_logsTails_acquire(t *testing.T) {
+	s := &logsTails{conf: logsTailConfig{maxPerInstance: 1}, running: make(map[int]int)}
+	if err := s.acquire(1); err != nil {
+		t.Fatalf("acquire() error = %v", err)
+	}
+	if err := s.acquire(1); !errors.Is(err, ErrLogsTailLimit) {
+		t.Errorf("acquire() error = %v, want %v", err, ErrLogsTailLimit)
+	}
+	if err := s.acquire(2); err != nil {
+		t.Errorf("acquire() of another instance error = %v", err)
+	}
+	s.release(1)
+	if err := s.acquire(1); err != nil {
+		t.Errorf("acquire() after release error = %v", err)
+	}
+}
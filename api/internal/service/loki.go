@@ -0,0 +1,716 @@
+package service
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+// The Loki APIs read a table chosen by the table label with a subset of LogQL: the label matchers are terms of the
+// query language, the regular expressions are alternations of literals and .* wildcards, the line filters search
+// the raw log and the metric queries are count_over_time or rate, optionally summed.
+
+const (
+	lokiTableLabel     = "table"
+	lokiDefaultLimit   = 100
+	lokiMaxPoints      = 11000
+	lokiCountOverTime  = "count_over_time"
+	lokiRate           = "rate"
+	lokiDirectionAsc   = "forward"
+	lokiResultStreams  = "streams"
+	lokiResultMatrix   = "matrix"
+	lokiDefaultRange   = time.Hour
+	lokiDefaultSamples = 250
+)
+
+var ErrLogQL = errors.New("logql")
+
+// lokiQuery is a LogQL query translated to the query language.
+type lokiQuery struct {
+	Table  string            // the value of the table label
+	Labels map[string]string // the labels matched with =, which are the labels of the results
+	Query  string            // the other matchers and the line filters
+	Func   string            // count_over_time or rate of a metric query
+	Range  int64             // seconds of the range vector of a metric query
+}
+
+// LokiQueryRange runs a query of the LogQL subset in [start, end].
+func LokiQueryRange(uid int, req view.ReqLokiQueryRange, now time.Time) (res view.LokiResult, err error) {
+	q, err := parseLogQL(req.Query)
+	if err != nil {
+		return res, err
+	}
+	st, et, err := lokiRange(req.Start, req.End, now)
+	if err != nil {
+		return res, err
+	}
+	tableInfo, err := CompatTable(uid, q.Table)
+	if err != nil {
+		return res, err
+	}
+	labels := lokiLabels(&tableInfo, q)
+	if q.Func == "" {
+		limit := req.Limit
+		if limit <= 0 {
+			limit = lokiDefaultLimit
+		}
+		if limit > compatMaxLogs {
+			limit = compatMaxLogs
+		}
+		logs, _, err := compatLogs(tableInfo, view.ReqQuery{Query: q.Query, ST: st, ET: et, PageSize: uint32(limit)}, req.Direction == lokiDirectionAsc, false)
+		if err != nil {
+			return res, err
+		}
+		streams := make([]view.LokiStream, 0, 1)
+		if len(logs) > 0 {
+			values := make([][2]string, 0, len(logs))
+			for _, log := range logs {
+				values = append(values, [2]string{strconv.FormatInt(log.Ts, 10), log.Line})
+			}
+			streams = append(streams, view.LokiStream{Stream: labels, Values: values})
+		}
+		return view.LokiResult{ResultType: lokiResultStreams, Result: streams}, nil
+	}
+	step, err := lokiStep(req.Step, st, et)
+	if err != nil {
+		return res, err
+	}
+	bucket := step
+	if q.Range < bucket {
+		bucket = q.Range
+	}
+	points, err := compatChart(tableInfo, view.ReqQuery{Query: q.Query, ST: st - q.Range, ET: et}, bucket)
+	if err != nil {
+		return res, err
+	}
+	series := make([]view.LokiSeries, 0, 1)
+	if values := lokiSamples(points, q, st, et, step); len(values) > 0 {
+		series = append(series, view.LokiSeries{Metric: labels, Values: values})
+	}
+	return view.LokiResult{ResultType: lokiResultMatrix, Result: series}, nil
+}
+
+// LokiLabels returns the table label and the analysis fields of the table of the query, or of all the tables
+// of the user when the query has no table.
+func LokiLabels(uid int, query string) ([]string, error) {
+	tids := make([]int, 0)
+	if q, err := parseLogQL(query); err == nil && q.Table != "" {
+		tableInfo, err := CompatTable(uid, q.Table)
+		if err != nil {
+			return nil, err
+		}
+		tids = append(tids, tableInfo.ID)
+	} else {
+		tables, err := CompatTables(uid)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			tids = append(tids, t.ID)
+		}
+	}
+	res := []string{lokiTableLabel}
+	if len(tids) == 0 {
+		return res, nil
+	}
+	indexes, err := db.IndexList(egorm.Conds{"tid": egorm.Cond{Op: "in", Val: tids}})
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{lokiTableLabel: true}
+	for _, index := range indexes {
+		if name := index.GetFieldName(); !seen[name] {
+			seen[name] = true
+			res = append(res, name)
+		}
+	}
+	sort.Strings(res[1:])
+	return res, nil
+}
+
+// LokiLabelValues returns the tables of the user for the table label, the values of the other labels are
+// the most frequent values of the field in the table of the query.
+func LokiLabelValues(uid int, name, query, start, end string, now time.Time) ([]string, error) {
+	res := make([]string, 0)
+	if name == lokiTableLabel {
+		tables, err := CompatTables(uid)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range tables {
+			res = append(res, CompatTableName(t))
+		}
+		sort.Strings(res)
+		return res, nil
+	}
+	if strings.TrimSpace(query) == "" {
+		return res, nil
+	}
+	q, err := parseLogQL(query)
+	if err != nil {
+		return nil, err
+	}
+	st, et, err := lokiRange(start, end, now)
+	if err != nil {
+		return nil, err
+	}
+	tableInfo, err := CompatTable(uid, q.Table)
+	if err != nil {
+		return nil, err
+	}
+	values, err := compatTerms(tableInfo, view.ReqQuery{Query: q.Query, ST: st, ET: et}, name, compatMaxTerms)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range values {
+		res = append(res, v.Value)
+	}
+	return res, nil
+}
+
+// LokiSeries returns the labels of the selectors, the selectors without the table label match all the tables
+// of the user.
+func LokiSeries(uid int, matches []string) ([]map[string]string, error) {
+	var tables []*db.BaseTable
+	res := make([]map[string]string, 0)
+	seen := make(map[string]bool)
+	for _, match := range matches {
+		q, err := parseLogQL(match)
+		if err != nil {
+			return nil, err
+		}
+		if q.Func != "" {
+			return nil, errors.Wrap(ErrLogQL, "series are matched by selectors")
+		}
+		candidates := make([]*db.BaseTable, 0, 1)
+		if q.Table != "" {
+			tableInfo, err := CompatTable(uid, q.Table)
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, &tableInfo)
+		} else {
+			if tables == nil {
+				if tables, err = CompatTables(uid); err != nil {
+					return nil, err
+				}
+			}
+			candidates = tables
+		}
+		for _, t := range candidates {
+			labels := lokiLabels(t, q)
+			key := lokiSeriesKey(labels)
+			if !seen[key] {
+				seen[key] = true
+				res = append(res, labels)
+			}
+		}
+	}
+	return res, nil
+}
+
+func lokiLabels(tableInfo *db.BaseTable, q lokiQuery) map[string]string {
+	res := make(map[string]string, len(q.Labels)+1)
+	for k, v := range q.Labels {
+		res[k] = v
+	}
+	res[lokiTableLabel] = CompatTableName(tableInfo)
+	return res
+}
+
+func lokiSeriesKey(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var sb strings.Builder
+	for _, k := range keys {
+		sb.WriteString(strconv.Quote(k) + "=" + strconv.Quote(labels[k]) + ",")
+	}
+	return sb.String()
+}
+
+// lokiSamples evaluates the metric query at every step in [st, et], a sample counts the logs of the buckets in the
+// range before it. The points are sorted, the steps without logs have no sample as in Loki.
+func lokiSamples(points []compatPoint, q lokiQuery, st, et, step int64) [][2]interface{} {
+	res := make([][2]interface{}, 0)
+	var (
+		sum        uint64
+		head, tail int
+	)
+	for t := st; t <= et; t += step {
+		for ; tail < len(points) && points[tail].From < t; tail++ {
+			sum += points[tail].Count
+		}
+		for ; head < tail && points[head].From < t-q.Range; head++ {
+			sum -= points[head].Count
+		}
+		if sum == 0 {
+			continue
+		}
+		v := float64(sum)
+		if q.Func == lokiRate {
+			v /= float64(q.Range)
+		}
+		res = append(res, [2]interface{}{t, strconv.FormatFloat(v, 'f', -1, 64)})
+	}
+	return res
+}
+
+// lokiRange parses the start and the end of a query to seconds, the last hour by default.
+func lokiRange(start, end string, now time.Time) (st, et int64, err error) {
+	e, err := lokiTime(end, now)
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "end")
+	}
+	s, err := lokiTime(start, e.Add(-lokiDefaultRange))
+	if err != nil {
+		return 0, 0, errors.Wrap(err, "start")
+	}
+	st, et = s.Unix(), e.Unix()
+	if e.Nanosecond() > 0 {
+		et++
+	}
+	if et <= st {
+		return 0, 0, errors.Wrap(ErrLogQL, "the end must be after the start")
+	}
+	return st, et, nil
+}
+
+// lokiTime parses a time as Loki does: seconds with a fraction, seconds of at most 10 digits, nanoseconds or RFC3339.
+func lokiTime(s string, def time.Time) (time.Time, error) {
+	if s == "" {
+		return def, nil
+	}
+	if strings.Contains(s, ".") {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			sec, frac := math.Modf(f)
+			return time.Unix(int64(sec), int64(frac*float64(time.Second))), nil
+		}
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Parse(time.RFC3339Nano, s)
+	}
+	if len(s) <= 10 {
+		return time.Unix(n, 0), nil
+	}
+	return time.Unix(0, n), nil
+}
+
+// lokiStep parses the step in seconds or as a duration, the range is split into 250 steps by default.
+func lokiStep(s string, st, et int64) (int64, error) {
+	var step int64
+	if s == "" {
+		step = (et - st) / lokiDefaultSamples
+	} else if f, err := strconv.ParseFloat(s, 64); err == nil {
+		step = int64(math.Ceil(f))
+	} else {
+		d, err := lokiDuration(s)
+		if err != nil {
+			return 0, errors.Wrap(err, "step")
+		}
+		step = d
+	}
+	if step < 1 {
+		step = 1
+	}
+	if (et-st)/step > lokiMaxPoints {
+		return 0, errors.Wrapf(ErrLogQL, "exceeded maximum resolution of %d points per timeseries, increase the step", lokiMaxPoints)
+	}
+	return step, nil
+}
+
+// lokiDuration parses a duration of LogQL to seconds, d and w are days and weeks.
+func lokiDuration(s string) (int64, error) {
+	var res time.Duration
+	for rest := s; rest != ""; {
+		i := strings.IndexFunc(rest, func(r rune) bool { return r < '0' || r > '9' })
+		if i <= 0 {
+			return 0, errors.Wrapf(ErrLogQL, "invalid duration %q", s)
+		}
+		n, _ := strconv.ParseInt(rest[:i], 10, 64)
+		j := strings.IndexFunc(rest[i:], func(r rune) bool { return r >= '0' && r <= '9' })
+		if j < 0 {
+			j = len(rest) - i
+		}
+		unit := map[string]time.Duration{
+			"ms": time.Millisecond, "s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour, "w": 7 * 24 * time.Hour,
+		}[rest[i:i+j]]
+		if unit == 0 {
+			return 0, errors.Wrapf(ErrLogQL, "invalid duration %q", s)
+		}
+		res += time.Duration(n) * unit
+		rest = rest[i+j:]
+	}
+	if res < time.Second {
+		return 0, errors.Wrapf(ErrLogQL, "duration %q is less than a second", s)
+	}
+	return int64(res / time.Second), nil
+}
+
+// parseLogQL parses a log selector with line filters, a count_over_time or rate of it, or the sum of them.
+func parseLogQL(s string) (q lokiQuery, err error) {
+	p := &logQLParser{s: s}
+	sum := p.word("sum")
+	if sum {
+		if p.word("by") || p.word("without") {
+			return q, errors.Wrap(ErrLogQL, "sum is not supported with grouping")
+		}
+		if err = p.expect("("); err != nil {
+			return q, err
+		}
+	}
+	switch {
+	case p.word(lokiCountOverTime):
+		q.Func = lokiCountOverTime
+	case p.word(lokiRate):
+		q.Func = lokiRate
+	case sum:
+		return q, errors.Wrapf(ErrLogQL, "expected %s or %s in sum at %d", lokiCountOverTime, lokiRate, p.pos)
+	}
+	if q.Func != "" {
+		if err = p.expect("("); err != nil {
+			return q, err
+		}
+	}
+	if err = p.selector(&q); err != nil {
+		return q, err
+	}
+	if q.Func != "" {
+		if err = p.expect("["); err != nil {
+			return q, err
+		}
+		end := strings.IndexByte(p.s[p.pos:], ']')
+		if end < 0 {
+			return q, errors.Wrapf(ErrLogQL, "expected ] at %d", p.pos)
+		}
+		if q.Range, err = lokiDuration(strings.TrimSpace(p.s[p.pos : p.pos+end])); err != nil {
+			return q, err
+		}
+		p.pos += end + 1
+		if err = p.expect(")"); err != nil {
+			return q, err
+		}
+	}
+	if sum {
+		if err = p.expect(")"); err != nil {
+			return q, err
+		}
+		if p.word("by") || p.word("without") {
+			return q, errors.Wrap(ErrLogQL, "sum is not supported with grouping")
+		}
+	}
+	if p.skip(); p.pos < len(p.s) {
+		return q, errors.Wrapf(ErrLogQL, "unexpected %q at %d", p.s[p.pos:], p.pos)
+	}
+	return q, nil
+}
+
+type logQLParser struct {
+	s   string
+	pos int
+}
+
+func (p *logQLParser) skip() {
+	for p.pos < len(p.s) && unicode.IsSpace(rune(p.s[p.pos])) {
+		p.pos++
+	}
+}
+
+func (p *logQLParser) accept(tok string) bool {
+	p.skip()
+	if strings.HasPrefix(p.s[p.pos:], tok) {
+		p.pos += len(tok)
+		return true
+	}
+	return false
+}
+
+func (p *logQLParser) expect(tok string) error {
+	if !p.accept(tok) {
+		return errors.Wrapf(ErrLogQL, "expected %s at %d", tok, p.pos)
+	}
+	return nil
+}
+
+// word accepts the keyword when it is not the prefix of a longer name.
+func (p *logQLParser) word(w string) bool {
+	p.skip()
+	if !strings.HasPrefix(p.s[p.pos:], w) {
+		return false
+	}
+	if next := p.pos + len(w); next < len(p.s) && isLogQLNameRune(rune(p.s[next])) {
+		return false
+	}
+	p.pos += len(w)
+	return true
+}
+
+func isLogQLNameRune(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// name reads a label name, the dots of the nested analysis fields are allowed.
+func (p *logQLParser) name() (string, error) {
+	p.skip()
+	start := p.pos
+	for p.pos < len(p.s) && isLogQLNameRune(rune(p.s[p.pos])) {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", errors.Wrapf(ErrLogQL, "expected a label name at %d", start)
+	}
+	return p.s[start:p.pos], nil
+}
+
+// str reads a double quoted string with escapes or a raw string in backticks.
+func (p *logQLParser) str() (string, error) {
+	p.skip()
+	if p.pos >= len(p.s) {
+		return "", errors.Wrapf(ErrLogQL, "expected a string at %d", p.pos)
+	}
+	start := p.pos
+	switch p.s[p.pos] {
+	case '`':
+		end := strings.IndexByte(p.s[start+1:], '`')
+		if end < 0 {
+			return "", errors.Wrapf(ErrLogQL, "unterminated string at %d", start)
+		}
+		p.pos = start + end + 2
+		return p.s[start+1 : start+1+end], nil
+	case '"':
+		for i := start + 1; i < len(p.s); i++ {
+			switch p.s[i] {
+			case '\\':
+				i++
+			case '"':
+				p.pos = i + 1
+				v, err := strconv.Unquote(p.s[start:p.pos])
+				if err != nil {
+					return "", errors.Wrapf(ErrLogQL, "invalid string at %d", start)
+				}
+				return v, nil
+			}
+		}
+		return "", errors.Wrapf(ErrLogQL, "unterminated string at %d", start)
+	}
+	return "", errors.Wrapf(ErrLogQL, "expected a string at %d", start)
+}
+
+// selector reads the label matchers and the line filters.
+func (p *logQLParser) selector(q *lokiQuery) error {
+	if err := p.expect("{"); err != nil {
+		return err
+	}
+	q.Labels = make(map[string]string)
+	terms := make([]string, 0)
+	for !p.accept("}") {
+		name, err := p.name()
+		if err != nil {
+			return err
+		}
+		op := ""
+		for _, tok := range []string{"=~", "!~", "!=", "="} {
+			if p.accept(tok) {
+				op = tok
+				break
+			}
+		}
+		if op == "" {
+			return errors.Wrapf(ErrLogQL, "expected a matcher of %s at %d", name, p.pos)
+		}
+		value, err := p.str()
+		if err != nil {
+			return err
+		}
+		if name == lokiTableLabel {
+			if op != "=" {
+				return errors.Wrap(ErrLogQL, "the table label is matched with =")
+			}
+			q.Table = value
+		} else {
+			term, err := lokiMatcher(name, op, value)
+			if err != nil {
+				return err
+			}
+			if op == "=" {
+				q.Labels[name] = value
+			}
+			terms = append(terms, term)
+		}
+		if !p.accept(",") {
+			if err = p.expect("}"); err != nil {
+				return err
+			}
+			break
+		}
+	}
+	for {
+		op := ""
+		for _, tok := range []string{"|=", "!=", "|~", "!~"} {
+			if p.accept(tok) {
+				op = tok
+				break
+			}
+		}
+		if op == "" {
+			break
+		}
+		value, err := p.str()
+		if err != nil {
+			return err
+		}
+		term, err := lokiMatcher("", strings.Replace(strings.Replace(op, "|~", "=~", 1), "|=", "=", 1), value)
+		if err != nil {
+			return err
+		}
+		terms = append(terms, term)
+	}
+	if p.skip(); strings.HasPrefix(p.s[p.pos:], "|") {
+		return errors.Wrapf(ErrLogQL, "parsers and formatters are not supported at %d", p.pos)
+	}
+	q.Query = lql.Join(terms...)
+	if q.Query == "*" {
+		q.Query = ""
+	}
+	return nil
+}
+
+// lokiMatcher translates a label matcher, or a line filter when the field is empty, to the query language.
+func lokiMatcher(field, op, value string) (string, error) {
+	var term string
+	switch op {
+	case "=", "!=":
+		term = (&lql.Term{Field: field, Op: lql.OpMatch, Value: value, Quoted: true}).String()
+	default:
+		alts, err := lokiRegexp(field, value)
+		if err != nil {
+			return "", err
+		}
+		term = alts
+	}
+	if strings.HasPrefix(op, "!") {
+		return "NOT " + term, nil
+	}
+	return term, nil
+}
+
+// lokiRegexp translates a regular expression of alternated literals with the wildcards .* .+ and . to the query
+// language. The label matchers are anchored as the terms of the fields, the line filters are not as the terms
+// of the raw log.
+func lokiRegexp(field, expr string) (string, error) {
+	var (
+		alts    = make([]string, 0)
+		segs    = make([]lokiSegment, 0)
+		literal strings.Builder
+		runes   = []rune(expr)
+	)
+	unsupported := errors.Wrapf(ErrLogQL, "only literals and .* are supported in %q", expr)
+	flushLiteral := func() {
+		if literal.Len() > 0 {
+			segs = append(segs, lokiSegment{text: literal.String()})
+			literal.Reset()
+		}
+	}
+	flush := func() error {
+		flushLiteral()
+		if len(segs) == 0 {
+			return errors.Wrapf(ErrLogQL, "empty alternative in %q", expr)
+		}
+		term, err := lokiPattern(field, segs)
+		if err != nil {
+			return err
+		}
+		alts = append(alts, term)
+		segs = segs[:0]
+		return nil
+	}
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case r == '\\':
+			if i+1 >= len(runes) || unicode.IsLetter(runes[i+1]) || unicode.IsDigit(runes[i+1]) {
+				return "", unsupported
+			}
+			i++
+			literal.WriteRune(runes[i])
+		case r == '.':
+			flushLiteral()
+			w := "?"
+			if i+1 < len(runes) && runes[i+1] == '*' {
+				w, i = "*", i+1
+			} else if i+1 < len(runes) && runes[i+1] == '+' {
+				w, i = "?*", i+1
+			}
+			segs = append(segs, lokiSegment{text: w, wildcard: true})
+		case r == '|':
+			if err := flush(); err != nil {
+				return "", err
+			}
+		case strings.ContainsRune("*+?()[]{}^$", r):
+			return "", unsupported
+		default:
+			literal.WriteRune(r)
+		}
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+	if len(alts) == 1 {
+		return alts[0], nil
+	}
+	return "(" + strings.Join(alts, " "+lql.OpOr+" ") + ")", nil
+}
+
+// lokiSegment is a literal or a wildcard of an alternative.
+type lokiSegment struct {
+	text     string
+	wildcard bool
+}
+
+// lokiPattern writes the segments of an alternative as a quoted term, or as a wildcard term of which the literals
+// are escaped since the quoted values have no wildcards.
+func lokiPattern(field string, segs []lokiSegment) (string, error) {
+	var sb strings.Builder
+	wildcard := false
+	for _, seg := range segs {
+		wildcard = wildcard || seg.wildcard
+	}
+	if !wildcard {
+		for _, seg := range segs {
+			sb.WriteString(seg.text)
+		}
+		return (&lql.Term{Field: field, Op: lql.OpMatch, Value: sb.String(), Quoted: true}).String(), nil
+	}
+	for i, seg := range segs {
+		if seg.wildcard {
+			sb.WriteString(seg.text)
+			continue
+		}
+		if strings.ContainsAny(seg.text, "*?") {
+			return "", errors.Wrapf(ErrLogQL, "* and ? can not be matched literally with wildcards: %q", seg.text)
+		}
+		for j, r := range seg.text {
+			if unicode.IsSpace(r) || strings.ContainsRune(`()":<>=\`, r) || (i == 0 && j == 0 && r == '-') {
+				sb.WriteRune('\\')
+			}
+			sb.WriteRune(r)
+		}
+	}
+	if field == "" {
+		return sb.String(), nil
+	}
+	return field + lql.OpMatch + sb.String(), nil
+}
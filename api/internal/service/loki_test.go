@@ -0,0 +1,141 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/lql"
+)
+
+func Test_parseLogQL(t *testing.T) {
+	tests := []struct {
+		query string
+		want  lokiQuery
+	}{
+		{
+			query: `{table="logs.app", level="error"}`,
+			want:  lokiQuery{Table: "logs.app", Labels: map[string]string{"level": "error"}, Query: `level:"error"`},
+		},
+		{
+			query: `{table="app",service!="api",req.path=~"/cart.*|/pay"} |= "timeout" != ` + "`retry`",
+			want: lokiQuery{Table: "app", Labels: map[string]string{},
+				Query: `(NOT service:"api") AND ((req.path:/cart* OR req.path:"/pay")) AND ("timeout") AND (NOT "retry")`},
+		},
+		{
+			query: `{table="app"} |~ "conn.+refused" !~ "a b.*"`,
+			want:  lokiQuery{Table: "app", Labels: map[string]string{}, Query: `(conn?*refused) AND (NOT a\ b*)`},
+		},
+		{
+			query: `sum(count_over_time({table="app"}[5m]))`,
+			want:  lokiQuery{Table: "app", Labels: map[string]string{}, Func: lokiCountOverTime, Range: 300},
+		},
+		{
+			query: `rate({table="app", level="warn"} |= "x" [1h30m])`,
+			want:  lokiQuery{Table: "app", Labels: map[string]string{"level": "warn"}, Query: `(level:"warn") AND ("x")`, Func: lokiRate, Range: 5400},
+		},
+	}
+	for _, tt := range tests {
+		got, err := parseLogQL(tt.query)
+		if err != nil {
+			t.Errorf("parseLogQL(%s) error = %v", tt.query, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseLogQL(%s) = %+v, want %+v", tt.query, got, tt.want)
+		}
+		if _, err = lql.Parse(got.Query); err != nil {
+			t.Errorf("parseLogQL(%s) query %s error = %v", tt.query, got.Query, err)
+		}
+	}
+	for _, query := range []string{
+		`{table=~"app"}`,
+		`{table="app"} | json`,
+		`{table="app", level=~"err(or)?"}`,
+		`sum by (level) (count_over_time({table="app"}[5m]))`,
+		`sum({table="app"})`,
+		`count_over_time({table="app"})`,
+		`{table="app"`,
+	} {
+		if _, err := parseLogQL(query); !errors.Is(err, ErrLogQL) {
+			t.Errorf("parseLogQL(%s) error = %v, want %v", query, err, ErrLogQL)
+		}
+	}
+}
+
+func Test_lokiSamples(t *testing.T) {
+	points := []compatPoint{{From: 0, Count: 1}, {From: 60, Count: 2}, {From: 120, Count: 4}}
+	q := lokiQuery{Func: lokiCountOverTime, Range: 120}
+	want := [][2]interface{}{{int64(60), "1"}, {int64(120), "3"}, {int64(180), "6"}, {int64(240), "4"}}
+	if got := lokiSamples(points, q, 0, 300, 60); !reflect.DeepEqual(got, want) {
+		t.Errorf("lokiSamples() = %v, want %v", got, want)
+	}
+	q.Func = lokiRate
+	if got := lokiSamples(points, q, 180, 180, 60); !reflect.DeepEqual(got, [][2]interface{}{{int64(180), "0.05"}}) {
+		t.Errorf("lokiSamples() of rate = %v", got)
+	}
+}
+
+func Test_lokiRange(t *testing.T) {
+	now := time.Unix(1700003600, 0)
+	st, et, err := lokiRange("", "", now)
+	if err != nil || st != 1700000000 || et != 1700003600 {
+		t.Errorf("lokiRange() = %d, %d, %v", st, et, err)
+	}
+	st, et, err = lokiRange("1700000000000000000", "1700000060.5", now)
+	if err != nil || st != 1700000000 || et != 1700000061 {
+		t.Errorf("lokiRange() of nanoseconds and seconds = %d, %d, %v", st, et, err)
+	}
+	if st, _, err = lokiRange("2023-11-14T22:13:20Z", "", now); err != nil || st != 1700000000 {
+		t.Errorf("lokiRange() of RFC3339 = %d, %v", st, err)
+	}
+	if _, _, err = lokiRange("1700003600", "1700000000", now); err == nil {
+		t.Error("lokiRange() of an end before the start succeeded")
+	}
+}
+
+func Test_lokiStep(t *testing.T) {
+	for _, tt := range []struct {
+		step string
+		want int64
+	}{{"", 14}, {"15", 15}, {"0.5", 1}, {"1m", 60}, {"1d", 86400}} {
+		if got, err := lokiStep(tt.step, 0, 3600); err != nil || got != tt.want {
+			t.Errorf("lokiStep(%q) = %d, %v, want %d", tt.step, got, err, tt.want)
+		}
+	}
+	if _, err := lokiStep("1", 0, 86400); !errors.Is(err, ErrLogQL) {
+		t.Errorf("lokiStep() beyond the points error = %v", err)
+	}
+}
+
+func Test_compatTable(t *testing.T) {
+	tables := []*db.BaseTable{
+		{BaseModel: db.BaseModel{ID: 1}, Name: "app", Database: &db.BaseDatabase{Name: "logs"}},
+		{BaseModel: db.BaseModel{ID: 2}, Name: "app", Database: &db.BaseDatabase{Name: "test"}},
+		{BaseModel: db.BaseModel{ID: 3}, Name: "nginx", Database: &db.BaseDatabase{Name: "logs"}},
+	}
+	for name, want := range map[string]int{"test.app": 2, "nginx": 3, "logs.nginx": 3} {
+		if got, err := compatTable(tables, name); err != nil || got.ID != want {
+			t.Errorf("compatTable(%s) = %d, %v, want %d", name, got.ID, err, want)
+		}
+	}
+	for _, name := range []string{"app", "logs.mysql"} {
+		if _, err := compatTable(tables, name); !errors.Is(err, ErrCompatTable) {
+			t.Errorf("compatTable(%s) error = %v, want %v", name, err, ErrCompatTable)
+		}
+	}
+}
+
+func Test_compatBuckets(t *testing.T) {
+	charts := []*view.HighChart{{From: 1200, Count: 1}, {From: 1260, Count: 2}, {From: 1800, Count: 4}, {From: 600, Count: 8}}
+	want := []compatPoint{{From: 600, Count: 8}, {From: 1200, Count: 3}, {From: 1800, Count: 4}}
+	if got := compatBuckets(charts, 600); !reflect.DeepEqual(got, want) {
+		t.Errorf("compatBuckets() = %v, want %v", got, want)
+	}
+	if got := compatGranularity(90); got != 1800 {
+		t.Errorf("compatGranularity(90) = %d, want 1800", got)
+	}
+}
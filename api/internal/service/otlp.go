@@ -0,0 +1,373 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"mime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builderv2"
+)
+
+const (
+	OTLPContentTypeProtobuf = "application/x-protobuf"
+	OTLPContentTypeJSON     = "application/json"
+)
+
+var (
+	ErrOTLPTable       = errors.New("the table of the ingest token is not an OTLP table of the signal")
+	ErrOTLPContentType = errors.New("unsupported content type, OTLP/HTTP takes application/x-protobuf or application/json")
+)
+
+// PushLogs queues the log records of the OTLP request into the OTLP logs table, body is the encoded request.
+func (i *ingest) PushLogs(tableInfo db.BaseTable, key string, body []byte, req *collogspb.ExportLogsServiceRequest) (view.RespIngest, error) {
+	return i.pushOTLP(tableInfo, constx.TableCreateTypeOTLPLogs, key, body, func(now time.Time) [][]interface{} {
+		return otlpLogRows(req, now)
+	})
+}
+
+// PushTraces queues the spans of the OTLP request into the OTLP traces table, body is the encoded request.
+func (i *ingest) PushTraces(tableInfo db.BaseTable, key string, body []byte, req *coltracepb.ExportTraceServiceRequest) (view.RespIngest, error) {
+	return i.pushOTLP(tableInfo, constx.TableCreateTypeOTLPTraces, key, body, func(now time.Time) [][]interface{} {
+		return otlpSpanRows(req, now)
+	})
+}
+
+func (i *ingest) pushOTLP(tableInfo db.BaseTable, createType int, key string, body []byte, rows func(now time.Time) [][]interface{}) (res view.RespIngest, err error) {
+	if !i.conf.enable {
+		return res, ErrIngestDisabled
+	}
+	if tableInfo.CreateType != createType {
+		return res, ErrOTLPTable
+	}
+	t := i.table(tableInfo)
+	if key == "" {
+		sum := sha256.Sum256(body)
+		key = hex.EncodeToString(sum[:])
+	}
+	schema, err := i.schema(t, tableInfo)
+	if err != nil {
+		return res, err
+	}
+	return i.enqueue(t, schema, key, rows(time.Now()), len(body))
+}
+
+func isOTLPTable(tableInfo db.BaseTable) bool {
+	return tableInfo.CreateType == constx.TableCreateTypeOTLPLogs || tableInfo.CreateType == constx.TableCreateTypeOTLPTraces
+}
+
+// newOTLPSchema lays out the rows of the OTLP tables, their columns are fixed by the builderv2 creators.
+func newOTLPSchema(tableInfo db.BaseTable) *ingestSchema {
+	columns := builderv2.OTLPLogsColumns
+	if tableInfo.CreateType == constx.TableCreateTypeOTLPTraces {
+		columns = builderv2.OTLPTracesColumns
+	}
+	s := &ingestSchema{table: tableInfo.Name, loadedAt: time.Now()}
+	if tableInfo.Database != nil {
+		s.iid, s.database = tableInfo.Database.Iid, tableInfo.Database.Name
+	}
+	for _, col := range columns {
+		s.columns = append(s.columns, view.IngestColumn{Name: col.Name, Type: col.Type})
+	}
+	return s
+}
+
+// otlpIndexes are the fields of the OTLP tables shown on the field sidebar.
+func otlpIndexes(createType int) []db.BaseIndex {
+	if createType == constx.TableCreateTypeOTLPTraces {
+		return []db.BaseIndex{
+			{Field: "service_name"},
+			{Field: "span_name"},
+			{Field: "span_kind"},
+			{Field: "status_code"},
+			{Field: "duration", Typ: 1},
+			{Field: "trace_id"},
+			{Field: "span_id"},
+			{Field: "parent_span_id"},
+		}
+	}
+	return []db.BaseIndex{
+		{Field: "service_name"},
+		{Field: "severity_text"},
+		{Field: "severity_number", Typ: 1},
+		{Field: "trace_id"},
+		{Field: "span_id"},
+		{Field: "scope_name"},
+	}
+}
+
+// otlpLogRows builds the rows of builderv2.OTLPLogsColumns, a record without time takes its observed time.
+func otlpLogRows(req *collogspb.ExportLogsServiceRequest, now time.Time) [][]interface{} {
+	rows := make([][]interface{}, 0)
+	for _, rl := range req.GetResourceLogs() {
+		resAttrs := otlpAttributes(rl.GetResource().GetAttributes())
+		for _, sl := range rl.GetScopeLogs() {
+			scope := sl.GetScope()
+			scopeAttrs := otlpAttributes(scope.GetAttributes())
+			for _, lr := range sl.GetLogRecords() {
+				observed := otlpTime(lr.GetObservedTimeUnixNano(), now)
+				t := otlpTime(lr.GetTimeUnixNano(), observed)
+				rows = append(rows, []interface{}{
+					t.Truncate(time.Second),
+					t,
+					otlpValueString(lr.GetBody()),
+					observed,
+					hex.EncodeToString(lr.GetTraceId()),
+					hex.EncodeToString(lr.GetSpanId()),
+					int64(lr.GetFlags()),
+					lr.GetSeverityText(),
+					int64(lr.GetSeverityNumber()),
+					resAttrs["service.name"],
+					resAttrs,
+					scope.GetName(),
+					scope.GetVersion(),
+					scopeAttrs,
+					otlpAttributes(lr.GetAttributes()),
+				})
+			}
+		}
+	}
+	return rows
+}
+
+// otlpSpanLog is the _raw_log_ of a span.
+type otlpSpanLog struct {
+	TraceID       string            `json:"traceId"`
+	SpanID        string            `json:"spanId"`
+	ParentSpanID  string            `json:"parentSpanId,omitempty"`
+	Name          string            `json:"name"`
+	Kind          string            `json:"kind"`
+	Service       string            `json:"service"`
+	Duration      int64             `json:"duration"`
+	Status        string            `json:"status"`
+	StatusMessage string            `json:"statusMessage,omitempty"`
+	Attributes    map[string]string `json:"attributes,omitempty"`
+}
+
+// otlpSpanRows builds the rows of builderv2.OTLPTracesColumns.
+func otlpSpanRows(req *coltracepb.ExportTraceServiceRequest, now time.Time) [][]interface{} {
+	rows := make([][]interface{}, 0)
+	for _, rs := range req.GetResourceSpans() {
+		resAttrs := otlpAttributes(rs.GetResource().GetAttributes())
+		service := resAttrs["service.name"]
+		for _, ss := range rs.GetScopeSpans() {
+			scope := ss.GetScope()
+			for _, span := range ss.GetSpans() {
+				start := otlpTime(span.GetStartTimeUnixNano(), now)
+				var duration int64
+				if span.GetStartTimeUnixNano() > 0 && span.GetEndTimeUnixNano() > span.GetStartTimeUnixNano() {
+					duration = int64(span.GetEndTimeUnixNano() - span.GetStartTimeUnixNano())
+				}
+				spanLog := otlpSpanLog{
+					TraceID:       hex.EncodeToString(span.GetTraceId()),
+					SpanID:        hex.EncodeToString(span.GetSpanId()),
+					ParentSpanID:  hex.EncodeToString(span.GetParentSpanId()),
+					Name:          span.GetName(),
+					Kind:          otlpEnumName(span.GetKind().String(), "SPAN_KIND_"),
+					Service:       service,
+					Duration:      duration,
+					Status:        otlpEnumName(span.GetStatus().GetCode().String(), "STATUS_CODE_"),
+					StatusMessage: span.GetStatus().GetMessage(),
+					Attributes:    otlpAttributes(span.GetAttributes()),
+				}
+				rawLog, _ := json.Marshal(spanLog)
+				eventTimes, eventNames, eventAttrs := otlpSpanEvents(span.GetEvents(), start)
+				linkTraceIDs, linkSpanIDs, linkStates, linkAttrs := otlpSpanLinks(span.GetLinks())
+				rows = append(rows, []interface{}{
+					start.Truncate(time.Second),
+					start,
+					string(rawLog),
+					spanLog.TraceID,
+					spanLog.SpanID,
+					spanLog.ParentSpanID,
+					span.GetTraceState(),
+					spanLog.Name,
+					spanLog.Kind,
+					service,
+					resAttrs,
+					scope.GetName(),
+					scope.GetVersion(),
+					spanLog.Attributes,
+					duration,
+					spanLog.Status,
+					spanLog.StatusMessage,
+					eventTimes,
+					eventNames,
+					eventAttrs,
+					linkTraceIDs,
+					linkSpanIDs,
+					linkStates,
+					linkAttrs,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+func otlpSpanEvents(events []*tracepb.Span_Event, start time.Time) ([]time.Time, []string, []map[string]string) {
+	times := make([]time.Time, 0, len(events))
+	names := make([]string, 0, len(events))
+	attrs := make([]map[string]string, 0, len(events))
+	for _, e := range events {
+		times = append(times, otlpTime(e.GetTimeUnixNano(), start))
+		names = append(names, e.GetName())
+		attrs = append(attrs, otlpAttributes(e.GetAttributes()))
+	}
+	return times, names, attrs
+}
+
+func otlpSpanLinks(links []*tracepb.Span_Link) ([]string, []string, []string, []map[string]string) {
+	traceIDs := make([]string, 0, len(links))
+	spanIDs := make([]string, 0, len(links))
+	states := make([]string, 0, len(links))
+	attrs := make([]map[string]string, 0, len(links))
+	for _, l := range links {
+		traceIDs = append(traceIDs, hex.EncodeToString(l.GetTraceId()))
+		spanIDs = append(spanIDs, hex.EncodeToString(l.GetSpanId()))
+		states = append(states, l.GetTraceState())
+		attrs = append(attrs, otlpAttributes(l.GetAttributes()))
+	}
+	return traceIDs, spanIDs, states, attrs
+}
+
+// otlpEnumName turns SPAN_KIND_SERVER into server.
+func otlpEnumName(name, prefix string) string {
+	return strings.ToLower(strings.TrimPrefix(name, prefix))
+}
+
+func otlpTime(ns uint64, def time.Time) time.Time {
+	if ns == 0 {
+		return def
+	}
+	return time.Unix(0, int64(ns))
+}
+
+// otlpAttributes keeps the values as strings, the arrays and the maps in JSON.
+func otlpAttributes(kvs []*commonpb.KeyValue) map[string]string {
+	attrs := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		attrs[kv.GetKey()] = otlpValueString(kv.GetValue())
+	}
+	return attrs
+}
+
+func otlpValueString(v *commonpb.AnyValue) string {
+	switch x := v.GetValue().(type) {
+	case nil:
+		return ""
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return strconv.FormatBool(x.BoolValue)
+	case *commonpb.AnyValue_IntValue:
+		return strconv.FormatInt(x.IntValue, 10)
+	case *commonpb.AnyValue_DoubleValue:
+		return strconv.FormatFloat(x.DoubleValue, 'f', -1, 64)
+	case *commonpb.AnyValue_BytesValue:
+		return base64.StdEncoding.EncodeToString(x.BytesValue)
+	}
+	b, _ := json.Marshal(otlpValue(v))
+	return string(b)
+}
+
+func otlpValue(v *commonpb.AnyValue) interface{} {
+	switch x := v.GetValue().(type) {
+	case *commonpb.AnyValue_StringValue:
+		return x.StringValue
+	case *commonpb.AnyValue_BoolValue:
+		return x.BoolValue
+	case *commonpb.AnyValue_IntValue:
+		return x.IntValue
+	case *commonpb.AnyValue_DoubleValue:
+		return x.DoubleValue
+	case *commonpb.AnyValue_BytesValue:
+		return x.BytesValue
+	case *commonpb.AnyValue_ArrayValue:
+		values := make([]interface{}, 0, len(x.ArrayValue.GetValues()))
+		for _, e := range x.ArrayValue.GetValues() {
+			values = append(values, otlpValue(e))
+		}
+		return values
+	case *commonpb.AnyValue_KvlistValue:
+		values := make(map[string]interface{}, len(x.KvlistValue.GetValues()))
+		for _, kv := range x.KvlistValue.GetValues() {
+			values[kv.GetKey()] = otlpValue(kv.GetValue())
+		}
+		return values
+	}
+	return nil
+}
+
+// UnmarshalOTLP decodes an OTLP/HTTP request by its content type, the ids of OTLP/JSON are hex instead of base64.
+func UnmarshalOTLP(contentType string, body []byte, m proto.Message) error {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case OTLPContentTypeProtobuf:
+		return proto.Unmarshal(body, m)
+	case OTLPContentTypeJSON:
+		body, err := otlpJSONIDs(body)
+		if err != nil {
+			return err
+		}
+		return protojson.UnmarshalOptions{DiscardUnknown: true}.Unmarshal(body, m)
+	}
+	return ErrOTLPContentType
+}
+
+// MarshalOTLP encodes the response in the content type of the request.
+func MarshalOTLP(contentType string, m proto.Message) ([]byte, string, error) {
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == OTLPContentTypeJSON {
+		b, err := protojson.Marshal(m)
+		return b, OTLPContentTypeJSON, err
+	}
+	b, err := proto.Marshal(m)
+	return b, OTLPContentTypeProtobuf, err
+}
+
+// otlpJSONIDs converts the hex ids of OTLP/JSON to base64, as protojson decodes the bytes fields.
+func otlpJSONIDs(body []byte) ([]byte, error) {
+	var doc interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	var walk func(v interface{})
+	walk = func(v interface{}) {
+		switch x := v.(type) {
+		case map[string]interface{}:
+			for key, e := range x {
+				if s, ok := e.(string); ok && (key == "traceId" || key == "spanId" || key == "parentSpanId") {
+					if b, err := hex.DecodeString(s); err == nil {
+						x[key] = base64.StdEncoding.EncodeToString(b)
+					}
+					continue
+				}
+				walk(e)
+			}
+		case []interface{}:
+			for _, e := range x {
+				walk(e)
+			}
+		}
+	}
+	walk(doc)
+	return json.Marshal(doc)
+}
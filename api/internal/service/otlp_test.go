@@ -0,0 +1,164 @@
+package service
+
+import (
+	"encoding/hex"
+	"errors"
+	"testing"
+	"time"
+
+	collogspb "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	coltracepb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory/builderv2"
+)
+
+func otlpString(s string) *commonpb.AnyValue {
+	return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: s}}
+}
+
+func otlpResource() *resourcepb.Resource {
+	return &resourcepb.Resource{Attributes: []*commonpb.KeyValue{{Key: "service.name", Value: otlpString("checkout")}}}
+}
+
+func Test_otlpLogRows(t *testing.T) {
+	traceID, _ := hex.DecodeString("5b8efff798038103d269b633813fc60c")
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		Resource: otlpResource(),
+		ScopeLogs: []*logspb.ScopeLogs{{
+			Scope: &commonpb.InstrumentationScope{Name: "app", Version: "1.0"},
+			LogRecords: []*logspb.LogRecord{{
+				TimeUnixNano:   1700000000500000000,
+				SeverityNumber: logspb.SeverityNumber_SEVERITY_NUMBER_ERROR,
+				SeverityText:   "ERROR",
+				Body:           otlpString("payment failed"),
+				TraceId:        traceID,
+				Attributes: []*commonpb.KeyValue{
+					{Key: "retry", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: 3}}},
+					{Key: "user", Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: &commonpb.KeyValueList{
+						Values: []*commonpb.KeyValue{{Key: "id", Value: otlpString("u1")}},
+					}}}},
+				},
+			}, {
+				Body: &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: &commonpb.ArrayValue{
+					Values: []*commonpb.AnyValue{otlpString("a"), {Value: &commonpb.AnyValue_BoolValue{BoolValue: true}}},
+				}}},
+			}},
+		}},
+	}}}
+	now := time.Unix(1700000100, 0)
+	rows := otlpLogRows(req, now)
+	if len(rows) != 2 || len(rows[0]) != len(builderv2.OTLPLogsColumns) {
+		t.Fatalf("otlpLogRows() = %v", rows)
+	}
+	row := rows[0]
+	if row[0] != time.Unix(1700000000, 0) || row[1] != time.Unix(1700000000, 5e8) || row[2] != "payment failed" || row[3] != now {
+		t.Errorf("otlpLogRows() time and body = %v", row[:4])
+	}
+	if row[4] != "5b8efff798038103d269b633813fc60c" || row[5] != "" || row[7] != "ERROR" || row[8] != int64(17) || row[9] != "checkout" {
+		t.Errorf("otlpLogRows() ids and severity = %v", row[4:10])
+	}
+	attrs := row[14].(map[string]string)
+	if attrs["retry"] != "3" || attrs["user"] != `{"id":"u1"}` || row[11] != "app" || row[12] != "1.0" {
+		t.Errorf("otlpLogRows() scope and attributes = %v", row[11:])
+	}
+	if row = rows[1]; row[1] != now || row[2] != `["a",true]` {
+		t.Errorf("otlpLogRows() of the record without time = %v", row[:3])
+	}
+}
+
+func Test_otlpSpanRows(t *testing.T) {
+	req := &coltracepb.ExportTraceServiceRequest{ResourceSpans: []*tracepb.ResourceSpans{{
+		Resource: otlpResource(),
+		ScopeSpans: []*tracepb.ScopeSpans{{
+			Spans: []*tracepb.Span{{
+				TraceId:           []byte{1, 2},
+				SpanId:            []byte{3, 4},
+				Name:              "GET /cart",
+				Kind:              tracepb.Span_SPAN_KIND_SERVER,
+				StartTimeUnixNano: 1700000000000000000,
+				EndTimeUnixNano:   1700000000250000000,
+				Status:            &tracepb.Status{Code: tracepb.Status_STATUS_CODE_ERROR, Message: "timeout"},
+				Events:            []*tracepb.Span_Event{{Name: "exception"}},
+				Links:             []*tracepb.Span_Link{{TraceId: []byte{5}, SpanId: []byte{6}}},
+			}},
+		}},
+	}}}
+	rows := otlpSpanRows(req, time.Now())
+	if len(rows) != 1 || len(rows[0]) != len(builderv2.OTLPTracesColumns) {
+		t.Fatalf("otlpSpanRows() = %v", rows)
+	}
+	row := rows[0]
+	if row[3] != "0102" || row[4] != "0304" || row[5] != "" || row[7] != "GET /cart" || row[8] != "server" || row[9] != "checkout" {
+		t.Errorf("otlpSpanRows() ids and names = %v", row[3:10])
+	}
+	if row[14] != int64(250000000) || row[15] != "error" || row[16] != "timeout" {
+		t.Errorf("otlpSpanRows() duration and status = %v", row[14:17])
+	}
+	want := `{"traceId":"0102","spanId":"0304","name":"GET /cart","kind":"server","service":"checkout","duration":250000000,"status":"error","statusMessage":"timeout"}`
+	if row[2] != want {
+		t.Errorf("otlpSpanRows() raw log = %s, want %s", row[2], want)
+	}
+	if times := row[17].([]time.Time); len(times) != 1 || times[0] != time.Unix(1700000000, 0) {
+		t.Errorf("otlpSpanRows() event times = %v", times)
+	}
+	if ids := row[20].([]string); len(ids) != 1 || ids[0] != "05" {
+		t.Errorf("otlpSpanRows() link trace ids = %v", ids)
+	}
+}
+
+func TestUnmarshalOTLP(t *testing.T) {
+	body := []byte(`{"resourceSpans":[{"scopeSpans":[{"spans":[{"traceId":"5b8efff798038103d269b633813fc60c","spanId":"eee19b7ec3c1b174","name":"a","kind":2,"startTimeUnixNano":"1700000000000000000"}]}]}]}`)
+	req := &coltracepb.ExportTraceServiceRequest{}
+	if err := UnmarshalOTLP("application/json; charset=utf-8", body, req); err != nil {
+		t.Fatalf("UnmarshalOTLP() error = %v", err)
+	}
+	span := req.GetResourceSpans()[0].GetScopeSpans()[0].GetSpans()[0]
+	if hex.EncodeToString(span.GetTraceId()) != "5b8efff798038103d269b633813fc60c" || hex.EncodeToString(span.GetSpanId()) != "eee19b7ec3c1b174" {
+		t.Errorf("UnmarshalOTLP() ids = %x, %x", span.GetTraceId(), span.GetSpanId())
+	}
+	if span.GetKind() != tracepb.Span_SPAN_KIND_SERVER || span.GetStartTimeUnixNano() != 1700000000000000000 {
+		t.Errorf("UnmarshalOTLP() span = %v", span)
+	}
+	if err := UnmarshalOTLP("text/plain", body, req); !errors.Is(err, ErrOTLPContentType) {
+		t.Errorf("UnmarshalOTLP() error = %v, want %v", err, ErrOTLPContentType)
+	}
+}
+
+func TestIngestPushOTLP(t *testing.T) {
+	var batches []view.IngestBatch
+	i := &ingest{
+		conf:   ingestConfig{enable: true, queueSize: 10, batchSize: 100, flushInterval: time.Hour, dedupWindow: time.Minute},
+		tables: make(map[int]*ingestTable),
+		stop:   make(chan struct{}),
+		load: func(tableInfo db.BaseTable) (*ingestSchema, error) {
+			return newOTLPSchema(tableInfo), nil
+		},
+		write: func(batch view.IngestBatch, iid int) error {
+			batches = append(batches, batch)
+			return nil
+		},
+	}
+	defer i.Close()
+	req := &collogspb.ExportLogsServiceRequest{ResourceLogs: []*logspb.ResourceLogs{{
+		ScopeLogs: []*logspb.ScopeLogs{{LogRecords: []*logspb.LogRecord{{Body: otlpString("a")}}}},
+	}}}
+	tableInfo := db.BaseTable{BaseModel: db.BaseModel{ID: 1}, Name: "otel_traces", CreateType: constx.TableCreateTypeOTLPTraces}
+	if _, err := i.PushLogs(tableInfo, "", []byte("a"), req); !errors.Is(err, ErrOTLPTable) {
+		t.Errorf("PushLogs() to a traces table error = %v, want %v", err, ErrOTLPTable)
+	}
+	tableInfo.CreateType, tableInfo.Name = constx.TableCreateTypeOTLPLogs, "otel_logs"
+	if res, err := i.PushLogs(tableInfo, "", []byte("a"), req); err != nil || res.Rows != 1 {
+		t.Fatalf("PushLogs() = %v, %v", res, err)
+	}
+	i.flush(i.tables[1])
+	if len(batches) != 1 || len(batches[0].Columns) != len(builderv2.OTLPLogsColumns) || batches[0].Columns[2].Name != "_raw_log_" {
+		t.Errorf("flush() batches = %v", batches)
+	}
+}
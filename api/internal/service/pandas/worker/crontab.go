@@ -187,5 +187,7 @@ func pushExec(channelIds []int, text string, iid int) {
 			text, strings.TrimRight(econf.GetString("app.rootURL"), "/"), iid,
 		),
 	}
-	_ = pusher.Execute(channelIds, &msg, &msg)
+	if err := pusher.Execute(channelIds, &msg, &msg); err != nil {
+		elog.Error("crontabRules", elog.String("step", "pushExec"), elog.Int("iid", iid), elog.String("error", err.Error()))
+	}
 }
@@ -284,15 +284,17 @@ const (
 	Log     = "base"
 	Alarm   = "alarm"
 	Pandas  = "bigdata"
+	RawSQL  = "rawsql" // raw SQL conditions of the log queries
 )
 
-var PermittedSubResourceList = []string{AllRsrc, Role, Log, Alarm, Pandas}
+var PermittedSubResourceList = []string{AllRsrc, Role, Log, Alarm, Pandas, RawSQL}
 var PermittedSubResource = map[string]string{
 	AllRsrc: "All(全部)",
 	Log:     "日志",
 	Alarm:   "告警",
 	Pandas:  "分析",
 	Role:    "角色",
+	RawSQL:  "原生 SQL 查询",
 }
 
 var PermittedAppAdminGrantSubResource = map[string]string{}
@@ -0,0 +1,360 @@
+package service
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/gotomicro/ego/core/emetric"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	QueryCacheModeMemory = "memory"
+	QueryCacheModeRedis  = "redis"
+	QueryCacheModeOff    = "off"
+)
+
+const (
+	queryCacheKeyPrefix     = "clickvisual:query-cache:"
+	queryCacheSettledTTL    = time.Hour // of the results of which the time range is settled, late logs show up after it
+	queryCacheSegmentPoints = 60        // points of a chart segment
+)
+
+// queryCacheCounter is exposed on the /metrics of the governor server.
+var queryCacheCounter = emetric.CounterVecOpts{
+	Namespace: "clickvisual",
+	Name:      "query_cache_total",
+	Help:      "lookups of the query cache by query (chart or count) and result (hit or miss)",
+	Labels:    []string{"query", "result"},
+}.Build()
+
+// queryCacheStore keeps the results of the queries. Every table has a version in the keys of its results,
+// a new version invalidates all of them.
+type queryCacheStore interface {
+	get(key string) ([]byte, bool)
+	set(key string, val []byte, ttl time.Duration)
+	version(tid int) int64
+	bump(tid int)
+}
+
+// queryCache caches the results of Chart and Count keyed by their normalized statements. A chart is split into
+// segments aligned to multiples of 60 points, so only the segments of which the logs may still change are read
+// again when the time range moves.
+type queryCache struct {
+	store  queryCacheStore
+	ttl    time.Duration // of the results which reach the unsettled logs, 0 does not cache them
+	settle int64         // seconds after which the logs of a time are not expected to change
+	now    func() time.Time
+}
+
+// NewQueryCache reads the app config: queryCache memory (or redis, or off), queryCacheSize 10000 results,
+// queryCacheTTL 10s and queryCacheSettle 15m by default, which covers the usual lag of the Kafka ingestion.
+// In multi-copy mode the results are always kept in redis unless the cache is off, as the invalidations of a
+// memory cache would not reach the other copies.
+func NewQueryCache() *queryCache {
+	c := &queryCache{
+		ttl:    10 * time.Second,
+		settle: 900,
+		now:    time.Now,
+	}
+	if econf.Get("app.queryCacheTTL") != nil {
+		c.ttl = econf.GetDuration("app.queryCacheTTL")
+	}
+	if settle := econf.GetDuration("app.queryCacheSettle"); settle > 0 {
+		c.settle = int64(settle.Seconds())
+	}
+	mode := econf.GetString("app.queryCache")
+	switch {
+	case mode == QueryCacheModeOff:
+		return c
+	case econf.GetBool("app.isMultiCopy"):
+		if invoker.Redis == nil {
+			elog.Warn("NewQueryCache", elog.String("msg", "redis is not configured in multi-copy mode, the results are not cached"))
+			return c
+		}
+		c.store = &queryCacheRedis{}
+	case mode == QueryCacheModeRedis && invoker.Redis != nil:
+		c.store = &queryCacheRedis{}
+	default:
+		if mode == QueryCacheModeRedis {
+			elog.Warn("NewQueryCache", elog.String("msg", "redis is not configured, the results are cached in memory"))
+		}
+		size := econf.GetInt("app.queryCacheSize")
+		if size <= 0 {
+			size = 10000
+		}
+		c.store = newQueryCacheMemory(size)
+	}
+	return c
+}
+
+// Wrap returns the operator with its Chart and Count cached, the operators which do not build statements are not cached.
+func (c *queryCache) Wrap(op factory.Operator) factory.Operator {
+	if c == nil || c.store == nil {
+		return op
+	}
+	if _, ok := op.(factory.SQLBuilder); !ok {
+		return op
+	}
+	return &cachedOperator{Operator: op, cache: c}
+}
+
+// Invalidate drops the results of the table, after its views or analysis fields change.
+func (c *queryCache) Invalidate(tid int) {
+	if c == nil || c.store == nil || tid == 0 {
+		return
+	}
+	c.store.bump(tid)
+}
+
+func (c *queryCache) key(tid int, query, sql string) string {
+	sum := sha1.Sum([]byte(strings.Join(strings.Fields(sql), " ")))
+	return fmt.Sprintf("%s%d:%d:%s:%x", queryCacheKeyPrefix, tid, c.store.version(tid), query, sum)
+}
+
+// expiration of the results of the time range ending at et, 0 when they are not cached.
+func (c *queryCache) expiration(et int64) time.Duration {
+	if et <= c.now().Unix()-c.settle {
+		return queryCacheSettledTTL
+	}
+	return c.ttl
+}
+
+func (c *queryCache) load(key, query string, res interface{}) bool {
+	if b, ok := c.store.get(key); ok && json.Unmarshal(b, res) == nil {
+		queryCacheCounter.Inc(query, "hit")
+		return true
+	}
+	queryCacheCounter.Inc(query, "miss")
+	return false
+}
+
+func (c *queryCache) save(key string, res interface{}, ttl time.Duration) {
+	b, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	c.store.set(key, b, ttl)
+}
+
+type queryCacheSegment struct {
+	st, et int64
+}
+
+// chartSegments splits [st, et) at the multiples of 60 points of the interval, the points of a segment
+// are counted again with the next when the points are not aligned to the multiples.
+func chartSegments(st, et, interval int64) []queryCacheSegment {
+	if interval <= 0 || et <= st {
+		return []queryCacheSegment{{st: st, et: et}}
+	}
+	size := interval * queryCacheSegmentPoints
+	res := make([]queryCacheSegment, 0)
+	for b := (st/size + 1) * size; b < et; b += size {
+		res = append(res, queryCacheSegment{st: st, et: b})
+		st = b
+	}
+	return append(res, queryCacheSegment{st: st, et: et})
+}
+
+// cachedOperator is the operator of a datasource with the query cache.
+type cachedOperator struct {
+	factory.Operator
+	cache *queryCache
+}
+
+func (o *cachedOperator) Unwrap() factory.Operator {
+	return o.Operator
+}
+
+func (o *cachedOperator) Count(param view.ReqQuery) (uint64, error) {
+	ttl := o.cache.expiration(param.ET)
+	if ttl <= 0 {
+		return o.Operator.Count(param)
+	}
+	var res uint64
+	key := o.cache.key(param.Tid, "count", o.Operator.(factory.SQLBuilder).CountSQL(param))
+	if o.cache.load(key, "count", &res) {
+		return res, nil
+	}
+	res, err := o.Operator.Count(param)
+	if err != nil {
+		return res, err
+	}
+	o.cache.save(key, res, ttl)
+	return res, nil
+}
+
+// Chart reads the segments of the time range and merges the points of the same time.
+func (o *cachedOperator) Chart(param view.ReqQuery) ([]*view.HighChart, string, error) {
+	q := o.Operator.(factory.SQLBuilder).ChartSQL(param)
+	points := make(map[int64]*view.HighChart)
+	for _, seg := range chartSegments(param.ST, param.ET, param.Interval) {
+		p := param
+		p.ST, p.ET = seg.st, seg.et
+		charts, err := o.chartSegment(p)
+		if err != nil {
+			return nil, q, err
+		}
+		for _, chart := range charts {
+			if point, ok := points[chart.From]; ok {
+				point.Count += chart.Count
+				continue
+			}
+			points[chart.From] = chart
+		}
+	}
+	res := make([]*view.HighChart, 0, len(points))
+	for _, point := range points {
+		res = append(res, point)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].From < res[j].From
+	})
+	return res, q, nil
+}
+
+func (o *cachedOperator) chartSegment(param view.ReqQuery) ([]*view.HighChart, error) {
+	ttl := o.cache.expiration(param.ET)
+	if ttl <= 0 {
+		res, _, err := o.Operator.Chart(param)
+		return res, err
+	}
+	res := make([]*view.HighChart, 0)
+	key := o.cache.key(param.Tid, "chart", o.Operator.(factory.SQLBuilder).ChartSQL(param))
+	if o.cache.load(key, "chart", &res) {
+		return res, nil
+	}
+	res, _, err := o.Operator.Chart(param)
+	if err != nil {
+		return nil, err
+	}
+	o.cache.save(key, res, ttl)
+	return res, nil
+}
+
+func (o *cachedOperator) SyncView(table db.BaseTable, current *db.BaseView, list []*db.BaseView, isAddOrUpdate bool) (string, string, error) {
+	defer o.cache.Invalidate(table.ID)
+	return o.Operator.SyncView(table, current, list, isAddOrUpdate)
+}
+
+func (o *cachedOperator) UpdateLogAnalysisFields(database db.BaseDatabase, table db.BaseTable, adds, dels, newList map[string]*db.BaseIndex) error {
+	defer o.cache.Invalidate(table.ID)
+	return o.Operator.UpdateLogAnalysisFields(database, table, adds, dels, newList)
+}
+
+func (o *cachedOperator) DeleteTable(database, table, cluster string, tid int) error {
+	defer o.cache.Invalidate(tid)
+	return o.Operator.DeleteTable(database, table, cluster, tid)
+}
+
+// queryCacheMemory is an LRU of the results in the process.
+type queryCacheMemory struct {
+	mu       sync.Mutex
+	size     int
+	items    *list.List
+	keys     map[string]*list.Element
+	versions map[int]int64
+}
+
+type queryCacheItem struct {
+	key      string
+	val      []byte
+	expireAt time.Time
+}
+
+func newQueryCacheMemory(size int) *queryCacheMemory {
+	return &queryCacheMemory{
+		size:     size,
+		items:    list.New(),
+		keys:     make(map[string]*list.Element),
+		versions: make(map[int]int64),
+	}
+}
+
+func (m *queryCacheMemory) get(key string) ([]byte, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	e, ok := m.keys[key]
+	if !ok {
+		return nil, false
+	}
+	item := e.Value.(*queryCacheItem)
+	if time.Now().After(item.expireAt) {
+		m.items.Remove(e)
+		delete(m.keys, key)
+		return nil, false
+	}
+	m.items.MoveToFront(e)
+	return item.val, true
+}
+
+func (m *queryCacheMemory) set(key string, val []byte, ttl time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := &queryCacheItem{key: key, val: val, expireAt: time.Now().Add(ttl)}
+	if e, ok := m.keys[key]; ok {
+		e.Value = item
+		m.items.MoveToFront(e)
+		return
+	}
+	m.keys[key] = m.items.PushFront(item)
+	for m.items.Len() > m.size {
+		e := m.items.Back()
+		m.items.Remove(e)
+		delete(m.keys, e.Value.(*queryCacheItem).key)
+	}
+}
+
+func (m *queryCacheMemory) version(tid int) int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.versions[tid]
+}
+
+// bump leaves the results of the older versions to the LRU.
+func (m *queryCacheMemory) bump(tid int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.versions[tid]++
+}
+
+// queryCacheRedis keeps the results in invoker.Redis, shared by the copies of multi-copy mode.
+type queryCacheRedis struct{}
+
+func (r *queryCacheRedis) get(key string) ([]byte, bool) {
+	b, err := invoker.Redis.GetBytes(context.Background(), key)
+	return b, err == nil
+}
+
+func (r *queryCacheRedis) set(key string, val []byte, ttl time.Duration) {
+	if err := invoker.Redis.Set(context.Background(), key, val, ttl); err != nil {
+		elog.Warn("queryCacheRedis", elog.String("step", "set"), elog.FieldErr(err))
+	}
+}
+
+func (r *queryCacheRedis) version(tid int) int64 {
+	v, _ := invoker.Redis.Get(context.Background(), queryCacheKeyPrefix+"version:"+strconv.Itoa(tid))
+	return cast.ToInt64(v)
+}
+
+// bump leaves the results of the older versions to expire.
+func (r *queryCacheRedis) bump(tid int) {
+	if _, err := invoker.Redis.Incr(context.Background(), queryCacheKeyPrefix+"version:"+strconv.Itoa(tid)); err != nil {
+		elog.Error("queryCacheRedis", elog.String("step", "bump"), elog.FieldErr(err), elog.Int("tid", tid))
+	}
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+// fakeChartOperator counts a log every second, charts by minute and records the time ranges it read.
+type fakeChartOperator struct {
+	factory.Operator
+	reads [][2]int64
+}
+
+func (f *fakeChartOperator) ChartSQL(param view.ReqQuery) string {
+	return fmt.Sprintf("SELECT  chart %d %d", param.ST, param.ET)
+}
+
+func (f *fakeChartOperator) CountSQL(param view.ReqQuery) string {
+	return fmt.Sprintf("SELECT count %d\n%d", param.ST, param.ET)
+}
+
+func (f *fakeChartOperator) Chart(param view.ReqQuery) ([]*view.HighChart, string, error) {
+	f.reads = append(f.reads, [2]int64{param.ST, param.ET})
+	res := make([]*view.HighChart, 0)
+	for t := param.ST; t < param.ET; t++ {
+		from := t / 60 * 60
+		if n := len(res); n > 0 && res[n-1].From == from {
+			res[n-1].Count++
+			continue
+		}
+		res = append(res, &view.HighChart{From: from, Count: 1})
+	}
+	return res, f.ChartSQL(param), nil
+}
+
+func (f *fakeChartOperator) Count(param view.ReqQuery) (uint64, error) {
+	f.reads = append(f.reads, [2]int64{param.ST, param.ET})
+	return uint64(param.ET - param.ST), nil
+}
+
+func (f *fakeChartOperator) SyncView(db.BaseTable, *db.BaseView, []*db.BaseView, bool) (string, string, error) {
+	return "", "", nil
+}
+
+func TestChartSegments(t *testing.T) {
+	want := []queryCacheSegment{{st: 3590, et: 3600}, {st: 3600, et: 7200}, {st: 7200, et: 7300}}
+	if got := chartSegments(3590, 7300, 60); !reflect.DeepEqual(got, want) {
+		t.Errorf("chartSegments() = %v, want %v", got, want)
+	}
+	if got := chartSegments(3590, 7300, 0); len(got) != 1 {
+		t.Errorf("chartSegments() without interval = %v", got)
+	}
+}
+
+func TestCachedOperator_Chart(t *testing.T) {
+	fake := &fakeChartOperator{}
+	now := int64(1700002800) // a multiple of the segment
+	c := &queryCache{store: newQueryCacheMemory(100), ttl: 0, settle: 60, now: func() time.Time { return time.Unix(now+60, 0) }}
+	op := c.Wrap(fake)
+	if factory.Unwrap(op) != fake {
+		t.Fatalf("Unwrap() is not the operator of the datasource")
+	}
+	param := view.ReqQuery{Tid: 1, ST: now - 3630, ET: now + 30, Interval: 60}
+	direct, _, _ := fake.Chart(param)
+	fake.reads = nil
+
+	res, _, err := op.Chart(param)
+	if err != nil || !reflect.DeepEqual(res, direct) {
+		t.Fatalf("Chart() = %v, %v, want %v", res, err, direct)
+	}
+	if len(fake.reads) != 3 {
+		t.Fatalf("Chart() read %v, want 3 segments", fake.reads)
+	}
+	// the settled segments are cached, the newest is read again
+	fake.reads = nil
+	if res, _, _ = op.Chart(param); !reflect.DeepEqual(res, direct) {
+		t.Errorf("Chart() of the cache = %v, want %v", res, direct)
+	}
+	if want := [][2]int64{{now, now + 30}}; !reflect.DeepEqual(fake.reads, want) {
+		t.Errorf("Chart() of the cache read %v, want %v", fake.reads, want)
+	}
+	// a new view invalidates the results of the table
+	_, _, _ = op.SyncView(db.BaseTable{BaseModel: db.BaseModel{ID: 1}}, nil, nil, true)
+	fake.reads = nil
+	_, _, _ = op.Chart(param)
+	if len(fake.reads) != 3 {
+		t.Errorf("Chart() after SyncView read %v, want 3 segments", fake.reads)
+	}
+}
+
+func TestCachedOperator_Count(t *testing.T) {
+	fake := &fakeChartOperator{}
+	c := &queryCache{store: newQueryCacheMemory(100), ttl: time.Minute, settle: 60, now: time.Now}
+	op := c.Wrap(fake)
+	param := view.ReqQuery{Tid: 1, ST: 100, ET: 200}
+	for i := 0; i < 2; i++ {
+		if n, err := op.Count(param); err != nil || n != 100 {
+			t.Fatalf("Count() = %d, %v", n, err)
+		}
+	}
+	if len(fake.reads) != 1 {
+		t.Errorf("Count() read %v, want once", fake.reads)
+	}
+	// the statements are normalized
+	if c.key(1, "count", "SELECT  a\n b") != c.key(1, "count", "SELECT a b") {
+		t.Errorf("key() of the same statement differs")
+	}
+}
+
+func TestQueryCacheMemory(t *testing.T) {
+	m := newQueryCacheMemory(2)
+	m.set("a", []byte("1"), time.Minute)
+	m.set("b", []byte("2"), time.Minute)
+	m.get("a")
+	m.set("c", []byte("3"), time.Minute)
+	if _, ok := m.get("b"); ok {
+		t.Errorf("get() of the least recently used key is found")
+	}
+	if v, ok := m.get("a"); !ok || string(v) != "1" {
+		t.Errorf("get() = %s, %v", v, ok)
+	}
+	m.set("d", []byte("4"), -time.Second)
+	if _, ok := m.get("d"); ok {
+		t.Errorf("get() of an expired key is found")
+	}
+}
@@ -0,0 +1,538 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ego-component/eredis"
+	"github.com/google/uuid"
+	"github.com/gotomicro/cetus/pkg/xgo"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	queryJobTTL          = 30 * time.Minute // finished jobs are kept for polling
+	queryJobSyncInterval = time.Second
+	queryJobRunningTTL   = time.Minute // of the saved running jobs, the jobs of a stopped copy are gone after it
+	queryJobPartialMax   = 1000        // partial rows kept of the running statement
+	queryJobKeyPrefix    = "clickvisual:query-job:"
+)
+
+var (
+	ErrQueryJobNotFound = errors.New("query job not found")
+	ErrQueryJobLimit    = errors.New("too many running query jobs on the instance")
+)
+
+// queryJobs runs the log queries in the background. A job runs in the process which accepted it, in multi-copy
+// mode it is saved to the shared store every second so that the other copies answer its polls and cancel it.
+type queryJobs struct {
+	mu       sync.Mutex
+	jobs     map[string]*queryJob
+	shared   queryJobStore // nil when a single copy runs
+	stopChan chan struct{}
+}
+
+// queryJobStore keeps the jobs of all the copies keyed by their ids.
+type queryJobStore interface {
+	save(job view.RespQueryJobResult, ttl time.Duration) error
+	// load returns ErrQueryJobNotFound when the job is not kept
+	load(id string) (view.RespQueryJobResult, error)
+	list(uid int) ([]view.RespQueryJob, error)
+	// requestCancel asks the copy which runs the job to cancel it
+	requestCancel(id string) error
+	cancelRequested(id string) bool
+}
+
+type queryJob struct {
+	mu         sync.Mutex
+	info       view.RespQueryJob
+	partialSQL string
+	partial    []map[string]interface{}
+	result     interface{}
+	cancel     context.CancelFunc
+	op         factory.JobOperator
+	saved      bool // the finished job is in the shared store
+}
+
+func NewQueryJobs() *queryJobs {
+	s := &queryJobs{jobs: make(map[string]*queryJob)}
+	if econf.GetBool("app.isMultiCopy") && invoker.Redis != nil {
+		s.shared = &queryJobsRedis{}
+	}
+	return s
+}
+
+// SubmitLogs starts a job of the log query of the table, as the query of /tables/:id/logs.
+func (s *queryJobs) SubmitLogs(uid int, tableInfo db.BaseTable, param view.ReqQuery) (view.RespQueryJob, error) {
+	param = logsQueryParam(tableInfo, param)
+	ins, op, err := s.load(tableInfo.Database.Iid)
+	if err != nil {
+		return view.RespQueryJob{}, err
+	}
+	param, err = op.Prepare(param, &tableInfo, false)
+	if err != nil {
+		return view.RespQueryJob{}, errors.Wrap(err, "param prepare failed")
+	}
+	if param.Query == "" {
+		return view.RespQueryJob{}, errors.New("query parameter error")
+	}
+	job := view.RespQueryJob{Kind: view.QueryJobKindLogs, Uid: uid, Iid: ins.ID, Tid: tableInfo.ID}
+	return s.submit(job, ins, op, func(op factory.Operator) (interface{}, error) {
+		st := time.Now()
+		res, err := op.GetLogs(param, tableInfo.ID)
+		if err != nil {
+			return nil, err
+		}
+		if tableInfo.V3TableType == db.V3TableTypeJaegerJSON {
+			res.IsTrace = 1
+		}
+		if param.IsQueryCount == 1 {
+			if res.Count, err = op.Count(param); err != nil {
+				return nil, err
+			}
+		}
+		res.Cost = time.Since(st).Milliseconds()
+		return res, nil
+	})
+}
+
+// logsQueryParam fills the table of the log query.
+func logsQueryParam(tableInfo db.BaseTable, param view.ReqQuery) view.ReqQuery {
+	param.TimeField = db.TimeFieldSecond
+	if tableInfo.CreateType == constx.TableCreateTypeExist && tableInfo.TimeField != "" {
+		param.TimeField = tableInfo.TimeField
+	}
+	param.Tid = tableInfo.ID
+	param.Table = tableInfo.Name
+	param.TimeFieldType = tableInfo.TimeFieldType
+	param.Database = tableInfo.Database.Name
+	return param
+}
+
+// SubmitSQL starts a job of the statement on the instance.
+func (s *queryJobs) SubmitSQL(uid, iid int, sql string) (view.RespQueryJob, error) {
+	ins, op, err := s.load(iid)
+	if err != nil {
+		return view.RespQueryJob{}, err
+	}
+	job := view.RespQueryJob{Kind: view.QueryJobKindSQL, Uid: uid, Iid: iid}
+	return s.submit(job, ins, op, func(op factory.Operator) (interface{}, error) {
+		return op.DoSQL(sql)
+	})
+}
+
+func (s *queryJobs) load(iid int) (db.BaseInstance, factory.Operator, error) {
+	ins, err := db.InstanceInfo(invoker.Db, iid)
+	if err != nil {
+		return ins, nil, err
+	}
+	op, err := InstanceManager.Load(iid)
+	return ins, op, err
+}
+
+// submit registers the job when the user is under the concurrency limit of the instance and runs it.
+// In multi-copy mode the running jobs of the other copies count too, and the job is saved before it starts.
+func (s *queryJobs) submit(info view.RespQueryJob, ins db.BaseInstance, op factory.Operator, run func(factory.Operator) (interface{}, error)) (view.RespQueryJob, error) {
+	jop, ok := factory.Unwrap(op).(factory.JobOperator)
+	if !ok {
+		return info, factory.ErrJobUnsupported
+	}
+	var shared []view.RespQueryJob
+	if s.shared != nil && ins.QueryMaxConcurrency > 0 {
+		var err error
+		if shared, err = s.shared.list(info.Uid); err != nil {
+			return info, err
+		}
+	}
+	info.Id = uuid.NewString()
+	info.Status = view.QueryJobRunning
+	info.Ctime = time.Now().Unix()
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &queryJob{info: info, cancel: cancel, op: jop}
+	s.mu.Lock()
+	if ins.QueryMaxConcurrency > 0 && s.running(info.Uid, info.Iid, shared) >= ins.QueryMaxConcurrency {
+		s.mu.Unlock()
+		cancel()
+		return info, errors.Wrapf(ErrQueryJobLimit, "limit %d", ins.QueryMaxConcurrency)
+	}
+	s.jobs[info.Id] = job
+	s.mu.Unlock()
+	if s.shared != nil {
+		if err := s.shared.save(view.RespQueryJobResult{RespQueryJob: info}, queryJobRunningTTL); err != nil {
+			s.mu.Lock()
+			delete(s.jobs, info.Id)
+			s.mu.Unlock()
+			cancel()
+			return info, err
+		}
+	}
+	jobOp := jop.WithJob(factory.Job{
+		Ctx:              ctx,
+		QueryId:          info.Id,
+		MaxExecutionTime: ins.QueryMaxExecutionTime,
+		Progress:         job.progress,
+		Row:              job.row,
+	})
+	xgo.Go(func() {
+		defer cancel()
+		res, err := run(jobOp)
+		job.finish(res, err)
+	})
+	return info, nil
+}
+
+// running counts the running jobs of the user on the instance, of this process and of the shared jobs of the
+// other copies, s.mu is held.
+func (s *queryJobs) running(uid, iid int, shared []view.RespQueryJob) int {
+	n := 0
+	for _, job := range s.jobs {
+		job.mu.Lock()
+		if job.info.Uid == uid && job.info.Iid == iid && job.info.Status == view.QueryJobRunning {
+			n++
+		}
+		job.mu.Unlock()
+	}
+	for _, job := range shared {
+		if _, ok := s.jobs[job.Id]; !ok && job.Uid == uid && job.Iid == iid && job.Status == view.QueryJobRunning {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *queryJobs) get(id string) (*queryJob, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrQueryJobNotFound
+	}
+	return job, nil
+}
+
+func (s *queryJobs) Info(id string) (view.RespQueryJob, error) {
+	res, err := s.Result(id)
+	return res.RespQueryJob, err
+}
+
+// List returns the jobs of the user, the latest first.
+func (s *queryJobs) List(uid int) []view.RespQueryJob {
+	s.mu.Lock()
+	res := make([]view.RespQueryJob, 0)
+	for _, job := range s.jobs {
+		job.mu.Lock()
+		if job.info.Uid == uid {
+			res = append(res, job.snapshot())
+		}
+		job.mu.Unlock()
+	}
+	if s.shared != nil {
+		shared, err := s.shared.list(uid)
+		if err != nil {
+			elog.Error("queryJobs", elog.String("step", "list"), elog.FieldErr(err), elog.Int("uid", uid))
+		}
+		for _, job := range shared {
+			if _, ok := s.jobs[job.Id]; !ok {
+				res = append(res, job)
+			}
+		}
+	}
+	s.mu.Unlock()
+	sort.Slice(res, func(i, j int) bool { return res[i].Ctime > res[j].Ctime })
+	return res
+}
+
+// Result returns the partial rows of a running job or the result of a finished one,
+// the jobs of the other copies are read from the shared store.
+func (s *queryJobs) Result(id string) (view.RespQueryJobResult, error) {
+	job, err := s.get(id)
+	if errors.Is(err, ErrQueryJobNotFound) && s.shared != nil {
+		return s.shared.load(id)
+	}
+	if err != nil {
+		return view.RespQueryJobResult{}, err
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	return job.snapshotResult(), nil
+}
+
+// Cancel stops a running job and kills its statements on the server.
+func (s *queryJobs) Cancel(id string) (view.RespQueryJob, error) {
+	job, err := s.get(id)
+	if errors.Is(err, ErrQueryJobNotFound) && s.shared != nil {
+		return s.cancelShared(id)
+	}
+	if err != nil {
+		return view.RespQueryJob{}, err
+	}
+	job.mu.Lock()
+	defer job.mu.Unlock()
+	if job.info.Status != view.QueryJobRunning {
+		return job.snapshot(), nil
+	}
+	job.cancelled()
+	xgo.Go(func() {
+		core.LoggerError("queryJobs", "killQuery", job.op.KillQuery(id))
+	})
+	return job.snapshot(), nil
+}
+
+// cancelShared cancels a job of another copy: the copy stops the job at its next sync, and the statements are
+// killed from here so that they stop at once.
+func (s *queryJobs) cancelShared(id string) (view.RespQueryJob, error) {
+	res, err := s.shared.load(id)
+	if err != nil {
+		return view.RespQueryJob{}, err
+	}
+	info := res.RespQueryJob
+	if info.Status != view.QueryJobRunning {
+		return info, nil
+	}
+	if err = s.shared.requestCancel(id); err != nil {
+		return info, err
+	}
+	if op, errLoad := InstanceManager.Load(info.Iid); errLoad == nil {
+		if jop, ok := factory.Unwrap(op).(factory.JobOperator); ok {
+			xgo.Go(func() {
+				core.LoggerError("queryJobs", "killQuery", jop.KillQuery(id))
+			})
+		}
+	}
+	info.Status = view.QueryJobCancelled
+	info.Ftime = time.Now().Unix()
+	info.PartialRows = 0
+	return info, nil
+}
+
+// tickerSync syncs the jobs with the other copies every second and removes the old ones.
+func (s *queryJobs) tickerSync() {
+	s.mu.Lock()
+	s.stopChan = make(chan struct{})
+	stopChan := s.stopChan
+	s.mu.Unlock()
+	ticker := time.NewTicker(queryJobSyncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			s.sync()
+			s.clean(now)
+		}
+	}
+}
+
+// sync cancels the running jobs of which the other copies requested the cancellation, and saves the running jobs
+// and the jobs which finished since the last sync to the shared store.
+func (s *queryJobs) sync() {
+	if s.shared == nil {
+		return
+	}
+	s.mu.Lock()
+	jobs := make([]*queryJob, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+	for _, job := range jobs {
+		job.mu.Lock()
+		id, running, saved := job.info.Id, job.info.Status == view.QueryJobRunning, job.saved
+		job.mu.Unlock()
+		if saved {
+			continue
+		}
+		if running && s.shared.cancelRequested(id) {
+			job.mu.Lock()
+			job.cancelled()
+			job.mu.Unlock()
+		}
+		job.mu.Lock()
+		res, ttl := job.snapshotResult(), queryJobRunningTTL
+		if res.Status != view.QueryJobRunning {
+			ttl = queryJobTTL
+		}
+		job.mu.Unlock()
+		if err := s.shared.save(res, ttl); err != nil {
+			elog.Error("queryJobs", elog.String("step", "save"), elog.FieldErr(err), elog.String("id", id))
+			continue
+		}
+		if ttl == queryJobTTL {
+			job.mu.Lock()
+			job.saved = true
+			job.mu.Unlock()
+		}
+	}
+}
+
+// clean removes the jobs finished for longer than the ttl.
+func (s *queryJobs) clean(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, job := range s.jobs {
+		job.mu.Lock()
+		if job.info.Ftime != 0 && now.Sub(time.Unix(job.info.Ftime, 0)) > queryJobTTL {
+			delete(s.jobs, id)
+		}
+		job.mu.Unlock()
+	}
+}
+
+func (s *queryJobs) stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopChan != nil {
+		close(s.stopChan)
+		s.stopChan = nil
+	}
+	for _, job := range s.jobs {
+		job.cancel()
+	}
+}
+
+// snapshot copies the info, job.mu is held.
+func (j *queryJob) snapshot() view.RespQueryJob {
+	res := j.info
+	res.PartialRows = len(j.partial)
+	return res
+}
+
+// snapshotResult copies the info, the partial rows and the result, job.mu is held.
+func (j *queryJob) snapshotResult() view.RespQueryJobResult {
+	res := view.RespQueryJobResult{RespQueryJob: j.snapshot(), Result: j.result}
+	res.Partial = append(make([]map[string]interface{}, 0, len(j.partial)), j.partial...)
+	return res
+}
+
+// cancelled stops the running job, its statements are killed by the caller, job.mu is held.
+func (j *queryJob) cancelled() {
+	j.info.Status = view.QueryJobCancelled
+	j.info.Ftime = time.Now().Unix()
+	j.partial = nil
+	j.cancel()
+}
+
+func (j *queryJob) progress(rows, bytes, totalRows uint64) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.info.ReadRows += rows
+	j.info.ReadBytes += bytes
+	j.info.TotalRows += totalRows
+}
+
+// row keeps the first rows of the running statement, a new statement replaces the rows of the previous one.
+func (j *queryJob) row(sql string, row map[string]interface{}) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.info.Status != view.QueryJobRunning {
+		return
+	}
+	if sql != j.partialSQL {
+		j.partialSQL = sql
+		j.partial = j.partial[:0]
+	}
+	if len(j.partial) >= queryJobPartialMax {
+		return
+	}
+	line := make(map[string]interface{}, len(row))
+	for k, v := range row {
+		if k != factory.CursorTsField && k != factory.CursorTieField {
+			line[k] = v
+		}
+	}
+	j.partial = append(j.partial, line)
+}
+
+func (j *queryJob) finish(res interface{}, err error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.info.Status != view.QueryJobRunning {
+		return
+	}
+	j.info.Ftime = time.Now().Unix()
+	j.partial = nil
+	if err != nil {
+		j.info.Status = view.QueryJobFailed
+		j.info.Error = err.Error()
+		return
+	}
+	j.info.Status = view.QueryJobSucceeded
+	j.result = res
+}
+
+// queryJobsRedis keeps the jobs in invoker.Redis, shared by the copies of multi-copy mode.
+type queryJobsRedis struct{}
+
+func (r *queryJobsRedis) save(job view.RespQueryJobResult, ttl time.Duration) error {
+	b, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	if err = invoker.Redis.Set(ctx, queryJobKeyPrefix+job.Id, b, ttl); err != nil {
+		return err
+	}
+	// the ids of the jobs of the user, the ids of the expired jobs are removed by list
+	key := queryJobKeyPrefix + "uid:" + strconv.Itoa(job.Uid)
+	if _, err = invoker.Redis.SAdd(ctx, key, job.Id); err != nil {
+		return err
+	}
+	_, err = invoker.Redis.Expire(ctx, key, queryJobTTL)
+	return err
+}
+
+func (r *queryJobsRedis) load(id string) (res view.RespQueryJobResult, err error) {
+	b, err := invoker.Redis.GetBytes(context.Background(), queryJobKeyPrefix+id)
+	if errors.Is(err, eredis.Nil) {
+		return res, ErrQueryJobNotFound
+	}
+	if err != nil {
+		return res, err
+	}
+	err = json.Unmarshal(b, &res)
+	return res, err
+}
+
+func (r *queryJobsRedis) list(uid int) ([]view.RespQueryJob, error) {
+	key := queryJobKeyPrefix + "uid:" + strconv.Itoa(uid)
+	ids, err := invoker.Redis.SMembers(context.Background(), key)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]view.RespQueryJob, 0, len(ids))
+	for _, id := range ids {
+		job, errLoad := r.load(id)
+		if errors.Is(errLoad, ErrQueryJobNotFound) {
+			_, _ = invoker.Redis.SRem(context.Background(), key, id)
+			continue
+		}
+		if errLoad != nil {
+			return nil, errLoad
+		}
+		res = append(res, job.RespQueryJob)
+	}
+	return res, nil
+}
+
+func (r *queryJobsRedis) requestCancel(id string) error {
+	return invoker.Redis.Set(context.Background(), queryJobKeyPrefix+id+":cancel", "1", queryJobTTL)
+}
+
+func (r *queryJobsRedis) cancelRequested(id string) bool {
+	ok, _ := invoker.Redis.Exists(context.Background(), queryJobKeyPrefix+id+":cancel")
+	return ok
+}
@@ -0,0 +1,202 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+type fakeJobOperator struct {
+	factory.Operator
+	job    factory.Job
+	killed chan string
+}
+
+func (o *fakeJobOperator) WithJob(job factory.Job) factory.Operator {
+	return &fakeJobOperator{job: job, killed: o.killed}
+}
+
+func (o *fakeJobOperator) KillQuery(queryId string) error {
+	o.killed <- queryId
+	return nil
+}
+
+// DoSQL reads two statements and blocks until the job is cancelled when sql is "block".
+func (o *fakeJobOperator) DoSQL(sql string) (view.RespComplete, error) {
+	o.job.Progress(10, 100, 30)
+	o.job.Row("first", map[string]interface{}{"a": 1})
+	o.job.Row("second", map[string]interface{}{"b": 2, factory.CursorTsField: 3})
+	o.job.Progress(20, 200, 0)
+	if sql == "block" {
+		<-o.job.Ctx.Done()
+		return view.RespComplete{}, o.job.Ctx.Err()
+	}
+	return view.RespComplete{Logs: []map[string]interface{}{{"b": 2}}}, nil
+}
+
+func waitQueryJob(t *testing.T, s *queryJobs, id string, status string) view.RespQueryJobResult {
+	for i := 0; i < 100; i++ {
+		res, err := s.Result(id)
+		if err != nil {
+			t.Fatalf("Result() error = %v", err)
+		}
+		if res.Status == status && (status != view.QueryJobRunning || res.ReadRows == 30) {
+			return res
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("job %s is not %s", id, status)
+	return view.RespQueryJobResult{}
+}
+
+func Test_queryJobs(t *testing.T) {
+	s := NewQueryJobs()
+	op := &fakeJobOperator{killed: make(chan string, 1)}
+	ins := db.BaseInstance{QueryMaxConcurrency: 1}
+	ins.ID = 1
+	run := func(sql string) func(factory.Operator) (interface{}, error) {
+		return func(op factory.Operator) (interface{}, error) { return op.DoSQL(sql) }
+	}
+
+	blocked, err := s.submit(view.RespQueryJob{Uid: 1, Iid: 1}, ins, op, run("block"))
+	if err != nil {
+		t.Fatalf("submit() error = %v", err)
+	}
+	if _, err = s.submit(view.RespQueryJob{Uid: 1, Iid: 1}, ins, op, run("block")); !errors.Is(err, ErrQueryJobLimit) {
+		t.Errorf("submit() error = %v, want %v", err, ErrQueryJobLimit)
+	}
+	res := waitQueryJob(t, s, blocked.Id, view.QueryJobRunning)
+	if res.ReadBytes != 300 || res.TotalRows != 30 {
+		t.Errorf("Result() progress = %d bytes %d total rows", res.ReadBytes, res.TotalRows)
+	}
+	if want := []map[string]interface{}{{"b": 2}}; !reflect.DeepEqual(res.Partial, want) {
+		t.Errorf("Result() partial = %v, want %v", res.Partial, want)
+	}
+
+	if _, err = s.Cancel(blocked.Id); err != nil {
+		t.Fatalf("Cancel() error = %v", err)
+	}
+	if id := <-op.killed; id != blocked.Id {
+		t.Errorf("KillQuery() = %s, want %s", id, blocked.Id)
+	}
+	res = waitQueryJob(t, s, blocked.Id, view.QueryJobCancelled)
+	if res.Error != "" || len(res.Partial) != 0 {
+		t.Errorf("Result() = %+v, want a cancelled job without rows", res)
+	}
+
+	done, err := s.submit(view.RespQueryJob{Uid: 1, Iid: 1}, ins, op, run("done"))
+	if err != nil {
+		t.Fatalf("submit() error = %v", err)
+	}
+	res = waitQueryJob(t, s, done.Id, view.QueryJobSucceeded)
+	if want := (view.RespComplete{Logs: []map[string]interface{}{{"b": 2}}}); !reflect.DeepEqual(res.Result, want) {
+		t.Errorf("Result() result = %v, want %v", res.Result, want)
+	}
+	if got := s.List(1); len(got) != 2 {
+		t.Errorf("List() = %d jobs, want 2", len(got))
+	}
+	s.clean(time.Now().Add(queryJobTTL + time.Minute))
+	if _, err = s.Info(done.Id); !errors.Is(err, ErrQueryJobNotFound) {
+		t.Errorf("Info() error = %v, want %v", err, ErrQueryJobNotFound)
+	}
+}
+
+// memQueryJobStore is the shared store of the copies in the tests.
+type memQueryJobStore struct {
+	mu     sync.Mutex
+	jobs   map[string]view.RespQueryJobResult
+	cancel map[string]bool
+}
+
+func (m *memQueryJobStore) save(job view.RespQueryJobResult, _ time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs[job.Id] = job
+	return nil
+}
+
+func (m *memQueryJobStore) load(id string) (view.RespQueryJobResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	if !ok {
+		return job, ErrQueryJobNotFound
+	}
+	return job, nil
+}
+
+func (m *memQueryJobStore) list(uid int) (res []view.RespQueryJob, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, job := range m.jobs {
+		if job.Uid == uid {
+			res = append(res, job.RespQueryJob)
+		}
+	}
+	return res, nil
+}
+
+func (m *memQueryJobStore) requestCancel(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cancel[id] = true
+	return nil
+}
+
+func (m *memQueryJobStore) cancelRequested(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cancel[id]
+}
+
+func Test_queryJobsShared(t *testing.T) {
+	store := &memQueryJobStore{jobs: make(map[string]view.RespQueryJobResult), cancel: make(map[string]bool)}
+	owner, other := NewQueryJobs(), NewQueryJobs()
+	owner.shared, other.shared = store, store
+	op := &fakeJobOperator{killed: make(chan string, 1)}
+	ins := db.BaseInstance{QueryMaxConcurrency: 1}
+	ins.ID = 1
+	block := func(op factory.Operator) (interface{}, error) { return op.DoSQL("block") }
+
+	job, err := owner.submit(view.RespQueryJob{Uid: 1, Iid: 1}, ins, op, block)
+	if err != nil {
+		t.Fatalf("submit() error = %v", err)
+	}
+	if res, errInfo := other.Info(job.Id); errInfo != nil || res.Status != view.QueryJobRunning {
+		t.Errorf("Info() of the other copy = %+v, %v, want a running job", res, errInfo)
+	}
+	if _, err = other.submit(view.RespQueryJob{Uid: 1, Iid: 1}, ins, op, block); !errors.Is(err, ErrQueryJobLimit) {
+		t.Errorf("submit() of the other copy error = %v, want %v", err, ErrQueryJobLimit)
+	}
+	waitQueryJob(t, owner, job.Id, view.QueryJobRunning)
+	owner.sync()
+	if res, _ := other.Result(job.Id); res.ReadRows != 30 || len(res.Partial) != 1 {
+		t.Errorf("Result() of the other copy = %+v, want the synced progress", res)
+	}
+
+	// the cancellation requested by the other copy stops the job at the next sync of the owner
+	if err = store.requestCancel(job.Id); err != nil {
+		t.Fatalf("requestCancel() error = %v", err)
+	}
+	owner.sync()
+	waitQueryJob(t, owner, job.Id, view.QueryJobCancelled)
+	owner.sync()
+	if res, _ := other.Info(job.Id); res.Status != view.QueryJobCancelled {
+		t.Errorf("Info() of the other copy = %s, want %s", res.Status, view.QueryJobCancelled)
+	}
+	if res, errCancel := other.Cancel(job.Id); errCancel != nil || res.Status != view.QueryJobCancelled {
+		t.Errorf("Cancel() of a finished job = %+v, %v", res, errCancel)
+	}
+	if got := other.List(1); len(got) != 1 || got[0].Id != job.Id {
+		t.Errorf("List() of the other copy = %+v, want the job of the owner", got)
+	}
+	if _, err = other.Info("missing"); !errors.Is(err, ErrQueryJobNotFound) {
+		t.Errorf("Info() error = %v, want %v", err, ErrQueryJobNotFound)
+	}
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+const (
+	readerImportTickInterval = 30 * time.Second
+	// readerImportLimit is the number of the files imported by a table each time
+	readerImportLimit = 20
+)
+
+// readerImporter imports the archived files of the tables read by db.ReaderTypeImport
+// once their import interval is over.
+type readerImporter struct {
+	mu       sync.Mutex
+	stopChan chan struct{}
+	// lastRuns keeps the last import of the tables by their id
+	lastRuns map[int]time.Time
+}
+
+func NewReaderImporter() *readerImporter {
+	return &readerImporter{lastRuns: make(map[int]time.Time)}
+}
+
+func (r *readerImporter) tickerImport() {
+	r.mu.Lock()
+	r.stopChan = make(chan struct{})
+	stopChan := r.stopChan
+	r.mu.Unlock()
+	ticker := time.NewTicker(readerImportTickInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("readerImporter", "import", r.importAll(now))
+		}
+	}
+}
+
+func (r *readerImporter) stop() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.stopChan != nil {
+		close(r.stopChan)
+		r.stopChan = nil
+	}
+}
+
+func (r *readerImporter) importAll(now time.Time) error {
+	tables, err := db.TableList(invoker.Db, map[string]interface{}{"reader_type": db.ReaderTypeImport})
+	if err != nil {
+		return err
+	}
+	runs := make(map[int]time.Time, len(tables))
+	for _, table := range tables {
+		last, ok := r.lastRuns[table.ID]
+		if ok && table.ReaderSettings.Import != nil &&
+			now.Sub(last) < time.Duration(table.ReaderSettings.Import.GetInterval())*time.Second {
+			runs[table.ID] = last
+			continue
+		}
+		runs[table.ID] = now
+		files, errImport := importTable(table)
+		if errImport != nil {
+			elog.Error("readerImporter", l.I("tid", table.ID), l.E(errImport))
+			continue
+		}
+		if len(files) > 0 {
+			elog.Info("readerImporter", l.I("tid", table.ID), l.I("files", len(files)))
+		}
+	}
+	// the deleted tables and the ones read by other readers are dropped
+	r.lastRuns = runs
+	return nil
+}
+
+func importTable(table *db.BaseTable) ([]string, error) {
+	if table.Database == nil {
+		return nil, errors.New("database of the table is not found")
+	}
+	op, err := InstanceManager.Load(table.Database.Iid)
+	if err != nil {
+		return nil, err
+	}
+	importer, ok := factory.Unwrap(op).(factory.ReaderImporter)
+	if !ok {
+		return nil, errors.New("datasource does not support the import reader")
+	}
+	return importer.ImportReader(table, readerImportLimit)
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/gotomicro/cetus/pkg/xgo"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+// NewPermalink returns the code of the permalink of a saved search.
+func NewPermalink() string {
+	return strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// SavedSearchVisible tells whether the user can open the saved search, a shared search requires the
+// permission to view its table besides its scope.
+func SavedSearchVisible(uid int, search *db.Collect) bool {
+	if search.Uid == uid {
+		return true
+	}
+	switch search.Scope {
+	case db.CollectScopeTeam:
+		if !search.IsMember(uid) {
+			return false
+		}
+	case db.CollectScopeTable:
+	default:
+		return false
+	}
+	tableInfo, err := db.TableInfo(invoker.Db, search.TableId)
+	if err != nil {
+		return false
+	}
+	return TableViewIsPermission(uid, tableInfo.Database.Iid, tableInfo.ID)
+}
+
+// SavedSearchURL returns the page of the saved search with all its fields, a relative time range ends at now.
+func SavedSearchURL(search *db.Collect, now time.Time) string {
+	st, et := search.TimeRange(now)
+	v := url.Values{}
+	v.Set("tid", strconv.Itoa(search.TableId))
+	v.Set("kw", search.Statement)
+	v.Set("start", strconv.FormatInt(st, 10))
+	v.Set("end", strconv.FormatInt(et, 10))
+	v.Set("tab", "custom")
+	v.Set("queryType", "rawLog")
+	v.Set("queryMode", strconv.Itoa(search.QueryMode))
+	if search.Sort != "" {
+		v.Set("sort", search.Sort)
+	}
+	for _, field := range search.Fields {
+		v.Add("fields", field)
+	}
+	for _, filter := range search.Filters {
+		v.Add("filters", filter)
+	}
+	return fmt.Sprintf("%s/share?%s", strings.TrimSuffix(econf.GetString("app.rootURL"), "/"), v.Encode())
+}
+
+// RecordQueryHistory adds the log query to the history of the user in the background,
+// the queries beyond the newest db.QueryHistoryKeep are deleted.
+func RecordQueryHistory(uid int, param view.ReqQuery, cost int64, rows uint64) {
+	if uid == 0 || param.Cursor != "" || param.Page > 1 {
+		// the next pages are the same query
+		return
+	}
+	history := db.QueryHistory{
+		Uid:       uid,
+		TableId:   param.Tid,
+		Query:     param.Query,
+		Filters:   param.Filters,
+		QueryMode: param.QueryMode,
+		St:        param.ST,
+		Et:        param.ET,
+		Cost:      cost,
+		Rows:      rows,
+	}
+	xgo.Go(func() {
+		if err := db.QueryHistoryCreate(invoker.Db, &history); err != nil {
+			elog.Error("RecordQueryHistory", elog.FieldErr(err), elog.Int("uid", uid))
+			return
+		}
+		if err := db.QueryHistoryTrim(invoker.Db, uid, db.QueryHistoryKeep); err != nil {
+			elog.Error("RecordQueryHistory", elog.FieldErr(err), elog.Int("uid", uid))
+		}
+	})
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+)
+
+func TestSavedSearchVisible(t *testing.T) {
+	tests := []struct {
+		name   string
+		search db.Collect
+		want   bool
+	}{
+		{name: "creator", search: db.Collect{Uid: 1, Scope: db.CollectScopePrivate}, want: true},
+		{name: "private", search: db.Collect{Uid: 2, Scope: db.CollectScopePrivate}, want: false},
+		{name: "team without the user", search: db.Collect{Uid: 2, Scope: db.CollectScopeTeam, Members: db.Ints{3}}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SavedSearchVisible(1, &tt.search); got != tt.want {
+				t.Errorf("SavedSearchVisible() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSavedSearchURL(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	search := db.Collect{TableId: 5, Statement: "level = 'error'", Relative: 900, QueryMode: 1, Sort: "asc",
+		Fields: db.Strings{"msg", "level"}, Filters: db.Strings{"app = 'api'"}}
+	u, err := url.Parse(SavedSearchURL(&search, now))
+	if err != nil {
+		t.Fatalf("SavedSearchURL() error = %v", err)
+	}
+	q := u.Query()
+	if u.Path != "/share" || q.Get("tid") != "5" || q.Get("kw") != search.Statement || q.Get("tab") != "custom" {
+		t.Errorf("SavedSearchURL() = %s", u)
+	}
+	if q.Get("queryMode") != "1" || q.Get("sort") != "asc" || !reflect.DeepEqual(q["fields"], []string{"msg", "level"}) ||
+		!reflect.DeepEqual(q["filters"], []string{"app = 'api'"}) {
+		t.Errorf("SavedSearchURL() search = %v", q)
+	}
+	if q.Get("start") != "1699999100" || q.Get("end") != "1700000000" {
+		t.Errorf("SavedSearchURL() time range = [%s, %s]", q.Get("start"), q.Get("end"))
+	}
+}
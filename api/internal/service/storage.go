@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/ego-component/egorm"
+	"github.com/pkg/errors"
 	"go.uber.org/multierr"
 
 	"github.com/clickvisual/clickvisual/api/internal/invoker"
@@ -12,6 +13,7 @@ import (
 	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
 	db2 "github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
 	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
 	"github.com/clickvisual/clickvisual/api/internal/service/storage"
 	"github.com/clickvisual/clickvisual/api/internal/service/storage/storageworker"
 )
@@ -151,6 +153,65 @@ func (s *srvStorage) CreateByILogtailTemplate(uid int, databaseInfo db2.BaseData
 	return
 }
 
+// CreateByOTLPTemplate creates the logs table and the traces table of the OTLP receiver.
+func (s *srvStorage) CreateByOTLPTemplate(uid int, databaseInfo db2.BaseDatabase, param view.ReqCreateStorageByTemplateOTLP) (err error) {
+	if err = s.createOTLPTable(uid, databaseInfo, param, constx.TableCreateTypeOTLPLogs, param.Name+"_logs"); err != nil {
+		return fmt.Errorf("create otlp logs table error: %w", err)
+	}
+	if err = s.createOTLPTable(uid, databaseInfo, param, constx.TableCreateTypeOTLPTraces, param.Name+"_traces"); err != nil {
+		return fmt.Errorf("create otlp traces table error: %w", err)
+	}
+	return
+}
+
+func (s *srvStorage) createOTLPTable(uid int, databaseInfo db2.BaseDatabase, param view.ReqCreateStorageByTemplateOTLP, createType int, name string) (err error) {
+	op, err := InstanceManager.Load(databaseInfo.Iid)
+	if err != nil {
+		return err
+	}
+	creator, ok := factory.Unwrap(op).(factory.OTLPCreator)
+	if !ok {
+		return errors.New("OTLP tables are not supported by the datasource")
+	}
+	conds := egorm.Conds{}
+	conds["did"] = databaseInfo.ID
+	conds["name"] = name
+	if tableInfo, _ := db2.TableInfoX(invoker.Db, conds); tableInfo.ID != 0 {
+		return errors.New("table is repeat")
+	}
+	names, sqls, err := creator.CreateOTLPTable(createType, databaseInfo.Name, databaseInfo.Cluster, name, param.Days)
+	if err != nil {
+		return err
+	}
+	tx := invoker.Db.Begin()
+	tableInfo := db2.BaseTable{
+		Did:        databaseInfo.ID,
+		Name:       name,
+		Days:       param.Days,
+		Desc:       param.Desc,
+		CreateType: createType,
+		Uid:        uid,
+		TimeField:  db2.TimeFieldSecond,
+	}
+	if err = db2.TableCreate(tx, &tableInfo); err != nil {
+		tx.Rollback()
+		return err
+	}
+	tableAttach := db2.BaseTableAttach{Tid: tableInfo.ID, SQLs: sqls, Names: names}
+	if err = tableAttach.Create(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, index := range otlpIndexes(createType) {
+		index.Tid = tableInfo.ID
+		if err = db2.IndexCreate(tx, &index); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit().Error
+}
+
 func (s *srvStorage) CreateByEgoTemplate(uid int, databaseInfo db2.BaseDatabase, param view.ReqCreateStorageByTemplateEgo) (err error) {
 	err = s.createByEgoAppStdout(uid, databaseInfo, param)
 	if err != nil {
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"strconv"
 
+	"github.com/gotomicro/ego/core/econf"
 	"github.com/gotomicro/ego/core/elog"
 	"github.com/pkg/errors"
 
@@ -25,6 +26,35 @@ func TableViewIsPermission(uid, iid, tid int) bool {
 	return false
 }
 
+// CheckQueryMode requires the raw SQL permission for raw SQL conditions, the query
+// language is compiled against the table indexes and needs the log permission only.
+func CheckQueryMode(uid, queryMode int, tableInfo db.BaseTable) error {
+	if queryMode == view.QueryModeLanguage {
+		return nil
+	}
+	return CheckRawSQL(uid, tableInfo.Database.Iid, tableInfo.ID)
+}
+
+// CheckRawSQL requires the raw SQL permission of the table, or of the instance when tid is 0.
+// With app.rawSQLPermission = false, raw SQL only needs the log permission as before the query language.
+func CheckRawSQL(uid, iid, tid int) error {
+	if econf.Get("app.rawSQLPermission") != nil && !econf.GetBool("app.rawSQLPermission") {
+		return nil
+	}
+	req := view.ReqPermission{
+		UserId:      uid,
+		ObjectType:  pmsplugin.PrefixInstance,
+		ObjectIdx:   strconv.Itoa(iid),
+		SubResource: pmsplugin.RawSQL,
+		Acts:        []string{pmsplugin.ActView},
+	}
+	if tid != 0 {
+		req.DomainType = pmsplugin.PrefixTable
+		req.DomainId = strconv.Itoa(tid)
+	}
+	return permission.Manager.CheckNormalPermission(req)
+}
+
 func tableViewIsPermission(uid, iid, tid int, subResource string) bool {
 	// check database permission
 	if err := permission.Manager.CheckNormalPermission(view.ReqPermission{
@@ -104,6 +134,8 @@ func StorageCreate(uid int, databaseInfo db.BaseDatabase, param view.ReqStorageC
 		SelectFields:            param.SelectFields(),
 		AnyJSON:                 param.JSON(),
 		KafkaSkipBrokenMessages: param.KafkaSkipBrokenMessages,
+		ReaderType:              param.GetReaderType(),
+		ReaderSettings:          param.ReaderSettings,
 	}
 	tx := invoker.Db.Begin()
 	err = db.TableCreate(tx, &tableInfo)
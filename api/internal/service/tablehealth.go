@@ -0,0 +1,363 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ego-component/egorm"
+	"github.com/gotomicro/cetus/l"
+	"github.com/gotomicro/ego/core/econf"
+	"github.com/gotomicro/ego/core/elog"
+	"github.com/pkg/errors"
+	"github.com/spf13/cast"
+
+	"github.com/clickvisual/clickvisual/api/internal/invoker"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/component/core"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/constx"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+	"github.com/clickvisual/clickvisual/api/internal/service/alarm/pusher"
+	"github.com/clickvisual/clickvisual/api/internal/service/event"
+	"github.com/clickvisual/clickvisual/api/internal/service/inquiry/factory"
+)
+
+// ErrTableHealthUnsupported is returned for the tables without readers and the datasources without the metrics.
+var ErrTableHealthUnsupported = errors.New("the ingestion health of the table is not supported")
+
+type tableHealthConfig struct {
+	enable       bool
+	interval     time.Duration // between the collections
+	stalledAfter time.Duration // without polls of the consumers, or without inserts while the lag is positive
+	lagThreshold int64         // the tables with a larger lag are degraded, 0 disables it
+	channelIds   []int         // alarm channels notified of the transitions
+}
+
+// tableHealth collects the ingestion health of the tables read by the stream tables, the transitions of their status
+// are recorded as events and sent to the alarm channels of the config.
+type tableHealth struct {
+	conf     tableHealthConfig
+	mu       sync.Mutex
+	stopChan chan struct{}
+	// read is IngestHealthReader.IngestHealth of the operator of the table, replaced by the tests
+	read func(table *db.BaseTable, since time.Time) (view.IngestHealthMetrics, error)
+}
+
+// NewTableHealth reads the ingestHealth config: enable true, interval 1m, stalledAfter 10m, lagThreshold 100000 messages
+// and no channels by default.
+func NewTableHealth() *tableHealth {
+	h := &tableHealth{
+		conf: tableHealthConfig{
+			enable:       true,
+			interval:     time.Minute,
+			stalledAfter: 10 * time.Minute,
+			lagThreshold: 100000,
+			channelIds:   cast.ToIntSlice(econf.Get("ingestHealth.channelIds")),
+		},
+		read: readTableHealth,
+	}
+	if econf.Get("ingestHealth.enable") != nil {
+		h.conf.enable = econf.GetBool("ingestHealth.enable")
+	}
+	if d := econf.GetDuration("ingestHealth.interval"); d > 0 {
+		h.conf.interval = d
+	}
+	if d := econf.GetDuration("ingestHealth.stalledAfter"); d > 0 {
+		h.conf.stalledAfter = d
+	}
+	if econf.Get("ingestHealth.lagThreshold") != nil {
+		h.conf.lagThreshold = econf.GetInt64("ingestHealth.lagThreshold")
+	}
+	return h
+}
+
+// Enabled tells whether the collector runs.
+func (h *tableHealth) Enabled() bool {
+	return h.conf.enable
+}
+
+func (h *tableHealth) tickerCollect() {
+	h.mu.Lock()
+	h.stopChan = make(chan struct{})
+	stopChan := h.stopChan
+	h.mu.Unlock()
+	ticker := time.NewTicker(h.conf.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stopChan:
+			return
+		case now := <-ticker.C:
+			core.LoggerError("tableHealth", "collect", h.collect(now))
+		}
+	}
+}
+
+func (h *tableHealth) stop() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopChan != nil {
+		close(h.stopChan)
+		h.stopChan = nil
+	}
+}
+
+// Check reads the ingestion health of the table now, the status is not saved and its transition is not notified.
+func (h *tableHealth) Check(table *db.BaseTable) (res view.RespTableHealth, err error) {
+	if !tableHealthMonitored(table) {
+		return res, ErrTableHealthUnsupported
+	}
+	now := time.Now()
+	m, err := h.read(table, now.Add(-h.conf.interval))
+	if err != nil {
+		return
+	}
+	current, err := db.TableHealthInfoX(invoker.Db, table.ID)
+	if err != nil {
+		return
+	}
+	var previous *db.TableHealth
+	if current.ID != 0 {
+		previous = &current
+	}
+	status, reason := h.evaluate(table, m, now)
+	return tableHealthView(h.record(previous, table.ID, status, reason, m, now)), nil
+}
+
+func (h *tableHealth) collect(now time.Time) error {
+	tables, err := db.TableList(invoker.Db, egorm.Conds{})
+	if err != nil {
+		return err
+	}
+	healths, err := db.TableHealthMap(invoker.Db)
+	if err != nil {
+		return err
+	}
+	for _, table := range tables {
+		if !tableHealthMonitored(table) {
+			continue
+		}
+		previous := healths[table.ID]
+		delete(healths, table.ID)
+		if errCollect := h.collectTable(table, previous, now); errCollect != nil {
+			elog.Error("tableHealth", l.I("tid", table.ID), l.E(errCollect))
+		}
+	}
+	// the deleted tables and the ones without readers
+	for tid := range healths {
+		if errDelete := db.TableHealthDelete(invoker.Db, tid); errDelete != nil {
+			elog.Error("tableHealth", l.I("tid", tid), l.E(errDelete))
+		}
+	}
+	return nil
+}
+
+// collectTable saves the health of the table, the exceptions are counted since the previous collection.
+func (h *tableHealth) collectTable(table *db.BaseTable, previous *db.TableHealth, now time.Time) error {
+	since := now.Add(-h.conf.interval)
+	if previous != nil && previous.Utime > 0 && previous.Utime < now.Unix() {
+		since = time.Unix(previous.Utime, 0)
+	}
+	m, err := h.read(table, since)
+	status, reason := db.TableHealthUnknown, ""
+	if err != nil {
+		m = view.IngestHealthMetrics{Consumers: -1, ConsumerLag: -1}
+		reason = err.Error()
+	} else {
+		status, reason = h.evaluate(table, m, now)
+	}
+	current := h.record(previous, table.ID, status, reason, m, now)
+	if err = db.TableHealthSave(invoker.Db, current); err != nil {
+		return err
+	}
+	if previous == nil && (status == db.TableHealthHealthy || status == db.TableHealthUnknown) {
+		return nil
+	}
+	if previous != nil && previous.Status == status {
+		return nil
+	}
+	h.notify(table, previous, current)
+	return nil
+}
+
+// record returns the health of the table, the status keeps its since when it does not change.
+func (h *tableHealth) record(previous *db.TableHealth, tid int, status, reason string, m view.IngestHealthMetrics, now time.Time) *db.TableHealth {
+	res := &db.TableHealth{}
+	if previous != nil {
+		res.BaseModel = previous.BaseModel
+	}
+	res.Tid = tid
+	res.Status = status
+	res.Reason = truncate(reason, 255)
+	res.Since = now.Unix()
+	if previous != nil && previous.Status == status {
+		res.Since = previous.Since
+	}
+	res.Consumers = m.Consumers
+	res.ConsumerLag = m.ConsumerLag
+	res.ConsumerExceptions = m.ConsumerExceptions
+	res.ViewExceptions = m.ViewExceptions
+	res.LastException = m.LastException
+	res.LastInsert = unixOrZero(m.LastInsert)
+	res.LastPoll = unixOrZero(m.LastPoll)
+	return res
+}
+
+// evaluate returns the status of the metrics and its reason. The Kafka tables are stalled when the consumers are gone,
+// do not poll, or the lag is positive without inserts, they are degraded by the exceptions of the consumers or
+// the views, or a lag over the threshold.
+func (h *tableHealth) evaluate(table *db.BaseTable, m view.IngestHealthMetrics, now time.Time) (status, reason string) {
+	if table.GetReaderType() == db.ReaderTypeKafka {
+		switch {
+		case m.Consumers == 0:
+			return db.TableHealthStalled, "no kafka consumers, the stream table or its view may be detached"
+		case m.Consumers > 0 && now.Sub(m.LastPoll) > h.conf.stalledAfter:
+			return db.TableHealthStalled, fmt.Sprintf("kafka consumers have not polled since %s", formatHealthTime(m.LastPoll))
+		case m.ConsumerLag > 0 && now.Sub(m.LastInsert) > h.conf.stalledAfter:
+			return db.TableHealthStalled, fmt.Sprintf("lag of %d messages without inserts since %s", m.ConsumerLag, formatHealthTime(m.LastInsert))
+		}
+	}
+	reasons := make([]string, 0)
+	if m.ConsumerExceptions > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d kafka consumer exceptions", m.ConsumerExceptions))
+	}
+	if m.ViewExceptions > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d view exceptions", m.ViewExceptions))
+	}
+	if h.conf.lagThreshold > 0 && m.ConsumerLag > h.conf.lagThreshold {
+		reasons = append(reasons, fmt.Sprintf("lag of %d messages", m.ConsumerLag))
+	}
+	if len(reasons) > 0 {
+		return db.TableHealthDegraded, strings.Join(reasons, ", ")
+	}
+	return db.TableHealthHealthy, ""
+}
+
+// notify records the transition as an event, and sends it to the alarm channels when the table turns degraded
+// or stalled, or recovers from them.
+func (h *tableHealth) notify(table *db.BaseTable, previous, current *db.TableHealth) {
+	from := ""
+	if previous != nil {
+		from = previous.Status
+	}
+	name := table.Name
+	if table.Database != nil {
+		name = table.Database.Name + "." + table.Name
+	}
+	event.Event.TableHealth(table.ID, map[string]interface{}{"table": name, "from": from, "to": current.Status, "reason": current.Reason})
+	if len(h.conf.channelIds) == 0 {
+		return
+	}
+	msg := tableHealthMsg(name, table.ID, from, current)
+	if msg == nil {
+		return
+	}
+	if err := pusher.Execute(h.conf.channelIds, msg, msg); err != nil {
+		elog.Error("tableHealth", l.I("tid", table.ID), l.E(err))
+	}
+}
+
+// tableHealthMsg returns the message of the transition, nil for the transitions between healthy and unknown.
+func tableHealthMsg(name string, tid int, from string, current *db.TableHealth) *db.PushMsg {
+	unhealthy := func(status string) bool {
+		return status == db.TableHealthDegraded || status == db.TableHealthStalled
+	}
+	msg := &db.PushMsg{
+		DedupKey: fmt.Sprintf("clickvisual-table-health-%d", tid),
+	}
+	switch {
+	case unhealthy(current.Status):
+		msg.Title = fmt.Sprintf("Table %s is %s", name, current.Status)
+		msg.Text = fmt.Sprintf("Ingestion of table %s is %s: %s", name, current.Status, current.Reason)
+		msg.Status = db.AlarmStatusFiring
+	case unhealthy(from) && current.Status == db.TableHealthHealthy:
+		msg.Title = fmt.Sprintf("Table %s is healthy", name)
+		msg.Text = fmt.Sprintf("Ingestion of table %s recovered from %s", name, from)
+		msg.Status = db.AlarmStatusNormal
+	default:
+		return nil
+	}
+	if current.LastException != "" {
+		msg.Text += "\n\n" + truncate(current.LastException, 1000)
+	}
+	return msg
+}
+
+// tableHealthMonitored tells whether the table reads its logs by a stream table.
+func tableHealthMonitored(table *db.BaseTable) bool {
+	switch table.CreateType {
+	case constx.TableCreateTypeCV, constx.TableCreateTypeUBW, constx.TableCreateTypeJSONEachRow, constx.TableCreateTypeJSONAsString:
+		return table.GetReaderType() != db.ReaderTypeImport
+	}
+	return false
+}
+
+func readTableHealth(table *db.BaseTable, since time.Time) (view.IngestHealthMetrics, error) {
+	if table.Database == nil {
+		return view.IngestHealthMetrics{}, errors.New("database of the table is not found")
+	}
+	op, err := InstanceManager.Load(table.Database.Iid)
+	if err != nil {
+		return view.IngestHealthMetrics{}, err
+	}
+	reader, ok := factory.Unwrap(op).(factory.IngestHealthReader)
+	if !ok {
+		return view.IngestHealthMetrics{}, ErrTableHealthUnsupported
+	}
+	return reader.IngestHealth(table, since)
+}
+
+func tableHealthView(m *db.TableHealth) view.RespTableHealth {
+	return view.RespTableHealth{
+		Tid:                m.Tid,
+		Status:             m.Status,
+		Reason:             m.Reason,
+		Since:              m.Since,
+		Consumers:          m.Consumers,
+		ConsumerLag:        m.ConsumerLag,
+		ConsumerExceptions: m.ConsumerExceptions,
+		ViewExceptions:     m.ViewExceptions,
+		LastException:      m.LastException,
+		LastInsert:         m.LastInsert,
+		LastPoll:           m.LastPoll,
+		Checked:            time.Now().Unix(),
+	}
+}
+
+// TableHealthStatus returns the status of the tables by their id, for the table lists.
+func TableHealthStatus() map[int]string {
+	healths, err := db.TableHealthMap(invoker.Db)
+	if err != nil {
+		elog.Error("tableHealth", l.E(err))
+		return map[int]string{}
+	}
+	res := make(map[int]string, len(healths))
+	for tid, m := range healths {
+		res[tid] = m.Status
+	}
+	return res
+}
+
+func formatHealthTime(t time.Time) string {
+	if t.IsZero() {
+		return "never"
+	}
+	return t.Format("2006-01-02 15:04:05")
+}
+
+func unixOrZero(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+// truncate keeps the first n runes of s.
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
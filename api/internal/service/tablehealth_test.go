@@ -0,0 +1,119 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/db"
+	"github.com/clickvisual/clickvisual/api/internal/pkg/model/view"
+)
+
+func TestTableHealthEvaluate(t *testing.T) {
+	h := &tableHealth{conf: tableHealthConfig{stalledAfter: 10 * time.Minute, lagThreshold: 1000}}
+	now := time.Unix(1700000000, 0)
+	recent := now.Add(-time.Minute)
+	old := now.Add(-time.Hour)
+	kafka := &db.BaseTable{}
+	nats := &db.BaseTable{ReaderType: db.ReaderTypeNATS}
+	tests := []struct {
+		name  string
+		table *db.BaseTable
+		m     view.IngestHealthMetrics
+		want  string
+	}{
+		{
+			name:  "healthy",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 10, LastPoll: recent, LastInsert: recent},
+			want:  db.TableHealthHealthy,
+		},
+		{
+			name:  "idle",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 0, LastPoll: recent, LastInsert: old},
+			want:  db.TableHealthHealthy,
+		},
+		{
+			name:  "no consumers",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 0, ConsumerLag: -1, LastInsert: recent},
+			want:  db.TableHealthStalled,
+		},
+		{
+			name:  "no polls",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 2, ConsumerLag: -1, LastPoll: old, LastInsert: recent},
+			want:  db.TableHealthStalled,
+		},
+		{
+			name:  "lag without inserts",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 5, LastPoll: recent, LastInsert: old},
+			want:  db.TableHealthStalled,
+		},
+		{
+			name:  "consumer exceptions",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 0, ConsumerExceptions: 3, LastPoll: recent, LastInsert: recent},
+			want:  db.TableHealthDegraded,
+		},
+		{
+			name:  "lag over threshold",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 5000, LastPoll: recent, LastInsert: recent},
+			want:  db.TableHealthDegraded,
+		},
+		{
+			name:  "consumers unknown",
+			table: kafka,
+			m:     view.IngestHealthMetrics{Consumers: -1, ConsumerLag: -1},
+			want:  db.TableHealthHealthy,
+		},
+		{
+			name:  "view exceptions of other readers",
+			table: nats,
+			m:     view.IngestHealthMetrics{Consumers: -1, ConsumerLag: -1, ViewExceptions: 1},
+			want:  db.TableHealthDegraded,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := h.evaluate(tt.table, tt.m, now)
+			if got != tt.want {
+				t.Errorf("evaluate() = %s (%s), want %s", got, reason, tt.want)
+			}
+			if (got == db.TableHealthHealthy) != (reason == "") {
+				t.Errorf("evaluate() reason = %q of %s", reason, got)
+			}
+		})
+	}
+}
+
+func TestTableHealthRecord(t *testing.T) {
+	h := &tableHealth{}
+	now := time.Unix(1700000000, 0)
+	previous := &db.TableHealth{BaseModel: db.BaseModel{ID: 3}, Tid: 1, Status: db.TableHealthStalled, Since: 1600000000}
+	m := view.IngestHealthMetrics{Consumers: 1, ConsumerLag: 2, LastPoll: now}
+	if got := h.record(previous, 1, db.TableHealthStalled, "lag", m, now); got.ID != 3 || got.Since != 1600000000 || got.LastPoll != now.Unix() {
+		t.Errorf("record() of the same status = %+v", got)
+	}
+	if got := h.record(previous, 1, db.TableHealthHealthy, "", m, now); got.Since != now.Unix() || got.LastInsert != 0 {
+		t.Errorf("record() of a transition = %+v", got)
+	}
+}
+
+func TestTableHealthMsg(t *testing.T) {
+	stalled := &db.TableHealth{Status: db.TableHealthStalled, Reason: "no kafka consumers"}
+	msg := tableHealthMsg("logs.app", 1, db.TableHealthHealthy, stalled)
+	if msg == nil || msg.Status != db.AlarmStatusFiring || msg.DedupKey != "clickvisual-table-health-1" {
+		t.Fatalf("tableHealthMsg() of stalled = %+v", msg)
+	}
+	healthy := &db.TableHealth{Status: db.TableHealthHealthy}
+	msg = tableHealthMsg("logs.app", 1, db.TableHealthStalled, healthy)
+	if msg == nil || msg.Status != db.AlarmStatusNormal || msg.DedupKey != "clickvisual-table-health-1" {
+		t.Fatalf("tableHealthMsg() of recovery = %+v", msg)
+	}
+	if msg = tableHealthMsg("logs.app", 1, db.TableHealthUnknown, healthy); msg != nil {
+		t.Errorf("tableHealthMsg() from unknown = %+v", msg)
+	}
+}
@@ -21,6 +21,11 @@ CREATE TABLE IF NOT EXISTS `cv_alarm` (
     `status` int DEFAULT NULL,
     `duty_officers` varchar(255) DEFAULT NULL,
     `is_disable_resolve` tinyint(1) DEFAULT NULL,
+    `escalation_id` int DEFAULT NULL,
+    `group_by` varchar(255) DEFAULT NULL,
+    `group_wait` int DEFAULT NULL,
+    `group_interval` int DEFAULT NULL,
+    `repeat_interval` int DEFAULT NULL,
     `view_ddl_s` text,
     `table_ids` varchar(255) NOT NULL DEFAULT '',
     `alert_rules` text,
@@ -40,6 +45,7 @@ CREATE TABLE IF NOT EXISTS `cv_alarm_channel` (
     `key` text,
     `typ` int DEFAULT NULL,
     `uid` int DEFAULT NULL,
+    `template` text,
     PRIMARY KEY (`id`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
 -- test.cv_alarm_condition definition
@@ -55,6 +61,8 @@ CREATE TABLE IF NOT EXISTS `cv_alarm_condition` (
     `cond` int DEFAULT NULL,
     `val_1` int DEFAULT NULL,
     `val_2` int DEFAULT NULL,
+    `baseline` int NOT NULL DEFAULT 0 COMMENT 'baseline window seconds of anomaly conditions',
+    `field` varchar(128) NOT NULL DEFAULT '' COMMENT 'signature field of new signature conditions',
     PRIMARY KEY (`id`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
 -- test.cv_alarm_filter definition
@@ -82,8 +90,95 @@ CREATE TABLE IF NOT EXISTS `cv_alarm_history` (
     `filter_id` int DEFAULT NULL,
     `filter_status` int DEFAULT NULL,
     `is_pushed` int DEFAULT NULL,
+    `silence_id` int DEFAULT NULL,
+    `escalation_step` int DEFAULT NULL,
+    `ack_uid` int DEFAULT NULL,
+    `ack_time` bigint DEFAULT NULL,
     PRIMARY KEY (`id`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_alarm_silence definition
+CREATE TABLE IF NOT EXISTS `cv_alarm_silence` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `uid` int DEFAULT NULL,
+    `alarm_id` int DEFAULT NULL,
+    `matchers` text,
+    `start_time` bigint DEFAULT NULL,
+    `end_time` bigint DEFAULT NULL,
+    `cron` varchar(128) DEFAULT NULL,
+    `duration` bigint DEFAULT NULL,
+    `reason` varchar(255) NOT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_alarm_id` (`alarm_id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_alarm_oncall definition
+CREATE TABLE IF NOT EXISTS `cv_alarm_oncall` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `uid` int DEFAULT NULL,
+    `name` varchar(128) NOT NULL,
+    `desc` varchar(255) NOT NULL,
+    `users` text,
+    `handoff_time` bigint DEFAULT NULL,
+    `period` bigint DEFAULT NULL,
+    `overrides` text,
+    PRIMARY KEY (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_alarm_escalation definition
+CREATE TABLE IF NOT EXISTS `cv_alarm_escalation` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `uid` int DEFAULT NULL,
+    `name` varchar(128) NOT NULL,
+    `desc` varchar(255) NOT NULL,
+    `steps` text,
+    PRIMARY KEY (`id`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_alarm_delivery definition
+CREATE TABLE IF NOT EXISTS `cv_alarm_delivery` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `history_id` int DEFAULT NULL,
+    `history_ids` text,
+    `alarm_id` int DEFAULT NULL,
+    `channel_id` int DEFAULT NULL,
+    `msg` longtext,
+    `status` int DEFAULT NULL,
+    `attempts` int DEFAULT NULL,
+    `next_time` bigint DEFAULT NULL,
+    `last_error` text,
+    `history` longtext,
+    PRIMARY KEY (`id`),
+    KEY `idx_cv_alarm_delivery_history_id` (`history_id`),
+    KEY `idx_cv_alarm_delivery_alarm_id` (`alarm_id`),
+    KEY `idx_cv_alarm_delivery_status` (`status`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_alarm_group definition
+CREATE TABLE IF NOT EXISTS `cv_alarm_group` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `group_key` varchar(64) NOT NULL,
+    `labels` text,
+    `group_wait` bigint DEFAULT NULL,
+    `group_interval` bigint DEFAULT NULL,
+    `repeat_interval` bigint DEFAULT NULL,
+    `next_flush` bigint DEFAULT NULL,
+    `last_flush` bigint DEFAULT NULL,
+    `members` longtext,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `idx_cv_alarm_group_group_key` (`group_key`),
+    KEY `idx_cv_alarm_group_next_flush` (`next_flush`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
 -- test.cv_base_database definition
 CREATE TABLE IF NOT EXISTS `cv_base_database` (
     `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
@@ -146,6 +241,8 @@ CREATE TABLE IF NOT EXISTS `cv_base_instance` (
     `namespace` varchar(128) DEFAULT NULL,
     `configmap` varchar(128) DEFAULT NULL,
     `config_prometheus_operator` text,
+    `query_max_concurrency` int DEFAULT NULL,
+    `query_max_execution_time` int DEFAULT NULL,
     PRIMARY KEY (`id`),
     UNIQUE KEY `idx_datasource_name` (`datasource`, `name`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
@@ -181,6 +278,8 @@ CREATE TABLE IF NOT EXISTS `cv_base_table` (
     `time_field` varchar(128) NOT NULL DEFAULT '',
     `raw_log_field` varchar(255) DEFAULT NULL,
     `kafka_skip_broken_messages` int DEFAULT NULL,
+    `reader_type` varchar(32) NOT NULL DEFAULT '',
+    `reader_settings` text,
     `is_kafka_timestamp` tinyint(1) DEFAULT NULL,
     `v3_table_type` int DEFAULT NULL,
     `select_fields` text,
@@ -367,7 +466,83 @@ CREATE TABLE IF NOT EXISTS `cv_collect` (
     `alias` varchar(255) NOT NULL DEFAULT '',
     `statement` text,
     `collect_type` bigint DEFAULT NULL,
-    PRIMARY KEY (`id`)
+    `query_mode` bigint DEFAULT NULL,
+    `relative` bigint DEFAULT NULL,
+    `st` bigint DEFAULT NULL,
+    `et` bigint DEFAULT NULL,
+    `fields` text,
+    `filters` text,
+    `sort` varchar(8) NOT NULL DEFAULT '',
+    `scope` bigint NOT NULL DEFAULT '0',
+    `members` text,
+    `permalink` varchar(64) NOT NULL DEFAULT '',
+    PRIMARY KEY (`id`),
+    KEY `idx_cv_collect_permalink` (`permalink`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_query_history definition
+CREATE TABLE IF NOT EXISTS `cv_query_history` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `uid` int DEFAULT NULL,
+    `table_id` int DEFAULT NULL,
+    `query` text,
+    `filters` text,
+    `query_mode` bigint DEFAULT NULL,
+    `st` bigint DEFAULT NULL,
+    `et` bigint DEFAULT NULL,
+    `cost` bigint DEFAULT NULL,
+    `row_count` bigint DEFAULT NULL,
+    PRIMARY KEY (`id`),
+    KEY `idx_cv_query_history_uid` (`uid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_ingest_token definition
+CREATE TABLE IF NOT EXISTS `cv_ingest_token` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `tid` int DEFAULT NULL,
+    `uid` int DEFAULT NULL,
+    `name` varchar(128) DEFAULT NULL,
+    `token` char(64) NOT NULL COMMENT 'sha256 of the token',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uix_cv_ingest_token_token` (`token`),
+    KEY `idx_cv_ingest_token_tid` (`tid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_api_token definition
+CREATE TABLE IF NOT EXISTS `cv_api_token` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `uid` int DEFAULT NULL,
+    `name` varchar(128) DEFAULT NULL,
+    `token` char(64) NOT NULL COMMENT 'sha256 of the token',
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uix_cv_api_token_token` (`token`),
+    KEY `idx_cv_api_token_uid` (`uid`)
+) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
+-- test.cv_table_health definition
+CREATE TABLE IF NOT EXISTS `cv_table_health` (
+    `id` bigint NOT NULL AUTO_INCREMENT COMMENT '自增id',
+    `ctime` bigint DEFAULT NULL COMMENT '创建时间',
+    `utime` bigint DEFAULT NULL COMMENT '更新时间',
+    `dtime` bigint unsigned DEFAULT NULL COMMENT '删除时间',
+    `tid` int DEFAULT NULL,
+    `status` varchar(16) NOT NULL DEFAULT '' COMMENT 'healthy, degraded, stalled or unknown',
+    `reason` varchar(255) NOT NULL DEFAULT '',
+    `since` bigint NOT NULL DEFAULT 0 COMMENT 'unix time the status started',
+    `consumers` int NOT NULL DEFAULT 0,
+    `consumer_lag` bigint NOT NULL DEFAULT 0,
+    `consumer_exceptions` bigint NOT NULL DEFAULT 0 COMMENT 'recent exceptions of the kafka consumers, at most the last 10 per consumer',
+    `view_exceptions` bigint NOT NULL DEFAULT 0,
+    `last_exception` text,
+    `last_insert` bigint NOT NULL DEFAULT 0,
+    `last_poll` bigint NOT NULL DEFAULT 0,
+    PRIMARY KEY (`id`),
+    UNIQUE KEY `uix_cv_table_health_tid` (`tid`)
 ) ENGINE = InnoDB DEFAULT CHARSET = utf8mb4 COLLATE = utf8mb4_general_ci;
 -- test.cv_configuration definition
 CREATE TABLE IF NOT EXISTS `cv_configuration` (
@@ -168,6 +168,9 @@ export default {
   "instance.form.placeholder.clusterName": "Please enter a cluster name",
   "instance.form.placeholder.dsn": "Please enter DSN, for example: {example}",
   "instance.form.placeholder.filePath": "Please enter the file path",
+  "instance.form.title.queryMaxConcurrency": "Query jobs per user",
+  "instance.form.title.queryMaxExecutionTime": "Query job timeout (s)",
+  "instance.form.placeholder.queryLimit": "0 is unlimited",
   "instance.form.moreOptions": "More Options",
   "instance.form.rule.dsn": "Please enter DSN",
   "instance.form.rule.configmap": "Please select ConfigMap",
@@ -320,6 +323,11 @@ export default {
   "datasource.deleted.success": "Delete database: {database} succeeded",
 
   "datasource.logLibrary.from.tableName": "Table Name",
+  "datasource.logLibrary.health": "Ingestion",
+  "datasource.logLibrary.health.healthy": "Healthy",
+  "datasource.logLibrary.health.degraded": "Degraded",
+  "datasource.logLibrary.health.stalled": "Stalled",
+  "datasource.logLibrary.health.unknown": "Unknown",
   "datasource.logLibrary.from.rule.tableName":
     "Please enter lowercase letters, uppercase letters, or underscores",
   "datasource.logLibrary.from.type": "_time_ Field Type",
@@ -619,6 +627,8 @@ export default {
   "alarm.rules.form.inspectionStatistics.error":
     "At least one table needs to be associated",
   "alarm.rules.form.triggerCondition": "Trigger condition",
+  "alarm.rules.form.baseline": "Baseline (seconds)",
+  "alarm.rules.form.signatureField": "Signature field",
   "alarm.rules.form.triggerCondition.error":
     "At least you need to add a trigger condition",
   "alarm.rules.form.noDataOp": "Alert state if no data or all values are null",
@@ -666,6 +676,7 @@ export default {
   "alarm.rules.history.isPushed.true": "Yes",
   "alarm.rules.history.isPushed.false": "No",
   "alarm.rules.history.isPushed.zero": "Repeat alarm - Not pushed",
+  "alarm.rules.history.isPushed.retrying": "Push failed - Retrying",
   "alarm.rules.history.title.total": "The total number of alarm",
   "alarm.rules.history.title.sucPublish": "Times of successful push",
 
@@ -713,6 +724,7 @@ export default {
   "themeLayout.alarm.environment.RuleStoreType.notOpen": "Did not open",
   "themeLayout.alarm.environment.form.notOpen": "Did not open",
   "themeLayout.alarm.environment.RuleStoreType.file": "File",
+  "themeLayout.alarm.environment.RuleStoreType.native": "Native",
   "themeLayout.alarm.environment.form.title": "Editing the Alarm Environment",
   "themeLayout.alarm.environment.form.ruleStoreType": "The alarm types",
   "themeLayout.alarm.environment.form.isPrometheusOK": "Prometheus state",
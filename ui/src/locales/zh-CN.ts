@@ -167,6 +167,9 @@ export default {
   "instance.form.placeholder.clusterName": "请输入集群名称",
   "instance.form.placeholder.dsn": "请输入数据源连接串，例如：{example}",
   "instance.form.placeholder.filePath": "请输入文件路径",
+  "instance.form.title.queryMaxConcurrency": "每用户查询任务数",
+  "instance.form.title.queryMaxExecutionTime": "查询任务超时（秒）",
+  "instance.form.placeholder.queryLimit": "0 表示不限制",
   "instance.form.moreOptions": "更多设置",
   "instance.form.rule.dsn": "请输入数据源连接串",
   "instance.form.rule.configmap": "请选择 ConfigMap",
@@ -312,6 +315,11 @@ export default {
   "datasource.deleted.success": "删除数据库：{database} 成功",
 
   "datasource.logLibrary.from.tableName": "数据表名称",
+  "datasource.logLibrary.health": "写入状态",
+  "datasource.logLibrary.health.healthy": "正常",
+  "datasource.logLibrary.health.degraded": "异常",
+  "datasource.logLibrary.health.stalled": "停滞",
+  "datasource.logLibrary.health.unknown": "未知",
   "datasource.logLibrary.from.rule.tableName":
     "请输入小写字母、大写字母或下划线",
   "datasource.logLibrary.from.type": "时间字段类型",
@@ -586,6 +594,8 @@ export default {
   "alarm.rules.form.addTable": "新增关联的表",
   "alarm.rules.form.inspectionStatistics.error": "最少需要关联一个表",
   "alarm.rules.form.triggerCondition": "触发条件",
+  "alarm.rules.form.baseline": "基线(秒)",
+  "alarm.rules.form.signatureField": "签名字段",
   "alarm.rules.form.triggerCondition.error": "最少需要添加一条触发条件",
   "alarm.rules.form.noDataOp": "空数据处理策略",
   "alarm.rules.form.preview": "预览",
@@ -632,6 +642,7 @@ export default {
   "alarm.rules.history.isPushed.true": "是",
   "alarm.rules.history.isPushed.false": "否",
   "alarm.rules.history.isPushed.zero": "重复报警-未推送",
+  "alarm.rules.history.isPushed.retrying": "推送失败-重试中",
   "alarm.rules.history.title.total": "总报警数",
   "alarm.rules.history.title.sucPublish": "成功推送次数",
 
@@ -674,6 +685,7 @@ export default {
   "themeLayout.alarm.environment.RuleStoreType.notOpen": "未开启",
   "themeLayout.alarm.environment.form.notOpen": "不开启",
   "themeLayout.alarm.environment.RuleStoreType.file": "文件",
+  "themeLayout.alarm.environment.RuleStoreType.native": "内置",
   "themeLayout.alarm.environment.form.title": "编辑告警环境",
   "themeLayout.alarm.environment.form.ruleStoreType": "告警类型",
   "themeLayout.alarm.environment.form.isPrometheusOK": "Prometheus 检测",
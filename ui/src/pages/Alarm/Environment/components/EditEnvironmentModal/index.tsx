@@ -170,6 +170,12 @@ const EditEnvironmentModal = (props: EditEnvironmentModalProps) => {
                 label: "operator",
                 value: RuleStoreType.operator,
               },
+              {
+                label: i18n.formatMessage({
+                  id: "themeLayout.alarm.environment.RuleStoreType.native",
+                }),
+                value: RuleStoreType.native,
+              },
             ]}
           />
         </Form.Item>
@@ -298,6 +304,7 @@ const EditEnvironmentModal = (props: EditEnvironmentModalProps) => {
               [RuleStoreType.k8s]: otherType(RuleStoreType.k8s),
               [RuleStoreType.file]: FileType,
               [RuleStoreType.operator]: otherType(RuleStoreType.operator),
+              [RuleStoreType.native]: <></>,
             };
             return ruleStoreTypeDom[ruleStoreType] || <></>;
           }}
@@ -26,6 +26,7 @@ export enum RuleStoreType {
   file = 1,
   k8s = 2,
   operator = 3,
+  native = 4,
 }
 
 const Environment = () => {
@@ -43,6 +43,14 @@ const HistoryTable = ({ dataList, loadList }: HistoryTableProps) => {
                 })}
               </span>
             );
+          case 4:
+            return (
+              <span>
+                {i18n.formatMessage({
+                  id: "alarm.rules.history.isPushed.retrying",
+                })}
+              </span>
+            );
           default:
             return (
               <span>
@@ -1,9 +1,15 @@
 import conditionStyles from "@/pages/Alarm/Rules/components/FormAlarmDraw/TriggerConditionItem/index.less";
-import { Button, Form, InputNumber, Select, Space } from "antd";
+import { Button, Form, Input, InputNumber, Select, Space } from "antd";
 import { useIntl } from "umi";
 import classNames from "classnames";
 import { PlusOutlined } from "@ant-design/icons";
-import { condList, expList, typList } from "@/pages/Alarm/service/type";
+import {
+  anomalyCondList,
+  condList,
+  expList,
+  newSignatureCond,
+  typList,
+} from "@/pages/Alarm/service/type";
 
 const { Option } = Select;
 
@@ -109,14 +115,15 @@ const TriggerConditionItem = ({ firstField }: TriggerConditionItemProps) => {
                         }
                       >
                         {({ getFieldValue }) => {
-                          const condFlag =
-                            getFieldValue([
-                              "filters",
-                              firstField.name,
-                              "conditions",
-                              field.name,
-                              "cond",
-                            ]) > 1;
+                          const cond = getFieldValue([
+                            "filters",
+                            firstField.name,
+                            "conditions",
+                            field.name,
+                            "cond",
+                          ]);
+                          const condFlag = cond === 2 || cond === 3;
+                          const anomalyFlag = anomalyCondList.includes(cond);
                           return (
                             <Space>
                               <Form.Item
@@ -165,6 +172,40 @@ const TriggerConditionItem = ({ firstField }: TriggerConditionItemProps) => {
                                   </Form.Item>
                                 </>
                               )}
+                              {anomalyFlag && (
+                                <Form.Item
+                                  className={conditionStyles.formItemMargin}
+                                  name={[field.name, "baseline"]}
+                                >
+                                  <InputNumber
+                                    min={0}
+                                    placeholder={`${i18n.formatMessage({
+                                      id: "alarm.rules.form.baseline",
+                                    })}`}
+                                    className={conditionStyles.inputNumber}
+                                  />
+                                </Form.Item>
+                              )}
+                              {cond === newSignatureCond && (
+                                <Form.Item
+                                  className={conditionStyles.formItemMargin}
+                                  name={[field.name, "field"]}
+                                  rules={[
+                                    {
+                                      required: true,
+                                      message: i18n.formatMessage({
+                                        id: "required",
+                                      }),
+                                    },
+                                  ]}
+                                >
+                                  <Input
+                                    placeholder={`${i18n.formatMessage({
+                                      id: "alarm.rules.form.signatureField",
+                                    })}`}
+                                  />
+                                </Form.Item>
+                              )}
                             </Space>
                           );
                         }}
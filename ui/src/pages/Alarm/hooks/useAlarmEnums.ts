@@ -9,6 +9,10 @@ const useAlarmEnums = () => {
     { name: i18n.formatMessage({ id: "slack" }), value: 4 },
     { name: "Webhook", value: 5 },
     { name: "Telegram", value: 6 },
+    { name: "Email", value: 7 },
+    { name: "PagerDuty", value: 8 },
+    { name: "Opsgenie", value: 9 },
+    { name: "Microsoft Teams", value: 10 },
   ];
   const AlarmStatus = [
     {
@@ -29,4 +29,11 @@ export const condList = [
   { key: 1, label: "below" },
   { key: 2, label: "outside range" },
   { key: 3, label: "within range" },
+  { key: 4, label: "percent change" },
+  { key: 5, label: "z-score" },
+  { key: 6, label: "new signature" },
 ];
+
+// condition keys compared to a baseline, the baseline is in seconds
+export const anomalyCondList = [4, 5, 6];
+export const newSignatureCond = 6;
@@ -27,7 +27,7 @@ import {
   LinkOutlined,
 } from "@ant-design/icons";
 import { useModel } from "@umijs/max";
-import { Dropdown, message, Tooltip } from "antd";
+import { Badge, Dropdown, message, Tooltip } from "antd";
 import classNames from "classnames";
 import lodash from "lodash";
 import moment from "moment";
@@ -363,6 +363,17 @@ const LogLibraryItem = (props: LogLibraryItemProps) => {
               : i18n.formatMessage({ id: "alarm.rules.history.isPushed.true" })}
           </div>
         </div>
+        {logLibrary.health && (
+          <div>
+            <div className={logLibraryListStyles.logTipTitle}>
+              {i18n.formatMessage({ id: "datasource.logLibrary.health" })}
+              :&nbsp;
+              {i18n.formatMessage({
+                id: `datasource.logLibrary.health.${logLibrary.health}`,
+              })}
+            </div>
+          </div>
+        )}
       </div>
     ),
     [logLibrary]
@@ -410,6 +421,13 @@ const LogLibraryItem = (props: LogLibraryItemProps) => {
             {logIcon}
 
             {logLibrary.tableName}
+            {(logLibrary.health == "degraded" ||
+              logLibrary.health == "stalled") && (
+              <Badge
+                status={logLibrary.health == "stalled" ? "error" : "warning"}
+                style={{ marginLeft: "4px" }}
+              />
+            )}
           </span>
         </Tooltip>
       </Dropdown>
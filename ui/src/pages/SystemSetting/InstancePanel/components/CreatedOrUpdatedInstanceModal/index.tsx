@@ -9,6 +9,7 @@ import {
   Form,
   FormInstance,
   Input,
+  InputNumber,
   message,
   Modal,
   Select,
@@ -282,6 +283,34 @@ const CreatedOrUpdatedInstanceModal = (
             })}
           />
         </Form.Item>
+        <Form.Item
+          name={"queryMaxConcurrency"}
+          label={i18n.formatMessage({
+            id: "instance.form.title.queryMaxConcurrency",
+          })}
+        >
+          <InputNumber
+            min={0}
+            style={{ width: "100%" }}
+            placeholder={i18n.formatMessage({
+              id: "instance.form.placeholder.queryLimit",
+            })}
+          />
+        </Form.Item>
+        <Form.Item
+          name={"queryMaxExecutionTime"}
+          label={i18n.formatMessage({
+            id: "instance.form.title.queryMaxExecutionTime",
+          })}
+        >
+          <InputNumber
+            min={0}
+            style={{ width: "100%" }}
+            placeholder={i18n.formatMessage({
+              id: "instance.form.placeholder.queryLimit",
+            })}
+          />
+        </Form.Item>
       </Form>
     </Modal>
   );
@@ -1,15 +1,123 @@
 import { TimeBaseType } from "@/services/systemSetting";
 import { request } from "@umijs/max";
+import { stringify } from "qs";
+
+// QueryMode 0 query language, 1 raw SQL which requires the raw SQL permission unless app.rawSQLPermission is off
+export enum QueryMode {
+  language = 0,
+  sql = 1,
+}
+
 export interface QueryLogsProps {
   st: number;
   et: number;
   query?: string | undefined;
+  queryMode?: QueryMode;
   pageSize?: number;
   page?: number;
+  cursor?: string; // search-after cursor of LogsResponse, replaces page
+  direction?: "older" | "newer";
   alarmMode?: number;
   filters?: string[];
 }
 
+export interface LogsContextRequest {
+  cursor?: string; // cursor of the log in LogsResponse
+  ts?: number; // _time_nanosecond_ of the log when there is no cursor
+  before?: number;
+  after?: number;
+  // values of the log which identify its source, _file_, _offset_ and _sign_ for agent and local instances
+  fields: Record<string, string>;
+}
+
+export interface LogsContextResponse {
+  before: any[];
+  after: any[];
+  query: string;
+}
+
+export interface LogsPattern {
+  id: string; // hash of the template
+  template: string; // variable tokens are masked by <*>
+  count: number; // estimated logs of the time range
+  sampled: number;
+  sparkline: number[]; // estimated logs of every bucket
+  samples: any[];
+}
+
+export interface LogsPatternsResponse {
+  field: string;
+  sampled: number;
+  total: number;
+  buckets: number[]; // start second of every point of the sparklines
+  patterns: LogsPattern[];
+}
+
+export interface LogsPatternDiff extends LogsPattern {
+  previous: number; // estimated logs of the previous time range
+  status: "new" | "up" | "gone" | "down" | "same";
+}
+
+export interface LogsPatternsDiffResponse
+  extends Omit<LogsPatternsResponse, "patterns"> {
+  previousSt: number;
+  previousEt: number;
+  patterns: LogsPatternDiff[];
+}
+
+export interface FieldStatsValue {
+  value: string;
+  count: number;
+  percent: number;
+  series: number[]; // logs of the value at every point of the timeline
+}
+
+export interface FieldStatsNumeric {
+  min: number;
+  max: number;
+  avg: number;
+  percentiles: Record<"p50" | "p90" | "p95" | "p99", number>;
+  histogram: { lower: number; upper: number; count: number }[];
+}
+
+export interface FieldStats {
+  field: string;
+  uniq: number; // estimated distinct values
+  empty: number; // logs of which the value is null or empty
+  emptyRatio: number;
+  top: FieldStatsValue[];
+  other: number; // logs of the values out of the top
+  numeric?: FieldStatsNumeric;
+}
+
+export interface FieldStatsResponse {
+  total: number;
+  interval: number;
+  timeline: number[]; // start second of every point of the time series
+  fields: FieldStats[];
+}
+
+export interface TableHealthResponse {
+  tid: number;
+  status: "healthy" | "degraded" | "stalled" | "unknown";
+  reason: string;
+  since: number; // unix time the status started
+  consumers: number; // -1 when unknown
+  consumerLag: number; // -1 when unknown
+  consumerExceptions: number; // recent exceptions of the kafka consumers, at most the last 10 per consumer
+  viewExceptions: number;
+  lastException: string;
+  lastInsert: number;
+  lastPoll: number;
+  checked: number;
+}
+
+export interface LogsTailEvent {
+  logs: any[]; // ascending time order
+  dropped: number; // logs left out by sampling
+  skipped: boolean; // the tail fell behind and jumped to the newest logs
+}
+
 export interface GetTableIdRequest {
   instance: string;
   database: string;
@@ -33,6 +141,9 @@ export interface LogsResponse extends StatisticalTableResponse {
   terms: string[][];
   query: string;
   where: string;
+  cursors: string[]; // cursor of every log line
+  older: string;
+  newer: string;
   isTrace: number; // 0 非链路模式 1 jaeger_json格式
 }
 
@@ -131,6 +242,8 @@ export interface TablesResponse {
   createType: number;
   desc: string;
   relTraceTableId: number;
+  // ingestion health: healthy, degraded, stalled or unknown, empty when it is not collected
+  health?: string;
 }
 
 export interface TableInfoResponse {
@@ -220,6 +333,7 @@ export interface IndexDetailRequest {
   st: number;
   et: number;
   query?: string | undefined;
+  queryMode?: QueryMode;
 }
 
 export interface IndexDetail {
@@ -254,6 +368,73 @@ export enum CollectType {
   tableFilter = 2,
   globalFilter = 4,
   allFilter = 6,
+  savedSearch = 8,
+}
+
+// SavedSearchScope is who can open a saved search besides its creator
+export enum SavedSearchScope {
+  private = 0,
+  team = 1, // the members
+  table = 2, // everyone with table access
+}
+
+export interface SavedSearchFields {
+  queryMode?: QueryMode;
+  relative?: number; // seconds before now, st and et are used when 0
+  st?: number;
+  et?: number;
+  fields?: string[]; // visible fields
+  filters?: string[]; // filters of the query, as filters of QueryLogsProps
+  sort?: "desc" | "asc";
+  scope?: SavedSearchScope;
+  members?: number[]; // users of the team scope
+}
+
+export interface SavedSearchType extends LogFilterType, SavedSearchFields {
+  permalink: string; // opened by api/share/searches/{permalink}
+}
+
+export interface QueryHistoryType {
+  id: number;
+  uid: number;
+  tableId: number;
+  query: string;
+  filters: string[];
+  queryMode: QueryMode;
+  st: number;
+  et: number;
+  cost: number; // milliseconds
+  rows: number;
+  ctime: number;
+}
+
+export interface QueryJobRequest {
+  kind: "logs" | "sql";
+  tid?: number;
+  param?: QueryLogsProps & { queryMode?: QueryMode };
+  iid?: number;
+  sql?: string;
+}
+
+export interface QueryJobType {
+  id: string;
+  kind: "logs" | "sql";
+  uid: number;
+  iid: number;
+  tid: number;
+  status: "running" | "succeeded" | "failed" | "cancelled";
+  readRows: number;
+  readBytes: number;
+  totalRows: number;
+  partialRows: number;
+  error: string;
+  ctime: number;
+  ftime: number;
+}
+
+export interface QueryJobResultType extends QueryJobType {
+  partial: any[];
+  result?: LogsResponse | StatisticalTableResponse;
 }
 
 export default {
@@ -268,7 +449,8 @@ export default {
       {
         cancelToken,
         method: "GET",
-        params,
+        // the search box writes SQL conditions
+        params: { queryMode: QueryMode.sql, ...params },
         skipErrorHandler: true,
       }
     );
@@ -281,12 +463,83 @@ export default {
       {
         cancelToken,
         method: "GET",
-        params,
+        params: { queryMode: QueryMode.sql, ...params },
         skipErrorHandler: true,
       }
     );
   },
 
+  // Cluster the logs of the query into templates, field is the raw log field by default
+  async getLogsPatterns(
+    tableId: number,
+    params: QueryLogsProps & { field?: string }
+  ) {
+    return request<API.Res<LogsPatternsResponse>>(
+      process.env.PUBLIC_PATH + `api/v1/tables/${tableId}/logs/patterns`,
+      { method: "GET", params: { queryMode: QueryMode.sql, ...params } }
+    );
+  },
+
+  // Compare the patterns of the time range with the previous range of the same length
+  async getLogsPatternsDiff(
+    tableId: number,
+    params: QueryLogsProps & { field?: string }
+  ) {
+    return request<API.Res<LogsPatternsDiffResponse>>(
+      process.env.PUBLIC_PATH + `api/v1/tables/${tableId}/logs/patterns/diff`,
+      { method: "GET", params: { queryMode: QueryMode.sql, ...params } }
+    );
+  },
+
+  // Top values, distinct count, empty ratio and numeric distribution of the fields
+  async getLogsStats(
+    tableId: number,
+    params: QueryLogsProps & { fields: string[]; k?: number; buckets?: number }
+  ) {
+    return request<API.Res<FieldStatsResponse>>(
+      process.env.PUBLIC_PATH + `api/v1/tables/${tableId}/logs/stats`,
+      { method: "GET", params: { queryMode: QueryMode.sql, ...params } }
+    );
+  },
+
+  // The logs around a log of the same pod, container or file, as grep -C
+  async getLogsContext(tableId: number, params: LogsContextRequest) {
+    return request<API.Res<LogsContextResponse>>(
+      process.env.PUBLIC_PATH +
+        `api/v1/tables/${tableId}/logs/context?${stringify(params)}`,
+      { method: "GET" }
+    );
+  },
+
+  // Follow the new logs of the query, the "logs" events carry a LogsTailEvent
+  tailLogs(tableId: number, params: QueryLogsProps) {
+    return new EventSource(
+      process.env.PUBLIC_PATH +
+        `api/v1/tables/${tableId}/logs/tail?${stringify(
+          { queryMode: QueryMode.sql, ...params },
+          { arrayFormat: "brackets" }
+        )}`
+    );
+  },
+
+  // Export the search results as a csv, ndjson or parquet file
+  async exportLogs(
+    tableId: number,
+    params: QueryLogsProps & {
+      format: "csv" | "ndjson" | "parquet";
+      limit?: number;
+    }
+  ) {
+    return request<Blob>(
+      process.env.PUBLIC_PATH + `api/v1/tables/${tableId}/logs/export`,
+      {
+        method: "GET",
+        params: { queryMode: QueryMode.sql, ...params },
+        responseType: "blob",
+      }
+    );
+  },
+
   // Get a list of log stores
   async getTableList(did: number) {
     return request<API.Res<TablesResponse[]>>(
@@ -450,7 +703,7 @@ export default {
       process.env.PUBLIC_PATH + `api/v1/tables/${tid}/indexes/${id}`,
       {
         method: "GET",
-        params,
+        params: { queryMode: QueryMode.sql, ...params },
       }
     );
   },
@@ -666,6 +919,81 @@ export default {
     );
   },
 
+  // Saved searches of the user and the searches shared with the user
+  async getSavedSearchList(params: { tableId?: number }) {
+    return request<API.Res<SavedSearchType[]>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/collects`,
+      {
+        method: "GET",
+        params: { collectType: CollectType.savedSearch, ...params },
+      }
+    );
+  },
+
+  async createSavedSearch(
+    data: SavedSearchFields & {
+      tableId: number;
+      alias: string;
+      statement: string;
+    }
+  ) {
+    return request<API.Res<SavedSearchType>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/collects`,
+      {
+        method: "POST",
+        data: { collectType: CollectType.savedSearch, ...data },
+      }
+    );
+  },
+
+  // A saved search is updated as a whole
+  async updateSavedSearch(
+    collectId: number,
+    data: SavedSearchFields & { alias: string; statement: string }
+  ) {
+    return request(
+      process.env.PUBLIC_PATH + `api/v2/storage/collects/${collectId}`,
+      { method: "PATCH", data }
+    );
+  },
+
+  async getSavedSearchByPermalink(code: string) {
+    return request<API.Res<SavedSearchType & { url: string }>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/collects/permalinks/${code}`,
+      { method: "GET" }
+    );
+  },
+
+  // Log queries of the user, the newest first
+  async getQueryHistoryList(params: {
+    tableId?: number;
+    current?: number;
+    pageSize?: number;
+  }) {
+    return request<API.Res<{ total: number; list: QueryHistoryType[] }>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/query-histories`,
+      { method: "GET", params }
+    );
+  },
+
+  async clearQueryHistory() {
+    return request(process.env.PUBLIC_PATH + `api/v2/storage/query-histories`, {
+      method: "DELETE",
+    });
+  },
+
+  // Save a query of the history, its time range is kept unless relative is set
+  async promoteQueryHistory(
+    historyId: number,
+    data: SavedSearchFields & { alias: string }
+  ) {
+    return request<API.Res<SavedSearchType>>(
+      process.env.PUBLIC_PATH +
+        `api/v2/storage/query-histories/${historyId}/promote`,
+      { method: "POST", data }
+    );
+  },
+
   // 获取当前表的字段
   async getColumns(storageId: number) {
     return request(
@@ -675,4 +1003,54 @@ export default {
       }
     );
   },
+
+  // Kafka consumers, lag, consumer exceptions, view exceptions and last insert of the table
+  async getTableHealth(storageId: number) {
+    return request<API.Res<TableHealthResponse>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/${storageId}/health`,
+      {
+        method: "GET",
+      }
+    );
+  },
+
+  // Submit a query job
+  async createQueryJob(data: QueryJobRequest) {
+    return request<API.Res<QueryJobType>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/query-jobs`,
+      {
+        method: "POST",
+        data,
+      }
+    );
+  },
+
+  // Poll the status and progress of a query job
+  async getQueryJob(id: string) {
+    return request<API.Res<QueryJobType>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/query-jobs/${id}`,
+      {
+        method: "GET",
+      }
+    );
+  },
+
+  // Partial rows of a running job or the result of a finished one
+  async getQueryJobResult(id: string) {
+    return request<API.Res<QueryJobResultType>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/query-jobs/${id}/result`,
+      {
+        method: "GET",
+      }
+    );
+  },
+
+  async cancelQueryJob(id: string) {
+    return request<API.Res<QueryJobType>>(
+      process.env.PUBLIC_PATH + `api/v2/storage/query-jobs/${id}/cancel`,
+      {
+        method: "POST",
+      }
+    );
+  },
 };
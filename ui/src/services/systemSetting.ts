@@ -17,6 +17,8 @@ export interface InstanceType extends TimeBaseType {
   prometheusTarget?: string;
   clusters?: string[];
   desc?: string;
+  queryMaxConcurrency?: number;
+  queryMaxExecutionTime?: number;
 }
 
 export interface TestInstanceRequest {